@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/config"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+var aliasNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// aliasTarget is the artifact an alias points to, e.g. workflow:reports/lcov.info.
+type aliasTarget struct {
+	ResourceType string
+	Path         string
+}
+
+func (t *aliasTarget) String() string {
+	return fmt.Sprintf("%s:%s", t.ResourceType, t.Path)
+}
+
+func NewAliasCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Manages named aliases for artifact paths",
+		Long: `Aliases give long artifact paths a short name that can be used
+in place of the category and path, e.g. 'artifact pull @coverage'.
+Aliases are stored in the config file.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set [NAME] [CATEGORY:PATH]",
+		Short: "Creates or updates an alias, e.g. 'alias set coverage workflow:reports/lcov.info'.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(setAlias(cmd.OutOrStdout(), args[0], args[1]))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "unset [NAME]",
+		Aliases: []string{"rm"},
+		Short:   "Removes an alias.",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(unsetAlias(cmd.OutOrStdout(), args[0]))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Lists all aliases.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			aliases := viper.GetStringMapString("aliases")
+
+			names := make([]string, 0, len(aliases))
+			for name := range aliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			for _, name := range names {
+				fmt.Fprintf(cmd.OutOrStdout(), "@%s\t%s\n", name, aliases[name])
+			}
+		},
+	})
+
+	return cmd
+}
+
+func setAlias(out io.Writer, name, rawTarget string) error {
+	name = strings.TrimPrefix(name, "@")
+	if !aliasNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid alias name '%s': use letters, digits, '-' and '_'", name)
+	}
+
+	target, err := parseAliasTarget(rawTarget)
+	if err != nil {
+		return err
+	}
+
+	f, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	f.Set("aliases."+strings.ToLower(name), target.String())
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Alias '@%s' now points to '%s'.\n", name, target)
+	return nil
+}
+
+func unsetAlias(out io.Writer, name string) error {
+	name = strings.ToLower(strings.TrimPrefix(name, "@"))
+
+	f, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if !f.Unset("aliases." + name) {
+		return fmt.Errorf("alias '@%s' is not defined", name)
+	}
+
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Alias '@%s' removed.\n", name)
+	return nil
+}
+
+// isAlias returns true if the argument refers to an alias, e.g. @coverage.
+func isAlias(arg string) bool {
+	return strings.HasPrefix(arg, "@")
+}
+
+// resolveAlias looks up an @alias in the config.
+func resolveAlias(arg string) (*aliasTarget, error) {
+	name := strings.ToLower(strings.TrimPrefix(arg, "@"))
+
+	target, ok := viper.GetStringMapString("aliases")[name]
+	if !ok {
+		return nil, fmt.Errorf("alias '@%s' is not defined; create it with 'artifact alias set %s CATEGORY:PATH'", name, name)
+	}
+
+	return parseAliasTarget(target)
+}
+
+// parseAliasTarget parses CATEGORY:PATH, where CATEGORY is job, workflow or project.
+func parseAliasTarget(target string) (*aliasTarget, error) {
+	parts := strings.SplitN(target, ":", 2)
+	if len(parts) != 2 || files.ToRelative(parts[1]) == "" {
+		return nil, fmt.Errorf("invalid alias target '%s': use CATEGORY:PATH, e.g. workflow:reports/lcov.info", target)
+	}
+
+	switch parts[0] {
+	case files.ResourceTypeJob, files.ResourceTypeWorkflow, files.ResourceTypeProject:
+		return &aliasTarget{ResourceType: parts[0], Path: files.ToRelative(parts[1])}, nil
+	default:
+		return nil, fmt.Errorf("invalid alias target '%s': category must be job, workflow or project", target)
+	}
+}
+
+// resolverForAlias resolves an @alias argument into the path resolver
+// for its category and the path inside that category.
+func resolverForAlias(arg string) (*files.PathResolver, string, error) {
+	target, err := resolveAlias(arg)
+	if err != nil {
+		return nil, "", err
+	}
+
+	resolver, err := files.NewPathResolver(target.ResourceType, "")
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resolver, target.Path, nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewAliasCmd())
+}
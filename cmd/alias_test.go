@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Alias(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), ".artifact.yaml")
+	viper.SetConfigFile(configFile)
+	defer viper.Reset()
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewAliasCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("set stores the alias in the config file", func(t *testing.T) {
+		output := run("set", "coverage", "workflow:reports/coverage/lcov.info")
+		assert.Contains(t, output, "Alias '@coverage' now points to 'workflow:reports/coverage/lcov.info'")
+		require.NoError(t, viper.ReadInConfig())
+
+		target, err := resolveAlias("@coverage")
+		require.NoError(t, err)
+		assert.Equal(t, files.ResourceTypeWorkflow, target.ResourceType)
+		assert.Equal(t, "reports/coverage/lcov.info", target.Path)
+	})
+
+	t.Run("list prints aliases", func(t *testing.T) {
+		run("set", "build", "project:releases/app.tar.gz")
+		require.NoError(t, viper.ReadInConfig())
+
+		output := run("list")
+		assert.Equal(t, "@build\tproject:releases/app.tar.gz\n@coverage\tworkflow:reports/coverage/lcov.info\n", output)
+	})
+
+	t.Run("set rejects invalid targets", func(t *testing.T) {
+		run("set", "broken", "nightly:reports")
+		require.NoError(t, viper.ReadInConfig())
+
+		_, err := resolveAlias("@broken")
+		assert.Error(t, err)
+	})
+
+	t.Run("unset removes the alias", func(t *testing.T) {
+		output := run("unset", "coverage")
+		assert.Contains(t, output, "Alias '@coverage' removed")
+		require.NoError(t, viper.ReadInConfig())
+
+		_, err := resolveAlias("@coverage")
+		assert.Error(t, err)
+	})
+}
+
+func Test__ParseAliasTarget(t *testing.T) {
+	target, err := parseAliasTarget("job:./logs/build.log")
+	require.NoError(t, err)
+	assert.Equal(t, "job:logs/build.log", target.String())
+
+	for _, invalid := range []string{"logs/build.log", "job:", "pipeline:logs/build.log"} {
+		_, err := parseAliasTarget(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
@@ -0,0 +1,204 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/batch"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func NewBatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "batch",
+		Short: "Runs the pushes, pulls and yanks described in a manifest",
+		Long: `Runs several pushes, pulls and yanks from a YAML manifest with one
+backend connection, and prints a single summary, e.g. for release jobs
+that store many files:
+
+  category: workflow
+  operations:
+    - push: build/app.tar.gz
+      destination: releases/app.tar.gz
+      force: true
+    - pull: reports/
+      category: job
+      id: 4b7a...
+    - yank: tmp/
+
+Operations run in order. Every operation takes category, id and, except
+yanks, destination and force; pushes also take ifChanged and metadata.`,
+	}
+
+	applyCmd := &cobra.Command{
+		Use:   "apply MANIFEST",
+		Short: "Runs the operations of a manifest, e.g. 'batch apply ops.yaml'.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runBatchApply,
+	}
+	applyCmd.Flags().Bool("keep-going", false, "run the remaining operations after one fails")
+	cmd.AddCommand(applyCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "validate MANIFEST",
+		Short: "Checks a manifest without running it.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			m, err := batch.LoadFile(args[0])
+			errutil.Check(err)
+
+			fmt.Fprintf(cmd.OutOrStdout(), "Manifest '%s' is valid: %d %s.\n", args[0], len(m.Operations), pluralize(len(m.Operations), "operation", "operations"))
+		},
+	})
+
+	return cmd
+}
+
+func runBatchApply(cmd *cobra.Command, args []string) {
+	keepGoing, _ := cmd.Flags().GetBool("keep-going")
+
+	m, err := batch.LoadFile(args[0])
+	errutil.Check(err)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	summary := runBatch(getContext(), b, m, keepGoing)
+	summary.log()
+
+	if summary.Failed > 0 {
+		errutil.Exit(1)
+	}
+}
+
+// batchSummary counts the outcome of the operations of a manifest.
+type batchSummary struct {
+	Succeeded int
+	Failed    int
+	Skipped   int // not run after a failure
+	Pushed    storage.PushStats
+	Pulled    storage.PullStats
+	Yanked    int
+}
+
+// runBatch runs the operations of m in order with b. After a failed
+// operation, the remaining ones are skipped unless keepGoing is set.
+func runBatch(ctx context.Context, b backend.Backend, m *batch.Manifest, keepGoing bool) *batchSummary {
+	summary := &batchSummary{}
+
+	for i, op := range m.Operations {
+		if summary.Failed > 0 && !keepGoing {
+			summary.Skipped++
+			continue
+		}
+
+		prefix := fmt.Sprintf("[%d/%d] %s %s '%s'", i+1, len(m.Operations), op.Kind, op.Category, op.Path)
+		result, err := runBatchOperation(ctx, b, op, summary)
+		if err != nil {
+			log.Errorf("%s: %v\n", prefix, err)
+			summary.Failed++
+			continue
+		}
+
+		log.Infof("%s: %s.\n", prefix, result)
+		summary.Succeeded++
+	}
+
+	return summary
+}
+
+// runBatchOperation runs op like the push, pull or yank command would, and
+// describes what it did.
+func runBatchOperation(ctx context.Context, b backend.Backend, op *batch.Operation, summary *batchSummary) (string, error) {
+	resolver, err := files.NewPathResolver(op.Category, op.ID)
+	if err != nil {
+		return "", err
+	}
+
+	switch op.Kind {
+	case files.OperationPush:
+		paths, err := resolver.Resolve(files.OperationPush, op.Path, op.Destination)
+		if err != nil {
+			return "", err
+		}
+
+		stats, err := checkPushPolicy(resolver, paths, op.Metadata, nil)
+		if err != nil {
+			return "", err
+		}
+
+		opts := backend.PushOptions{Force: op.Force, Metadata: op.Metadata}
+		skipped := 0
+		if op.IfChanged {
+			stats, skipped, err = pushChanged(ctx, b, paths, nil, opts, false)
+		} else {
+			err = b.Push(ctx, paths.Source, paths.Destination, opts)
+		}
+		if err != nil {
+			return "", err
+		}
+
+		summary.Pushed.FileCount += stats.FileCount
+		summary.Pushed.TotalSize += stats.TotalSize
+
+		result := fmt.Sprintf("pushed %d %s (%s) to '%s'", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize), paths.Destination)
+		if skipped > 0 {
+			result += fmt.Sprintf(", skipped %d unchanged", skipped)
+		}
+		return result, nil
+
+	case files.OperationPull:
+		destination := op.Destination
+		if destination == "" {
+			destination = files.MappedDestination(viper.GetStringMapString("pullMappings"), resolver.ResourceType, op.Path)
+		}
+
+		paths, err := resolver.Resolve(files.OperationPull, op.Path, destination)
+		if err != nil {
+			return "", err
+		}
+
+		stats, err := pullResolved(ctx, b, paths, backend.PullOptions{Force: op.Force})
+		if err != nil {
+			return "", err
+		}
+
+		summary.Pulled.FileCount += stats.FileCount
+		summary.Pulled.TotalSize += stats.TotalSize
+		return fmt.Sprintf("pulled %d %s (%s) to '%s'", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize), paths.Destination), nil
+
+	default:
+		paths, err := resolver.Resolve(files.OperationYank, op.Path, "")
+		if err != nil {
+			return "", err
+		}
+
+		if err := checkYankPolicy(resolver, paths); err != nil {
+			return "", err
+		}
+
+		if err := b.Yank(ctx, paths.Source); err != nil {
+			return "", err
+		}
+
+		summary.Yanked++
+		return fmt.Sprintf("yanked '%s'", paths.Source), nil
+	}
+}
+
+func (s *batchSummary) log() {
+	log.Infof("Batch finished: %d succeeded, %d failed, %d skipped.\n", s.Succeeded, s.Failed, s.Skipped)
+	log.Infof("* Pushed %d %s. Total of %s\n", s.Pushed.FileCount, pluralize(s.Pushed.FileCount, "file", "files"), formatBytes(s.Pushed.TotalSize))
+	log.Infof("* Pulled %d %s. Total of %s\n", s.Pulled.FileCount, pluralize(s.Pulled.FileCount, "file", "files"), formatBytes(s.Pulled.TotalSize))
+	log.Infof("* Yanked %d %s.\n", s.Yanked, pluralize(s.Yanked, "path", "paths"))
+}
+
+func init() {
+	rootCmd.AddCommand(NewBatchCmd())
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/batch"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__RunBatch(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv("SEMAPHORE_WORKFLOW_ID", "2")
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.txt"), []byte("app"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "reports"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reports", "a.xml"), []byte("aa"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "reports", "b.xml"), []byte("bbb"), 0600))
+
+	manifest := func(operations ...*batch.Operation) *batch.Manifest {
+		m := &batch.Manifest{Operations: operations}
+		require.NoError(t, m.Validate())
+		return m
+	}
+
+	t.Run("runs every operation", func(t *testing.T) {
+		memory := memorybackend.New()
+		memory.Put("artifacts/jobs/1/tmp/x.log", []byte("x"))
+		memory.Put("artifacts/workflows/2/cache.txt", []byte("cache"))
+
+		summary := runBatch(ctx, memory, manifest(
+			&batch.Operation{Push: filepath.Join(dir, "app.txt"), Category: "workflow", Destination: "releases/app.txt", Metadata: map[string]string{"version": "1"}},
+			&batch.Operation{Push: filepath.Join(dir, "reports"), Destination: "reports"},
+			&batch.Operation{Pull: "cache.txt", Category: "workflow", Destination: filepath.Join(dir, "cache.txt")},
+			&batch.Operation{Yank: "tmp"},
+		), false)
+
+		assert.Equal(t, 4, summary.Succeeded)
+		assert.Equal(t, 0, summary.Failed)
+		assert.Equal(t, 1, summary.Yanked)
+		assert.Equal(t, 3, summary.Pushed.FileCount)
+		assert.Equal(t, int64(8), summary.Pushed.TotalSize)
+		assert.Equal(t, 1, summary.Pulled.FileCount)
+
+		data, ok := memory.Get("artifacts/workflows/2/releases/app.txt")
+		require.True(t, ok)
+		assert.Equal(t, "app", string(data))
+		assert.Equal(t, map[string]string{"version": "1"}, memory.Metadata("artifacts/workflows/2/releases/app.txt"))
+
+		_, ok = memory.Get("artifacts/jobs/1/reports/b.xml")
+		assert.True(t, ok)
+		_, ok = memory.Get("artifacts/jobs/1/tmp/x.log")
+		assert.False(t, ok)
+
+		pulled, err := os.ReadFile(filepath.Join(dir, "cache.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "cache", string(pulled))
+	})
+
+	t.Run("skips unchanged files", func(t *testing.T) {
+		memory := memorybackend.New()
+		operation := &batch.Operation{Push: filepath.Join(dir, "reports"), Destination: "reports", IfChanged: true}
+
+		summary := runBatch(ctx, memory, manifest(operation), false)
+		assert.Equal(t, 2, summary.Pushed.FileCount)
+
+		summary = runBatch(ctx, memory, manifest(operation), false)
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, 0, summary.Pushed.FileCount)
+	})
+
+	t.Run("skips the remaining operations after a failure", func(t *testing.T) {
+		memory := memorybackend.New()
+		m := manifest(
+			&batch.Operation{Pull: "missing.txt", Destination: filepath.Join(dir, "missing.txt")},
+			&batch.Operation{Push: filepath.Join(dir, "app.txt")},
+		)
+
+		summary := runBatch(ctx, memory, m, false)
+		assert.Equal(t, 1, summary.Failed)
+		assert.Equal(t, 1, summary.Skipped)
+		assert.Empty(t, memory.Paths())
+
+		summary = runBatch(ctx, memory, m, true)
+		assert.Equal(t, 1, summary.Failed)
+		assert.Equal(t, 1, summary.Succeeded)
+		assert.Equal(t, []string{"artifacts/jobs/1/app.txt"}, memory.Paths())
+	})
+
+	t.Run("rejects existing files without force", func(t *testing.T) {
+		memory := memorybackend.New()
+		memory.Put("artifacts/jobs/1/app.txt", []byte("old"))
+
+		summary := runBatch(ctx, memory, manifest(&batch.Operation{Push: filepath.Join(dir, "app.txt")}), false)
+		assert.Equal(t, 1, summary.Failed)
+
+		summary = runBatch(ctx, memory, manifest(&batch.Operation{Push: filepath.Join(dir, "app.txt"), Force: true}), false)
+		assert.Equal(t, 1, summary.Succeeded)
+	})
+}
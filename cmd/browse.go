@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/browse"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/term"
+)
+
+func NewBrowseCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "browse [CATEGORY]",
+		Short: "Browses stored files in an interactive terminal UI",
+		Long: `Navigates the files stored for the current job, workflow and project, or
+only for CATEGORY, e.g. while debugging on a runner over SSH:
+
+  artifact browse job
+
+Enter opens directories and previews text files up to 64 KB. Space marks
+files and directories; p pulls the marked ones, or the one under the
+cursor, into the current directory, and d yanks them after confirmation.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(runBrowse(cmd, args))
+		},
+	}
+
+	for _, c := range categories {
+		cmd.Flags().StringP(c.IDFlag, c.IDShorthand, "", fmt.Sprintf("set explicit %s id", c.ResourceType))
+	}
+
+	return cmd
+}
+
+func runBrowse(cmd *cobra.Command, args []string) error {
+	actions := &browseActions{resolvers: map[browse.Store]*files.PathResolver{}}
+	stores, err := browseStores(cmd, args, actions.resolvers)
+	if err != nil {
+		return err
+	}
+
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		return fmt.Errorf("browse requires a terminal; use ls, pull and yank in scripts")
+	}
+
+	actions.b = getBackend()
+	defer func() { _ = actions.b.Close() }()
+
+	if actions.lister, err = getLister(actions.b); err != nil {
+		return err
+	}
+
+	// Logs of the backend would be drawn over the UI
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	_, err = tea.NewProgram(browse.New(getContext(), actions, stores), tea.WithAltScreen()).Run()
+	return err
+}
+
+// browseStores returns the stores to browse: the one of the category given
+// in args, or else every store whose id is known.
+func browseStores(cmd *cobra.Command, args []string, resolvers map[browse.Store]*files.PathResolver) ([]browse.Store, error) {
+	stores := []browse.Store{}
+	for _, c := range categories {
+		if len(args) > 0 && args[0] != c.ResourceType {
+			continue
+		}
+
+		id, _ := cmd.Flags().GetString(c.IDFlag)
+		resolver, err := files.NewPathResolver(c.ResourceType, id)
+		if err != nil {
+			if len(args) > 0 {
+				return nil, err
+			}
+			continue
+		}
+
+		store := browse.Store{Category: c.ResourceType, ID: resolver.ResourceIdentifier}
+		resolvers[store] = resolver
+		stores = append(stores, store)
+	}
+
+	if len(args) > 0 && len(stores) == 0 {
+		return nil, fmt.Errorf("unknown category '%s': use job, workflow or project", args[0])
+	}
+
+	if len(stores) == 0 {
+		return nil, fmt.Errorf("no job, workflow or project ID is set: use SEMAPHORE_JOB_ID, SEMAPHORE_WORKFLOW_ID, SEMAPHORE_PROJECT_ID or the --job-id, --workflow-id and --project-id flags")
+	}
+
+	return stores, nil
+}
+
+// browseActions runs the operations of the browser on the backend, with
+// the same paths, policies and pull mappings as the pull and yank commands.
+type browseActions struct {
+	b         backend.Backend
+	lister    backend.Lister
+	resolvers map[browse.Store]*files.PathResolver
+}
+
+func (a *browseActions) List(ctx context.Context, store browse.Store) ([]backend.ObjectInfo, error) {
+	root := a.resolvers[store].PrefixedPath("")
+	objects := []backend.ObjectInfo{}
+	err := walkRemote(ctx, a.lister, root, func(obj backend.ObjectInfo) error {
+		obj.Path = relativeName(obj.Path, root)
+		objects = append(objects, obj)
+		return nil
+	})
+
+	return objects, err
+}
+
+func (a *browseActions) Read(ctx context.Context, store browse.Store, name string, limit int64) ([]byte, error) {
+	r, err := openRemote(ctx, a.b, a.resolvers[store].PrefixedPath(name))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(io.LimitReader(r, limit))
+}
+
+func (a *browseActions) Pull(ctx context.Context, store browse.Store, name string) (string, error) {
+	resolver := a.resolvers[store]
+	destination := files.MappedDestination(viper.GetStringMapString("pullMappings"), resolver.ResourceType, name)
+	paths, err := resolver.Resolve(files.OperationPull, name, destination)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := pullResolved(ctx, a.b, paths, backend.PullOptions{}); err != nil {
+		return "", err
+	}
+
+	return paths.Destination, nil
+}
+
+func (a *browseActions) Yank(ctx context.Context, store browse.Store, name string) error {
+	resolver := a.resolvers[store]
+	paths, err := resolver.Resolve(files.OperationYank, name, "")
+	if err != nil {
+		return err
+	}
+
+	if err := checkYankPolicy(resolver, paths); err != nil {
+		return err
+	}
+
+	return a.b.Yank(ctx, paths.Source)
+}
+
+func init() {
+	rootCmd.AddCommand(NewBrowseCmd())
+}
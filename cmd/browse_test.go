@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/browse"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__BrowseStores(t *testing.T) {
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv("SEMAPHORE_WORKFLOW_ID", "")
+	t.Setenv("SEMAPHORE_PROJECT_ID", "")
+
+	stores := func(args ...string) ([]browse.Store, error) {
+		cmd := NewBrowseCmd()
+		require.NoError(t, cmd.ParseFlags(args))
+		return browseStores(cmd, cmd.Flags().Args(), map[browse.Store]*files.PathResolver{})
+	}
+
+	// Stores without an ID are left out
+	s, err := stores()
+	require.NoError(t, err)
+	assert.Equal(t, []browse.Store{{Category: "job", ID: "1"}}, s)
+
+	s, err = stores("--project-id", "p")
+	require.NoError(t, err)
+	assert.Equal(t, []browse.Store{{Category: "job", ID: "1"}, {Category: "project", ID: "p"}}, s)
+
+	_, err = stores("workflow")
+	assert.ErrorContains(t, err, "workflow ID is not set")
+
+	_, err = stores("jobs")
+	assert.ErrorContains(t, err, "unknown category 'jobs'")
+}
+
+func Test__BrowseActions(t *testing.T) {
+	t.Chdir(t.TempDir())
+
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/logs/app.log", []byte("started"))
+	memory.Put("artifacts/jobs/1/app.zip", []byte("zip"))
+	memory.Put("artifacts/jobs/10/other.txt", []byte("other"))
+
+	store := browse.Store{Category: "job", ID: "1"}
+	resolver, err := files.NewPathResolver(files.ResourceTypeJob, "1")
+	require.NoError(t, err)
+	actions := &browseActions{b: memory, lister: memory, resolvers: map[browse.Store]*files.PathResolver{store: resolver}}
+
+	objects, err := actions.List(ctx, store)
+	require.NoError(t, err)
+	names := []string{}
+	for _, obj := range objects {
+		names = append(names, obj.Path)
+	}
+	assert.ElementsMatch(t, []string{"logs/app.log", "app.zip"}, names)
+
+	data, err := actions.Read(ctx, store, "logs/app.log", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "start", string(data))
+
+	destination, err := actions.Pull(ctx, store, "logs")
+	require.NoError(t, err)
+	assert.Equal(t, "logs", destination)
+	contents, err := os.ReadFile(filepath.Join("logs", "app.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "started", string(contents))
+
+	require.NoError(t, actions.Yank(ctx, store, "app.zip"))
+	assert.ElementsMatch(t, []string{"artifacts/jobs/1/logs/app.log", "artifacts/jobs/10/other.txt"}, memory.Paths())
+}
@@ -0,0 +1,200 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/cache"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewCacheCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cache",
+		Short: "Stores and restores caches of dependencies",
+		Long: `Stores files and directories, e.g. installed dependencies, under a key in
+the project store, and restores them in later jobs:
+
+  artifact cache store 'gems-{{ checksum "Gemfile.lock" }}' vendor/bundle
+  artifact cache restore 'gems-{{ checksum "Gemfile.lock" }},gems-'
+
+Keys are templates: checksum returns the SHA256 checksum of one or more
+files, env the value of an environment variable, and os and arch the
+platform. Slashes and spaces in keys become dashes.
+
+Caches are stored as tar.gz archives, and restored to the path they were
+stored from. Restoring tries the keys in order: a cache stored with exactly
+the key, or else the most recent one whose key starts with it.`,
+	}
+
+	cmd.PersistentFlags().StringP("project-id", "p", "", "set explicit project id")
+
+	storeCmd := &cobra.Command{
+		Use:   "store KEY PATH",
+		Short: "Stores a file or directory under a key, unless the key exists.",
+		Args:  cobra.ExactArgs(2),
+		Run:   runCacheStore,
+	}
+	storeCmd.Flags().BoolP("force", "f", false, "replace the cache stored under the key")
+	cmd.AddCommand(storeCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "restore KEY[,FALLBACK...]",
+		Short: "Restores the cache of the first key found.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runCacheRestore,
+	})
+
+	return cmd
+}
+
+func runCacheStore(cmd *cobra.Command, args []string) {
+	force, _ := cmd.Flags().GetBool("force")
+
+	resolver, err := cacheResolver(cmd)
+	errutil.Check(err)
+
+	key, err := cache.RenderKey(args[0])
+	errutil.Check(err)
+
+	localPath := filepath.Clean(args[1])
+	paths := &files.ResolvedPath{Source: localPath, Destination: resolver.PrefixedPath(cache.ObjectName(key, localPath))}
+	localStats, err := checkPushPolicy(resolver, paths, nil, nil)
+	errutil.Check(err)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	log.Infof("Storing '%s' with cache key '%s'...\n", localPath, key)
+	size, err := storeCache(getContext(), b, resolver, key, paths, force)
+	if err != nil {
+		log.Errorf("Error storing cache: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	if size < 0 {
+		log.Infof("Cache key '%s' already exists; use --force to replace it.\n", key)
+		return
+	}
+
+	log.Infof("Stored %d %s (%s) in %s.\n", localStats.FileCount, pluralize(localStats.FileCount, "file", "files"), formatBytes(localStats.TotalSize), formatBytes(size))
+}
+
+func runCacheRestore(cmd *cobra.Command, args []string) {
+	resolver, err := cacheResolver(cmd)
+	errutil.Check(err)
+
+	keys, err := cache.RenderKeys(args[0])
+	errutil.Check(err)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	entry, stats, err := restoreCache(getContext(), b, resolver, keys)
+	if err != nil {
+		log.Errorf("Error restoring cache: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	// A missing cache is expected, e.g. after a lockfile changed
+	if entry == nil {
+		log.Infof("No cache found for '%s'.\n", strings.Join(keys, "', '"))
+		return
+	}
+
+	log.Infof("Restored %d %s (%s) to '%s' from cache key '%s'.\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize), filepath.FromSlash(entry.LocalPath), entry.Key)
+}
+
+// cacheResolver returns the project store caches are kept in.
+func cacheResolver(cmd *cobra.Command) (*files.PathResolver, error) {
+	projectID, _ := cmd.Flags().GetString("project-id")
+	return files.NewPathResolver(files.ResourceTypeProject, projectID)
+}
+
+// storeCache pushes paths.Source as a tar.gz archive to paths.Destination,
+// unless a cache is already stored under key and force is not set. It
+// returns the size of the archive, or -1 if the key already exists.
+func storeCache(ctx context.Context, b backend.Backend, resolver *files.PathResolver, key string, paths *files.ResolvedPath, force bool) (int64, error) {
+	keyDir := resolver.PrefixedPath(path.Join(cache.Dir, key))
+	exists, err := remoteExists(ctx, b, keyDir)
+	if err != nil {
+		return 0, err
+	}
+
+	if exists && !force {
+		return -1, nil
+	}
+
+	// The key may hold the cache of another path, which would be restored too
+	if exists {
+		if err := b.Yank(ctx, keyDir); err != nil && !isNotFound(err) {
+			return 0, fmt.Errorf("failed to replace cache key '%s': %v", key, err)
+		}
+	}
+
+	// Entries are named relative to the parent, so they restore to the same path
+	return pushArchiveStream(ctx, b, paths.Destination, backend.PushOptions{Force: true}, func(w io.Writer) error {
+		_, _, err := files.WriteArchiveRelative(w, files.ArchiveTarGz, paths.Source, filepath.Dir(paths.Source))
+		return err
+	})
+}
+
+// restoreCache restores the cache of the first of keys that matches one,
+// and returns it, or nil if none does.
+func restoreCache(ctx context.Context, b backend.Backend, resolver *files.PathResolver, keys []string) (*cache.Entry, *storage.PullStats, error) {
+	lister, err := getLister(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := resolver.PrefixedPath("")
+	for _, key := range keys {
+		entries := []cache.Entry{}
+		err := lister.List(ctx, resolver.PrefixedPath(cache.Dir)+"/"+key, func(obj backend.ObjectInfo) error {
+			if entry, ok := cache.ParseObjectName(relativeName(obj.Path, root)); ok {
+				entry.ModTime = obj.ModTime
+				entries = append(entries, *entry)
+			}
+			return nil
+		})
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		entry, ok := cache.Match(entries, key)
+		if !ok {
+			log.Debugf("No cache found for key '%s'.\n", key)
+			continue
+		}
+
+		paths := &files.ResolvedPath{
+			Source:      resolver.PrefixedPath(entry.Path),
+			Destination: filepath.Dir(filepath.FromSlash(entry.LocalPath)),
+		}
+
+		stats, err := pullExtracted(ctx, b, paths, files.ArchiveTarGz, true, nil)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to restore cache key '%s': %v", entry.Key, err)
+		}
+
+		return entry, stats, nil
+	}
+
+	return nil, nil, nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewCacheCmd())
+}
@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/cache"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__CacheStoreAndRestore(t *testing.T) {
+	ctx := context.Background()
+	t.Chdir(t.TempDir())
+
+	require.NoError(t, os.MkdirAll(filepath.Join("vendor", "bundle", "gems"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join("vendor", "bundle", "gems", "rake.rb"), []byte("rake"), 0600))
+
+	memory := memorybackend.New()
+	resolver, err := files.NewPathResolver(files.ResourceTypeProject, "1")
+	require.NoError(t, err)
+
+	store := func(key, localPath string, force bool) int64 {
+		paths := &files.ResolvedPath{Source: localPath, Destination: resolver.PrefixedPath(cache.ObjectName(key, localPath))}
+		size, err := storeCache(ctx, memory, resolver, key, paths, force)
+		require.NoError(t, err)
+		return size
+	}
+
+	assert.Positive(t, store("gems-abc", filepath.Join("vendor", "bundle"), false))
+	assert.Equal(t, []string{"artifacts/projects/1/.cache/gems-abc/vendor%2Fbundle.tar.gz"}, memory.Paths())
+
+	// Existing keys are kept unless forced
+	assert.Equal(t, int64(-1), store("gems-abc", filepath.Join("vendor", "bundle"), false))
+
+	require.NoError(t, os.RemoveAll("vendor"))
+
+	t.Run("restores exact keys to the stored path", func(t *testing.T) {
+		entry, stats, err := restoreCache(ctx, memory, resolver, []string{"gems-abc"})
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		assert.Equal(t, 1, stats.FileCount)
+
+		data, err := os.ReadFile(filepath.Join("vendor", "bundle", "gems", "rake.rb"))
+		require.NoError(t, err)
+		assert.Equal(t, "rake", string(data))
+	})
+
+	t.Run("falls back to keys by prefix", func(t *testing.T) {
+		entry, _, err := restoreCache(ctx, memory, resolver, []string{"gems-def", "gems-"})
+		require.NoError(t, err)
+		require.NotNil(t, entry)
+		assert.Equal(t, "gems-abc", entry.Key)
+	})
+
+	t.Run("restores nothing for unknown keys", func(t *testing.T) {
+		entry, _, err := restoreCache(ctx, memory, resolver, []string{"node-"})
+		require.NoError(t, err)
+		assert.Nil(t, entry)
+	})
+
+	t.Run("replaces forced keys", func(t *testing.T) {
+		require.NoError(t, os.WriteFile("Gemfile.lock", []byte("lock"), 0600))
+		assert.Positive(t, store("gems-abc", "Gemfile.lock", true))
+		assert.Equal(t, []string{"artifacts/projects/1/.cache/gems-abc/Gemfile.lock.tar.gz"}, memory.Paths())
+
+		require.NoError(t, os.Remove("Gemfile.lock"))
+		_, _, err := restoreCache(ctx, memory, resolver, []string{"gems-abc"})
+		require.NoError(t, err)
+		assert.FileExists(t, "Gemfile.lock")
+	})
+}
@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/semaphoreci/artifact/pkg/pack"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewCatCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cat",
+		Short: "Writes a file from the storage to stdout",
+		Long: `Streams a file stored for a project, workflow or job to stdout, without
+writing it to the local disk, e.g.:
+
+  artifact cat workflow build.json | jq .version`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Writes a %s file to stdout.", cobra.ExactArgs(1), nil, runCatForCategory)
+	return cmd
+}
+
+func runCatForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	remotePath := resolver.PrefixedPath(files.ToRelative(args[0]))
+
+	b := getBackend()
+	err := catFile(getContext(), b, remotePath, cmd.OutOrStdout())
+	_ = b.Close()
+
+	if err != nil {
+		log.Errorf("Error reading artifact: %v\n", err)
+		errutil.Exit(1)
+	}
+}
+
+// catFile copies the file at remotePath to out. Files that are not stored
+// on their own are looked up in the packs of the directories above them.
+func catFile(ctx context.Context, b backend.Backend, remotePath string, out io.Writer) error {
+	r, err := openRemote(ctx, b, remotePath)
+	if isNotFound(err) {
+		if found, packedErr := catPacked(ctx, b, remotePath, out); found {
+			return packedErr
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if _, err := files.Copy(out, r); err != nil {
+		return fmt.Errorf("failed to read '%s': %w", remotePath, err)
+	}
+
+	return nil
+}
+
+// catPacked copies the file at remotePath to out from the pack holding it,
+// if a directory above it was pushed with --pack, checking it against its
+// checksum. It reports whether a pack holds the file.
+func catPacked(ctx context.Context, b backend.Backend, remotePath string, out io.Writer) (bool, error) {
+	for dir := path.Dir(remotePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		index := readPackIndex(ctx, b, dir)
+		if index == nil {
+			continue
+		}
+
+		relative := strings.TrimPrefix(remotePath, dir+"/")
+		p, _, ok := index.Find(relative)
+		if !ok {
+			continue
+		}
+
+		r, err := openRemote(ctx, b, pack.Path(dir, p.Name))
+		if err != nil {
+			return true, err
+		}
+		defer r.Close()
+
+		return true, pack.Read(r, p, func(entry manifest.Entry, mode os.FileMode, contents io.Reader) error {
+			if entry.Path != relative {
+				return nil
+			}
+
+			if _, err := files.Copy(out, contents); err != nil {
+				return fmt.Errorf("failed to read '%s': %w", remotePath, err)
+			}
+
+			return nil
+		})
+	}
+
+	return false, nil
+}
+
+// openRemote streams the file at remotePath from the backend. Backends that
+// cannot stream files pull it into a temporary file instead, which is
+// removed when the returned reader is closed.
+func openRemote(ctx context.Context, b backend.Backend, remotePath string) (io.ReadCloser, error) {
+	if opener, ok := b.(backend.Opener); ok && backend.CapabilitiesOf(b).Open {
+		r, err := opener.Open(ctx, remotePath)
+		if !errors.Is(err, backend.ErrOpenNotSupported) {
+			return r, err
+		}
+	}
+
+	log.Debug("Backend cannot stream this file, staging it in a temporary file...\n")
+
+	tmpDir, err := os.MkdirTemp("", "artifact-cat-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+
+	remove := removeOnQuit(tmpDir)
+
+	localPath := filepath.Join(tmpDir, "file")
+	if err := b.Pull(ctx, remotePath, localPath, backend.PullOptions{}); err != nil {
+		remove()
+		return nil, err
+	}
+
+	f, err := os.Open(localPath)
+	if err == nil {
+		var info os.FileInfo
+		if info, err = f.Stat(); err == nil && info.IsDir() {
+			f.Close()
+			err = fmt.Errorf("'%s' is a directory", remotePath)
+		}
+	}
+	if err != nil {
+		remove()
+		return nil, err
+	}
+
+	return &stagedFile{File: f, remove: remove}, nil
+}
+
+// stagedFile is a temporary copy of a remote file, removed on Close.
+type stagedFile struct {
+	*os.File
+	remove func()
+}
+
+func (s *stagedFile) Close() error {
+	err := s.File.Close()
+	s.remove()
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(NewCatCmd())
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pullOnlyBackend hides the streaming capability of a backend.
+type pullOnlyBackend struct {
+	backend.Backend
+}
+
+func Test__CatFile(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/workflows/1/build.json", []byte(`{"version":"1.2.3"}`))
+	memory.Put("artifacts/workflows/1/reports/a.xml", []byte("<a/>"))
+
+	for name, b := range map[string]backend.Backend{
+		"streamed": memory,
+		"staged":   pullOnlyBackend{memory},
+		"wrapped":  backend.NewReadOnly(pullOnlyBackend{memory}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			require.NoError(t, catFile(ctx, b, "artifacts/workflows/1/build.json", out))
+			assert.Equal(t, `{"version":"1.2.3"}`, out.String())
+
+			var notFound *backend.ErrNotFound
+			err := catFile(ctx, b, "artifacts/workflows/1/missing.json", out)
+			assert.ErrorAs(t, err, &notFound)
+		})
+	}
+
+	err := catFile(ctx, pullOnlyBackend{memory}, "artifacts/workflows/1/reports", &bytes.Buffer{})
+	assert.ErrorContains(t, err, "is a directory")
+}
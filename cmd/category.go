@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+)
+
+// category describes one of the artifact stores (job, workflow or project)
+// and the flag used to override its identifier.
+type category struct {
+	ResourceType string
+	IDFlag       string
+	IDShorthand  string
+}
+
+var categories = []category{
+	{ResourceType: files.ResourceTypeJob, IDFlag: "job-id", IDShorthand: "j"},
+	{ResourceType: files.ResourceTypeWorkflow, IDFlag: "workflow-id", IDShorthand: "w"},
+	{ResourceType: files.ResourceTypeProject, IDFlag: "project-id", IDShorthand: "p"},
+}
+
+// categoryFor returns the category of resourceType.
+func categoryFor(resourceType string) category {
+	for _, c := range categories {
+		if c.ResourceType == resourceType {
+			return c
+		}
+	}
+
+	return category{ResourceType: resourceType}
+}
+
+// categoryRunFunc runs a command for an already resolved artifact store.
+type categoryRunFunc func(cmd *cobra.Command, args []string, resolver *files.PathResolver)
+
+// newCategoryCmd builds the job, workflow or project subcommand of a command.
+// The short description may reference the category name with a single %s verb.
+func newCategoryCmd(c category, use, short string, args cobra.PositionalArgs, run categoryRunFunc) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   fmt.Sprintf("%s %s", c.ResourceType, use),
+		Short: fmt.Sprintf(short, c.ResourceType),
+		Long:  ``,
+		Args:  args,
+
+		Run: func(cmd *cobra.Command, args []string) {
+			id, err := cmd.Flags().GetString(c.IDFlag)
+			errutil.Check(err)
+
+			resolver, err := files.NewPathResolver(c.ResourceType, id)
+			errutil.Check(err)
+
+			run(cmd, args, resolver)
+		},
+	}
+
+	cmd.Flags().StringP(c.IDFlag, c.IDShorthand, "", fmt.Sprintf("set explicit %s id", c.ResourceType))
+
+	// Commands taking a local DIRECTORY complete local paths instead
+	if !strings.Contains(use, "DIRECTORY") {
+		cmd.ValidArgsFunction = completeRemotePath(c, len(strings.Fields(use)))
+	}
+
+	return cmd
+}
+
+// addCategoryCmds adds a job, workflow and project subcommand to parent.
+// The flags function is called for each subcommand to register its flags.
+func addCategoryCmds(parent *cobra.Command, use, short string, args cobra.PositionalArgs, flags func(*cobra.Command), run categoryRunFunc) {
+	for _, c := range categories {
+		cmd := newCategoryCmd(c, use, short, args, run)
+		if flags != nil {
+			flags(cmd)
+		}
+
+		parent.AddCommand(cmd)
+	}
+}
@@ -0,0 +1,171 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the listing done to complete a remote path, so
+// the shell stays responsive with slow or unreachable backends.
+const completionTimeout = 2 * time.Second
+
+// completionCacheTTL is how long a listing is reused by later completions,
+// e.g. while completing one directory after another.
+const completionCacheTTL = 30 * time.Second
+
+// completeRemotePath returns a completion function for the remote paths of
+// the category, one directory level at a time. Only the first paths
+// arguments are completed.
+func completeRemotePath(c category, paths int) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= paths {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		id, _ := cmd.Flags().GetString(c.IDFlag)
+		resolver, err := files.NewPathResolver(c.ResourceType, id)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		candidates, err := remoteCompletions(resolver, toComplete)
+		if err != nil {
+			cobra.CompDebugln(err.Error(), true)
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+
+		// Directories are completed further, so no space is added after them
+		directive := cobra.ShellCompDirectiveNoFileComp
+		for _, candidate := range candidates {
+			if strings.HasSuffix(candidate, "/") {
+				directive |= cobra.ShellCompDirectiveNoSpace
+			}
+		}
+
+		return candidates, directive
+	}
+}
+
+// remoteCompletions returns the files and directories, with a trailing
+// slash, in the remote directory of toComplete whose names start with it.
+func remoteCompletions(resolver *files.PathResolver, toComplete string) ([]string, error) {
+	dir := toComplete[:strings.LastIndex(toComplete, "/")+1]
+	remoteDir := resolver.PrefixedPath(dir)
+
+	children, ok := readCompletionCache(remoteDir)
+	if !ok {
+		b, err := backend.NewBackend()
+		if err != nil {
+			return nil, err
+		}
+		defer func() { _ = b.Close() }()
+
+		lister, err := getLister(b)
+		if err != nil {
+			return nil, err
+		}
+
+		ctx, cancel := context.WithTimeout(getContext(), completionTimeout)
+		defer cancel()
+
+		if children, err = listChildren(ctx, lister, remoteDir); err != nil {
+			return nil, err
+		}
+
+		writeCompletionCache(remoteDir, children)
+	}
+
+	candidates := []string{}
+	for _, child := range children {
+		if strings.HasPrefix(dir+child, toComplete) {
+			candidates = append(candidates, dir+child)
+		}
+	}
+
+	return candidates, nil
+}
+
+// listChildren returns the names of the files and directories, with a
+// trailing slash, directly under remoteDir.
+func listChildren(ctx context.Context, lister backend.Lister, remoteDir string) ([]string, error) {
+	seen := map[string]bool{}
+	err := walkRemote(ctx, lister, remoteDir, func(obj backend.ObjectInfo) error {
+		name := relativeName(obj.Path, remoteDir)
+		if i := strings.Index(name, "/"); i >= 0 {
+			name = name[:i+1]
+		}
+
+		if name != "" {
+			seen[name] = true
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	children := []string{}
+	for name := range seen {
+		children = append(children, name)
+	}
+	sort.Strings(children)
+
+	return children, nil
+}
+
+// completionCachePath returns the file the listing of remoteDir is cached
+// in. Listings of different backends are kept apart.
+func completionCachePath(remoteDir string) string {
+	sum := sha256.Sum256([]byte(backend.GetBackendSetting() + "\n" + remoteDir))
+	return filepath.Join(userCacheDir(), "completion", hex.EncodeToString(sum[:])+".json")
+}
+
+func readCompletionCache(remoteDir string) ([]string, bool) {
+	path := completionCachePath(remoteDir)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > completionCacheTTL {
+		return nil, false
+	}
+
+	// #nosec
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+
+	children := []string{}
+	if err := json.Unmarshal(data, &children); err != nil {
+		return nil, false
+	}
+
+	return children, true
+}
+
+// writeCompletionCache caches a listing. Failing to cache it only makes
+// the next completion slower, so errors are ignored.
+func writeCompletionCache(remoteDir string, children []string) {
+	path := completionCachePath(remoteDir)
+	data, err := json.Marshal(children)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return
+	}
+
+	_ = os.WriteFile(path, data, 0600)
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__ListChildren(t *testing.T) {
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/reports/unit/junit.xml", []byte("1"))
+	memory.Put("artifacts/jobs/1/reports/coverage.html", []byte("2"))
+	memory.Put("artifacts/jobs/1/app.zip", []byte("3"))
+	memory.Put("artifacts/jobs/10/other.txt", []byte("4"))
+
+	children, err := listChildren(context.Background(), memory, "artifacts/jobs/1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"app.zip", "reports/"}, children)
+
+	children, err = listChildren(context.Background(), memory, "artifacts/jobs/1/reports")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"coverage.html", "unit/"}, children)
+}
+
+func Test__CompleteRemotePath(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/reports/junit.xml", Contents: "1"},
+		{Name: "artifacts/jobs/1/release.zip", Contents: "2"},
+		{Name: "artifacts/jobs/1/app.zip", Contents: "3"},
+	})
+	require.NoError(t, err)
+
+	complete := func(args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		cmd := NewPullJobCmd()
+		return cmd.ValidArgsFunction(cmd, args, toComplete)
+	}
+
+	candidates, directive := complete(nil, "re")
+	assert.Equal(t, []string{"release.zip", "reports/"}, candidates)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp|cobra.ShellCompDirectiveNoSpace, directive)
+
+	candidates, directive = complete(nil, "reports/")
+	assert.Equal(t, []string{"reports/junit.xml"}, candidates)
+	assert.Equal(t, cobra.ShellCompDirectiveNoFileComp, directive)
+
+	// Only one path is completed
+	candidates, _ = complete([]string{"app.zip"}, "")
+	assert.Empty(t, candidates)
+
+	// Listings are cached for a while
+	require.NoError(t, s3Server.PutFiles([]testsupport.FileMock{{Name: "artifacts/jobs/1/reports/new.xml", Contents: "4"}}))
+	candidates, _ = complete(nil, "reports/")
+	assert.Equal(t, []string{"reports/junit.xml"}, candidates)
+
+	resolver, err := files.NewPathResolver(files.ResourceTypeJob, "1")
+	require.NoError(t, err)
+	writeCompletionCache(resolver.PrefixedPath("reports"), []string{"junit.xml", "new.xml"})
+	candidates, _ = complete(nil, "reports/n")
+	assert.Equal(t, []string{"reports/new.xml"}, candidates)
+}
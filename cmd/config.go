@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/config"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+func NewConfigCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Manages the config file",
+		Long: `Creates and edits the config file ($HOME/.artifact.yaml, or the one given
+with --config). Keys are dot-separated paths into its sections, e.g.
+s3.bucket or profiles.prod.s3.bucket. Unknown keys are rejected, since
+they would be silently ignored.`,
+	}
+
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Creates the config file, e.g. 'config init --backend s3'.",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			backendSetting, _ := cmd.Flags().GetString("backend")
+			force, _ := cmd.Flags().GetBool("force")
+			errutil.Check(initConfigFile(cmd.OutOrStdout(), backendSetting, force))
+		},
+	}
+	initCmd.Flags().String("backend", string(backend.BackendTypeHub), "backend to configure, e.g. s3")
+	initCmd.Flags().BoolP("force", "f", false, "overwrite an existing config file")
+	cmd.AddCommand(initCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "get [KEY]",
+		Short: "Prints a value of the config file, e.g. 'config get s3.bucket'.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(getConfigValue(cmd.OutOrStdout(), args[0]))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "set [KEY] [VALUE]",
+		Short: "Sets a value in the config file, e.g. 'config set s3.bucket my-bucket'.",
+		Args:  cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(setConfigValue(cmd.OutOrStdout(), args[0], args[1]))
+		},
+	})
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "unset [KEY]",
+		Aliases: []string{"rm"},
+		Short:   "Removes a value from the config file.",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(unsetConfigValue(cmd.OutOrStdout(), args[0]))
+		},
+	})
+
+	listCmd := &cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Lists the values in the config file, or every setting with --all.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if all, _ := cmd.Flags().GetBool("all"); all {
+				listSettings(cmd.OutOrStdout())
+				return
+			}
+
+			errutil.Check(listConfigValues(cmd.OutOrStdout()))
+		},
+	}
+	listCmd.Flags().Bool("all", false, "describe every setting instead of listing the config file")
+	cmd.AddCommand(listCmd)
+
+	return cmd
+}
+
+func initConfigFile(out io.Writer, backendSetting string, force bool) error {
+	if _, _, ok := backend.ParseBackendSetting(backendSetting); !ok {
+		return fmt.Errorf("invalid backend '%s'", backendSetting)
+	}
+
+	path, err := config.Path()
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(path); err == nil && !force {
+		return fmt.Errorf("config file '%s' already exists; use --force to overwrite it", path)
+	}
+
+	if err := os.WriteFile(path, config.Template(backendSetting), 0600); err != nil {
+		return fmt.Errorf("failed to write config file '%s': %v", path, err)
+	}
+
+	fmt.Fprintf(out, "Created config file '%s' for the %s backend.\n", path, backendSetting)
+	return nil
+}
+
+func getConfigValue(out io.Writer, key string) error {
+	setting, err := config.LookupSetting(key)
+	if err != nil {
+		return err
+	}
+
+	f, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	value, ok := f.Get(setting.Key)
+	if !ok {
+		return fmt.Errorf("'%s' is not set in '%s'", setting.Key, f.Path)
+	}
+
+	formatted, err := formatConfigValue(value)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(out, formatted)
+	return nil
+}
+
+func setConfigValue(out io.Writer, key, rawValue string) error {
+	setting, err := config.LookupSetting(key)
+	if err != nil {
+		return err
+	}
+
+	value, err := setting.ParseValue(rawValue)
+	if err != nil {
+		return err
+	}
+
+	if setting.Key == "backend" || strings.HasSuffix(setting.Key, ".backend") {
+		if _, _, ok := backend.ParseBackendSetting(rawValue); !ok {
+			return fmt.Errorf("invalid backend '%s'", rawValue)
+		}
+	}
+
+	f, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	f.Set(setting.Key, value)
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Set '%s' to '%s' in '%s'.\n", setting.Key, rawValue, f.Path)
+	return nil
+}
+
+func unsetConfigValue(out io.Writer, key string) error {
+	setting, err := config.LookupSetting(key)
+	if err != nil {
+		return err
+	}
+
+	f, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	if !f.Unset(setting.Key) {
+		return fmt.Errorf("'%s' is not set in '%s'", setting.Key, f.Path)
+	}
+
+	if err := f.Save(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(out, "Removed '%s' from '%s'.\n", setting.Key, f.Path)
+	return nil
+}
+
+// listConfigValues prints every value in the config file as KEY=VALUE,
+// and warns about the keys that are not read.
+func listConfigValues(out io.Writer) error {
+	f, err := config.LoadDefault()
+	if err != nil {
+		return err
+	}
+
+	for _, key := range f.Keys() {
+		value, _ := f.Get(key)
+		formatted, err := formatConfigValue(value)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(out, "%s=%s\n", key, formatted)
+	}
+
+	for _, err := range f.Validate() {
+		log.Warnf("%v\n", err)
+	}
+
+	return nil
+}
+
+func listSettings(out io.Writer) {
+	for _, setting := range config.Settings {
+		fmt.Fprintf(out, "%-28s %-8s %s\n", setting.Key, setting.Kind, setting.Description)
+	}
+}
+
+// formatConfigValue prints lists as comma-separated values, like they are
+// set, and sections in YAML.
+func formatConfigValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case []interface{}:
+		values := []string{}
+		for _, item := range v {
+			if _, ok := item.(map[string]interface{}); ok {
+				return formatConfigSection(value)
+			}
+			values = append(values, fmt.Sprint(item))
+		}
+		return strings.Join(values, ","), nil
+	case map[string]interface{}:
+		return formatConfigSection(value)
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
+
+func formatConfigSection(value interface{}) (string, error) {
+	data, err := yaml.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// warnAboutUnknownSettings warns about the keys in the config file in use
+// that are not read, e.g. because of typos, which viper ignores silently.
+func warnAboutUnknownSettings() {
+	path := viper.ConfigFileUsed()
+	if path == "" {
+		return
+	}
+
+	f, err := config.Load(path)
+	if err != nil {
+		return
+	}
+
+	for _, err := range f.Validate() {
+		log.Warnf("Config file '%s': %v\n", path, err)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(NewConfigCmd())
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Config(t *testing.T) {
+	// Commands read the config file given with --config when they start
+	configFile := filepath.Join(t.TempDir(), ".artifact.yaml")
+	cfgFile = configFile
+	defer func() {
+		cfgFile = ""
+		viper.Reset()
+	}()
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewConfigCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("init creates the config file", func(t *testing.T) {
+		output := run("init", "--backend", "s3")
+		assert.Contains(t, output, "Created config file")
+
+		data, err := os.ReadFile(configFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "backend: s3\n# s3:\n#   bucket:")
+
+		require.NoError(t, viper.ReadInConfig())
+		assert.Equal(t, "s3", viper.GetString("backend"))
+	})
+
+	t.Run("init does not overwrite the config file", func(t *testing.T) {
+		output := run("init", "--backend", "http")
+		assert.Empty(t, output)
+		assert.Equal(t, "s3\n", run("get", "backend"))
+	})
+
+	t.Run("set stores typed values", func(t *testing.T) {
+		output := run("set", "s3.bucket", "my-bucket")
+		assert.Contains(t, output, "Set 's3.bucket' to 'my-bucket'")
+
+		run("set", "S3.ForcePathStyle", "true")
+		run("set", "mirror.backends", "hub,s3")
+		run("set", "profiles.prod.s3.bucket", "prod-bucket")
+		require.NoError(t, viper.ReadInConfig())
+
+		assert.Equal(t, "my-bucket", viper.GetString("s3.bucket"))
+		assert.True(t, viper.GetBool("s3.forcePathStyle"))
+		assert.Equal(t, []string{"hub", "s3"}, viper.GetStringSlice("mirror.backends"))
+		assert.Equal(t, "hub,s3\n", run("get", "mirror.backends"))
+	})
+
+	t.Run("set rejects unknown keys and invalid values", func(t *testing.T) {
+		assert.Empty(t, run("set", "s3.bukcet", "typo"))
+		assert.Empty(t, run("set", "s3.forcePathStyle", "sometimes"))
+		assert.Empty(t, run("set", "backend", "dropbox"))
+		assert.Empty(t, run("set", "policy", "deny"))
+		assert.Empty(t, run("get", "s3.bukcet"))
+	})
+
+	t.Run("list prints the config file", func(t *testing.T) {
+		assert.Equal(t, `backend=s3
+mirror.backends=hub,s3
+profiles.prod.s3.bucket=prod-bucket
+s3.bucket=my-bucket
+s3.forcePathStyle=true
+`, run("list"))
+
+		assert.Contains(t, run("list", "--all"), "s3.objectLockMode")
+	})
+
+	t.Run("unset removes the value", func(t *testing.T) {
+		output := run("unset", "s3.bucket")
+		assert.Contains(t, output, "Removed 's3.bucket'")
+		assert.Empty(t, run("get", "s3.bucket"))
+	})
+}
@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"filippo.io/age"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/encrypt"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// errInterrupted is the cause of operations cancelled by a signal.
+var errInterrupted = errors.New("interrupted")
+
+var (
+	operationMu   sync.Mutex
+	operationCtx  context.Context
+	stopOperation context.CancelFunc
+	timeout       time.Duration
+)
+
+// getContext returns the context of backend operations. It is cancelled
+// on SIGINT or SIGTERM, so transfers stop and partial multipart uploads
+// are aborted, and once the --timeout of the command is over. The signals
+// are only caught once a command asks for the context; a second one quits
+// at once, removing the temporary files of removeOnQuit. It carries the
+// keys encrypted files are decrypted with, and the stats of the transfers
+// of the command.
+func getContext() context.Context {
+	operationMu.Lock()
+	defer operationMu.Unlock()
+
+	if operationCtx == nil {
+		operationCtx, stopOperation = newOperationContext(timeout)
+		operationCtx = encrypt.NewContext(operationCtx, getIdentities())
+		operationCtx = backend.WithTransferStats(operationCtx, backend.NewTransferStats())
+	}
+
+	return operationCtx
+}
+
+// resetContext discards the context of the previous command, e.g. when
+// commands are run one after another in tests, and sets the timeout of
+// the next one.
+func resetContext(next time.Duration) {
+	operationMu.Lock()
+	defer operationMu.Unlock()
+
+	if stopOperation != nil {
+		stopOperation()
+	}
+
+	operationCtx, stopOperation, timeout = nil, nil, next
+}
+
+func newOperationContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+
+	stopped := make(chan struct{})
+	stopSignals := sync.OnceFunc(func() { close(stopped) })
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		defer signal.Stop(signals)
+
+		select {
+		case sig := <-signals:
+			log.Warnf("Received %s, cancelling; send it again to quit at once.\n", sig)
+			cancel(errInterrupted)
+		case <-stopped:
+			return
+		}
+
+		select {
+		case sig := <-signals:
+			log.Warnf("Received %s again, quitting.\n", sig)
+			quit()
+		case <-stopped:
+		}
+	}()
+
+	if timeout <= 0 {
+		return ctx, func() {
+			stopSignals()
+			cancel(nil)
+		}
+	}
+
+	timeoutCtx, cancelTimeout := context.WithTimeoutCause(ctx, timeout, fmt.Errorf("timed out after %s", timeout))
+	stopAfter := context.AfterFunc(timeoutCtx, func() {
+		if errors.Is(timeoutCtx.Err(), context.DeadlineExceeded) {
+			log.Errorf("Timed out after %s, cancelling.\n", timeout)
+		}
+	})
+
+	return timeoutCtx, func() {
+		stopSignals()
+		stopAfter()
+		cancelTimeout()
+		cancel(nil)
+	}
+}
+
+var (
+	tempMu    sync.Mutex
+	tempPaths = map[string]bool{}
+)
+
+// removeOnQuit records the temporary file or directory at path, so it is
+// removed even if the command is quit at once by a second signal, when
+// deferred calls do not run. It returns the function removing it, to defer.
+func removeOnQuit(path string) func() {
+	tempMu.Lock()
+	tempPaths[path] = true
+	tempMu.Unlock()
+
+	return func() {
+		tempMu.Lock()
+		delete(tempPaths, path)
+		tempMu.Unlock()
+
+		_ = os.RemoveAll(path)
+	}
+}
+
+// quit removes the temporary files recorded with removeOnQuit, and exits
+// as a process killed by SIGINT would.
+func quit() {
+	tempMu.Lock()
+	for path := range tempPaths {
+		_ = os.RemoveAll(path)
+	}
+	tempMu.Unlock()
+
+	errutil.Exit(130)
+}
+
+// logResumeHint tells how to finish a push or pull that failed because it
+// was cancelled by a signal or --timeout.
+func logResumeHint(verb string) {
+	if getContext().Err() == nil {
+		return
+	}
+
+	switch verb {
+	case "push":
+		log.Info("The push was interrupted: run the same command again to resume it, skipping the files it already uploaded.\n")
+	case "pull":
+		log.Info("The pull was interrupted: run the same command again with --force to finish it, resuming the partially downloaded files.\n")
+	}
+}
+
+func addTimeoutFlag(cmd *cobra.Command) {
+	cmd.PersistentFlags().Duration("timeout", 0, "cancel the operation after this long, e.g. 30m (default is $ARTIFACT_TIMEOUT, or none)")
+}
+
+// getTimeout returns the total time the command may take, from --timeout
+// or the ARTIFACT_TIMEOUT env var, or zero for no limit.
+func getTimeout(cmd *cobra.Command) time.Duration {
+	if cmd.Flags().Changed("timeout") {
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		return timeout
+	}
+
+	value := os.Getenv("ARTIFACT_TIMEOUT")
+	if value == "" {
+		return 0
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("Ignoring invalid ARTIFACT_TIMEOUT '%s': %v\n", value, err)
+		return 0
+	}
+
+	return timeout
+}
+
+// getIdentities returns the keys of the environment encrypted files are
+// decrypted with. Invalid ones are ignored: pulling files in the clear
+// does not need them.
+func getIdentities() []age.Identity {
+	identities, err := encrypt.IdentitiesFromEnv()
+	if err != nil {
+		log.Warnf("Ignoring encryption keys: %v\n", err)
+	}
+
+	return identities
+}
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__getContext(t *testing.T) {
+	t.Run("no timeout by default", func(t *testing.T) {
+		resetContext(0)
+		defer resetContext(0)
+
+		_, hasDeadline := getContext().Deadline()
+		assert.False(t, hasDeadline)
+		assert.Same(t, getContext(), getContext())
+	})
+
+	t.Run("cancelled after the timeout", func(t *testing.T) {
+		resetContext(20 * time.Millisecond)
+		defer resetContext(0)
+
+		ctx := getContext()
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("context was not cancelled")
+		}
+
+		assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+		assert.EqualError(t, context.Cause(ctx), "timed out after 20ms")
+	})
+
+	t.Run("cancelled on interrupt", func(t *testing.T) {
+		resetContext(0)
+		defer resetContext(0)
+
+		ctx := getContext()
+		process, _ := os.FindProcess(os.Getpid())
+		if err := process.Signal(os.Interrupt); err != nil {
+			t.Skipf("cannot interrupt the test process: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Fatal("context was not cancelled")
+		}
+
+		assert.True(t, errors.Is(context.Cause(ctx), errInterrupted))
+	})
+
+	t.Run("a second interrupt removes the temporary files", func(t *testing.T) {
+		resetContext(0)
+		defer resetContext(0)
+
+		tmpDir := t.TempDir()
+		staged := filepath.Join(tmpDir, "staged")
+		require.NoError(t, os.WriteFile(staged, []byte("staged"), 0644))
+		defer removeOnQuit(staged)()
+
+		ctx := getContext()
+		process, _ := os.FindProcess(os.Getpid())
+		if err := process.Signal(os.Interrupt); err != nil {
+			t.Skipf("cannot interrupt the test process: %v", err)
+		}
+		<-ctx.Done()
+		assert.FileExists(t, staged)
+
+		require.NoError(t, process.Signal(os.Interrupt))
+		assert.Eventually(t, func() bool {
+			_, err := os.Stat(staged)
+			return os.IsNotExist(err)
+		}, time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("reset starts a new context", func(t *testing.T) {
+		resetContext(0)
+		ctx := getContext()
+		resetContext(0)
+
+		assert.Error(t, ctx.Err())
+		assert.NoError(t, getContext().Err())
+	})
+}
+
+func Test__getTimeout(t *testing.T) {
+	cmd := NewPushJobCmd()
+	addTimeoutFlag(cmd)
+	assert.Zero(t, getTimeout(cmd))
+
+	t.Setenv("ARTIFACT_TIMEOUT", "30m")
+	assert.Equal(t, 30*time.Minute, getTimeout(cmd))
+
+	cmd.ParseFlags([]string{"--timeout", "5s"})
+	assert.Equal(t, 5*time.Second, getTimeout(cmd))
+
+	t.Setenv("ARTIFACT_TIMEOUT", "soon")
+	assert.Zero(t, getTimeout(NewPushJobCmd()))
+}
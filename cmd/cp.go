@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewCpCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "cp",
+		Short: "Copies a file or directory to another store",
+		Long: `Copies a file or directory stored for a job, workflow or project to the
+store given with --to, e.g. to promote a release binary built in a job:
+
+  artifact cp job dist/app.zip --to project --destination releases/v1.2.3/app.zip
+
+Backends that can copy files in place, such as S3, copy them without
+downloading them; others download and upload them again.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Copies a %s file or directory to another store.", cobra.ExactArgs(1), addCpFlags, runCpForCategory)
+	return cmd
+}
+
+func addCpFlags(cmd *cobra.Command) {
+	cmd.Flags().String("to", "", "store to copy to: job, workflow or project")
+	cmd.Flags().String("to-id", "", "set explicit id of the store to copy to")
+	cmd.Flags().StringP("destination", "d", "", "path in the store to copy to; defaults to PATH")
+	cmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	_ = cmd.MarkFlagRequired("to")
+}
+
+func runCpForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	to, _ := cmd.Flags().GetString("to")
+	toID, _ := cmd.Flags().GetString("to-id")
+	destination, _ := cmd.Flags().GetString("destination")
+	force, _ := cmd.Flags().GetBool("force")
+
+	target, err := files.NewPathResolver(to, toID)
+	errutil.Check(err)
+
+	name := files.ToRelative(args[0])
+	if destination == "" {
+		destination = name
+	}
+
+	srcPath := resolver.PrefixedPath(name)
+	dstPath := target.PrefixedPath(files.ToRelative(destination))
+	if srcPath == dstPath {
+		errutil.Check(fmt.Errorf("'%s' cannot be copied onto itself", srcPath))
+		return
+	}
+
+	errutil.Check(checkPolicy(policyRequest(policy.OperationPush, target, dstPath)))
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	if !backend.CapabilitiesOf(b).Copy {
+		log.Infof("The %s backend cannot copy files in place, downloading and uploading '%s' again...\n", backend.GetBackendSetting(), srcPath)
+	}
+
+	err = backend.Copy(getContext(), b, srcPath, dstPath, backend.PushOptions{Force: force})
+	if err != nil {
+		log.Errorf("Error copying artifact: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Successfully copied artifact to the %s store.\n", target.ResourceType)
+	log.Infof("* Source: %s.\n", srcPath)
+	log.Infof("* Destination: %s.\n", dstPath)
+}
+
+func init() {
+	rootCmd.AddCommand(NewCpCmd())
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Cp(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv("SEMAPHORE_WORKFLOW_ID", "2")
+	t.Setenv("SEMAPHORE_PROJECT_ID", "3")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/dist/app.zip", Contents: "app"},
+		{Name: "artifacts/jobs/1/dist/bin/app", Contents: "binary"},
+	})
+	require.NoError(t, err)
+
+	exists := func(remotePath string) bool {
+		b := getBackend()
+		defer b.Close()
+
+		ok, err := b.Exists(context.Background(), remotePath)
+		require.NoError(t, err)
+		return ok
+	}
+
+	run := func(args ...string) {
+		cmd := NewCpCmd()
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+	}
+
+	t.Run("copies a file to the project store", func(t *testing.T) {
+		run("dist/app.zip", "--to", "project", "--destination", "releases/v1/app.zip")
+		assert.True(t, exists("artifacts/projects/3/releases/v1/app.zip"))
+	})
+
+	t.Run("copies a directory under the same path", func(t *testing.T) {
+		run("dist", "--to", "workflow")
+		assert.True(t, exists("artifacts/workflows/2/dist/app.zip"))
+		assert.True(t, exists("artifacts/workflows/2/dist/bin/app"))
+	})
+
+	t.Run("copies to an explicit store id", func(t *testing.T) {
+		run("dist/bin/app", "--to", "job", "--to-id", "4")
+		assert.True(t, exists("artifacts/jobs/4/dist/bin/app"))
+	})
+}
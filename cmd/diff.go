@@ -0,0 +1,107 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the diff command, following diff(1).
+const (
+	diffExitSame      = 0
+	diffExitDifferent = 1
+	diffExitError     = 2
+)
+
+func NewDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "diff",
+		Short: "Compares a local directory with its copy in the storage",
+		Long: `Compares a local directory with a directory stored for a project, workflow
+or job, and prints the files that were added locally (A), changed (M) or
+removed locally (D). Files are compared like sync does: by size, then by
+ETag or stored checksum. Exits with status 0 if the directories are the
+same, 1 if they differ, and 2 if the comparison failed.`,
+	}
+
+	addCategoryCmds(cmd, "DIRECTORY", "Compares a local directory with the %s storage.", cobra.ExactArgs(1), addDiffFlags, runDiffForCategory)
+	return cmd
+}
+
+func addDiffFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("destination", "d", "", "remote directory to compare with; defaults to the name of DIRECTORY")
+}
+
+func runDiffForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	destination, _ := cmd.Flags().GetString("destination")
+
+	info, err := os.Stat(args[0])
+	if err == nil && !info.IsDir() {
+		err = fmt.Errorf("'%s' is not a directory", args[0])
+	}
+	if err != nil {
+		log.Errorf("Error comparing '%s': %v\n", args[0], err)
+		errutil.Exit(diffExitError)
+		return
+	}
+
+	paths := resolver.Push(args[0], destination)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	var plan *syncPlan
+	lister, err := getLister(b)
+	if err == nil {
+		plan, err = planSync(getContext(), b, lister, paths.Source, paths.Destination, true)
+	}
+	if err == nil {
+		err = writeDiff(cmd.OutOrStdout(), plan, resolver.PrefixedPath(""))
+	}
+
+	if err != nil {
+		log.Errorf("Error comparing '%s' with the storage: %v\n", paths.Source, err)
+		errutil.Exit(diffExitError)
+		return
+	}
+
+	if len(plan.Upload) > 0 || len(plan.Delete) > 0 {
+		log.Infof("'%s' differs from %s.\n", paths.Source, paths.Destination)
+		errutil.Exit(diffExitDifferent)
+		return
+	}
+
+	log.Infof("'%s' matches %s.\n", paths.Source, paths.Destination)
+}
+
+// writeDiff prints the differences in a sync plan, one file per line,
+// with paths relative to root.
+func writeDiff(out io.Writer, plan *syncPlan, root string) error {
+	for _, upload := range plan.Upload {
+		status := "A"
+		if upload.Stored {
+			status = "M"
+		}
+
+		if _, err := fmt.Fprintf(out, "%s  %s\n", status, relativeName(upload.RemotePath, root)); err != nil {
+			return err
+		}
+	}
+
+	for _, remotePath := range plan.Delete {
+		if _, err := fmt.Fprintf(out, "D  %s\n", relativeName(remotePath, root)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewDiffCmd())
+}
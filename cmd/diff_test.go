@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Diff(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	bundle := filepath.Join(t.TempDir(), "bundle")
+	require.NoError(t, os.MkdirAll(bundle, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(bundle, "app.js"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(bundle, "index.html"), []byte("index"), 0644))
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/bundle/app.js", Contents: "app"},
+		{Name: "artifacts/jobs/1/bundle/index.html", Contents: "old index"},
+		{Name: "artifacts/jobs/1/bundle/removed.css", Contents: "removed"},
+		{Name: "artifacts/jobs/1/deploy/app.js", Contents: "app"},
+		{Name: "artifacts/jobs/1/deploy/index.html", Contents: "index"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewDiffCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job", bundle}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	assert.Equal(t, "M  bundle/index.html\nD  bundle/removed.css\n", run())
+	assert.Equal(t, "", run("--destination", "deploy"))
+
+	require.NoError(t, os.WriteFile(filepath.Join(bundle, "new.js"), []byte("new"), 0644))
+	assert.Equal(t, "A  deploy/new.js\n", run("--destination", "deploy"))
+}
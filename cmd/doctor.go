@@ -0,0 +1,241 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/config"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+const (
+	// doctorTimeout bounds the connectivity check, so that an unreachable
+	// endpoint is reported instead of hanging.
+	doctorTimeout = 30 * time.Second
+
+	// Signed URLs and request signatures are rejected by most storages
+	// once the clocks drift apart by 15 minutes.
+	clockSkewWarning = time.Minute
+	clockSkewFailure = 15 * time.Minute
+)
+
+func NewDoctorCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Diagnoses the configuration and the connection to the storage",
+		Long: `Checks the environment variables, the config file and profile in use,
+the backend setting, the connection to the storage with its credentials
+and the clock of this machine, and prints how to fix every problem found.
+Exits with 1 if a check fails.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !runDoctor(getContext(), cmd.OutOrStdout()) {
+				errutil.Exit(1)
+			}
+		},
+	}
+}
+
+// doctorReport prints the outcome of checks and counts the failed ones.
+type doctorReport struct {
+	out      io.Writer
+	failures int
+	warnings int
+}
+
+func (d *doctorReport) ok(format string, a ...interface{}) {
+	fmt.Fprintf(d.out, "[ ok ] %s\n", fmt.Sprintf(format, a...))
+}
+
+func (d *doctorReport) warn(remedy, format string, a ...interface{}) {
+	d.warnings++
+	d.print("warn", remedy, fmt.Sprintf(format, a...))
+}
+
+func (d *doctorReport) fail(remedy, format string, a ...interface{}) {
+	d.failures++
+	d.print("FAIL", remedy, fmt.Sprintf(format, a...))
+}
+
+func (d *doctorReport) print(status, remedy, message string) {
+	fmt.Fprintf(d.out, "[%s] %s\n", status, message)
+	if remedy != "" {
+		fmt.Fprintf(d.out, "       -> %s\n", remedy)
+	}
+}
+
+// runDoctor runs every check and reports if none failed.
+func runDoctor(ctx context.Context, out io.Writer) bool {
+	d := &doctorReport{out: out}
+
+	checkEnvironment(d)
+	checkConfigFile(d)
+	checkBackendSetting(d)
+	checkConnectivity(ctx, d)
+
+	fmt.Fprintf(out, "\n%d %s failed, %d %s.\n", d.failures, pluralize(d.failures, "check", "checks"), d.warnings, pluralize(d.warnings, "warning", "warnings"))
+	return d.failures == 0
+}
+
+// checkEnvironment reports the IDs the stores are resolved with, and the
+// credentials of the hub backend, which Semaphore sets in every job.
+func checkEnvironment(d *doctorReport) {
+	ids := []struct{ env, flag string }{
+		{"SEMAPHORE_JOB_ID", "--job-id"},
+		{"SEMAPHORE_WORKFLOW_ID", "--workflow-id"},
+		{"SEMAPHORE_PROJECT_ID", "--project-id"},
+	}
+
+	for _, id := range ids {
+		if value := os.Getenv(id.env); value != "" {
+			d.ok("%s is '%s'", id.env, value)
+		} else {
+			d.warn(fmt.Sprintf("set %s, or pass %s to every command", id.env, id.flag), "%s is not set", id.env)
+		}
+	}
+
+	if backend.GetBackendType() != backend.BackendTypeHub {
+		return
+	}
+
+	for _, env := range []string{"SEMAPHORE_ARTIFACT_TOKEN", "SEMAPHORE_ORGANIZATION_URL"} {
+		if os.Getenv(env) != "" {
+			d.ok("%s is set", env)
+		} else {
+			d.fail(fmt.Sprintf("the hub backend only works inside Semaphore jobs, which set %s; use another backend elsewhere, e.g. 'artifact config init --backend s3'", env), "%s is not set", env)
+		}
+	}
+}
+
+// checkConfigFile reports the config file in use, keys in it that are not
+// read, and the active profile.
+func checkConfigFile(d *doctorReport) {
+	path := viper.ConfigFileUsed()
+	if path == "" && cfgFile != "" {
+		path = cfgFile
+	}
+
+	if path == "" {
+		d.ok("No config file found, using environment variables and defaults")
+	} else if _, err := os.Stat(path); err != nil {
+		d.fail("create it with 'artifact config init', or fix the --config flag", "Config file '%s' cannot be read: %v", path, err)
+	} else if f, err := config.Load(path); err != nil {
+		d.fail("fix the YAML syntax of the file", "Config file '%s' is not valid: %v", path, err)
+	} else {
+		d.ok("Using config file '%s'", path)
+		for _, err := range f.Validate() {
+			d.warn("fix or remove the key, it is ignored; 'artifact config list --all' describes every setting", "Config file '%s': %v", path, err)
+		}
+	}
+
+	if name := config.ActiveProfile(profile); name != "" {
+		d.ok("Using profile '%s'", name)
+	}
+}
+
+// checkBackendSetting reports the backend in use and where it is set.
+// Invalid settings are skipped by GetBackendSetting, so they are reported
+// here instead of being noticed when the wrong backend is used.
+func checkBackendSetting(d *doctorReport) {
+	if env := os.Getenv("ARTIFACT_BACKEND"); env != "" {
+		if _, _, ok := backend.ParseBackendSetting(env); !ok {
+			d.warn("set ARTIFACT_BACKEND to a valid backend, or unset it", "ARTIFACT_BACKEND '%s' is not a valid backend and is ignored", env)
+		}
+	}
+
+	if setting := viper.GetString("backend"); setting != "" && os.Getenv("ARTIFACT_BACKEND") != setting {
+		if _, _, ok := backend.ParseBackendSetting(setting); !ok {
+			d.warn("fix it with 'artifact config set backend NAME'", "Backend '%s' in the config file is not valid and is ignored", setting)
+		}
+	}
+
+	d.ok("Using the %s backend", backend.GetBackendSetting())
+}
+
+// checkConnectivity reaches the storage with the configured credentials,
+// reports the capabilities of the backend, and compares the clock of the
+// server with the local one.
+func checkConnectivity(ctx context.Context, d *doctorReport) {
+	backendType := backend.GetBackendType()
+
+	b, err := backend.NewBackend()
+	if err != nil {
+		d.fail(backendRemedy(backendType), "Backend cannot be created: %v", err)
+		return
+	}
+	defer func() { _ = b.Close() }()
+
+	ctx, cancel := context.WithTimeout(ctx, doctorTimeout)
+	defer cancel()
+
+	start := time.Now()
+	serverTime, err := backend.Check(ctx, b, doctorProbePath())
+	if err != nil {
+		d.fail(backendRemedy(backendType), "Storage cannot be reached: %v", err)
+		// Rejected signatures may be caused by the clock, so it is still checked
+	} else {
+		d.ok("Storage reached in %s", time.Since(start).Round(time.Millisecond))
+	}
+
+	d.ok("Backend capabilities: %s", backend.CapabilitiesOf(b))
+
+	checkClockSkew(d, serverTime, start)
+}
+
+// checkClockSkew compares serverTime, as of when the request was sent,
+// with the local clock. Backends that do not report it are skipped.
+func checkClockSkew(d *doctorReport, serverTime, local time.Time) {
+	if serverTime.IsZero() {
+		return
+	}
+
+	// The Date header has a resolution of a second
+	skew := local.Sub(serverTime).Round(time.Second)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	remedy := "synchronize the clock of this machine, e.g. with NTP; signed URLs and requests are rejected by the storage otherwise"
+	switch {
+	case skew < clockSkewWarning:
+		d.ok("Clock is in sync with the storage")
+	case skew < clockSkewFailure:
+		d.warn(remedy, "Clock is %s off the storage's", skew)
+	default:
+		d.fail(remedy, "Clock is %s off the storage's", skew)
+	}
+}
+
+// doctorProbePath is the path looked up to check the connection, inside a
+// store the environment points to, since tokens may be scoped to it.
+func doctorProbePath() string {
+	for _, resourceType := range []string{files.ResourceTypeJob, files.ResourceTypeWorkflow, files.ResourceTypeProject} {
+		if resolver, err := files.NewPathResolver(resourceType, ""); err == nil {
+			return resolver.PrefixedPath(".artifact-doctor")
+		}
+	}
+
+	return "artifacts/.artifact-doctor"
+}
+
+func backendRemedy(backendType backend.BackendType) string {
+	switch backendType {
+	case backend.BackendTypeHub:
+		return "check SEMAPHORE_ARTIFACT_TOKEN and SEMAPHORE_ORGANIZATION_URL; the token is only valid while its job runs"
+	case backend.BackendTypeS3:
+		return "check s3.bucket, s3.region and s3.endpoint with 'artifact config list', and the AWS credentials, e.g. AWS_ACCESS_KEY_ID or AWS_PROFILE"
+	default:
+		return fmt.Sprintf("check the %s settings with 'artifact config list' and the credentials of the backend", backendType)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(NewDoctorCmd())
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Doctor(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv("SEMAPHORE_WORKFLOW_ID", "")
+	t.Setenv("SEMAPHORE_PROJECT_ID", "1")
+
+	configFile := filepath.Join(t.TempDir(), ".artifact.yaml")
+	require.NoError(t, os.WriteFile(configFile, []byte("backend: s3\n"), 0600))
+	cfgFile = configFile
+	defer func() {
+		cfgFile = ""
+		viper.Reset()
+	}()
+
+	run := func() string {
+		out := &bytes.Buffer{}
+		cmd := NewDoctorCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{})
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("reports a working setup", func(t *testing.T) {
+		output := run()
+		assert.Contains(t, output, "[ ok ] SEMAPHORE_JOB_ID is '1'\n")
+		assert.Contains(t, output, "[warn] SEMAPHORE_WORKFLOW_ID is not set\n       -> set SEMAPHORE_WORKFLOW_ID, or pass --workflow-id to every command\n")
+		assert.Contains(t, output, "[ ok ] Using config file '"+configFile+"'\n")
+		assert.Contains(t, output, "[ ok ] Using the s3 backend\n")
+		assert.Contains(t, output, "[ ok ] Storage reached in ")
+		assert.Contains(t, output, "[ ok ] Backend capabilities: list, copy, share, stat, open, push-stream, checksum\n")
+		assert.Contains(t, output, "[ ok ] Clock is in sync with the storage\n")
+		assert.Contains(t, output, "\n0 checks failed, 1 warning.\n")
+	})
+
+	t.Run("reports unknown keys in the config file", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(configFile, []byte("s3:\n  bukcet: b\n"), 0600))
+		defer os.WriteFile(configFile, []byte("backend: s3\n"), 0600)
+
+		output := run()
+		assert.Contains(t, output, "[warn] Config file '"+configFile+"': unknown setting 's3.bukcet', did you mean 's3.bucket'?\n")
+	})
+
+	t.Run("reports a missing bucket", func(t *testing.T) {
+		t.Setenv("ARTIFACT_S3_BUCKET", "missing")
+
+		output := run()
+		assert.Contains(t, output, "[FAIL] Storage cannot be reached: bucket 'missing' does not exist\n       -> check s3.bucket")
+		assert.Contains(t, output, "\n1 check failed, 1 warning.\n")
+	})
+
+	t.Run("reports an invalid backend", func(t *testing.T) {
+		t.Setenv("ARTIFACT_BACKEND", "s4")
+
+		output := run()
+		assert.Contains(t, output, "[warn] ARTIFACT_BACKEND 's4' is not a valid backend and is ignored\n")
+		assert.Contains(t, output, "[ ok ] Using the s3 backend\n")
+	})
+
+	t.Run("reports a missing config file", func(t *testing.T) {
+		cfgFile = filepath.Join(t.TempDir(), "missing.yaml")
+		defer func() { cfgFile = configFile }()
+
+		output := run()
+		assert.Contains(t, output, "[FAIL] Config file '"+cfgFile+"' cannot be read")
+	})
+}
+
+func Test__CheckClockSkew(t *testing.T) {
+	check := func(skew time.Duration) string {
+		out := &bytes.Buffer{}
+		local := time.Now()
+		checkClockSkew(&doctorReport{out: out}, local.Add(skew), local)
+		return out.String()
+	}
+
+	assert.Equal(t, "[ ok ] Clock is in sync with the storage\n", check(20*time.Second))
+	assert.Contains(t, check(-5*time.Minute), "[warn] Clock is 5m0s off the storage's\n")
+	assert.Contains(t, check(time.Hour), "[FAIL] Clock is 1h0m0s off the storage's\n       -> synchronize the clock")
+
+	out := &bytes.Buffer{}
+	checkClockSkew(&doctorReport{out: out}, time.Time{}, time.Now())
+	assert.Empty(t, out.String())
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// duUsage is the number of files and bytes stored under a path.
+type duUsage struct {
+	Path  string `json:"path"`
+	Files int    `json:"files"`
+	Bytes int64  `json:"bytes"`
+}
+
+// duReport is the usage of a path and of the directories below it,
+// down to a depth.
+type duReport struct {
+	duUsage
+	Depth       int        `json:"depth"`
+	Directories []*duUsage `json:"directories"`
+}
+
+func NewDuCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "du",
+		Short: "Reports the number of files and bytes stored per directory",
+		Long: `Adds up the files and bytes stored for a project, workflow or job, or
+under a directory in it, and rolls them up per directory down to --depth
+levels. Use stats to see how usage grew over time.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Reports storage usage of %s directories.", cobra.MaximumNArgs(1), addDuFlags, runDuForCategory)
+	return cmd
+}
+
+func addDuFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("depth", 1, "report directories up to this many levels below PATH; 0 only reports the total")
+	cmd.Flags().BoolP("human-readable", "H", false, "print sizes in human readable format")
+	cmd.Flags().StringP("output", "o", "text", "output format: text or json")
+}
+
+func runDuForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	depth, _ := cmd.Flags().GetInt("depth")
+	humanReadable, _ := cmd.Flags().GetBool("human-readable")
+	output, _ := cmd.Flags().GetString("output")
+
+	if depth < 0 {
+		errutil.Check(fmt.Errorf("invalid --depth %d: use 0 or more", depth))
+		return
+	}
+
+	if output != "text" && output != "json" {
+		errutil.Check(fmt.Errorf("invalid --output '%s': use text or json", output))
+		return
+	}
+
+	root := resolver.PrefixedPath("")
+	remotePath := root
+	if len(args) > 0 {
+		remotePath = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	report, err := diskUsage(getContext(), lister, remotePath, root, depth)
+	if err != nil {
+		log.Errorf("Error listing artifacts: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	errutil.Check(report.write(cmd.OutOrStdout(), output, humanReadable))
+}
+
+// diskUsage adds up the objects under remotePath, per directory down to
+// depth levels below it. Paths in the report are relative to root.
+func diskUsage(ctx context.Context, lister backend.Lister, remotePath, root string, depth int) (*duReport, error) {
+	dir := strings.TrimSuffix(remotePath, "/")
+	name := relativeName(dir, strings.TrimSuffix(root, "/"))
+	if name == "" {
+		name = "."
+	}
+
+	report := &duReport{duUsage: duUsage{Path: name}, Depth: depth, Directories: []*duUsage{}}
+	dirs := map[string]*duUsage{}
+
+	err := walkRemote(ctx, lister, dir, func(obj backend.ObjectInfo) error {
+		report.Files++
+		report.Bytes += obj.Size
+
+		// Every directory containing the object, up to depth levels deep
+		parts := strings.Split(relativeName(obj.Path, dir), "/")
+		for level := 1; level < len(parts) && level <= depth; level++ {
+			key := strings.Join(parts[:level], "/")
+			usage, ok := dirs[key]
+			if !ok {
+				usage = &duUsage{Path: duPath(name, key)}
+				dirs[key] = usage
+				report.Directories = append(report.Directories, usage)
+			}
+
+			usage.Files++
+			usage.Bytes += obj.Size
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(report.Directories, func(i, j int) bool {
+		return report.Directories[i].Path < report.Directories[j].Path
+	})
+
+	return report, nil
+}
+
+func duPath(parent, dir string) string {
+	if parent == "." {
+		return dir + "/"
+	}
+
+	return parent + "/" + dir + "/"
+}
+
+func (r *duReport) write(out io.Writer, format string, humanReadable bool) error {
+	if format == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r)
+	}
+
+	size := func(bytes int64) string {
+		if humanReadable {
+			return formatBytes(bytes)
+		}
+		return strconv.FormatInt(bytes, 10)
+	}
+
+	for _, usage := range append(r.Directories, &r.duUsage) {
+		if _, err := fmt.Fprintf(out, "%10s  %8d  %s\n", size(usage.Bytes), usage.Files, usage.Path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewDuCmd())
+}
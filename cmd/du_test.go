@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__DiskUsage(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/workflows/1/a.txt", []byte("aaaa"))
+	memory.Put("artifacts/workflows/1/logs/b.log", []byte("bb"))
+	memory.Put("artifacts/workflows/1/logs/test/c.log", []byte("ccc"))
+	memory.Put("artifacts/workflows/1/logs-old/d.log", []byte("d"))
+	memory.Put("artifacts/workflows/12/e.log", []byte("eeeee"))
+
+	root := "artifacts/workflows/1"
+	usage := func(report *duReport) []duUsage {
+		result := []duUsage{}
+		for _, dir := range report.Directories {
+			result = append(result, *dir)
+		}
+		return append(result, report.duUsage)
+	}
+
+	report, err := diskUsage(ctx, memory, root, root, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []duUsage{{Path: ".", Files: 4, Bytes: 10}}, usage(report))
+
+	report, err = diskUsage(ctx, memory, root, root, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []duUsage{
+		{Path: "logs-old/", Files: 1, Bytes: 1},
+		{Path: "logs/", Files: 2, Bytes: 5},
+		{Path: "logs/test/", Files: 1, Bytes: 3},
+		{Path: ".", Files: 4, Bytes: 10},
+	}, usage(report))
+
+	report, err = diskUsage(ctx, memory, root+"/logs", root, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []duUsage{
+		{Path: "logs/test/", Files: 1, Bytes: 3},
+		{Path: "logs", Files: 2, Bytes: 5},
+	}, usage(report))
+}
+
+func Test__Du(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/a.txt", Contents: "aaaa"},
+		{Name: "artifacts/jobs/1/logs/b.log", Contents: "bb"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewDuCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	assert.Equal(t, "         2         1  logs/\n         6         2  .\n", run())
+
+	report := duReport{}
+	require.NoError(t, json.Unmarshal([]byte(run("-o", "json", "--depth", "0")), &report))
+	assert.Equal(t, 2, report.Files)
+	assert.Equal(t, int64(6), report.Bytes)
+	assert.Empty(t, report.Directories)
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of the exists command, so scripts can tell a missing
+// file apart from a failed check.
+const (
+	existsExitFound    = 0
+	existsExitNotFound = 1
+	existsExitError    = 2
+)
+
+func NewExistsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "exists",
+		Short: "Checks if a file exists in the storage",
+		Long: `Checks if a file is stored for a project, workflow or job, and exits
+with status 0 if it is, 1 if it is not, and 2 if the check failed,
+e.g. because the storage could not be reached:
+
+  if artifact exists job reports/out.xml; then ...`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Checks if a %s file exists in the storage.", cobra.ExactArgs(1), nil, runExistsForCategory)
+	return cmd
+}
+
+func runExistsForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	remotePath := resolver.PrefixedPath(files.ToRelative(args[0]))
+
+	b := getBackend()
+	code := checkExists(getContext(), b, remotePath)
+	_ = b.Close()
+
+	if code != existsExitFound {
+		errutil.Exit(code)
+	}
+}
+
+// checkExists logs whether remotePath exists, and returns the exit code for it.
+func checkExists(ctx context.Context, b backend.Backend, remotePath string) int {
+	exists, err := b.Exists(ctx, remotePath)
+	if err != nil {
+		log.Errorf("Error checking if artifact exists: %v\n", err)
+		return existsExitError
+	}
+
+	if !exists {
+		log.Infof("'%s' does not exist.\n", remotePath)
+		return existsExitNotFound
+	}
+
+	log.Infof("'%s' exists.\n", remotePath)
+	return existsExitFound
+}
+
+func init() {
+	rootCmd.AddCommand(NewExistsCmd())
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+)
+
+// unreachableBackend fails every existence check.
+type unreachableBackend struct {
+	*memorybackend.MemoryBackend
+}
+
+func (unreachableBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func Test__CheckExists(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/reports/out.xml", []byte("<testsuites/>"))
+
+	assert.Equal(t, existsExitFound, checkExists(ctx, memory, "artifacts/jobs/1/reports/out.xml"))
+	assert.Equal(t, existsExitNotFound, checkExists(ctx, memory, "artifacts/jobs/1/reports/missing.xml"))
+	assert.Equal(t, existsExitError, checkExists(ctx, unreachableBackend{memory}, "artifacts/jobs/1/reports/out.xml"))
+}
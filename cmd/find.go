@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// findOptions holds the parsed filters and output flags of find.
+type findOptions struct {
+	Glob   string
+	Regex  *regexp.Regexp
+	Print0 bool
+}
+
+func NewFindCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "find",
+		Short: "Searches the storage for files matching a pattern",
+		Long: `Searches every file stored for a project, workflow or job, or under a
+directory in it, and prints the paths matching a glob pattern and/or a
+regular expression, one per line. Paths are relative to the store and
+are printed as the listing arrives. Use --print0 to pipe them into xargs -0.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Searches %s files in the storage.", cobra.MaximumNArgs(1), addFindFlags, runFindForCategory)
+	return cmd
+}
+
+func addFindFlags(cmd *cobra.Command) {
+	cmd.Flags().String("glob", "", "only print paths matching the glob pattern, e.g. '**/junit-*.xml'")
+	cmd.Flags().String("regex", "", "only print paths matching the regular expression, e.g. 'flaky_.*\\.log$'")
+	cmd.Flags().Bool("print0", false, "separate paths with a NUL character instead of a newline")
+}
+
+func runFindForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	opts, err := parseFindOptions(cmd)
+	errutil.Check(err)
+
+	remotePath := resolver.PrefixedPath("")
+	if len(args) > 0 {
+		remotePath = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	err = findObjects(getContext(), lister, remotePath, resolver.PrefixedPath(""), opts, cmd.OutOrStdout())
+	if err != nil {
+		log.Errorf("Error searching artifacts: %v\n", err)
+		errutil.Exit(1)
+	}
+}
+
+func parseFindOptions(cmd *cobra.Command) (*findOptions, error) {
+	opts := &findOptions{}
+	opts.Print0, _ = cmd.Flags().GetBool("print0")
+
+	opts.Glob, _ = cmd.Flags().GetString("glob")
+	if err := files.ValidateGlob(opts.Glob); err != nil {
+		return nil, fmt.Errorf("invalid --glob '%s': %v", opts.Glob, err)
+	}
+
+	if expr, _ := cmd.Flags().GetString("regex"); expr != "" {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid --regex '%s': %v", expr, err)
+		}
+		opts.Regex = re
+	}
+
+	return opts, nil
+}
+
+// findObjects prints the paths of the objects under remotePath that match
+// opts, relative to root. Without filters, every path is printed.
+func findObjects(ctx context.Context, lister backend.Lister, remotePath, root string, opts *findOptions, out io.Writer) error {
+	separator := "\n"
+	if opts.Print0 {
+		separator = "\x00"
+	}
+
+	return walkRemote(ctx, lister, remotePath, func(obj backend.ObjectInfo) error {
+		name := relativeName(obj.Path, root)
+		if !opts.matches(name) {
+			return nil
+		}
+
+		_, err := io.WriteString(out, name+separator)
+		return err
+	})
+}
+
+func (o *findOptions) matches(name string) bool {
+	if o.Glob != "" {
+		if matched, _ := files.MatchGlob(o.Glob, name); !matched {
+			return false
+		}
+	}
+
+	return o.Regex == nil || o.Regex.MatchString(name)
+}
+
+func init() {
+	rootCmd.AddCommand(NewFindCmd())
+}
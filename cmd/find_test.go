@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Find(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_WORKFLOW_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/workflows/1/job-a/junit.xml", Contents: "a"},
+		{Name: "artifacts/workflows/1/job-a/logs/flaky_login.log", Contents: "a"},
+		{Name: "artifacts/workflows/1/job-b/junit.xml", Contents: "b"},
+		{Name: "artifacts/workflows/1/job-b/logs/app.log", Contents: "b"},
+		{Name: "artifacts/workflows/12/job-c/junit.xml", Contents: "c"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewFindCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"workflow"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("matches a glob", func(t *testing.T) {
+		assert.Equal(t, "job-a/junit.xml\njob-b/junit.xml\n", run("--glob", "**/junit.xml"))
+	})
+
+	t.Run("matches a regex", func(t *testing.T) {
+		assert.Equal(t, "job-a/logs/flaky_login.log\n", run("--regex", `flaky_.*\.log$`))
+	})
+
+	t.Run("combines filters under a directory", func(t *testing.T) {
+		assert.Equal(t, "job-b/logs/app.log\n", run("job-b", "--glob", "**/*.log", "--regex", "app"))
+	})
+
+	t.Run("separates paths with NUL", func(t *testing.T) {
+		assert.Equal(t, "job-a/junit.xml\x00job-b/junit.xml\x00", run("--glob", "**/junit.xml", "--print0"))
+	})
+}
@@ -0,0 +1,421 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	"github.com/semaphoreci/artifact/pkg/retention"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// gcDirs maps the stores gc can delete to the directories holding them.
+// Project stores live as long as their project, so gc never deletes them.
+var gcDirs = map[string]string{
+	files.ResourceTypeJob:      "artifacts/jobs",
+	files.ResourceTypeWorkflow: "artifacts/workflows",
+}
+
+// gcStore is a job or workflow store found in the backend.
+type gcStore struct {
+	ResourceType string
+	ID           string
+	Path         string
+	FileCount    int
+	Size         int64
+	ModTime      time.Time // Modification time of the newest file
+}
+
+func NewGCCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gc",
+		Short: "Deletes the stores of old jobs and workflows",
+		Long: `Scans the backend for job and workflow stores and deletes the ones that
+are no longer needed, e.g. on S3 buckets where the retention of Semaphore
+does not apply:
+
+  artifact gc --older-than 30d
+  artifact gc --ids-from finished-jobs.txt --stores job
+
+With --older-than, a store is deleted once its newest file is older than
+the given age. With --ids-from, only the stores of the listed job and
+workflow ids are deleted, e.g. ids exported from the Semaphore API. Given
+both, a store must satisfy both. With --retention, the files of the other
+stores expired by the retention policy stored in the backend are deleted
+too. Use --dry-run to see what would be deleted.`,
+		Args: cobra.NoArgs,
+		Run:  runGC,
+	}
+
+	cmd.Flags().String("older-than", "", "delete stores whose newest file is older than the given age, e.g. 30d")
+	cmd.Flags().String("ids-from", "", "delete only the stores of the ids in this file, URL, or - for stdin; one id per line or a JSON array")
+	cmd.Flags().StringSlice("stores", []string{files.ResourceTypeJob, files.ResourceTypeWorkflow}, "kinds of stores to delete: job, workflow or both")
+	cmd.Flags().Bool("retention", false, "also delete files expired by the retention policy stored in the backend")
+	cmd.Flags().Int("batch-size", 10, "number of stores deleted concurrently")
+	cmd.Flags().Bool("dry-run", false, "print the stores that would be deleted without deleting them")
+	return cmd
+}
+
+func runGC(cmd *cobra.Command, args []string) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	resourceTypes, _ := cmd.Flags().GetStringSlice("stores")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	useRetention, _ := cmd.Flags().GetBool("retention")
+
+	for _, t := range resourceTypes {
+		if _, ok := gcDirs[t]; !ok {
+			errutil.Check(fmt.Errorf("invalid --stores '%s': use job, workflow or both", t))
+		}
+	}
+
+	if batchSize < 1 {
+		errutil.Check(fmt.Errorf("--batch-size must be at least 1"))
+	}
+
+	var olderThan time.Duration
+	if value, _ := cmd.Flags().GetString("older-than"); value != "" {
+		age, err := common.ParseAge(value)
+		errutil.Check(err)
+		olderThan = age
+	}
+
+	var ids map[string]bool
+	if source, _ := cmd.Flags().GetString("ids-from"); source != "" {
+		var err error
+		ids, err = readGCIDs(source, cmd.InOrStdin())
+		errutil.Check(err)
+	}
+
+	// Without filters, gc would delete every job and workflow store
+	if olderThan == 0 && ids == nil && !useRetention {
+		errutil.Check(fmt.Errorf("gc requires --older-than, --ids-from, --retention or a combination of them"))
+	}
+
+	p, err := getPolicy()
+	errutil.Check(err)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	ctx := getContext()
+	stores, err := scanGCStores(ctx, lister, resourceTypes)
+	if err != nil {
+		log.Errorf("Error scanning stores: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	now := time.Now()
+	candidates := []gcStore{}
+	if olderThan > 0 || ids != nil {
+		candidates = selectGCStores(stores, ids, olderThan, now)
+	}
+
+	var expired []lsEntry
+	if useRetention {
+		rp, err := loadRetention(ctx, b)
+		errutil.Check(err)
+
+		expired, err = expiredGCFiles(ctx, lister, rp, stores, candidates, now)
+		if err != nil {
+			log.Errorf("Error scanning files: %v\n", err)
+			errutil.Exit(1)
+			return
+		}
+	}
+
+	if dryRun {
+		errutil.Check(printGCStores(cmd.OutOrStdout(), candidates))
+		log.Infof("Would delete %d of %d %s (%s).\n", len(candidates), len(stores), pluralize(len(stores), "store", "stores"), formatBytes(totalGCSize(candidates)))
+		if useRetention {
+			errutil.Check(printPruneCandidates(cmd.OutOrStdout(), expired))
+		}
+		return
+	}
+
+	removed, failed := collectGarbage(ctx, b, candidates, batchSize, func(store gcStore) error {
+		return checkGCYank(p, store.ResourceType, store.ID, store.Path, true)
+	})
+
+	log.Infof("Deleted %d %s (%s).\n", len(removed), pluralize(len(removed), "store", "stores"), formatBytes(totalGCSize(removed)))
+
+	if useRetention {
+		removedFiles, failedFiles := pruneFiles(ctx, b, expired, func(entry lsEntry) error {
+			resourceType, id := gcStoreOf(entry.Info.Path)
+			return checkGCYank(p, resourceType, id, entry.Info.Path, false)
+		})
+
+		log.Infof("Pruned %d expired %s (%s).\n", len(removedFiles), pluralize(len(removedFiles), "file", "files"), formatBytes(totalSize(removedFiles)))
+		failed += failedFiles
+	}
+
+	if failed > 0 {
+		log.Errorf("Failed to delete %d %s.\n", failed, pluralize(failed, "store or file", "stores or files"))
+		errutil.Exit(1)
+	}
+}
+
+// checkGCYank evaluates the policy for yanking remotePath from a store.
+func checkGCYank(p *policy.Policy, resourceType, id, remotePath string, dir bool) error {
+	resolver, err := files.NewPathResolver(resourceType, id)
+	if err != nil {
+		return err
+	}
+
+	request := policyRequest(policy.OperationYank, resolver, remotePath)
+	request.Dir = dir
+	return p.Evaluate(request)
+}
+
+// gcStoreOf returns the kind and id of the store holding remotePath.
+func gcStoreOf(remotePath string) (string, string) {
+	for resourceType, dir := range gcDirs {
+		if rest, ok := strings.CutPrefix(remotePath, dir+"/"); ok {
+			id, _, _ := strings.Cut(rest, "/")
+			return resourceType, id
+		}
+	}
+
+	return "", ""
+}
+
+// expiredGCFiles lists the files of stores, except the deleted ones, that
+// have outlived the retention rules matching them. The rules match names
+// relative to the store; the listed names include the store, e.g.
+// jobs/1/app.log.
+func expiredGCFiles(ctx context.Context, lister backend.Lister, p *retention.Policy, stores, deleted []gcStore, now time.Time) ([]lsEntry, error) {
+	skip := map[string]bool{}
+	for _, store := range deleted {
+		skip[store.Path] = true
+	}
+
+	expired := []lsEntry{}
+	for _, store := range stores {
+		if skip[store.Path] {
+			continue
+		}
+
+		err := walkRemote(ctx, lister, store.Path, func(obj backend.ObjectInfo) error {
+			name := relativeName(obj.Path, store.Path)
+			if p.Expired(store.ResourceType, name, obj.ModTime, now) {
+				expired = append(expired, lsEntry{Name: relativeName(obj.Path, "artifacts"), Info: obj})
+			}
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return expired, nil
+}
+
+// readGCIDs reads the ids listed in a file, at an http(s) URL, or on stdin
+// for "-": one per line, ignoring blank lines and # comments, or a JSON array.
+func readGCIDs(source string, stdin io.Reader) (map[string]bool, error) {
+	var r io.Reader
+	switch {
+	case source == "-":
+		r = stdin
+	case strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://"):
+		response, err := storage.NewHTTPClient().Get(source)
+		if err != nil {
+			return nil, fmt.Errorf("failed to download '%s': %v", source, err)
+		}
+
+		// #nosec
+		defer response.Body.Close()
+
+		if !common.IsStatusOK(response.StatusCode) {
+			return nil, fmt.Errorf("GET request to %s failed with %d status code", source, response.StatusCode)
+		}
+		r = response.Body
+	default:
+		f, err := os.Open(source)
+		if err != nil {
+			return nil, err
+		}
+
+		// #nosec
+		defer f.Close()
+		r = f
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ids from '%s': %v", source, err)
+	}
+
+	ids := map[string]bool{}
+	if trimmed := strings.TrimSpace(string(data)); strings.HasPrefix(trimmed, "[") {
+		list := []string{}
+		if err := json.Unmarshal([]byte(trimmed), &list); err != nil {
+			return nil, fmt.Errorf("failed to parse ids from '%s': %v", source, err)
+		}
+
+		for _, id := range list {
+			ids[id] = true
+		}
+
+		return ids, nil
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" && !strings.HasPrefix(line, "#") {
+			ids[line] = true
+		}
+	}
+
+	return ids, nil
+}
+
+// scanGCStores lists the stores of the given kinds, sorted from the oldest.
+func scanGCStores(ctx context.Context, lister backend.Lister, resourceTypes []string) ([]gcStore, error) {
+	stores := []gcStore{}
+	for _, t := range resourceTypes {
+		dir := gcDirs[t]
+		byID := map[string]*gcStore{}
+
+		err := walkRemote(ctx, lister, dir, func(obj backend.ObjectInfo) error {
+			id, _, _ := strings.Cut(relativeName(obj.Path, dir), "/")
+			if id == "" {
+				return nil
+			}
+
+			store, ok := byID[id]
+			if !ok {
+				store = &gcStore{ResourceType: t, ID: id, Path: path.Join(dir, id)}
+				byID[id] = store
+			}
+
+			store.FileCount++
+			store.Size += obj.Size
+			if obj.ModTime.After(store.ModTime) {
+				store.ModTime = obj.ModTime
+			}
+
+			return nil
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, store := range byID {
+			stores = append(stores, *store)
+		}
+	}
+
+	sort.Slice(stores, func(i, j int) bool {
+		if !stores[i].ModTime.Equal(stores[j].ModTime) {
+			return stores[i].ModTime.Before(stores[j].ModTime)
+		}
+		return stores[i].Path < stores[j].Path
+	})
+
+	return stores, nil
+}
+
+// selectGCStores returns the stores whose id is in ids, if given, and whose
+// newest file is older than olderThan, if set.
+func selectGCStores(stores []gcStore, ids map[string]bool, olderThan time.Duration, now time.Time) []gcStore {
+	selected := []gcStore{}
+	for _, store := range stores {
+		if ids != nil && !ids[store.ID] {
+			continue
+		}
+
+		if olderThan > 0 && now.Sub(store.ModTime) < olderThan {
+			continue
+		}
+
+		selected = append(selected, store)
+	}
+
+	return selected
+}
+
+// collectGarbage yanks the stores that pass check, batchSize at a time,
+// and logs the progress after each batch. A store that cannot be deleted is
+// logged and skipped. It returns the deleted stores and the failure count.
+func collectGarbage(ctx context.Context, b backend.Backend, stores []gcStore, batchSize int, check func(gcStore) error) ([]gcStore, int) {
+	removed := []gcStore{}
+	failed := 0
+
+	for start := 0; start < len(stores); start += batchSize {
+		batch := stores[start:min(start+batchSize, len(stores))]
+		errs := make([]error, len(batch))
+
+		var wg sync.WaitGroup
+		for i, store := range batch {
+			wg.Add(1)
+			go func(i int, store gcStore) {
+				defer wg.Done()
+				if errs[i] = check(store); errs[i] == nil {
+					errs[i] = b.Yank(ctx, store.Path)
+				}
+			}(i, store)
+		}
+		wg.Wait()
+
+		for i, store := range batch {
+			if errs[i] != nil && !isNotFound(errs[i]) {
+				log.Warnf("Failed to delete %s store '%s': %v\n", store.ResourceType, store.ID, errs[i])
+				failed++
+				continue
+			}
+
+			log.Debugf("Deleted %s store '%s'.\n", store.ResourceType, store.ID)
+			removed = append(removed, store)
+		}
+
+		log.Infof("Processed %d of %d %s.\n", start+len(batch), len(stores), pluralize(len(stores), "store", "stores"))
+	}
+
+	return removed, failed
+}
+
+func printGCStores(out io.Writer, stores []gcStore) error {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, store := range stores {
+		_, err := fmt.Fprintf(w, "%s\t%s\t%d %s\t%s\t%s\n", store.ResourceType, store.ID, store.FileCount, pluralize(store.FileCount, "file", "files"), formatBytes(store.Size), store.ModTime.Format(time.RFC3339))
+		if err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func totalGCSize(stores []gcStore) int64 {
+	var size int64
+	for _, store := range stores {
+		size += store.Size
+	}
+
+	return size
+}
+
+func init() {
+	rootCmd.AddCommand(NewGCCmd())
+}
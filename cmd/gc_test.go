@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__GC(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/a.txt", []byte("aa"))
+	memory.Put("artifacts/jobs/1/logs/b.txt", []byte("b"))
+	memory.Put("artifacts/jobs/2/a.txt", []byte("a"))
+	memory.Put("artifacts/workflows/3/a.txt", []byte("a"))
+	memory.Put("artifacts/projects/4/a.txt", []byte("a"))
+
+	stores, err := scanGCStores(ctx, memory, []string{files.ResourceTypeJob, files.ResourceTypeWorkflow})
+	require.NoError(t, err)
+	require.Len(t, stores, 3)
+	assert.Equal(t, gcStore{ResourceType: "job", ID: "1", Path: "artifacts/jobs/1", FileCount: 2, Size: 3, ModTime: stores[0].ModTime}, stores[0])
+
+	t.Run("selects stores by age and id", func(t *testing.T) {
+		assert.Empty(t, selectGCStores(stores, nil, time.Hour, time.Now()))
+		assert.Len(t, selectGCStores(stores, nil, time.Hour, time.Now().Add(2*time.Hour)), 3)
+
+		selected := selectGCStores(stores, map[string]bool{"2": true, "3": true}, time.Hour, time.Now().Add(2*time.Hour))
+		assert.Equal(t, []string{"artifacts/jobs/2", "artifacts/workflows/3"}, gcPaths(selected))
+	})
+
+	t.Run("deletes stores in batches, skipping failed ones", func(t *testing.T) {
+		removed, failed := collectGarbage(ctx, memory, stores, 2, func(store gcStore) error {
+			if store.ID == "2" {
+				return errors.New("denied")
+			}
+			return nil
+		})
+
+		assert.Equal(t, 1, failed)
+		assert.Equal(t, []string{"artifacts/jobs/1", "artifacts/workflows/3"}, gcPaths(removed))
+		assert.Equal(t, []string{"artifacts/jobs/2/a.txt", "artifacts/projects/4/a.txt"}, memory.Paths())
+	})
+}
+
+func Test__ReadGCIDs(t *testing.T) {
+	ids, err := readGCIDs("-", strings.NewReader("# finished jobs\n1\n\n 2 \n"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"1": true, "2": true}, ids)
+
+	ids, err = readGCIDs("-", strings.NewReader(`["1", "3"]`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"1": true, "3": true}, ids)
+
+	_, err = readGCIDs("-", strings.NewReader(`[1`))
+	assert.ErrorContains(t, err, "failed to parse ids")
+}
+
+func gcPaths(stores []gcStore) []string {
+	paths := []string{}
+	for _, store := range stores {
+		paths = append(paths, store.Path)
+	}
+
+	return paths
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/pack"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addKeepGoingFlags(cmd *cobra.Command, verb string) {
+	cmd.Flags().Bool("keep-going", false, fmt.Sprintf("keep going when a file of a directory fails to %s, and list the failed ones at the end", verb))
+}
+
+// fileFailure is a file of a directory that failed to transfer.
+type fileFailure struct {
+	Path string
+	Err  error
+}
+
+// failedFiles collects the files of a directory that failed to transfer
+// concurrently. It is the error of pushes and pulls run with --keep-going
+// when some files failed, listing every failed file and why.
+type failedFiles struct {
+	mu       sync.Mutex
+	verb     string // "push" or "pull"
+	total    int
+	failures []fileFailure
+}
+
+func newFailedFiles(verb string, total int) *failedFiles {
+	return &failedFiles{verb: verb, total: total}
+}
+
+// add records the failure of the file at path.
+func (f *failedFiles) add(path string, err error) {
+	log.Warnf("Failed to %s '%s': %v\n", f.verb, path, err)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures = append(f.failures, fileFailure{Path: path, Err: err})
+}
+
+// err returns f, with the failed files sorted by path, or nil if none
+// failed.
+func (f *failedFiles) err() error {
+	if len(f.failures) == 0 {
+		return nil
+	}
+
+	sort.Slice(f.failures, func(i, j int) bool {
+		return f.failures[i].Path < f.failures[j].Path
+	})
+
+	return f
+}
+
+func (f *failedFiles) Error() string {
+	lines := []string{fmt.Sprintf("failed to %s %d of %d %s:", f.verb, len(f.failures), f.total, pluralize(f.total, "file", "files"))}
+	for _, failure := range f.failures {
+		lines = append(lines, fmt.Sprintf("* '%s': %v", failure.Path, failure.Err))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// pullEach pulls the files under the remote directory paths.Source one by
+// one, opts.Concurrency at a time, keeping going when some fail. It
+// returns the stats of the pulled files, and a *failedFiles error if
+// some failed. Remote paths that are no directory are pulled as usual.
+// The small files of directories pushed with --pack are unpacked first,
+// and a failing pack fails the files it holds.
+func pullEach(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, opts backend.PullOptions) (*storage.PullStats, error) {
+	lister, err := getLister(b)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := []backend.ObjectInfo{}
+	err = walkRemote(ctx, lister, paths.Source, func(obj backend.ObjectInfo) error {
+		if obj.Path != strings.TrimSuffix(paths.Source, "/") {
+			objects = append(objects, obj)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(objects) == 0 {
+		return pullResolved(ctx, b, paths, opts)
+	}
+
+	packs := readPackIndex(ctx, b, paths.Source)
+
+	// Keep other artifact processes from writing into the same destination
+	lock, err := files.LockDestination(paths.Destination, getLockTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	var mu sync.Mutex
+	transfer := backend.TransferStatsFromContext(ctx)
+	stats := &storage.PullStats{}
+	failures := newFailedFiles("pull", len(objects)+packedFiles(packs))
+
+	if packs != nil {
+		for _, p := range packs.Packs {
+			single := &pack.Index{Version: packs.Version, Packs: []pack.Pack{p}}
+			packed, err := pullPacks(ctx, b, single, paths, opts, false)
+			if err != nil {
+				for _, entry := range p.Files {
+					transfer.Failed()
+					failures.add(path.Join(strings.TrimSuffix(paths.Source, "/"), entry.Path), err)
+				}
+				continue
+			}
+
+			stats.FileCount += packed.FileCount
+			stats.TotalSize += packed.TotalSize
+		}
+	}
+
+	_ = backend.Parallel(len(objects), opts.Concurrency, func(i int) error {
+		obj := objects[i]
+		localPath := pulledPath(paths, obj.Path)
+		err := b.Pull(ctx, obj.Path, localPath, opts)
+		if err == nil {
+			err = expandDelta(ctx, b, obj.Path, localPath)
+		}
+		if err != nil {
+			transfer.Failed()
+			failures.add(obj.Path, err)
+			return nil
+		}
+
+		size := obj.Size
+		if info, err := os.Stat(localPath); err == nil {
+			size = info.Size()
+		}
+
+		transfer.Transferred(size)
+
+		mu.Lock()
+		stats.FileCount++
+		stats.TotalSize += size
+		mu.Unlock()
+		return nil
+	})
+
+	return stats, failures.err()
+}
+
+// packedFiles returns how many files the packs of the index hold.
+func packedFiles(index *pack.Index) int {
+	if index == nil {
+		return 0
+	}
+
+	count := 0
+	for _, p := range index.Packs {
+		count += len(p.Files)
+	}
+
+	return count
+}
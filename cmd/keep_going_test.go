@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenBackend fails to push and pull the files named "broken*".
+type brokenBackend struct {
+	*memorybackend.MemoryBackend
+}
+
+func (b brokenBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if strings.HasPrefix(filepath.Base(localPath), "broken") {
+		return errors.New("connection reset")
+	}
+
+	return b.MemoryBackend.Push(ctx, localPath, remotePath, opts)
+}
+
+func (b brokenBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if strings.HasPrefix(filepath.Base(remotePath), "broken") {
+		return errors.New("connection reset")
+	}
+
+	return b.MemoryBackend.Pull(ctx, remotePath, localPath, opts)
+}
+
+func Test__PushEachKeepGoing(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "logs"), 0755))
+	for _, name := range []string{"a.txt", "broken.txt", "logs/b.txt", "logs/broken.log"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+	}
+
+	paths := &files.ResolvedPath{Source: dir, Destination: "artifacts/jobs/1/out"}
+	pushed, err := walkPushed(paths, nil)
+	require.NoError(t, err)
+
+	b := brokenBackend{memorybackend.New()}
+	_, _, err = pushEach(ctx, b, pushed, backend.PushOptions{Concurrency: 1}, false, nil)
+	assert.EqualError(t, err, "connection reset")
+
+	b = brokenBackend{memorybackend.New()}
+	_, _, err = pushEach(ctx, b, pushed, backend.PushOptions{}, true, nil)
+	assert.EqualError(t, err, "failed to push 2 of 4 files:\n"+
+		"* '"+filepath.Join(dir, "broken.txt")+"': connection reset\n"+
+		"* '"+filepath.Join(dir, "logs", "broken.log")+"': connection reset")
+	assert.ElementsMatch(t, []string{"artifacts/jobs/1/out/a.txt", "artifacts/jobs/1/out/logs/b.txt"}, b.Paths())
+}
+
+func Test__PullEachKeepGoing(t *testing.T) {
+	ctx := context.Background()
+	b := brokenBackend{memorybackend.New()}
+	b.Put("artifacts/jobs/1/out/a.txt", []byte("a"))
+	b.Put("artifacts/jobs/1/out/broken.txt", []byte("broken"))
+	b.Put("artifacts/jobs/1/out/logs/b.txt", []byte("b"))
+
+	dir := t.TempDir()
+	paths := &files.ResolvedPath{Source: "artifacts/jobs/1/out", Destination: filepath.Join(dir, "out")}
+	stats, err := pullEach(ctx, b, paths, backend.PullOptions{})
+	assert.EqualError(t, err, "failed to pull 1 of 3 files:\n* 'artifacts/jobs/1/out/broken.txt': connection reset")
+	assert.Equal(t, 2, stats.FileCount)
+	assert.FileExists(t, filepath.Join(dir, "out", "a.txt"))
+	assert.FileExists(t, filepath.Join(dir, "out", "logs", "b.txt"))
+	assert.NoFileExists(t, filepath.Join(dir, "out", "broken.txt"))
+
+	// Single files are pulled as usual
+	paths = &files.ResolvedPath{Source: "artifacts/jobs/1/out/a.txt", Destination: filepath.Join(dir, "a.txt")}
+	stats, err = pullEach(ctx, b, paths, backend.PullOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.FileCount)
+}
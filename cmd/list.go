@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+)
+
+func NewListCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "Lists the contents of a directory in the storage",
+		Long: `Lists the files and directories directly under the root of a project,
+workflow or job store, or under a directory in it, with their size and
+modification time. Directories show the total size and the latest
+modification time of the files they contain. It is ls with --dirs set,
+and takes the same flags; use ls to list every file recursively.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Lists the contents of a %s directory in the storage.", cobra.MaximumNArgs(1), addListFlags, runListForCategory)
+	return cmd
+}
+
+func addListFlags(cmd *cobra.Command) {
+	addLsFlags(cmd)
+	_ = cmd.Flags().MarkHidden("dirs")
+}
+
+func runListForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	_ = cmd.Flags().Set("dirs", "true")
+	runLsForCategory(cmd, args, resolver)
+}
+
+func init() {
+	rootCmd.AddCommand(NewListCmd())
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__List(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/a.txt", Contents: "aaaaa"},
+		{Name: "artifacts/jobs/1/logs-old.txt", Contents: "old"},
+		{Name: "artifacts/jobs/1/logs/b.log", Contents: "b"},
+		{Name: "artifacts/jobs/1/logs/nested/c.log", Contents: "ccc"},
+		{Name: "artifacts/jobs/12/other.txt", Contents: "other"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) []string {
+		out := &bytes.Buffer{}
+		cmd := NewListCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+
+		// Drop the modification time, which depends on the test run
+		lines := []string{}
+		for _, line := range strings.Split(strings.TrimRight(out.String(), "\n"), "\n") {
+			fields := strings.Fields(line)
+			lines = append(lines, fields[0]+" "+fields[len(fields)-1])
+		}
+		return lines
+	}
+
+	t.Run("lists the root of the store", func(t *testing.T) {
+		assert.Equal(t, []string{"5 a.txt", "3 logs-old.txt", "4 logs/"}, run())
+	})
+
+	t.Run("lists a directory", func(t *testing.T) {
+		assert.Equal(t, []string{"1 b.log", "3 nested/"}, run("logs"))
+	})
+
+	t.Run("lists a file", func(t *testing.T) {
+		assert.Equal(t, []string{"5 a.txt"}, run("a.txt"))
+	})
+
+	t.Run("takes the flags of ls", func(t *testing.T) {
+		assert.Equal(t, []string{"3 logs-old.txt", "4 logs/", "5 a.txt"}, run("--sort", "size"))
+		assert.Equal(t, []string{"5 a.txt", "3 logs-old.txt"}, run("--limit", "2"))
+		assert.Equal(t, []string{"1 b.log"}, run("logs", "--match", "*.log"))
+	})
+}
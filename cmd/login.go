@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// loginFields describes the credentials stored for a backend: an
+// identifier, empty if there is none, and a secret.
+type loginFields struct {
+	idFlag       string
+	idPrompt     string
+	idOptional   bool
+	secretPrompt string
+	build        func(id, secret string) *backend.Credentials
+}
+
+var loginBackends = map[backend.BackendType]loginFields{
+	backend.BackendTypeS3: {
+		idFlag:       "access-key-id",
+		idPrompt:     "Access key ID",
+		secretPrompt: "Secret access key",
+		build: func(id, secret string) *backend.Credentials {
+			return &backend.Credentials{AccessKeyID: id, SecretAccessKey: secret}
+		},
+	},
+	backend.BackendTypeHTTP: {
+		secretPrompt: "Token",
+		build: func(id, secret string) *backend.Credentials {
+			return &backend.Credentials{Token: secret}
+		},
+	},
+	backend.BackendTypeFTP: {
+		idFlag:       "username",
+		idPrompt:     "Username",
+		secretPrompt: "Password",
+		build: func(id, secret string) *backend.Credentials {
+			return &backend.Credentials{Username: id, Password: secret}
+		},
+	},
+	backend.BackendTypeArtifactory: {
+		idFlag:       "username",
+		idPrompt:     "Username (empty to use an access token)",
+		idOptional:   true,
+		secretPrompt: "Password or access token",
+		build: func(id, secret string) *backend.Credentials {
+			if id == "" {
+				return &backend.Credentials{Token: secret}
+			}
+			return &backend.Credentials{Username: id, Password: secret}
+		},
+	},
+}
+
+func NewLoginCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "login [BACKEND]",
+		Short: "Stores credentials of a backend in the OS keychain or an encrypted file",
+		Long: `Stores the credentials of a backend (the one in use by default), so they
+do not have to be kept in shell history or plain environment variables.
+The secret is prompted for without echo, or read from stdin with
+--secret-stdin. Credentials are stored in the OS keychain, or with
+--store file in a file encrypted with ARTIFACT_CREDENTIAL_PASSPHRASE.
+
+Stored credentials are used like those of a credential helper, which
+takes precedence over them. Supported backends: s3, http, ftp and
+artifactory.`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(runLogin(cmd, args))
+		},
+	}
+
+	cmd.Flags().String("store", "", "credential store: keychain or file (default is the configured one, or keychain)")
+	cmd.Flags().String("access-key-id", "", "access key ID of the s3 backend")
+	cmd.Flags().String("username", "", "username of the ftp or artifactory backend")
+	cmd.Flags().Bool("secret-stdin", false, "read the secret access key, token or password from stdin")
+	return cmd
+}
+
+func NewLogoutCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "logout [BACKEND]",
+		Short: "Removes stored credentials of a backend",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(runLogout(cmd.OutOrStdout(), args))
+		},
+	}
+}
+
+func runLogin(cmd *cobra.Command, args []string) error {
+	backendType, fields, err := loginBackend(args)
+	if err != nil {
+		return err
+	}
+
+	kind, _ := cmd.Flags().GetString("store")
+	if kind == "" {
+		kind = credentialStoreKind()
+	}
+
+	store, err := backend.NewCredentialStore(kind)
+	if err != nil {
+		return err
+	}
+
+	secretStdin, _ := cmd.Flags().GetBool("secret-stdin")
+	interactive := !secretStdin && term.IsTerminal(int(os.Stdin.Fd()))
+	out := cmd.OutOrStdout()
+
+	id := ""
+	if fields.idFlag != "" {
+		id, _ = cmd.Flags().GetString(fields.idFlag)
+		if id == "" && interactive {
+			if id, err = promptLine(out, os.Stdin, fields.idPrompt); err != nil {
+				return err
+			}
+		}
+		if id == "" && !fields.idOptional {
+			return fmt.Errorf("no %s given: use --%s", strings.ToLower(fields.idPrompt), fields.idFlag)
+		}
+	}
+
+	var secret string
+	switch {
+	case secretStdin:
+		secret, err = readLine(cmd.InOrStdin())
+	case interactive:
+		secret, err = promptSecret(out, fields.secretPrompt)
+	default:
+		return fmt.Errorf("no %s given: use --secret-stdin, or run login in a terminal", strings.ToLower(fields.secretPrompt))
+	}
+	if err != nil {
+		return err
+	}
+	if secret == "" {
+		return fmt.Errorf("%s must not be empty", strings.ToLower(fields.secretPrompt))
+	}
+
+	if err := store.Save(backendType, fields.build(id, secret)); err != nil {
+		return fmt.Errorf("failed to store credentials in the %s credential store: %v", kind, err)
+	}
+
+	fmt.Fprintf(out, "Stored credentials of the %s backend in the %s credential store.\n", backendType, kind)
+
+	// Stored credentials are only read from the configured store
+	if backend.GetCredentialStore() != kind {
+		if err := setConfigValue(out, "credentialStore", kind); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func runLogout(out io.Writer, args []string) error {
+	backendType, _, err := loginBackend(args)
+	if err != nil {
+		return err
+	}
+
+	kind := credentialStoreKind()
+	store, err := backend.NewCredentialStore(kind)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := store.Delete(backendType)
+	if err != nil {
+		return fmt.Errorf("failed to remove credentials from the %s credential store: %v", kind, err)
+	}
+	if !deleted {
+		return fmt.Errorf("no credentials of the %s backend stored in the %s credential store", backendType, kind)
+	}
+
+	fmt.Fprintf(out, "Removed credentials of the %s backend from the %s credential store.\n", backendType, kind)
+	return nil
+}
+
+// loginBackend returns the backend credentials are stored for: the one
+// given, or the one in use.
+func loginBackend(args []string) (backend.BackendType, loginFields, error) {
+	backendType := backend.GetBackendType()
+	if len(args) > 0 {
+		backendType = backend.BackendType(args[0])
+	}
+
+	fields, ok := loginBackends[backendType]
+	if !ok {
+		return "", loginFields{}, fmt.Errorf("the %s backend does not read stored credentials: use s3, http, ftp or artifactory", backendType)
+	}
+
+	return backendType, fields, nil
+}
+
+func credentialStoreKind() string {
+	if kind := backend.GetCredentialStore(); kind != "" {
+		return kind
+	}
+
+	return backend.CredentialStoreKeychain
+}
+
+func promptLine(out io.Writer, in io.Reader, prompt string) (string, error) {
+	fmt.Fprintf(out, "%s: ", prompt)
+	return readLine(in)
+}
+
+// promptSecret reads a secret from the terminal without echoing it.
+func promptSecret(out io.Writer, prompt string) (string, error) {
+	fmt.Fprintf(out, "%s: ", prompt)
+	secret, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(out)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(secret)), nil
+}
+
+func readLine(in io.Reader) (string, error) {
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+
+	return strings.TrimSpace(line), nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewLoginCmd())
+	rootCmd.AddCommand(NewLogoutCmd())
+}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func Test__Login(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("ARTIFACT_CREDENTIAL_STORE", "")
+
+	configFile := filepath.Join(t.TempDir(), ".artifact.yaml")
+	cfgFile = configFile
+	defer func() {
+		cfgFile = ""
+		viper.Reset()
+	}()
+
+	login := func(stdin string, args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewLoginCmd()
+		cmd.SetOut(out)
+		cmd.SetIn(strings.NewReader(stdin))
+		cmd.SetArgs(args)
+		cmd.Execute()
+		return out.String()
+	}
+
+	logout := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewLogoutCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(args)
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("stores credentials in the keychain", func(t *testing.T) {
+		output := login("secret\n", "s3", "--access-key-id", "AKIA", "--secret-stdin")
+		assert.Contains(t, output, "Stored credentials of the s3 backend in the keychain credential store.\n")
+
+		data, err := keyring.Get("artifact", "s3")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"accessKeyId": "AKIA", "secretAccessKey": "secret"}`, data)
+
+		config, err := os.ReadFile(configFile)
+		require.NoError(t, err)
+		assert.Contains(t, string(config), "credentialStore: keychain\n")
+	})
+
+	t.Run("stores a token", func(t *testing.T) {
+		login("token\n", "artifactory", "--secret-stdin")
+
+		data, err := keyring.Get("artifact", "artifactory")
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"token": "token"}`, data)
+	})
+
+	t.Run("requires the identifier", func(t *testing.T) {
+		login("secret\n", "ftp", "--secret-stdin")
+
+		_, err := keyring.Get("artifact", "ftp")
+		assert.ErrorIs(t, err, keyring.ErrNotFound)
+	})
+
+	t.Run("rejects backends without stored credentials", func(t *testing.T) {
+		output := login("token\n", "hub", "--secret-stdin")
+		assert.NotContains(t, output, "Stored credentials")
+	})
+
+	t.Run("stores credentials in an encrypted file", func(t *testing.T) {
+		credentialFile := filepath.Join(t.TempDir(), "credentials")
+		t.Setenv("ARTIFACT_CREDENTIAL_FILE", credentialFile)
+		t.Setenv("ARTIFACT_CREDENTIAL_PASSPHRASE", "correct horse")
+
+		output := login("token\n", "http", "--secret-stdin", "--store", "file")
+		assert.Contains(t, output, "Stored credentials of the http backend in the file credential store.\n")
+
+		data, err := os.ReadFile(credentialFile)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "token")
+	})
+
+	t.Run("logout removes credentials", func(t *testing.T) {
+		t.Setenv("ARTIFACT_CREDENTIAL_STORE", "keychain")
+
+		output := logout("s3")
+		assert.Equal(t, "Removed credentials of the s3 backend from the keychain credential store.\n", output)
+
+		_, err := keyring.Get("artifact", "s3")
+		assert.ErrorIs(t, err, keyring.ErrNotFound)
+	})
+}
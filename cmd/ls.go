@@ -0,0 +1,441 @@
+package cmd
+
+import (
+	"container/heap"
+	"context"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	lsSortName = "name"
+	lsSortSize = "size"
+	lsSortTime = "time"
+
+	lsTimeFormat = "2006-01-02 15:04:05"
+)
+
+var lsColumns = []string{"size", "time", "etag", "name"}
+
+// lsOptions holds the parsed sorting, filtering and formatting flags of ls.
+type lsOptions struct {
+	Sort          string
+	Reverse       bool
+	Match         string
+	MinSize       int64
+	OlderThan     time.Duration
+	NewerThan     time.Duration
+	Columns       []string
+	HumanReadable bool
+	Limit         int
+	Dirs          bool
+	Now           time.Time
+}
+
+// lsEntry is a listed object along with its path relative to the artifact store.
+type lsEntry struct {
+	Name string
+	Info backend.ObjectInfo
+}
+
+func NewLsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "ls",
+		Short: "Lists files stored in the storage",
+		Long: `Lists the files stored for a project, workflow or job, optionally
+limited to a directory. Results can be filtered, sorted and formatted.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Lists %s files in the storage.", cobra.MaximumNArgs(1), addLsFlags, runLsForCategory)
+	return cmd
+}
+
+func addLsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("sort", lsSortName, "sort by name, size or time")
+	cmd.Flags().BoolP("reverse", "r", false, "reverse the sort order")
+	cmd.Flags().String("match", "", "only list files matching the glob pattern, e.g. '**/*.log'")
+	cmd.Flags().String("min-size", "", "only list files of at least this size, e.g. 10MB")
+	cmd.Flags().String("older-than", "", "only list files older than the given age, e.g. 30d")
+	cmd.Flags().String("newer-than", "", "only list files newer than the given age, e.g. 12h")
+	cmd.Flags().String("columns", "size,time,name", "comma separated columns to print: "+strings.Join(lsColumns, ", "))
+	cmd.Flags().BoolP("human-readable", "H", false, "print sizes in human readable format")
+	cmd.Flags().Int("limit", 0, "stop after printing this many files (0 means no limit)")
+	cmd.Flags().Bool("dirs", false, "only list the files and directories directly under PATH, with the total size of each directory")
+}
+
+func runLsForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	opts, err := parseLsOptions(cmd)
+	errutil.Check(err)
+
+	remotePath := resolver.PrefixedPath("")
+	if len(args) > 0 {
+		remotePath = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	err = listObjects(getContext(), lister, remotePath, resolver.PrefixedPath(""), opts, cmd.OutOrStdout())
+	if err != nil {
+		log.Errorf("Error listing artifacts: %v\n", err)
+		errutil.Exit(1)
+	}
+}
+
+func parseLsOptions(cmd *cobra.Command) (*lsOptions, error) {
+	opts := &lsOptions{Now: time.Now()}
+
+	opts.Sort, _ = cmd.Flags().GetString("sort")
+	if opts.Sort != lsSortName && opts.Sort != lsSortSize && opts.Sort != lsSortTime {
+		return nil, fmt.Errorf("invalid --sort '%s': use name, size or time", opts.Sort)
+	}
+
+	opts.Reverse, _ = cmd.Flags().GetBool("reverse")
+	opts.Dirs, _ = cmd.Flags().GetBool("dirs")
+	opts.HumanReadable, _ = cmd.Flags().GetBool("human-readable")
+
+	opts.Limit, _ = cmd.Flags().GetInt("limit")
+	if opts.Limit < 0 {
+		return nil, fmt.Errorf("invalid --limit %d: must not be negative", opts.Limit)
+	}
+
+	opts.Match, _ = cmd.Flags().GetString("match")
+	if err := files.ValidateGlob(opts.Match); err != nil {
+		return nil, fmt.Errorf("invalid --match '%s': %v", opts.Match, err)
+	}
+
+	if minSize, _ := cmd.Flags().GetString("min-size"); minSize != "" {
+		size, err := common.ParseSize(minSize)
+		if err != nil {
+			return nil, err
+		}
+		opts.MinSize = size
+	}
+
+	if olderThan, _ := cmd.Flags().GetString("older-than"); olderThan != "" {
+		age, err := common.ParseAge(olderThan)
+		if err != nil {
+			return nil, err
+		}
+		opts.OlderThan = age
+	}
+
+	if newerThan, _ := cmd.Flags().GetString("newer-than"); newerThan != "" {
+		age, err := common.ParseAge(newerThan)
+		if err != nil {
+			return nil, err
+		}
+		opts.NewerThan = age
+	}
+
+	columns, _ := cmd.Flags().GetString("columns")
+	for _, column := range strings.Split(columns, ",") {
+		column = strings.TrimSpace(column)
+		if !containsString(lsColumns, column) {
+			return nil, fmt.Errorf("invalid column '%s': use %s", column, strings.Join(lsColumns, ", "))
+		}
+		opts.Columns = append(opts.Columns, column)
+	}
+
+	return opts, nil
+}
+
+// listObjects prints the objects stored under remotePath, with names relative to root.
+// Listings sorted by name are printed as they arrive from the backend,
+// since backends list objects in key order, and stop as soon as the limit is hit.
+// Other orderings need the full listing: with a limit, only the best
+// entries seen so far are kept, otherwise every matching entry is.
+// With Dirs, names are relative to remotePath instead, and the files of
+// each directory under it are listed as one entry (see groupDirs).
+func listObjects(ctx context.Context, lister backend.Lister, remotePath, root string, opts *lsOptions, out io.Writer) error {
+	streaming := opts.Sort == lsSortName && !opts.Reverse
+	entries := &lsEntryHeap{opts: opts}
+	printed := 0
+
+	emit := func(entry lsEntry) error {
+		if !streaming {
+			entries.add(entry)
+			return nil
+		}
+
+		if opts.Limit > 0 && printed >= opts.Limit {
+			return backend.StopListing
+		}
+
+		if _, err := fmt.Fprintln(out, opts.format(entry)); err != nil {
+			return err
+		}
+
+		printed++
+		if opts.Limit > 0 && printed >= opts.Limit {
+			return backend.StopListing
+		}
+
+		return nil
+	}
+
+	name := func(obj backend.ObjectInfo) string {
+		return relativeName(obj.Path, root)
+	}
+	add, flush := emit, func() error { return nil }
+
+	if opts.Dirs {
+		dir := strings.TrimSuffix(remotePath, "/")
+		name = func(obj backend.ObjectInfo) string {
+			if obj.Path == dir {
+				return path.Base(obj.Path)
+			}
+			return relativeName(obj.Path, dir)
+		}
+		add, flush = groupDirs(dir, emit)
+	}
+
+	err := walkRemote(ctx, lister, remotePath, func(obj backend.ObjectInfo) error {
+		entry := lsEntry{Name: name(obj), Info: obj}
+		if !opts.matches(entry) {
+			return nil
+		}
+
+		return add(entry)
+	})
+
+	if err == nil {
+		err = flush()
+	}
+	if err == backend.StopListing {
+		err = nil
+	}
+	if err != nil || streaming {
+		return err
+	}
+
+	for _, entry := range entries.sorted() {
+		if _, err := fmt.Fprintln(out, opts.format(entry)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupDirs returns an add function passing the entries of files directly
+// under dir on to emit, and gathering the files of each directory under it
+// into one entry with their total size and latest modification time.
+// Backends list objects in key order, so the files of a directory arrive
+// one after the other, and its entry is emitted as soon as the listing
+// moves past it, or by the returned flush function for the last one.
+func groupDirs(dir string, emit func(lsEntry) error) (func(lsEntry) error, func() error) {
+	var current *lsEntry
+
+	flush := func() error {
+		if current == nil {
+			return nil
+		}
+
+		entry := *current
+		current = nil
+		return emit(entry)
+	}
+
+	add := func(entry lsEntry) error {
+		first, _, inSubdir := strings.Cut(entry.Name, "/")
+		if current != nil && (!inSubdir || current.Name != first+"/") {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+
+		if !inSubdir {
+			return emit(entry)
+		}
+
+		if current == nil {
+			current = &lsEntry{Name: first + "/", Info: backend.ObjectInfo{Path: dir + "/" + first}}
+		}
+
+		current.Info.Size += entry.Info.Size
+		if entry.Info.ModTime.After(current.Info.ModTime) {
+			current.Info.ModTime = entry.Info.ModTime
+		}
+
+		return nil
+	}
+
+	return add, flush
+}
+
+// lsEntryHeap collects entries for sorted output. When a limit is set,
+// it is a max-heap holding at most opts.Limit entries, so memory stays
+// bounded no matter how large the listing is.
+type lsEntryHeap struct {
+	opts    *lsOptions
+	entries []lsEntry
+}
+
+func (h *lsEntryHeap) Len() int           { return len(h.entries) }
+func (h *lsEntryHeap) Less(i, j int) bool { return h.opts.less(h.entries[j], h.entries[i]) }
+func (h *lsEntryHeap) Swap(i, j int)      { h.entries[i], h.entries[j] = h.entries[j], h.entries[i] }
+func (h *lsEntryHeap) Push(x interface{}) { h.entries = append(h.entries, x.(lsEntry)) }
+
+func (h *lsEntryHeap) Pop() interface{} {
+	last := h.entries[len(h.entries)-1]
+	h.entries = h.entries[:len(h.entries)-1]
+	return last
+}
+
+func (h *lsEntryHeap) add(entry lsEntry) {
+	if h.opts.Limit == 0 {
+		h.entries = append(h.entries, entry)
+		return
+	}
+
+	if len(h.entries) < h.opts.Limit {
+		heap.Push(h, entry)
+		return
+	}
+
+	// The root is the worst entry kept so far.
+	if h.opts.less(entry, h.entries[0]) {
+		h.entries[0] = entry
+		heap.Fix(h, 0)
+	}
+}
+
+func (h *lsEntryHeap) sorted() []lsEntry {
+	sort.SliceStable(h.entries, func(i, j int) bool {
+		return h.opts.less(h.entries[i], h.entries[j])
+	})
+
+	return h.entries
+}
+
+func (o *lsOptions) matches(entry lsEntry) bool {
+	if o.Match != "" {
+		if matched, _ := files.MatchGlob(o.Match, entry.Name); !matched {
+			return false
+		}
+	}
+
+	if entry.Info.Size < o.MinSize {
+		return false
+	}
+
+	age := o.Now.Sub(entry.Info.ModTime)
+	if o.OlderThan > 0 && age < o.OlderThan {
+		return false
+	}
+
+	if o.NewerThan > 0 && age > o.NewerThan {
+		return false
+	}
+
+	return true
+}
+
+func (o *lsOptions) less(a, b lsEntry) bool {
+	if o.Reverse {
+		a, b = b, a
+	}
+
+	switch o.Sort {
+	case lsSortSize:
+		if a.Info.Size != b.Info.Size {
+			return a.Info.Size < b.Info.Size
+		}
+	case lsSortTime:
+		if !a.Info.ModTime.Equal(b.Info.ModTime) {
+			return a.Info.ModTime.Before(b.Info.ModTime)
+		}
+	}
+
+	return a.Name < b.Name
+}
+
+// format renders an entry using fixed column widths, so output
+// stays aligned without buffering the listing.
+func (o *lsOptions) format(entry lsEntry) string {
+	fields := []string{}
+
+	for _, column := range o.Columns {
+		switch column {
+		case "size":
+			if o.HumanReadable {
+				fields = append(fields, fmt.Sprintf("%9s", formatBytes(entry.Info.Size)))
+			} else {
+				fields = append(fields, fmt.Sprintf("%12d", entry.Info.Size))
+			}
+		case "time":
+			fields = append(fields, entry.Info.ModTime.Local().Format(lsTimeFormat))
+		case "etag":
+			fields = append(fields, fmt.Sprintf("%-32s", entry.Info.ETag))
+		case "name":
+			fields = append(fields, entry.Name)
+		}
+	}
+
+	return strings.Join(fields, "  ")
+}
+
+// getLister returns the backend's listing capability, if it has one.
+func getLister(b backend.Backend) (backend.Lister, error) {
+	lister, ok := b.(backend.Lister)
+	if !ok || !backend.CapabilitiesOf(b).List {
+		return nil, backend.ErrListingNotSupported
+	}
+
+	return lister, nil
+}
+
+// walkRemote calls fn for the object at remotePath, or for every object
+// under it if remotePath is a directory, streaming pages from the backend. Objects that merely share a name
+// prefix with remotePath (e.g. "logs" and "logs-old") are skipped.
+func walkRemote(ctx context.Context, lister backend.Lister, remotePath string, fn func(backend.ObjectInfo) error) error {
+	remotePath = strings.TrimSuffix(remotePath, "/")
+
+	err := lister.List(ctx, remotePath, func(obj backend.ObjectInfo) error {
+		if obj.Path != remotePath && !strings.HasPrefix(obj.Path, remotePath+"/") {
+			return nil
+		}
+
+		return fn(obj)
+	})
+
+	if err == backend.StopListing {
+		return nil
+	}
+
+	return err
+}
+
+// relativeName returns remotePath relative to the root of its artifact store.
+func relativeName(remotePath, root string) string {
+	return strings.TrimPrefix(strings.TrimPrefix(remotePath, root), "/")
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
+
+func init() {
+	rootCmd.AddCommand(NewLsCmd())
+}
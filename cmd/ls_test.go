@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Ls(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/a.txt", Contents: "aaaaa"},
+		{Name: "artifacts/jobs/1/logs/b.log", Contents: "b"},
+		{Name: "artifacts/jobs/1/logs/c.log", Contents: "ccc"},
+		{Name: "artifacts/jobs/12/other.txt", Contents: "other"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) []string {
+		out := &bytes.Buffer{}
+		cmd := NewLsCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+		return strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+	}
+
+	t.Run("lists the whole store by name", func(t *testing.T) {
+		assert.Equal(t, []string{"a.txt", "logs/b.log", "logs/c.log"}, run("--columns", "name"))
+	})
+
+	t.Run("lists a directory", func(t *testing.T) {
+		assert.Equal(t, []string{"logs/b.log", "logs/c.log"}, run("logs", "--columns", "name"))
+	})
+
+	t.Run("sorts by size", func(t *testing.T) {
+		assert.Equal(t, []string{"logs/b.log", "logs/c.log", "a.txt"}, run("--sort", "size", "--columns", "name"))
+		assert.Equal(t, []string{"a.txt", "logs/c.log", "logs/b.log"}, run("--sort", "size", "-r", "--columns", "name"))
+	})
+
+	t.Run("filters by glob and size", func(t *testing.T) {
+		assert.Equal(t, []string{"logs/b.log", "logs/c.log"}, run("--match", "**/*.log", "--columns", "name"))
+		assert.Equal(t, []string{"a.txt", "logs/c.log"}, run("--min-size", "3", "--columns", "name"))
+	})
+
+	t.Run("limits results", func(t *testing.T) {
+		assert.Equal(t, []string{"a.txt", "logs/b.log"}, run("--limit", "2", "--columns", "name"))
+		assert.Equal(t, []string{"a.txt", "logs/c.log"}, run("--limit", "2", "--sort", "size", "-r", "--columns", "name"))
+		assert.Equal(t, []string{"logs/b.log"}, run("--limit", "1", "--sort", "size", "--columns", "name"))
+	})
+
+	t.Run("formats selected columns", func(t *testing.T) {
+		assert.Equal(t, []string{"           5  a.txt"}, run("--match", "a.txt", "--columns", "size,name"))
+	})
+}
+
+func Test__LsEntryHeap_KeepsOnlyLimit(t *testing.T) {
+	opts := &lsOptions{Sort: lsSortSize, Limit: 3}
+	entries := &lsEntryHeap{opts: opts}
+
+	for _, size := range []int64{7, 3, 9, 1, 8, 2, 6} {
+		entries.add(lsEntry{Name: "file", Info: backend.ObjectInfo{Size: size}})
+		assert.LessOrEqual(t, entries.Len(), 3)
+	}
+
+	sizes := []int64{}
+	for _, entry := range entries.sorted() {
+		sizes = append(sizes, entry.Info.Size)
+	}
+
+	assert.Equal(t, []int64{1, 2, 3}, sizes)
+}
+
+func Test__LsOptions(t *testing.T) {
+	now := time.Now()
+	opts := &lsOptions{Now: now, OlderThan: 24 * time.Hour, Columns: []string{"size", "name"}, HumanReadable: true}
+
+	old := lsEntry{Name: "old.txt", Info: backend.ObjectInfo{Size: 2048, ModTime: now.Add(-48 * time.Hour)}}
+	recent := lsEntry{Name: "new.txt", Info: backend.ObjectInfo{Size: 2048, ModTime: now.Add(-time.Hour)}}
+
+	assert.True(t, opts.matches(old))
+	assert.False(t, opts.matches(recent))
+	assert.Equal(t, "   2.0 KB  old.txt", opts.format(old))
+
+	opts = &lsOptions{Now: now, NewerThan: 24 * time.Hour, Sort: lsSortTime}
+	assert.False(t, opts.matches(old))
+	assert.True(t, opts.matches(recent))
+	assert.True(t, opts.less(old, recent))
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewManifestCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "manifest",
+		Short: "Shows and checks the manifests of pushed directories",
+		Long: `Directories pushed with --manifest are stored with a manifest listing the
+path, size and SHA256 checksum of every file, and the metadata they were
+pushed with:
+
+  artifact push job build/ --manifest
+  artifact pull job build
+  artifact manifest verify job build
+
+Verifying against the manifest reads a single file from the backend,
+instead of listing the directory and reading the checksum of every file.`,
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Prints the manifest of a pushed directory.",
+	}
+	addCategoryCmds(showCmd, "PATH", "Prints the manifest of a %s directory.", cobra.ExactArgs(1), addManifestShowFlags, runManifestShowForCategory)
+	cmd.AddCommand(showCmd)
+
+	verifyCmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verifies a pulled directory against its manifest.",
+	}
+	addCategoryCmds(verifyCmd, "PATH", "Verifies a directory pulled from the %s storage against its manifest.", cobra.ExactArgs(1), addVerifyFlags, runManifestVerifyForCategory)
+	cmd.AddCommand(verifyCmd)
+
+	return cmd
+}
+
+func addManifestShowFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "text", "output format: text or json")
+}
+
+func runManifestShowForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" {
+		errutil.Check(fmt.Errorf("invalid --output '%s': use text or json", output))
+		return
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	m, err := loadManifest(getContext(), b, resolver.PrefixedPath(files.ToRelative(args[0])))
+	if err != nil {
+		log.Errorf("Error reading manifest: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	errutil.Check(writeManifest(cmd.OutOrStdout(), m, output))
+}
+
+func runManifestVerifyForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	destination, _ := cmd.Flags().GetString("destination")
+	paths := resolver.Pull(args[0], destination)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	m, err := loadManifest(getContext(), b, paths.Source)
+	if err != nil {
+		log.Errorf("Error reading manifest: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	manifestResults, err := m.Verify(paths.Destination)
+	if err != nil {
+		log.Errorf("Error verifying '%s': %v\n", paths.Destination, err)
+		errutil.Exit(1)
+		return
+	}
+
+	results := []verifyResult{}
+	for _, result := range manifestResults {
+		results = append(results, verifyResult{Path: result.Path, Status: result.Status})
+	}
+
+	failed, err := writeVerifyResults(cmd.OutOrStdout(), results)
+	errutil.Check(err)
+
+	if failed > 0 {
+		log.Errorf("%d of %d %s failed verification.\n", failed, len(results), pluralize(len(results), "file", "files"))
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Verified %d %s in '%s'.\n", len(results), pluralize(len(results), "file", "files"), paths.Destination)
+}
+
+// loadManifest reads the manifest stored for the remote directory remoteDir.
+func loadManifest(ctx context.Context, b backend.Backend, remoteDir string) (*manifest.Manifest, error) {
+	r, err := openRemote(ctx, b, manifest.Path(remoteDir))
+	if isNotFound(err) {
+		return nil, fmt.Errorf("'%s' has no manifest; push it with --manifest", remoteDir)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return manifest.Parse(data)
+}
+
+// writeManifest prints the files of the manifest in the given output
+// format; as text, one per line like sha256sum, with their size.
+func writeManifest(out io.Writer, m *manifest.Manifest, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(m)
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, entry := range m.Files {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", entry.SHA256, formatBytes(entry.Size), entry.Path); err != nil {
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	log.Infof("%d %s, %s, pushed at %s.\n", len(m.Files), pluralize(len(m.Files), "file", "files"), formatBytes(m.TotalSize()), m.Created.Format("2006-01-02 15:04:05 MST"))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewManifestCmd())
+}
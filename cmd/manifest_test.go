@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Manifest(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	local := filepath.Join(t.TempDir(), "build")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "app.zip"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "bin", "app"), []byte("binary"), 0644))
+
+	push := NewPushJobCmd()
+	push.SetArgs([]string{local, "--destination", "build", "--manifest", "--metadata", "commit=abc"})
+	require.NoError(t, push.Execute())
+
+	run := func(cmd string, args ...string) string {
+		out := &bytes.Buffer{}
+		c := NewManifestCmd()
+		c.SetOut(out)
+		c.SetArgs(append([]string{cmd, "job"}, args...))
+		c.Execute()
+		return out.String()
+	}
+
+	t.Run("the manifest is hidden from listings", func(t *testing.T) {
+		b := getBackend()
+		defer b.Close()
+
+		lister, err := getLister(b)
+		require.NoError(t, err)
+
+		names, err := listChildren(getContext(), lister, "artifacts/jobs/1/")
+		require.NoError(t, err)
+		assert.Equal(t, []string{"build/"}, names)
+	})
+
+	t.Run("shows the manifest", func(t *testing.T) {
+		m := manifest.Manifest{}
+		require.NoError(t, json.Unmarshal([]byte(run("show", "build", "-o", "json")), &m))
+		require.Len(t, m.Files, 2)
+		assert.Equal(t, "bin/app", m.Files[1].Path)
+		assert.Equal(t, int64(6), m.Files[1].Size)
+		assert.Equal(t, "abc", m.Metadata["commit"])
+
+		assert.Contains(t, run("show", "build"), "a172cedcae47474b615c54d510a5d84a8dea3032e958587430b413538be3f333  3 B  app.zip\n")
+		assert.Empty(t, run("show", "missing"))
+	})
+
+	t.Run("verifies a pulled directory", func(t *testing.T) {
+		assert.Empty(t, run("verify", "build", "--destination", local))
+
+		require.NoError(t, os.WriteFile(filepath.Join(local, "bin", "app"), []byte("tampered"), 0644))
+		assert.Equal(t, "mismatch    bin/app\n", run("verify", "build", "--destination", local))
+	})
+
+	t.Run("needs a directory", func(t *testing.T) {
+		push := NewPushJobCmd()
+		require.NoError(t, push.ParseFlags([]string{"--manifest"}))
+
+		resolver, err := files.NewPathResolver(files.ResourceTypeJob, "")
+		require.NoError(t, err)
+
+		_, _, err = runPushForCategory(push, []string{filepath.Join(local, "app.zip")}, resolver)
+		assert.ErrorContains(t, err, "--manifest needs a directory")
+	})
+}
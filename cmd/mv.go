@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewMvCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mv",
+		Short: "Renames a file or directory in the storage",
+		Long: `Moves a file or directory stored for a job, workflow or project to a new
+path in the same store. The files are copied to the new path and removed
+from the old one; backends that can copy files in place, such as S3, do
+so without downloading them.`,
+	}
+
+	addCategoryCmds(cmd, "SOURCE DESTINATION", "Renames a %s file or directory in the storage.", cobra.ExactArgs(2), addMvFlags, runMvForCategory)
+	return cmd
+}
+
+func addMvFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+}
+
+func runMvForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	force, _ := cmd.Flags().GetBool("force")
+
+	srcPath := resolver.PrefixedPath(files.ToRelative(args[0]))
+	dstPath := resolver.PrefixedPath(files.ToRelative(args[1]))
+	if srcPath == dstPath {
+		errutil.Check(fmt.Errorf("'%s' cannot be moved onto itself", srcPath))
+		return
+	}
+
+	// Moving is pushing to the new path and yanking the old one
+	yank := policyRequest(policy.OperationYank, resolver, srcPath)
+	yank.Dir = true
+	errutil.Check(checkPolicy(policyRequest(policy.OperationPush, resolver, dstPath)))
+	errutil.Check(checkPolicy(yank))
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	err := moveRemote(getContext(), b, srcPath, dstPath, backend.PushOptions{Force: force})
+	if err != nil {
+		log.Errorf("Error moving artifact: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Successfully moved '%s' to '%s'.\n", args[0], args[1])
+}
+
+// moveRemote copies srcPath to dstPath and yanks srcPath. If the yank fails,
+// the files are left at both paths and the error says so.
+func moveRemote(ctx context.Context, b backend.Backend, srcPath, dstPath string, opts backend.PushOptions) error {
+	if err := backend.Copy(ctx, b, srcPath, dstPath, opts); err != nil {
+		return err
+	}
+
+	if err := b.Yank(ctx, srcPath); err != nil {
+		return fmt.Errorf("copied to '%s', but failed to remove '%s': %w", dstPath, srcPath, err)
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewMvCmd())
+}
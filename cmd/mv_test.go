@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__MoveRemote(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/old/a.txt", []byte("a"))
+	memory.Put("artifacts/jobs/1/old/b/c.txt", []byte("c"))
+	memory.Put("artifacts/jobs/1/taken.txt", []byte("taken"))
+
+	require.NoError(t, moveRemote(ctx, memory, "artifacts/jobs/1/old", "artifacts/jobs/1/new", backend.PushOptions{}))
+	assert.Equal(t, []string{
+		"artifacts/jobs/1/new/a.txt",
+		"artifacts/jobs/1/new/b/c.txt",
+		"artifacts/jobs/1/taken.txt",
+	}, memory.Paths())
+
+	// Existing files are only overwritten with force, like push
+	var alreadyExists *backend.ErrAlreadyExists
+	err := moveRemote(ctx, memory, "artifacts/jobs/1/new/a.txt", "artifacts/jobs/1/taken.txt", backend.PushOptions{})
+	assert.ErrorAs(t, err, &alreadyExists)
+	assert.Len(t, memory.Paths(), 3)
+
+	require.NoError(t, moveRemote(ctx, memory, "artifacts/jobs/1/new/a.txt", "artifacts/jobs/1/taken.txt", backend.PushOptions{Force: true}))
+	data, _ := memory.Get("artifacts/jobs/1/taken.txt")
+	assert.Equal(t, "a", string(data))
+
+	var notFound *backend.ErrNotFound
+	err = moveRemote(ctx, memory, "artifacts/jobs/1/missing", "artifacts/jobs/1/other", backend.PushOptions{})
+	assert.ErrorAs(t, err, &notFound)
+}
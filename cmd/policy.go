@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+)
+
+var policyFile string
+
+// getPolicy loads the policy from --policy-file if given, and from the config file otherwise.
+func getPolicy() (*policy.Policy, error) {
+	if policyFile != "" {
+		return policy.LoadFile(policyFile)
+	}
+
+	return policy.Load()
+}
+
+// policyRequest describes an operation on remotePath for policy evaluation.
+func policyRequest(operation string, resolver *files.PathResolver, remotePath string) policy.Request {
+	return policy.Request{
+		Operation: operation,
+		Category:  resolver.ResourceType,
+		Path:      relativeName(remotePath, resolver.PrefixedPath("")),
+		Size:      -1,
+	}
+}
+
+// checkPolicy loads the policy and evaluates req against it.
+func checkPolicy(req policy.Request) error {
+	p, err := getPolicy()
+	if err != nil {
+		return err
+	}
+
+	return p.Evaluate(req)
+}
+
+// policySizeLimiter fails reads once more than limit bytes were read,
+// enforcing size limits on streams whose size is not known upfront.
+type policySizeLimiter struct {
+	r     io.Reader
+	req   policy.Request
+	limit int64
+	read  int64
+}
+
+func (l *policySizeLimiter) Read(p []byte) (int, error) {
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n, fmt.Errorf("%s of '%s' denied by policy: more than %s", l.req.Operation, l.req.Path, formatBytes(l.limit))
+	}
+
+	return n, err
+}
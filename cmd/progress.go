@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"context"
+	"os"
+
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	"github.com/spf13/cobra"
+)
+
+func addProgressFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-progress", false, "show no progress bars, nor log the progress of long transfers")
+}
+
+// startProgress starts reporting the progress of the transfer run with
+// the returned context on stderr: with bars on a terminal, and with a log
+// line every few seconds otherwise. The tracker is nil with --no-progress,
+// and must be stopped once the transfer ends.
+func startProgress(ctx context.Context, cmd *cobra.Command, verb string) (context.Context, *progress.Tracker) {
+	noProgress, err := cmd.Flags().GetBool("no-progress")
+	errutil.Check(err)
+
+	if noProgress {
+		return ctx, nil
+	}
+
+	tracker := progress.New(verb, os.Stderr)
+	tracker.Start()
+	return progress.NewContext(ctx, tracker), tracker
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// promotionMetadata maps the metadata keys set on promoted files to the
+// environment variables they are read from.
+var promotionMetadata = []struct{ key, env string }{
+	{"git-sha", "SEMAPHORE_GIT_SHA"},
+	{"git-branch", "SEMAPHORE_GIT_BRANCH"},
+	{"pipeline-id", "SEMAPHORE_PIPELINE_ID"},
+	{"workflow-id", "SEMAPHORE_WORKFLOW_ID"},
+	{"job-id", "SEMAPHORE_JOB_ID"},
+}
+
+// scopeRank orders the stores from the shortest-lived to the longest-lived.
+var scopeRank = map[string]int{
+	files.ResourceTypeJob:      0,
+	files.ResourceTypeWorkflow: 1,
+	files.ResourceTypeProject:  2,
+}
+
+func NewPromoteCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "promote",
+		Short: "Promotes a file or directory to a longer-lived store",
+		Long: `Copies a file or directory from a job or workflow store to the workflow or
+project store given with --to, so it outlives the store it was built in:
+
+  artifact promote job dist/app.zip --to project --destination releases/v1.2.3/app.zip
+
+The promoted files get metadata describing where they come from: the
+commit SHA, branch, pipeline, workflow and job ids found in the
+environment, the source path and the time of the promotion. Use --yank-source
+to remove the source afterwards, and the lock flags to retain the promoted
+files with S3 Object Lock.
+
+A promotion either succeeds as a whole or is rolled back: if the copy or
+the removal of the source fails, the files copied so far are removed.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Promotes a %s file or directory to a longer-lived store.", cobra.ExactArgs(1), addPromoteFlags, runPromoteForCategory)
+	return cmd
+}
+
+func addPromoteFlags(cmd *cobra.Command) {
+	cmd.Flags().String("to", "", "store to promote to: workflow or project")
+	cmd.Flags().String("to-id", "", "set explicit id of the store to promote to")
+	cmd.Flags().StringP("destination", "d", "", "path in the store to promote to; defaults to PATH")
+	cmd.Flags().BoolP("force", "f", false, "overwrite existing files; they cannot be restored by a rollback")
+	cmd.Flags().Bool("yank-source", false, "remove the source once it is promoted")
+	addPushMetadataFlags(cmd)
+	addPushLockFlags(cmd)
+	_ = cmd.MarkFlagRequired("to")
+}
+
+func runPromoteForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	to, _ := cmd.Flags().GetString("to")
+	toID, _ := cmd.Flags().GetString("to-id")
+	destination, _ := cmd.Flags().GetString("destination")
+	force, _ := cmd.Flags().GetBool("force")
+	yankSource, _ := cmd.Flags().GetBool("yank-source")
+
+	target, err := files.NewPathResolver(to, toID)
+	errutil.Check(err)
+
+	if scopeRank[target.ResourceType] <= scopeRank[resolver.ResourceType] {
+		errutil.Check(fmt.Errorf("a %s artifact can only be promoted to a longer-lived store, not to the %s store", resolver.ResourceType, target.ResourceType))
+		return
+	}
+
+	lock, err := parseObjectLock(cmd)
+	errutil.Check(err)
+
+	extra, err := parsePushMetadata(cmd)
+	errutil.Check(err)
+
+	name := files.ToRelative(args[0])
+	if destination == "" {
+		destination = name
+	}
+
+	srcPath := resolver.PrefixedPath(name)
+	dstPath := target.PrefixedPath(files.ToRelative(destination))
+	metadata := promotionMetadataFor(srcPath, extra)
+
+	push := policyRequest(policy.OperationPush, target, dstPath)
+	push.Metadata = metadata
+	errutil.Check(checkPolicy(push))
+
+	if yankSource {
+		yank := policyRequest(policy.OperationYank, resolver, srcPath)
+		yank.Dir = true
+		errutil.Check(checkPolicy(yank))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	err = promote(getContext(), b, srcPath, dstPath, backend.PushOptions{Force: force, Lock: lock, Metadata: metadata}, yankSource)
+	if err != nil {
+		log.Errorf("Error promoting artifact: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Successfully promoted artifact to the %s store.\n", target.ResourceType)
+	log.Infof("* Source: %s.\n", srcPath)
+	log.Infof("* Destination: %s.\n", dstPath)
+	if yankSource {
+		log.Infof("Removed '%s'.\n", srcPath)
+	}
+}
+
+// promotionMetadataFor returns the metadata of files promoted from srcPath:
+// where they come from, overridden by the metadata given with --metadata.
+func promotionMetadataFor(srcPath string, extra map[string]string) map[string]string {
+	metadata := map[string]string{
+		"promoted-from": srcPath,
+		"promoted-at":   time.Now().UTC().Format(time.RFC3339),
+	}
+
+	for _, m := range promotionMetadata {
+		if value := os.Getenv(m.env); value != "" {
+			metadata[m.key] = value
+		}
+	}
+
+	for key, value := range extra {
+		metadata[key] = value
+	}
+
+	return metadata
+}
+
+// promote copies srcPath to dstPath and, with yankSource, removes srcPath.
+// If either step fails, the copied files are removed again, unless they
+// replaced existing ones, which cannot be restored.
+func promote(ctx context.Context, b backend.Backend, srcPath, dstPath string, opts backend.PushOptions, yankSource bool) error {
+	existed, err := remoteExists(ctx, b, dstPath)
+	if err != nil {
+		return err
+	}
+
+	if existed && !opts.Force {
+		return &backend.ErrAlreadyExists{Path: dstPath}
+	}
+
+	err = backend.Copy(ctx, b, srcPath, dstPath, opts)
+	if isNotFound(err) {
+		return err
+	}
+	if err != nil {
+		return rollbackPromotion(ctx, b, dstPath, existed, err)
+	}
+
+	if yankSource {
+		if err := b.Yank(ctx, srcPath); err != nil {
+			return rollbackPromotion(ctx, b, dstPath, existed, fmt.Errorf("failed to remove '%s': %w", srcPath, err))
+		}
+	}
+
+	return nil
+}
+
+// rollbackPromotion removes the files copied to dstPath after a failed
+// promotion, and returns cause along with the outcome of the rollback.
+func rollbackPromotion(ctx context.Context, b backend.Backend, dstPath string, existed bool, cause error) error {
+	if existed {
+		return fmt.Errorf("%w; '%s' was overwritten and cannot be rolled back", cause, dstPath)
+	}
+
+	if err := b.Yank(ctx, dstPath); err != nil && !isNotFound(err) {
+		return fmt.Errorf("%w; rolling back failed too, remove '%s' with 'artifact yank': %v", cause, dstPath, err)
+	}
+
+	return fmt.Errorf("%w; the promotion was rolled back", cause)
+}
+
+// remoteExists returns true if remotePath is a stored file, or a directory
+// with files in it if the backend can list.
+func remoteExists(ctx context.Context, b backend.Backend, remotePath string) (bool, error) {
+	exists, err := b.Exists(ctx, remotePath)
+	if err != nil || exists {
+		return exists, err
+	}
+
+	lister, ok := b.(backend.Lister)
+	if !ok {
+		return false, nil
+	}
+
+	found := false
+	err = walkRemote(ctx, lister, remotePath, func(obj backend.ObjectInfo) error {
+		found = true
+		return backend.StopListing
+	})
+	if errors.Is(err, backend.ErrListingNotSupported) {
+		return false, nil
+	}
+
+	return found, err
+}
+
+func init() {
+	rootCmd.AddCommand(NewPromoteCmd())
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unyankableBackend fails to yank anything under path.
+type unyankableBackend struct {
+	*memorybackend.MemoryBackend
+	path string
+}
+
+func (b unyankableBackend) Yank(ctx context.Context, remotePath string) error {
+	if remotePath == b.path {
+		return errors.New("access denied")
+	}
+
+	return b.MemoryBackend.Yank(ctx, remotePath)
+}
+
+func Test__Promote(t *testing.T) {
+	ctx := context.Background()
+	t.Setenv("SEMAPHORE_GIT_SHA", "abc123")
+	t.Setenv("SEMAPHORE_PIPELINE_ID", "p1")
+
+	newMemory := func() *memorybackend.MemoryBackend {
+		memory := memorybackend.New()
+		memory.Put("artifacts/jobs/1/dist/app", []byte("app"))
+		memory.Put("artifacts/jobs/1/dist/lib.so", []byte("lib"))
+		return memory
+	}
+
+	t.Run("copies files with metadata", func(t *testing.T) {
+		memory := newMemory()
+		metadata := promotionMetadataFor("artifacts/jobs/1/dist", map[string]string{"pipeline-id": "override"})
+
+		err := promote(ctx, memory, "artifacts/jobs/1/dist", "artifacts/projects/2/releases/v1", backend.PushOptions{Metadata: metadata}, true)
+		require.NoError(t, err)
+
+		data, ok := memory.Get("artifacts/projects/2/releases/v1/app")
+		require.True(t, ok)
+		assert.Equal(t, "app", string(data))
+
+		stored := memory.Metadata("artifacts/projects/2/releases/v1/lib.so")
+		assert.Equal(t, "abc123", stored["git-sha"])
+		assert.Equal(t, "override", stored["pipeline-id"])
+		assert.Equal(t, "artifacts/jobs/1/dist", stored["promoted-from"])
+		assert.NotEmpty(t, stored["promoted-at"])
+
+		_, ok = memory.Get("artifacts/jobs/1/dist/app")
+		assert.False(t, ok)
+	})
+
+	t.Run("rejects existing destinations without force", func(t *testing.T) {
+		memory := newMemory()
+		memory.Put("artifacts/projects/2/dist/app", []byte("old"))
+
+		err := promote(ctx, memory, "artifacts/jobs/1/dist", "artifacts/projects/2/dist", backend.PushOptions{}, false)
+		assert.IsType(t, &backend.ErrAlreadyExists{}, err)
+
+		data, _ := memory.Get("artifacts/projects/2/dist/app")
+		assert.Equal(t, "old", string(data))
+		_, ok := memory.Get("artifacts/projects/2/dist/lib.so")
+		assert.False(t, ok)
+	})
+
+	t.Run("rolls back when the source cannot be removed", func(t *testing.T) {
+		memory := newMemory()
+		b := unyankableBackend{MemoryBackend: memory, path: "artifacts/jobs/1/dist"}
+
+		err := promote(ctx, b, "artifacts/jobs/1/dist", "artifacts/projects/2/dist", backend.PushOptions{}, true)
+		assert.ErrorContains(t, err, "failed to remove 'artifacts/jobs/1/dist': access denied; the promotion was rolled back")
+		assert.Equal(t, []string{"artifacts/jobs/1/dist/app", "artifacts/jobs/1/dist/lib.so"}, memory.Paths())
+	})
+
+	t.Run("reports overwritten files that cannot be rolled back", func(t *testing.T) {
+		memory := newMemory()
+		memory.Put("artifacts/projects/2/dist/app", []byte("old"))
+		b := unyankableBackend{MemoryBackend: memory, path: "artifacts/jobs/1/dist"}
+
+		err := promote(ctx, b, "artifacts/jobs/1/dist", "artifacts/projects/2/dist", backend.PushOptions{Force: true}, true)
+		assert.ErrorContains(t, err, "was overwritten and cannot be rolled back")
+	})
+
+	t.Run("fails for missing sources", func(t *testing.T) {
+		err := promote(ctx, newMemory(), "artifacts/jobs/1/missing", "artifacts/projects/2/missing", backend.PushOptions{}, false)
+		assert.IsType(t, &backend.ErrNotFound{}, err)
+	})
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	"github.com/semaphoreci/artifact/pkg/retention"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Deletes files older than an age or matching a pattern",
+		Long: `Deletes the files stored for a project, workflow or job, or under a
+directory in it, that are older than --older-than, match --match and,
+with --retention, are expired by the retention policy stored in the
+backend. At least one of them is required. Use --dry-run to see what
+would be removed first.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Deletes old or matching %s files from the storage.", cobra.MaximumNArgs(1), addPruneFlags, runPruneForCategory)
+	return cmd
+}
+
+func addPruneFlags(cmd *cobra.Command) {
+	cmd.Flags().String("older-than", "", "delete files older than the given age, e.g. 30d")
+	cmd.Flags().String("match", "", "delete files matching the glob pattern, e.g. '**/*.tmp'")
+	cmd.Flags().Bool("retention", false, "delete files expired by the retention policy stored in the backend")
+	cmd.Flags().Bool("dry-run", false, "print the files that would be deleted without deleting them")
+}
+
+func runPruneForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+	useRetention, _ := cmd.Flags().GetBool("retention")
+
+	opts, err := parsePruneOptions(cmd)
+	errutil.Check(err)
+
+	root := resolver.PrefixedPath("")
+	remotePath := root
+	if len(args) > 0 {
+		remotePath = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	p, err := getPolicy()
+	errutil.Check(err)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	ctx := getContext()
+	candidates, err := pruneCandidates(ctx, lister, remotePath, root, opts)
+	if err != nil {
+		log.Errorf("Error listing artifacts: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	if useRetention {
+		rp, err := loadRetention(ctx, b)
+		errutil.Check(err)
+		candidates = expiredEntries(candidates, rp, resolver.ResourceType, opts.Now)
+	}
+
+	if dryRun {
+		errutil.Check(printPruneCandidates(cmd.OutOrStdout(), candidates))
+		return
+	}
+
+	removed, failed := pruneFiles(ctx, b, candidates, func(entry lsEntry) error {
+		return p.Evaluate(policyRequest(policy.OperationYank, resolver, entry.Info.Path))
+	})
+
+	log.Infof("Pruned %d %s (%s).\n", len(removed), pluralize(len(removed), "file", "files"), formatBytes(totalSize(removed)))
+	if failed > 0 {
+		log.Errorf("Failed to delete %d %s.\n", failed, pluralize(failed, "file", "files"))
+		errutil.Exit(1)
+	}
+}
+
+func parsePruneOptions(cmd *cobra.Command) (*lsOptions, error) {
+	opts := &lsOptions{Now: time.Now()}
+
+	opts.Match, _ = cmd.Flags().GetString("match")
+	if err := files.ValidateGlob(opts.Match); err != nil {
+		return nil, fmt.Errorf("invalid --match '%s': %v", opts.Match, err)
+	}
+
+	if olderThan, _ := cmd.Flags().GetString("older-than"); olderThan != "" {
+		age, err := common.ParseAge(olderThan)
+		if err != nil {
+			return nil, err
+		}
+		opts.OlderThan = age
+	}
+
+	// Without filters, prune would delete everything
+	useRetention, _ := cmd.Flags().GetBool("retention")
+	if opts.Match == "" && opts.OlderThan == 0 && !useRetention {
+		return nil, fmt.Errorf("prune requires --older-than, --match, --retention or a combination of them")
+	}
+
+	return opts, nil
+}
+
+// pruneCandidates lists the files under remotePath matching opts,
+// with names relative to root. The full listing is collected before
+// anything is deleted, so deletions cannot disturb the listing.
+func pruneCandidates(ctx context.Context, lister backend.Lister, remotePath, root string, opts *lsOptions) ([]lsEntry, error) {
+	candidates := []lsEntry{}
+	err := walkRemote(ctx, lister, remotePath, func(obj backend.ObjectInfo) error {
+		entry := lsEntry{Name: relativeName(obj.Path, root), Info: obj}
+		if opts.matches(entry) {
+			candidates = append(candidates, entry)
+		}
+
+		return nil
+	})
+
+	return candidates, err
+}
+
+// expiredEntries returns the entries of category that have outlived the
+// retention rules matching them.
+func expiredEntries(entries []lsEntry, p *retention.Policy, category string, now time.Time) []lsEntry {
+	expired := []lsEntry{}
+	for _, entry := range entries {
+		if p.Expired(category, entry.Name, entry.Info.ModTime, now) {
+			expired = append(expired, entry)
+		}
+	}
+
+	return expired
+}
+
+// pruneFiles yanks the candidates that pass check, one by one. A file that
+// cannot be deleted, e.g. because it is locked or denied by a policy, is
+// logged and skipped. It returns the deleted entries and the failure count.
+func pruneFiles(ctx context.Context, b backend.Backend, candidates []lsEntry, check func(lsEntry) error) ([]lsEntry, int) {
+	removed := []lsEntry{}
+	failed := 0
+
+	for _, entry := range candidates {
+		err := check(entry)
+		if err == nil {
+			err = b.Yank(ctx, entry.Info.Path)
+		}
+
+		if err != nil {
+			log.Warnf("Failed to delete '%s': %v\n", entry.Name, err)
+			failed++
+			continue
+		}
+
+		log.Debugf("Deleted '%s'.\n", entry.Name)
+		removed = append(removed, entry)
+	}
+
+	return removed, failed
+}
+
+func printPruneCandidates(out io.Writer, candidates []lsEntry) error {
+	for _, entry := range candidates {
+		if _, err := fmt.Fprintln(out, entry.Name); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Would prune %d %s (%s).\n", len(candidates), pluralize(len(candidates), "file", "files"), formatBytes(totalSize(candidates)))
+	return nil
+}
+
+func totalSize(entries []lsEntry) int64 {
+	var size int64
+	for _, entry := range entries {
+		size += entry.Info.Size
+	}
+
+	return size
+}
+
+func init() {
+	rootCmd.AddCommand(NewPruneCmd())
+}
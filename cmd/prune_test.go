@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Prune(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/build/a.tmp", Contents: "a"},
+		{Name: "artifacts/jobs/1/build/app.zip", Contents: "app"},
+		{Name: "artifacts/jobs/1/logs/b.tmp", Contents: "b"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewPruneCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	// Files were just uploaded, so none are old enough
+	assert.Equal(t, "", run("--older-than", "1d", "--dry-run"))
+	assert.Equal(t, "build/a.tmp\nlogs/b.tmp\n", run("--match", "**/*.tmp", "--dry-run"))
+	assert.Equal(t, "build/a.tmp\n", run("build", "--match", "**/*.tmp", "--dry-run"))
+
+	run("--match", "**/*.tmp")
+	assert.Equal(t, "", run("--match", "**/*.tmp", "--dry-run"))
+	assert.Equal(t, "build/app.zip\n", run("--match", "**", "--dry-run"))
+}
+
+func Test__PruneFiles(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/a.tmp", []byte("a"))
+	memory.Put("artifacts/jobs/1/b.tmp", []byte("bb"))
+
+	candidates, err := pruneCandidates(ctx, memory, "artifacts/jobs/1", "artifacts/jobs/1", &lsOptions{Match: "*.tmp"})
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+
+	// Files denied by the check are skipped, the rest are deleted
+	removed, failed := pruneFiles(ctx, memory, candidates, func(entry lsEntry) error {
+		if entry.Name == "a.tmp" {
+			return errors.New("denied")
+		}
+		return nil
+	})
+	assert.Equal(t, 1, failed)
+	require.Len(t, removed, 1)
+	assert.Equal(t, "b.tmp", removed[0].Name)
+	assert.Equal(t, []string{"artifacts/jobs/1/a.tmp"}, memory.Paths())
+}
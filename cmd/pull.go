@@ -1,6 +1,9 @@
 package cmd
 
 import (
+	"context"
+	"crypto"
+	"fmt"
 	"os"
 	"path/filepath"
 
@@ -10,24 +13,144 @@ import (
 	"github.com/semaphoreci/artifact/pkg/storage"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // pullCmd represents the pull command
 var pullCmd = &cobra.Command{
-	Use:   "pull",
+	Use:   "pull [@ALIAS]",
 	Short: "Downloads a file or directory from the storage you pushed earlier",
 	Long: `You may store files project, workflow or job related files with
 artifact push. With artifact pull you can download them to the current directory
-to use them in a later phase, debug, or getting the results.`,
+to use them in a later phase, debug, or getting the results.
+
+Only the files matching a glob pattern are pulled with a quoted pattern,
+e.g. 'artifact pull workflow "reports/**/junit*.xml"'.
+
+Artifacts with an alias can be pulled with 'artifact pull @ALIAS'.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runPullForAlias,
+}
+
+func runPullForAlias(cmd *cobra.Command, args []string) {
+	if !isAlias(args[0]) {
+		errutil.Check(fmt.Errorf("unknown category '%s': use job, workflow, project or an @alias", args[0]))
+		return
+	}
+
+	resolver, path, err := resolverForAlias(args[0])
+	if err != nil {
+		errutil.Check(err)
+		return
+	}
+
+	paths, stats, err := runPullForCategory(cmd, []string{path}, resolver)
+	if err != nil {
+		log.Errorf("Error pulling artifact: %v\n", err)
+		reportTransfer(cmd, "pull", nil, 0, 0, err)
+		logResumeHint("pull")
+		log.Error("Please check if the artifact you are trying to pull exists.\n")
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Successfully pulled artifact %s.\n", args[0])
+	log.Infof("* Remote source: '%s'.\n", paths.Source)
+	log.Infof("* Local destination: '%s'.\n", paths.Destination)
+	reportTransfer(cmd, "pull", paths, stats.FileCount, stats.TotalSize, nil)
 }
 
 func runPullForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) (*files.ResolvedPath, *storage.PullStats, error) {
+	if err := checkTransferStatsFlags(cmd); err != nil {
+		return nil, nil, err
+	}
+
 	destinationOverride, err := cmd.Flags().GetString("destination")
 	errutil.Check(err)
 
 	force, err := cmd.Flags().GetBool("force")
 	errutil.Check(err)
 
+	tarOutput, err := parsePullOutput(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	extract, err := cmd.Flags().GetBool("extract")
+	errutil.Check(err)
+
+	if tarOutput != "" && extract {
+		return nil, nil, fmt.Errorf("use either --tar or --extract, not both")
+	}
+
+	keepGoing, err := cmd.Flags().GetBool("keep-going")
+	errutil.Check(err)
+
+	if keepGoing && (tarOutput != "" || extract || files.IsGlob(args[0])) {
+		return nil, nil, fmt.Errorf("--keep-going cannot be used with --tar, --extract or glob patterns")
+	}
+
+	ifChanged, err := cmd.Flags().GetBool("if-changed")
+	errutil.Check(err)
+
+	if ifChanged && (keepGoing || tarOutput != "" || extract || files.IsGlob(args[0])) {
+		return nil, nil, fmt.Errorf("--if-changed cannot be used with --keep-going, --tar, --extract or glob patterns")
+	}
+
+	concurrency, err := cmd.Flags().GetInt("concurrency")
+	errutil.Check(err)
+
+	if concurrency < 1 {
+		return nil, nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	noSpaceCheck, err := cmd.Flags().GetBool("no-space-check")
+	errutil.Check(err)
+
+	requireSignature, err := cmd.Flags().GetBool("require-signature")
+	errutil.Check(err)
+
+	var signatureKey crypto.PublicKey
+	if requireSignature {
+		if tarOutput != "" {
+			return nil, nil, fmt.Errorf("--require-signature cannot be used with --tar")
+		}
+
+		if signatureKey, err = loadVerificationKey(""); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if files.IsGlob(args[0]) {
+		if tarOutput != "" || extract {
+			return nil, nil, fmt.Errorf("glob patterns cannot be used with --tar or --extract")
+		}
+
+		pattern := files.ToRelative(args[0])
+		if destinationOverride == "" {
+			destinationOverride = files.MappedDestination(viper.GetStringMapString("pullMappings"), resolver.ResourceType, files.GlobBase(pattern))
+		}
+
+		return runPullMatching(resolver, pattern, destinationOverride, force, signatureKey)
+	}
+
+	if tarOutput != "" {
+		return runPullAsTar(cmd, args, resolver, destinationOverride, tarOutput)
+	}
+
+	if extract {
+		if destinationOverride == "" {
+			destinationOverride = files.MappedDestination(viper.GetStringMapString("pullMappings"), resolver.ResourceType, args[0])
+		}
+
+		return runPullExtracted(resolver, args[0], destinationOverride, force, signatureKey)
+	}
+
+	// Fall back to the configured pull mappings when no destination is given
+	if destinationOverride == "" {
+		destinationOverride = files.MappedDestination(viper.GetStringMapString("pullMappings"), resolver.ResourceType, args[0])
+	}
+
 	// Resolve paths
 	paths, err := resolver.Resolve(files.OperationPull, args[0], destinationOverride)
 	if err != nil {
@@ -38,20 +161,78 @@ func runPullForCategory(cmd *cobra.Command, args []string, resolver *files.PathR
 	b := getBackend()
 	defer func() { _ = b.Close() }()
 
-	// Pull using the backend
-	ctx := getContext()
-	err = b.Pull(ctx, paths.Source, paths.Destination, backend.PullOptions{Force: force})
+	// Fail before downloading anything rather than when the disk is full
+	if !noSpaceCheck {
+		if err := checkPullSpace(getContext(), b, paths); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	pull := pullResolved
+	if keepGoing {
+		pull = pullEach
+	} else if ifChanged {
+		pull = pullChanged
+	}
+
+	ctx, tracker := startProgress(getContext(), cmd, "Pulled")
+	stats, err := pull(ctx, b, paths, backend.PullOptions{Force: force, Concurrency: concurrency})
+	tracker.Stop()
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if signatureKey != nil {
+		if err := verifyPulledSignatures(getContext(), b, signatureKey, paths); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return paths, stats, nil
+}
+
+// pullResolved pulls paths.Source into paths.Destination with b, and
+// returns the stats of the pulled files.
+func pullResolved(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, opts backend.PullOptions) (*storage.PullStats, error) {
+	// Keep other artifact processes from writing into the same destination
+	lock, err := files.LockDestination(paths.Destination, getLockTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	// Directories pushed with --transactional are read through the
+	// manifest committing them
+	if m := readTransaction(ctx, b, paths.Source); m != nil {
+		return pullTransaction(ctx, b, m, paths, opts)
+	}
+
+	// The small files of directories pushed with --pack are unpacked first,
+	// and the directory may have no other files
+	packs := readPackIndex(ctx, b, paths.Source)
+	if packs != nil {
+		if _, err := pullPacks(ctx, b, packs, paths, opts, false); err != nil {
+			return nil, err
+		}
+	}
+
+	// Pull using the backend
+	if err := b.Pull(ctx, paths.Source, paths.Destination, opts); err != nil && (packs == nil || !isNotFound(err)) {
+		return nil, err
+	}
+
+	// Reassemble the files pushed with --delta
+	if err := expandDeltas(ctx, b, paths.Source, paths.Destination); err != nil {
+		return nil, err
+	}
+
 	// Get stats from downloaded files
 	stats, err := getPullStats(paths.Destination)
 	if err != nil {
-		return paths, &storage.PullStats{}, nil
+		return nil, fmt.Errorf("failed to read the stats of the pulled files in '%s': %w", paths.Destination, err)
 	}
 
-	return paths, stats, nil
+	return stats, nil
 }
 
 // getPullStats calculates stats for pulled files
@@ -59,9 +240,12 @@ func getPullStats(localPath string) (*storage.PullStats, error) {
 	stats := &storage.PullStats{}
 
 	info, err := os.Stat(localPath)
-	if err != nil {
+	if os.IsNotExist(err) {
 		return stats, nil
 	}
+	if err != nil {
+		return nil, err
+	}
 
 	if !info.IsDir() {
 		stats.FileCount = 1
@@ -100,6 +284,8 @@ func NewPullJobCmd() *cobra.Command {
 			paths, stats, err := runPullForCategory(cmd, args, resolver)
 			if err != nil {
 				log.Errorf("Error pulling artifact: %v\n", err)
+				reportTransfer(cmd, "pull", nil, 0, 0, err)
+				logResumeHint("pull")
 				log.Error("Please check if the artifact you are trying to pull exists.\n")
 				errutil.Exit(1)
 				return
@@ -108,13 +294,23 @@ func NewPullJobCmd() *cobra.Command {
 			log.Info("Successfully pulled artifact for current job.\n")
 			log.Infof("* Remote source: '%s'.\n", paths.Source)
 			log.Infof("* Local destination: '%s'.\n", paths.Destination)
-			log.Infof("Pulled %d %s. Total of %s\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize))
+			reportTransfer(cmd, "pull", paths, stats.FileCount, stats.TotalSize, nil)
 		},
 	}
 
 	cmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
 	cmd.Flags().BoolP("force", "f", false, "force overwrite")
+	cmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory downloaded at once")
+	addProgressFlags(cmd)
+	addTransferStatsFlags(cmd)
+	addPullTarFlags(cmd)
+	addPullExtractFlags(cmd)
+	addKeepGoingFlags(cmd, "pull")
+	addPullChangedFlags(cmd)
+	addPullSpaceFlags(cmd)
+	cmd.Flags().Bool("require-signature", false, "fail unless every pulled file has a valid signature, see 'artifact sign'")
 	cmd.Flags().StringP("job-id", "j", "", "set explicit job id")
+	cmd.ValidArgsFunction = completeRemotePath(categoryFor(files.ResourceTypeJob), 1)
 	return cmd
 }
 
@@ -135,6 +331,8 @@ func NewPullWorkflowCmd() *cobra.Command {
 			paths, stats, err := runPullForCategory(cmd, args, resolver)
 			if err != nil {
 				log.Errorf("Error pulling artifact: %v\n", err)
+				reportTransfer(cmd, "pull", nil, 0, 0, err)
+				logResumeHint("pull")
 				log.Error("Please check if the artifact you are trying to pull exists.\n")
 				errutil.Exit(1)
 				return
@@ -143,13 +341,23 @@ func NewPullWorkflowCmd() *cobra.Command {
 			log.Info("Successfully pulled artifact for current workflow.\n")
 			log.Infof("* Remote source: '%s'.\n", paths.Source)
 			log.Infof("* Local destination: '%s'.\n", paths.Destination)
-			log.Infof("Pulled %d %s. Total of %s\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize))
+			reportTransfer(cmd, "pull", paths, stats.FileCount, stats.TotalSize, nil)
 		},
 	}
 
 	cmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
 	cmd.Flags().BoolP("force", "f", false, "force overwrite")
+	cmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory downloaded at once")
+	addProgressFlags(cmd)
+	addTransferStatsFlags(cmd)
+	addPullTarFlags(cmd)
+	addPullExtractFlags(cmd)
+	addKeepGoingFlags(cmd, "pull")
+	addPullChangedFlags(cmd)
+	addPullSpaceFlags(cmd)
+	cmd.Flags().Bool("require-signature", false, "fail unless every pulled file has a valid signature, see 'artifact sign'")
 	cmd.Flags().StringP("workflow-id", "w", "", "set explicit workflow id")
+	cmd.ValidArgsFunction = completeRemotePath(categoryFor(files.ResourceTypeWorkflow), 1)
 	return cmd
 }
 
@@ -170,6 +378,8 @@ func NewPullProjectCmd() *cobra.Command {
 			paths, stats, err := runPullForCategory(cmd, args, resolver)
 			if err != nil {
 				log.Errorf("Error pulling artifact: %v\n", err)
+				reportTransfer(cmd, "pull", nil, 0, 0, err)
+				logResumeHint("pull")
 				log.Error("Please check if the artifact you are trying to pull exists.\n")
 				errutil.Exit(1)
 				return
@@ -178,17 +388,39 @@ func NewPullProjectCmd() *cobra.Command {
 			log.Info("Successfully pulled artifact for current project.\n")
 			log.Infof("* Remote source: '%s'.\n", paths.Source)
 			log.Infof("* Local destination: '%s'.\n", paths.Destination)
-			log.Infof("Pulled %d %s. Total of %s\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize))
+			reportTransfer(cmd, "pull", paths, stats.FileCount, stats.TotalSize, nil)
 		},
 	}
 
 	cmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
 	cmd.Flags().BoolP("force", "f", false, "force overwrite")
+	cmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory downloaded at once")
+	addProgressFlags(cmd)
+	addTransferStatsFlags(cmd)
+	addPullTarFlags(cmd)
+	addPullExtractFlags(cmd)
+	addKeepGoingFlags(cmd, "pull")
+	addPullChangedFlags(cmd)
+	addPullSpaceFlags(cmd)
+	cmd.Flags().Bool("require-signature", false, "fail unless every pulled file has a valid signature, see 'artifact sign'")
 	cmd.Flags().StringP("project-id", "p", "", "set explicit project id")
+	cmd.ValidArgsFunction = completeRemotePath(categoryFor(files.ResourceTypeProject), 1)
 	return cmd
 }
 
 func init() {
+	pullCmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
+	pullCmd.Flags().BoolP("force", "f", false, "force overwrite")
+	pullCmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory downloaded at once")
+	addProgressFlags(pullCmd)
+	addTransferStatsFlags(pullCmd)
+	addPullTarFlags(pullCmd)
+	addPullExtractFlags(pullCmd)
+	addKeepGoingFlags(pullCmd, "pull")
+	addPullChangedFlags(pullCmd)
+	addPullSpaceFlags(pullCmd)
+	pullCmd.Flags().Bool("require-signature", false, "fail unless every pulled file has a valid signature, see 'artifact sign'")
+
 	rootCmd.AddCommand(pullCmd)
 	pullCmd.AddCommand(NewPullJobCmd())
 	pullCmd.AddCommand(NewPullWorkflowCmd())
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPullChangedFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("if-changed", false, "skip files whose local copy matches the stored one, and overwrite the others")
+}
+
+// pullChanged pulls the files under paths.Source whose local copy under
+// paths.Destination is missing or differs from the stored file,
+// opts.Concurrency at a time, overwriting the local copies that differ.
+// Files are compared like sync does, see syncUnchanged, with a single
+// listing of the remote directory; the files of a transactional push with
+// the checksums of its manifest, and packed files, see pushPacked, with the
+// checksums of the pack index. Backends that cannot list only skip a
+// single file whose stored checksum matches, and pull directories whole.
+func pullChanged(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, opts backend.PullOptions) (*storage.PullStats, error) {
+	reader, _ := b.(backend.ChecksumReader)
+	opts.Force = true
+
+	if m := readTransaction(ctx, b, paths.Source); m != nil {
+		return pullTransactionChanged(ctx, b, m, paths, opts)
+	}
+
+	lister, ok := b.(backend.Lister)
+	if !ok {
+		return pullWholeChanged(ctx, b, reader, paths, opts)
+	}
+
+	objects := []backend.ObjectInfo{}
+	err := walkRemote(ctx, lister, paths.Source, func(obj backend.ObjectInfo) error {
+		objects = append(objects, obj)
+		return nil
+	})
+	if errors.Is(err, backend.ErrListingNotSupported) {
+		return pullWholeChanged(ctx, b, reader, paths, opts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	packs := readPackIndex(ctx, b, paths.Source)
+	if len(objects) == 0 && packs == nil {
+		return nil, &backend.ErrNotFound{Path: paths.Source}
+	}
+
+	// Keep other artifact processes from writing into the same destination
+	lock, err := files.LockDestination(paths.Destination, getLockTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	var mu sync.Mutex
+	stats := &storage.PullStats{}
+	skipped := 0
+	transfer := backend.TransferStatsFromContext(ctx)
+
+	err = backend.Parallel(len(objects), opts.Concurrency, func(i int) error {
+		obj := objects[i]
+		localPath := pulledPath(paths, obj.Path)
+
+		if info, err := os.Stat(localPath); err == nil && !info.IsDir() {
+			same, err := syncUnchanged(ctx, reader, localPath, info, obj)
+			if err != nil {
+				return err
+			}
+
+			if same {
+				log.Debugf("Skipping unchanged '%s'.\n", localPath)
+				progress.FromContext(ctx).Skip(info.Size())
+				transfer.Skipped(info.Size())
+				mu.Lock()
+				skipped++
+				mu.Unlock()
+				return nil
+			}
+		}
+
+		if err := b.Pull(ctx, obj.Path, localPath, opts); err != nil {
+			return err
+		}
+
+		if err := expandDelta(ctx, b, obj.Path, localPath); err != nil {
+			return err
+		}
+
+		size := obj.Size
+		if info, err := os.Stat(localPath); err == nil {
+			size = info.Size()
+		}
+
+		transfer.Transferred(size)
+		mu.Lock()
+		stats.FileCount++
+		stats.TotalSize += size
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if packs != nil {
+		unpacked, err := pullPacks(ctx, b, packs, paths, opts, true)
+		if err != nil {
+			return nil, err
+		}
+
+		stats.FileCount += unpacked.FileCount
+		stats.TotalSize += unpacked.TotalSize
+	}
+
+	logSkippedUnchanged(skipped)
+	return stats, nil
+}
+
+// pullTransactionChanged pulls the files the manifest m commits whose
+// local copy is missing or has another checksum.
+func pullTransactionChanged(ctx context.Context, b backend.Backend, m *manifest.Manifest, paths *files.ResolvedPath, opts backend.PullOptions) (*storage.PullStats, error) {
+	changed := *m
+	changed.Files = []manifest.Entry{}
+	for _, entry := range m.Files {
+		localPath := filepath.Join(paths.Destination, filepath.FromSlash(entry.Path))
+		if checksum, err := files.SHA256File(localPath); err == nil && strings.EqualFold(checksum, entry.SHA256) {
+			backend.TransferStatsFromContext(ctx).Skipped(entry.Size)
+			continue
+		}
+
+		changed.Files = append(changed.Files, entry)
+	}
+
+	logSkippedUnchanged(len(m.Files) - len(changed.Files))
+	return pullTransaction(ctx, b, &changed, paths, opts)
+}
+
+// pullWholeChanged is pullChanged for backends that cannot list: the local
+// file paths.Destination is kept if the checksum stored for paths.Source
+// matches it, and everything else is pulled.
+func pullWholeChanged(ctx context.Context, b backend.Backend, reader backend.ChecksumReader, paths *files.ResolvedPath, opts backend.PullOptions) (*storage.PullStats, error) {
+	if info, err := os.Stat(paths.Destination); err == nil && !info.IsDir() && reader != nil {
+		same, err := checksumUnchanged(ctx, reader, paths.Destination, paths.Source)
+		if err != nil {
+			log.Debugf("Failed to compare '%s' with the stored file, pulling it: %v\n", paths.Destination, err)
+		}
+
+		if err == nil && same {
+			backend.TransferStatsFromContext(ctx).Skipped(info.Size())
+			logSkippedUnchanged(1)
+			return &storage.PullStats{}, nil
+		}
+	}
+
+	log.Debugf("The backend cannot list '%s', pulling all of it.\n", paths.Source)
+	return pullResolved(ctx, b, paths, opts)
+}
+
+func logSkippedUnchanged(skipped int) {
+	if skipped > 0 {
+		log.Infof("Skipped %d unchanged %s.\n", skipped, pluralize(skipped, "file", "files"))
+	}
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pullRecorder records the remote paths pulled from it.
+type pullRecorder struct {
+	*memorybackend.MemoryBackend
+	mu     sync.Mutex
+	pulled []string
+}
+
+func (b *pullRecorder) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	b.mu.Lock()
+	b.pulled = append(b.pulled, remotePath)
+	b.mu.Unlock()
+
+	return b.MemoryBackend.Pull(ctx, remotePath, localPath, opts)
+}
+
+func Test__PullChanged(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("only missing and changed files are pulled", func(t *testing.T) {
+		b := &pullRecorder{MemoryBackend: memorybackend.New()}
+		b.Put("artifacts/jobs/1/out/a.txt", []byte("a"))
+		b.Put("artifacts/jobs/1/out/logs/b.log", []byte("b"))
+		b.Put("artifacts/jobs/1/out/c.txt", []byte("c"))
+
+		dest := filepath.Join(t.TempDir(), "out")
+		paths := &files.ResolvedPath{Source: "artifacts/jobs/1/out", Destination: dest}
+		_, err := pullResolved(ctx, b, paths, backend.PullOptions{Concurrency: 1})
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dest, "a.txt"), []byte("stale"), 0644))
+		require.NoError(t, os.Remove(filepath.Join(dest, "c.txt")))
+		b.pulled = nil
+
+		stats := backend.NewTransferStats()
+		pulled, err := pullChanged(backend.WithTransferStats(ctx, stats), b, paths, backend.PullOptions{Concurrency: 2})
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []string{"artifacts/jobs/1/out/a.txt", "artifacts/jobs/1/out/c.txt"}, b.pulled)
+		assert.Equal(t, 2, pulled.FileCount)
+		assert.Equal(t, 1, stats.Summary().SkippedFiles)
+
+		data, err := os.ReadFile(filepath.Join(dest, "a.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "a", string(data))
+
+		// Nothing is pulled once the local copies are current
+		b.pulled = nil
+		pulled, err = pullChanged(ctx, b, paths, backend.PullOptions{Concurrency: 2})
+		require.NoError(t, err)
+		assert.Empty(t, b.pulled)
+		assert.Equal(t, 0, pulled.FileCount)
+	})
+
+	t.Run("transactional pushes are compared with their manifest", func(t *testing.T) {
+		b := &pullRecorder{MemoryBackend: memorybackend.New()}
+		source := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(source, "a.txt"), []byte("a"), 0644))
+		require.NoError(t, os.WriteFile(filepath.Join(source, "b.txt"), []byte("b"), 0644))
+
+		remote := &files.ResolvedPath{Source: source, Destination: "artifacts/jobs/1/out"}
+		_, err := pushTransactional(ctx, b, remote, nil, nil, backend.PushOptions{Concurrency: 1}, false)
+		require.NoError(t, err)
+
+		dest := filepath.Join(t.TempDir(), "out")
+		paths := &files.ResolvedPath{Source: "artifacts/jobs/1/out", Destination: dest}
+		_, err = pullResolved(ctx, b, paths, backend.PullOptions{Concurrency: 1})
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(filepath.Join(dest, "b.txt"), []byte("stale"), 0644))
+		b.pulled = nil
+
+		pulled, err := pullChanged(ctx, b, paths, backend.PullOptions{Concurrency: 1})
+		require.NoError(t, err)
+		require.Len(t, b.pulled, 1)
+		assert.Equal(t, "b.txt", filepath.Base(b.pulled[0]))
+		assert.Equal(t, 1, pulled.FileCount)
+
+		data, err := os.ReadFile(filepath.Join(dest, "b.txt"))
+		require.NoError(t, err)
+		assert.Equal(t, "b", string(data))
+	})
+}
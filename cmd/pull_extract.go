@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	"github.com/spf13/cobra"
+)
+
+func addPullExtractFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("extract", false, "unpack a tar.gz or zip archive pushed with --archive into a local directory")
+}
+
+// runPullExtracted pulls the archive at source and unpacks it into a local
+// directory, named after the archive without its extension unless a
+// destination is given.
+func runPullExtracted(resolver *files.PathResolver, source, destinationOverride string, force bool, signatureKey crypto.PublicKey) (*files.ResolvedPath, *storage.PullStats, error) {
+	format, trimmed, ok := files.ArchiveFormatOf(source)
+	if !ok {
+		return nil, nil, fmt.Errorf("'%s' is not a tar.gz or zip archive", source)
+	}
+
+	if destinationOverride == "" {
+		destinationOverride = path.Base(trimmed)
+	}
+
+	paths, err := resolver.Resolve(files.OperationPull, source, destinationOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	stats, err := pullExtracted(getContext(), b, paths, format, force, signatureKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return paths, stats, nil
+}
+
+// pullExtracted pulls the archive at paths.Source into a temporary file,
+// and unpacks it into the local directory paths.Destination. With a
+// signatureKey, the archive is only unpacked if its signature is valid.
+func pullExtracted(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, format string, force bool, signatureKey crypto.PublicKey) (*storage.PullStats, error) {
+	// Keep other artifact processes from writing into the same destination
+	lock, err := files.LockDestination(paths.Destination, getLockTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	tmpDir, err := ioutil.TempDir("", "artifact-extract-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer removeOnQuit(tmpDir)()
+
+	archivePath := filepath.Join(tmpDir, "archive."+format)
+	if err := b.Pull(ctx, paths.Source, archivePath, backend.PullOptions{Force: true}); err != nil {
+		return nil, err
+	}
+
+	if signatureKey != nil {
+		status, err := verifyRemoteSignature(ctx, b, signatureKey, paths.Source, func() (io.ReadCloser, error) {
+			return os.Open(archivePath)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if status != verifyOK {
+			return nil, fmt.Errorf("signature of '%s' is %s", paths.Source, status)
+		}
+	}
+
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	count, size, err := files.ExtractArchive(f, format, paths.Destination, force)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract '%s': %v", paths.Source, err)
+	}
+
+	return &storage.PullStats{FileCount: count, TotalSize: size}, nil
+}
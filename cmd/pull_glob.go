@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"fmt"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/storage"
+)
+
+// runPullMatching pulls the files of the category matching pattern. They
+// keep their paths under the directory the pattern starts with, which is
+// pulled to destinationOverride, or to a directory of the same name.
+func runPullMatching(resolver *files.PathResolver, pattern, destinationOverride string, force bool, signatureKey crypto.PublicKey) (*files.ResolvedPath, *storage.PullStats, error) {
+	if err := files.ValidateGlob(pattern); err != nil {
+		return nil, nil, fmt.Errorf("invalid pattern '%s': %v", pattern, err)
+	}
+
+	paths, err := resolver.Resolve(files.OperationPull, files.GlobBase(pattern), destinationOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	stats, err := pullMatching(getContext(), b, paths, resolver.PrefixedPath(""), pattern, backend.PullOptions{Force: force}, signatureKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return paths, stats, nil
+}
+
+// pullMatching pulls the files under paths.Source whose names relative to
+// root match pattern into paths.Destination. Only the matching files are
+// downloaded, so the rest of the directory is never transferred.
+func pullMatching(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, root, pattern string, opts backend.PullOptions, signatureKey crypto.PublicKey) (*storage.PullStats, error) {
+	lister, err := getLister(b)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, err := pruneCandidates(ctx, lister, paths.Source, root, &lsOptions{Match: pattern})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no files match '%s'", pattern)
+	}
+
+	// Keep other artifact processes from writing into the same destination
+	lock, err := files.LockDestination(paths.Destination, getLockTimeout())
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = lock.Unlock() }()
+
+	stats := &storage.PullStats{}
+	remotePaths := []string{}
+	for _, entry := range candidates {
+		localPath := pulledPath(paths, entry.Info.Path)
+		if err := b.Pull(ctx, entry.Info.Path, localPath, opts); err != nil {
+			return nil, fmt.Errorf("failed to pull '%s': %v", entry.Name, err)
+		}
+
+		if err := expandDelta(ctx, b, entry.Info.Path, localPath); err != nil {
+			return nil, err
+		}
+
+		stats.FileCount++
+		stats.TotalSize += entry.Info.Size
+		remotePaths = append(remotePaths, entry.Info.Path)
+	}
+
+	if signatureKey != nil {
+		if err := verifyLocalSignatures(ctx, b, signatureKey, remotePaths, func(remotePath string) string {
+			return pulledPath(paths, remotePath)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+// pulledPath returns where remotePath, paths.Source or a file under it, is
+// pulled to.
+func pulledPath(paths *files.ResolvedPath, remotePath string) string {
+	name := relativeName(remotePath, paths.Source)
+	if name == "" {
+		return paths.Destination
+	}
+
+	return filepath.Join(paths.Destination, filepath.FromSlash(name))
+}
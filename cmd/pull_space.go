@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPullSpaceFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-space-check", false, "do not check that the destination has enough free space before pulling")
+}
+
+// checkPullSpace fails if the filesystem of paths.Destination has less
+// free space than the files the pull of paths.Source writes, see
+// pullSize. The check is skipped if the size of the pull cannot be told.
+func checkPullSpace(ctx context.Context, b backend.Backend, paths *files.ResolvedPath) error {
+	size, ok, err := pullSize(ctx, b, paths)
+	if err != nil {
+		// The pull reports it, if it fails too
+		log.Debugf("Failed to get the size of '%s', not checking the free space: %v\n", paths.Source, err)
+		return nil
+	}
+
+	if !ok {
+		log.Debugf("The backend cannot tell the size of '%s', not checking the free space.\n", paths.Source)
+		return nil
+	}
+
+	err = files.CheckFreeSpace(paths.Destination, size)
+	var notEnough *files.ErrNotEnoughSpace
+	if errors.As(err, &notEnough) {
+		return err
+	}
+
+	if err != nil {
+		log.Debugf("Not checking the free space: %v\n", err)
+	}
+
+	return nil
+}
+
+// pullSize returns how many bytes the pull of paths.Source adds to
+// paths.Destination: the size of its files, from the manifest of a
+// transactional push, or else from a listing and the index of its packed
+// files, less the size of the local files they replace. ok is false if the
+// backend can neither list nor stat files.
+func pullSize(ctx context.Context, b backend.Backend, paths *files.ResolvedPath) (int64, bool, error) {
+	var size int64
+	add := func(localPath string, remoteSize int64) {
+		if info, err := os.Stat(localPath); err == nil && !info.IsDir() {
+			remoteSize -= info.Size()
+		}
+		size += max(remoteSize, 0)
+	}
+
+	addEntries := func(entries []manifest.Entry) {
+		for _, entry := range entries {
+			add(filepath.Join(paths.Destination, filepath.FromSlash(entry.Path)), entry.Size)
+		}
+	}
+
+	if m := readTransaction(ctx, b, paths.Source); m != nil {
+		addEntries(m.Files)
+		return size, true, nil
+	}
+
+	lister, ok := b.(backend.Lister)
+	if !ok {
+		stater, ok := b.(backend.Stater)
+		if !ok {
+			return 0, false, nil
+		}
+
+		info, err := stater.Stat(ctx, paths.Source)
+		if err != nil {
+			// Directories cannot be stat'ed
+			return 0, false, nil
+		}
+
+		add(paths.Destination, info.Size)
+		return size, true, nil
+	}
+
+	err := walkRemote(ctx, lister, paths.Source, func(obj backend.ObjectInfo) error {
+		add(pulledPath(paths, obj.Path), obj.Size)
+		return nil
+	})
+	if err != nil {
+		return 0, false, err
+	}
+
+	if index := readPackIndex(ctx, b, paths.Source); index != nil {
+		for _, p := range index.Packs {
+			addEntries(p.Files)
+		}
+	}
+
+	return size, true, nil
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// hugeBackend lists every file as 1 EB large.
+type hugeBackend struct {
+	*memorybackend.MemoryBackend
+}
+
+func (b hugeBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	return b.MemoryBackend.List(ctx, remotePrefix, func(obj backend.ObjectInfo) error {
+		obj.Size = 1 << 60
+		return fn(obj)
+	})
+}
+
+func Test__PullSpace(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+	b.Put("artifacts/jobs/1/out/a.txt", []byte("aaaa"))
+	b.Put("artifacts/jobs/1/out/logs/b.log", []byte("bbbbbb"))
+
+	dest := filepath.Join(t.TempDir(), "out")
+	paths := &files.ResolvedPath{Source: "artifacts/jobs/1/out", Destination: dest}
+
+	size, ok, err := pullSize(ctx, b, paths)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(10), size)
+
+	// Local files the pull replaces free their space
+	require.NoError(t, os.MkdirAll(dest, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dest, "a.txt"), []byte("aa"), 0644))
+	size, _, err = pullSize(ctx, b, paths)
+	require.NoError(t, err)
+	assert.Equal(t, int64(8), size)
+
+	assert.NoError(t, checkPullSpace(ctx, b, paths))
+
+	err = checkPullSpace(ctx, hugeBackend{b}, paths)
+	var notEnough *files.ErrNotEnoughSpace
+	assert.True(t, errors.As(err, &notEnough))
+	assert.ErrorContains(t, err, "not enough free space for '"+dest+"'")
+}
@@ -0,0 +1,356 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/delta"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/semaphoreci/artifact/pkg/pack"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPullTarFlags(cmd *cobra.Command) {
+	cmd.Flags().String("tar", "", "write the file or directory as a tar archive to this path instead, '-' for stdout")
+	cmd.Flags().StringP("output", "o", "", "'-' streams the file or directory to stdout as a tar archive instead, like --tar -")
+}
+
+// parsePullOutput returns the tar output of the pull: the --tar path, or
+// '-' for --output -, which is the only output it supports.
+func parsePullOutput(cmd *cobra.Command) (string, error) {
+	tarOutput, err := cmd.Flags().GetString("tar")
+	errutil.Check(err)
+
+	output, err := cmd.Flags().GetString("output")
+	errutil.Check(err)
+
+	if output == "" {
+		return tarOutput, nil
+	}
+
+	if output != "-" {
+		return "", fmt.Errorf("invalid --output '%s': use '-' for stdout, --destination to pull into a local path, or --tar to write an archive", output)
+	}
+
+	if tarOutput != "" {
+		return "", fmt.Errorf("use either --tar or --output, not both")
+	}
+
+	return output, nil
+}
+
+// runPullAsTar packs the remote file or directory into a tar archive
+// written to tarOutput. Entries are named as a regular pull would name
+// them locally, so 'tar x -C dir' gives the same result as pulling into dir.
+func runPullAsTar(cmd *cobra.Command, args []string, resolver *files.PathResolver, destinationOverride, tarOutput string) (*files.ResolvedPath, *storage.PullStats, error) {
+	paths, err := resolver.Resolve(files.OperationPull, args[0], destinationOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root := path.Base(paths.Destination)
+	paths.Destination = tarOutput
+
+	out := cmd.OutOrStdout()
+	if tarOutput != "-" {
+		f, err := os.Create(tarOutput)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create '%s': %v", tarOutput, err)
+		}
+		defer f.Close()
+		out = f
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	tw := tar.NewWriter(out)
+
+	// The small files of directories pushed with --pack are archived from
+	// their packs, after the other files
+	packs := readPackIndex(getContext(), b, paths.Source)
+	staged := false
+
+	var stats *storage.PullStats
+	lister, canList := b.(backend.Lister)
+	opener, canOpen := b.(backend.Opener)
+	treeOpener, canOpenTree := b.(backend.TreeOpener)
+	err = backend.ErrListingNotSupported
+	if canList && canOpen {
+		stats, err = streamRemoteTar(tw, lister, opener, paths.Source, root)
+	}
+
+	// Listing fails before anything is written, e.g. with Hubs without the
+	// list API
+	if errors.Is(err, backend.ErrListingNotSupported) {
+		if canOpenTree && canOpen {
+			stats, err = streamRemoteTreeTar(tw, treeOpener, opener, paths.Source, root)
+		} else {
+			stats, err = stageRemoteTar(tw, b, paths.Source, root, packs)
+			staged = true
+		}
+	}
+
+	// and the directory may have no other files
+	if packs != nil && isNotFound(err) {
+		stats, err = &storage.PullStats{}, nil
+	}
+	if err == nil && packs != nil && !staged {
+		err = writePackedTar(getContext(), tw, b, packs, paths.Source, root, stats)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to write tar archive: %v", err)
+	}
+
+	return paths, stats, nil
+}
+
+// streamRemoteTar copies every remote object straight into the archive,
+// one at a time, so nothing touches the local disk.
+func streamRemoteTar(tw *tar.Writer, lister backend.Lister, opener backend.Opener, remotePath, root string) (*storage.PullStats, error) {
+	ctx := getContext()
+	stats := &storage.PullStats{}
+
+	err := walkRemote(ctx, lister, remotePath, func(obj backend.ObjectInfo) error {
+		r, err := opener.Open(ctx, obj.Path)
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		return writeRemoteTarEntry(ctx, tw, opener, obj, path.Join(root, relativeName(obj.Path, remotePath)), r, stats)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if stats.FileCount == 0 {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return stats, nil
+}
+
+// streamRemoteTreeTar is streamRemoteTar for backends that cannot list
+// files, but stream every file of a directory, e.g. Hub.
+func streamRemoteTreeTar(tw *tar.Writer, treeOpener backend.TreeOpener, opener backend.Opener, remotePath, root string) (*storage.PullStats, error) {
+	ctx := getContext()
+	stats := &storage.PullStats{}
+
+	err := treeOpener.OpenTree(ctx, remotePath, func(obj backend.ObjectInfo, r io.Reader) error {
+		if obj.Size < 0 {
+			return fmt.Errorf("cannot stream '%s' into a tar archive: the storage did not send its size", obj.Path)
+		}
+
+		return writeRemoteTarEntry(ctx, tw, opener, obj, path.Join(root, relativeName(obj.Path, remotePath)), r, stats)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// writeRemoteTarEntry writes the remote object obj, read from r, to the
+// archive as name, and adds it to the stats. Files pushed with --delta
+// are reassembled from their blocks on the way. Files stored compressed
+// or encrypted are read with a different size than the one listed, so
+// their size is taken from r, and when r does not know it, they are
+// staged to find it out.
+func writeRemoteTarEntry(ctx context.Context, tw *tar.Writer, opener backend.Opener, obj backend.ObjectInfo, name string, r io.Reader, stats *storage.PullStats) error {
+	modTime := obj.ModTime
+	if modTime.IsZero() {
+		modTime = time.Now()
+	}
+
+	header := &tar.Header{Name: name, Mode: 0644, Size: obj.Size, ModTime: modTime}
+
+	if sized, ok := r.(backend.SizedReader); ok {
+		header.Size = sized.Size()
+	}
+
+	if header.Size < 0 {
+		staged, cleanup, err := stageStream(r)
+		if err != nil {
+			return err
+		}
+		defer cleanup()
+
+		f, err := os.Open(staged)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+
+		header.Size = info.Size()
+		r = f
+	}
+
+	var err error
+	br := bufio.NewReader(r)
+	if head, _ := br.Peek(len(delta.Magic)); delta.IsIndex(head) {
+		err = writeDeltaTarEntry(ctx, tw, opener, obj.Path, header, br)
+	} else {
+		err = writeTarEntry(tw, header, br)
+	}
+	if err != nil {
+		return err
+	}
+
+	stats.FileCount++
+	stats.TotalSize += header.Size
+	return nil
+}
+
+// stageRemoteTar is used for backends that cannot list or stream files:
+// it pulls into a temporary directory, unpacking the packs of the index if
+// it is not nil, and archives that.
+func stageRemoteTar(tw *tar.Writer, b backend.Backend, remotePath, root string, packs *pack.Index) (*storage.PullStats, error) {
+	log.Debug("Backend cannot stream this pull, staging it in a temporary directory...\n")
+
+	tmpDir, err := ioutil.TempDir("", "artifact-tar-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer removeOnQuit(tmpDir)()
+
+	localRoot := filepath.Join(tmpDir, root)
+	if packs != nil {
+		paths := &files.ResolvedPath{Source: remotePath, Destination: localRoot}
+		if _, err := pullPacks(getContext(), b, packs, paths, backend.PullOptions{Force: true}, false); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := b.Pull(getContext(), remotePath, localRoot, backend.PullOptions{}); err != nil && (packs == nil || !isNotFound(err)) {
+		return nil, err
+	}
+
+	if err := expandDeltas(getContext(), b, remotePath, localRoot); err != nil {
+		return nil, err
+	}
+
+	stats := &storage.PullStats{}
+	err = filepath.Walk(localRoot, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(tmpDir, filename)
+		if err != nil {
+			return err
+		}
+
+		f, err := os.Open(filename)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		header := &tar.Header{Name: filepath.ToSlash(rel), Mode: 0644, Size: info.Size(), ModTime: info.ModTime()}
+		if err := writeTarEntry(tw, header, f); err != nil {
+			return err
+		}
+
+		stats.FileCount++
+		stats.TotalSize += info.Size()
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// writePackedTar writes the files of the packs of the index to the archive,
+// named under root like the other files of the remote directory remoteDir,
+// and adds them to the stats. They are checked against their checksums.
+func writePackedTar(ctx context.Context, tw *tar.Writer, b backend.Backend, index *pack.Index, remoteDir, root string, stats *storage.PullStats) error {
+	for _, p := range index.Packs {
+		r, err := openRemote(ctx, b, pack.Path(remoteDir, p.Name))
+		if err != nil {
+			return err
+		}
+
+		err = pack.Read(r, p, func(entry manifest.Entry, mode os.FileMode, contents io.Reader) error {
+			header := &tar.Header{Name: path.Join(root, entry.Path), Mode: int64(mode), Size: entry.Size, ModTime: time.Now()}
+			if err := writeTarEntry(tw, header, contents); err != nil {
+				return err
+			}
+
+			stats.FileCount++
+			stats.TotalSize += entry.Size
+			return nil
+		})
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeTarEntry(tw *tar.Writer, header *tar.Header, r io.Reader) error {
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header for '%s': %v", header.Name, err)
+	}
+
+	if _, err := files.Copy(tw, r); err != nil {
+		return fmt.Errorf("failed to write '%s' to tar archive: %v", header.Name, err)
+	}
+
+	return nil
+}
+
+// writeDeltaTarEntry writes the file at remotePath, whose index r reads,
+// to the archive, reassembled from its blocks. The entry has the size of
+// the reassembled file.
+func writeDeltaTarEntry(ctx context.Context, tw *tar.Writer, opener backend.Opener, remotePath string, header *tar.Header, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	index, err := delta.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to reassemble '%s': %v", remotePath, err)
+	}
+
+	header.Size = index.Size
+	if err := tw.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write tar header for '%s': %v", header.Name, err)
+	}
+
+	return index.Assemble(tw, func(block delta.Block) (io.ReadCloser, error) {
+		return opener.Open(ctx, delta.BlockPath(remotePath, block.SHA256))
+	})
+}
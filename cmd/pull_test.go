@@ -1,14 +1,25 @@
 package cmd
 
 import (
+	"archive/tar"
+	"bytes"
+	"context"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
 	testsupport "github.com/semaphoreci/artifact/test/support"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	// Register backends for tests
 	_ "github.com/semaphoreci/artifact/pkg/backend/hubbackend"
@@ -116,6 +127,19 @@ func runForPullTestCase(t *testing.T, testCase pullTestCase) {
 		os.Remove("another.txt")
 	})
 
+	t.Run(testCase.Prefix+" single file with pull mapping", func(t *testing.T) {
+		viper.Set("pullMappings", map[string]string{"one-level/": "mapped"})
+		defer viper.Set("pullMappings", nil)
+
+		cmd := testCase.Command()
+		cmd.SetArgs([]string{"one-level/file1.txt"})
+		cmd.Execute()
+
+		assert.FileExists(t, "mapped/file1.txt")
+		assertFileDoesNotExist(t, "file1.txt")
+		os.RemoveAll("mapped")
+	})
+
 	t.Run(testCase.Prefix+" single-level dir", func(t *testing.T) {
 		cmd := testCase.Command()
 		cmd.SetArgs([]string{"one-level/"})
@@ -170,6 +194,192 @@ func runForPullTestCase(t *testing.T, testCase pullTestCase) {
 		assert.FileExists(t, "another.txt")
 		os.Remove("another.txt")
 	})
+
+	t.Run(testCase.Prefix+" two-levels dir as tar", func(t *testing.T) {
+		out := &bytes.Buffer{}
+		cmd := testCase.Command()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"two-levels/"})
+		cmd.Flags().Set("tar", "-")
+		cmd.Execute()
+
+		assert.Equal(t, map[string]string{
+			"two-levels/file1.txt":     "something",
+			"two-levels/sub/file1.txt": "something",
+		}, readTar(t, out))
+		assertFileDoesNotExist(t, "two-levels")
+	})
+
+	t.Run(testCase.Prefix+" two-levels dir streamed to stdout", func(t *testing.T) {
+		tmpDir := t.TempDir()
+		t.Setenv("TMPDIR", tmpDir)
+
+		out := &bytes.Buffer{}
+		cmd := testCase.Command()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"two-levels/"})
+		cmd.Flags().Set("output", "-")
+		cmd.Execute()
+
+		assert.Equal(t, map[string]string{
+			"two-levels/file1.txt":     "something",
+			"two-levels/sub/file1.txt": "something",
+		}, readTar(t, out))
+		assertFileDoesNotExist(t, "two-levels")
+
+		// Nothing was staged on the local disk
+		staged, err := os.ReadDir(tmpDir)
+		require.NoError(t, err)
+		assert.Empty(t, staged)
+	})
+}
+
+func Test__PullTar(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/results/a.txt", Contents: "aaa"},
+		{Name: "artifacts/jobs/1/results/nested/b.txt", Contents: "b"},
+		{Name: "artifacts/jobs/1/results-old/c.txt", Contents: "c"},
+	})
+	if !assert.Nil(t, err) {
+		return
+	}
+
+	out := &bytes.Buffer{}
+	cmd := NewPullJobCmd()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"results/"})
+	cmd.Flags().Set("destination", "out")
+	cmd.Flags().Set("tar", "-")
+	cmd.Execute()
+
+	assert.Equal(t, map[string]string{
+		"out/a.txt":        "aaa",
+		"out/nested/b.txt": "b",
+	}, readTar(t, out))
+	assertFileDoesNotExist(t, "out")
+}
+
+func Test__PullTar_Compressed(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	contents := strings.Repeat("compressible ", 1000)
+	require.NoError(t, os.MkdirAll(filepath.Join(tempDir, "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.txt"), []byte(contents), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "nested", "b.txt"), []byte("b"), 0644))
+
+	b := getBackend()
+	defer b.Close()
+	require.NoError(t, b.Push(getContext(), tempDir, "artifacts/jobs/1/results", backend.PushOptions{Compress: "gzip"}))
+
+	// Archived files have the size they are pulled with, not the stored one
+	out := &bytes.Buffer{}
+	cmd := NewPullJobCmd()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"results/"})
+	cmd.Flags().Set("destination", "out")
+	cmd.Flags().Set("tar", "-")
+	cmd.Execute()
+
+	assert.Equal(t, map[string]string{
+		"out/a.txt":        contents,
+		"out/nested/b.txt": "b",
+	}, readTar(t, out))
+	assertFileDoesNotExist(t, "out")
+}
+
+func Test__PullTar_Packed(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "a.cov"), []byte("aa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "b.cov"), []byte("bb"), 0644))
+
+	b := getBackend()
+	defer b.Close()
+	paths := &files.ResolvedPath{Source: tempDir, Destination: "artifacts/jobs/1/coverage"}
+	_, err = pushPacked(getContext(), b, paths, nil, &packOptions{Threshold: 100, Size: 400}, backend.PushOptions{})
+	require.NoError(t, err)
+
+	// The directory has no files but its packs
+	out := &bytes.Buffer{}
+	cmd := NewPullJobCmd()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"coverage"})
+	cmd.Flags().Set("tar", "-")
+	cmd.Execute()
+
+	assert.Equal(t, map[string]string{
+		"coverage/a.cov": "aa",
+		"coverage/b.cov": "bb",
+	}, readTar(t, out))
+}
+
+func Test__PullMappings_MixedCase(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	require.NoError(t, v.ReadConfig(strings.NewReader("pullMappings:\n  Reports/: out\n")))
+
+	mappings := v.GetStringMapString("pullMappings")
+	assert.Equal(t, "out/Junit.xml", files.MappedDestination(mappings, files.ResourceTypeJob, "Reports/Junit.xml"))
+}
+
+func Test__parsePullOutput(t *testing.T) {
+	cmd := NewPullJobCmd()
+	output, err := parsePullOutput(cmd)
+	require.NoError(t, err)
+	assert.Empty(t, output)
+
+	require.NoError(t, cmd.Flags().Set("output", "-"))
+	output, err = parsePullOutput(cmd)
+	require.NoError(t, err)
+	assert.Equal(t, "-", output)
+
+	require.NoError(t, cmd.Flags().Set("tar", "out.tar"))
+	_, err = parsePullOutput(cmd)
+	assert.EqualError(t, err, "use either --tar or --output, not both")
+
+	cmd = NewPullJobCmd()
+	require.NoError(t, cmd.Flags().Set("output", "out"))
+	_, err = parsePullOutput(cmd)
+	assert.ErrorContains(t, err, "invalid --output 'out'")
+}
+
+func readTar(t *testing.T, r io.Reader) map[string]string {
+	contents := map[string]string{}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return contents
+		}
+		if !assert.Nil(t, err) {
+			return contents
+		}
+
+		data, _ := io.ReadAll(tr)
+		contents[header.Name] = string(data)
+	}
 }
 
 func assertFileDoesNotExist(t *testing.T, fileName string) {
@@ -177,6 +387,28 @@ func assertFileDoesNotExist(t *testing.T, fileName string) {
 	assert.True(t, os.IsNotExist(err))
 }
 
+func Test__getPullStats(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "pulled", "sub"), 0755)
+	os.WriteFile(filepath.Join(dir, "pulled", "a.txt"), []byte("aaa"), 0644)
+	os.WriteFile(filepath.Join(dir, "pulled", "sub", "b.txt"), []byte("bb"), 0644)
+
+	stats, err := getPullStats(filepath.Join(dir, "pulled"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, 2, stats.FileCount)
+		assert.Equal(t, int64(5), stats.TotalSize)
+	}
+
+	// Nothing pulled is no files, but unreadable paths are errors
+	stats, err = getPullStats(filepath.Join(dir, "missing"))
+	if assert.Nil(t, err) {
+		assert.Equal(t, 0, stats.FileCount)
+	}
+
+	_, err = getPullStats(filepath.Join(dir, "pulled", "a.txt", "c.txt"))
+	assert.NotNil(t, err)
+}
+
 func Test__formatBytes(t *testing.T) {
 	testCases := []struct {
 		name     string
@@ -200,3 +432,27 @@ func Test__formatBytes(t *testing.T) {
 		})
 	}
 }
+
+func Test__PullMatching(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/workflows/1/reports/junit-1.xml", []byte("1"))
+	memory.Put("artifacts/workflows/1/reports/unit/junit-2.xml", []byte("22"))
+	memory.Put("artifacts/workflows/1/reports/unit/coverage.html", []byte("coverage"))
+	memory.Put("artifacts/workflows/1/other/junit-3.xml", []byte("3"))
+
+	destination := filepath.Join(t.TempDir(), "reports")
+	paths := &files.ResolvedPath{Source: "artifacts/workflows/1/reports", Destination: destination}
+
+	// Only the matching files are pulled, keeping their paths
+	stats, err := pullMatching(ctx, memory, paths, "artifacts/workflows/1", "reports/**/junit*.xml", backend.PullOptions{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stats.FileCount)
+	assert.Equal(t, int64(3), stats.TotalSize)
+	assert.FileExists(t, filepath.Join(destination, "junit-1.xml"))
+	assert.FileExists(t, filepath.Join(destination, "unit", "junit-2.xml"))
+	assert.NoFileExists(t, filepath.Join(destination, "unit", "coverage.html"))
+
+	_, err = pullMatching(ctx, memory, paths, "artifacts/workflows/1", "reports/**/*.txt", backend.PullOptions{}, nil)
+	assert.ErrorContains(t, err, "no files match")
+}
@@ -1,23 +1,23 @@
 package cmd
 
 import (
-	"bufio"
 	"fmt"
-	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/semaphoreci/artifact/pkg/backend"
 	errutil "github.com/semaphoreci/artifact/pkg/errors"
 	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
 	"github.com/semaphoreci/artifact/pkg/storage"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
-// getLocalStats calculates stats for local files/directories
-func getLocalStats(localPath string) (*storage.PushStats, error) {
+// getLocalStats calculates stats for local files/directories,
+// counting only the files of a directory the filter keeps
+func getLocalStats(localPath string, filter *files.Filter) (*storage.PushStats, error) {
 	stats := &storage.PushStats{}
 
 	info, err := os.Stat(localPath)
@@ -31,11 +31,20 @@ func getLocalStats(localPath string) (*storage.PushStats, error) {
 		return stats, nil
 	}
 
-	err = filepath.Walk(localPath, func(path string, info os.FileInfo, err error) error {
+	err = files.Walk(localPath, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
+		if info.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(localPath, path)
+		if err != nil {
+			return err
+		}
+
+		if filter.Keep(filepath.ToSlash(rel)) {
 			stats.FileCount++
 			stats.TotalSize += info.Size()
 		}
@@ -45,18 +54,6 @@ func getLocalStats(localPath string) (*storage.PushStats, error) {
 	return stats, err
 }
 
-const ExpireInDescription = `removes the files after the given amount of time.
-
-- Nd for N days
-- Nw for N weeks
-- Nm for N months
-- Ny for N years
-
-WARNING: This is an obsolete flag and has no effect.
-Set up a retention policy in your project instead.
-Docs: https://docs.semaphoreci.com/essentials/artifacts/#artifact-retention-policies.
-`
-
 // pushCmd represents the push command
 var pushCmd = &cobra.Command{
 	Use:   "push",
@@ -66,53 +63,321 @@ while the rest of the semaphore process, or after it.`,
 }
 
 func runPushForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) (*files.ResolvedPath, *storage.PushStats, error) {
-	localSource, err := getSrc(args)
+	if err := checkTransferStatsFlags(cmd); err != nil {
+		return nil, nil, err
+	}
+
+	fromURL, err := cmd.Flags().GetString("from-url")
 	errutil.Check(err)
 
+	stdin, err := cmd.Flags().GetBool("stdin")
+	errutil.Check(err)
+
+	if stdin && (fromURL != "" || len(args) > 0) {
+		return nil, nil, fmt.Errorf("use either a source path, --from-url or --stdin, not several")
+	}
+
+	if fromURL != "" && len(args) > 0 {
+		return nil, nil, fmt.Errorf("use either a source path or --from-url, not both")
+	}
+
+	if fromURL == "" && !stdin && len(args) == 0 {
+		return nil, nil, fmt.Errorf("a source path, --from-url or --stdin is required")
+	}
+
+	archive, err := cmd.Flags().GetString("archive")
+	errutil.Check(err)
+
+	if archive != "" && (stdin || fromURL != "" || shouldUseStdin(args[0])) {
+		return nil, nil, fmt.Errorf("--archive needs a local file or directory, not --from-url or --stdin")
+	}
+
+	if archive != "" && (cmd.Flags().Changed("include") || cmd.Flags().Changed("exclude")) {
+		return nil, nil, fmt.Errorf("--archive cannot be used with --include or --exclude")
+	}
+
+	keepGoing, err := cmd.Flags().GetBool("keep-going")
+	errutil.Check(err)
+
+	deltaPush, err := cmd.Flags().GetBool("delta")
+	errutil.Check(err)
+
+	if deltaPush && (stdin || fromURL != "" || archive != "" || shouldUseStdin(args[0])) {
+		return nil, nil, fmt.Errorf("--delta needs a local file, not --from-url, --stdin or --archive")
+	}
+
+	transactional, err := cmd.Flags().GetBool("transactional")
+	errutil.Check(err)
+
+	cas, err := cmd.Flags().GetBool("cas")
+	errutil.Check(err)
+
+	if transactional && cas {
+		return nil, nil, fmt.Errorf("use either --transactional or --cas, not both")
+	}
+
+	// --cas pushes are transactional, storing their files as blobs
+	transactionalFlag := "--transactional"
+	if cas {
+		transactional, transactionalFlag = true, "--cas"
+	}
+
+	if transactional && (stdin || fromURL != "" || archive != "" || shouldUseStdin(args[0])) {
+		return nil, nil, fmt.Errorf("%s needs a local directory, not --from-url, --stdin or --archive", transactionalFlag)
+	}
+
+	packed, err := parsePushPack(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if packed != nil && (stdin || fromURL != "" || archive != "" || shouldUseStdin(args[0])) {
+		return nil, nil, fmt.Errorf("--pack needs a local directory, not --from-url, --stdin or --archive")
+	}
+
+	limits, err := parsePushLimits(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	withManifest, err := cmd.Flags().GetBool("manifest")
+	errutil.Check(err)
+
+	if withManifest && (stdin || fromURL != "" || archive != "" || shouldUseStdin(args[0])) {
+		return nil, nil, fmt.Errorf("--manifest needs a local directory, not --from-url, --stdin or --archive")
+	}
+
 	destinationOverride, err := cmd.Flags().GetString("destination")
 	errutil.Check(err)
 
 	force, err := cmd.Flags().GetBool("force")
 	errutil.Check(err)
 
-	expireIn, err := cmd.Flags().GetString("expire-in")
+	expireIn, err := parseExpireIn(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	lock, err := parseObjectLock(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	metadata, err := parsePushMetadata(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata = withExpiry(metadata, expireIn, time.Now())
+
+	concurrency, err := cmd.Flags().GetInt("concurrency")
 	errutil.Check(err)
-	if len(expireIn) != 0 {
-		displayWarningThatExpireInIsNoLongerSupported()
+
+	if concurrency < 1 {
+		return nil, nil, fmt.Errorf("--concurrency must be at least 1")
+	}
+
+	compression, err := parsePushCompress(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	recipients, err := parsePushEncrypt(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if deltaPush && (compression != "" || recipients != nil) {
+		return nil, nil, fmt.Errorf("--delta cannot be used with --compress or --encrypt")
+	}
+
+	if packed != nil && (compression != "" || recipients != nil) {
+		return nil, nil, fmt.Errorf("--pack cannot be used with --compress or --encrypt")
+	}
+
+	if cas && (compression != "" || recipients != nil) {
+		return nil, nil, fmt.Errorf("--cas cannot be used with --compress or --encrypt")
+	}
+
+	opts := backend.PushOptions{Force: force, Lock: lock, Metadata: metadata, ExpireIn: expireIn, Concurrency: concurrency, Compress: compression, Encrypt: recipients}
+	opts.Headers = parsePushHeaders(cmd)
+
+	if fromURL != "" {
+		return runPushFromURL(cmd, resolver, fromURL, destinationOverride, opts, limits)
+	}
+
+	if stdin || shouldUseStdin(args[0]) {
+		return runPushFromStdin(resolver, cmd.InOrStdin(), destinationOverride, opts)
+	}
+
+	ifChanged, err := cmd.Flags().GetBool("if-changed")
+	errutil.Check(err)
+
+	forceIfDifferent, err := cmd.Flags().GetBool("force-if-different")
+	errutil.Check(err)
+
+	if archive != "" {
+		if ifChanged || forceIfDifferent {
+			return nil, nil, fmt.Errorf("--archive cannot be used with --if-changed or --force-if-different")
+		}
+
+		if err := limits.check(args[0], nil); err != nil {
+			return nil, nil, err
+		}
+
+		return runPushAsArchive(resolver, args[0], destinationOverride, archive, opts)
 	}
 
 	// Resolve paths
-	paths, err := resolver.Resolve(files.OperationPush, localSource, destinationOverride)
+	paths, err := resolver.Resolve(files.OperationPush, args[0], destinationOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filter, err := parsePushFilter(cmd, paths.Source)
 	if err != nil {
 		return nil, nil, err
 	}
 
+	if err := limits.check(paths.Source, filter); err != nil {
+		return nil, nil, err
+	}
+
+	// Check the push against the policy before uploading anything
+	localStats, err := checkPushPolicy(resolver, paths, metadata, filter)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if withManifest {
+		if info, err := os.Stat(paths.Source); err == nil && !info.IsDir() {
+			return nil, nil, fmt.Errorf("--manifest needs a directory, '%s' is a file", paths.Source)
+		}
+	}
+
+	if transactional {
+		if ifChanged || forceIfDifferent || deltaPush || keepGoing || withManifest {
+			return nil, nil, fmt.Errorf("%s cannot be used with --if-changed, --force-if-different, --delta, --keep-going or --manifest", transactionalFlag)
+		}
+
+		if info, err := os.Stat(paths.Source); err == nil && !info.IsDir() {
+			return nil, nil, fmt.Errorf("%s needs a directory, '%s' is a file", transactionalFlag, paths.Source)
+		}
+	}
+
+	if packed != nil {
+		if ifChanged || forceIfDifferent || deltaPush || transactional || keepGoing {
+			return nil, nil, fmt.Errorf("--pack cannot be used with --if-changed, --force-if-different, --delta, --transactional, --cas or --keep-going")
+		}
+
+		if info, err := os.Stat(paths.Source); err == nil && !info.IsDir() {
+			return nil, nil, fmt.Errorf("--pack needs a directory, '%s' is a file", paths.Source)
+		}
+	}
+
+	if deltaPush {
+		if ifChanged || forceIfDifferent {
+			return nil, nil, fmt.Errorf("--delta cannot be used with --if-changed or --force-if-different")
+		}
+
+		if info, err := os.Stat(paths.Source); err == nil && info.IsDir() {
+			return nil, nil, fmt.Errorf("--delta needs a file, '%s' is a directory", paths.Source)
+		}
+	}
+
 	// Get the configured backend
 	b := getBackend()
 	defer func() { _ = b.Close() }()
 
-	// Push using the backend
 	ctx := getContext()
-	err = b.Push(ctx, paths.Source, paths.Destination, backend.PushOptions{Force: force})
+
+	// Record the progress, so an interrupted push can be resumed
+	noResume, err := cmd.Flags().GetBool("no-resume")
+	errutil.Check(err)
+
+	opts.Resume = openPushState(paths, noResume)
+
+	ctx, tracker := startProgress(ctx, cmd, "Pushed")
+	tracker.SetTotal(localStats.FileCount, localStats.TotalSize)
+
+	// Only push files that differ from the stored ones
+	if ifChanged || forceIfDifferent {
+		changedOpts := opts
+		changedOpts.Force = force || forceIfDifferent
+
+		stats, skipped, err := pushChanged(ctx, b, paths, filter, changedOpts, keepGoing)
+		tracker.Stop()
+		finishPushState(opts.Resume, err)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if skipped > 0 {
+			log.Infof("Skipped %d unchanged %s.\n", skipped, pluralize(skipped, "file", "files"))
+		}
+
+		if withManifest {
+			if err := pushManifest(ctx, b, paths, metadata, filter); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		return paths, stats, nil
+	}
+
+	// Push the files a filter keeps, or all of them with --keep-going, one
+	// by one, or else the whole path at once
+	if transactional {
+		_, err = pushTransactional(ctx, b, paths, filter, metadata, opts, cas)
+	} else if packed != nil {
+		_, err = pushPacked(ctx, b, paths, filter, packed, opts)
+	} else if deltaPush {
+		err = pushDelta(ctx, b, paths.Source, paths.Destination, opts)
+	} else if filter != nil || keepGoing {
+		var pushed []pushedFile
+		pushed, err = walkPushed(paths, filter)
+		if err == nil {
+			_, _, err = pushEach(ctx, b, pushed, opts, keepGoing, nil)
+		}
+	} else {
+		err = b.Push(ctx, paths.Source, paths.Destination, opts)
+	}
+	tracker.Stop()
+	finishPushState(opts.Resume, err)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	// Get stats (approximate - backend doesn't return detailed stats yet)
-	stats, err := getLocalStats(paths.Source)
-	if err != nil {
-		return paths, &storage.PushStats{}, nil
+	if withManifest {
+		if err := pushManifest(ctx, b, paths, metadata, filter); err != nil {
+			return nil, nil, err
+		}
 	}
 
-	return paths, stats, nil
+	// Stats are approximate - backend doesn't return detailed stats yet
+	return paths, localStats, nil
 }
 
-func displayWarningThatExpireInIsNoLongerSupported() {
-	fmt.Println("")
-	fmt.Println("WARNING: The --expire-in flag is obsolete and will have no efffect.")
-	fmt.Println("Use artifact retention policies to control the lifetime of artifacts.")
-	fmt.Println("Docs: https://docs.semaphoreci.com/essentials/artifacts/#artifact-retention-policies")
-	fmt.Println("")
+// checkPushPolicy checks a push of the local paths.Source, or of the files
+// of it the filter keeps, against the policy, and returns the stats of the
+// files to push.
+func checkPushPolicy(resolver *files.PathResolver, paths *files.ResolvedPath, metadata map[string]string, filter *files.Filter) (*storage.PushStats, error) {
+	info, err := os.Stat(paths.Source)
+	if err != nil {
+		return nil, err
+	}
+
+	localStats, err := getLocalStats(paths.Source, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	request := policyRequest(policy.OperationPush, resolver, paths.Destination)
+	request.Dir, request.Size, request.Metadata = info.IsDir(), localStats.TotalSize, metadata
+	if err := checkPolicy(request); err != nil {
+		return nil, err
+	}
+
+	return localStats, nil
 }
 
 func NewPushJobCmd() *cobra.Command {
@@ -120,7 +385,7 @@ func NewPushJobCmd() *cobra.Command {
 		Use:   "job [SOURCE PATH]",
 		Short: "Uploads a job file or directory to the storage.",
 		Long:  ``,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			jobId, err := cmd.Flags().GetString("job-id")
@@ -132,6 +397,8 @@ func NewPushJobCmd() *cobra.Command {
 			paths, stats, err := runPushForCategory(cmd, args, resolver)
 			if err != nil {
 				log.Errorf("Error pushing artifact: %v\n", err)
+				reportTransfer(cmd, "push", nil, 0, 0, err)
+				logResumeHint("push")
 				errutil.Exit(1)
 				return
 			}
@@ -139,13 +406,33 @@ func NewPushJobCmd() *cobra.Command {
 			log.Info("Successfully pushed artifact for current job.\n")
 			log.Infof("* Local source: %s.\n", paths.Source)
 			log.Infof("* Remote destination: %s.\n", paths.Destination)
-			log.Infof("Pushed %d %s. Total of %s\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize))
+			reportTransfer(cmd, "push", paths, stats.FileCount, stats.TotalSize, nil)
 		},
 	}
 
 	cmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
 	cmd.Flags().BoolP("force", "f", false, "force overwrite")
 	cmd.Flags().StringP("expire-in", "e", "", ExpireInDescription)
+	cmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory uploaded at once")
+	addPushURLFlags(cmd)
+	addPushStdinFlags(cmd)
+	addPushArchiveFlags(cmd)
+	addPushChecksumFlags(cmd)
+	addPushManifestFlags(cmd)
+	addPushResumeFlags(cmd)
+	addPushCompressFlags(cmd)
+	addPushEncryptFlags(cmd)
+	addPushHeaderFlags(cmd)
+	addPushFilterFlags(cmd)
+	addKeepGoingFlags(cmd, "push")
+	addPushDeltaFlags(cmd)
+	addPushTransactionalFlags(cmd)
+	addPushPackFlags(cmd)
+	addPushLimitFlags(cmd)
+	addProgressFlags(cmd)
+	addTransferStatsFlags(cmd)
+	addPushLockFlags(cmd)
+	addPushMetadataFlags(cmd)
 	cmd.Flags().StringP("job-id", "j", "", "set explicit job id")
 
 	return cmd
@@ -156,7 +443,7 @@ func NewPushWorkflowCmd() *cobra.Command {
 		Use:   "workflow [SOURCE PATH]",
 		Short: "Uploads a workflow or directory file to the storage.",
 		Long:  ``,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			workflowId, err := cmd.Flags().GetString("workflow-id")
@@ -168,6 +455,8 @@ func NewPushWorkflowCmd() *cobra.Command {
 			paths, stats, err := runPushForCategory(cmd, args, resolver)
 			if err != nil {
 				log.Errorf("Error pushing artifact: %v\n", err)
+				reportTransfer(cmd, "push", nil, 0, 0, err)
+				logResumeHint("push")
 				errutil.Exit(1)
 				return
 			}
@@ -175,13 +464,33 @@ func NewPushWorkflowCmd() *cobra.Command {
 			log.Info("Successfully pushed artifact for current workflow.\n")
 			log.Infof("* Local source: %s.\n", paths.Source)
 			log.Infof("* Remote destination: %s.\n", paths.Destination)
-			log.Infof("Pushed %d %s. Total of %s\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize))
+			reportTransfer(cmd, "push", paths, stats.FileCount, stats.TotalSize, nil)
 		},
 	}
 
 	cmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
 	cmd.Flags().BoolP("force", "f", false, "force overwrite")
 	cmd.Flags().StringP("expire-in", "e", "", ExpireInDescription)
+	cmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory uploaded at once")
+	addPushURLFlags(cmd)
+	addPushStdinFlags(cmd)
+	addPushArchiveFlags(cmd)
+	addPushChecksumFlags(cmd)
+	addPushManifestFlags(cmd)
+	addPushResumeFlags(cmd)
+	addPushCompressFlags(cmd)
+	addPushEncryptFlags(cmd)
+	addPushHeaderFlags(cmd)
+	addPushFilterFlags(cmd)
+	addKeepGoingFlags(cmd, "push")
+	addPushDeltaFlags(cmd)
+	addPushTransactionalFlags(cmd)
+	addPushPackFlags(cmd)
+	addPushLimitFlags(cmd)
+	addProgressFlags(cmd)
+	addTransferStatsFlags(cmd)
+	addPushLockFlags(cmd)
+	addPushMetadataFlags(cmd)
 	cmd.Flags().StringP("workflow-id", "w", "", "set explicit workflow id")
 
 	return cmd
@@ -192,7 +501,7 @@ func NewPushProjectCmd() *cobra.Command {
 		Use:   "project [SOURCE PATH]",
 		Short: "Upload a project file or directory to the storage.",
 		Long:  ``,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			projectId, err := cmd.Flags().GetString("project-id")
@@ -204,6 +513,8 @@ func NewPushProjectCmd() *cobra.Command {
 			paths, stats, err := runPushForCategory(cmd, args, resolver)
 			if err != nil {
 				log.Errorf("Error pushing artifact: %v\n", err)
+				reportTransfer(cmd, "push", nil, 0, 0, err)
+				logResumeHint("push")
 				errutil.Exit(1)
 				return
 			}
@@ -211,13 +522,33 @@ func NewPushProjectCmd() *cobra.Command {
 			log.Info("Successfully pushed artifact for current project.\n")
 			log.Infof("* Local source: %s.\n", paths.Source)
 			log.Infof("* Remote destination: %s.\n", paths.Destination)
-			log.Infof("Pushed %d %s. Total of %s\n", stats.FileCount, pluralize(stats.FileCount, "file", "files"), formatBytes(stats.TotalSize))
+			reportTransfer(cmd, "push", paths, stats.FileCount, stats.TotalSize, nil)
 		},
 	}
 
 	cmd.Flags().StringP("destination", "d", "", "rename the file while uploading")
 	cmd.Flags().BoolP("force", "f", false, "force overwrite")
 	cmd.Flags().StringP("expire-in", "e", "", ExpireInDescription)
+	cmd.Flags().Int("concurrency", backend.DefaultConcurrency, "number of files of a directory uploaded at once")
+	addPushURLFlags(cmd)
+	addPushStdinFlags(cmd)
+	addPushArchiveFlags(cmd)
+	addPushChecksumFlags(cmd)
+	addPushManifestFlags(cmd)
+	addPushResumeFlags(cmd)
+	addPushCompressFlags(cmd)
+	addPushEncryptFlags(cmd)
+	addPushHeaderFlags(cmd)
+	addPushFilterFlags(cmd)
+	addKeepGoingFlags(cmd, "push")
+	addPushDeltaFlags(cmd)
+	addPushTransactionalFlags(cmd)
+	addPushPackFlags(cmd)
+	addPushLimitFlags(cmd)
+	addProgressFlags(cmd)
+	addTransferStatsFlags(cmd)
+	addPushLockFlags(cmd)
+	addPushMetadataFlags(cmd)
 	cmd.Flags().StringP("project-id", "p", "", "set explicit project id")
 
 	return cmd
@@ -229,68 +560,3 @@ func init() {
 	pushCmd.AddCommand(NewPushWorkflowCmd())
 	pushCmd.AddCommand(NewPushProjectCmd())
 }
-
-func getSrc(args []string) (string, error) {
-	input := args[0]
-	if shouldUseStdin(input) {
-		log.Debug("Detected stdin, saving it to a temporary file...\n")
-		return saveStdinToTempFile()
-	}
-
-	return input, nil
-}
-
-func shouldUseStdin(input string) bool {
-	if input == "-" || input == "/dev/stdin" {
-		return true
-	}
-
-	return false
-}
-
-func saveStdinToTempFile() (string, error) {
-	tmpFile, err := ioutil.TempFile("", "*")
-	if err != nil {
-		log.Errorf("Error creating temporary file to read stdin: %v\n", err)
-		return "", err
-	}
-
-	r := bufio.NewReader(os.Stdin)
-	buf := make([]byte, 0, 4*1024)
-
-	for {
-		nRead, err := r.Read(buf[:cap(buf)])
-		buf = buf[:nRead]
-
-		if nRead == 0 {
-			// nothing was read and no error was thrown, so just try again
-			if err == nil {
-				continue
-			}
-
-			// there's nothing more to read
-			if err == io.EOF {
-				break
-			}
-
-			// nothing was read and we had an error
-			log.Errorf("Error reading stdin: %v\n", err)
-			return "", err
-		}
-
-		// something was read, but we still got an error
-		if err != nil && err != io.EOF {
-			log.Errorf("Error reading stdin: %v\n", err)
-			return "", err
-		}
-
-		// no errors when reading from stdin, just write it to the temporary file
-		_, err = tmpFile.Write(buf)
-		if err != nil {
-			log.Errorf("Error writing to temp file: %v\n", err)
-			return "", err
-		}
-	}
-
-	return tmpFile.Name(), nil
-}
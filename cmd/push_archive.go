@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushArchiveFlags(cmd *cobra.Command) {
+	cmd.Flags().String("archive", "", "bundle the file or directory into a single tar.gz or zip archive; pull it with --extract")
+}
+
+// runPushAsArchive streams the file or directory at source as an archive
+// to the backend, so a tree of many small files is stored as one object.
+// The archive is named after the source unless a destination is given.
+func runPushAsArchive(resolver *files.PathResolver, source, destinationOverride, format string, opts backend.PushOptions) (*files.ResolvedPath, *storage.PushStats, error) {
+	if err := files.ValidateArchiveFormat(format); err != nil {
+		return nil, nil, err
+	}
+
+	if destinationOverride == "" {
+		destinationOverride = path.Base(path.Clean(source)) + "." + format
+	}
+
+	paths, err := resolver.Resolve(files.OperationPush, source, destinationOverride)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	localStats, err := checkPushPolicy(resolver, paths, opts.Metadata, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	size, err := pushArchive(getContext(), b, paths, format, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	log.Infof("Archived %d %s (%s) into %s of %s.\n", localStats.FileCount, pluralize(localStats.FileCount, "file", "files"), formatBytes(localStats.TotalSize), format, formatBytes(size))
+	return paths, localStats, nil
+}
+
+// pushArchive pushes the local paths.Source as an archive to
+// paths.Destination, and returns the size of the archive.
+func pushArchive(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, format string, opts backend.PushOptions) (int64, error) {
+	return pushArchiveStream(ctx, b, paths.Destination, opts, func(w io.Writer) error {
+		_, _, err := files.WriteArchive(w, format, paths.Source)
+		return err
+	})
+}
+
+// pushArchiveStream pushes the archive written by write to remotePath, and
+// returns its size.
+func pushArchiveStream(ctx context.Context, b backend.Backend, remotePath string, opts backend.PushOptions, write func(io.Writer) error) (int64, error) {
+	// The archive is written while it is uploaded, so it never touches the disk
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(write(pw))
+	}()
+
+	counter := &countingWriter{}
+	err := pushStream(ctx, b, io.TeeReader(pr, counter), -1, remotePath, opts)
+	pr.CloseWithError(err)
+	if err != nil {
+		return 0, fmt.Errorf("failed to push archive: %v", err)
+	}
+
+	return counter.n, nil
+}
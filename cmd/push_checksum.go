@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushChecksumFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("if-changed", false, "skip files whose stored checksum matches the local file")
+	cmd.Flags().Bool("force-if-different", false, "overwrite files whose stored checksum differs from the local file, skip identical ones")
+}
+
+// getChecksumReader returns the backend's checksum capability, if it has one.
+func getChecksumReader(b backend.Backend) (backend.ChecksumReader, error) {
+	reader, ok := b.(backend.ChecksumReader)
+	if !ok {
+		return nil, fmt.Errorf("the configured backend does not store checksums")
+	}
+
+	return reader, nil
+}
+
+// remoteChecksum returns the checksum stored for remotePath,
+// or "" if the file does not exist or has no checksum.
+func remoteChecksum(ctx context.Context, reader backend.ChecksumReader, remotePath string) (string, error) {
+	checksum, err := reader.Checksum(ctx, remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return "", nil
+	}
+
+	return checksum, err
+}
+
+// pushChanged pushes the files under paths.Source the filter keeps,
+// opts.Concurrency at a time, skipping the ones identical to the stored
+// file. Directories are compared with a single listing of the remote one,
+// if the backend can list: files missing from it are new, and files whose
+// ETag is their digest are identical, with no request for either. Other
+// files are compared with their stored checksum; files without one count
+// as changed. It returns the stats of the pushed files and the number of
+// skipped ones. With keepGoing, failed files do not stop the push, see
+// pushEach.
+func pushChanged(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, filter *files.Filter, opts backend.PushOptions, keepGoing bool) (*storage.PushStats, int, error) {
+	reader, err := getChecksumReader(b)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	pushed, err := walkPushed(paths, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	stored := listStored(ctx, b, paths)
+	return pushEach(ctx, b, pushed, opts, keepGoing, func(f pushedFile) (bool, error) {
+		return pushUnchanged(ctx, reader, stored, f.LocalPath, f.RemotePath, opts)
+	})
+}
+
+// listStored returns the files stored under the remote directory pushed
+// to, or nil if it cannot be listed, e.g. when a single file is pushed.
+func listStored(ctx context.Context, b backend.Backend, paths *files.ResolvedPath) map[string]backend.ObjectInfo {
+	lister, ok := b.(backend.Lister)
+	if info, err := os.Stat(paths.Source); !ok || err != nil || !info.IsDir() {
+		return nil
+	}
+
+	stored := map[string]backend.ObjectInfo{}
+	err := walkRemote(ctx, lister, paths.Destination, func(obj backend.ObjectInfo) error {
+		stored[obj.Path] = obj
+		return nil
+	})
+	if err != nil {
+		log.Debugf("Failed to list '%s', comparing files one by one: %v\n", paths.Destination, err)
+		return nil
+	}
+
+	return stored
+}
+
+// pushUnchanged reports whether the local file is identical to the one
+// stored at remotePath. stored is the listing of the remote directory, or
+// nil if there is none. Files pushed compressed or encrypted are stored
+// encoded, so only their stored checksum tells.
+func pushUnchanged(ctx context.Context, reader backend.ChecksumReader, stored map[string]backend.ObjectInfo, filename, remotePath string, opts backend.PushOptions) (bool, error) {
+	if stored == nil {
+		return checksumUnchanged(ctx, reader, filename, remotePath)
+	}
+
+	obj, ok := stored[remotePath]
+	if !ok {
+		return false, nil
+	}
+
+	if opts.Compress != "" || opts.Encrypt != nil {
+		return checksumUnchanged(ctx, reader, filename, remotePath)
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return false, err
+	}
+
+	return syncUnchanged(ctx, reader, filename, info, obj)
+}
+
+// checksumUnchanged reports whether the checksum stored for remotePath is
+// the one of the local file.
+func checksumUnchanged(ctx context.Context, reader backend.ChecksumReader, filename, remotePath string) (bool, error) {
+	localChecksum, err := files.SHA256File(filename)
+	if err != nil {
+		return false, err
+	}
+
+	storedChecksum, err := remoteChecksum(ctx, reader, remotePath)
+	if err != nil {
+		return false, err
+	}
+
+	return storedChecksum == localChecksum, nil
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/semaphoreci/artifact/pkg/compress"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func addPushCompressFlags(cmd *cobra.Command) {
+	cmd.Flags().String("compress", "", "compress files while uploading, with gzip or zstd; pulls decompress them")
+}
+
+// parsePushCompress returns the compression requested with --compress,
+// or "" to store files as they are.
+func parsePushCompress(cmd *cobra.Command) (string, error) {
+	encoding, err := cmd.Flags().GetString("compress")
+	errutil.Check(err)
+
+	if encoding == "" {
+		return "", nil
+	}
+
+	return encoding, compress.Validate(encoding)
+}
@@ -0,0 +1,221 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/delta"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushDeltaFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("delta", false, "push a large file as blocks, uploading only the blocks that changed since its last push")
+}
+
+// pushDelta pushes the local file at localPath to remotePath as blocks,
+// see pkg/delta: the blocks the previous version of the file did not have
+// are uploaded, then the index of the file replaces it. The blocks the
+// version before the previous one retired are deleted afterwards.
+func pushDelta(ctx context.Context, b backend.Backend, localPath, remotePath string, opts backend.PushOptions) error {
+	exists, err := b.Exists(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if exists && !opts.Force {
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	}
+
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	index, err := delta.Split(f)
+	if err != nil {
+		return fmt.Errorf("failed to split '%s' into blocks: %v", localPath, err)
+	}
+
+	previous := &delta.Index{}
+	if exists {
+		previous = previousDeltaIndex(ctx, b, remotePath)
+	}
+
+	stored := map[string]bool{}
+	for _, sum := range previous.Retired {
+		stored[sum] = true
+	}
+	for _, block := range previous.Blocks {
+		stored[block.SHA256] = true
+	}
+
+	// Upload every missing block once, even if the file repeats it
+	missing := []delta.Block{}
+	for _, block := range index.Blocks {
+		if stored[block.SHA256] {
+			progress.FromContext(ctx).Skip(block.Size)
+			continue
+		}
+
+		stored[block.SHA256] = true
+		missing = append(missing, block)
+	}
+
+	blockOpts := backend.PushOptions{Force: true, Concurrency: 1}
+	err = backend.Parallel(len(missing), opts.Concurrency, func(i int) error {
+		block := missing[i]
+		r := io.NewSectionReader(f, block.Offset, block.Size)
+		return pushStream(ctx, b, r, block.Size, delta.BlockPath(remotePath, block.SHA256), blockOpts)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload the blocks of '%s': %v", localPath, err)
+	}
+
+	uploaded := int64(0)
+	for _, block := range missing {
+		uploaded += block.Size
+	}
+
+	log.Infof("Uploaded %d of %d blocks of '%s' (%s of %s).\n", len(missing), len(index.Blocks), localPath, formatBytes(uploaded), formatBytes(index.Size))
+
+	retired := map[string]bool{}
+	for _, block := range previous.Blocks {
+		if !index.Uses(block.SHA256) && !retired[block.SHA256] {
+			retired[block.SHA256] = true
+			index.Retired = append(index.Retired, block.SHA256)
+		}
+	}
+
+	data, err := index.Marshal()
+	if err != nil {
+		return err
+	}
+
+	indexOpts := opts
+	indexOpts.Force = true
+	if err := pushStream(ctx, b, bytes.NewReader(data), int64(len(data)), remotePath, indexOpts); err != nil {
+		return err
+	}
+
+	deleteRetiredBlocks(ctx, b, remotePath, previous.Retired, index)
+	return nil
+}
+
+// previousDeltaIndex returns the index stored at remotePath, or an empty
+// one if the file stored there is no index, e.g. because it was not pushed
+// with --delta.
+func previousDeltaIndex(ctx context.Context, b backend.Backend, remotePath string) *delta.Index {
+	r, err := openRemote(ctx, b, remotePath)
+	if err != nil {
+		return &delta.Index{}
+	}
+	defer r.Close()
+
+	head := make([]byte, len(delta.Magic))
+	if _, err := io.ReadFull(r, head); err != nil || !delta.IsIndex(head) {
+		return &delta.Index{}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return &delta.Index{}
+	}
+
+	index, err := delta.Parse(append(head, data...))
+	if err != nil {
+		log.Debugf("Ignoring the invalid index of '%s': %v\n", remotePath, err)
+		return &delta.Index{}
+	}
+
+	return index
+}
+
+// deleteRetiredBlocks deletes the retired blocks of remotePath the index
+// does not use again. Failing to delete them is only logged: they take
+// space, but break nothing, and are yanked with the file.
+func deleteRetiredBlocks(ctx context.Context, b backend.Backend, remotePath string, retired []string, index *delta.Index) {
+	unused := []string{}
+	for _, sum := range retired {
+		if !index.Uses(sum) {
+			unused = append(unused, sum)
+		}
+	}
+
+	err := backend.Parallel(len(unused), backend.DefaultConcurrency, func(i int) error {
+		return b.Yank(ctx, delta.BlockPath(remotePath, unused[i]))
+	})
+	if err != nil {
+		log.Warnf("Failed to delete the unused blocks of '%s': %v\n", remotePath, err)
+	}
+}
+
+// expandDeltas reassembles the files pulled from remotePath to localPath
+// that are the index of a file pushed with --delta, see pushDelta.
+func expandDeltas(ctx context.Context, b backend.Backend, remotePath, localPath string) error {
+	return filepath.Walk(localPath, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Size() < int64(len(delta.Magic)) {
+			return err
+		}
+
+		rel, err := filepath.Rel(localPath, filename)
+		if err != nil {
+			return err
+		}
+
+		return expandDelta(ctx, b, path.Join(remotePath, filepath.ToSlash(rel)), filename)
+	})
+}
+
+// expandDelta reassembles the file pulled from remotePath to localPath if
+// it is an index, downloading its blocks.
+func expandDelta(ctx context.Context, b backend.Backend, remotePath, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+
+	head := make([]byte, len(delta.Magic))
+	_, err = io.ReadFull(f, head)
+	if err != nil || !delta.IsIndex(head) {
+		f.Close()
+		return nil
+	}
+
+	data, err := io.ReadAll(io.MultiReader(bytes.NewReader(head), f))
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	index, err := delta.Parse(data)
+	if err != nil {
+		return fmt.Errorf("failed to reassemble '%s': %v", localPath, err)
+	}
+
+	log.Debugf("Reassembling '%s' from %d blocks...\n", localPath, len(index.Blocks))
+
+	open := func(block delta.Block) (io.ReadCloser, error) {
+		return openRemote(ctx, b, delta.BlockPath(remotePath, block.SHA256))
+	}
+
+	pr, pw := io.Pipe()
+	go func() { _ = pw.CloseWithError(index.Assemble(pw, open)) }()
+
+	err = files.WriteAtomically(localPath, pr)
+	_ = pr.CloseWithError(err)
+	if err != nil {
+		return fmt.Errorf("failed to reassemble '%s': %v", localPath, err)
+	}
+
+	return nil
+}
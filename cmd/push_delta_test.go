@@ -0,0 +1,116 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/delta"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__PushDelta(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+	remotePath := "artifacts/projects/1/model.ckpt"
+
+	blocks := func() []string {
+		paths := []string{}
+		for _, p := range b.Paths() {
+			if strings.HasPrefix(p, delta.BlockDir(remotePath)+"/") {
+				paths = append(paths, p)
+			}
+		}
+		return paths
+	}
+
+	pull := func() []byte {
+		localPath := filepath.Join(t.TempDir(), "model.ckpt")
+		paths := &files.ResolvedPath{Source: remotePath, Destination: localPath}
+		stats, err := pullResolved(ctx, b, paths, backend.PullOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 1, stats.FileCount)
+
+		data, err := os.ReadFile(localPath)
+		require.NoError(t, err)
+		return data
+	}
+
+	data := make([]byte, 8*1024*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	localPath := filepath.Join(t.TempDir(), "model.ckpt")
+	require.NoError(t, os.WriteFile(localPath, data, 0644))
+
+	require.NoError(t, pushDelta(ctx, b, localPath, remotePath, backend.PushOptions{}))
+	stored, ok := b.Get(remotePath)
+	require.True(t, ok)
+	assert.True(t, delta.IsIndex(stored))
+	first := blocks()
+	assert.NotEmpty(t, first)
+	assert.Equal(t, data, pull())
+
+	// Pushing again needs --force
+	err := pushDelta(ctx, b, localPath, remotePath, backend.PushOptions{})
+	assert.ErrorContains(t, err, "already exists")
+
+	// Only the blocks around the change are uploaded, and the replaced
+	// ones are kept for one more push
+	copy(data[3*1024*1024:], "changed")
+	require.NoError(t, os.WriteFile(localPath, data, 0644))
+	require.NoError(t, pushDelta(ctx, b, localPath, remotePath, backend.PushOptions{Force: true}))
+	second := blocks()
+	added := len(second) - len(first)
+	assert.Greater(t, added, 0)
+	assert.LessOrEqual(t, added, 2)
+	assert.Equal(t, data, pull())
+
+	copy(data[6*1024*1024:], "changed again")
+	require.NoError(t, os.WriteFile(localPath, data, 0644))
+	require.NoError(t, pushDelta(ctx, b, localPath, remotePath, backend.PushOptions{Force: true}))
+	assert.Equal(t, data, pull())
+
+	// The blocks retired by the previous push are deleted
+	stored, _ = b.Get(remotePath)
+	index, err := delta.Parse(stored)
+	require.NoError(t, err)
+	assert.Len(t, index.Retired, 1)
+	assert.Len(t, blocks(), len(second))
+	for _, sum := range index.Retired {
+		assert.Contains(t, blocks(), delta.BlockPath(remotePath, sum))
+	}
+}
+
+func Test__PullDeltaAsTar(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+	b.Put("artifacts/projects/1/models/notes.txt", []byte("notes"))
+
+	data := make([]byte, 3*1024*1024)
+	rand.New(rand.NewSource(1)).Read(data)
+	localPath := filepath.Join(t.TempDir(), "model.ckpt")
+	require.NoError(t, os.WriteFile(localPath, data, 0644))
+	require.NoError(t, pushDelta(ctx, b, localPath, "artifacts/projects/1/models/model.ckpt", backend.PushOptions{}))
+
+	out := &bytes.Buffer{}
+	tw := tar.NewWriter(out)
+	stats, err := streamRemoteTar(tw, b, b, "artifacts/projects/1/models", "models")
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+
+	// The tar has the reassembled file, not its index
+	assert.Equal(t, 2, stats.FileCount)
+	assert.Equal(t, int64(len(data)+len("notes")), stats.TotalSize)
+	assert.Equal(t, map[string]string{
+		"models/model.ckpt": string(data),
+		"models/notes.txt":  "notes",
+	}, readTar(t, out))
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/semaphoreci/artifact/pkg/encrypt"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func addPushEncryptFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("encrypt", false, "encrypt files before uploading, to --recipient, $ARTIFACT_ENCRYPTION_RECIPIENTS or $ARTIFACT_ENCRYPTION_PASSPHRASE")
+	cmd.Flags().StringArray("recipient", []string{}, "age public key to encrypt to, with --encrypt; can be repeated")
+}
+
+// parsePushEncrypt returns the recipients requested with --encrypt: the
+// --recipient keys, or failing those, the ones of the environment. It
+// returns none to store files in the clear.
+func parsePushEncrypt(cmd *cobra.Command) ([]age.Recipient, error) {
+	enabled, err := cmd.Flags().GetBool("encrypt")
+	errutil.Check(err)
+
+	keys, err := cmd.Flags().GetStringArray("recipient")
+	errutil.Check(err)
+
+	if !enabled {
+		if len(keys) > 0 {
+			return nil, fmt.Errorf("--recipient needs --encrypt")
+		}
+		return nil, nil
+	}
+
+	if len(keys) > 0 {
+		return encrypt.ParseRecipients(strings.Join(keys, "\n"))
+	}
+
+	recipients, err := encrypt.RecipientsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(recipients) == 0 {
+		return nil, fmt.Errorf("--encrypt needs a key: pass --recipient, or set $%s, $%s or $%s",
+			encrypt.RecipientsEnv, encrypt.RecipientsFileEnv, encrypt.PassphraseEnv)
+	}
+
+	return recipients, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/spf13/cobra"
+)
+
+const ExpireInDescription = `removes the files after the given amount of time.
+
+- Nd for N days
+- Nw for N weeks
+- Nm for N months
+- Ny for N years
+- never, the default, keeps them until they are deleted
+
+Hub removes expired files itself. The S3 backend tags the files, so a
+bucket lifecycle rule can expire them; other backends record when they
+expire in the expire-at metadata.
+`
+
+// parseExpireIn returns how long the files pushed with --expire-in are
+// kept, or 0 to keep them until they are deleted.
+func parseExpireIn(cmd *cobra.Command) (time.Duration, error) {
+	value, _ := cmd.Flags().GetString("expire-in")
+	if value == "" || strings.EqualFold(value, "never") {
+		return 0, nil
+	}
+
+	return common.ParseAge(value)
+}
+
+// withExpiry returns metadata along with the time files pushed now expire,
+// for backends that cannot expire files themselves.
+func withExpiry(metadata map[string]string, expireIn time.Duration, now time.Time) map[string]string {
+	if expireIn <= 0 {
+		return metadata
+	}
+
+	withExpiry := map[string]string{}
+	for key, value := range metadata {
+		withExpiry[key] = value
+	}
+
+	withExpiry[backend.ExpireAtMetadataKey] = now.Add(expireIn).UTC().Format(time.RFC3339)
+	return withExpiry
+}
@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushFilterFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("include", []string{}, "only push the files of a directory matching this glob, e.g. '**/*.xml'; can be repeated")
+	cmd.Flags().StringArray("exclude", []string{}, "skip the files and directories matching this glob, e.g. '.git' or '**/*.tmp'; can be repeated")
+}
+
+// parsePushFilter returns the filter of the pushed directory source, from
+// --include, --exclude and its .artifactignore file, or nil if source is
+// a file, or nothing is filtered out.
+func parsePushFilter(cmd *cobra.Command, source string) (*files.Filter, error) {
+	include, err := cmd.Flags().GetStringArray("include")
+	errutil.Check(err)
+
+	exclude, err := cmd.Flags().GetStringArray("exclude")
+	errutil.Check(err)
+
+	if info, err := os.Stat(source); err != nil || !info.IsDir() {
+		return nil, nil
+	}
+
+	return files.NewFilter(source, include, exclude)
+}
+
+// pushedFile is a local file of a pushed directory.
+type pushedFile struct {
+	LocalPath  string
+	RemotePath string
+	Info       os.FileInfo
+}
+
+// walkPushed returns the files under paths.Source the filter keeps, with
+// the remote paths they are pushed to.
+func walkPushed(paths *files.ResolvedPath, filter *files.Filter) ([]pushedFile, error) {
+	pushed := []pushedFile{}
+	err := files.Walk(paths.Source, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(paths.Source, filename)
+		if err != nil {
+			return err
+		}
+
+		rel = filepath.ToSlash(rel)
+		if !filter.Keep(rel) {
+			log.Debugf("Skipping filtered out '%s'.\n", filename)
+			return nil
+		}
+
+		pushed = append(pushed, pushedFile{LocalPath: filename, RemotePath: path.Join(paths.Destination, rel), Info: info})
+		return nil
+	})
+
+	return pushed, err
+}
+
+// pushEach pushes the files one by one, opts.Concurrency at a time,
+// skipping the ones an interrupted run already pushed, and the ones
+// unchanged reports, if set. It returns the stats of the pushed files and
+// the number of files unchanged skipped. It stops at the first failure,
+// unless keepGoing is set: the other files are pushed, and a *failedFiles
+// error lists the failed ones.
+func pushEach(ctx context.Context, b backend.Backend, pushed []pushedFile, opts backend.PushOptions, keepGoing bool, unchanged func(pushedFile) (bool, error)) (*storage.PushStats, int, error) {
+	pending := []pushedFile{}
+	for _, f := range pushed {
+		if opts.Resume != nil && opts.Resume.Pushed(f.LocalPath, f.Info) {
+			progress.FromContext(ctx).Skip(f.Info.Size())
+			backend.TransferStatsFromContext(ctx).Skipped(f.Info.Size())
+			continue
+		}
+
+		pending = append(pending, f)
+	}
+
+	backend.LogResumed(len(pushed) - len(pending))
+
+	var mu sync.Mutex
+	transfer := backend.TransferStatsFromContext(ctx)
+	stats := &storage.PushStats{}
+	skipped := 0
+	failures := newFailedFiles("push", len(pending))
+
+	err := backend.Parallel(len(pending), opts.Concurrency, func(i int) error {
+		f := pending[i]
+		err := pushOne(ctx, b, f, opts, unchanged)
+		if err == errUnchanged {
+			progress.FromContext(ctx).Skip(f.Info.Size())
+			transfer.Skipped(f.Info.Size())
+			mu.Lock()
+			skipped++
+			mu.Unlock()
+			return nil
+		}
+
+		if err != nil {
+			transfer.Failed()
+		}
+
+		if err != nil && keepGoing {
+			failures.add(f.LocalPath, err)
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		transfer.Transferred(f.Info.Size())
+		mu.Lock()
+		stats.FileCount++
+		stats.TotalSize += f.Info.Size()
+		mu.Unlock()
+		return nil
+	})
+
+	if err == nil {
+		err = failures.err()
+	}
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return stats, skipped, nil
+}
+
+// errUnchanged is returned by pushOne for files it skips as unchanged.
+var errUnchanged = errors.New("unchanged")
+
+// pushOne pushes the file f, unless unchanged, if set, reports it is
+// unchanged, and records the push for resuming.
+func pushOne(ctx context.Context, b backend.Backend, f pushedFile, opts backend.PushOptions, unchanged func(pushedFile) (bool, error)) error {
+	if unchanged != nil {
+		same, err := unchanged(f)
+		if err != nil {
+			return err
+		}
+
+		if same {
+			log.Debugf("Skipping unchanged '%s'.\n", f.LocalPath)
+			return errUnchanged
+		}
+	}
+
+	if err := b.Push(ctx, f.LocalPath, f.RemotePath, opts); err != nil {
+		return err
+	}
+
+	if opts.Resume != nil {
+		if err := opts.Resume.MarkPushed(f.LocalPath, f.Info); err != nil {
+			log.Warnf("Failed to record the push of '%s': %v\n", f.LocalPath, err)
+		}
+	}
+
+	return nil
+}
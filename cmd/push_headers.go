@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+func addPushHeaderFlags(cmd *cobra.Command) {
+	cmd.Flags().String("content-type", "", "MIME type of the pushed files (default is detected from each file)")
+	cmd.Flags().String("cache-control", "", "Cache-Control header the pushed files are served with, e.g. max-age=3600")
+	cmd.Flags().String("content-disposition", "", "Content-Disposition header the pushed files are served with, e.g. attachment")
+}
+
+// parsePushHeaders returns the headers requested with --content-type,
+// --cache-control and --content-disposition.
+func parsePushHeaders(cmd *cobra.Command) backend.Headers {
+	contentType, err := cmd.Flags().GetString("content-type")
+	errutil.Check(err)
+
+	cacheControl, err := cmd.Flags().GetString("cache-control")
+	errutil.Check(err)
+
+	contentDisposition, err := cmd.Flags().GetString("content-disposition")
+	errutil.Check(err)
+
+	return backend.Headers{ContentType: contentType, CacheControl: cacheControl, ContentDisposition: contentDisposition}
+}
@@ -0,0 +1,142 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+func addPushLimitFlags(cmd *cobra.Command) {
+	cmd.Flags().String("max-file-size", "", "fail before uploading anything if a file is larger than this, e.g. 500MB")
+	cmd.Flags().String("max-total-size", "", "fail before uploading anything if the files add up to more than this, e.g. 10GB")
+	cmd.Flags().Int("max-files", 0, "fail before uploading anything if there are more files than this")
+}
+
+// pushLimits are the largest file, total size and number of files a push
+// of local files may have. Zero values are no limit.
+type pushLimits struct {
+	MaxFileSize  int64
+	MaxTotalSize int64
+	MaxFiles     int
+}
+
+// errLimitExceeded stops the walk of checkPushLimits at the first file
+// over a limit.
+var errLimitExceeded = errors.New("limit exceeded")
+
+// parsePushLimits returns the limits of --max-file-size, --max-total-size
+// and --max-files, or failing that, of the ARTIFACT_PUSH_MAX_FILE_SIZE,
+// ARTIFACT_PUSH_MAX_TOTAL_SIZE and ARTIFACT_PUSH_MAX_FILES env vars, or of
+// the push.maxFileSize, push.maxTotalSize and push.maxFiles config keys.
+func parsePushLimits(cmd *cobra.Command) (pushLimits, error) {
+	limits := pushLimits{}
+
+	maxFileSize := pushLimitSetting(cmd, "max-file-size", "ARTIFACT_PUSH_MAX_FILE_SIZE", "push.maxFileSize")
+	maxTotalSize := pushLimitSetting(cmd, "max-total-size", "ARTIFACT_PUSH_MAX_TOTAL_SIZE", "push.maxTotalSize")
+	maxFiles := pushLimitSetting(cmd, "max-files", "ARTIFACT_PUSH_MAX_FILES", "push.maxFiles")
+
+	var err error
+	if maxFileSize != "" {
+		if limits.MaxFileSize, err = common.ParseSize(maxFileSize); err != nil {
+			return limits, fmt.Errorf("--max-file-size: %v", err)
+		}
+	}
+
+	if maxTotalSize != "" {
+		if limits.MaxTotalSize, err = common.ParseSize(maxTotalSize); err != nil {
+			return limits, fmt.Errorf("--max-total-size: %v", err)
+		}
+	}
+
+	if maxFiles != "" {
+		if limits.MaxFiles, err = strconv.Atoi(maxFiles); err != nil || limits.MaxFiles < 0 {
+			return limits, fmt.Errorf("--max-files: invalid number of files '%s'", maxFiles)
+		}
+	}
+
+	return limits, nil
+}
+
+// pushLimitSetting returns the value of the flag, if set, or else of the
+// env var or config key.
+func pushLimitSetting(cmd *cobra.Command, flag, env, key string) string {
+	if cmd.Flags().Changed(flag) {
+		return cmd.Flags().Lookup(flag).Value.String()
+	}
+
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+
+	return viper.GetString(key)
+}
+
+// check fails if the local file or directory source, counting only the
+// files the filter keeps, is over the limits. Directories are walked until
+// a limit is exceeded, so huge ones fail fast.
+func (l pushLimits) check(source string, filter *files.Filter) error {
+	if l == (pushLimits{}) {
+		return nil
+	}
+
+	var total int64
+	count := 0
+	var exceeded error
+
+	err := files.Walk(source, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(source, filename)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && !filter.Keep(filepath.ToSlash(rel)) {
+			return nil
+		}
+
+		total += info.Size()
+		count++
+
+		switch {
+		case l.MaxFileSize > 0 && info.Size() > l.MaxFileSize:
+			exceeded = fmt.Errorf("'%s' is %s, more than --max-file-size %s", filename, formatBytes(info.Size()), formatBytes(l.MaxFileSize))
+		case l.MaxTotalSize > 0 && total > l.MaxTotalSize:
+			exceeded = fmt.Errorf("'%s' has more than --max-total-size %s of files", source, formatBytes(l.MaxTotalSize))
+		case l.MaxFiles > 0 && count > l.MaxFiles:
+			exceeded = fmt.Errorf("'%s' has more than --max-files %d files", source, l.MaxFiles)
+		default:
+			return nil
+		}
+
+		return errLimitExceeded
+	})
+
+	if err == errLimitExceeded {
+		return fmt.Errorf("nothing was pushed: %v", exceeded)
+	}
+
+	return err
+}
+
+// checkSize fails if a single file of size bytes is over --max-file-size
+// or --max-total-size. Negative sizes are unknown and pass.
+func (l pushLimits) checkSize(source string, size int64) error {
+	switch {
+	case l.MaxFileSize > 0 && size > l.MaxFileSize:
+		return fmt.Errorf("nothing was pushed: '%s' is more than --max-file-size %s", source, formatBytes(l.MaxFileSize))
+	case l.MaxTotalSize > 0 && size > l.MaxTotalSize:
+		return fmt.Errorf("nothing was pushed: '%s' is more than --max-total-size %s", source, formatBytes(l.MaxTotalSize))
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/spf13/cobra"
+)
+
+func addPushLockFlags(cmd *cobra.Command) {
+	cmd.Flags().String("lock-mode", "", "S3 Object Lock retention mode for the pushed files: governance or compliance")
+	cmd.Flags().String("lock-until", "", "retain the pushed files until this date, e.g. 2030-01-01 or an RFC 3339 time")
+	cmd.Flags().String("lock-for", "", "retain the pushed files for this long, e.g. 365d")
+	cmd.Flags().Bool("legal-hold", false, "put an S3 Object Lock legal hold on the pushed files")
+}
+
+// parseObjectLock builds the object lock requested with the lock flags,
+// or returns nil if none of them is set, leaving the backend default in place.
+func parseObjectLock(cmd *cobra.Command) (*backend.ObjectLock, error) {
+	mode, _ := cmd.Flags().GetString("lock-mode")
+	until, _ := cmd.Flags().GetString("lock-until")
+	retainFor, _ := cmd.Flags().GetString("lock-for")
+	legalHold, _ := cmd.Flags().GetBool("legal-hold")
+
+	if mode == "" && until == "" && retainFor == "" && !legalHold {
+		return nil, nil
+	}
+
+	if until != "" && retainFor != "" {
+		return nil, fmt.Errorf("use either --lock-until or --lock-for, not both")
+	}
+
+	lock := &backend.ObjectLock{Mode: strings.ToUpper(mode), LegalHold: legalHold}
+
+	if until != "" {
+		retainUntil, err := parseLockDate(until)
+		if err != nil {
+			return nil, err
+		}
+		lock.RetainUntil = retainUntil
+	}
+
+	if retainFor != "" {
+		age, err := common.ParseAge(retainFor)
+		if err != nil {
+			return nil, err
+		}
+		lock.RetainUntil = time.Now().Add(age)
+	}
+
+	if err := lock.Validate(); err != nil {
+		return nil, err
+	}
+
+	return lock, nil
+}
+
+func parseLockDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", value); err == nil {
+		return t, nil
+	}
+
+	return time.Time{}, fmt.Errorf("invalid --lock-until '%s': use a date like 2030-01-01 or an RFC 3339 time", value)
+}
@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushManifestFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("manifest", false, "store a manifest of the pushed directory, with the size and SHA256 of every file")
+}
+
+// pushManifest generates the manifest of the files of the local directory
+// paths.Source the filter keeps, and stores it with the pushed directory.
+func pushManifest(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, metadata map[string]string, filter *files.Filter) error {
+	m, err := manifest.Generate(paths.Source, metadata)
+	if err != nil {
+		return fmt.Errorf("failed to generate manifest: %v", err)
+	}
+
+	kept := []manifest.Entry{}
+	for _, entry := range m.Files {
+		if filter.Keep(entry.Path) {
+			kept = append(kept, entry)
+		}
+	}
+	m.Files = kept
+
+	data, err := m.Marshal()
+	if err != nil {
+		return err
+	}
+
+	err = pushStream(ctx, b, bytes.NewReader(data), int64(len(data)), manifest.Path(paths.Destination), backend.PushOptions{Force: true})
+	if err != nil {
+		return fmt.Errorf("failed to store manifest: %v", err)
+	}
+
+	log.Infof("Stored a manifest of %d %s.\n", len(m.Files), pluralize(len(m.Files), "file", "files"))
+	return nil
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/spf13/cobra"
+)
+
+var metadataKeyRegex = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+func addPushMetadataFlags(cmd *cobra.Command) {
+	cmd.Flags().StringToString("metadata", nil, "metadata stored with the pushed files, e.g. --metadata owner=platform (repeatable)")
+}
+
+// parsePushMetadata returns the --metadata flags with lowercase keys,
+// since storage providers do not preserve the case of metadata keys.
+func parsePushMetadata(cmd *cobra.Command) (map[string]string, error) {
+	values, err := cmd.Flags().GetStringToString("metadata")
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]string{}
+	for key, value := range values {
+		key = strings.ToLower(strings.TrimSpace(key))
+		if !metadataKeyRegex.MatchString(key) {
+			return nil, fmt.Errorf("invalid metadata key '%s': use letters, digits, '-' and '_'", key)
+		}
+
+		if key == backend.ChecksumMetadataKey {
+			return nil, fmt.Errorf("metadata key '%s' is reserved for checksums", key)
+		}
+
+		metadata[key] = value
+	}
+
+	return metadata, nil
+}
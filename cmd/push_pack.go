@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/semaphoreci/artifact/pkg/pack"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushPackFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("pack", false, "upload the small files of a directory packed into larger archives, which pulls unpack")
+	cmd.Flags().String("pack-threshold", "64KB", "with --pack, pack the files smaller than this")
+	cmd.Flags().String("pack-size", "16MB", "with --pack, the size of each pack")
+}
+
+// packOptions are the sizes of --pack-threshold and --pack-size.
+type packOptions struct {
+	Threshold int64
+	Size      int64
+}
+
+// parsePushPack returns the options of --pack, or nil without it.
+func parsePushPack(cmd *cobra.Command) (*packOptions, error) {
+	packed, err := cmd.Flags().GetBool("pack")
+	errutil.Check(err)
+
+	if !packed {
+		return nil, nil
+	}
+
+	threshold, err := common.ParseSize(cmd.Flags().Lookup("pack-threshold").Value.String())
+	if err != nil {
+		return nil, fmt.Errorf("--pack-threshold: %v", err)
+	}
+
+	size, err := common.ParseSize(cmd.Flags().Lookup("pack-size").Value.String())
+	if err != nil || size <= 0 {
+		return nil, fmt.Errorf("--pack-size: invalid size '%s'", cmd.Flags().Lookup("pack-size").Value.String())
+	}
+
+	return &packOptions{Threshold: threshold, Size: size}, nil
+}
+
+// pushPacked pushes the files of the local directory paths.Source the
+// filter keeps: the ones smaller than the threshold are packed, see
+// pkg/pack, and the others pushed one by one. The index of the packs is
+// stored last, so pulls only unpack complete packs; pushing the same files
+// again only uploads the packs an interrupted push did not. The packs of
+// the previous push the new index does not list are deleted afterwards.
+func pushPacked(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, filter *files.Filter, popts *packOptions, opts backend.PushOptions) (*storage.PushStats, error) {
+	pushed, err := walkPushed(paths, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := readPackIndex(ctx, b, paths.Destination)
+	if previous != nil && !opts.Force {
+		return nil, &backend.ErrAlreadyExists{Path: paths.Destination}
+	}
+
+	large := []pushedFile{}
+	small := []manifest.Entry{}
+	for _, f := range pushed {
+		if f.Info.Size() >= popts.Threshold {
+			large = append(large, f)
+			continue
+		}
+
+		rel := strings.TrimPrefix(f.RemotePath, strings.TrimSuffix(paths.Destination, "/")+"/")
+		small = append(small, manifest.Entry{Path: rel, Size: f.Info.Size()})
+	}
+
+	stats, _, err := pushEach(ctx, b, large, opts, false, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(small) == 0 && previous == nil {
+		return stats, nil
+	}
+
+	groups := pack.Plan(small, popts.Size)
+	index := &pack.Index{Version: pack.Version, Packs: make([]pack.Pack, len(groups))}
+	transfer := backend.TransferStatsFromContext(ctx)
+	var mu sync.Mutex
+	reused := 0
+
+	err = backend.Parallel(len(groups), opts.Concurrency, func(i int) error {
+		buf := &bytes.Buffer{}
+		packed, err := pack.Write(buf, paths.Source, groups[i])
+		if err != nil {
+			return err
+		}
+
+		sum := sha256.Sum256(buf.Bytes())
+		p := pack.Pack{Name: hex.EncodeToString(sum[:]), Size: int64(buf.Len()), Files: packed}
+		index.Packs[i] = p
+
+		stored := previous != nil && previous.Has(p.Name)
+		if !stored {
+			if stored, err = b.Exists(ctx, pack.Path(paths.Destination, p.Name)); err != nil {
+				return err
+			}
+		}
+
+		if !stored {
+			err = pushStream(ctx, b, bytes.NewReader(buf.Bytes()), p.Size, pack.Path(paths.Destination, p.Name), backend.PushOptions{Force: true})
+			if err != nil {
+				return fmt.Errorf("failed to upload a pack of '%s': %v", paths.Source, err)
+			}
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, entry := range packed {
+			if stored {
+				transfer.Skipped(entry.Size)
+			} else {
+				transfer.Transferred(entry.Size)
+				stats.FileCount++
+				stats.TotalSize += entry.Size
+			}
+		}
+		if stored {
+			reused++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := index.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	err = pushStream(ctx, b, bytes.NewReader(data), int64(len(data)), pack.IndexPath(paths.Destination), backend.PushOptions{Force: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to store the index of the packs of '%s': %v", paths.Source, err)
+	}
+
+	log.Infof("Packed %d small %s into %d %s, %d of them already stored.\n", len(small), pluralize(len(small), "file", "files"),
+		len(groups), pluralize(len(groups), "pack", "packs"), reused)
+
+	if previous != nil {
+		deleteUnusedPacks(ctx, b, paths.Destination, previous, index)
+	}
+
+	return stats, nil
+}
+
+// deleteUnusedPacks deletes the packs of the previous index the new one
+// does not list. Failing to delete them is only logged: they take space,
+// but break nothing, and are yanked with the directory.
+func deleteUnusedPacks(ctx context.Context, b backend.Backend, remoteDir string, previous, index *pack.Index) {
+	unused := []string{}
+	for _, p := range previous.Packs {
+		if !index.Has(p.Name) {
+			unused = append(unused, p.Name)
+		}
+	}
+
+	err := backend.Parallel(len(unused), backend.DefaultConcurrency, func(i int) error {
+		return b.Yank(ctx, pack.Path(remoteDir, unused[i]))
+	})
+	if err != nil {
+		log.Warnf("Failed to delete the unused packs of '%s': %v\n", remoteDir, err)
+	}
+}
+
+// readPackIndex returns the index of the packs of the remote directory
+// remoteDir, or nil if it was not pushed with --pack.
+func readPackIndex(ctx context.Context, b backend.Backend, remoteDir string) *pack.Index {
+	r, err := openRemote(ctx, b, pack.IndexPath(remoteDir))
+	if err != nil {
+		if !isNotFound(err) {
+			log.Debugf("Failed to read the pack index of '%s': %v\n", remoteDir, err)
+		}
+		return nil
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		log.Debugf("Failed to read the pack index of '%s': %v\n", remoteDir, err)
+		return nil
+	}
+
+	index, err := pack.Parse(data)
+	if err != nil {
+		log.Warnf("Ignoring the pack index of '%s': %v\n", remoteDir, err)
+		return nil
+	}
+
+	return index
+}
+
+// pullPacks unpacks the files of the packs of the index into the local
+// directory paths.Destination, opts.Concurrency packs at a time. Without
+// opts.Force, it fails before downloading anything if one of them exists
+// locally. With changedOnly, packs whose files all match their local copy
+// are not downloaded, and only the files that differ are written.
+func pullPacks(ctx context.Context, b backend.Backend, index *pack.Index, paths *files.ResolvedPath, opts backend.PullOptions, changedOnly bool) (*storage.PullStats, error) {
+	transfer := backend.TransferStatsFromContext(ctx)
+	pulled := map[string]bool{}
+	pending := []pack.Pack{}
+
+	for _, p := range index.Packs {
+		stale := false
+		for _, entry := range p.Files {
+			localPath := filepath.Join(paths.Destination, filepath.FromSlash(entry.Path))
+			if !opts.Force {
+				if _, err := os.Stat(localPath); err == nil {
+					return nil, fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+				}
+			}
+
+			if changedOnly {
+				if checksum, err := files.SHA256File(localPath); err == nil && strings.EqualFold(checksum, entry.SHA256) {
+					transfer.Skipped(entry.Size)
+					continue
+				}
+			}
+
+			pulled[entry.Path] = true
+			stale = true
+		}
+
+		if stale {
+			pending = append(pending, p)
+		}
+	}
+
+	keep := func(entry manifest.Entry) bool { return pulled[entry.Path] }
+
+	var mu sync.Mutex
+	stats := &storage.PullStats{}
+	err := backend.Parallel(len(pending), opts.Concurrency, func(i int) error {
+		p := pending[i]
+		r, err := openRemote(ctx, b, pack.Path(paths.Source, p.Name))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+
+		count, size, err := pack.Extract(r, paths.Destination, p, keep)
+		if err != nil {
+			return err
+		}
+
+		for _, entry := range p.Files {
+			if keep(entry) {
+				transfer.Transferred(entry.Size)
+			}
+		}
+
+		mu.Lock()
+		stats.FileCount += count
+		stats.TotalSize += size
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/pack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__PushPacked(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+	remoteDir := "artifacts/jobs/1/coverage"
+
+	source := t.TempDir()
+	write := func(name, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(source, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(source, name), []byte(contents), 0644))
+	}
+	for i := 0; i < 100; i++ {
+		write(fmt.Sprintf("fragments/%03d.cov", i), strings.Repeat("x", 10))
+	}
+	write("report.html", strings.Repeat("r", 200))
+
+	popts := &packOptions{Threshold: 100, Size: 400}
+	push := func(force bool) error {
+		paths := &files.ResolvedPath{Source: source, Destination: remoteDir}
+		_, err := pushPacked(ctx, b, paths, nil, popts, backend.PushOptions{Force: force, Concurrency: 4})
+		return err
+	}
+
+	packs := func() []string {
+		stored := []string{}
+		for _, p := range b.Paths() {
+			if strings.HasSuffix(p, ".tar") {
+				stored = append(stored, p)
+			}
+		}
+		return stored
+	}
+
+	require.NoError(t, push(false))
+
+	// 100 small files take 3 packs, and the large file is pushed as is
+	assert.Len(t, packs(), 3)
+	assert.Len(t, b.Paths(), 5)
+	_, ok := b.Get(remoteDir + "/report.html")
+	assert.True(t, ok)
+
+	dest := filepath.Join(t.TempDir(), "coverage")
+	paths := &files.ResolvedPath{Source: remoteDir, Destination: dest}
+	stats, err := pullResolved(ctx, b, paths, backend.PullOptions{Concurrency: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 101, stats.FileCount)
+
+	data, err := os.ReadFile(filepath.Join(dest, "fragments", "042.cov"))
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("x", 10), string(data))
+
+	// Local files are not overwritten without --force
+	_, err = pullResolved(ctx, b, paths, backend.PullOptions{Concurrency: 2})
+	assert.ErrorContains(t, err, "already exists locally")
+
+	// Pushing again needs --force, and only replaces the packs that changed
+	assert.ErrorContains(t, push(false), "already exists")
+
+	before := packs()
+	write("fragments/099.cov", "changed")
+	require.NoError(t, push(true))
+	after := packs()
+	assert.Len(t, after, 3)
+	changed := 0
+	for _, p := range after {
+		if !slices.Contains(before, p) {
+			changed++
+		}
+	}
+	assert.Equal(t, 1, changed)
+
+	// Pulls with --if-changed only unpack the files that changed
+	pulled, err := pullChanged(ctx, b, paths, backend.PullOptions{Concurrency: 2})
+	require.NoError(t, err)
+	assert.Equal(t, 1, pulled.FileCount)
+
+	data, err = os.ReadFile(filepath.Join(dest, "fragments", "099.cov"))
+	require.NoError(t, err)
+	assert.Equal(t, "changed", string(data))
+
+	index := readPackIndex(ctx, b, remoteDir)
+	require.NotNil(t, index)
+	assert.Len(t, index.Packs, 3)
+	_, ok = b.Get(pack.IndexPath(remoteDir))
+	assert.True(t, ok)
+}
+
+func Test__PushPacked_Readers(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+	remoteDir := "artifacts/jobs/1/coverage"
+
+	source := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(source, "fragments"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "fragments", "a.cov"), []byte("aa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "fragments", "b.cov"), []byte("bb"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "report.html"), []byte(strings.Repeat("r", 200)), 0644))
+
+	paths := &files.ResolvedPath{Source: source, Destination: remoteDir}
+	_, err := pushPacked(ctx, b, paths, nil, &packOptions{Threshold: 100, Size: 400}, backend.PushOptions{})
+	require.NoError(t, err)
+
+	t.Run("cat reads packed files", func(t *testing.T) {
+		out := &strings.Builder{}
+		require.NoError(t, catFile(ctx, b, remoteDir+"/fragments/b.cov", out))
+		assert.Equal(t, "bb", out.String())
+
+		err := catFile(ctx, b, remoteDir+"/fragments/missing.cov", out)
+		assert.True(t, isNotFound(err))
+	})
+
+	t.Run("keep-going pulls packed files", func(t *testing.T) {
+		dest := filepath.Join(t.TempDir(), "coverage")
+		stats, err := pullEach(ctx, b, &files.ResolvedPath{Source: remoteDir, Destination: dest}, backend.PullOptions{})
+		require.NoError(t, err)
+		assert.Equal(t, 3, stats.FileCount)
+
+		data, err := os.ReadFile(filepath.Join(dest, "fragments", "a.cov"))
+		require.NoError(t, err)
+		assert.Equal(t, "aa", string(data))
+	})
+
+	t.Run("tar archives packed files", func(t *testing.T) {
+		buf := &bytes.Buffer{}
+		tw := tar.NewWriter(buf)
+		stats, err := streamRemoteTar(tw, b, b, remoteDir, "coverage")
+		require.NoError(t, err)
+		require.NoError(t, writePackedTar(ctx, tw, b, readPackIndex(ctx, b, remoteDir), remoteDir, "coverage", stats))
+		require.NoError(t, tw.Close())
+
+		assert.Equal(t, 3, stats.FileCount)
+		assert.Equal(t, map[string]string{
+			"coverage/report.html":     strings.Repeat("r", 200),
+			"coverage/fragments/a.cov": "aa",
+			"coverage/fragments/b.cov": "bb",
+		}, readTar(t, buf))
+	})
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/resume"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushResumeFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("no-resume", false, "push every file again, discarding the progress of an interrupted push")
+}
+
+// pushStatePath returns the file the progress of pushing paths is recorded
+// in. Pushes of the same source to the same destination share it, and
+// pushes to different backends are kept apart.
+func pushStatePath(paths *files.ResolvedPath) string {
+	source, err := filepath.Abs(paths.Source)
+	if err != nil {
+		source = paths.Source
+	}
+
+	sum := sha256.Sum256([]byte(backend.GetBackendSetting() + "\n" + source + "\n" + paths.Destination))
+	return filepath.Join(userCacheDir(), "uploads", hex.EncodeToString(sum[:])+".jsonl")
+}
+
+// openPushState opens the progress of an interrupted run of the push, or
+// starts recording a new one. Without a state, the push still works, but
+// cannot be resumed, so errors are only logged.
+func openPushState(paths *files.ResolvedPath, noResume bool) *resume.State {
+	path := pushStatePath(paths)
+	if noResume {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Warnf("Failed to discard the progress of an interrupted push: %v\n", err)
+		}
+	}
+
+	state, err := resume.Open(path)
+	if err != nil {
+		log.Warnf("Failed to load the progress of an interrupted push, pushing every file: %v\n", err)
+		_ = os.Remove(path)
+		return nil
+	}
+
+	return state
+}
+
+// finishPushState discards the recorded progress once the push succeeded,
+// and keeps it for the next run otherwise.
+func finishPushState(state *resume.State, pushErr error) {
+	if state == nil {
+		return
+	}
+
+	if pushErr == nil {
+		if err := state.Remove(); err != nil {
+			log.Warnf("Failed to remove the progress of the push: %v\n", err)
+		}
+		return
+	}
+
+	if !state.Empty() {
+		log.Info("The progress of the push was saved; run it again to resume it, or with --no-resume to start over.\n")
+	}
+
+	_ = state.Close()
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushStdinFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("stdin", false, "upload the data piped into stdin to --destination; same as passing - as the source path")
+}
+
+// shouldUseStdin reports whether the source path names stdin.
+func shouldUseStdin(input string) bool {
+	if input == "-" || input == "/dev/stdin" {
+		return true
+	}
+
+	return false
+}
+
+// runPushFromStdin streams r into the backend at destination. Backends that
+// cannot upload a stream stage it in a temporary file.
+func runPushFromStdin(resolver *files.PathResolver, r io.Reader, destination string, opts backend.PushOptions) (*files.ResolvedPath, *storage.PushStats, error) {
+	if destination == "" {
+		return nil, nil, fmt.Errorf("pushing from stdin requires --destination")
+	}
+
+	paths := resolver.Push("stdin", destination)
+
+	p, err := getPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	request := policyRequest(policy.OperationPush, resolver, paths.Destination)
+	request.Metadata = opts.Metadata
+	if err := p.Evaluate(request); err != nil {
+		return nil, nil, err
+	}
+
+	counter := &countingWriter{}
+	body := io.TeeReader(r, counter)
+
+	// The size of stdin is not known upfront, so limits are enforced while streaming
+	if limit := p.MaxSize(request); limit > 0 {
+		body = &policySizeLimiter{r: body, req: request, limit: limit}
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	log.Debug("Detected stdin, streaming it to the storage...\n")
+	err = pushStream(getContext(), b, body, -1, paths.Destination, opts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return paths, &storage.PushStats{FileCount: 1, TotalSize: counter.n}, nil
+}
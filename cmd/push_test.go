@@ -1,12 +1,22 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/encrypt"
+	"github.com/semaphoreci/artifact/pkg/files"
 	testsupport "github.com/semaphoreci/artifact/test/support"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -17,6 +27,20 @@ import (
 	_ "github.com/semaphoreci/artifact/pkg/backend/s3backend"
 )
 
+// TestMain keeps the progress of pushes made by the tests, which are
+// recorded in the user cache directory, out of the real one.
+func TestMain(m *testing.M) {
+	cacheDir, err := os.MkdirTemp("", "artifact-cache-*")
+	if err != nil {
+		panic(err)
+	}
+
+	os.Setenv("XDG_CACHE_HOME", cacheDir)
+	code := m.Run()
+	os.RemoveAll(cacheDir)
+	os.Exit(code)
+}
+
 type pushTestCase struct {
 	EnvVar               string
 	Prefix               string
@@ -268,4 +292,520 @@ func runPushTestCase(t *testing.T, testCase pushTestCase, hub *testsupport.HubMo
 		assert.True(t, storage.IsFile(fmt.Sprintf("artifacts/%s/2/%s", testCase.Prefix, filepath.Base(tempFile.Name()))))
 		os.Remove(tempFile.Name())
 	})
+
+	t.Run(testCase.Prefix+" stores checksum", func(t *testing.T) {
+		tempFile, _ := ioutil.TempFile("", "*")
+		tempFile.Write([]byte("something"))
+
+		cmd := testCase.Command()
+		cmd.SetArgs([]string{tempFile.Name()})
+		cmd.Flags().Set("destination", "checksummed.txt")
+		cmd.Execute()
+
+		assert.True(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/.checksums/checksummed.txt.sha256", testCase.Prefix)))
+		os.Remove(tempFile.Name())
+	})
+
+	t.Run(testCase.Prefix+" from url", func(t *testing.T) {
+		source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("toolchain"))
+		}))
+		defer source.Close()
+
+		cmd := testCase.Command()
+		cmd.SetArgs([]string{})
+		cmd.Flags().Set("from-url", source.URL+"/downloads/toolchain.tar.gz")
+		cmd.Flags().Set("sha256", "c09ffc9ba56fbcdc6b3ce9e2d9aba1ea4b0e5e4f71c4ad41b9b3be8a1a8b3a3b")
+		cmd.Execute()
+
+		assert.False(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/toolchain.tar.gz", testCase.Prefix)))
+
+		cmd = testCase.Command()
+		cmd.SetArgs([]string{})
+		cmd.Flags().Set("from-url", source.URL+"/downloads/toolchain.tar.gz")
+		cmd.Flags().Set("destination", "deps/toolchain.tar.gz")
+		cmd.Flags().Set("sha256", "0db3de82a739e43a2b560d166d037c3c0061601bb194866eb79b2c87045d00f2")
+		cmd.Execute()
+
+		assert.True(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/deps/toolchain.tar.gz", testCase.Prefix)))
+
+		// A mismatching download is never left behind, even if the upload went
+		// through before the mismatch was noticed
+		cmd = testCase.Command()
+		cmd.SetArgs([]string{})
+		cmd.Flags().Set("from-url", source.URL+"/downloads/toolchain.tar.gz")
+		cmd.Flags().Set("destination", "deps/toolchain.tar.gz")
+		cmd.Flags().Set("sha256", "c09ffc9ba56fbcdc6b3ce9e2d9aba1ea4b0e5e4f71c4ad41b9b3be8a1a8b3a3b")
+		cmd.Flags().Set("force", "true")
+		cmd.Execute()
+
+		assert.True(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/deps/toolchain.tar.gz", testCase.Prefix)))
+
+		// Size limits apply to the downloaded bytes
+		cmd = testCase.Command()
+		cmd.SetArgs([]string{})
+		cmd.Flags().Set("from-url", source.URL+"/downloads/toolchain.tar.gz")
+		cmd.Flags().Set("destination", "deps/limited.tar.gz")
+		cmd.Flags().Set("max-file-size", "4B")
+		cmd.Execute()
+
+		assert.False(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/deps/limited.tar.gz", testCase.Prefix)))
+	})
+
+	t.Run(testCase.Prefix+" from stdin", func(t *testing.T) {
+		cmd := testCase.Command()
+		cmd.SetIn(strings.NewReader("build output"))
+		cmd.SetArgs([]string{"--stdin"})
+		cmd.Execute()
+
+		assert.False(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/stdin", testCase.Prefix)))
+
+		cmd = testCase.Command()
+		cmd.SetIn(strings.NewReader("build output"))
+		cmd.SetArgs([]string{"--stdin", "--destination", "logs/build.log"})
+		cmd.Execute()
+
+		assert.True(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/logs/build.log", testCase.Prefix)))
+
+		cmd = testCase.Command()
+		cmd.SetIn(strings.NewReader("test output"))
+		cmd.SetArgs([]string{"-", "--destination", "logs/test.log"})
+		cmd.Execute()
+
+		assert.True(t, storage.IsFile(fmt.Sprintf("artifacts/%s/1/logs/test.log", testCase.Prefix)))
+	})
+}
+
+func Test__PushChanged(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("b"), 0644)
+
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+	paths := resolver.Push(tempDir, "results")
+
+	b := getBackend()
+	defer b.Close()
+
+	stats, skipped, err := pushChanged(getContext(), b, paths, nil, backend.PushOptions{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 2, stats.FileCount)
+	assert.Equal(t, 0, skipped)
+
+	// Unchanged files are skipped, changed ones need force
+	ioutil.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("changed"), 0644)
+
+	_, _, err = pushChanged(getContext(), b, paths, nil, backend.PushOptions{}, false)
+	assert.Error(t, err)
+
+	stats, skipped, err = pushChanged(getContext(), b, paths, nil, backend.PushOptions{Force: true}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.FileCount)
+	assert.Equal(t, int64(7), stats.TotalSize)
+	assert.Equal(t, 1, skipped)
+
+	// New files need no force
+	ioutil.WriteFile(filepath.Join(tempDir, "c.txt"), []byte("c"), 0644)
+
+	stats, skipped, err = pushChanged(getContext(), b, paths, nil, backend.PushOptions{}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, stats.FileCount)
+	assert.Equal(t, 2, skipped)
+
+	// Compressed files are compared by their stored checksum
+	compressed := resolver.Push(tempDir, "compressed")
+	stats, _, err = pushChanged(getContext(), b, compressed, nil, backend.PushOptions{Compress: "gzip"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, stats.FileCount)
+
+	stats, skipped, err = pushChanged(getContext(), b, compressed, nil, backend.PushOptions{Compress: "gzip"}, false)
+	assert.Nil(t, err)
+	assert.Equal(t, 0, stats.FileCount)
+	assert.Equal(t, 3, skipped)
+}
+
+func Test__PushArchive(t *testing.T) {
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644)
+	os.MkdirAll(filepath.Join(tempDir, "nested"), 0755)
+	ioutil.WriteFile(filepath.Join(tempDir, "nested", "b.txt"), []byte("bb"), 0644)
+
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+	memory := memorybackend.New()
+
+	for _, format := range []string{files.ArchiveTarGz, files.ArchiveZip} {
+		paths := resolver.Push(tempDir, "build."+format)
+		size, err := pushArchive(getContext(), memory, paths, format, backend.PushOptions{})
+		assert.Nil(t, err)
+
+		data, ok := memory.Get("artifacts/jobs/1/build." + format)
+		assert.True(t, ok)
+		assert.Equal(t, int64(len(data)), size)
+
+		// The archive is unpacked into a directory on pull
+		destination := filepath.Join(t.TempDir(), "build")
+		stats, err := pullExtracted(getContext(), memory, &files.ResolvedPath{Source: paths.Destination, Destination: destination}, format, false, nil)
+		assert.Nil(t, err)
+		assert.Equal(t, 2, stats.FileCount)
+		assert.Equal(t, int64(3), stats.TotalSize)
+
+		pulled, _ := ioutil.ReadFile(filepath.Join(destination, "nested", "b.txt"))
+		assert.Equal(t, "bb", string(pulled))
+	}
+}
+
+func Test__ParseObjectLock(t *testing.T) {
+	parse := func(flags map[string]string) (*backend.ObjectLock, error) {
+		cmd := NewPushJobCmd()
+		for name, value := range flags {
+			cmd.Flags().Set(name, value)
+		}
+		return parseObjectLock(cmd)
+	}
+
+	lock, err := parse(map[string]string{})
+	assert.Nil(t, err)
+	assert.Nil(t, lock)
+
+	lock, err = parse(map[string]string{"lock-mode": "compliance", "lock-until": "2040-01-01"})
+	if assert.Nil(t, err) {
+		assert.Equal(t, backend.ObjectLockCompliance, lock.Mode)
+		assert.Equal(t, 2040, lock.RetainUntil.Year())
+	}
+
+	lock, err = parse(map[string]string{"lock-mode": "governance", "lock-for": "30d", "legal-hold": "true"})
+	if assert.Nil(t, err) {
+		assert.True(t, lock.LegalHold)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), lock.RetainUntil, time.Minute)
+	}
+
+	_, err = parse(map[string]string{"lock-mode": "governance"})
+	assert.Error(t, err)
+
+	_, err = parse(map[string]string{"lock-mode": "governance", "lock-until": "2040-01-01", "lock-for": "30d"})
+	assert.Error(t, err)
+
+	_, err = parse(map[string]string{"lock-mode": "governance", "lock-until": "someday"})
+	assert.Error(t, err)
+}
+
+func Test__ParseExpireIn(t *testing.T) {
+	parse := func(value string) (time.Duration, error) {
+		cmd := NewPushJobCmd()
+		cmd.Flags().Set("expire-in", value)
+		return parseExpireIn(cmd)
+	}
+
+	expireIn, err := parse("")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), expireIn)
+
+	expireIn, err = parse("Never")
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), expireIn)
+
+	expireIn, err = parse("2w")
+	assert.Nil(t, err)
+	assert.Equal(t, 14*24*time.Hour, expireIn)
+
+	_, err = parse("soon")
+	assert.Error(t, err)
+
+	now := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	metadata := map[string]string{"owner": "platform"}
+	assert.Equal(t, map[string]string{"owner": "platform", "expire-at": "2030-01-15T00:00:00Z"}, withExpiry(metadata, expireIn, now))
+	assert.Equal(t, map[string]string{"owner": "platform"}, metadata)
+	assert.Nil(t, withExpiry(nil, 0, now))
+}
+
+func Test__PushResume(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(tempDir, "a.txt"), []byte("a"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "b.txt"), []byte("b"), 0644)
+
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+	paths := resolver.Push(tempDir, "results")
+
+	// An interrupted run pushed a.txt
+	assert.Nil(t, s3Server.PutFiles([]testsupport.FileMock{{Name: "artifacts/jobs/1/results/a.txt", Contents: "a"}}))
+	state := openPushState(paths, false)
+	info, _ := os.Stat(filepath.Join(tempDir, "a.txt"))
+	assert.Nil(t, state.MarkPushed(filepath.Join(tempDir, "a.txt"), info))
+	finishPushState(state, fmt.Errorf("interrupted"))
+	assert.FileExists(t, pushStatePath(paths))
+
+	push := NewPushJobCmd()
+	push.ParseFlags([]string{"--destination", "results"})
+	_, _, err = runPushForCategory(push, []string{tempDir}, resolver)
+	assert.Nil(t, err)
+	assert.NoFileExists(t, pushStatePath(paths))
+
+	// Without the recorded progress, a.txt fails the push
+	s3Server.PutFiles([]testsupport.FileMock{{Name: "artifacts/jobs/1/other/a.txt", Contents: "a"}})
+	state = openPushState(resolver.Push(tempDir, "other"), false)
+	assert.Nil(t, state.MarkPushed(filepath.Join(tempDir, "a.txt"), info))
+	finishPushState(state, fmt.Errorf("interrupted"))
+
+	push = NewPushJobCmd()
+	push.ParseFlags([]string{"--destination", "other", "--no-resume"})
+	_, _, err = runPushForCategory(push, []string{tempDir}, resolver)
+	assert.Error(t, err)
+}
+
+func Test__PushCompressed(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	contents := strings.Repeat("PASS: Test__Something\n", 100)
+	ioutil.WriteFile(filepath.Join(tempDir, "test.log"), []byte(contents), 0644)
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+
+	push := NewPushJobCmd()
+	push.ParseFlags([]string{"--compress", "brotli"})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "test.log")}, resolver)
+	assert.ErrorContains(t, err, "unsupported compression 'brotli'")
+
+	push = NewPushJobCmd()
+	push.ParseFlags([]string{"--compress", "zstd"})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "test.log")}, resolver)
+	assert.Nil(t, err)
+
+	b := getBackend()
+	defer b.Close()
+
+	r, err := b.(backend.Opener).Open(context.Background(), "artifacts/jobs/1/test.log")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer r.Close()
+
+	pulled, _ := io.ReadAll(r)
+	assert.Equal(t, contents, string(pulled))
+}
+
+func Test__PushEncrypted(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv(encrypt.RecipientsEnv, "")
+	t.Setenv(encrypt.PassphraseEnv, "")
+
+	tempDir := t.TempDir()
+	ioutil.WriteFile(filepath.Join(tempDir, "export.csv"), []byte("42,someone@example.com\n"), 0644)
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+
+	push := NewPushJobCmd()
+	push.ParseFlags([]string{"--recipient", "age1notakey"})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "export.csv")}, resolver)
+	assert.ErrorContains(t, err, "--recipient needs --encrypt")
+
+	push = NewPushJobCmd()
+	push.ParseFlags([]string{"--encrypt"})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "export.csv")}, resolver)
+	assert.ErrorContains(t, err, "--encrypt needs a key")
+
+	t.Setenv(encrypt.PassphraseEnv, "correct horse battery staple")
+	push = NewPushJobCmd()
+	push.ParseFlags([]string{"--encrypt"})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "export.csv")}, resolver)
+	assert.Nil(t, err)
+
+	b := getBackend()
+	defer b.Close()
+
+	_, err = b.(backend.Opener).Open(context.Background(), "artifacts/jobs/1/export.csv")
+	assert.ErrorIs(t, err, encrypt.ErrNoKey)
+
+	// The context of commands carries the keys of the environment
+	resetContext(0)
+	r, err := b.(backend.Opener).Open(getContext(), "artifacts/jobs/1/export.csv")
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer r.Close()
+
+	pulled, _ := io.ReadAll(r)
+	assert.Equal(t, "42,someone@example.com\n", string(pulled))
+}
+
+func Test__PushFiltered(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "reports", "cache"), 0755)
+	ioutil.WriteFile(filepath.Join(tempDir, "reports", "junit.xml"), []byte("<testsuites/>"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "reports", "coverage.txt"), []byte("87%"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "reports", "debug.log"), []byte("debug"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "reports", "keep.log"), []byte("keep"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "reports", "cache", "index.xml"), []byte("<cache/>"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "reports", files.IgnoreFile), []byte("# logs\n*.log\n!keep.log\n"), 0644)
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+
+	push := NewPushJobCmd()
+	push.ParseFlags([]string{"--archive", "tar.gz", "--exclude", "cache"})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "reports")}, resolver)
+	assert.ErrorContains(t, err, "--archive cannot be used with --include or --exclude")
+
+	push = NewPushJobCmd()
+	push.ParseFlags([]string{"--exclude", "["})
+	_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "reports")}, resolver)
+	assert.ErrorContains(t, err, "invalid glob '['")
+
+	push = NewPushJobCmd()
+	push.ParseFlags([]string{"--include", "**/*.xml", "--include", "*.log", "--exclude", "cache"})
+	_, stats, err := runPushForCategory(push, []string{filepath.Join(tempDir, "reports")}, resolver)
+	if !assert.Nil(t, err) {
+		return
+	}
+	assert.Equal(t, 2, stats.FileCount)
+
+	b := getBackend()
+	defer b.Close()
+
+	for remotePath, pushed := range map[string]bool{
+		"artifacts/jobs/1/reports/junit.xml":       true,
+		"artifacts/jobs/1/reports/keep.log":        true,
+		"artifacts/jobs/1/reports/debug.log":       false,
+		"artifacts/jobs/1/reports/coverage.txt":    false,
+		"artifacts/jobs/1/reports/cache/index.xml": false,
+		"artifacts/jobs/1/reports/.artifactignore": false,
+	} {
+		exists, err := b.Exists(context.Background(), remotePath)
+		assert.Nil(t, err)
+		assert.Equal(t, pushed, exists, remotePath)
+	}
+}
+
+func Test__PushLimits(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	t.Setenv("ARTIFACT_PUSH_MAX_FILES", "")
+
+	tempDir := t.TempDir()
+	os.MkdirAll(filepath.Join(tempDir, "workspace", "node_modules"), 0755)
+	ioutil.WriteFile(filepath.Join(tempDir, "workspace", "app.js"), []byte("console.log(1)"), 0644)
+	ioutil.WriteFile(filepath.Join(tempDir, "workspace", "node_modules", "big.js"), make([]byte, 2048), 0644)
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+
+	for _, testCase := range []struct {
+		flags []string
+		err   string
+	}{
+		{[]string{"--max-file-size", "1KB"}, "big.js' is 2.0 KB, more than --max-file-size 1.0 KB"},
+		{[]string{"--max-total-size", "2KB"}, "has more than --max-total-size 2.0 KB of files"},
+		{[]string{"--max-files", "1"}, "has more than --max-files 1 files"},
+		{[]string{"--max-files", "1", "--archive", "tar.gz"}, "has more than --max-files 1 files"},
+		{[]string{"--max-file-size", "lots"}, "--max-file-size: invalid size 'lots'"},
+	} {
+		push := NewPushJobCmd()
+		push.ParseFlags(testCase.flags)
+		_, _, err = runPushForCategory(push, []string{filepath.Join(tempDir, "workspace")}, resolver)
+		assert.ErrorContains(t, err, testCase.err, testCase.flags)
+	}
+
+	b := getBackend()
+	defer b.Close()
+
+	exists, err := b.Exists(context.Background(), "artifacts/jobs/1/workspace/app.js")
+	assert.Nil(t, err)
+	assert.False(t, exists, "nothing is pushed over the limits")
+
+	// Filtered out files do not count, and the env var sets a default
+	t.Setenv("ARTIFACT_PUSH_MAX_FILES", "1")
+	push := NewPushJobCmd()
+	push.ParseFlags([]string{"--max-file-size", "1KB", "--exclude", "node_modules"})
+	_, stats, err := runPushForCategory(push, []string{filepath.Join(tempDir, "workspace")}, resolver)
+	if assert.Nil(t, err) {
+		assert.Equal(t, 1, stats.FileCount)
+	}
+}
+
+func Test__PushFromURL_Streamed(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	if !assert.Nil(t, err) {
+		return
+	}
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	// Flushing drops the Content-Length, so checks can only happen while streaming
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tool"))
+		w.(http.Flusher).Flush()
+		w.Write([]byte("chain"))
+	}))
+	defer source.Close()
+
+	resolver, _ := files.NewPathResolver(files.ResourceTypeJob, "")
+	push := func(flags ...string) error {
+		cmd := NewPushJobCmd()
+		cmd.ParseFlags(append([]string{"--from-url", source.URL + "/toolchain.tar.gz"}, flags...))
+		_, _, err := runPushForCategory(cmd, []string{}, resolver)
+		return err
+	}
+
+	b := getBackend()
+	defer b.Close()
+
+	exists := func() bool {
+		exists, err := b.Exists(context.Background(), "artifacts/jobs/1/toolchain.tar.gz")
+		assert.Nil(t, err)
+		return exists
+	}
+
+	assert.ErrorContains(t, push("--sha256", "c09ffc9ba56fbcdc6b3ce9e2d9aba1ea4b0e5e4f71c4ad41b9b3be8a1a8b3a3b"), "checksum mismatch")
+	assert.False(t, exists(), "nothing is pushed on mismatch")
+
+	assert.ErrorContains(t, push("--max-file-size", "4B"), "more than --max-file-size 4 B")
+	assert.False(t, exists(), "nothing is pushed over the limits")
+
+	assert.Nil(t, push("--sha256", "0db3de82a739e43a2b560d166d037c3c0061601bb194866eb79b2c87045d00f2", "--max-total-size", "1KB"))
+	assert.True(t, exists())
 }
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushTransactionalFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("transactional", false, "push a directory so it appears all at once: its files are uploaded aside, then committed by its manifest")
+	cmd.Flags().Bool("cas", false, "like --transactional, but store the files by their checksum, once for every job, workflow and project")
+}
+
+// pushTransactional pushes the files of the local directory paths.Source
+// the filter keeps as a transaction: they are uploaded under the
+// TransactionDir of paths.Destination, see pkg/manifest, then its manifest
+// is stored, which commits them. Pulls read the files through the
+// manifest, so they see either the previous push or this one, never a
+// part of it. Pushing the same files again uploads only the ones an
+// interrupted push did not, and is a no-op once they are committed.
+//
+// With blobs, the files are stored as blobs instead, see BlobPath, and
+// only the ones no earlier push stored are uploaded. Blobs may be shared
+// with other directories, so they are never deleted.
+func pushTransactional(ctx context.Context, b backend.Backend, paths *files.ResolvedPath, filter *files.Filter, metadata map[string]string, opts backend.PushOptions, blobs bool) (*storage.PushStats, error) {
+	m, err := manifest.Generate(paths.Source, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate manifest: %v", err)
+	}
+
+	kept := []manifest.Entry{}
+	for _, entry := range m.Files {
+		if filter.Keep(entry.Path) {
+			kept = append(kept, entry)
+		}
+	}
+	m.Files = kept
+	m.Transaction = m.TransactionID()
+	m.Blobs = blobs
+
+	previous := readTransaction(ctx, b, paths.Destination)
+	if previous != nil && previous.Transaction == m.Transaction && previous.Blobs == m.Blobs {
+		log.Infof("The same files were already committed to '%s'.\n", paths.Destination)
+		for _, entry := range m.Files {
+			backend.TransferStatsFromContext(ctx).Skipped(entry.Size)
+		}
+		return &storage.PushStats{}, nil
+	}
+
+	if previous != nil && !opts.Force {
+		return nil, &backend.ErrAlreadyExists{Path: paths.Destination}
+	}
+
+	// Files an interrupted push of the same transaction uploaded, and
+	// blobs, are complete, as pushes only store whole files. Identical
+	// files are one blob, uploaded once.
+	pushed := []pushedFile{}
+	queued := map[string]bool{}
+	for _, entry := range m.Files {
+		remotePath := m.FilePath(paths.Destination, entry)
+		if queued[remotePath] {
+			backend.TransferStatsFromContext(ctx).Skipped(entry.Size)
+			continue
+		}
+
+		localPath := filepath.Join(paths.Source, filepath.FromSlash(entry.Path))
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return nil, err
+		}
+
+		queued[remotePath] = true
+		pushed = append(pushed, pushedFile{LocalPath: localPath, RemotePath: remotePath, Info: info})
+	}
+
+	fileOpts := opts
+	fileOpts.Force, fileOpts.Resume = true, nil
+	stats, skipped, err := pushEach(ctx, b, pushed, fileOpts, false, func(f pushedFile) (bool, error) {
+		return b.Exists(ctx, f.RemotePath)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if skipped > 0 && blobs {
+		log.Infof("Skipped %d %s already stored.\n", skipped, pluralize(skipped, "file", "files"))
+	} else if skipped > 0 {
+		log.Infof("Skipped %d %s an interrupted push already uploaded.\n", skipped, pluralize(skipped, "file", "files"))
+	}
+
+	// Only transactions have files to keep for the pulls still reading them
+	if previous != nil && !previous.Blobs && !blobs {
+		m.Previous = previous.Transaction
+	}
+
+	data, err := m.Marshal()
+	if err != nil {
+		return nil, err
+	}
+
+	err = pushStream(ctx, b, bytes.NewReader(data), int64(len(data)), manifest.Path(paths.Destination), backend.PushOptions{Force: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to commit the push: %v", err)
+	}
+
+	log.Infof("Committed %d %s to '%s'.\n", len(m.Files), pluralize(len(m.Files), "file", "files"), paths.Destination)
+
+	// The files of the transaction before the previous one are not read
+	// by any pull anymore
+	if previous != nil && previous.Previous != "" && previous.Previous != m.Transaction {
+		if err := b.Yank(ctx, manifest.TransactionDir(paths.Destination, previous.Previous)); err != nil {
+			log.Warnf("Failed to delete the files of an earlier push of '%s': %v\n", paths.Destination, err)
+		}
+	}
+
+	return stats, nil
+}
+
+// readTransaction returns the manifest committing the transactional push
+// of the remote directory remoteDir, or nil if it was not pushed with
+// --transactional.
+func readTransaction(ctx context.Context, b backend.Backend, remoteDir string) *manifest.Manifest {
+	m, err := loadManifest(ctx, b, remoteDir)
+	if err != nil {
+		if !isNotFound(err) {
+			log.Debugf("Failed to read the manifest of '%s': %v\n", remoteDir, err)
+		}
+		return nil
+	}
+
+	if m.Transaction == "" {
+		return nil
+	}
+
+	return m
+}
+
+// pullTransaction pulls the files the manifest m commits to the local
+// directory paths.Destination, checking them against their checksums.
+func pullTransaction(ctx context.Context, b backend.Backend, m *manifest.Manifest, paths *files.ResolvedPath, opts backend.PullOptions) (*storage.PullStats, error) {
+	if !opts.Force {
+		for _, entry := range m.Files {
+			localPath := filepath.Join(paths.Destination, filepath.FromSlash(entry.Path))
+			if _, err := os.Stat(localPath); err == nil {
+				return nil, fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+			}
+		}
+	}
+
+	log.Debugf("Pulling %d files committed by transaction %s...\n", len(m.Files), m.Transaction)
+
+	var mu sync.Mutex
+	stats := &storage.PullStats{}
+	transfer := backend.TransferStatsFromContext(ctx)
+	fileOpts := backend.PullOptions{Force: true, Concurrency: 1}
+
+	err := backend.Parallel(len(m.Files), opts.Concurrency, func(i int) error {
+		entry := m.Files[i]
+		remotePath := m.FilePath(paths.Source, entry)
+		localPath := filepath.Join(paths.Destination, filepath.FromSlash(entry.Path))
+		if err := b.Pull(ctx, remotePath, localPath, fileOpts); err != nil {
+			return err
+		}
+
+		if err := backend.VerifyFile(localPath, path.Join(paths.Source, entry.Path), entry.SHA256); err != nil {
+			_ = os.Remove(localPath)
+			return err
+		}
+
+		transfer.Transferred(entry.Size)
+		mu.Lock()
+		stats.FileCount++
+		stats.TotalSize += entry.Size
+		mu.Unlock()
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
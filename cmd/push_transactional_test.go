@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__PushTransactional(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+	remoteDir := "artifacts/jobs/1/out"
+
+	source := t.TempDir()
+	write := func(name, contents string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(source, name)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(source, name), []byte(contents), 0644))
+	}
+	write("a.txt", "a")
+	write("logs/b.log", "b")
+
+	push := func(b backend.Backend, force bool) error {
+		paths := &files.ResolvedPath{Source: source, Destination: remoteDir}
+		_, err := pushTransactional(ctx, b, paths, nil, nil, backend.PushOptions{Force: force, Concurrency: 1}, false)
+		return err
+	}
+
+	pull := func() map[string]string {
+		dest := filepath.Join(t.TempDir(), "out")
+		paths := &files.ResolvedPath{Source: remoteDir, Destination: dest}
+		_, err := pullResolved(ctx, b, paths, backend.PullOptions{Concurrency: 2})
+		require.NoError(t, err)
+
+		pulled := map[string]string{}
+		require.NoError(t, filepath.Walk(dest, func(filename string, info os.FileInfo, err error) error {
+			if err != nil || info.IsDir() {
+				return err
+			}
+			rel, _ := filepath.Rel(dest, filename)
+			data, _ := os.ReadFile(filename)
+			pulled[filepath.ToSlash(rel)] = string(data)
+			return nil
+		}))
+		return pulled
+	}
+
+	transactions := func() []string {
+		ids := []string{}
+		prefix := backend.ChecksumSidecarPrefix(remoteDir) + "/transactions/"
+		for _, p := range b.Paths() {
+			id := strings.SplitN(strings.TrimPrefix(p, prefix), "/", 2)[0]
+			if strings.HasPrefix(p, prefix) && !slices.Contains(ids, id) {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	}
+
+	require.NoError(t, push(b, false))
+	assert.Equal(t, map[string]string{"a.txt": "a", "logs/b.log": "b"}, pull())
+	assert.Len(t, transactions(), 1)
+
+	// Nothing is stored at the visible paths of the directory
+	for _, p := range b.Paths() {
+		assert.True(t, backend.IsChecksumSidecar(p), p)
+	}
+
+	// Pushing the same files again is a no-op
+	require.NoError(t, push(b, false))
+
+	// Changed files need --force
+	write("a.txt", "changed")
+	err := push(b, false)
+	assert.ErrorContains(t, err, "already exists")
+
+	// A push failing half-way is not seen by pulls
+	write("broken.txt", "broken")
+	err = push(brokenBackend{b}, true)
+	assert.ErrorContains(t, err, "connection reset")
+	assert.Equal(t, map[string]string{"a.txt": "a", "logs/b.log": "b"}, pull())
+
+	require.NoError(t, os.Remove(filepath.Join(source, "broken.txt")))
+	require.NoError(t, push(b, true))
+	assert.Equal(t, map[string]string{"a.txt": "changed", "logs/b.log": "b"}, pull())
+
+	// The previous transaction is kept for one more push
+	m, err := loadManifest(ctx, b, remoteDir)
+	require.NoError(t, err)
+	assert.NotEmpty(t, m.Previous)
+	write("c.txt", "c")
+	require.NoError(t, push(b, true))
+	assert.Equal(t, map[string]string{"a.txt": "changed", "c.txt": "c", "logs/b.log": "b"}, pull())
+
+	committed, err := loadManifest(ctx, b, remoteDir)
+	require.NoError(t, err)
+	assert.Contains(t, transactions(), committed.Transaction)
+	assert.Contains(t, transactions(), committed.Previous)
+	assert.NotContains(t, transactions(), m.Previous)
+	_, ok := b.Get(manifest.Path(remoteDir))
+	assert.True(t, ok)
+}
+
+func Test__PushCAS(t *testing.T) {
+	ctx := context.Background()
+	b := memorybackend.New()
+
+	push := func(remoteDir string, contents map[string]string) {
+		source := t.TempDir()
+		for name, data := range contents {
+			require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(source, name)), 0755))
+			require.NoError(t, os.WriteFile(filepath.Join(source, name), []byte(data), 0644))
+		}
+
+		paths := &files.ResolvedPath{Source: source, Destination: remoteDir}
+		_, err := pushTransactional(ctx, b, paths, nil, nil, backend.PushOptions{Force: true, Concurrency: 2}, true)
+		require.NoError(t, err)
+	}
+
+	blobs := func() []string {
+		stored := []string{}
+		for _, p := range b.Paths() {
+			if strings.HasPrefix(p, manifest.BlobDir+"/") {
+				stored = append(stored, p)
+			}
+		}
+		return stored
+	}
+
+	// Identical files are stored once, within and across stores
+	push("artifacts/jobs/1/out", map[string]string{"a.txt": "same", "copy/a.txt": "same", "b.txt": "b"})
+	assert.Len(t, blobs(), 2)
+
+	push("artifacts/workflows/2/out", map[string]string{"a.txt": "same", "c.txt": "c"})
+	assert.Len(t, blobs(), 3)
+
+	dest := filepath.Join(t.TempDir(), "out")
+	paths := &files.ResolvedPath{Source: "artifacts/jobs/1/out", Destination: dest}
+	_, err := pullResolved(ctx, b, paths, backend.PullOptions{Concurrency: 2})
+	require.NoError(t, err)
+
+	data, err := os.ReadFile(filepath.Join(dest, "copy", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "same", string(data))
+
+	// Pushing other files keeps the blobs, which other stores may use
+	push("artifacts/jobs/1/out", map[string]string{"d.txt": "d"})
+	assert.Len(t, blobs(), 4)
+
+	m, err := loadManifest(ctx, b, "artifacts/jobs/1/out")
+	require.NoError(t, err)
+	assert.True(t, m.Blobs)
+	assert.Empty(t, m.Previous)
+}
@@ -0,0 +1,215 @@
+package cmd
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addPushURLFlags(cmd *cobra.Command) {
+	cmd.Flags().String("from-url", "", "stream the file at this http(s) URL into the storage instead of a local path")
+	cmd.Flags().String("sha256", "", "expected SHA256 checksum of the --from-url file; nothing is pushed on mismatch")
+}
+
+// runPushFromURL streams an HTTP(S) download straight into the backend.
+// The destination defaults to the last element of the URL path. The
+// download is hashed and counted as it streams: a checksum mismatch with
+// --sha256, or going over --max-file-size or --max-total-size, fails the
+// read, aborting the upload. Should a backend finish an upload without
+// reading to the end, the object is yanked if the rest fails the checks.
+func runPushFromURL(cmd *cobra.Command, resolver *files.PathResolver, sourceURL, destinationOverride string, opts backend.PushOptions, limits pushLimits) (*files.ResolvedPath, *storage.PushStats, error) {
+	expectedSum, err := cmd.Flags().GetString("sha256")
+	if err != nil {
+		return nil, nil, err
+	}
+	expectedSum = strings.ToLower(strings.TrimSpace(expectedSum))
+
+	u, err := url.Parse(sourceURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, nil, fmt.Errorf("invalid --from-url '%s': use an http or https URL", sourceURL)
+	}
+
+	name := path.Base(u.Path)
+	if destinationOverride == "" && (name == "/" || name == ".") {
+		return nil, nil, fmt.Errorf("cannot derive a file name from '%s'; use --destination", sourceURL)
+	}
+
+	paths := resolver.Push(name, destinationOverride)
+	paths.Source = sourceURL
+
+	ctx := getContext()
+	request, err := retryablehttp.NewRequestWithContext(ctx, http.MethodGet, sourceURL, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid --from-url '%s': %v", sourceURL, err)
+	}
+
+	log.Debugf("Downloading '%s'...\n", sourceURL)
+	response, err := storage.NewHTTPClient().Do(request)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download '%s': %v", sourceURL, err)
+	}
+
+	// #nosec
+	defer response.Body.Close()
+
+	if !common.IsStatusOK(response.StatusCode) {
+		return nil, nil, fmt.Errorf("GET request to %s failed with %d status code", sourceURL, response.StatusCode)
+	}
+
+	p, err := getPolicy()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	policyReq := policyRequest(policy.OperationPush, resolver, paths.Destination)
+	policyReq.Size, policyReq.Metadata = response.ContentLength, opts.Metadata
+	if err := p.Evaluate(policyReq); err != nil {
+		return nil, nil, err
+	}
+
+	if err := limits.checkSize(sourceURL, response.ContentLength); err != nil {
+		return nil, nil, err
+	}
+
+	verifier := &streamVerifier{r: response.Body, hash: sha256.New(), size: response.ContentLength, expectedSum: expectedSum, limits: limits, source: sourceURL}
+	var body io.Reader = verifier
+
+	// Without a Content-Length, size limits can only be enforced while streaming
+	if limit := p.MaxSize(policyReq); limit > 0 && policyReq.Size < 0 {
+		body = &policySizeLimiter{r: body, req: policyReq, limit: limit}
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	if err := pushStream(ctx, b, body, response.ContentLength, paths.Destination, opts); err != nil {
+		if verifier.err != nil {
+			return nil, nil, verifier.err
+		}
+		return nil, nil, err
+	}
+
+	// A backend may finish the upload without reading past the Content-Length;
+	// if the rest of the download fails the checks, the upload is yanked
+	if !verifier.verified {
+		if _, err := io.Copy(io.Discard, body); err != nil {
+			if yankErr := b.Yank(ctx, paths.Destination); yankErr != nil {
+				log.Warnf("Failed to yank '%s': %v\n", paths.Destination, yankErr)
+			}
+			return nil, nil, err
+		}
+	}
+
+	return paths, &storage.PushStats{FileCount: 1, TotalSize: verifier.n}, nil
+}
+
+// pushStream uploads r to remotePath directly if the backend supports it,
+// and stages it in a temporary file otherwise.
+func pushStream(ctx context.Context, b backend.Backend, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	if sp, ok := b.(backend.StreamPusher); ok {
+		err := sp.PushStream(ctx, r, size, remotePath, opts)
+		if !errors.Is(err, backend.ErrStreamingNotSupported) {
+			return err
+		}
+	}
+
+	log.Debug("Backend cannot upload this stream directly, staging it in a temporary file...\n")
+	staged, remove, err := stageStream(r)
+	if err != nil {
+		return err
+	}
+	defer remove()
+
+	return b.Push(ctx, staged, remotePath, opts)
+}
+
+// stageStream copies r into a temporary file, and returns its path and a
+// function removing it.
+func stageStream(r io.Reader) (string, func(), error) {
+	tmpFile, err := os.CreateTemp("", "artifact-stream-*")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary file: %v", err)
+	}
+
+	remove := removeOnQuit(tmpFile.Name())
+
+	_, err = files.Copy(tmpFile, r)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		remove()
+		return "", nil, fmt.Errorf("failed to stage stream in '%s': %v", tmpFile.Name(), err)
+	}
+
+	return tmpFile.Name(), remove, nil
+}
+
+// streamVerifier hashes and counts the bytes read through it. Reads fail
+// once there are more bytes than the push limits allow, and if the
+// checksum is not the expected one. The checksum is verified as soon as
+// size bytes were read, and on a mismatch, the last bytes are held back,
+// so the reader never gets a complete upload to store.
+type streamVerifier struct {
+	r           io.Reader
+	hash        hash.Hash
+	size        int64
+	expectedSum string
+	limits      pushLimits
+	source      string
+	n           int64
+	verified    bool
+	err         error
+}
+
+func (v *streamVerifier) Read(p []byte) (int, error) {
+	if v.err != nil {
+		return 0, v.err
+	}
+
+	n, err := v.r.Read(p)
+	v.hash.Write(p[:n])
+	v.n += int64(n)
+
+	if v.err = v.limits.checkSize(v.source, v.n); v.err != nil {
+		return 0, v.err
+	}
+
+	if err == io.EOF || v.n == v.size {
+		if actualSum := hex.EncodeToString(v.hash.Sum(nil)); v.expectedSum != "" && actualSum != v.expectedSum {
+			v.err = fmt.Errorf("nothing was pushed: checksum mismatch for '%s': expected %s, got %s", v.source, v.expectedSum, actualSum)
+			return 0, v.err
+		}
+		v.verified = err == io.EOF || v.size >= 0
+	}
+
+	return n, err
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
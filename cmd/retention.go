@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/retention"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewRetentionCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "retention",
+		Short: "Manages the retention policy stored in the backend",
+		Long: `Defines how long stored files are kept, e.g. on S3 buckets where the
+retention of Semaphore does not apply:
+
+  artifact retention set job --match '**/*.log' --expire-in 7d
+
+The policy is stored in the backend, so every machine applies the same
+rules. Files are not deleted by the rules themselves: run
+'artifact prune CATEGORY --retention' or 'artifact gc --retention',
+e.g. from a scheduled pipeline, to delete expired files. If several rules
+match a file, the longest retention wins.`,
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set CATEGORY",
+		Short: "Expires the files of a category, e.g. 'retention set job --match \"**/*.log\" --expire-in 7d'.",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			match, _ := cmd.Flags().GetString("match")
+			expireIn, _ := cmd.Flags().GetString("expire-in")
+
+			b := getBackend()
+			defer func() { _ = b.Close() }()
+
+			errutil.Check(updateRetention(getContext(), b, func(p *retention.Policy) error {
+				return p.Set(args[0], match, expireIn)
+			}))
+
+			log.Infof("Files of the %s store matching '%s' now expire in %s.\n", args[0], matchOrAll(match), expireIn)
+		},
+	}
+	setCmd.Flags().String("match", "", "glob relative to the category, e.g. '**/*.log' (default is every file)")
+	setCmd.Flags().String("expire-in", "", "age after which files expire, e.g. 7d")
+	_ = setCmd.MarkFlagRequired("expire-in")
+	cmd.AddCommand(setCmd)
+
+	unsetCmd := &cobra.Command{
+		Use:     "unset CATEGORY",
+		Aliases: []string{"rm"},
+		Short:   "Removes a rule, so its files are kept.",
+		Args:    cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			match, _ := cmd.Flags().GetString("match")
+
+			b := getBackend()
+			defer func() { _ = b.Close() }()
+
+			errutil.Check(updateRetention(getContext(), b, func(p *retention.Policy) error {
+				if !p.Unset(args[0], match) {
+					return fmt.Errorf("no retention rule for %s files matching '%s'", args[0], matchOrAll(match))
+				}
+				return nil
+			}))
+
+			log.Infof("Removed the retention rule for %s files matching '%s'.\n", args[0], matchOrAll(match))
+		},
+	}
+	unsetCmd.Flags().String("match", "", "glob of the rule to remove (default is the rule for every file)")
+	cmd.AddCommand(unsetCmd)
+
+	cmd.AddCommand(&cobra.Command{
+		Use:     "list",
+		Aliases: []string{"ls"},
+		Short:   "Lists the retention rules.",
+		Args:    cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			b := getBackend()
+			defer func() { _ = b.Close() }()
+
+			p, err := loadRetention(getContext(), b)
+			errutil.Check(err)
+			errutil.Check(printRetention(cmd.OutOrStdout(), p))
+		},
+	})
+
+	return cmd
+}
+
+// loadRetention reads the retention policy from the backend. Without a
+// stored policy, nothing expires.
+func loadRetention(ctx context.Context, b backend.Backend) (*retention.Policy, error) {
+	r, err := openRemote(ctx, b, retention.Path)
+	if isNotFound(err) {
+		return &retention.Policy{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention policy: %v", err)
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read retention policy: %v", err)
+	}
+
+	return retention.Parse(data)
+}
+
+// updateRetention applies update to the stored retention policy.
+func updateRetention(ctx context.Context, b backend.Backend, update func(*retention.Policy) error) error {
+	p, err := loadRetention(ctx, b)
+	if err != nil {
+		return err
+	}
+
+	if err := update(p); err != nil {
+		return err
+	}
+
+	data, err := p.Marshal()
+	if err != nil {
+		return err
+	}
+
+	err = pushStream(ctx, b, bytes.NewReader(data), int64(len(data)), retention.Path, backend.PushOptions{Force: true})
+	if err != nil {
+		return fmt.Errorf("failed to store retention policy: %v", err)
+	}
+
+	return nil
+}
+
+func printRetention(out io.Writer, p *retention.Policy) error {
+	if len(p.Rules) == 0 {
+		log.Info("No retention rules; files are kept until they are deleted.\n")
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, rule := range p.Rules {
+		if _, err := fmt.Fprintf(w, "%s\t%s\t%s\n", rule.Category, rule.Match, rule.ExpireIn); err != nil {
+			return err
+		}
+	}
+
+	return w.Flush()
+}
+
+func matchOrAll(match string) string {
+	if match == "" {
+		return "**"
+	}
+
+	return match
+}
+
+func init() {
+	rootCmd.AddCommand(NewRetentionCmd())
+}
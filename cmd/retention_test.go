@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/retention"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Retention(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/build.log", []byte("log"))
+	memory.Put("artifacts/jobs/1/app.zip", []byte("app"))
+	memory.Put("artifacts/jobs/2/test.log", []byte("log"))
+
+	p, err := loadRetention(ctx, memory)
+	require.NoError(t, err)
+	assert.Empty(t, p.Rules)
+
+	require.NoError(t, updateRetention(ctx, memory, func(p *retention.Policy) error {
+		return p.Set("job", "**/*.log", "7d")
+	}))
+
+	p, err = loadRetention(ctx, memory)
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 1)
+	assert.Equal(t, "7d", p.Rules[0].ExpireIn)
+
+	later := time.Now().Add(8 * 24 * time.Hour)
+
+	t.Run("prune selects expired files", func(t *testing.T) {
+		candidates, err := pruneCandidates(ctx, memory, "artifacts/jobs/1", "artifacts/jobs/1", &lsOptions{Now: later})
+		require.NoError(t, err)
+
+		expired := expiredEntries(candidates, p, files.ResourceTypeJob, later)
+		require.Len(t, expired, 1)
+		assert.Equal(t, "build.log", expired[0].Name)
+
+		assert.Empty(t, expiredEntries(candidates, p, files.ResourceTypeJob, time.Now()))
+	})
+
+	t.Run("gc skips the stores it deletes", func(t *testing.T) {
+		stores, err := scanGCStores(ctx, memory, []string{files.ResourceTypeJob})
+		require.NoError(t, err)
+
+		expired, err := expiredGCFiles(ctx, memory, p, stores, stores[1:], later)
+		require.NoError(t, err)
+		require.Len(t, expired, 1)
+		assert.Equal(t, "jobs/1/build.log", expired[0].Name)
+
+		resourceType, id := gcStoreOf(expired[0].Info.Path)
+		assert.Equal(t, "job", resourceType)
+		assert.Equal(t, "1", id)
+	})
+}
@@ -1,7 +1,10 @@
 package cmd
 
 import (
+	"strings"
+
 	homedir "github.com/mitchellh/go-homedir"
+	"github.com/semaphoreci/artifact/pkg/config"
 	errutil "github.com/semaphoreci/artifact/pkg/errors"
 	"github.com/semaphoreci/artifact/pkg/logger"
 	log "github.com/sirupsen/logrus"
@@ -11,6 +14,7 @@ import (
 
 var (
 	cfgFile string
+	profile string
 	verbose bool
 )
 
@@ -24,9 +28,22 @@ var rootCmd = &cobra.Command{
 		if verbose {
 			log.SetLevel(log.DebugLevel)
 		}
+
+		resetContext(getTimeout(cmd))
+
+		// config list reports unknown settings itself
+		if !strings.HasPrefix(cmd.CommandPath(), "artifact config") {
+			warnAboutUnknownSettings()
+		}
 	},
 }
 
+// SetVersion sets the version of the CLI, printed by --version and
+// compared with the latest release by self-update.
+func SetVersion(version string) {
+	rootCmd.Version = version
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -41,7 +58,10 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.artifact.yaml)")
+	rootCmd.PersistentFlags().StringVar(&profile, "profile", "", "use the settings of this profile from the config file (default is $ARTIFACT_PROFILE)")
+	rootCmd.PersistentFlags().StringVar(&policyFile, "policy-file", "", "evaluate pushes and yanks against this policy file instead of the config")
 	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "verbose logging")
+	addTimeoutFlag(rootCmd)
 }
 
 // initConfig reads in config file and ENV variables if set.
@@ -69,4 +89,9 @@ func initConfig() {
 	if err := viper.ReadInConfig(); err == nil {
 		log.Debugf("Using config file: %s\n", viper.ConfigFileUsed())
 	}
+
+	if name := config.ActiveProfile(profile); name != "" {
+		errutil.Check(config.ApplyProfile(name))
+		log.Debugf("Using profile: %s\n", name)
+	}
 }
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/selfupdate"
+	"github.com/semaphoreci/artifact/pkg/signing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewSelfUpdateCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Updates the CLI to the latest release",
+		Long: `Downloads the latest release from GitHub and replaces the running binary,
+e.g. in jobs running on images that bake an old version:
+
+  artifact self-update --channel edge
+
+The archive is checked against the checksums published with the release,
+and, with --key, the checksums against their signature. Nothing is
+replaced unless the release is newer, or --force is set.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			errutil.Check(runSelfUpdate(cmd))
+		},
+	}
+
+	cmd.Flags().String("channel", selfupdate.ChannelStable, "release channel: stable, or edge to include pre-releases")
+	cmd.Flags().Bool("check", false, "only report whether a newer release is available")
+	cmd.Flags().Bool("force", false, "install the latest release even if it is not newer")
+	cmd.Flags().String("key", "", "public key the release checksums must be signed with, e.g. cosign.pub")
+	return cmd
+}
+
+// selfUpdateOptions configure selfUpdate.
+type selfUpdateOptions struct {
+	Channel string
+	Check   bool
+	Force   bool
+	Key     crypto.PublicKey // nil to only check checksums
+	Version string           // the running version
+	Archive string           // the release archive of the platform
+	Binary  string           // the binary in the archive
+}
+
+func runSelfUpdate(cmd *cobra.Command) error {
+	opts := selfUpdateOptions{
+		Version: rootCmd.Version,
+		Archive: selfupdate.ArchiveName(runtime.GOOS, runtime.GOARCH, goarm()),
+		Binary:  "artifact",
+	}
+
+	opts.Channel, _ = cmd.Flags().GetString("channel")
+	opts.Check, _ = cmd.Flags().GetBool("check")
+	opts.Force, _ = cmd.Flags().GetBool("force")
+	if runtime.GOOS == "windows" {
+		opts.Binary += ".exe"
+	}
+
+	if keyPath, _ := cmd.Flags().GetString("key"); keyPath != "" {
+		key, err := signing.LoadPublicKey(keyPath)
+		if err != nil {
+			return err
+		}
+		opts.Key = key
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// Package managers install symlinks; the binary they point to is replaced
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return err
+	}
+
+	client := &selfupdate.Client{
+		URL:        selfUpdateURL(),
+		Token:      os.Getenv("GITHUB_TOKEN"),
+		HttpClient: &http.Client{Timeout: 5 * time.Minute},
+	}
+
+	return selfUpdate(getContext(), client, exe, opts)
+}
+
+// selfUpdate replaces the binary at exe with the latest release of the
+// channel, if it is newer than the running version.
+func selfUpdate(ctx context.Context, client *selfupdate.Client, exe string, opts selfUpdateOptions) error {
+	release, err := client.Latest(ctx, opts.Channel)
+	if err != nil {
+		return err
+	}
+
+	if !opts.Force && !selfupdate.Newer(release.Version(), opts.Version) {
+		log.Infof("artifact %s is up to date; the latest %s release is %s.\n", opts.Version, opts.Channel, release.Tag)
+		return nil
+	}
+
+	if opts.Check {
+		log.Infof("artifact %s is available (running %s); run 'artifact self-update' to install it.\n", release.Tag, opts.Version)
+		return nil
+	}
+
+	checksums, err := downloadAsset(ctx, client, release, selfupdate.ChecksumsName)
+	if err != nil {
+		return err
+	}
+
+	if opts.Key != nil {
+		signature, err := downloadAsset(ctx, client, release, selfupdate.ChecksumsName+signing.SignatureExt)
+		if err != nil {
+			return err
+		}
+
+		if err := selfupdate.VerifyChecksums(opts.Key, checksums, signature); err != nil {
+			return err
+		}
+	}
+
+	log.Infof("Downloading artifact %s (%s)...\n", release.Tag, opts.Archive)
+	archive, err := downloadAsset(ctx, client, release, opts.Archive)
+	if err != nil {
+		return err
+	}
+
+	if err := selfupdate.VerifyChecksum(checksums, opts.Archive, archive); err != nil {
+		return err
+	}
+
+	binary, err := selfupdate.ExtractBinary(bytes.NewReader(archive), opts.Binary)
+	if err != nil {
+		return err
+	}
+
+	if err := selfupdate.Replace(exe, binary); err != nil {
+		return err
+	}
+
+	log.Infof("Updated '%s' from %s to %s.\n", exe, opts.Version, release.Tag)
+	return nil
+}
+
+func downloadAsset(ctx context.Context, client *selfupdate.Client, release *selfupdate.Release, name string) ([]byte, error) {
+	asset, err := release.Asset(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Download(ctx, asset)
+}
+
+// selfUpdateURL returns the API endpoint releases are fetched from:
+// ARTIFACT_SELF_UPDATE_URL, e.g. for a GitHub Enterprise mirror, or GitHub.
+func selfUpdateURL() string {
+	if url := os.Getenv("ARTIFACT_SELF_UPDATE_URL"); url != "" {
+		return url
+	}
+
+	return selfupdate.DefaultURL
+}
+
+// goarm returns the ARM version the binary was built for, which names the
+// release archive on 32-bit ARM.
+func goarm() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "GOARM" {
+				return setting.Value
+			}
+		}
+	}
+
+	return "6"
+}
+
+func init() {
+	rootCmd.AddCommand(NewSelfUpdateCmd())
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/selfupdate"
+	"github.com/semaphoreci/artifact/pkg/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__SelfUpdate(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	archive := &bytes.Buffer{}
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "artifact", Mode: 0755, Size: 3, Typeflag: tar.TypeReg}))
+	_, err = tw.Write([]byte("new"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	archiveName := "artifact_Linux_x86_64.tar.gz"
+	sum := sha256.Sum256(archive.Bytes())
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  " + archiveName + "\n")
+	signature, err := signing.Sign(key, bytes.NewReader(checksums))
+	require.NoError(t, err)
+
+	assets := map[string][]byte{
+		archiveName:              archive.Bytes(),
+		selfupdate.ChecksumsName: checksums,
+		selfupdate.ChecksumsName + signing.SignatureExt: signature,
+	}
+
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	release := func(tag string, prerelease bool) *selfupdate.Release {
+		r := &selfupdate.Release{Tag: tag, Prerelease: prerelease}
+		for name := range assets {
+			r.Assets = append(r.Assets, selfupdate.Asset{Name: name, URL: server.URL + "/download/" + name})
+		}
+		return r
+	}
+
+	mux.HandleFunc("/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(release("v1.2.0", false))
+	})
+	mux.HandleFunc("/releases", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode([]*selfupdate.Release{release("v1.3.0-rc.1", true)})
+	})
+	mux.HandleFunc("/download/", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(assets[filepath.Base(r.URL.Path)])
+	})
+
+	client := &selfupdate.Client{URL: server.URL, HttpClient: http.DefaultClient}
+	update := func(opts selfUpdateOptions) (string, error) {
+		exe := filepath.Join(t.TempDir(), "artifact")
+		require.NoError(t, os.WriteFile(exe, []byte("old"), 0755))
+
+		opts.Archive, opts.Binary = archiveName, "artifact"
+		err := selfUpdate(context.Background(), client, exe, opts)
+
+		contents, readErr := os.ReadFile(exe)
+		require.NoError(t, readErr)
+		return string(contents), err
+	}
+
+	contents, err := update(selfUpdateOptions{Channel: "stable", Version: "v1.1.0", Key: key.Public()})
+	require.NoError(t, err)
+	assert.Equal(t, "new", contents)
+
+	// Releases that are not newer are skipped, unless forced
+	contents, err = update(selfUpdateOptions{Channel: "stable", Version: "v1.2.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "old", contents)
+
+	contents, err = update(selfUpdateOptions{Channel: "stable", Version: "v1.2.0", Force: true})
+	require.NoError(t, err)
+	assert.Equal(t, "new", contents)
+
+	// Edge includes pre-releases
+	contents, err = update(selfUpdateOptions{Channel: "edge", Version: "v1.2.0", Check: true})
+	require.NoError(t, err)
+	assert.Equal(t, "old", contents)
+
+	contents, err = update(selfUpdateOptions{Channel: "edge", Version: "v1.2.0"})
+	require.NoError(t, err)
+	assert.Equal(t, "new", contents)
+
+	t.Run("rejects tampered releases", func(t *testing.T) {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		contents, err := update(selfUpdateOptions{Channel: "stable", Version: "v1.1.0", Key: other.Public()})
+		assert.ErrorContains(t, err, "signature of artifact_checksums.txt")
+		assert.Equal(t, "old", contents)
+
+		assets[archiveName] = []byte("tampered")
+		contents, err = update(selfUpdateOptions{Channel: "stable", Version: "v1.1.0"})
+		assert.ErrorContains(t, err, "does not match")
+		assert.Equal(t, "old", contents)
+	})
+}
@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"html/template"
+	"mime"
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewServeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serves the files in the storage over local HTTP",
+		Long: `Starts a read-only HTTP server for the files stored for a project,
+workflow or job, or under a directory in it. Directories are listed, and
+their index.html is served if they have one, so test reports and coverage
+HTML can be browsed without pulling them. Requests are proxied to the
+configured backend. The server stops with Ctrl-C.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Serves %s files over HTTP.", cobra.MaximumNArgs(1), addServeFlags, runServeForCategory)
+	return cmd
+}
+
+func addServeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("addr", "127.0.0.1:8080", "address to listen on; use 0.0.0.0:PORT to accept connections from other hosts")
+}
+
+func runServeForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	addr, _ := cmd.Flags().GetString("addr")
+
+	root := resolver.PrefixedPath("")
+	if len(args) > 0 {
+		root = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	_, err := getLister(b)
+	errutil.Check(err)
+
+	listener, err := net.Listen("tcp", addr)
+	errutil.Check(err)
+
+	server := &http.Server{
+		Handler:           newArtifactServer(backend.NewReadOnly(b), root),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	log.Infof("Serving '%s' at http://%s/ (Ctrl-C to stop)...\n", strings.TrimSuffix(root, "/"), listener.Addr())
+	errutil.Check(server.Serve(listener))
+}
+
+// artifactServer serves the files stored under root over HTTP, read-only.
+type artifactServer struct {
+	b      backend.Backend
+	lister backend.Lister
+	root   string
+}
+
+// newArtifactServer serves the files under root from b, which must implement Lister.
+func newArtifactServer(b backend.Backend, root string) *artifactServer {
+	lister, _ := b.(backend.Lister)
+	return &artifactServer{b: b, lister: lister, root: strings.TrimSuffix(root, "/")}
+}
+
+func (s *artifactServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	log.Debugf("%s %s\n", r.Method, r.URL.Path)
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "the artifact server is read-only", http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Cleaning the path keeps requests inside root
+	name := strings.TrimPrefix(path.Clean("/"+r.URL.Path), "/")
+	remotePath := path.Join(s.root, name)
+
+	tree, err := buildTree(r.Context(), s.lister, remotePath, s.root)
+	if err != nil {
+		s.fail(w, remotePath, err)
+		return
+	}
+
+	if !tree.Dir {
+		s.serveFile(w, r, remotePath, tree.Size)
+		return
+	}
+
+	if len(tree.Children) == 0 && name != "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	// Relative links in listings and index pages need the trailing slash
+	if !strings.HasSuffix(r.URL.Path, "/") {
+		http.Redirect(w, r, path.Base(r.URL.Path)+"/", http.StatusMovedPermanently)
+		return
+	}
+
+	for _, child := range tree.Children {
+		if !child.Dir && child.Name == "index.html" {
+			s.serveFile(w, r, path.Join(remotePath, child.Name), child.Size)
+			return
+		}
+	}
+
+	s.serveListing(w, r, name, tree)
+}
+
+// serveFile streams the file at remotePath, with a content type guessed
+// from its extension, or sniffed from its contents if that fails.
+func (s *artifactServer) serveFile(w http.ResponseWriter, r *http.Request, remotePath string, size int64) {
+	if contentType := mime.TypeByExtension(path.Ext(remotePath)); contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	body, err := openRemote(r.Context(), s.b, remotePath)
+	if err != nil {
+		w.Header().Del("Content-Length")
+		s.fail(w, remotePath, err)
+		return
+	}
+	defer body.Close()
+
+	if _, err := files.Copy(w, body); err != nil {
+		log.Warnf("Failed to serve '%s': %v\n", remotePath, err)
+	}
+}
+
+// listingEntry is a file or directory in a directory listing.
+type listingEntry struct {
+	Name string
+	Href string
+	Size string
+}
+
+var listingTemplate = template.Must(template.New("listing").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Index of {{.Title}}</title>
+</head>
+<body>
+<h1>Index of {{.Title}}</h1>
+<table>
+{{- if .Parent}}
+<tr><td><a href="../">../</a></td><td></td></tr>
+{{- end}}
+{{- range .Entries}}
+<tr><td><a href="{{.Href}}">{{.Name}}</a></td><td align="right">{{.Size}}</td></tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+func (s *artifactServer) serveListing(w http.ResponseWriter, r *http.Request, name string, tree *treeNode) {
+	entries := []listingEntry{}
+	for _, child := range tree.Children {
+		entry := listingEntry{Name: child.Name, Size: formatBytes(child.Size)}
+
+		// The ./ prefix keeps names with a colon from being read as a scheme
+		entry.Href = "./" + (&url.URL{Path: child.Name}).EscapedPath()
+		if child.Dir {
+			entry.Name += "/"
+			entry.Href += "/"
+		}
+
+		entries = append(entries, entry)
+	}
+
+	title := "/"
+	if name != "" {
+		title += name + "/"
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if r.Method == http.MethodHead {
+		return
+	}
+
+	err := listingTemplate.Execute(w, map[string]interface{}{
+		"Title":   title,
+		"Parent":  name != "",
+		"Entries": entries,
+	})
+	if err != nil {
+		log.Warnf("Failed to list '%s': %v\n", name, err)
+	}
+}
+
+// fail answers with 404 for files that do not exist, and 500 otherwise.
+func (s *artifactServer) fail(w http.ResponseWriter, remotePath string, err error) {
+	if isNotFound(err) {
+		http.Error(w, "404 page not found", http.StatusNotFound)
+		return
+	}
+
+	log.Errorf("Error serving '%s': %v\n", remotePath, err)
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
+func init() {
+	rootCmd.AddCommand(NewServeCmd())
+}
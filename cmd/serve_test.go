@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Serve(t *testing.T) {
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/build.log", []byte("built"))
+	memory.Put("artifacts/jobs/1/coverage/index.html", []byte("<h1>coverage</h1>"))
+	memory.Put("artifacts/jobs/1/coverage/app.css", []byte("body {}"))
+	memory.Put("artifacts/jobs/1/reports/junit #1.xml", []byte("<testsuite/>"))
+	memory.Put("artifacts/jobs/12/other.log", []byte("other"))
+
+	server := httptest.NewServer(newArtifactServer(backend.NewReadOnly(memory), "artifacts/jobs/1/"))
+	defer server.Close()
+
+	// Redirects are checked, not followed
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+
+	get := func(method, path string) (*http.Response, string) {
+		req, err := http.NewRequest(method, server.URL+path, strings.NewReader(""))
+		require.NoError(t, err)
+
+		response, err := client.Do(req)
+		require.NoError(t, err)
+		defer response.Body.Close()
+
+		body, err := io.ReadAll(response.Body)
+		require.NoError(t, err)
+		return response, string(body)
+	}
+
+	t.Run("lists directories", func(t *testing.T) {
+		response, body := get(http.MethodGet, "/")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Contains(t, body, "<title>Index of /</title>")
+		assert.Contains(t, body, `<a href="./build.log">build.log</a></td><td align="right">5 B</td>`)
+		assert.Contains(t, body, `<a href="./coverage/">coverage/</a></td><td align="right">24 B</td>`)
+		assert.NotContains(t, body, "../")
+		assert.NotContains(t, body, "other.log")
+
+		_, body = get(http.MethodGet, "/reports/")
+		assert.Contains(t, body, "<title>Index of /reports/</title>")
+		assert.Contains(t, body, `<a href="../">../</a>`)
+		assert.Contains(t, body, `<a href="./junit%20%231.xml">junit #1.xml</a>`)
+	})
+
+	t.Run("serves files", func(t *testing.T) {
+		response, body := get(http.MethodGet, "/coverage/app.css")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, "text/css; charset=utf-8", response.Header.Get("Content-Type"))
+		assert.Equal(t, "body {}", body)
+
+		response, body = get(http.MethodHead, "/build.log")
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, int64(5), response.ContentLength)
+		assert.Equal(t, "", body)
+	})
+
+	t.Run("serves index pages", func(t *testing.T) {
+		response, _ := get(http.MethodGet, "/coverage")
+		assert.Equal(t, http.StatusMovedPermanently, response.StatusCode)
+		assert.Equal(t, "/coverage/", response.Header.Get("Location"))
+
+		response, body := get(http.MethodGet, "/coverage/")
+		assert.Equal(t, "text/html; charset=utf-8", response.Header.Get("Content-Type"))
+		assert.Equal(t, "<h1>coverage</h1>", body)
+	})
+
+	t.Run("stays inside the store", func(t *testing.T) {
+		response, _ := get(http.MethodGet, "/missing.log")
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+
+		response, _ = get(http.MethodGet, "/../12/other.log")
+		assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	})
+
+	t.Run("rejects writes", func(t *testing.T) {
+		response, _ := get(http.MethodPut, "/build.log")
+		assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+		assert.Equal(t, "GET, HEAD", response.Header.Get("Allow"))
+	})
+}
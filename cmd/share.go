@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewShareCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "share",
+		Short: "Prints a time-limited link to download a file",
+		Long: `Prints a link anyone can use to download a stored file until it expires,
+e.g. to paste a test report into a pull request. Only the link is written
+to stdout:
+
+  artifact share job reports/index.html --expires-in 2h | pbcopy
+
+The S3 backend presigns the link for --expires-in, up to 7 days. The Hub
+backend hands out its own signed URLs, whose lifetime is set by Hub.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Prints a link to download a %s file.", cobra.ExactArgs(1), addShareFlags, runShareForCategory)
+	return cmd
+}
+
+func addShareFlags(cmd *cobra.Command) {
+	cmd.Flags().String("expires-in", "24h", "how long the link works, e.g. 30m, 12h or 7d")
+}
+
+func runShareForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	value, _ := cmd.Flags().GetString("expires-in")
+	expiresIn, err := parseShareExpiry(value)
+	errutil.Check(err)
+
+	remotePath := resolver.PrefixedPath(files.ToRelative(args[0]))
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	link, expires, err := share(getContext(), b, remotePath, expiresIn)
+	if err != nil {
+		log.Errorf("Error sharing '%s': %v\n", remotePath, err)
+		errutil.Exit(1)
+		return
+	}
+
+	fmt.Fprintln(cmd.OutOrStdout(), link)
+
+	switch {
+	case expires.IsZero():
+		log.Info("The link expires when the backend's signature does.\n")
+	case cmd.Flags().Changed("expires-in") && expires.Sub(time.Now().Add(expiresIn)).Abs() > time.Minute:
+		log.Warnf("The backend does not support --expires-in; the link expires at %s.\n", expires.Local().Format(time.RFC1123))
+	default:
+		log.Infof("The link expires at %s.\n", expires.Local().Format(time.RFC1123))
+	}
+}
+
+// parseShareExpiry parses --expires-in as a Go duration, e.g. 30m, or
+// an age such as 7d.
+func parseShareExpiry(value string) (time.Duration, error) {
+	expiresIn, err := time.ParseDuration(value)
+	if err != nil {
+		expiresIn, err = common.ParseAge(value)
+	}
+
+	if err != nil || expiresIn <= 0 {
+		return 0, fmt.Errorf("invalid --expires-in '%s': use e.g. 30m, 12h or 7d", value)
+	}
+
+	return expiresIn, nil
+}
+
+// share creates a link to remotePath with the backend's Sharer.
+func share(ctx context.Context, b backend.Backend, remotePath string, expiresIn time.Duration) (string, time.Time, error) {
+	sharer, ok := b.(backend.Sharer)
+	if !ok || !backend.CapabilitiesOf(b).Share {
+		return "", time.Time{}, backend.ErrSharingNotSupported
+	}
+
+	link, expires, err := sharer.Share(ctx, remotePath, expiresIn)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return "", time.Time{}, fmt.Errorf("'%s' does not exist; only files can be shared, push directories with --archive", remotePath)
+	}
+
+	return link, expires, err
+}
+
+func init() {
+	rootCmd.AddCommand(NewShareCmd())
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Share(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/reports/index.html", Contents: "<html>"},
+	})
+	require.NoError(t, err)
+
+	out := &bytes.Buffer{}
+	cmd := NewShareCmd()
+	cmd.SetOut(out)
+	cmd.SetArgs([]string{"job", "reports/index.html", "--expires-in", "2h"})
+	require.NoError(t, cmd.Execute())
+
+	link := strings.TrimSpace(out.String())
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "7200", u.Query().Get("X-Amz-Expires"))
+
+	resp, err := http.Get(link)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, "<html>", string(body))
+}
+
+func Test__ShareErrors(t *testing.T) {
+	ctx := context.Background()
+
+	_, _, err := share(ctx, memorybackend.New(), "artifacts/jobs/1/a.txt", time.Hour)
+	assert.ErrorIs(t, err, backend.ErrSharingNotSupported)
+}
+
+func Test__ParseShareExpiry(t *testing.T) {
+	check := func(value string, expected time.Duration) {
+		expiresIn, err := parseShareExpiry(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, expiresIn, value)
+	}
+
+	check("30m", 30*time.Minute)
+	check("12h", 12*time.Hour)
+	check("7d", 7*24*time.Hour)
+	check("1w", 7*24*time.Hour)
+
+	for _, value := range []string{"", "soon", "0s", "-1h"} {
+		_, err := parseShareExpiry(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/signing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewSignCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sign",
+		Short: "Signs stored files with a private key",
+		Long: `Signs a stored file, or every file of a stored directory, and pushes a
+detached signature next to each one, e.g. bin/app.sig for bin/app.
+Signatures have the format of 'cosign sign-blob --key', so they can be
+checked with 'artifact verify-signature', 'artifact pull --require-signature'
+or 'cosign verify-blob --key cosign.pub --signature app.sig app'.
+
+The key is an unencrypted PEM encoded ECDSA or RSA private key, given with
+--key, ARTIFACT_SIGNING_KEY or the signing.key config key.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Signs %s files with a private key.", cobra.ExactArgs(1), addSignFlags, runSignForCategory)
+	return cmd
+}
+
+func addSignFlags(cmd *cobra.Command) {
+	cmd.Flags().String("key", "", "PEM encoded private key (default is ARTIFACT_SIGNING_KEY or the signing.key config key)")
+}
+
+func runSignForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	keyPath, _ := cmd.Flags().GetString("key")
+	if keyPath == "" {
+		keyPath = signing.PrivateKeyPath()
+	}
+
+	if keyPath == "" {
+		errutil.Check(fmt.Errorf("no signing key: use --key, ARTIFACT_SIGNING_KEY or the signing.key config key"))
+		return
+	}
+
+	signer, err := signing.LoadPrivateKey(keyPath)
+	errutil.Check(err)
+
+	remotePath := resolver.PrefixedPath(files.ToRelative(args[0]))
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	signed, err := signFiles(getContext(), b, signer, remotePath)
+	if err != nil {
+		log.Errorf("Error signing '%s': %v\n", remotePath, err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Signed %d %s in '%s'.\n", signed, pluralize(signed, "file", "files"), remotePath)
+}
+
+// signFiles signs the file at remotePath, or every file under it, and
+// pushes the signatures next to them. It returns how many files it signed.
+func signFiles(ctx context.Context, b backend.Backend, signer crypto.Signer, remotePath string) (int, error) {
+	paths, err := signableFiles(ctx, b, remotePath)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, p := range paths {
+		r, err := openRemote(ctx, b, p)
+		if err != nil {
+			return 0, err
+		}
+
+		signature, err := signing.Sign(signer, r)
+		r.Close()
+		if err != nil {
+			return 0, fmt.Errorf("failed to sign '%s': %v", p, err)
+		}
+
+		// Signing again replaces the signature, e.g. after a key rotation
+		signaturePath := signing.SignaturePath(p)
+		err = pushStream(ctx, b, bytes.NewReader(signature), int64(len(signature)), signaturePath, backend.PushOptions{Force: true})
+		if err != nil {
+			return 0, fmt.Errorf("failed to push signature '%s': %v", signaturePath, err)
+		}
+
+		log.Debugf("Signed '%s'.\n", p)
+	}
+
+	return len(paths), nil
+}
+
+// signableFiles returns the file at remotePath, or the files under it,
+// without their signatures. Backends that cannot list only sign single files.
+func signableFiles(ctx context.Context, b backend.Backend, remotePath string) ([]string, error) {
+	lister, err := getLister(b)
+	if errors.Is(err, backend.ErrListingNotSupported) {
+		return []string{remotePath}, nil
+	}
+
+	paths := []string{}
+	err = walkRemote(ctx, lister, remotePath, func(obj backend.ObjectInfo) error {
+		if !signing.IsSignature(obj.Path) {
+			paths = append(paths, obj.Path)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(paths) == 0 {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return paths, nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewSignCmd())
+}
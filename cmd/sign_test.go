@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__SignAndVerifySignatures(t *testing.T) {
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	memory := memorybackend.New()
+	memory.Put("artifacts/projects/1/bin/app", []byte("app"))
+	memory.Put("artifacts/projects/1/bin/tool", []byte("tool"))
+
+	signed, err := signFiles(ctx, memory, key, "artifacts/projects/1/bin")
+	require.NoError(t, err)
+	assert.Equal(t, 2, signed)
+
+	_, ok := memory.Get("artifacts/projects/1/bin/app.sig")
+	assert.True(t, ok)
+
+	results, err := verifySignatures(ctx, memory, key.Public(), "artifacts/projects/1/bin")
+	require.NoError(t, err)
+	assert.Equal(t, []verifyResult{{Path: "app", Status: verifyOK}, {Path: "tool", Status: verifyOK}}, results)
+
+	// Signing again only signs the files, not their signatures
+	signed, err = signFiles(ctx, memory, key, "artifacts/projects/1/bin")
+	require.NoError(t, err)
+	assert.Equal(t, 2, signed)
+
+	t.Run("reports tampered and unsigned files", func(t *testing.T) {
+		memory.Put("artifacts/projects/1/bin/app", []byte("tampered"))
+		memory.Put("artifacts/projects/1/bin/new", []byte("new"))
+
+		results, err := verifySignatures(ctx, memory, key.Public(), "artifacts/projects/1/bin")
+		require.NoError(t, err)
+		assert.Equal(t, []verifyResult{
+			{Path: "app", Status: signatureInvalid},
+			{Path: "new", Status: signatureUnsigned},
+			{Path: "tool", Status: verifyOK},
+		}, results)
+
+		results, err = verifySignatures(ctx, memory, key.Public(), "artifacts/projects/1/bin/tool")
+		require.NoError(t, err)
+		assert.Equal(t, []verifyResult{{Path: "tool", Status: verifyOK}}, results)
+	})
+
+	t.Run("removes pulled files with invalid signatures", func(t *testing.T) {
+		destination := filepath.Join(t.TempDir(), "bin")
+		paths := &files.ResolvedPath{Source: "artifacts/projects/1/bin", Destination: destination}
+		_, err := pullResolved(ctx, memory, paths, backend.PullOptions{})
+		require.NoError(t, err)
+
+		err = verifyPulledSignatures(ctx, memory, key.Public(), paths)
+		assert.ErrorContains(t, err, "2 of 3 files failed signature verification")
+
+		assert.NoFileExists(t, filepath.Join(destination, "app"))
+		assert.NoFileExists(t, filepath.Join(destination, "new"))
+		assert.FileExists(t, filepath.Join(destination, "tool"))
+
+		// Single files are checked against the signature of the remote file
+		single := &files.ResolvedPath{Source: "artifacts/projects/1/bin/tool", Destination: filepath.Join(t.TempDir(), "tool")}
+		_, err = pullResolved(ctx, memory, single, backend.PullOptions{})
+		require.NoError(t, err)
+		assert.NoError(t, verifyPulledSignatures(ctx, memory, key.Public(), single))
+	})
+
+	t.Run("checks archives before extracting them", func(t *testing.T) {
+		source := t.TempDir()
+		require.NoError(t, os.WriteFile(filepath.Join(source, "a.txt"), []byte("a"), 0600))
+
+		archive := &files.ResolvedPath{Source: source, Destination: "artifacts/projects/1/dist.zip"}
+		_, err := pushArchive(ctx, memory, archive, files.ArchiveZip, backend.PushOptions{})
+		require.NoError(t, err)
+
+		paths := &files.ResolvedPath{Source: "artifacts/projects/1/dist.zip", Destination: filepath.Join(t.TempDir(), "dist")}
+		_, err = pullExtracted(ctx, memory, paths, files.ArchiveZip, false, key.Public())
+		assert.ErrorContains(t, err, "is unsigned")
+		assert.NoDirExists(t, paths.Destination)
+
+		_, err = signFiles(ctx, memory, key, "artifacts/projects/1/dist.zip")
+		require.NoError(t, err)
+		_, err = pullExtracted(ctx, memory, paths, files.ArchiveZip, false, key.Public())
+		assert.NoError(t, err)
+		assert.FileExists(t, filepath.Join(paths.Destination, "a.txt"))
+	})
+}
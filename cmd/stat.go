@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// statInfo describes a single stored file. Fields the backend
+// does not provide are left empty.
+type statInfo struct {
+	Path         string `json:"path"`
+	Size         int64  `json:"size"`
+	ContentType  string `json:"contentType,omitempty"`
+	Checksum     string `json:"sha256,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+func NewStatCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stat",
+		Short: "Describes a single file in the storage",
+		Long: `Prints the size, content type, checksum, ETag and last modification time
+of a file stored for a project, workflow or job. Backends that do not
+store some of these leave them out.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Describes a %s file in the storage.", cobra.ExactArgs(1), addStatFlags, runStatForCategory)
+	return cmd
+}
+
+func addStatFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("output", "o", "text", "output format: text or json")
+}
+
+func runStatForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	output, _ := cmd.Flags().GetString("output")
+	if output != "text" && output != "json" {
+		errutil.Check(fmt.Errorf("invalid --output '%s': use text or json", output))
+		return
+	}
+
+	name := files.ToRelative(args[0])
+	remotePath := resolver.PrefixedPath(name)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	ctx := getContext()
+	obj, err := backend.Stat(ctx, b, remotePath)
+	if err != nil {
+		log.Errorf("Error describing artifact: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	info := &statInfo{Path: name, Size: obj.Size, ContentType: obj.ContentType, ETag: obj.ETag}
+	if !obj.ModTime.IsZero() {
+		info.LastModified = obj.ModTime.UTC().Format(time.RFC3339)
+	}
+
+	if reader, ok := b.(backend.ChecksumReader); ok {
+		info.Checksum, err = reader.Checksum(ctx, remotePath)
+		if err != nil {
+			log.Debugf("Failed to get the checksum of '%s': %v\n", remotePath, err)
+		}
+	}
+
+	errutil.Check(info.write(cmd.OutOrStdout(), output))
+}
+
+// write prints the file description in the given output format.
+func (s *statInfo) write(out io.Writer, output string) error {
+	if output == "json" {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+	}
+
+	rows := [][2]string{
+		{"Path", s.Path},
+		{"Size", fmt.Sprintf("%d (%s)", s.Size, formatBytes(s.Size))},
+		{"Content type", s.ContentType},
+		{"SHA256", s.Checksum},
+		{"ETag", s.ETag},
+		{"Last modified", s.LastModified},
+	}
+
+	for _, row := range rows {
+		if row[1] == "" {
+			continue
+		}
+
+		if _, err := fmt.Fprintf(out, "%-15s%s\n", row[0]+":", row[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewStatCmd())
+}
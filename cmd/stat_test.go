@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Stat(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/logs/a.txt", Contents: "hello"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewStatCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("prints a file as json", func(t *testing.T) {
+		info := statInfo{}
+		require.NoError(t, json.Unmarshal([]byte(run("logs/a.txt", "-o", "json")), &info))
+		assert.Equal(t, "logs/a.txt", info.Path)
+		assert.Equal(t, int64(5), info.Size)
+		assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", info.ETag)
+		assert.NotEmpty(t, info.LastModified)
+	})
+
+	t.Run("prints a file as text", func(t *testing.T) {
+		output := run("logs/a.txt")
+		assert.Contains(t, output, "Path:          logs/a.txt\n")
+		assert.Contains(t, output, "Size:          5 (5 B)\n")
+	})
+
+	t.Run("prints nothing for missing files", func(t *testing.T) {
+		assert.Empty(t, run("logs/missing.txt"))
+	})
+}
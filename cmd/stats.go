@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	statsIntervalDay   = "day"
+	statsIntervalWeek  = "week"
+	statsIntervalMonth = "month"
+
+	statsDateFormat = "2006-01-02"
+)
+
+// storageStats aggregates the files stored under a path: totals, plus how
+// many files and bytes were added in every interval since a point in time.
+type storageStats struct {
+	Path     string         `json:"path"`
+	Files    int            `json:"files"`
+	Bytes    int64          `json:"bytes"`
+	Since    string         `json:"since"`
+	Interval string         `json:"interval"`
+	Periods  []*statsPeriod `json:"periods"`
+}
+
+// statsPeriod is one interval of the growth report. Totals include
+// everything stored up to the end of the period.
+type statsPeriod struct {
+	Start      string `json:"start"`
+	Files      int    `json:"files"`
+	Bytes      int64  `json:"bytes"`
+	TotalFiles int    `json:"totalFiles"`
+	TotalBytes int64  `json:"totalBytes"`
+}
+
+func NewStatsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Reports storage usage and growth over time",
+		Long: `Aggregates the number of files and bytes stored for a project, workflow
+or job, and how much was added in every day, week or month of a time range.
+Growth is based on the upload time of the files currently stored, so
+yanked or expired files are not included.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Reports usage of %s storage.", cobra.MaximumNArgs(1), addStatsFlags, runStatsForCategory)
+	return cmd
+}
+
+func addStatsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("since", "30d", "report growth over this period, e.g. 12w")
+	cmd.Flags().String("interval", statsIntervalDay, "group growth by day, week or month")
+	cmd.Flags().StringP("output", "o", "table", "output format: table, json or csv")
+}
+
+func runStatsForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	since, _ := cmd.Flags().GetString("since")
+	interval, _ := cmd.Flags().GetString("interval")
+	output, _ := cmd.Flags().GetString("output")
+
+	age, err := common.ParseAge(since)
+	errutil.Check(err)
+
+	if interval != statsIntervalDay && interval != statsIntervalWeek && interval != statsIntervalMonth {
+		errutil.Check(fmt.Errorf("invalid --interval '%s': use day, week or month", interval))
+		return
+	}
+
+	if output != "table" && output != "json" && output != "csv" {
+		errutil.Check(fmt.Errorf("invalid --output '%s': use table, json or csv", output))
+		return
+	}
+
+	remotePath := resolver.PrefixedPath("")
+	if len(args) > 0 {
+		remotePath = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	stats := newStorageStats(remotePath, time.Now().Add(-age), interval)
+	err = walkRemote(getContext(), lister, remotePath, func(obj backend.ObjectInfo) error {
+		stats.add(obj)
+		return nil
+	})
+	if err != nil {
+		log.Errorf("Error listing artifacts: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	stats.finish()
+	errutil.Check(stats.write(cmd.OutOrStdout(), output))
+}
+
+// newStorageStats prepares an empty period for every interval from since until now.
+func newStorageStats(remotePath string, since time.Time, interval string) *storageStats {
+	stats := &storageStats{
+		Path:     remotePath,
+		Since:    since.UTC().Format(time.RFC3339),
+		Interval: interval,
+		Periods:  []*statsPeriod{},
+	}
+
+	now := time.Now().UTC()
+	for start := periodStart(since.UTC(), interval); !start.After(now); start = nextPeriod(start, interval) {
+		stats.Periods = append(stats.Periods, &statsPeriod{Start: start.Format(statsDateFormat)})
+	}
+
+	return stats
+}
+
+// add counts an object in the totals, and in the period it was uploaded in.
+// Objects uploaded before the first period only count towards the totals.
+func (s *storageStats) add(obj backend.ObjectInfo) {
+	s.Files++
+	s.Bytes += obj.Size
+
+	start := periodStart(obj.ModTime.UTC(), s.Interval).Format(statsDateFormat)
+	for _, period := range s.Periods {
+		if period.Start == start {
+			period.Files++
+			period.Bytes += obj.Size
+			return
+		}
+	}
+}
+
+// finish computes the running totals of every period.
+func (s *storageStats) finish() {
+	added := 0
+	addedBytes := int64(0)
+	for _, period := range s.Periods {
+		added += period.Files
+		addedBytes += period.Bytes
+	}
+
+	files := s.Files - added
+	bytes := s.Bytes - addedBytes
+	for _, period := range s.Periods {
+		files += period.Files
+		bytes += period.Bytes
+		period.TotalFiles = files
+		period.TotalBytes = bytes
+	}
+}
+
+func (s *storageStats) write(out io.Writer, format string) error {
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(s)
+
+	case "csv":
+		w := csv.NewWriter(out)
+		_ = w.Write([]string{"start", "files", "bytes", "totalFiles", "totalBytes"})
+		for _, p := range s.Periods {
+			_ = w.Write([]string{
+				p.Start,
+				strconv.Itoa(p.Files),
+				strconv.FormatInt(p.Bytes, 10),
+				strconv.Itoa(p.TotalFiles),
+				strconv.FormatInt(p.TotalBytes, 10),
+			})
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		fmt.Fprintf(out, "%s: %d %s, %s\n\n", s.Path, s.Files, pluralize(s.Files, "file", "files"), formatBytes(s.Bytes))
+		fmt.Fprintf(out, "%-10s  %8s  %10s  %8s  %10s\n", "START", "ADDED", "SIZE", "TOTAL", "TOTAL SIZE")
+		for _, p := range s.Periods {
+			fmt.Fprintf(out, "%-10s  %8d  %10s  %8d  %10s\n", p.Start, p.Files, formatBytes(p.Bytes), p.TotalFiles, formatBytes(p.TotalBytes))
+		}
+		return nil
+	}
+}
+
+// periodStart returns the start of the day, week (Monday) or month t falls in.
+func periodStart(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	switch interval {
+	case statsIntervalWeek:
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case statsIntervalMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+func nextPeriod(start time.Time, interval string) time.Time {
+	switch interval {
+	case statsIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	case statsIntervalMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func init() {
+	rootCmd.AddCommand(NewStatsCmd())
+}
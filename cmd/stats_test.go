@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Stats(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_PROJECT_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/projects/1/a.txt", Contents: "aaaaa"},
+		{Name: "artifacts/projects/1/logs/b.log", Contents: "b"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewStatsCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"project"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("json", func(t *testing.T) {
+		stats := storageStats{}
+		require.NoError(t, json.Unmarshal([]byte(run("--since", "7d", "-o", "json")), &stats))
+
+		assert.Equal(t, 2, stats.Files)
+		assert.Equal(t, int64(6), stats.Bytes)
+		require.Len(t, stats.Periods, 8)
+
+		today := stats.Periods[len(stats.Periods)-1]
+		assert.Equal(t, time.Now().UTC().Format(statsDateFormat), today.Start)
+		assert.Equal(t, 2, today.Files)
+		assert.Equal(t, 2, today.TotalFiles)
+	})
+
+	t.Run("csv for a directory", func(t *testing.T) {
+		lines := strings.Split(strings.TrimSpace(run("logs", "--since", "1m", "--interval", "month", "-o", "csv")), "\n")
+		assert.Equal(t, "start,files,bytes,totalFiles,totalBytes", lines[0])
+		assert.True(t, strings.HasSuffix(lines[len(lines)-1], ",1,1,1,1"))
+	})
+}
+
+func Test__StorageStats(t *testing.T) {
+	now := time.Now().UTC()
+	stats := newStorageStats("artifacts/jobs/1", now.AddDate(0, 0, -2), statsIntervalDay)
+	require.Len(t, stats.Periods, 3)
+
+	stats.add(backend.ObjectInfo{Size: 100, ModTime: now.AddDate(0, 0, -30)})
+	stats.add(backend.ObjectInfo{Size: 10, ModTime: now.AddDate(0, 0, -1)})
+	stats.add(backend.ObjectInfo{Size: 1, ModTime: now})
+	stats.finish()
+
+	assert.Equal(t, 3, stats.Files)
+	assert.Equal(t, int64(111), stats.Bytes)
+
+	// Older files count towards the running totals from the start
+	assert.Equal(t, []int{0, 1, 1}, []int{stats.Periods[0].Files, stats.Periods[1].Files, stats.Periods[2].Files})
+	assert.Equal(t, []int64{100, 110, 111}, []int64{stats.Periods[0].TotalBytes, stats.Periods[1].TotalBytes, stats.Periods[2].TotalBytes})
+}
+
+func Test__PeriodStart(t *testing.T) {
+	thursday := time.Date(2026, 10, 15, 13, 30, 0, 0, time.UTC)
+
+	assert.Equal(t, "2026-10-15", periodStart(thursday, statsIntervalDay).Format(statsDateFormat))
+	assert.Equal(t, "2026-10-12", periodStart(thursday, statsIntervalWeek).Format(statsDateFormat))
+	assert.Equal(t, "2026-10-01", periodStart(thursday, statsIntervalMonth).Format(statsDateFormat))
+}
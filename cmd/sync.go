@@ -0,0 +1,238 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewSyncCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Uploads the files of a directory that changed since the last sync",
+		Long: `Compares a local directory with its copy in the storage and only uploads
+new and changed files. Files are compared by size, then by ETag or stored
+checksum, from a single listing of the remote directory. With --delete,
+stored files missing from the local directory are removed.`,
+	}
+
+	addCategoryCmds(cmd, "DIRECTORY", "Synchronizes a local directory with the %s storage.", cobra.ExactArgs(1), addSyncFlags, runSyncForCategory)
+	return cmd
+}
+
+func addSyncFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("destination", "d", "", "remote directory to synchronize; defaults to the name of DIRECTORY")
+	cmd.Flags().Bool("delete", false, "remove stored files that do not exist in DIRECTORY")
+	cmd.Flags().Bool("dry-run", false, "print what would be uploaded and removed without doing it")
+}
+
+func runSyncForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	destination, _ := cmd.Flags().GetString("destination")
+	del, _ := cmd.Flags().GetBool("delete")
+	dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+	info, err := os.Stat(args[0])
+	errutil.Check(err)
+	if !info.IsDir() {
+		errutil.Check(fmt.Errorf("'%s' is not a directory; use push for single files", args[0]))
+		return
+	}
+
+	paths := resolver.Push(args[0], destination)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	ctx := getContext()
+	plan, err := planSync(ctx, b, lister, paths.Source, paths.Destination, del)
+	if err != nil {
+		log.Errorf("Error comparing '%s' with the storage: %v\n", paths.Source, err)
+		errutil.Exit(1)
+		return
+	}
+
+	if dryRun {
+		plan.print(cmd.OutOrStdout(), resolver.PrefixedPath(""))
+		return
+	}
+
+	push := policyRequest(policy.OperationPush, resolver, paths.Destination)
+	push.Dir, push.Size = true, plan.uploadSize()
+	errutil.Check(checkPolicy(push))
+
+	if len(plan.Delete) > 0 {
+		yank := policyRequest(policy.OperationYank, resolver, paths.Destination)
+		yank.Dir = true
+		errutil.Check(checkPolicy(yank))
+	}
+
+	if err := plan.apply(ctx, b); err != nil {
+		log.Errorf("Error synchronizing '%s': %v\n", paths.Source, err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Synchronized '%s' with %s.\n", paths.Source, paths.Destination)
+	log.Infof("Uploaded %d %s (%s), removed %d, %d unchanged.\n",
+		len(plan.Upload), pluralize(len(plan.Upload), "file", "files"), formatBytes(plan.uploadSize()), len(plan.Delete), plan.Unchanged)
+}
+
+// syncUpload is a local file to upload during a sync.
+type syncUpload struct {
+	LocalPath  string
+	RemotePath string
+	Size       int64
+	Stored     bool // a different version of the file is stored
+}
+
+// syncPlan lists the changes that bring a remote directory in line
+// with a local one.
+type syncPlan struct {
+	Upload    []syncUpload
+	Delete    []string
+	Unchanged int
+}
+
+// planSync compares the files under localDir with the ones stored under
+// remoteDir. Remote files missing locally are only deleted if del is set.
+func planSync(ctx context.Context, b backend.Backend, lister backend.Lister, localDir, remoteDir string, del bool) (*syncPlan, error) {
+	stored := map[string]backend.ObjectInfo{}
+	err := walkRemote(ctx, lister, remoteDir, func(obj backend.ObjectInfo) error {
+		stored[obj.Path] = obj
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	reader, _ := b.(backend.ChecksumReader)
+	plan := &syncPlan{}
+
+	err = filepath.Walk(localDir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(localDir, filename)
+		if err != nil {
+			return err
+		}
+		remotePath := path.Join(remoteDir, filepath.ToSlash(rel))
+
+		obj, ok := stored[remotePath]
+		delete(stored, remotePath)
+
+		if ok {
+			unchanged, err := syncUnchanged(ctx, reader, filename, info, obj)
+			if err != nil {
+				return err
+			}
+			if unchanged {
+				log.Debugf("Skipping unchanged '%s'.\n", filename)
+				plan.Unchanged++
+				return nil
+			}
+		}
+
+		plan.Upload = append(plan.Upload, syncUpload{LocalPath: filename, RemotePath: remotePath, Size: info.Size(), Stored: ok})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if del {
+		for remotePath := range stored {
+			plan.Delete = append(plan.Delete, remotePath)
+		}
+		sort.Strings(plan.Delete)
+	}
+
+	return plan, nil
+}
+
+// syncUnchanged reports whether the stored file obj matches the local file.
+// Files of a different size changed. Otherwise the ETag is compared with the
+// MD5 (S3 single-part uploads) and SHA256 of the local file, and then the
+// checksum stored by the backend. Files that cannot be compared count as changed.
+func syncUnchanged(ctx context.Context, reader backend.ChecksumReader, filename string, info os.FileInfo, obj backend.ObjectInfo) (bool, error) {
+	if obj.Size != info.Size() {
+		return false, nil
+	}
+
+	md5sum, sha256sum, err := files.FileDigests(filename)
+	if err != nil {
+		return false, err
+	}
+
+	if obj.ETag != "" && (obj.ETag == md5sum || obj.ETag == sha256sum) {
+		return true, nil
+	}
+
+	if reader == nil {
+		return false, nil
+	}
+
+	storedChecksum, err := remoteChecksum(ctx, reader, obj.Path)
+	if err != nil {
+		return false, err
+	}
+
+	return storedChecksum == sha256sum, nil
+}
+
+// apply uploads and deletes the files in the plan.
+func (p *syncPlan) apply(ctx context.Context, b backend.Backend) error {
+	for _, upload := range p.Upload {
+		if err := b.Push(ctx, upload.LocalPath, upload.RemotePath, backend.PushOptions{Force: true}); err != nil {
+			return err
+		}
+	}
+
+	for _, remotePath := range p.Delete {
+		if err := b.Yank(ctx, remotePath); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// print writes the changes in the plan, one file per line,
+// with paths relative to root.
+func (p *syncPlan) print(out io.Writer, root string) {
+	for _, upload := range p.Upload {
+		fmt.Fprintf(out, "upload %s\n", relativeName(upload.RemotePath, root))
+	}
+
+	for _, remotePath := range p.Delete {
+		fmt.Fprintf(out, "delete %s\n", relativeName(remotePath, root))
+	}
+}
+
+func (p *syncPlan) uploadSize() int64 {
+	var size int64
+	for _, upload := range p.Upload {
+		size += upload.Size
+	}
+
+	return size
+}
+
+func init() {
+	rootCmd.AddCommand(NewSyncCmd())
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Sync(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	site := filepath.Join(t.TempDir(), "site")
+	require.NoError(t, os.MkdirAll(filepath.Join(site, "docs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(site, "index.html"), []byte("index"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(site, "docs", "a.html"), []byte("a"), 0644))
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/site/docs/a.html", Contents: "a"},
+		{Name: "artifacts/jobs/1/site/index.html", Contents: "old index"},
+		{Name: "artifacts/jobs/1/site/removed.html", Contents: "removed"},
+		{Name: "artifacts/jobs/1/site-old/index.html", Contents: "unrelated"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewSyncCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job", site}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("prints the plan on a dry run", func(t *testing.T) {
+		assert.Equal(t, "upload site/index.html\n", run("--dry-run"))
+		assert.Equal(t, "upload site/index.html\ndelete site/removed.html\n", run("--dry-run", "--delete"))
+	})
+
+	t.Run("uploads changed files and deletes removed ones", func(t *testing.T) {
+		run("--delete")
+		assert.Equal(t, "", run("--dry-run", "--delete"))
+
+		b := getBackend()
+		defer b.Close()
+
+		exists, err := b.Exists(context.Background(), "artifacts/jobs/1/site-old/index.html")
+		require.NoError(t, err)
+		assert.True(t, exists)
+	})
+
+	t.Run("uploads new files", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(site, "docs", "b.html"), []byte("b"), 0644))
+		assert.Equal(t, "upload site/docs/b.html\n", run("--dry-run"))
+		run()
+		assert.Equal(t, "", run("--dry-run"))
+	})
+}
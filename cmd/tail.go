@@ -0,0 +1,224 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// tailChunkSize is how much of the end of a file is read at first to find
+// its last lines. It doubles until enough lines are found.
+const tailChunkSize = 64 * 1024
+
+func NewTailCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Prints the end of a stored file and follows what is appended to it",
+		Long: `Prints the last lines of a file stored for a project, workflow or job.
+With --follow, the file is polled with range requests and new bytes are
+printed as they are pushed, e.g. to follow the log of a long-running job
+from another job or a laptop. Following stops with Ctrl-C.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Prints the end of a %s file in the storage.", cobra.ExactArgs(1), addTailFlags, runTailForCategory)
+	return cmd
+}
+
+func addTailFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("follow", "f", false, "keep printing what is appended to the file, waiting for it to be pushed if needed")
+	cmd.Flags().IntP("lines", "n", 10, "number of lines to print from the end of the file")
+	cmd.Flags().Duration("interval", 2*time.Second, "how often to poll the file with --follow")
+}
+
+func runTailForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	follow, _ := cmd.Flags().GetBool("follow")
+	lines, _ := cmd.Flags().GetInt("lines")
+	interval, _ := cmd.Flags().GetDuration("interval")
+
+	if lines < 0 {
+		errutil.Check(fmt.Errorf("invalid --lines %d: must not be negative", lines))
+		return
+	}
+	if interval <= 0 {
+		errutil.Check(fmt.Errorf("invalid --interval %s: must be positive", interval))
+		return
+	}
+
+	remotePath := resolver.PrefixedPath(files.ToRelative(args[0]))
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	ctx := getContext()
+	tail := &tailFollower{b: b, remotePath: remotePath, out: cmd.OutOrStdout()}
+
+	err := tail.start(ctx, lines)
+	if follow && isNotFound(err) {
+		log.Infof("Waiting for '%s' to be pushed...\n", args[0])
+		err = tail.follow(ctx, interval)
+	} else if follow && err == nil {
+		err = tail.follow(ctx, interval)
+	}
+
+	if err != nil {
+		log.Errorf("Error reading '%s': %v\n", args[0], err)
+		errutil.Exit(1)
+	}
+}
+
+// tailFollower prints a stored file as it grows.
+type tailFollower struct {
+	b          backend.Backend
+	remotePath string
+	out        io.Writer
+	offset     int64 // bytes of the file printed or skipped so far
+}
+
+// start prints the last lines of the file and moves the offset to its end.
+// Only the end of the file is read if the backend can tell its size.
+func (t *tailFollower) start(ctx context.Context, lines int) error {
+	size, err := t.size(ctx)
+	if err != nil {
+		return err
+	}
+
+	for window := int64(tailChunkSize); ; window *= 2 {
+		from := int64(0)
+		if size > window {
+			from = size - window
+		}
+
+		data, err := t.read(ctx, from)
+		if err != nil {
+			return err
+		}
+
+		// The first line in a window may be cut off, so the window
+		// grows until it holds enough lines or the whole file
+		index := lastLinesIndex(data, lines)
+		if index < 0 && from > 0 {
+			continue
+		}
+		if index < 0 {
+			index = 0
+		}
+
+		t.offset = from + int64(len(data))
+		_, err = t.out.Write(data[index:])
+		return err
+	}
+}
+
+// poll prints what was appended to the file since the last call. A file
+// that became shorter was replaced, and is printed from its start.
+func (t *tailFollower) poll(ctx context.Context) error {
+	size, err := t.size(ctx)
+	if err != nil {
+		return err
+	}
+
+	if size >= 0 && size < t.offset {
+		log.Warnf("'%s' was truncated, printing it from the start.\n", t.remotePath)
+		t.offset = 0
+	}
+	if size == t.offset {
+		return nil
+	}
+
+	r, err := backend.OpenRange(ctx, t.b, t.remotePath, t.offset)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	n, err := io.Copy(t.out, r)
+	t.offset += n
+	return err
+}
+
+// follow polls the file every interval until ctx is done. The file may
+// not exist yet, or be missing for a while when it is pushed again.
+func (t *tailFollower) follow(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		err := t.poll(ctx)
+		if isNotFound(err) {
+			log.Debugf("'%s' not found, waiting for it...\n", t.remotePath)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// size returns the size of the file, or -1 if the backend cannot tell it.
+func (t *tailFollower) size(ctx context.Context) (int64, error) {
+	info, err := backend.Stat(ctx, t.b, t.remotePath)
+	if errors.Is(err, backend.ErrListingNotSupported) {
+		return -1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	return info.Size, nil
+}
+
+func (t *tailFollower) read(ctx context.Context, offset int64) ([]byte, error) {
+	r, err := backend.OpenRange(ctx, t.b, t.remotePath, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	return io.ReadAll(r)
+}
+
+// lastLinesIndex returns where the last n lines of data start, or -1 if
+// data holds fewer lines. A final newline does not start another line.
+func lastLinesIndex(data []byte, n int) int {
+	end := len(data)
+	if n == 0 {
+		return end
+	}
+
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+
+	for i := 0; i < n; i++ {
+		end = bytes.LastIndexByte(data[:end], '\n')
+		if end < 0 {
+			return -1
+		}
+	}
+
+	return end + 1
+}
+
+func isNotFound(err error) bool {
+	var notFound *backend.ErrNotFound
+	return errors.As(err, &notFound)
+}
+
+func init() {
+	rootCmd.AddCommand(NewTailCmd())
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Tail(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/build.log", Contents: "one\ntwo\nthree\n"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewTailCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"job"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	assert.Equal(t, "two\nthree\n", run("build.log", "-n", "2"))
+	assert.Equal(t, "one\ntwo\nthree\n", run("build.log"))
+	assert.Equal(t, "", run("build.log", "-n", "0"))
+}
+
+func Test__TailFollower(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	out := &bytes.Buffer{}
+	tail := &tailFollower{b: memory, remotePath: "artifacts/jobs/1/build.log", out: out}
+
+	assert.True(t, isNotFound(tail.start(ctx, 10)))
+
+	memory.Put("artifacts/jobs/1/build.log", []byte("one\ntwo\nthree"))
+	require.NoError(t, tail.start(ctx, 1))
+	assert.Equal(t, "three", out.String())
+
+	t.Run("prints appended bytes", func(t *testing.T) {
+		out.Reset()
+		require.NoError(t, tail.poll(ctx))
+		assert.Equal(t, "", out.String())
+
+		memory.Put("artifacts/jobs/1/build.log", []byte("one\ntwo\nthree\nfour\n"))
+		require.NoError(t, tail.poll(ctx))
+		assert.Equal(t, "\nfour\n", out.String())
+	})
+
+	t.Run("restarts truncated files", func(t *testing.T) {
+		out.Reset()
+		memory.Put("artifacts/jobs/1/build.log", []byte("new\n"))
+		require.NoError(t, tail.poll(ctx))
+		assert.Equal(t, "new\n", out.String())
+	})
+
+	t.Run("follows until cancelled", func(t *testing.T) {
+		out.Reset()
+		memory.Put("artifacts/jobs/1/build.log", []byte("new\nmore\n"))
+
+		ctx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+
+		require.NoError(t, tail.follow(ctx, 10*time.Millisecond))
+		assert.Equal(t, "more\n", out.String())
+	})
+
+	t.Run("reads long files from their end", func(t *testing.T) {
+		long := strings.Repeat("x", 3*tailChunkSize) + "\nlast\n"
+		memory.Put("artifacts/jobs/1/long.log", []byte(long))
+
+		out := &bytes.Buffer{}
+		tail := &tailFollower{b: memory, remotePath: "artifacts/jobs/1/long.log", out: out}
+		require.NoError(t, tail.start(ctx, 2))
+		assert.Equal(t, long, out.String())
+		assert.Equal(t, int64(len(long)), tail.offset)
+	})
+}
+
+func Test__LastLinesIndex(t *testing.T) {
+	assert.Equal(t, 4, lastLinesIndex([]byte("one\ntwo\n"), 1))
+	assert.Equal(t, 4, lastLinesIndex([]byte("one\ntwo"), 1))
+	assert.Equal(t, -1, lastLinesIndex([]byte("one\ntwo\n"), 2))
+	assert.Equal(t, 8, lastLinesIndex([]byte("one\ntwo\n"), 0))
+}
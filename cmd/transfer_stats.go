@@ -0,0 +1,115 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const (
+	transferStatsJSON    = "json"
+	defaultTransferStats = "artifact-stats.json"
+)
+
+func addTransferStatsFlags(cmd *cobra.Command) {
+	cmd.Flags().String("stats", "", "also write the stats of the transfer to --stats-file in this format: json")
+	cmd.Flags().String("stats-file", defaultTransferStats, "file --stats writes the stats of the transfer to")
+}
+
+// checkTransferStatsFlags checks the --stats format before anything is
+// transferred.
+func checkTransferStatsFlags(cmd *cobra.Command) error {
+	format, err := cmd.Flags().GetString("stats")
+	errutil.Check(err)
+
+	if format != "" && format != transferStatsJSON {
+		return fmt.Errorf("invalid --stats '%s': use json", format)
+	}
+
+	return nil
+}
+
+// transferReport is the document --stats json writes: the summary of a
+// push or pull, with its paths, and its error if it failed.
+type transferReport struct {
+	Operation   string `json:"operation"`
+	Source      string `json:"source,omitempty"`
+	Destination string `json:"destination,omitempty"`
+	Error       string `json:"error,omitempty"`
+	backend.TransferSummary
+}
+
+// reportTransfer logs the summary of the push or pull of the command, e.g.
+// "Pushed 3 files (1.2 MB) in 2.1s at 580 KB/s, skipped 1 file (10 KB),
+// 2 requests retried.", and writes it to the --stats-file with --stats.
+// The files and bytes a successful transfer reported count as
+// transferred, unless its files were recorded one by one.
+func reportTransfer(cmd *cobra.Command, operation string, paths *files.ResolvedPath, fileCount int, size int64, err error) {
+	stats := backend.TransferStatsFromContext(getContext())
+	if err == nil {
+		stats.Complete(fileCount, size)
+	}
+
+	summary := stats.Summary()
+	log.Info(formatTransferSummary(operation, summary))
+
+	format, _ := cmd.Flags().GetString("stats")
+	if format != transferStatsJSON {
+		return
+	}
+
+	report := transferReport{Operation: operation, TransferSummary: summary}
+	if paths != nil {
+		report.Source, report.Destination = paths.Source, paths.Destination
+	}
+	if err != nil {
+		report.Error = err.Error()
+	}
+
+	statsFile, _ := cmd.Flags().GetString("stats-file")
+	if err := writeTransferReport(statsFile, report); err != nil {
+		log.Warnf("Failed to write the stats to '%s': %v\n", statsFile, err)
+	}
+}
+
+func writeTransferReport(path string, report transferReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// formatTransferSummary returns the summary logged at the end of a push or
+// pull. Skipped and failed files and retries are only mentioned if any.
+func formatTransferSummary(operation string, s backend.TransferSummary) string {
+	verb := "Pushed"
+	if operation == "pull" {
+		verb = "Pulled"
+	}
+
+	elapsed := time.Duration(s.ElapsedSeconds * float64(time.Second)).Round(10 * time.Millisecond)
+	parts := []string{fmt.Sprintf("%s %d %s (%s) in %s at %s/s", verb, s.Files, pluralize(s.Files, "file", "files"),
+		formatBytes(s.Bytes), elapsed, formatBytes(int64(s.BytesPerSecond)))}
+
+	if s.SkippedFiles > 0 {
+		parts = append(parts, fmt.Sprintf("skipped %d %s (%s)", s.SkippedFiles, pluralize(s.SkippedFiles, "file", "files"), formatBytes(s.SkippedBytes)))
+	}
+	if s.FailedFiles > 0 {
+		parts = append(parts, fmt.Sprintf("%d failed", s.FailedFiles))
+	}
+	if s.Retries > 0 {
+		parts = append(parts, fmt.Sprintf("%d %s retried", s.Retries, pluralize(int(s.Retries), "request", "requests")))
+	}
+
+	return strings.Join(parts, ", ") + ".\n"
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__TransferStats(t *testing.T) {
+	t.Run("pushes record every file", func(t *testing.T) {
+		dir := t.TempDir()
+		for _, name := range []string{"a.txt", "b.txt", "broken.txt"} {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(name), 0644))
+		}
+
+		paths := &files.ResolvedPath{Source: dir, Destination: "artifacts/jobs/1/out"}
+		pushed, err := walkPushed(paths, nil)
+		require.NoError(t, err)
+
+		stats := backend.NewTransferStats()
+		ctx := backend.WithTransferStats(context.Background(), stats)
+		unchanged := func(f pushedFile) (bool, error) { return filepath.Base(f.LocalPath) == "b.txt", nil }
+		_, _, err = pushEach(ctx, brokenBackend{memorybackend.New()}, pushed, backend.PushOptions{}, true, unchanged)
+		assert.Error(t, err)
+
+		summary := stats.Summary()
+		assert.Equal(t, 1, summary.Files)
+		assert.Equal(t, int64(5), summary.Bytes)
+		assert.Equal(t, 1, summary.SkippedFiles)
+		assert.Equal(t, 1, summary.FailedFiles)
+	})
+
+	t.Run("--stats json writes the summary to a file", func(t *testing.T) {
+		resetContext(0)
+		defer resetContext(0)
+
+		statsFile := filepath.Join(t.TempDir(), "stats.json")
+		cmd := &cobra.Command{}
+		addTransferStatsFlags(cmd)
+		require.NoError(t, cmd.Flags().Set("stats", "json"))
+		require.NoError(t, cmd.Flags().Set("stats-file", statsFile))
+		require.NoError(t, checkTransferStatsFlags(cmd))
+
+		paths := &files.ResolvedPath{Source: "out", Destination: "artifacts/jobs/1/out"}
+		reportTransfer(cmd, "push", paths, 2, 300, nil)
+
+		data, err := os.ReadFile(statsFile)
+		require.NoError(t, err)
+
+		report := transferReport{}
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.Equal(t, "push", report.Operation)
+		assert.Equal(t, "artifacts/jobs/1/out", report.Destination)
+		assert.Equal(t, 2, report.Files)
+		assert.Equal(t, int64(300), report.Bytes)
+		assert.Empty(t, report.Error)
+
+		// Failed transfers are reported too
+		reportTransfer(cmd, "pull", nil, 0, 0, errors.New("connection reset"))
+
+		data, err = os.ReadFile(statsFile)
+		require.NoError(t, err)
+		require.NoError(t, json.Unmarshal(data, &report))
+		assert.Equal(t, "pull", report.Operation)
+		assert.Equal(t, "connection reset", report.Error)
+	})
+
+	t.Run("invalid formats are rejected", func(t *testing.T) {
+		cmd := &cobra.Command{}
+		addTransferStatsFlags(cmd)
+		require.NoError(t, cmd.Flags().Set("stats", "xml"))
+		assert.EqualError(t, checkTransferStatsFlags(cmd), "invalid --stats 'xml': use json")
+	})
+
+	t.Run("the summary mentions skips, failures and retries if any", func(t *testing.T) {
+		summary := backend.TransferSummary{Files: 3, Bytes: 3000, ElapsedSeconds: 1.5, BytesPerSecond: 2000}
+		assert.Equal(t, "Pushed 3 files (2.9 KB) in 1.5s at 2.0 KB/s.\n", formatTransferSummary("push", summary))
+
+		summary = backend.TransferSummary{Files: 1, Bytes: 10, SkippedFiles: 1, SkippedBytes: 20, FailedFiles: 2, Retries: 1, ElapsedSeconds: 2, BytesPerSecond: 5}
+		assert.Equal(t, "Pulled 1 file (10 B) in 2s at 5 B/s, skipped 1 file (20 B), 2 failed, 1 request retried.\n", formatTransferSummary("pull", summary))
+	})
+}
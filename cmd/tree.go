@@ -0,0 +1,180 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// treeNode is a file or directory in a rendered tree. Directories carry
+// the total size of the files below them.
+type treeNode struct {
+	Name     string
+	Size     int64
+	Dir      bool
+	Children []*treeNode
+}
+
+func NewTreeCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tree",
+		Short: "Prints the files in the storage as a tree",
+		Long: `Renders the files stored for a project, workflow or job, or under a
+directory in it, as a tree with the size of every file and directory.`,
+	}
+
+	addCategoryCmds(cmd, "[PATH]", "Prints %s files in the storage as a tree.", cobra.MaximumNArgs(1), addTreeFlags, runTreeForCategory)
+	return cmd
+}
+
+func addTreeFlags(cmd *cobra.Command) {
+	cmd.Flags().Int("depth", 0, "only descend this many levels below PATH (0 means no limit)")
+	cmd.Flags().BoolP("human-readable", "H", false, "print sizes in human readable format")
+}
+
+func runTreeForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	depth, _ := cmd.Flags().GetInt("depth")
+	humanReadable, _ := cmd.Flags().GetBool("human-readable")
+
+	if depth < 0 {
+		errutil.Check(fmt.Errorf("invalid --depth %d: must not be negative", depth))
+		return
+	}
+
+	root := resolver.PrefixedPath("")
+	remotePath := root
+	if len(args) > 0 {
+		remotePath = resolver.PrefixedPath(files.ToRelative(args[0]))
+	}
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	tree, err := buildTree(getContext(), lister, remotePath, root)
+	if err != nil {
+		log.Errorf("Error listing artifacts: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	errutil.Check(tree.write(cmd.OutOrStdout(), depth, humanReadable))
+}
+
+// buildTree builds the tree of the objects under remotePath. The root
+// node is named after remotePath, relative to root.
+func buildTree(ctx context.Context, lister backend.Lister, remotePath, root string) (*treeNode, error) {
+	dir := strings.TrimSuffix(remotePath, "/")
+	name := relativeName(dir, strings.TrimSuffix(root, "/"))
+	if name == "" {
+		name = "."
+	}
+
+	tree := &treeNode{Name: name, Dir: true}
+	err := walkRemote(ctx, lister, dir, func(obj backend.ObjectInfo) error {
+		rel := relativeName(obj.Path, dir)
+		if rel == "" {
+			// remotePath is a file
+			tree.Dir = false
+			tree.Size = obj.Size
+			return nil
+		}
+
+		tree.add(strings.Split(rel, "/"), obj.Size)
+		return nil
+	})
+
+	return tree, err
+}
+
+// add adds a file at the path given by parts below the node. Listings are
+// in key order, so the files of a directory arrive one after the other and
+// a directory can only be the last child added.
+func (n *treeNode) add(parts []string, size int64) {
+	n.Size += size
+	if len(parts) == 1 {
+		n.Children = append(n.Children, &treeNode{Name: parts[0], Size: size})
+		return
+	}
+
+	var child *treeNode
+	if last := len(n.Children) - 1; last >= 0 && n.Children[last].Dir && n.Children[last].Name == parts[0] {
+		child = n.Children[last]
+	} else {
+		child = &treeNode{Name: parts[0], Dir: true}
+		n.Children = append(n.Children, child)
+	}
+
+	child.add(parts[1:], size)
+}
+
+// write renders the tree down to depth levels (0 means all of them),
+// followed by the number of directories and files in it.
+func (n *treeNode) write(out io.Writer, depth int, humanReadable bool) error {
+	size := func(node *treeNode) string {
+		if humanReadable {
+			return fmt.Sprintf("[%9s]", formatBytes(node.Size))
+		}
+		return fmt.Sprintf("[%12s]", strconv.FormatInt(node.Size, 10))
+	}
+
+	dirs, fileCount := 0, 0
+	var walk func(node *treeNode, prefix string, level int) error
+	walk = func(node *treeNode, prefix string, level int) error {
+		for i, child := range node.Children {
+			branch, indent := "├── ", "│   "
+			if i == len(node.Children)-1 {
+				branch, indent = "└── ", "    "
+			}
+
+			name := child.Name
+			if child.Dir {
+				name += "/"
+				dirs++
+			} else {
+				fileCount++
+			}
+
+			if _, err := fmt.Fprintf(out, "%s%s%s  %s\n", prefix, branch, size(child), name); err != nil {
+				return err
+			}
+
+			if child.Dir && (depth == 0 || level < depth) {
+				if err := walk(child, prefix+indent, level+1); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	}
+
+	if _, err := fmt.Fprintf(out, "%s  %s\n", size(n), n.Name); err != nil {
+		return err
+	}
+
+	if err := walk(n, "", 1); err != nil {
+		return err
+	}
+
+	if !n.Dir {
+		fileCount = 1
+	}
+
+	_, err := fmt.Fprintf(out, "\n%d %s, %d %s\n", dirs, pluralize(dirs, "directory", "directories"), fileCount, pluralize(fileCount, "file", "files"))
+	return err
+}
+
+func init() {
+	rootCmd.AddCommand(NewTreeCmd())
+}
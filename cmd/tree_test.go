@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Tree(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_WORKFLOW_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/workflows/1/a.txt", Contents: "aaaa"},
+		{Name: "artifacts/workflows/1/logs/b.log", Contents: "bb"},
+		{Name: "artifacts/workflows/1/logs/test/c.log", Contents: "ccc"},
+		{Name: "artifacts/workflows/1/logs.txt", Contents: "l"},
+		{Name: "artifacts/workflows/12/d.log", Contents: "d"},
+	})
+	require.NoError(t, err)
+
+	run := func(args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewTreeCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs(append([]string{"workflow", "-H"}, args...))
+		cmd.Execute()
+		return out.String()
+	}
+
+	t.Run("renders the store", func(t *testing.T) {
+		assert.Equal(t, `[     10 B]  .
+├── [      4 B]  a.txt
+├── [      1 B]  logs.txt
+└── [      5 B]  logs/
+    ├── [      2 B]  b.log
+    └── [      3 B]  test/
+        └── [      3 B]  c.log
+
+2 directories, 4 files
+`, run())
+	})
+
+	t.Run("limits the depth", func(t *testing.T) {
+		assert.Equal(t, `[      5 B]  logs
+├── [      2 B]  b.log
+└── [      3 B]  test/
+
+1 directory, 1 file
+`, run("logs", "--depth", "1"))
+	})
+
+	t.Run("renders a file", func(t *testing.T) {
+		assert.Equal(t, "[      4 B]  a.txt\n\n0 directories, 1 file\n", run("a.txt"))
+	})
+}
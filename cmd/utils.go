@@ -1,11 +1,14 @@
 package cmd
 
 import (
-	"context"
-	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
 	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 // getBackend returns the configured storage backend.
@@ -17,25 +20,30 @@ func getBackend() backend.Backend {
 	return b
 }
 
-// getContext returns a context for backend operations.
-// Currently returns a background context, but can be extended
-// to support timeouts and cancellation.
-func getContext() context.Context {
-	return context.Background()
+// defaultLockTimeout is how long a pull waits for other artifact
+// processes writing into the same local destination.
+const defaultLockTimeout = 10 * time.Minute
+
+// getLockTimeout returns the destination lock timeout,
+// configurable with the ARTIFACT_LOCK_TIMEOUT env var (e.g. "30s").
+func getLockTimeout() time.Duration {
+	value := os.Getenv("ARTIFACT_LOCK_TIMEOUT")
+	if value == "" {
+		return defaultLockTimeout
+	}
+
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		log.Warnf("Ignoring invalid ARTIFACT_LOCK_TIMEOUT '%s': %v\n", value, err)
+		return defaultLockTimeout
+	}
+
+	return timeout
 }
 
 // formatBytes converts bytes to human readable format
 func formatBytes(bytes int64) string {
-	const unit = 1024
-	if bytes < unit {
-		return fmt.Sprintf("%d B", bytes)
-	}
-	div, exp := int64(unit), 0
-	for n := bytes / unit; n >= unit; n /= unit {
-		div *= unit
-		exp++
-	}
-	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+	return common.FormatSize(bytes)
 }
 
 // pluralize returns singular or plural form based on count
@@ -45,3 +53,14 @@ func pluralize(count int, singular, plural string) string {
 	}
 	return plural
 }
+
+// userCacheDir returns the directory the CLI caches files in, e.g.
+// ~/.cache/artifact, falling back to the temporary directory.
+func userCacheDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+
+	return filepath.Join(dir, "artifact")
+}
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Outcomes of verifying a single file.
+const (
+	verifyOK         = "ok"
+	verifyMismatch   = "mismatch"   // the local file differs from the stored one
+	verifyMissing    = "missing"    // the file is stored, but not found locally
+	verifyExtra      = "extra"      // the file exists locally, but is not stored
+	verifyUnverified = "unverified" // the storage has no checksum to compare with
+)
+
+// verifyResult is the outcome of verifying one file, with its path
+// relative to the verified directory.
+type verifyResult struct {
+	Path   string
+	Status string
+}
+
+func NewVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify",
+		Short: "Verifies pulled files against the checksums in the storage",
+		Long: `Recomputes the SHA256 checksum of every file pulled from a project,
+workflow or job store and compares it with the checksum stored with the
+file, or with its ETag for files stored without one. Fails if any file
+differs, is missing, was added locally or cannot be verified.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Verifies files pulled from the %s storage.", cobra.ExactArgs(1), addVerifyFlags, runVerifyForCategory)
+	return cmd
+}
+
+func addVerifyFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("destination", "d", "", "local path the files were pulled to; defaults to the name of PATH")
+}
+
+func runVerifyForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	destination, _ := cmd.Flags().GetString("destination")
+	paths := resolver.Pull(args[0], destination)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	results, err := verifyFiles(getContext(), b, lister, paths.Source, paths.Destination)
+	if err != nil {
+		log.Errorf("Error verifying '%s': %v\n", paths.Destination, err)
+		errutil.Exit(1)
+		return
+	}
+
+	failed, err := writeVerifyResults(cmd.OutOrStdout(), results)
+	errutil.Check(err)
+
+	if failed > 0 {
+		log.Errorf("%d of %d %s failed verification.\n", failed, len(results), pluralize(len(results), "file", "files"))
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Verified %d %s in '%s'.\n", len(results), pluralize(len(results), "file", "files"), paths.Destination)
+}
+
+// verifyFiles compares the files stored at remotePath with the local copy
+// at localPath, which is a single file if remotePath is one.
+func verifyFiles(ctx context.Context, b backend.Backend, lister backend.Lister, remotePath, localPath string) ([]verifyResult, error) {
+	reader, _ := b.(backend.ChecksumReader)
+	dir := strings.TrimSuffix(remotePath, "/")
+
+	results := []verifyResult{}
+	seen := map[string]bool{}
+
+	err := walkRemote(ctx, lister, dir, func(obj backend.ObjectInfo) error {
+		name := relativeName(obj.Path, dir)
+		filename := filepath.Join(localPath, filepath.FromSlash(name))
+		if name == "" {
+			name, filename = path.Base(obj.Path), localPath
+		}
+
+		seen[filename] = true
+		status, err := verifyFile(ctx, reader, filename, obj)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, verifyResult{Path: name, Status: status})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(results) == 0 {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	// Local files that were never stored
+	err = filepath.Walk(localPath, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || seen[filename] {
+			return err
+		}
+
+		rel, err := filepath.Rel(localPath, filename)
+		if err != nil {
+			return err
+		}
+
+		results = append(results, verifyResult{Path: filepath.ToSlash(rel), Status: verifyExtra})
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	return results, nil
+}
+
+// verifyFile compares a local file with the stored object obj, using the
+// stored checksum if there is one and the ETag otherwise.
+func verifyFile(ctx context.Context, reader backend.ChecksumReader, filename string, obj backend.ObjectInfo) (string, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return verifyMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	md5sum, sha256sum, err := files.FileDigests(filename)
+	if err != nil {
+		return "", err
+	}
+
+	if reader != nil {
+		storedChecksum, err := remoteChecksum(ctx, reader, obj.Path)
+		if err != nil {
+			return "", err
+		}
+
+		if storedChecksum != "" {
+			if storedChecksum != sha256sum {
+				return verifyMismatch, nil
+			}
+			return verifyOK, nil
+		}
+	}
+
+	if info.Size() != obj.Size {
+		return verifyMismatch, nil
+	}
+
+	// ETags only identify contents if they are one of the digests;
+	// anything else, e.g. the ETag of a multipart upload, proves nothing
+	if obj.ETag == md5sum || obj.ETag == sha256sum {
+		return verifyOK, nil
+	}
+
+	return verifyUnverified, nil
+}
+
+// writeVerifyResults prints the files that failed verification, one per
+// line, and returns how many there were.
+func writeVerifyResults(out io.Writer, results []verifyResult) (int, error) {
+	failed := 0
+	for _, result := range results {
+		if result.Status == verifyOK {
+			log.Debugf("Verified '%s'.\n", result.Path)
+			continue
+		}
+
+		failed++
+		if _, err := fmt.Fprintf(out, "%-10s  %s\n", result.Status, result.Path); err != nil {
+			return failed, err
+		}
+	}
+
+	return failed, nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewVerifyCmd())
+}
@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/signing"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// Outcomes of checking the signature of a file, besides verifyOK.
+const (
+	signatureUnsigned = "unsigned" // no signature is stored for the file
+	signatureInvalid  = "invalid"  // the signature does not match the file
+)
+
+func NewVerifySignatureCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "verify-signature",
+		Short: "Checks the signatures of stored files",
+		Long: `Checks that a stored file, or every file of a stored directory, has a
+signature pushed by 'artifact sign' that matches it. Fails if any file is
+unsigned or its signature is invalid.
+
+The key is a PEM encoded ECDSA or RSA public key, e.g. cosign.pub, given
+with --key, ARTIFACT_SIGNING_PUBLIC_KEY or the signing.publicKey config key.`,
+	}
+
+	addCategoryCmds(cmd, "PATH", "Checks the signatures of %s files.", cobra.ExactArgs(1), addVerifySignatureFlags, runVerifySignatureForCategory)
+	return cmd
+}
+
+func addVerifySignatureFlags(cmd *cobra.Command) {
+	cmd.Flags().String("key", "", "PEM encoded public key (default is ARTIFACT_SIGNING_PUBLIC_KEY or the signing.publicKey config key)")
+}
+
+func runVerifySignatureForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	keyPath, _ := cmd.Flags().GetString("key")
+	key, err := loadVerificationKey(keyPath)
+	errutil.Check(err)
+
+	remotePath := resolver.PrefixedPath(files.ToRelative(args[0]))
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	results, err := verifySignatures(getContext(), b, key, remotePath)
+	if err != nil {
+		log.Errorf("Error checking signatures of '%s': %v\n", remotePath, err)
+		errutil.Exit(1)
+		return
+	}
+
+	failed, err := writeVerifyResults(cmd.OutOrStdout(), results)
+	errutil.Check(err)
+
+	if failed > 0 {
+		log.Errorf("%d of %d %s failed signature verification.\n", failed, len(results), pluralize(len(results), "file", "files"))
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Verified the signatures of %d %s in '%s'.\n", len(results), pluralize(len(results), "file", "files"), remotePath)
+}
+
+// loadVerificationKey loads the public key at keyPath, or the configured one.
+func loadVerificationKey(keyPath string) (crypto.PublicKey, error) {
+	if keyPath == "" {
+		keyPath = signing.PublicKeyPath()
+	}
+
+	if keyPath == "" {
+		return nil, fmt.Errorf("no public key: use --key, ARTIFACT_SIGNING_PUBLIC_KEY or the signing.publicKey config key")
+	}
+
+	return signing.LoadPublicKey(keyPath)
+}
+
+// verifySignatures checks the signature of the stored file at remotePath,
+// or of every file under it.
+func verifySignatures(ctx context.Context, b backend.Backend, key crypto.PublicKey, remotePath string) ([]verifyResult, error) {
+	paths, err := signableFiles(ctx, b, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []verifyResult{}
+	for _, p := range paths {
+		name := relativeName(p, remotePath)
+		if name == "" {
+			name = path.Base(p)
+		}
+
+		status, err := verifyRemoteSignature(ctx, b, key, p, func() (io.ReadCloser, error) {
+			return openRemote(ctx, b, p)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, verifyResult{Path: name, Status: status})
+	}
+
+	return results, nil
+}
+
+// verifyPulledSignatures checks the files pulled from paths.Source into
+// paths.Destination against their stored signatures, and removes the local
+// files that fail, so unverified files are never left behind.
+func verifyPulledSignatures(ctx context.Context, b backend.Backend, key crypto.PublicKey, paths *files.ResolvedPath) error {
+	remotePaths, err := signableFiles(ctx, b, paths.Source)
+	if err != nil {
+		return err
+	}
+
+	return verifyLocalSignatures(ctx, b, key, remotePaths, func(remotePath string) string {
+		return pulledPath(paths, remotePath)
+	})
+}
+
+// verifyLocalSignatures checks the local copies of remotePaths, found with
+// localPathOf, against their stored signatures, and removes those that fail.
+func verifyLocalSignatures(ctx context.Context, b backend.Backend, key crypto.PublicKey, remotePaths []string, localPathOf func(string) string) error {
+	failed := 0
+	for _, p := range remotePaths {
+		localPath := localPathOf(p)
+		status, err := verifyRemoteSignature(ctx, b, key, p, func() (io.ReadCloser, error) {
+			return os.Open(localPath)
+		})
+		if err != nil {
+			return err
+		}
+
+		if status != verifyOK {
+			failed++
+			log.Errorf("Signature of '%s' is %s; removing it.\n", localPath, status)
+			if err := os.Remove(localPath); err != nil {
+				log.Warnf("Failed to remove '%s': %v\n", localPath, err)
+			}
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d %s failed signature verification", failed, len(remotePaths), pluralize(len(remotePaths), "file", "files"))
+	}
+
+	log.Infof("Verified the signatures of %d %s.\n", len(remotePaths), pluralize(len(remotePaths), "file", "files"))
+	return nil
+}
+
+// verifyRemoteSignature checks the contents opened by open against the
+// signature stored for remotePath.
+func verifyRemoteSignature(ctx context.Context, b backend.Backend, key crypto.PublicKey, remotePath string, open func() (io.ReadCloser, error)) (string, error) {
+	sr, err := openRemote(ctx, b, signing.SignaturePath(remotePath))
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return signatureUnsigned, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	signature, err := io.ReadAll(sr)
+	sr.Close()
+	if err != nil {
+		return "", err
+	}
+
+	r, err := open()
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+
+	err = signing.Verify(key, r, signature)
+	if errors.Is(err, signing.ErrInvalidSignature) {
+		return signatureInvalid, nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to check signature of '%s': %v", remotePath, err)
+	}
+
+	return verifyOK, nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewVerifySignatureCmd())
+}
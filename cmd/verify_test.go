@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__VerifyFiles(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/dist/app.zip", []byte("app"))
+	memory.Put("artifacts/jobs/1/dist/bin/app", []byte("binary"))
+	memory.Put("artifacts/jobs/1/dist/missing.txt", []byte("missing"))
+
+	local := filepath.Join(t.TempDir(), "dist")
+	require.NoError(t, os.MkdirAll(filepath.Join(local, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "app.zip"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "bin", "app"), []byte("tampered"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "extra.sh"), []byte("extra"), 0644))
+
+	results, err := verifyFiles(ctx, memory, memory, "artifacts/jobs/1/dist", local)
+	require.NoError(t, err)
+	assert.Equal(t, []verifyResult{
+		{Path: "app.zip", Status: verifyOK},
+		{Path: "bin/app", Status: verifyMismatch},
+		{Path: "extra.sh", Status: verifyExtra},
+		{Path: "missing.txt", Status: verifyMissing},
+	}, results)
+
+	results, err = verifyFiles(ctx, memory, memory, "artifacts/jobs/1/dist/app.zip", filepath.Join(local, "app.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, []verifyResult{{Path: "app.zip", Status: verifyOK}}, results)
+
+	_, err = verifyFiles(ctx, memory, memory, "artifacts/jobs/1/other", local)
+	assert.Error(t, err)
+}
+
+func Test__Verify(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	// Files stored without a checksum are verified with their ETag
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/dist/app.zip", Contents: "app"},
+		{Name: "artifacts/jobs/1/dist/index.html", Contents: "index"},
+	})
+	require.NoError(t, err)
+
+	local := filepath.Join(t.TempDir(), "dist")
+	require.NoError(t, os.MkdirAll(local, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "app.zip"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(local, "index.html"), []byte("tampered"), 0644))
+
+	run := func() string {
+		out := &bytes.Buffer{}
+		cmd := NewVerifyCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"job", "dist", "--destination", local})
+		cmd.Execute()
+		return out.String()
+	}
+
+	assert.Equal(t, "mismatch    index.html\n", run())
+
+	require.NoError(t, os.WriteFile(filepath.Join(local, "index.html"), []byte("index"), 0644))
+	assert.Equal(t, "", run())
+}
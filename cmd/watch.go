@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func NewWatchCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Pushes the files of a local directory whenever they change",
+		Long: `Watches a local directory and pushes files to the storage as they are
+created or updated, e.g. screenshots, logs or checkpoints produced by a
+long-running job. A file is pushed once it has not changed for the
+--debounce period, overwriting the stored one. Files that are pending
+when the command is stopped with Ctrl-C are pushed before it exits.`,
+	}
+
+	addCategoryCmds(cmd, "DIRECTORY", "Pushes the files of a directory to %s storage as they change.", cobra.ExactArgs(1), addWatchFlags, runWatchForCategory)
+	return cmd
+}
+
+func addWatchFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("destination", "d", "", "remote directory to push to (default is the name of DIRECTORY)")
+	cmd.Flags().Duration("debounce", 2*time.Second, "how long a file must stay unchanged before it is pushed")
+	cmd.Flags().Bool("skip-existing", false, "only push files created or updated after the watch started")
+	cmd.Flags().StringSlice("exclude", nil, "skip files matching this glob, relative to DIRECTORY, e.g. '**/*.tmp'")
+	addPushMetadataFlags(cmd)
+}
+
+func runWatchForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) {
+	destinationOverride, _ := cmd.Flags().GetString("destination")
+	debounce, _ := cmd.Flags().GetDuration("debounce")
+	skipExisting, _ := cmd.Flags().GetBool("skip-existing")
+	exclude, _ := cmd.Flags().GetStringSlice("exclude")
+
+	if debounce <= 0 {
+		errutil.Check(fmt.Errorf("invalid --debounce %s: must be positive", debounce))
+		return
+	}
+
+	for _, pattern := range exclude {
+		errutil.Check(files.ValidateGlob(pattern))
+	}
+
+	metadata, err := parsePushMetadata(cmd)
+	errutil.Check(err)
+
+	info, err := os.Stat(args[0])
+	errutil.Check(err)
+	if !info.IsDir() {
+		errutil.Check(fmt.Errorf("'%s' is not a directory", args[0]))
+		return
+	}
+
+	paths := resolver.Push(args[0], destinationOverride)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	w := &dirWatcher{
+		b:        b,
+		resolver: resolver,
+		root:     paths.Source,
+		dest:     paths.Destination,
+		debounce: debounce,
+		exclude:  exclude,
+		opts:     backend.PushOptions{Force: true, Metadata: metadata},
+	}
+
+	ctx, stop := signal.NotifyContext(getContext(), os.Interrupt)
+	defer stop()
+
+	log.Infof("Watching '%s', pushing to '%s' (Ctrl-C to stop)...\n", paths.Source, paths.Destination)
+	if err := w.run(ctx, !skipExisting); err != nil {
+		log.Errorf("Error watching '%s': %v\n", args[0], err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Pushed %d %s. Total of %s\n", w.pushedCount, pluralize(w.pushedCount, "file", "files"), formatBytes(w.pushedSize))
+}
+
+// dirWatcher pushes the files under root to dest as they change.
+type dirWatcher struct {
+	b        backend.Backend
+	resolver *files.PathResolver
+	root     string
+	dest     string
+	debounce time.Duration
+	exclude  []string
+	opts     backend.PushOptions
+
+	watcher *fsnotify.Watcher
+	pending map[string]time.Time     // files to push, by the time they last changed
+	pushed  map[string]watchedFileID // versions of the pushed files
+
+	pushedCount int
+	pushedSize  int64
+}
+
+// watchedFileID tells apart versions of a file, to skip pushing a file
+// again after events that did not change it.
+type watchedFileID struct {
+	size    int64
+	modTime time.Time
+}
+
+// run watches root until ctx is done, and then pushes the pending files.
+// Existing files are pushed first if pushExisting is set.
+func (w *dirWatcher) run(ctx context.Context, pushExisting bool) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	w.watcher = watcher
+	w.pending = map[string]time.Time{}
+	w.pushed = map[string]watchedFileID{}
+
+	// Directories are watched one by one, including the ones created later
+	if err := w.addDir(w.root, pushExisting); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(max(w.debounce/4, 10*time.Millisecond))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// Pending files are pushed without waiting for the debounce period
+			w.flush(context.Background(), time.Time{})
+			return nil
+		case event := <-watcher.Events:
+			w.handle(event)
+		case err := <-watcher.Errors:
+			log.Warnf("Watch error: %v\n", err)
+		case now := <-ticker.C:
+			w.flush(ctx, now)
+		}
+	}
+}
+
+// addDir watches dir and the directories below it, and marks their files
+// as pending if enqueue is set.
+func (w *dirWatcher) addDir(dir string, enqueue bool) error {
+	return filepath.Walk(dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return w.watcher.Add(name)
+		}
+
+		if enqueue {
+			w.enqueue(name, time.Time{})
+		}
+		return nil
+	})
+}
+
+func (w *dirWatcher) handle(event fsnotify.Event) {
+	log.Debugf("Watch event: %s\n", event)
+
+	switch {
+	case event.Has(fsnotify.Create):
+		info, err := os.Stat(event.Name)
+		if err != nil {
+			return
+		}
+
+		if info.IsDir() {
+			// Files may have been created in it before it was watched
+			if err := w.addDir(event.Name, true); err != nil {
+				log.Warnf("Failed to watch '%s': %v\n", event.Name, err)
+			}
+			return
+		}
+
+		w.enqueue(event.Name, time.Now())
+	case event.Has(fsnotify.Write):
+		w.enqueue(event.Name, time.Now())
+	case event.Has(fsnotify.Remove), event.Has(fsnotify.Rename):
+		delete(w.pending, event.Name)
+	}
+}
+
+func (w *dirWatcher) enqueue(name string, changed time.Time) {
+	rel, err := filepath.Rel(w.root, name)
+	if err != nil {
+		return
+	}
+
+	for _, pattern := range w.exclude {
+		if matched, _ := files.MatchGlob(pattern, filepath.ToSlash(rel)); matched {
+			log.Debugf("Skipping excluded '%s'.\n", rel)
+			return
+		}
+	}
+
+	w.pending[name] = changed
+}
+
+// flush pushes the pending files that did not change for the debounce
+// period before now, or all of them if now is zero.
+func (w *dirWatcher) flush(ctx context.Context, now time.Time) {
+	for name, changed := range w.pending {
+		if !now.IsZero() && now.Sub(changed) < w.debounce {
+			continue
+		}
+
+		delete(w.pending, name)
+		if err := w.push(ctx, name); err != nil {
+			log.Errorf("Error pushing '%s': %v\n", name, err)
+		}
+	}
+}
+
+func (w *dirWatcher) push(ctx context.Context, name string) error {
+	info, err := os.Stat(name)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	id := watchedFileID{size: info.Size(), modTime: info.ModTime()}
+	if w.pushed[name] == id {
+		return nil
+	}
+
+	rel, err := filepath.Rel(w.root, name)
+	if err != nil {
+		return err
+	}
+
+	paths := &files.ResolvedPath{Source: name, Destination: path.Join(w.dest, filepath.ToSlash(rel))}
+	if _, err := checkPushPolicy(w.resolver, paths, w.opts.Metadata, nil); err != nil {
+		return err
+	}
+
+	if err := w.b.Push(ctx, paths.Source, paths.Destination, w.opts); err != nil {
+		return err
+	}
+
+	w.pushed[name] = id
+	w.pushedCount++
+	w.pushedSize += info.Size()
+	log.Infof("Pushed '%s' to '%s' (%s).\n", filepath.ToSlash(rel), paths.Destination, formatBytes(info.Size()))
+	return nil
+}
+
+func init() {
+	rootCmd.AddCommand(NewWatchCmd())
+}
@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__DirWatcher(t *testing.T) {
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+	resolver, err := files.NewPathResolver(files.ResourceTypeJob, "")
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "existing.log"), []byte("e"), 0600))
+
+	memory := memorybackend.New()
+	w := &dirWatcher{
+		b:        memory,
+		resolver: resolver,
+		root:     dir,
+		dest:     "artifacts/jobs/1/out",
+		debounce: 50 * time.Millisecond,
+		exclude:  []string{"**/*.tmp"},
+		opts:     backend.PushOptions{Force: true},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- w.run(ctx, true) }()
+
+	stored := func(remotePath string) func() bool {
+		return func() bool {
+			_, ok := memory.Get(remotePath)
+			return ok
+		}
+	}
+
+	assert.Eventually(t, stored("artifacts/jobs/1/out/existing.log"), 2*time.Second, 10*time.Millisecond)
+
+	// New files, files in new directories and updated files are pushed
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.png"), []byte("aa"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "logs", "nested"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs", "nested", "b.log"), []byte("bbb"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs", "c.tmp"), []byte("c"), 0600))
+
+	assert.Eventually(t, stored("artifacts/jobs/1/out/a.png"), 2*time.Second, 10*time.Millisecond)
+	assert.Eventually(t, stored("artifacts/jobs/1/out/logs/nested/b.log"), 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "a.png"), []byte("updated"), 0600))
+	assert.Eventually(t, func() bool {
+		data, _ := memory.Get("artifacts/jobs/1/out/a.png")
+		return string(data) == "updated"
+	}, 2*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logs", "d.log"), []byte("d"), 0600))
+	assert.Eventually(t, stored("artifacts/jobs/1/out/logs/d.log"), 2*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	_, ok := memory.Get("artifacts/jobs/1/out/logs/c.tmp")
+	assert.False(t, ok)
+	assert.Equal(t, 5, w.pushedCount)
+
+	t.Run("pushes pending files when stopped", func(t *testing.T) {
+		w := &dirWatcher{b: memory, resolver: resolver, root: dir, dest: "artifacts/jobs/1/pending", debounce: time.Hour}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error)
+		go func() { done <- w.run(ctx, false) }()
+
+		time.Sleep(100 * time.Millisecond)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "last.log"), []byte("last"), 0600))
+		time.Sleep(100 * time.Millisecond)
+		cancel()
+		require.NoError(t, <-done)
+
+		assert.Equal(t, []string{"artifacts/jobs/1/pending/last.log"}, filterPaths(memory.Paths(), "artifacts/jobs/1/pending/"))
+	})
+}
+
+func filterPaths(paths []string, prefix string) []string {
+	result := []string{}
+	for _, p := range paths {
+		if strings.HasPrefix(p, prefix) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
@@ -1,19 +1,55 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
 	errutil "github.com/semaphoreci/artifact/pkg/errors"
 	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 var yankCmd = &cobra.Command{
-	Use:     "yank",
+	Use:     "yank [@ALIAS]",
 	Aliases: []string{"delete"},
 	Short:   "Deletes a file or directory from the storage you pushed earlier",
 	Long: `You may store files project, workflow or job related files with
 artifact push. With artifact yank you can delete them if you
-don't need them any more.`,
+don't need them any more.
+
+Files matching a glob pattern are deleted at once with a quoted
+pattern, e.g. 'artifact yank job "screenshots/**/*.png"', or --match.
+The matching files are listed first, and deleted once confirmed, or
+right away with --yes.
+
+Artifacts with an alias can be deleted with 'artifact yank @ALIAS'.`,
+	Args: cobra.ExactArgs(1),
+	Run:  runYankForAlias,
+}
+
+func runYankForAlias(cmd *cobra.Command, args []string) {
+	if !isAlias(args[0]) {
+		errutil.Check(fmt.Errorf("unknown category '%s': use job, workflow, project or an @alias", args[0]))
+		return
+	}
+
+	resolver, path, err := resolverForAlias(args[0])
+	if err != nil {
+		errutil.Check(err)
+		return
+	}
+
+	paths, err := runYankForCategory(cmd, []string{path}, resolver)
+	if err != nil {
+		logYankError(err)
+		errutil.Exit(1)
+		return
+	}
+
+	log.Infof("Successfully yanked '%s' (%s).\n", paths.Source, args[0])
 }
 
 func runYankForCategory(cmd *cobra.Command, args []string, resolver *files.PathResolver) (*files.ResolvedPath, error) {
@@ -21,6 +57,10 @@ func runYankForCategory(cmd *cobra.Command, args []string, resolver *files.PathR
 	paths, err := resolver.Resolve(files.OperationYank, args[0], "")
 	errutil.Check(err)
 
+	if err := checkYankPolicy(resolver, paths); err != nil {
+		return nil, err
+	}
+
 	// Get the configured backend
 	b := getBackend()
 	defer func() { _ = b.Close() }()
@@ -30,12 +70,32 @@ func runYankForCategory(cmd *cobra.Command, args []string, resolver *files.PathR
 	return paths, b.Yank(ctx, paths.Source)
 }
 
+// checkYankPolicy checks a yank of paths.Source against the policy.
+func checkYankPolicy(resolver *files.PathResolver, paths *files.ResolvedPath) error {
+	// Yanking a directory yanks every file in it, so rules for any of them apply
+	request := policyRequest(policy.OperationYank, resolver, paths.Source)
+	request.Dir = true
+	return checkPolicy(request)
+}
+
+// logYankError logs why a yank failed. Denied yanks are not
+// about missing artifacts, so they get no hint to check for one.
+func logYankError(err error) {
+	log.Errorf("Error yanking artifact: %v\n", err)
+
+	var violation *policy.ViolationError
+	var readOnly *backend.ErrReadOnly
+	if !errors.As(err, &violation) && !errors.As(err, &readOnly) {
+		log.Error("Please check if the artifact you are trying to yank exists.\n")
+	}
+}
+
 func NewYankJobCmd() *cobra.Command {
 	cmd := &cobra.Command{
 		Use:   "job [PATH]",
 		Short: "Deletes a job file or directory from the storage.",
 		Long:  ``,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			jobId, err := cmd.Flags().GetString("job-id")
@@ -44,10 +104,16 @@ func NewYankJobCmd() *cobra.Command {
 			resolver, err := files.NewPathResolver(files.ResourceTypeJob, jobId)
 			errutil.Check(err)
 
+			pattern, err := yankPattern(cmd, args)
+			errutil.Check(err)
+			if pattern != "" {
+				runYankMatching(cmd, resolver, pattern)
+				return
+			}
+
 			paths, err := runYankForCategory(cmd, args, resolver)
 			if err != nil {
-				log.Errorf("Error yanking artifact: %v\n", err)
-				log.Error("Please check if the artifact you are trying to yank exists.\n")
+				logYankError(err)
 				errutil.Exit(1)
 				return
 			}
@@ -57,6 +123,8 @@ func NewYankJobCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("job-id", "j", "", "set explicit job id")
+	cmd.ValidArgsFunction = completeRemotePath(categoryFor(files.ResourceTypeJob), 1)
+	addYankMatchFlags(cmd)
 	return cmd
 }
 
@@ -65,7 +133,7 @@ func NewYankWorkflowCmd() *cobra.Command {
 		Use:   "workflow [PATH]",
 		Short: "Deletes a workflow file or directory from the storage.",
 		Long:  ``,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			workflowId, err := cmd.Flags().GetString("workflow-id")
@@ -74,10 +142,16 @@ func NewYankWorkflowCmd() *cobra.Command {
 			resolver, err := files.NewPathResolver(files.ResourceTypeWorkflow, workflowId)
 			errutil.Check(err)
 
+			pattern, err := yankPattern(cmd, args)
+			errutil.Check(err)
+			if pattern != "" {
+				runYankMatching(cmd, resolver, pattern)
+				return
+			}
+
 			paths, err := runYankForCategory(cmd, args, resolver)
 			if err != nil {
-				log.Errorf("Error yanking artifact: %v\n", err)
-				log.Error("Please check if the artifact you are trying to yank exists.\n")
+				logYankError(err)
 				errutil.Exit(1)
 				return
 			}
@@ -87,6 +161,8 @@ func NewYankWorkflowCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("workflow-id", "w", "", "set explicit workflow id")
+	cmd.ValidArgsFunction = completeRemotePath(categoryFor(files.ResourceTypeWorkflow), 1)
+	addYankMatchFlags(cmd)
 	return cmd
 }
 
@@ -95,7 +171,7 @@ func NewYankProjectCmd() *cobra.Command {
 		Use:   "project [PATH]",
 		Short: "Deletes a project file or directory from the storage.",
 		Long:  ``,
-		Args:  cobra.ExactArgs(1),
+		Args:  cobra.MaximumNArgs(1),
 
 		Run: func(cmd *cobra.Command, args []string) {
 			projectId, err := cmd.Flags().GetString("project-id")
@@ -104,10 +180,16 @@ func NewYankProjectCmd() *cobra.Command {
 			resolver, err := files.NewPathResolver(files.ResourceTypeProject, projectId)
 			errutil.Check(err)
 
+			pattern, err := yankPattern(cmd, args)
+			errutil.Check(err)
+			if pattern != "" {
+				runYankMatching(cmd, resolver, pattern)
+				return
+			}
+
 			paths, err := runYankForCategory(cmd, args, resolver)
 			if err != nil {
-				log.Errorf("Error yanking artifact: %v\n", err)
-				log.Error("Please check if the artifact you are trying to yank exists.\n")
+				logYankError(err)
 				errutil.Exit(1)
 				return
 			}
@@ -117,6 +199,8 @@ func NewYankProjectCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("project-id", "p", "", "set explicit project id")
+	cmd.ValidArgsFunction = completeRemotePath(categoryFor(files.ResourceTypeProject), 1)
+	addYankMatchFlags(cmd)
 	return cmd
 }
 
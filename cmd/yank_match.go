@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	errutil "github.com/semaphoreci/artifact/pkg/errors"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/policy"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+func addYankMatchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("match", "", "delete the files matching the glob pattern, e.g. 'screenshots/**/*.png'")
+	cmd.Flags().BoolP("yes", "y", false, "delete matching files without asking for confirmation")
+}
+
+// yankPattern returns the glob pattern of the files to yank, given with
+// --match or as a PATH with glob metacharacters, or "" to yank PATH itself.
+func yankPattern(cmd *cobra.Command, args []string) (string, error) {
+	match, _ := cmd.Flags().GetString("match")
+
+	switch {
+	case match != "" && len(args) > 0:
+		return "", fmt.Errorf("use either a PATH or --match")
+	case match == "" && len(args) == 0:
+		return "", fmt.Errorf("yank requires a PATH or --match")
+	case match == "" && files.IsGlob(args[0]):
+		match = args[0]
+	}
+
+	if match == "" {
+		return "", nil
+	}
+
+	match = files.ToRelative(match)
+	if err := files.ValidateGlob(match); err != nil {
+		return "", fmt.Errorf("invalid pattern '%s': %v", match, err)
+	}
+
+	return match, nil
+}
+
+// runYankMatching deletes the files of the category matching pattern,
+// after listing them and asking for confirmation unless --yes is set.
+func runYankMatching(cmd *cobra.Command, resolver *files.PathResolver, pattern string) {
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	p, err := getPolicy()
+	errutil.Check(err)
+
+	b := getBackend()
+	defer func() { _ = b.Close() }()
+
+	lister, err := getLister(b)
+	errutil.Check(err)
+
+	// Only the directory the pattern starts with needs to be listed
+	ctx := getContext()
+	root := resolver.PrefixedPath("")
+	candidates, err := pruneCandidates(ctx, lister, resolver.PrefixedPath(files.GlobBase(pattern)), root, &lsOptions{Match: pattern})
+	if err != nil {
+		log.Errorf("Error listing artifacts: %v\n", err)
+		errutil.Exit(1)
+		return
+	}
+
+	if len(candidates) == 0 {
+		log.Errorf("No files match '%s'.\n", pattern)
+		errutil.Exit(1)
+		return
+	}
+
+	for _, entry := range candidates {
+		_, err := fmt.Fprintln(cmd.OutOrStdout(), entry.Name)
+		errutil.Check(err)
+	}
+
+	log.Infof("%d %s (%s) match '%s'.\n", len(candidates), pluralize(len(candidates), "file", "files"), formatBytes(totalSize(candidates)), pattern)
+	if !yes && !confirmYank(cmd.InOrStdin(), cmd.ErrOrStderr(), len(candidates)) {
+		log.Error("Nothing was deleted; use --yes to delete without confirmation.\n")
+		errutil.Exit(1)
+		return
+	}
+
+	removed, failed := pruneFiles(ctx, b, candidates, func(entry lsEntry) error {
+		return p.Evaluate(policyRequest(policy.OperationYank, resolver, entry.Info.Path))
+	})
+
+	log.Infof("Yanked %d %s (%s).\n", len(removed), pluralize(len(removed), "file", "files"), formatBytes(totalSize(removed)))
+	if failed > 0 {
+		log.Errorf("Failed to delete %d %s.\n", failed, pluralize(failed, "file", "files"))
+		errutil.Exit(1)
+	}
+}
+
+// confirmYank asks whether to delete count files, and returns true if the
+// answer read from in is yes. Without an answer, e.g. in a pipeline with
+// no input, nothing is deleted.
+func confirmYank(in io.Reader, out io.Writer, count int) bool {
+	fmt.Fprintf(out, "Delete %d %s? [y/N] ", count, pluralize(count, "file", "files"))
+
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	default:
+		return false
+	}
+}
@@ -1,14 +1,18 @@
 package cmd
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	testsupport "github.com/semaphoreci/artifact/test/support"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	// Register backends for tests
 	_ "github.com/semaphoreci/artifact/pkg/backend/hubbackend"
@@ -159,6 +163,61 @@ func runYankTestCase(t *testing.T, testCase yankTestCase) {
 		storage.Close()
 	})
 
+	t.Run(testCase.Prefix+" denied by policy", func(t *testing.T) {
+		hub, storage, err := prepareMocks(testCase)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		os.Setenv("SEMAPHORE_ORGANIZATION_URL", hub.URL())
+
+		policyFile = filepath.Join(t.TempDir(), "policy.yml")
+		defer func() { policyFile = "" }()
+
+		err = os.WriteFile(policyFile, []byte("rules:\n  - name: keep-subs\n    operations: [yank]\n    match: \"*/sub/**\"\n    deny: true\n"), 0600)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		subDirName := fmt.Sprintf("artifacts/%s/1/two-levels/sub/", testCase.Prefix)
+
+		// Yanking the parent directory would yank the protected files too
+		for _, path := range []string{"two-levels/sub", "two-levels"} {
+			cmd := testCase.Command()
+			cmd.SetArgs([]string{path})
+			cmd.Execute()
+			assert.True(t, storage.IsFile(fmt.Sprintf("%sfile1.txt", subDirName)))
+		}
+
+		fileName := fmt.Sprintf("artifacts/%s/1/one-level/file1.txt", testCase.Prefix)
+		cmd := testCase.Command()
+		cmd.SetArgs([]string{"one-level/file1.txt"})
+		cmd.Execute()
+		assert.False(t, storage.IsFile(fileName))
+
+		hub.Close()
+		storage.Close()
+	})
+
+	t.Run(testCase.Prefix+" read-only", func(t *testing.T) {
+		hub, storage, err := prepareMocks(testCase)
+		if !assert.Nil(t, err) {
+			return
+		}
+
+		os.Setenv("SEMAPHORE_ORGANIZATION_URL", hub.URL())
+		t.Setenv("ARTIFACT_READONLY", "true")
+
+		fileName := fmt.Sprintf("artifacts/%s/1/file1.txt", testCase.Prefix)
+		cmd := testCase.Command()
+		cmd.SetArgs([]string{"file1.txt"})
+		cmd.Execute()
+
+		assert.True(t, storage.IsFile(fileName))
+		hub.Close()
+		storage.Close()
+	})
+
 	t.Run(testCase.Prefix+" overriding category id", func(t *testing.T) {
 		hub, storage, err := prepareMocks(testCase)
 		if !assert.Nil(t, err) {
@@ -206,3 +265,81 @@ func prepareMocks(testCase yankTestCase) (*testsupport.HubMockServer, *testsuppo
 	hubServer.Init()
 	return hubServer, storageServer, nil
 }
+
+func Test__YankMatching(t *testing.T) {
+	s3Server, err := testsupport.NewS3MockServer()
+	require.NoError(t, err)
+	defer s3Server.Close()
+
+	s3Server.UseAsBackend()
+	t.Setenv("SEMAPHORE_JOB_ID", "1")
+
+	err = s3Server.PutFiles([]testsupport.FileMock{
+		{Name: "artifacts/jobs/1/screenshots/a.png", Contents: "a"},
+		{Name: "artifacts/jobs/1/screenshots/login/b.png", Contents: "b"},
+		{Name: "artifacts/jobs/1/screenshots/login/b.html", Contents: "b"},
+		{Name: "artifacts/jobs/1/app.png", Contents: "app"},
+	})
+	require.NoError(t, err)
+
+	yank := func(input string, args ...string) string {
+		out := &bytes.Buffer{}
+		cmd := NewYankJobCmd()
+		cmd.SetOut(out)
+		cmd.SetErr(&bytes.Buffer{})
+		cmd.SetIn(strings.NewReader(input))
+		cmd.SetArgs(args)
+		cmd.Execute()
+		return out.String()
+	}
+
+	remaining := func() string {
+		out := &bytes.Buffer{}
+		cmd := NewPruneCmd()
+		cmd.SetOut(out)
+		cmd.SetArgs([]string{"job", "--match", "**", "--dry-run"})
+		cmd.Execute()
+		return out.String()
+	}
+
+	all := "app.png\nscreenshots/a.png\nscreenshots/login/b.html\nscreenshots/login/b.png\n"
+
+	// Matching files are listed, but kept unless confirmed
+	assert.Equal(t, "screenshots/a.png\nscreenshots/login/b.png\n", yank("", "screenshots/**/*.png"))
+	assert.Equal(t, all, remaining())
+	yank("n\n", "screenshots/**/*.png")
+	assert.Equal(t, all, remaining())
+
+	yank("y\n", "screenshots/**/*.png")
+	assert.Equal(t, "app.png\nscreenshots/login/b.html\n", remaining())
+
+	yank("", "--match", "*.png", "--yes")
+	assert.Equal(t, "screenshots/login/b.html\n", remaining())
+}
+
+func Test__YankPattern(t *testing.T) {
+	pattern := func(args ...string) (string, error) {
+		cmd := NewYankJobCmd()
+		require.NoError(t, cmd.ParseFlags(args))
+		return yankPattern(cmd, cmd.Flags().Args())
+	}
+
+	p, err := pattern("screenshots/a.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "", p)
+
+	p, err = pattern("./screenshots/**/*.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "screenshots/**/*.png", p)
+
+	p, err = pattern("--match", "*.png")
+	assert.NoError(t, err)
+	assert.Equal(t, "*.png", p)
+
+	_, err = pattern()
+	assert.Error(t, err)
+	_, err = pattern("a.png", "--match", "*.png")
+	assert.Error(t, err)
+	_, err = pattern("reports/[a-")
+	assert.Error(t, err)
+}
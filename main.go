@@ -4,10 +4,24 @@ import (
 	"github.com/semaphoreci/artifact/cmd"
 
 	// Register storage backends
+	_ "github.com/semaphoreci/artifact/pkg/backend/artifactorybackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/cachebackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/execbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/ftpbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/httpbackend"
 	_ "github.com/semaphoreci/artifact/pkg/backend/hubbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/ipfsbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/mirrorbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/pluginbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/rclonebackend"
 	_ "github.com/semaphoreci/artifact/pkg/backend/s3backend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/webhdfsbackend"
 )
 
+// VERSION is set at build time by the release configuration.
+var VERSION = "dev"
+
 func main() {
+	cmd.SetVersion(VERSION)
 	cmd.Execute()
 }
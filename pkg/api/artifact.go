@@ -4,6 +4,10 @@ type Artifact struct {
 	RemotePath string
 	LocalPath  string
 	URLs       []*SignedURL
+
+	// SHA256 is the hex-encoded checksum of the bytes a PUT URL uploaded,
+	// set once the upload succeeds.
+	SHA256 string
 }
 
 func RemotePaths(artifacts []*Artifact) []string {
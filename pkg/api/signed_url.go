@@ -1,16 +1,23 @@
 package api
 
 import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io"
+	"net/http"
 	"net/url"
 	"os"
-	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/progress"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -23,45 +30,63 @@ type SignedURL struct {
 	Method string `json:"method,omitempty"`
 }
 
-func (u *SignedURL) Follow(client *retryablehttp.Client, artifact *Artifact) error {
+// Follow executes the request of the signed URL for the artifact. Requests
+// are cancelled, between and during retries, when ctx is done.
+func (u *SignedURL) Follow(ctx context.Context, client *retryablehttp.Client, artifact *Artifact) error {
 	switch u.Method {
 	case "HEAD":
-		return u.head(client, artifact)
+		return u.head(ctx, client, artifact)
 
 	case "GET":
-		return u.get(client, artifact)
+		return u.get(ctx, client, artifact)
 
 	case "PUT":
-		return u.put(client, artifact)
+		return u.put(ctx, client, artifact)
 
 	case "DELETE":
-		return u.delete(client, artifact)
+		return u.delete(ctx, client, artifact)
 
 	default:
 		return fmt.Errorf("method '%s' not implemented", u.Method)
 	}
 }
 
-func (u *SignedURL) head(client *retryablehttp.Client, artifact *Artifact) error {
+func (u *SignedURL) head(ctx context.Context, client *retryablehttp.Client, artifact *Artifact) error {
+	exists, err := u.Exists(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return fmt.Errorf("'%s' already exists in the remote storage; delete it first, or use --force flag", artifact.RemotePath)
+	}
+
+	return nil
+}
+
+// Exists follows the signed HEAD URL, and reports whether the file it
+// was signed for exists.
+func (u *SignedURL) Exists(ctx context.Context, client *retryablehttp.Client) (bool, error) {
 	log.Debugf("HEAD '%s'...\n", u.URL)
 
-	resp, err := client.Head(u.URL)
+	req, err := retryablehttp.NewRequestWithContext(ctx, "HEAD", u.URL, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to create HEAD request: %v", err)
+	}
+
+	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("error executing HEAD '%s': %v", u, err)
+		return false, fmt.Errorf("error executing HEAD '%s': %v", u, err)
 	}
 
 	// #nosec
 	defer resp.Body.Close()
 
 	log.Debugf("HEAD request got %d response.\n", resp.StatusCode)
-	if common.IsStatusOK(resp.StatusCode) {
-		return fmt.Errorf("'%s' already exists in the remote storage; delete it first, or use --force flag", artifact.RemotePath)
-	}
-
-	return nil
+	return common.IsStatusOK(resp.StatusCode), nil
 }
 
-func (u *SignedURL) put(client *retryablehttp.Client, artifact *Artifact) error {
+func (u *SignedURL) put(ctx context.Context, client *retryablehttp.Client, artifact *Artifact) error {
 	log.Debugf("Opening '%s' for upload...\n", artifact.LocalPath)
 
 	f, err := os.Open(artifact.LocalPath)
@@ -77,17 +102,31 @@ func (u *SignedURL) put(client *retryablehttp.Client, artifact *Artifact) error
 		return fmt.Errorf("failed to stat '%s': %v", artifact.LocalPath, err)
 	}
 
-	var contentBody io.Reader = f
+	tracked := progress.FromContext(ctx).File(artifact.LocalPath, fileInfo.Size())
+	defer tracked.Done()
+
+	// The bytes sent are hashed as they are read, and the file rewound
+	// and hashed again for every retry
+	hash := sha256.New()
+	contentBody := tracked.Reader(f).(io.ReadSeeker)
+	var body interface{} = retryablehttp.ReaderFunc(func() (io.Reader, error) {
+		if _, err := contentBody.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		hash.Reset()
+		return io.TeeReader(contentBody, hash), nil
+	})
 
 	// If the file has no bytes, we need to use http.NoBody
 	// See https://cs.opensource.google/go/go/+/refs/tags/go1.18.2:src/net/http/request.go;l=920
 	if fileInfo.Size() == 0 {
 		log.Debugf("'%s' is empty.\n", artifact.LocalPath)
-		contentBody = nil
+		body = nil
 	}
 
 	log.Debugf("PUT '%s'...\n", u.URL)
-	req, err := retryablehttp.NewRequest("PUT", u.URL, contentBody)
+	req, err := retryablehttp.NewRequestWithContext(ctx, "PUT", u.URL, body)
 	if err != nil {
 		return fmt.Errorf("failed to create new http request: %v", err)
 	}
@@ -111,43 +150,125 @@ func (u *SignedURL) put(client *retryablehttp.Client, artifact *Artifact) error
 		)
 	}
 
+	artifact.SHA256 = hex.EncodeToString(hash.Sum(nil))
 	return nil
 }
 
-func (u *SignedURL) get(client *retryablehttp.Client, artifact *Artifact) error {
-	log.Debugf("GET '%s'...\n", u.URL)
+// PutStream uploads size bytes read from the reader body returns to the
+// signed URL, with the retry policy of client. body is called again for
+// every retry, and returns an error if the stream cannot be read again.
+func (u *SignedURL) PutStream(ctx context.Context, client *retryablehttp.Client, body retryablehttp.ReaderFunc, size int64) error {
+	var reqBody interface{} = body
+	if size == 0 {
+		reqBody = nil
+	}
 
-	parentDir := filepath.Dir(artifact.LocalPath)
+	log.Debugf("PUT '%s' (stream of %d bytes)...\n", u.URL, size)
+	req, err := retryablehttp.NewRequestWithContext(ctx, "PUT", u.URL, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create new http request: %v", err)
+	}
+
+	req.ContentLength = size
+	response, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute http request: %v", err)
+	}
 
 	// #nosec
-	err := os.MkdirAll(parentDir, 0755)
+	defer response.Body.Close()
+
+	log.Debugf("PUT request got %d response.\n", response.StatusCode)
+	if !common.IsStatusOK(response.StatusCode) {
+		return fmt.Errorf(
+			"%s request to %s failed with %d status code",
+			u.Method,
+			u.URL,
+			response.StatusCode,
+		)
+	}
+
+	return nil
+}
+
+// Open starts a GET request for the signed URL and returns the response body,
+// which the caller must close.
+func (u *SignedURL) Open(ctx context.Context, client *retryablehttp.Client) (io.ReadCloser, error) {
+	body, _, err := u.OpenSized(ctx, client)
+	return body, err
+}
+
+// OpenSized is Open, also returning the size of the contents, or -1 if
+// the server did not send it.
+func (u *SignedURL) OpenSized(ctx context.Context, client *retryablehttp.Client) (io.ReadCloser, int64, error) {
+	log.Debugf("GET '%s'...\n", u.URL)
+
+	req, err := retryablehttp.NewRequestWithContext(ctx, "GET", u.URL, nil)
 	if err != nil {
-		return fmt.Errorf("failed to create parent directory '%s': %v", parentDir, err)
+		return nil, 0, fmt.Errorf("failed to create GET request: %v", err)
 	}
 
-	var f *os.File
-	if f, err = os.Create(artifact.LocalPath); err != nil {
-		return fmt.Errorf("failed to create local file '%s': %v", artifact.LocalPath, err)
+	response, err := client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to execute GET request: %v", err)
 	}
 
-	// #nosec
-	defer f.Close()
+	log.Debugf("GET request got %d response.\n", response.StatusCode)
+	if !common.IsStatusOK(response.StatusCode) {
+		_ = response.Body.Close()
+		return nil, 0, fmt.Errorf(
+			"%s request to %s failed with %d status code",
+			u.Method,
+			u.URL,
+			response.StatusCode,
+		)
+	}
+
+	return response.Body, response.ContentLength, nil
+}
+
+// get downloads the signed URL to the artifact's local path, through a
+// partial file moved into place once complete. A partial file left by an
+// interrupted download is resumed with a Range request, which the server
+// answers with the whole file instead if it changed since (If-Range).
+func (u *SignedURL) get(ctx context.Context, client *retryablehttp.Client, artifact *Artifact) error {
+	log.Debugf("GET '%s'...\n", u.URL)
+
+	partial, err := files.OpenPartial(artifact.LocalPath)
+	if err != nil {
+		return err
+	}
 
-	req, err := retryablehttp.NewRequest("GET", u.URL, nil)
+	req, err := retryablehttp.NewRequestWithContext(ctx, "GET", u.URL, nil)
 	if err != nil {
-		u.closeFile(f, true)
+		_ = partial.Close()
 		return fmt.Errorf("failed to create GET request: %v", err)
 	}
 
+	if partial.Offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", partial.Offset))
+		req.Header.Set("If-Range", partial.ETag)
+	}
+
 	response, err := client.Do(req)
 	if err != nil {
-		u.closeFile(f, true)
+		_ = partial.Close()
 		return fmt.Errorf("failed to execute GET request: %v", err)
 	}
 
+	// #nosec
+	defer response.Body.Close()
+
 	log.Debugf("GET request got %d response.\n", response.StatusCode)
+
+	// The partial file is already complete
+	if response.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		_ = partial.Discard()
+		return fmt.Errorf("cannot resume the download of '%s'; pull it again", artifact.LocalPath)
+	}
+
 	if !common.IsStatusOK(response.StatusCode) {
-		u.closeFile(f, true)
+		_ = partial.Close()
 		return fmt.Errorf(
 			"%s request to %s failed with %d status code",
 			u.Method,
@@ -156,34 +277,35 @@ func (u *SignedURL) get(client *retryablehttp.Client, artifact *Artifact) error
 		)
 	}
 
-	// #nosec
-	defer response.Body.Close()
+	if response.StatusCode == http.StatusPartialContent {
+		log.Infof("Resuming download of '%s' from byte %d.\n", artifact.LocalPath, partial.Offset)
+	} else if err := partial.Restart(response.Header.Get("ETag")); err != nil {
+		_ = partial.Close()
+		return err
+	}
 
-	log.Debugf("Writing response to '%s'...\n", artifact.LocalPath)
-	if _, err := io.Copy(f, response.Body); err != nil {
-		return fmt.Errorf("failed to read HTTP response: %v", err)
+	size := int64(-1)
+	if response.ContentLength >= 0 {
+		size = partial.Offset + response.ContentLength
 	}
 
-	u.closeFile(f, false)
-	return nil
-}
+	tracked := progress.FromContext(ctx).File(artifact.LocalPath, size)
+	defer tracked.Done()
+	tracked.Skip(partial.Offset)
 
-func (u *SignedURL) closeFile(f *os.File, remove bool) {
-	if err := f.Close(); err != nil {
-		log.Errorf("Error closing file '%s': %v", f.Name(), err)
+	log.Debugf("Writing response to '%s'...\n", artifact.LocalPath)
+	if _, err := files.Copy(partial, tracked.Reader(response.Body)); err != nil {
+		_ = partial.Close()
+		return fmt.Errorf("failed to read HTTP response, pull again to resume: %v", err)
 	}
 
-	if remove {
-		if err := os.Remove(f.Name()); err != nil {
-			log.Errorf("Error removing file '%s': %v", f.Name(), err)
-		}
-	}
+	return partial.Complete()
 }
 
-func (u *SignedURL) delete(client *retryablehttp.Client, artifact *Artifact) error {
+func (u *SignedURL) delete(ctx context.Context, client *retryablehttp.Client, artifact *Artifact) error {
 	log.Debugf("DELETE '%s'...\n", u.URL)
 
-	req, err := retryablehttp.NewRequest("DELETE", u.URL, nil)
+	req, err := retryablehttp.NewRequestWithContext(ctx, "DELETE", u.URL, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create DELETE request: %v", err)
 	}
@@ -235,6 +357,37 @@ func (u *SignedURL) GetObject() (string, error) {
 	}
 }
 
+// Expires returns when the signed URL stops working, read from its V4
+// signature (X-Amz-/X-Goog-Date and -Expires) or V2 Expires parameter.
+// It returns the zero time if the URL does not tell.
+func (u *SignedURL) Expires() time.Time {
+	URL, err := url.Parse(u.URL)
+	if err != nil {
+		return time.Time{}
+	}
+
+	query := URL.Query()
+	for _, prefix := range []string{"X-Amz-", "X-Goog-"} {
+		signed, err := time.Parse("20060102T150405Z", query.Get(prefix+"Date"))
+		if err != nil {
+			continue
+		}
+
+		seconds, err := strconv.ParseInt(query.Get(prefix+"Expires"), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		return signed.Add(time.Duration(seconds) * time.Second)
+	}
+
+	if seconds, err := strconv.ParseInt(query.Get("Expires"), 10, 64); err == nil {
+		return time.Unix(seconds, 0)
+	}
+
+	return time.Time{}
+}
+
 // GCS URLs follow the format 'https://storage.googleapis.com/<bucket-name>/<path>'
 func parseGoogleStorageURL(URL *url.URL) (string, error) {
 	re := regexp.MustCompile(`https:\/\/storage\.googleapis\.com\/[a-z0-9\-]+\/([^?]+)\?Expires=`)
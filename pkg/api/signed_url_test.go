@@ -1,8 +1,18 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/semaphoreci/artifact/pkg/files"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -83,3 +93,99 @@ func Test__GetObject(t *testing.T) {
 		assert.NotNil(t, err)
 	})
 }
+
+func Test__Expires(t *testing.T) {
+	check := func(rawURL string, expected time.Time) {
+		signedURL := SignedURL{URL: rawURL}
+		assert.True(t, expected.Equal(signedURL.Expires()), rawURL)
+	}
+
+	check("https://storage.googleapis.com/b/artifacts/a.txt?X-Goog-Algorithm=GOOG4-RSA-SHA256&X-Goog-Date=20240102T030405Z&X-Goog-Expires=900", time.Date(2024, 1, 2, 3, 19, 5, 0, time.UTC))
+	check("https://b.s3.amazonaws.com/artifacts/a.txt?X-Amz-Date=20240102T030405Z&X-Amz-Expires=3600", time.Date(2024, 1, 2, 4, 4, 5, 0, time.UTC))
+	check("https://storage.googleapis.com/b/artifacts/a.txt?Expires=1704164645", time.Unix(1704164645, 0))
+	check("https://storage.googleapis.com/b/artifacts/a.txt", time.Time{})
+}
+
+func Test__GetResumesPartialDownload(t *testing.T) {
+	contents := []byte("0123456789")
+	etag := `"v1"`
+	ranges := []string{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etag)
+		http.ServeContent(w, r, "file", time.Time{}, bytes.NewReader(contents))
+	}))
+	defer server.Close()
+
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+
+	t.Run("download is resumed from the partial file", func(t *testing.T) {
+		ranges = []string{}
+		localPath := filepath.Join(t.TempDir(), "file")
+		writePartial(t, localPath, etag, "0123")
+
+		u := SignedURL{URL: server.URL, Method: "GET"}
+		assert.Nil(t, u.Follow(context.Background(), client, &Artifact{LocalPath: localPath}))
+
+		assert.Equal(t, []string{"bytes=4-"}, ranges)
+		data, _ := os.ReadFile(localPath)
+		assert.Equal(t, "0123456789", string(data))
+		assert.NoFileExists(t, localPath+files.PartialSuffix)
+	})
+
+	t.Run("changed file is downloaded again", func(t *testing.T) {
+		ranges = []string{}
+		localPath := filepath.Join(t.TempDir(), "file")
+		writePartial(t, localPath, `"v0"`, "abcd")
+
+		u := SignedURL{URL: server.URL, Method: "GET"}
+		assert.Nil(t, u.Follow(context.Background(), client, &Artifact{LocalPath: localPath}))
+
+		data, _ := os.ReadFile(localPath)
+		assert.Equal(t, "0123456789", string(data))
+	})
+}
+
+func writePartial(t *testing.T, localPath, etag, contents string) {
+	partial, err := files.OpenPartial(localPath)
+	assert.Nil(t, err)
+	assert.Nil(t, partial.Restart(etag))
+	partial.Write([]byte(contents))
+	assert.Nil(t, partial.Close())
+}
+
+func Test__PutHashesSentBytes(t *testing.T) {
+	attempts := 0
+	received := []string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = append(received, string(body))
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+	client.RetryWaitMin, client.RetryWaitMax = time.Millisecond, time.Millisecond
+
+	localPath := filepath.Join(t.TempDir(), "file")
+	assert.Nil(t, os.WriteFile(localPath, []byte("hello"), 0644))
+
+	// A retried upload sends, and hashes, the whole file again
+	artifact := &Artifact{LocalPath: localPath}
+	u := SignedURL{URL: server.URL, Method: "PUT"}
+	assert.Nil(t, u.Follow(context.Background(), client, artifact))
+	assert.Equal(t, []string{"hello", "hello"}, received)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", artifact.SHA256)
+
+	empty := filepath.Join(t.TempDir(), "empty")
+	assert.Nil(t, os.WriteFile(empty, nil, 0644))
+	artifact = &Artifact{LocalPath: empty}
+	assert.Nil(t, u.Follow(context.Background(), client, artifact))
+	assert.Equal(t, "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", artifact.SHA256)
+}
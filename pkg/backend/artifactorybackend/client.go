@@ -0,0 +1,568 @@
+package artifactorybackend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterArtifactoryBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// Item types in AQL results.
+const (
+	itemTypeFile   = "file"
+	itemTypeFolder = "folder"
+)
+
+// ArtifactoryBackend implements the Backend interface on an Artifactory repository.
+type ArtifactoryBackend struct {
+	client *retryablehttp.Client
+	cfg    *Config
+}
+
+// item is a file or folder in AQL results.
+type item struct {
+	Path     string    `json:"path"`
+	Name     string    `json:"name"`
+	Type     string    `json:"type"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	SHA256   string    `json:"sha256"`
+	SHA1     string    `json:"actual_sha1"`
+}
+
+// itemFields are the fields AQL queries include in results.
+var itemFields = []string{"path", "name", "type", "size", "modified", "sha256", "actual_sha1"}
+
+// New creates a new ArtifactoryBackend instance from the environment/config file.
+func New() (*ArtifactoryBackend, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg), nil
+}
+
+// NewWithConfig creates a new ArtifactoryBackend instance for a validated configuration.
+func NewWithConfig(cfg *Config) *ArtifactoryBackend {
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+	retry.Load().Configure(client)
+
+	log.Debug("ArtifactoryBackend: Client initialized\n")
+	log.Debugf("* URL: %s\n", cfg.URL)
+	log.Debugf("* Repository: %s\n", cfg.Repository)
+
+	return &ArtifactoryBackend{client: client, cfg: cfg}
+}
+
+// Push deploys a local file or directory with one PUT request per file,
+// setting the properties of the file.
+func (a *ArtifactoryBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("ArtifactoryBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		return a.pushFile(ctx, localPath, remotePath, opts)
+	}
+
+	return files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return a.pushFile(ctx, filePath, remotePath+"/"+filepath.ToSlash(relPath), opts)
+	})
+}
+
+func (a *ArtifactoryBackend) pushFile(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if err := a.checkNotExists(ctx, remotePath, opts); err != nil {
+		return err
+	}
+
+	checksum, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file '%s': %w", localPath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", localPath, err)
+	}
+
+	// Empty files need a nil body, or they are sent with chunked encoding
+	var body interface{} = file
+	if info.Size() == 0 {
+		body = nil
+	}
+
+	u := a.url(remotePath) + matrixParams(a.cfg.properties(remotePath, opts.Metadata))
+	req, err := a.newRequest(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return err
+	}
+
+	// Artifactory rejects the upload if the content does not match
+	req.Header.Set("X-Checksum-Sha256", checksum)
+	req.ContentLength = info.Size()
+	opts.Headers.WithContentType(localPath).Set(req.Header)
+
+	response, err := a.do(req, "push", remotePath)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	log.Debugf("Deployed: %s -> %s\n", localPath, a.url(remotePath))
+	return nil
+}
+
+// PushStream deploys a stream with a single PUT request. Streams of unknown
+// size are sent with chunked transfer encoding.
+func (a *ArtifactoryBackend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	log.Debug("ArtifactoryBackend: Pushing stream...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Size: %d\n", size)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	if err := a.checkNotExists(ctx, remotePath, opts); err != nil {
+		return err
+	}
+
+	body := r
+	if size == 0 {
+		body = nil
+	}
+
+	// A stream cannot be replayed, so it is sent without retries.
+	u := a.url(remotePath) + matrixParams(a.cfg.properties(remotePath, opts.Metadata))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u, body)
+	if err != nil {
+		return fmt.Errorf("failed to create new http request: %w", err)
+	}
+
+	req.ContentLength = size
+	opts.Headers.Set(req.Header)
+	if err := a.authorize(ctx, req); err != nil {
+		return err
+	}
+
+	response, err := a.client.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute http request: %w", err)
+	}
+	if err := a.check(response, "push", remotePath); err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	return nil
+}
+
+// Pull downloads a file, or every file in a folder.
+func (a *ArtifactoryBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("ArtifactoryBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	it, err := a.find(ctx, remotePath, "pull")
+	if err != nil {
+		return err
+	}
+	if it == nil {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	if it.Type != itemTypeFolder {
+		return a.pullFile(ctx, remotePath, localPath, opts)
+	}
+
+	dir := strings.TrimSuffix(remotePath, "/")
+	return backend.Walk(ctx, a.readDir, dir, nil, func(info backend.ObjectInfo) error {
+		destPath := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(info.Path, dir+"/")))
+		return a.pullFile(ctx, info.Path, destPath, opts)
+	})
+}
+
+func (a *ArtifactoryBackend) pullFile(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	body, err := a.Open(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	if err := files.WriteAtomically(localPath, body); err != nil {
+		return err
+	}
+
+	log.Debugf("Downloaded: %s -> %s\n", a.url(remotePath), localPath)
+	return nil
+}
+
+// Open streams the contents of a file with a GET request.
+func (a *ArtifactoryBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("ArtifactoryBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	req, err := a.newRequest(ctx, http.MethodGet, a.url(remotePath), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := a.do(req, "pull", remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// Yank deletes a file, or a folder recursively.
+// Paths that do not exist are not an error.
+func (a *ArtifactoryBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("ArtifactoryBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	req, err := a.newRequest(ctx, http.MethodDelete, a.url(remotePath), nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := a.do(req, "yank", remotePath)
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	return nil
+}
+
+// Exists checks if a file exists with an AQL query.
+func (a *ArtifactoryBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("ArtifactoryBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	it, err := a.find(ctx, remotePath, "exists")
+	if err != nil {
+		return false, err
+	}
+
+	return it != nil && it.Type == itemTypeFile, nil
+}
+
+// List lists the files under remotePrefix with one AQL query per folder
+// the walk reaches. The ETag of a file is its SHA-1, as in Artifactory's
+// ETag header.
+func (a *ArtifactoryBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("ArtifactoryBackend: Listing...\n")
+	log.Debugf("* Prefix: %s\n", remotePrefix)
+
+	return backend.WalkPrefix(ctx, a.readDir, remotePrefix, fn)
+}
+
+// Checksum returns the SHA-256 checksum Artifactory computed for the file.
+func (a *ArtifactoryBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	it, err := a.find(ctx, remotePath, "pull")
+	if err != nil {
+		return "", err
+	}
+	if it == nil || it.Type != itemTypeFile {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return it.SHA256, nil
+}
+
+// Close releases resources. For Artifactory backend, this is a no-op.
+func (a *ArtifactoryBackend) Close() error {
+	return nil
+}
+
+// Helper functions
+
+// checkNotExists returns ErrAlreadyExists if remotePath exists, unless forced.
+func (a *ArtifactoryBackend) checkNotExists(ctx context.Context, remotePath string, opts backend.PushOptions) error {
+	if opts.Force {
+		return nil
+	}
+
+	exists, err := a.Exists(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	}
+
+	return nil
+}
+
+// find returns the file or folder at remotePath, or nil if there is none.
+func (a *ArtifactoryBackend) find(ctx context.Context, remotePath, operation string) (*item, error) {
+	// Items at the root of the repository have the path "."
+	repoPath := a.cfg.repoPath(remotePath)
+	items, err := a.search(ctx, map[string]string{"path": path.Dir(repoPath), "name": path.Base(repoPath)}, operation, remotePath)
+	if err != nil || len(items) == 0 {
+		return nil, err
+	}
+
+	return &items[0], nil
+}
+
+// readDir returns the files and folders of a folder, for backend.Walk.
+func (a *ArtifactoryBackend) readDir(ctx context.Context, dir string) ([]backend.DirEntry, error) {
+	folder := a.cfg.repoPath(dir)
+	if folder == "" {
+		folder = "."
+	}
+
+	items, err := a.search(ctx, map[string]string{"path": folder}, "pull", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []backend.DirEntry{}
+	for _, it := range items {
+		entries = append(entries, backend.DirEntry{
+			Name:  it.Name,
+			IsDir: it.Type == itemTypeFolder,
+			Info:  backend.ObjectInfo{Size: it.Size, ModTime: it.Modified, ETag: it.SHA1},
+		})
+	}
+
+	return entries, nil
+}
+
+// search runs an AQL query for the files and folders of the repository
+// matching criteria, e.g. items.find({"repo":"generic-local","path":"ci/artifacts"}).
+func (a *ArtifactoryBackend) search(ctx context.Context, criteria map[string]string, operation, remotePath string) ([]item, error) {
+	find := map[string]string{"repo": a.cfg.Repository, "type": "any"}
+	for key, value := range criteria {
+		find[key] = value
+	}
+
+	findJSON, err := json.Marshal(find)
+	if err != nil {
+		return nil, err
+	}
+	fieldsJSON, err := json.Marshal(itemFields)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("items.find(%s).include(%s)", findJSON, strings.Trim(string(fieldsJSON), "[]"))
+	log.Debugf("AQL: %s\n", query)
+
+	u := strings.TrimSuffix(a.cfg.URL, "/") + "/api/search/aql"
+	req, err := a.newRequest(ctx, http.MethodPost, u, []byte(query))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "text/plain")
+
+	response, err := a.do(req, operation, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	result := struct {
+		Results []item `json:"results"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode AQL results for '%s': %w", remotePath, err)
+	}
+
+	// The root folder of the repository is reported as "." in "."
+	items := result.Results[:0]
+	for _, it := range result.Results {
+		if it.Name != "." {
+			items = append(items, it)
+		}
+	}
+
+	return items, nil
+}
+
+// url returns the URL of remotePath in the repository.
+func (a *ArtifactoryBackend) url(remotePath string) string {
+	segments := strings.Split(a.cfg.repoPath(remotePath), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.TrimSuffix(a.cfg.URL, "/") + "/" + url.PathEscape(a.cfg.Repository) + "/" + strings.Join(segments, "/")
+}
+
+func (a *ArtifactoryBackend) newRequest(ctx context.Context, method, u string, body interface{}) (*retryablehttp.Request, error) {
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new http request: %w", err)
+	}
+
+	if err := a.authorize(ctx, req.Request); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// authorize adds the configured credentials to a request. Credentials from
+// the credential helper take precedence over the configured ones.
+func (a *ArtifactoryBackend) authorize(ctx context.Context, req *http.Request) error {
+	token, user, password := a.cfg.Token, a.cfg.User, a.cfg.Password
+	if a.cfg.CredentialHelper != nil {
+		credentials, err := a.cfg.CredentialHelper.Get(ctx)
+		if err != nil {
+			return err
+		}
+		if credentials.Token != "" {
+			token, user, password = credentials.Token, "", ""
+		} else if credentials.Username != "" {
+			token, user, password = "", credentials.Username, credentials.Password
+		}
+	}
+
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	} else if user != "" {
+		req.SetBasicAuth(user, password)
+	}
+
+	return nil
+}
+
+// do executes a request, mapping error responses to backend errors.
+// The caller must close the body of the returned response.
+func (a *ArtifactoryBackend) do(req *retryablehttp.Request, operation, remotePath string) (*http.Response, error) {
+	log.Debugf("%s '%s'...\n", req.Method, req.URL)
+
+	response, err := a.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request to %s failed: %w", req.Method, req.URL, err)
+	}
+
+	if err := a.check(response, operation, remotePath); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// check closes unsuccessful responses and maps them to backend errors,
+// using the message of Artifactory's error response, if any.
+func (a *ArtifactoryBackend) check(response *http.Response, operation, remotePath string) error {
+	log.Debugf("%s request got %d response.\n", response.Request.Method, response.StatusCode)
+	if common.IsStatusOK(response.StatusCode) {
+		return nil
+	}
+
+	// #nosec
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+
+	message := strings.TrimSpace(string(body))
+	apiError := struct {
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}{}
+	if json.Unmarshal(body, &apiError) == nil && len(apiError.Errors) > 0 {
+		message = apiError.Errors[0].Message
+	}
+
+	switch response.StatusCode {
+	case http.StatusNotFound:
+		return &backend.ErrNotFound{Path: remotePath}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		reason := response.Status
+		if message != "" {
+			reason = message
+		}
+		return &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: reason}
+	default:
+		return fmt.Errorf("%s request to %s failed with %d status code: %s",
+			response.Request.Method, response.Request.URL, response.StatusCode, message)
+	}
+}
@@ -0,0 +1,276 @@
+package artifactorybackend
+
+import (
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeArtifactory serves the deploy, download and delete endpoints of
+// a repository, and the AQL queries the backend sends.
+type fakeArtifactory struct {
+	mu         sync.Mutex
+	repository string
+	files      map[string][]byte
+	properties map[string]map[string]string
+}
+
+func (f *fakeArtifactory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		_, _ = w.Write([]byte(`{"errors":[{"status":401,"message":"Bad credentials"}]}`))
+		return
+	}
+
+	if r.URL.Path == "/artifactory/api/search/aql" {
+		f.aql(w, r)
+		return
+	}
+
+	// /artifactory/<repo>/<path>;key=value;...
+	escaped, params, _ := strings.Cut(r.URL.EscapedPath(), ";")
+	p, _ := url.PathUnescape(strings.TrimPrefix(escaped, "/artifactory/"+f.repository+"/"))
+
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		if checksum := r.Header.Get("X-Checksum-Sha256"); checksum != "" && checksum != sha256Hex(data) {
+			w.WriteHeader(http.StatusConflict)
+			return
+		}
+		if strings.Contains(p, "readonly/") {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errors":[{"status":403,"message":"Not enough permissions to deploy"}]}`))
+			return
+		}
+		f.files[p] = data
+		f.properties[p] = map[string]string{}
+		for _, param := range strings.Split(params, ";") {
+			if key, value, ok := strings.Cut(param, "="); ok {
+				key, _ = url.PathUnescape(key)
+				value, _ = url.PathUnescape(value)
+				f.properties[p][key] = value
+			}
+		}
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet:
+		data, ok := f.files[p]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		deleted := false
+		for key := range f.files {
+			if key == p || strings.HasPrefix(key, p+"/") {
+				delete(f.files, key)
+				deleted = true
+			}
+		}
+		if !deleted {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// aql answers items.find queries by path and name, with files and the
+// folders they imply.
+func (f *fakeArtifactory) aql(w http.ResponseWriter, r *http.Request) {
+	query, _ := io.ReadAll(r.Body)
+	criteria := strings.TrimPrefix(strings.SplitN(string(query), ").include(", 2)[0], "items.find(")
+	find := map[string]string{}
+	if err := json.Unmarshal([]byte(criteria), &find); err != nil || find["repo"] != f.repository {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	items := map[string]item{}
+	for key, data := range f.files {
+		// Every parent folder of a file is an item too
+		for p := key; p != "."; p = path.Dir(p) {
+			it := item{Path: path.Dir(p), Name: path.Base(p), Type: itemTypeFolder}
+			if p == key {
+				sha1Sum := sha1.Sum(data)
+				it = item{Path: path.Dir(p), Name: path.Base(p), Type: itemTypeFile, Size: int64(len(data)),
+					Modified: time.Now(), SHA256: sha256Hex(data), SHA1: hex.EncodeToString(sha1Sum[:])}
+			}
+			items[p] = it
+		}
+	}
+
+	results := []item{}
+	for _, it := range items {
+		if it.Path == find["path"] && (find["name"] == "" || it.Name == find["name"]) {
+			results = append(results, it)
+		}
+	}
+
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{"results": results})
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func createTestArtifactoryBackend(t *testing.T) (*ArtifactoryBackend, *fakeArtifactory) {
+	fake := &fakeArtifactory{repository: "generic-local", files: map[string][]byte{}, properties: map[string]map[string]string{}}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg := &Config{
+		URL:        server.URL + "/artifactory",
+		Repository: "generic-local",
+		Prefix:     "ci",
+		Token:      "secret",
+		Properties: map[string]string{"retention": "30d"},
+	}
+	require.NoError(t, cfg.Validate())
+
+	return NewWithConfig(cfg), fake
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	return p
+}
+
+func TestArtifactoryBackend_PushPullYank(t *testing.T) {
+	artifactoryBackend, fake := createTestArtifactoryBackend(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	localFile := writeTestFile(t, dir, "a.txt", "hello")
+	opts := backend.PushOptions{Metadata: map[string]string{"commit": "abc,def"}}
+	require.NoError(t, artifactoryBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", opts))
+	assert.Equal(t, "hello", string(fake.files["ci/artifacts/jobs/1/a.txt"]))
+	assert.Equal(t, map[string]string{
+		"artifact.level": "job",
+		"artifact.id":    "1",
+		"retention":      "30d",
+		"commit":         `abc\,def`,
+	}, fake.properties["ci/artifacts/jobs/1/a.txt"])
+
+	exists, err := artifactoryBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = artifactoryBackend.Exists(ctx, "artifacts/jobs/1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = artifactoryBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	checksum, err := artifactoryBackend.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", checksum)
+
+	require.NoError(t, artifactoryBackend.PushStream(ctx, strings.NewReader("streamed"), -1, "artifacts/jobs/1/reports/s.txt", backend.PushOptions{}))
+	writeTestFile(t, dir, "reports/index.html", "<html>")
+	writeTestFile(t, dir, "reports/css/site.css", "body {}")
+	require.NoError(t, artifactoryBackend.Push(ctx, filepath.Join(dir, "reports"), "artifacts/jobs/1/reports", backend.PushOptions{}))
+
+	pulled := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, artifactoryBackend.Pull(ctx, "artifacts/jobs/1/reports", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(filepath.Join(pulled, "css", "site.css"))
+	require.NoError(t, err)
+	assert.Equal(t, "body {}", string(data))
+	assert.FileExists(t, filepath.Join(pulled, "s.txt"))
+
+	objects := []backend.ObjectInfo{}
+	err = artifactoryBackend.List(ctx, "artifacts/jobs/1/", func(info backend.ObjectInfo) error {
+		objects = append(objects, info)
+		return nil
+	})
+	require.NoError(t, err)
+	paths := []string{}
+	for _, object := range objects {
+		paths = append(paths, object.Path)
+	}
+	assert.Equal(t, []string{
+		"artifacts/jobs/1/a.txt",
+		"artifacts/jobs/1/reports/css/site.css",
+		"artifacts/jobs/1/reports/index.html",
+		"artifacts/jobs/1/reports/s.txt",
+	}, paths)
+	assert.Equal(t, int64(5), objects[0].Size)
+	assert.Equal(t, "aaf4c61ddcc5e8a2dabede0f3b482cd9aea9434d", objects[0].ETag)
+
+	require.NoError(t, artifactoryBackend.Yank(ctx, "artifacts/jobs/1/reports"))
+	require.NoError(t, artifactoryBackend.Yank(ctx, "artifacts/jobs/1/missing"))
+	assert.NotContains(t, fake.files, "ci/artifacts/jobs/1/reports/index.html")
+	assert.Contains(t, fake.files, "ci/artifacts/jobs/1/a.txt")
+
+	err = artifactoryBackend.Pull(ctx, "artifacts/jobs/1/reports", pulled, backend.PullOptions{Force: true})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestArtifactoryBackend_Errors(t *testing.T) {
+	artifactoryBackend, _ := createTestArtifactoryBackend(t)
+	ctx := context.Background()
+	localFile := writeTestFile(t, t.TempDir(), "a.txt", "hello")
+
+	err := artifactoryBackend.Push(ctx, localFile, "readonly/a.txt", backend.PushOptions{Force: true})
+	var denied *backend.ErrPermissionDenied
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "Not enough permissions to deploy", denied.Reason)
+
+	err = artifactoryBackend.Push(ctx, localFile, "a.txt", backend.PushOptions{Lock: &backend.ObjectLock{LegalHold: true}})
+	assert.Equal(t, backend.ErrObjectLockNotSupported, err)
+
+	artifactoryBackend.cfg.Token = ""
+	_, err = artifactoryBackend.Exists(ctx, "a.txt")
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "Bad credentials", denied.Reason)
+}
+
+func TestConfig(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{URL: "https://example.jfrog.io/artifactory"}).Validate())
+	assert.Error(t, (&Config{URL: "example.jfrog.io", Repository: "generic-local"}).Validate())
+	assert.NoError(t, (&Config{URL: "https://example.jfrog.io/artifactory", Repository: "generic-local"}).Validate())
+
+	cfg := &Config{Prefix: "/ci/"}
+	assert.Equal(t, "ci/artifacts/jobs/1/a.txt", cfg.repoPath("artifacts/jobs/1/a.txt"))
+	assert.Equal(t, "artifacts/jobs/1/a.txt", (&Config{}).repoPath("artifacts/jobs/1/a.txt"))
+	assert.Equal(t, map[string]string{"team": "web"}, cfg.properties("builds/a.txt", map[string]string{"team": "web"}))
+	assert.Equal(t, ";a%5C=b=c%5C%3Bd;artifact.id=1", matrixParams(map[string]string{"artifact.id": "1", "a=b": "c;d"}))
+
+	t.Setenv("ARTIFACT_ARTIFACTORY_URL", "https://example.jfrog.io/artifactory")
+	t.Setenv("ARTIFACT_ARTIFACTORY_REPOSITORY", "generic-local")
+	t.Setenv("ARTIFACT_ARTIFACTORY_PROPERTIES", "retention=30d, team=web")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"retention": "30d", "team": "web"}, cfg.Properties)
+
+	_, err = ParseProperties("retention")
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+// Package artifactorybackend implements the Backend interface on a JFrog
+// Artifactory repository through its REST API. Unlike the generic HTTP
+// backend, it sets Artifactory properties on deployed files, so retention
+// jobs can find them with AQL, and it uses the checksums Artifactory
+// computes instead of checksum sidecars.
+package artifactorybackend
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/spf13/viper"
+)
+
+// Properties set from the path layout, e.g. artifacts/jobs/<id>/app.zip
+// gets artifact.level=job and artifact.id=<id>.
+const (
+	PropertyLevel = "artifact.level"
+	PropertyID    = "artifact.id"
+)
+
+// Config holds Artifactory backend configuration.
+type Config struct {
+	// URL is the Artifactory base URL (required),
+	// e.g. https://example.jfrog.io/artifactory
+	URL string
+
+	// Repository is the key of the repository artifacts are deployed to (required),
+	// e.g. generic-local
+	Repository string
+
+	// Prefix is the folder in the repository artifacts are stored under
+	Prefix string
+
+	// Token is an access token, sent as a bearer token
+	Token string
+
+	// User and Password authenticate with basic authentication instead of
+	// a token. The password can also be an API key.
+	User     string
+	Password string
+
+	// Properties are set on every deployed file, along with the push metadata
+	Properties map[string]string
+
+	// CredentialHelper provides the token, or user and password, instead
+	// of the configured ones, if configured
+	CredentialHelper *backend.CredentialHelper
+}
+
+// LoadConfig loads Artifactory configuration from environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_ARTIFACTORY_URL (required)
+//   - ARTIFACT_ARTIFACTORY_REPOSITORY (required)
+//   - ARTIFACT_ARTIFACTORY_PREFIX (optional)
+//   - ARTIFACT_ARTIFACTORY_TOKEN (optional)
+//   - ARTIFACT_ARTIFACTORY_USER, ARTIFACT_ARTIFACTORY_PASSWORD (optional)
+//   - ARTIFACT_ARTIFACTORY_PROPERTIES (optional, e.g. "retention=30d,team=web")
+//   - ARTIFACT_CREDENTIAL_HELPER (optional, see backend.CredentialHelper)
+//
+// Config file keys (under 'artifactory' section):
+//   - url, repository, prefix, token, user, password, properties (a map)
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// Load from environment variables first
+	cfg.URL = os.Getenv("ARTIFACT_ARTIFACTORY_URL")
+	cfg.Repository = os.Getenv("ARTIFACT_ARTIFACTORY_REPOSITORY")
+	cfg.Prefix = os.Getenv("ARTIFACT_ARTIFACTORY_PREFIX")
+	cfg.Token = os.Getenv("ARTIFACT_ARTIFACTORY_TOKEN")
+	cfg.User = os.Getenv("ARTIFACT_ARTIFACTORY_USER")
+	cfg.Password = os.Getenv("ARTIFACT_ARTIFACTORY_PASSWORD")
+
+	properties, err := ParseProperties(os.Getenv("ARTIFACT_ARTIFACTORY_PROPERTIES"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.Properties = properties
+
+	// Fall back to config file for unset values
+	if cfg.URL == "" {
+		cfg.URL = viper.GetString("artifactory.url")
+	}
+	if cfg.Repository == "" {
+		cfg.Repository = viper.GetString("artifactory.repository")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = viper.GetString("artifactory.prefix")
+	}
+	if cfg.Token == "" {
+		cfg.Token = viper.GetString("artifactory.token")
+	}
+	if cfg.User == "" {
+		cfg.User = viper.GetString("artifactory.user")
+	}
+	if cfg.Password == "" {
+		cfg.Password = viper.GetString("artifactory.password")
+	}
+	if len(cfg.Properties) == 0 {
+		cfg.Properties = viper.GetStringMapString("artifactory.properties")
+	}
+
+	cfg.CredentialHelper = backend.NewCredentialHelper(backend.BackendTypeArtifactory)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// ParseProperties parses properties given as comma-separated key=value pairs.
+func ParseProperties(s string) (map[string]string, error) {
+	properties := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(key) == "" {
+			return nil, fmt.Errorf("invalid Artifactory property '%s': use key=value", pair)
+		}
+		properties[strings.TrimSpace(key)] = strings.TrimSpace(value)
+	}
+
+	return properties, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("Artifactory URL is required: set ARTIFACT_ARTIFACTORY_URL environment variable or artifactory.url in config")
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid Artifactory URL '%s': use an http or https URL, e.g. https://example.jfrog.io/artifactory", c.URL)
+	}
+
+	if c.Repository == "" || strings.Contains(c.Repository, "/") {
+		return fmt.Errorf("Artifactory repository key is required: set ARTIFACT_ARTIFACTORY_REPOSITORY environment variable or artifactory.repository in config")
+	}
+
+	if c.User != "" && c.Token != "" {
+		return fmt.Errorf("set either an Artifactory token or a user, not both")
+	}
+
+	return nil
+}
+
+// repoPath returns the path of remotePath in the repository, without the repository key.
+func (c *Config) repoPath(remotePath string) string {
+	return strings.TrimPrefix(path.Join("/", c.Prefix, remotePath), "/")
+}
+
+// properties returns the properties of a file deployed to remotePath:
+// the artifact level and ID from the path layout, the configured
+// properties, and the push metadata, each overriding the previous ones.
+func (c *Config) properties(remotePath string, metadata map[string]string) map[string]string {
+	properties := map[string]string{}
+
+	// artifacts/<projects|workflows|jobs>/<id>/...
+	parts := strings.SplitN(remotePath, "/", 4)
+	if len(parts) == 4 && parts[0] == "artifacts" {
+		properties[PropertyLevel] = strings.TrimSuffix(parts[1], "s")
+		properties[PropertyID] = parts[2]
+	}
+
+	for key, value := range c.Properties {
+		properties[key] = value
+	}
+	for key, value := range metadata {
+		properties[key] = value
+	}
+
+	return properties
+}
+
+// matrixParams encodes properties as the matrix parameters of a deploy
+// request, e.g. ";artifact.level=job;team=web", sorted by key.
+// Separators in keys and values are escaped with a backslash.
+func matrixParams(properties map[string]string) string {
+	escape := func(s string) string {
+		for _, special := range []string{`\`, ",", "|", "=", ";"} {
+			s = strings.ReplaceAll(s, special, `\`+special)
+		}
+		return url.PathEscape(s)
+	}
+
+	keys := make([]string, 0, len(properties))
+	for key := range properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var params strings.Builder
+	for _, key := range keys {
+		params.WriteString(";" + escape(key) + "=" + escape(properties[key]))
+	}
+
+	return params.String()
+}
@@ -0,0 +1,59 @@
+package backend
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// The S3 backend records the permissions and modification time of pushed
+// files in the ModeMetadataKey and MTimeMetadataKey metadata entries, and
+// restores them on pull, so pulled binaries stay executable and build
+// tools comparing modification times do not see every pulled file as
+// changed. Files pushed without them are pulled as before.
+const (
+	ModeMetadataKey  = "mode"  // octal permission bits, e.g. "0755"
+	MTimeMetadataKey = "mtime" // RFC 3339 time, with nanoseconds
+)
+
+// WithFileAttributes returns a copy of metadata recording the permissions
+// and modification time of the local file described by info.
+func WithFileAttributes(metadata map[string]string, info os.FileInfo) map[string]string {
+	withAttributes := make(map[string]string, len(metadata)+2)
+	for key, value := range metadata {
+		withAttributes[key] = value
+	}
+
+	withAttributes[ModeMetadataKey] = fmt.Sprintf("%04o", info.Mode().Perm())
+	withAttributes[MTimeMetadataKey] = info.ModTime().UTC().Format(time.RFC3339Nano)
+	return withAttributes
+}
+
+// RestoreFileAttributes gives the local file at localPath the permissions
+// and modification time recorded in metadata, if any.
+func RestoreFileAttributes(localPath string, metadata map[string]string) error {
+	if value, ok := metadata[ModeMetadataKey]; ok {
+		mode, err := strconv.ParseUint(value, 8, 32)
+		if err != nil {
+			return fmt.Errorf("invalid mode '%s' stored for '%s'", value, localPath)
+		}
+
+		if err := os.Chmod(localPath, os.FileMode(mode).Perm()); err != nil {
+			return fmt.Errorf("failed to restore the mode of '%s': %w", localPath, err)
+		}
+	}
+
+	if value, ok := metadata[MTimeMetadataKey]; ok {
+		mtime, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("invalid modification time '%s' stored for '%s'", value, localPath)
+		}
+
+		if err := os.Chtimes(localPath, time.Time{}, mtime); err != nil {
+			return fmt.Errorf("failed to restore the modification time of '%s': %w", localPath, err)
+		}
+	}
+
+	return nil
+}
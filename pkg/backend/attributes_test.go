@@ -0,0 +1,41 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileAttributes(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	require.NoError(t, os.WriteFile(src, []byte("a"), 0644))
+	require.NoError(t, os.Chmod(src, 0750))
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, os.Chtimes(src, mtime, mtime))
+
+	info, err := os.Stat(src)
+	require.NoError(t, err)
+
+	pushed := map[string]string{"branch": "main"}
+	metadata := WithFileAttributes(pushed, info)
+	assert.Equal(t, map[string]string{"branch": "main", ModeMetadataKey: "0750", MTimeMetadataKey: "2024-01-02T03:04:05Z"}, metadata)
+	assert.Len(t, pushed, 1)
+
+	dst := filepath.Join(dir, "dst")
+	require.NoError(t, os.WriteFile(dst, []byte("a"), 0644))
+	require.NoError(t, RestoreFileAttributes(dst, metadata))
+
+	restored, err := os.Stat(dst)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0750), restored.Mode().Perm())
+	assert.True(t, mtime.Equal(restored.ModTime()))
+
+	// Files pushed without attributes are left as they are
+	assert.NoError(t, RestoreFileAttributes(dst, map[string]string{}))
+	assert.ErrorContains(t, RestoreFileAttributes(dst, map[string]string{ModeMetadataKey: "rwx"}), "invalid mode 'rwx'")
+}
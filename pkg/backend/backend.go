@@ -5,20 +5,108 @@ package backend
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"strings"
+	"time"
 
+	"filippo.io/age"
+	"github.com/semaphoreci/artifact/pkg/resume"
 	"github.com/spf13/viper"
 )
 
 // PushOptions contains options for push operations.
 type PushOptions struct {
-	Force bool // Overwrite existing files
+	Force    bool              // Overwrite existing files
+	Lock     *ObjectLock       // Write-once protection for pushed files, nil for the backend default
+	Metadata map[string]string // User metadata stored with every pushed file
+	ExpireIn time.Duration     // How long pushed files are kept, zero for the backend default
+
+	// Concurrency is how many files of a directory are uploaded at once,
+	// zero for DefaultConcurrency. Backends uploading one file at a time ignore it.
+	Concurrency int
+
+	// Resume records the progress of the push, and skips what an earlier,
+	// interrupted run of it already uploaded. Nil to push everything.
+	// Backends that cannot resume ignore it.
+	Resume *resume.State
+
+	// Compress is the compression files are stored with, see pkg/compress,
+	// empty to store them as they are. They are decompressed on pull.
+	Compress string
+
+	// Encrypt are the recipients files are encrypted to before they are
+	// uploaded, see pkg/encrypt, nil to store them in the clear. They are
+	// decrypted on pull with the identities of the context.
+	Encrypt []age.Recipient
+
+	// Headers are served with the pushed files. Backends that cannot
+	// store headers ignore them.
+	Headers Headers
+}
+
+// ExpireAtMetadataKey is the metadata entry recording when a file pushed
+// with PushOptions.ExpireIn expires, as an RFC 3339 time, for backends
+// that cannot expire files themselves.
+const ExpireAtMetadataKey = "expire-at"
+
+// EncodingMetadataKey and EncryptionMetadataKey record the compression a
+// file pushed with PushOptions.Compress is stored with, and the encryption
+// of one pushed with PushOptions.Encrypt. SizeMetadataKey records its size
+// before either.
+const (
+	EncodingMetadataKey   = "encoding"
+	EncryptionMetadataKey = "encryption"
+	SizeMetadataKey       = "original-size"
+)
+
+// Object Lock retention modes.
+const (
+	ObjectLockGovernance = "GOVERNANCE" // users with special permissions can still delete
+	ObjectLockCompliance = "COMPLIANCE" // nobody can delete until retention ends
+)
+
+// ObjectLock requests write-once-read-many protection for pushed files.
+type ObjectLock struct {
+	Mode        string    // ObjectLockGovernance or ObjectLockCompliance, empty for no retention
+	RetainUntil time.Time // When retention ends, required with Mode
+	LegalHold   bool      // Protect files until the hold is removed, regardless of retention
+}
+
+// Validate checks that the lock describes retention, a legal hold, or both.
+func (l *ObjectLock) Validate() error {
+	switch l.Mode {
+	case "":
+		if !l.RetainUntil.IsZero() {
+			return fmt.Errorf("object lock retention needs a mode: %s or %s", ObjectLockGovernance, ObjectLockCompliance)
+		}
+	case ObjectLockGovernance, ObjectLockCompliance:
+		if l.RetainUntil.IsZero() {
+			return fmt.Errorf("object lock mode %s needs a retain-until date", l.Mode)
+		}
+		if !l.RetainUntil.After(time.Now()) {
+			return fmt.Errorf("object lock retain-until date %s is in the past", l.RetainUntil.Format(time.RFC3339))
+		}
+	default:
+		return fmt.Errorf("invalid object lock mode '%s': use %s or %s", l.Mode, ObjectLockGovernance, ObjectLockCompliance)
+	}
+
+	if l.Mode == "" && !l.LegalHold {
+		return fmt.Errorf("object lock needs a mode, a legal hold, or both")
+	}
+
+	return nil
 }
 
 // PullOptions contains options for pull operations.
 type PullOptions struct {
 	Force bool // Overwrite existing local files
+
+	// Concurrency is how many files of a directory are downloaded at once,
+	// zero for DefaultConcurrency. Backends downloading one file at a time ignore it.
+	Concurrency int
 }
 
 // Backend defines the interface for artifact storage operations.
@@ -51,6 +139,158 @@ type Backend interface {
 	Close() error
 }
 
+// ObjectInfo describes a single object stored in remote storage.
+type ObjectInfo struct {
+	Path        string    // Full remote path (artifacts/projects|workflows|jobs/ID/...)
+	Size        int64     // Size in bytes
+	ModTime     time.Time // Last modification time
+	ETag        string    // Backend-specific entity tag, if available
+	ContentType string    // MIME type, if stored; only set by Stat
+}
+
+// Lister is implemented by backends that can enumerate remote objects.
+// It is kept separate from Backend because not every storage provider
+// offers a listing API.
+type Lister interface {
+	// List calls fn for every object stored under remotePrefix, in key order.
+	// Objects are passed to fn as listing pages arrive, so implementations
+	// must not buffer the full listing in memory.
+	// If fn returns StopListing, no further pages are requested and List returns nil.
+	List(ctx context.Context, remotePrefix string, fn func(ObjectInfo) error) error
+}
+
+// StopListing is returned by a List callback to end the listing early,
+// e.g. once a --limit has been reached. It is never returned by List itself.
+var StopListing = errors.New("stop listing")
+
+// ErrListingNotSupported is returned when listing is requested from a backend
+// that does not implement Lister.
+var ErrListingNotSupported = errors.New("the configured backend does not support listing artifacts")
+
+// StreamPusher is implemented by backends that can upload data straight from
+// a stream, without staging it in a local file first.
+type StreamPusher interface {
+	// PushStream uploads everything read from r to remotePath.
+	// size is the number of bytes r will produce, or -1 if unknown.
+	// Backends that need the size upfront return ErrStreamingNotSupported
+	// for unknown sizes before reading anything from r.
+	PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts PushOptions) error
+}
+
+// Opener is implemented by backends that can read a stored file as a stream,
+// without downloading it to the local disk first.
+type Opener interface {
+	// Open returns the contents of the file at remotePath.
+	// It returns ErrNotFound if the file does not exist.
+	Open(ctx context.Context, remotePath string) (io.ReadCloser, error)
+}
+
+// SizedReader is implemented by the readers Opener.Open returns for files
+// whose contents differ in size from what is stored, e.g. files stored
+// compressed. Size is the number of bytes read, or negative if unknown.
+type SizedReader interface {
+	Size() int64
+}
+
+// TreeOpener is implemented by backends that cannot list stored files,
+// but can stream every file of a remote directory, e.g. Hub, whose signed
+// URLs for a directory cover its files. Lister and Opener together do the
+// same for the backends that can list.
+type TreeOpener interface {
+	// OpenTree calls fn with the contents of every file under remotePath,
+	// or of the file at remotePath, in path order, one at a time. The
+	// ObjectInfo passed to fn has the path and size of the file. It
+	// returns ErrNotFound if there is no file.
+	OpenTree(ctx context.Context, remotePath string, fn func(ObjectInfo, io.Reader) error) error
+}
+
+// RangeOpener is implemented by backends that can read a stored file from
+// an offset, e.g. with HTTP Range requests, to follow a file as it grows.
+// See OpenRange for backends that cannot.
+type RangeOpener interface {
+	// OpenRange returns the contents of the file at remotePath from offset on,
+	// which are empty if offset is at or past its end. It returns ErrNotFound
+	// if the file does not exist.
+	OpenRange(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error)
+}
+
+// Stater is implemented by backends that can describe a single file
+// without listing its directory. See Stat for backends that cannot.
+type Stater interface {
+	// Stat returns the size, modification time, ETag and content type of
+	// the file at remotePath. It returns ErrNotFound if the file does not exist.
+	Stat(ctx context.Context, remotePath string) (*ObjectInfo, error)
+}
+
+// Copier is implemented by backends that can copy stored files without
+// downloading them, e.g. to promote a job artifact to the project store.
+// See Copy for backends that cannot.
+type Copier interface {
+	// Copy copies the file or directory at srcPath to dstPath, along with
+	// the checksums and metadata stored for it; opts.Metadata is added to
+	// the metadata of the copies. It returns ErrNotFound if srcPath does
+	// not exist, and ErrAlreadyExists if a destination file exists and
+	// opts.Force is not set.
+	Copy(ctx context.Context, srcPath, dstPath string, opts PushOptions) error
+}
+
+// ErrCopyNotSupported is returned by wrapping backends when a copy is
+// requested from a backend that does not implement Copier.
+var ErrCopyNotSupported = errors.New("the configured backend cannot copy files in place")
+
+// ErrOpenNotSupported is returned by wrapping backends when streaming
+// a file is requested from a backend that does not implement Opener.
+var ErrOpenNotSupported = errors.New("the configured backend cannot stream files")
+
+// Checker is implemented by backends that can verify their configuration and
+// credentials with a single request, e.g. for artifact doctor. See Check for
+// backends that cannot.
+type Checker interface {
+	// Check returns an error if the storage cannot be reached or rejects the
+	// credentials for remotePath, a file in a store that need not exist. It
+	// also returns the time reported by the server, zero if it is unknown.
+	Check(ctx context.Context, remotePath string) (time.Time, error)
+}
+
+// ChecksumReader is implemented by backends that store a checksum
+// for every pushed file, following the convention in checksum.go.
+type ChecksumReader interface {
+	// Checksum returns the hex-encoded SHA256 checksum stored for the file
+	// at remotePath, or "" if none is stored. It returns ErrNotFound if
+	// the file does not exist.
+	Checksum(ctx context.Context, remotePath string) (string, error)
+}
+
+// Sharer is implemented by backends that can hand out time-limited links
+// to stored files, e.g. S3 presigned URLs, for artifact share.
+type Sharer interface {
+	// Share returns a URL to download the file at remotePath without
+	// credentials, valid for about expiresIn, and the time it expires at,
+	// zero if it is unknown. Backends whose links have a fixed lifetime may
+	// ignore expiresIn. It returns ErrNotFound if the file does not exist.
+	Share(ctx context.Context, remotePath string, expiresIn time.Duration) (string, time.Time, error)
+}
+
+// ErrSharingNotSupported is returned when a link is requested from a
+// backend that does not implement Sharer.
+var ErrSharingNotSupported = errors.New("the configured backend cannot create links to files")
+
+// ErrObjectLockNotSupported is returned when pushing with an object lock
+// to a backend that cannot protect files against deletion.
+var ErrObjectLockNotSupported = errors.New("object lock is only supported by the S3 backend")
+
+// ErrCompressionNotSupported is returned when pushing compressed files to
+// a backend that cannot record how to decompress them on pull.
+var ErrCompressionNotSupported = errors.New("compression is only supported by the S3 backend")
+
+// ErrEncryptionNotSupported is returned when pushing encrypted files to a
+// backend that cannot record that they need decrypting on pull.
+var ErrEncryptionNotSupported = errors.New("encryption is only supported by the S3 backend")
+
+// ErrStreamingNotSupported is returned when a stream cannot be uploaded directly
+// and needs to be staged in a local file instead.
+var ErrStreamingNotSupported = errors.New("the configured backend cannot upload this stream directly")
+
 // BackendType represents the type of storage backend.
 type BackendType string
 
@@ -60,6 +300,45 @@ const (
 
 	// BackendTypeS3 uses direct S3 API calls.
 	BackendTypeS3 BackendType = "s3"
+
+	// BackendTypeHTTP uses plain HTTP requests against a base URL.
+	BackendTypeHTTP BackendType = "http"
+
+	// BackendTypeWebHDFS stores artifacts in HDFS through the WebHDFS REST API.
+	BackendTypeWebHDFS BackendType = "webhdfs"
+
+	// BackendTypeFTP stores artifacts on an FTP server, optionally over TLS.
+	BackendTypeFTP BackendType = "ftp"
+
+	// BackendTypeRclone runs an installed rclone binary against a configured remote.
+	BackendTypeRclone BackendType = "rclone"
+
+	// BackendTypeIPFS stores artifacts in the MFS of an IPFS node, addressed by content.
+	BackendTypeIPFS BackendType = "ipfs"
+
+	// BackendTypeArtifactory deploys artifacts to an Artifactory repository with properties.
+	BackendTypeArtifactory BackendType = "artifactory"
+
+	// BackendTypeExec delegates operations to an external plugin executable,
+	// selected with ARTIFACT_BACKEND=exec:/path/to/plugin.
+	BackendTypeExec BackendType = "exec"
+
+	// BackendTypePlugin loads a go-plugin backend from the plugin directory,
+	// selected with ARTIFACT_BACKEND=plugin:<name>.
+	BackendTypePlugin BackendType = "plugin"
+
+	// BackendTypeMirror writes to several backends and reads from the first available.
+	BackendTypeMirror BackendType = "mirror"
+
+	// BackendTypeFallback reads from the first of several backends that has a file,
+	// and writes to the last one.
+	BackendTypeFallback BackendType = "fallback"
+)
+
+// Prefixes of backend settings that name an external backend.
+const (
+	ExecBackendPrefix   = "exec:"   // followed by the plugin executable
+	PluginBackendPrefix = "plugin:" // followed by the plugin name
 )
 
 // Config holds common configuration for backends.
@@ -68,33 +347,44 @@ type Config struct {
 	Verbose bool
 }
 
-// GetBackendType determines which backend to use based on environment and config.
+// GetBackendSetting returns the backend setting in use, e.g. "s3" or "exec:/path/to/plugin".
 // Priority: ARTIFACT_BACKEND env var > config file > default (hub)
-func GetBackendType() BackendType {
-	// Check environment variable first
-	if envBackend := os.Getenv("ARTIFACT_BACKEND"); envBackend != "" {
-		switch envBackend {
-		case "s3":
-			return BackendTypeS3
-		case "hub":
-			return BackendTypeHub
-		default:
-			// Unknown backend type, fall through to config/default
+func GetBackendSetting() string {
+	for _, setting := range []string{os.Getenv("ARTIFACT_BACKEND"), viper.GetString("backend")} {
+		// Unknown backend types fall through to config/default
+		if _, _, ok := ParseBackendSetting(setting); ok {
+			return setting
 		}
 	}
 
-	// Check config file
-	if configBackend := viper.GetString("backend"); configBackend != "" {
-		switch configBackend {
-		case "s3":
-			return BackendTypeS3
-		case "hub":
-			return BackendTypeHub
-		}
+	// Default to hub for backwards compatibility
+	return string(BackendTypeHub)
+}
+
+// GetBackendType determines which backend to use based on environment and config.
+func GetBackendType() BackendType {
+	backendType, _, _ := ParseBackendSetting(GetBackendSetting())
+	return backendType
+}
+
+// ParseBackendSetting splits a backend setting into its type and argument:
+// the plugin executable for exec backends, the plugin name for plugin backends,
+// and "" otherwise. ok is false for unknown backend types.
+func ParseBackendSetting(setting string) (backendType BackendType, arg string, ok bool) {
+	switch BackendType(setting) {
+	case BackendTypeHub, BackendTypeS3, BackendTypeHTTP, BackendTypeWebHDFS, BackendTypeFTP, BackendTypeRclone, BackendTypeIPFS, BackendTypeArtifactory, BackendTypeMirror, BackendTypeFallback:
+		return BackendType(setting), "", true
 	}
 
-	// Default to hub for backwards compatibility
-	return BackendTypeHub
+	if strings.HasPrefix(setting, ExecBackendPrefix) {
+		return BackendTypeExec, strings.TrimPrefix(setting, ExecBackendPrefix), true
+	}
+
+	if strings.HasPrefix(setting, PluginBackendPrefix) {
+		return BackendTypePlugin, strings.TrimPrefix(setting, PluginBackendPrefix), true
+	}
+
+	return "", "", false
 }
 
 // ErrNotFound is returned when a requested artifact does not exist.
@@ -125,3 +415,25 @@ type ErrPermissionDenied struct {
 func (e *ErrPermissionDenied) Error() string {
 	return fmt.Sprintf("permission denied for %s on %s: %s", e.Operation, e.Path, e.Reason)
 }
+
+// ErrReadOnly is returned when pushing, copying or yanking through a read-only backend.
+type ErrReadOnly struct {
+	Operation string
+	Path      string
+}
+
+func (e *ErrReadOnly) Error() string {
+	return fmt.Sprintf("%s of %s rejected: the backend is read-only", e.Operation, e.Path)
+}
+
+// ErrChecksumMismatch is returned when a pulled file does not match the
+// checksum it was pushed with, e.g. because a proxy corrupted it.
+type ErrChecksumMismatch struct {
+	Path     string
+	Expected string
+	Actual   string
+}
+
+func (e *ErrChecksumMismatch) Error() string {
+	return fmt.Sprintf("%s does not match its checksum (expected sha256 %s, got %s); pull it again", e.Path, e.Expected, e.Actual)
+}
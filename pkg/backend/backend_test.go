@@ -0,0 +1,51 @@
+package backend
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestObjectLock_Validate(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+
+	assert.NoError(t, (&ObjectLock{Mode: ObjectLockGovernance, RetainUntil: future}).Validate())
+	assert.NoError(t, (&ObjectLock{Mode: ObjectLockCompliance, RetainUntil: future, LegalHold: true}).Validate())
+	assert.NoError(t, (&ObjectLock{LegalHold: true}).Validate())
+
+	assert.Error(t, (&ObjectLock{}).Validate())
+	assert.Error(t, (&ObjectLock{Mode: "FOREVER", RetainUntil: future}).Validate())
+	assert.Error(t, (&ObjectLock{Mode: ObjectLockGovernance}).Validate())
+	assert.Error(t, (&ObjectLock{Mode: ObjectLockGovernance, RetainUntil: time.Now().Add(-time.Hour)}).Validate())
+	assert.Error(t, (&ObjectLock{RetainUntil: future}).Validate())
+}
+
+func TestParseBackendSetting(t *testing.T) {
+	backendType, arg, ok := ParseBackendSetting("exec:/usr/local/bin/artifact-backend-foo")
+	assert.True(t, ok)
+	assert.Equal(t, BackendTypeExec, backendType)
+	assert.Equal(t, "/usr/local/bin/artifact-backend-foo", arg)
+
+	backendType, arg, ok = ParseBackendSetting("plugin:ceph")
+	assert.True(t, ok)
+	assert.Equal(t, BackendTypePlugin, backendType)
+	assert.Equal(t, "ceph", arg)
+
+	backendType, arg, ok = ParseBackendSetting("mirror")
+	assert.True(t, ok)
+	assert.Equal(t, BackendTypeMirror, backendType)
+	assert.Empty(t, arg)
+
+	_, _, ok = ParseBackendSetting("gopher")
+	assert.False(t, ok)
+}
+
+func TestGetBackendSetting(t *testing.T) {
+	t.Setenv("ARTIFACT_BACKEND", "exec:/usr/local/bin/artifact-backend-foo")
+	assert.Equal(t, "exec:/usr/local/bin/artifact-backend-foo", GetBackendSetting())
+	assert.Equal(t, BackendTypeExec, GetBackendType())
+
+	t.Setenv("ARTIFACT_BACKEND", "gopher")
+	assert.Equal(t, "hub", GetBackendSetting())
+}
@@ -0,0 +1,286 @@
+// Package cachebackend keeps pulled files in a local directory, in front of
+// any backend. Persistent runners pulling the same heavy dependencies in
+// every job then only download them once.
+//
+// Cached files are keyed by their remote path and version: the checksum
+// stored with the file or, without one, the ETag from a listing. A file
+// pushed again gets a new version, so stale copies are never served.
+// Files whose version cannot be determined, and directories, are not cached.
+package cachebackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func init() {
+	backend.RegisterCache(func(b backend.Backend) (backend.Backend, error) {
+		dir := Dir()
+		if dir == "" {
+			return b, nil
+		}
+
+		return New(b, dir)
+	})
+}
+
+// Dir returns the cache directory: ARTIFACT_CACHE_DIR or the cacheDir
+// config key. Caching is disabled if neither is set.
+func Dir() string {
+	if dir := os.Getenv("ARTIFACT_CACHE_DIR"); dir != "" {
+		return dir
+	}
+
+	return viper.GetString("cacheDir")
+}
+
+// CacheBackend wraps a backend, serving pulls from the cache directory when possible.
+type CacheBackend struct {
+	backend.Backend
+	dir string
+}
+
+// New wraps b with a cache in dir, creating the directory if needed.
+func New(b backend.Backend, dir string) (*CacheBackend, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory '%s': %w", dir, err)
+	}
+
+	log.Debug("CacheBackend: Created\n")
+	log.Debugf("* Directory: %s\n", dir)
+
+	return &CacheBackend{Backend: b, dir: dir}, nil
+}
+
+// Pull copies a file from the cache, downloading it into the cache first
+// on a miss. Directories and unversioned files are pulled directly.
+func (c *CacheBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	cached, err := c.fetch(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if cached == "" {
+		return c.Backend.Pull(ctx, remotePath, localPath, opts)
+	}
+
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	if err := copyFile(cached, localPath); err != nil {
+		return err
+	}
+
+	log.Debugf("Copied from cache: %s -> %s\n", cached, localPath)
+	return nil
+}
+
+// Capabilities are the ones of the wrapped backend.
+func (c *CacheBackend) Capabilities() backend.Capabilities {
+	return backend.CapabilitiesOf(c.Backend)
+}
+
+// Open streams a file from the cache, downloading it into the cache first
+// on a miss. Unversioned files are opened directly, if the backend can.
+func (c *CacheBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	cached, err := c.fetch(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if cached != "" {
+		return os.Open(cached) // #nosec
+	}
+
+	opener, ok := c.Backend.(backend.Opener)
+	if !ok {
+		return nil, backend.ErrOpenNotSupported
+	}
+
+	return opener.Open(ctx, remotePath)
+}
+
+// OpenRange reads a file of the wrapped backend from offset on, bypassing
+// the cache: ranges are read to follow files that are still growing.
+func (c *CacheBackend) OpenRange(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error) {
+	return backend.OpenRange(ctx, c.Backend, remotePath, offset)
+}
+
+// Check checks the wrapped backend.
+func (c *CacheBackend) Check(ctx context.Context, remotePath string) (time.Time, error) {
+	return backend.Check(ctx, c.Backend, remotePath)
+}
+
+// List lists the wrapped backend, if it supports listing.
+func (c *CacheBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	lister, ok := c.Backend.(backend.Lister)
+	if !ok {
+		return backend.ErrListingNotSupported
+	}
+
+	return lister.List(ctx, remotePrefix, fn)
+}
+
+// Stat describes a file of the wrapped backend.
+func (c *CacheBackend) Stat(ctx context.Context, remotePath string) (*backend.ObjectInfo, error) {
+	return backend.Stat(ctx, c.Backend, remotePath)
+}
+
+// Share creates a link to a file of the wrapped backend, if it can.
+func (c *CacheBackend) Share(ctx context.Context, remotePath string, expiresIn time.Duration) (string, time.Time, error) {
+	sharer, ok := c.Backend.(backend.Sharer)
+	if !ok {
+		return "", time.Time{}, backend.ErrSharingNotSupported
+	}
+
+	return sharer.Share(ctx, remotePath, expiresIn)
+}
+
+// Checksum returns the checksum stored by the wrapped backend, if it stores checksums.
+func (c *CacheBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	reader, ok := c.Backend.(backend.ChecksumReader)
+	if !ok {
+		return "", fmt.Errorf("the configured backend does not store checksums")
+	}
+
+	return reader.Checksum(ctx, remotePath)
+}
+
+// PushStream uploads a stream through the wrapped backend, if it supports streaming.
+func (c *CacheBackend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	pusher, ok := c.Backend.(backend.StreamPusher)
+	if !ok {
+		return backend.ErrStreamingNotSupported
+	}
+
+	return pusher.PushStream(ctx, r, size, remotePath, opts)
+}
+
+// Copy copies files through the wrapped backend, if it can copy them in place.
+func (c *CacheBackend) Copy(ctx context.Context, srcPath, dstPath string, opts backend.PushOptions) error {
+	copier, ok := c.Backend.(backend.Copier)
+	if !ok {
+		return backend.ErrCopyNotSupported
+	}
+
+	return copier.Copy(ctx, srcPath, dstPath, opts)
+}
+
+// fetch returns the cached copy of remotePath, downloading it on a miss,
+// or "" if the file cannot be cached.
+func (c *CacheBackend) fetch(ctx context.Context, remotePath string) (string, error) {
+	version, err := c.version(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+
+	if version == "" {
+		log.Debugf("No version available for '%s', not caching it\n", remotePath)
+		return "", nil
+	}
+
+	cached := c.path(remotePath, version)
+	if _, err := os.Stat(cached); err == nil {
+		log.Debugf("Cache hit: %s\n", remotePath)
+
+		// Keep the modification time current, so old entries can be told apart
+		now := time.Now()
+		_ = os.Chtimes(cached, now, now)
+		return cached, nil
+	}
+
+	log.Debugf("Cache miss: %s\n", remotePath)
+
+	tmpFile, err := os.CreateTemp(c.dir, ".download-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file in cache directory: %w", err)
+	}
+	_ = tmpFile.Close()
+	defer os.Remove(tmpFile.Name())
+
+	if err := c.Backend.Pull(ctx, remotePath, tmpFile.Name(), backend.PullOptions{Force: true}); err != nil {
+		return "", err
+	}
+
+	// Renaming is atomic, so concurrent jobs never see partial entries
+	if err := os.Rename(tmpFile.Name(), cached); err != nil {
+		return "", fmt.Errorf("failed to store '%s' in cache: %w", remotePath, err)
+	}
+
+	return cached, nil
+}
+
+// version returns the stored checksum of remotePath, or its ETag, or ""
+// if neither is available or remotePath is not a single file.
+func (c *CacheBackend) version(ctx context.Context, remotePath string) (string, error) {
+	var notFound *backend.ErrNotFound
+
+	if reader, ok := c.Backend.(backend.ChecksumReader); ok {
+		checksum, err := reader.Checksum(ctx, remotePath)
+		if err != nil && !errors.As(err, &notFound) {
+			return "", err
+		}
+
+		if checksum != "" {
+			return "sha256:" + checksum, nil
+		}
+	}
+
+	lister, ok := c.Backend.(backend.Lister)
+	if !ok {
+		return "", nil
+	}
+
+	var etag string
+	err := lister.List(ctx, remotePath, func(obj backend.ObjectInfo) error {
+		if obj.Path == remotePath {
+			etag = obj.ETag
+		}
+		return backend.StopListing
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if etag == "" {
+		return "", nil
+	}
+
+	return "etag:" + etag, nil
+}
+
+// path returns where the given version of remotePath is cached.
+func (c *CacheBackend) path(remotePath, version string) string {
+	sum := sha256.Sum256([]byte(remotePath + "\x00" + version))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+func copyFile(source, destination string) error {
+	dir := filepath.Dir(destination)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	in, err := os.Open(source) // #nosec
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	return files.WriteAtomically(destination, in)
+}
@@ -0,0 +1,159 @@
+package cachebackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend keeps files in memory and counts downloads.
+type fakeBackend struct {
+	files map[string]string
+	pulls int
+}
+
+func (f *fakeBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	f.files[remotePath] = string(data)
+	return nil
+}
+
+func (f *fakeBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	data, ok := f.files[remotePath]
+	if !ok {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	f.pulls++
+	return os.WriteFile(localPath, []byte(data), 0644)
+}
+
+func (f *fakeBackend) Yank(ctx context.Context, remotePath string) error {
+	delete(f.files, remotePath)
+	return nil
+}
+
+func (f *fakeBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	_, ok := f.files[remotePath]
+	return ok, nil
+}
+
+func (f *fakeBackend) Close() error {
+	return nil
+}
+
+// checksumBackend stores checksums for its files.
+type checksumBackend struct {
+	*fakeBackend
+}
+
+func (c *checksumBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	data, ok := c.files[remotePath]
+	if !ok {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sum := sha256.Sum256([]byte(data))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// listingBackend lists its files, with their content as ETag.
+type listingBackend struct {
+	*fakeBackend
+}
+
+func (l *listingBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	for path, data := range l.files {
+		if strings.HasPrefix(path, remotePrefix) {
+			err := fn(backend.ObjectInfo{Path: path, Size: int64(len(data)), ETag: data})
+			if err == backend.StopListing {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func pullString(t *testing.T, b backend.Backend, remotePath string) string {
+	localPath := filepath.Join(t.TempDir(), "pulled.txt")
+	require.NoError(t, b.Pull(context.Background(), remotePath, localPath, backend.PullOptions{}))
+
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	return string(data)
+}
+
+func TestCacheBackend_Checksum(t *testing.T) {
+	inner := &fakeBackend{files: map[string]string{"artifacts/jobs/1/a.txt": "v1"}}
+	cache, err := New(&checksumBackend{inner}, filepath.Join(t.TempDir(), "cache"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, "v1", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, 1, inner.pulls)
+
+	// A new version is downloaded again
+	inner.files["artifacts/jobs/1/a.txt"] = "v2"
+	assert.Equal(t, "v2", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, 2, inner.pulls)
+
+	r, err := cache.Open(context.Background(), "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, "v2", string(data))
+	assert.Equal(t, 2, inner.pulls)
+
+	// Existing local files are only overwritten when forced
+	localPath := filepath.Join(t.TempDir(), "pulled.txt")
+	require.NoError(t, os.WriteFile(localPath, []byte("local"), 0644))
+	assert.Error(t, cache.Pull(context.Background(), "artifacts/jobs/1/a.txt", localPath, backend.PullOptions{}))
+	require.NoError(t, cache.Pull(context.Background(), "artifacts/jobs/1/a.txt", localPath, backend.PullOptions{Force: true}))
+
+	err = cache.Pull(context.Background(), "artifacts/jobs/1/missing.txt", localPath, backend.PullOptions{Force: true})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestCacheBackend_ETag(t *testing.T) {
+	inner := &fakeBackend{files: map[string]string{"artifacts/jobs/1/a.txt": "v1"}}
+	cache, err := New(&listingBackend{inner}, t.TempDir())
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, "v1", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, 1, inner.pulls)
+}
+
+func TestCacheBackend_Unversioned(t *testing.T) {
+	inner := &fakeBackend{files: map[string]string{"artifacts/jobs/1/a.txt": "v1"}}
+	cache, err := New(inner, t.TempDir())
+	require.NoError(t, err)
+
+	assert.Equal(t, "v1", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, "v1", pullString(t, cache, "artifacts/jobs/1/a.txt"))
+	assert.Equal(t, 2, inner.pulls)
+
+	err = cache.List(context.Background(), "artifacts/", func(backend.ObjectInfo) error { return nil })
+	assert.Equal(t, backend.ErrListingNotSupported, err)
+
+	err = cache.PushStream(context.Background(), strings.NewReader("x"), 1, "artifacts/jobs/1/b.txt", backend.PushOptions{})
+	assert.Equal(t, backend.ErrStreamingNotSupported, err)
+}
@@ -0,0 +1,78 @@
+package backend
+
+import "strings"
+
+// Capabilities are the optional features of a backend, i.e. which of the
+// optional interfaces in backend.go it implements and can serve. Commands
+// check them up front to fall back, or fail with a clear error, instead of
+// finding out half-way through an operation.
+type Capabilities struct {
+	List       bool // Lister: list stored files
+	Copy       bool // Copier: copy files without downloading them
+	Share      bool // Sharer: create links to files, e.g. S3 presigned URLs
+	Stat       bool // Stater: describe a file without listing its directory
+	Open       bool // Opener: stream a stored file
+	PushStream bool // StreamPusher: upload a stream without staging it
+	Checksum   bool // ChecksumReader: read the checksums stored for files
+}
+
+// CapabilityReporter is implemented by backends whose capabilities are not
+// told by the interfaces they implement, e.g. wrapping backends, which
+// implement every optional interface and return an ErrXxxNotSupported
+// error when the wrapped backend does not.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of b: the ones it reports, if it
+// is a CapabilityReporter, or else the optional interfaces it implements.
+func CapabilitiesOf(b Backend) Capabilities {
+	if reporter, ok := b.(CapabilityReporter); ok {
+		return reporter.Capabilities()
+	}
+
+	_, list := b.(Lister)
+	_, copier := b.(Copier)
+	_, share := b.(Sharer)
+	_, stat := b.(Stater)
+	_, open := b.(Opener)
+	_, pushStream := b.(StreamPusher)
+	_, checksum := b.(ChecksumReader)
+
+	return Capabilities{
+		List:       list,
+		Copy:       copier,
+		Share:      share,
+		Stat:       stat,
+		Open:       open,
+		PushStream: pushStream,
+		Checksum:   checksum,
+	}
+}
+
+// String lists the capabilities, e.g. "list, copy, share", or "none".
+func (c Capabilities) String() string {
+	names := []string{}
+	for _, capability := range []struct {
+		name string
+		ok   bool
+	}{
+		{"list", c.List},
+		{"copy", c.Copy},
+		{"share", c.Share},
+		{"stat", c.Stat},
+		{"open", c.Open},
+		{"push-stream", c.PushStream},
+		{"checksum", c.Checksum},
+	} {
+		if capability.ok {
+			names = append(names, capability.name)
+		}
+	}
+
+	if len(names) == 0 {
+		return "none"
+	}
+
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,26 @@
+package backend_test
+
+import (
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilitiesOf(t *testing.T) {
+	memory := memorybackend.New()
+
+	assert.Equal(t, backend.Capabilities{List: true, Copy: true, Stat: true, Open: true, PushStream: true, Checksum: true}, backend.CapabilitiesOf(memory))
+	assert.Equal(t, backend.Capabilities{}, backend.CapabilitiesOf(plain{Backend: memory}))
+	assert.Equal(t, backend.Capabilities{List: true}, backend.CapabilitiesOf(listOnly{Backend: memory, Lister: memory}))
+
+	// Wrapping backends implement every optional interface, but report
+	// the capabilities of the backend they wrap
+	assert.Equal(t, backend.Capabilities{List: true}, backend.CapabilitiesOf(backend.NewReadOnly(listOnly{Backend: memory, Lister: memory})))
+}
+
+func TestCapabilities_String(t *testing.T) {
+	assert.Equal(t, "list, copy, stat, open, push-stream, checksum", backend.CapabilitiesOf(memorybackend.New()).String())
+	assert.Equal(t, "none", backend.Capabilities{}.String())
+}
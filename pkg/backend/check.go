@@ -0,0 +1,18 @@
+package backend
+
+import (
+	"context"
+	"time"
+)
+
+// Check verifies that the storage of the backend can be reached with its
+// credentials, using the backend's Checker. Other backends are checked by
+// looking up remotePath, without the time of the server.
+func Check(ctx context.Context, b Backend, remotePath string) (time.Time, error) {
+	if checker, ok := b.(Checker); ok {
+		return checker.Check(ctx, remotePath)
+	}
+
+	_, err := b.Exists(ctx, remotePath)
+	return time.Time{}, err
+}
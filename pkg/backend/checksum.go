@@ -0,0 +1,83 @@
+package backend
+
+import (
+	"path"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/files"
+)
+
+// Every pushed file gets a hex-encoded SHA256 checksum stored next to it,
+// so features that compare local and remote files (sync, diff, verify,
+// --if-changed) work the same way on every backend.
+//
+// Backends with object metadata store it in the ChecksumMetadataKey entry.
+// Others store it in a sidecar object under the artifact store's
+// ChecksumSidecarDir, mirroring the path of the file:
+//
+//	artifacts/jobs/<id>/logs/a.log -> artifacts/jobs/<id>/.checksums/logs/a.log.sha256
+//
+// Files pushed before checksums were stored have none; consumers must
+// treat a missing checksum as unknown rather than different.
+const (
+	ChecksumMetadataKey = "sha256"
+	ChecksumSidecarDir  = ".checksums"
+	checksumSidecarExt  = ".sha256"
+)
+
+// ChecksumSidecarPath returns the path of the sidecar holding the checksum of remotePath.
+func ChecksumSidecarPath(remotePath string) string {
+	return ChecksumSidecarPrefix(remotePath) + checksumSidecarExt
+}
+
+// ChecksumSidecarPrefix returns the path under which the sidecars of
+// remotePath live: the sidecar itself is this path plus ".sha256", and the
+// sidecars of the files in a remotePath directory are below it.
+func ChecksumSidecarPrefix(remotePath string) string {
+	root, rel := splitStoreRoot(strings.TrimSuffix(remotePath, "/"))
+	return path.Join(root, ChecksumSidecarDir, rel)
+}
+
+// IsChecksumSidecar returns true for paths of checksum sidecars,
+// which are hidden from listings and pulls.
+func IsChecksumSidecar(remotePath string) bool {
+	return strings.Contains("/"+remotePath+"/", "/"+ChecksumSidecarDir+"/")
+}
+
+// VerifyFile checks the local file pulled from remotePath against the
+// checksum it was pushed with, returning ErrChecksumMismatch if it differs.
+// Files without a checksum are not checked.
+func VerifyFile(localPath, remotePath, expected string) error {
+	if expected == "" {
+		return nil
+	}
+
+	actual, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
+	return VerifyChecksum(remotePath, expected, actual)
+}
+
+// VerifyChecksum returns ErrChecksumMismatch if the checksum of the file
+// pulled from remotePath is not the expected one, unless none is known.
+func VerifyChecksum(remotePath, expected, actual string) error {
+	if expected == "" || strings.EqualFold(expected, actual) {
+		return nil
+	}
+
+	return &ErrChecksumMismatch{Path: remotePath, Expected: expected, Actual: actual}
+}
+
+// splitStoreRoot splits a remote path into its artifact store,
+// e.g. artifacts/jobs/<id>, and the path inside it. Paths outside
+// the usual layout use their parent directory as the root.
+func splitStoreRoot(remotePath string) (string, string) {
+	parts := strings.SplitN(remotePath, "/", 4)
+	if len(parts) == 4 && parts[0] == "artifacts" {
+		return path.Join(parts[:3]...), parts[3]
+	}
+
+	return path.Dir(remotePath), path.Base(remotePath)
+}
@@ -0,0 +1,42 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecksumSidecarPath(t *testing.T) {
+	assert.Equal(t, "artifacts/jobs/1/.checksums/logs/a.log.sha256", ChecksumSidecarPath("artifacts/jobs/1/logs/a.log"))
+	assert.Equal(t, "artifacts/projects/p/.checksums/x.zip.sha256", ChecksumSidecarPath("artifacts/projects/p/x.zip"))
+	assert.Equal(t, "artifacts/jobs/1/.checksums/logs", ChecksumSidecarPrefix("artifacts/jobs/1/logs/"))
+	assert.Equal(t, "other/.checksums/x.zip.sha256", ChecksumSidecarPath("other/x.zip"))
+}
+
+func TestIsChecksumSidecar(t *testing.T) {
+	assert.True(t, IsChecksumSidecar("artifacts/jobs/1/.checksums/logs/a.log.sha256"))
+	assert.False(t, IsChecksumSidecar("artifacts/jobs/1/logs/a.log"))
+	assert.False(t, IsChecksumSidecar("artifacts/jobs/1/my.checksums/a.log"))
+}
+
+func TestVerifyFile(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(localPath, []byte("hello"), 0644))
+
+	// sha256 of "hello"
+	checksum := "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	assert.NoError(t, VerifyFile(localPath, "artifacts/jobs/1/a.txt", checksum))
+	assert.NoError(t, VerifyFile(localPath, "artifacts/jobs/1/a.txt", strings.ToUpper(checksum)))
+	assert.NoError(t, VerifyFile(localPath, "artifacts/jobs/1/a.txt", ""))
+
+	err := VerifyFile(localPath, "artifacts/jobs/1/a.txt", strings.Repeat("0", 64))
+	var mismatch *ErrChecksumMismatch
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "artifacts/jobs/1/a.txt", mismatch.Path)
+	assert.Equal(t, checksum, mismatch.Actual)
+	assert.ErrorContains(t, err, "artifacts/jobs/1/a.txt does not match its checksum")
+}
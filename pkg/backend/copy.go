@@ -0,0 +1,45 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Copy copies the file or directory at srcPath to dstPath with the backend's
+// Copier, so the data never leaves the storage. Backends without the Copy
+// capability pull it into a temporary directory and push it again.
+func Copy(ctx context.Context, b Backend, srcPath, dstPath string, opts PushOptions) error {
+	if strings.HasPrefix(dstPath, strings.TrimSuffix(srcPath, "/")+"/") {
+		return fmt.Errorf("cannot copy '%s' into itself", srcPath)
+	}
+
+	if copier, ok := b.(Copier); ok && CapabilitiesOf(b).Copy {
+		err := copier.Copy(ctx, srcPath, dstPath, opts)
+		if !errors.Is(err, ErrCopyNotSupported) {
+			return err
+		}
+	}
+
+	log.Debug("Backend cannot copy files in place, downloading and uploading them again...\n")
+
+	tmpDir, err := os.MkdirTemp("", "artifact-copy-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+
+	defer os.RemoveAll(tmpDir)
+
+	localPath := filepath.Join(tmpDir, path.Base(srcPath))
+	if err := b.Pull(ctx, srcPath, localPath, PullOptions{}); err != nil {
+		return err
+	}
+
+	return b.Push(ctx, localPath, dstPath, opts)
+}
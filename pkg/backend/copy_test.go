@@ -0,0 +1,52 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCopy(t *testing.T) {
+	ctx := context.Background()
+
+	for name, wrap := range map[string]func(*memorybackend.MemoryBackend) backend.Backend{
+		"copier":        func(m *memorybackend.MemoryBackend) backend.Backend { return m },
+		"pull and push": func(m *memorybackend.MemoryBackend) backend.Backend { return plain{Backend: m} },
+	} {
+		t.Run(name, func(t *testing.T) {
+			memory := memorybackend.New()
+			memory.Put("artifacts/jobs/1/dist/app.zip", []byte("app"))
+			memory.Put("artifacts/jobs/1/dist/bin/app", []byte("binary"))
+			b := wrap(memory)
+
+			require.NoError(t, backend.Copy(ctx, b, "artifacts/jobs/1/dist", "artifacts/projects/2/release", backend.PushOptions{}))
+			require.NoError(t, backend.Copy(ctx, b, "artifacts/jobs/1/dist/app.zip", "artifacts/workflows/3/app.zip", backend.PushOptions{}))
+			assert.Equal(t, []string{
+				"artifacts/jobs/1/dist/app.zip",
+				"artifacts/jobs/1/dist/bin/app",
+				"artifacts/projects/2/release/app.zip",
+				"artifacts/projects/2/release/bin/app",
+				"artifacts/workflows/3/app.zip",
+			}, memory.Paths())
+
+			data, _ := memory.Get("artifacts/projects/2/release/bin/app")
+			assert.Equal(t, "binary", string(data))
+
+			var alreadyExists *backend.ErrAlreadyExists
+			err := backend.Copy(ctx, b, "artifacts/jobs/1/dist/app.zip", "artifacts/workflows/3/app.zip", backend.PushOptions{})
+			assert.ErrorAs(t, err, &alreadyExists)
+			assert.NoError(t, backend.Copy(ctx, b, "artifacts/jobs/1/dist/app.zip", "artifacts/workflows/3/app.zip", backend.PushOptions{Force: true}))
+
+			err = backend.Copy(ctx, b, "artifacts/jobs/1/dist", "artifacts/jobs/1/dist/old", backend.PushOptions{})
+			assert.ErrorContains(t, err, "into itself")
+
+			var notFound *backend.ErrNotFound
+			err = backend.Copy(ctx, b, "artifacts/jobs/1/missing", "artifacts/workflows/3/missing", backend.PushOptions{})
+			assert.ErrorAs(t, err, &notFound)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// A credential helper is an external command backends obtain credentials
+// from, instead of static environment variables, e.g. a script fetching
+// short-lived credentials from Vault. It is configured with
+// ARTIFACT_CREDENTIAL_HELPER or the credentialHelper config key, and called
+// with the backend type as its last argument. It prints Credentials as JSON:
+//
+//	$ vault-artifact-helper s3
+//	{"accessKeyId":"ASIA...","secretAccessKey":"...","sessionToken":"...","expiration":"2026-01-02T15:04:05Z"}
+//
+// Credentials with an expiration are requested again shortly before they expire.
+
+// credentialRefreshMargin is how long before their expiration credentials are refreshed.
+const credentialRefreshMargin = time.Minute
+
+// Credentials are returned by a credential helper. Backends use the fields
+// they support: S3 the access keys, HTTP the token, FTP the username and password.
+type Credentials struct {
+	AccessKeyID     string     `json:"accessKeyId,omitempty"`
+	SecretAccessKey string     `json:"secretAccessKey,omitempty"`
+	SessionToken    string     `json:"sessionToken,omitempty"`
+	Token           string     `json:"token,omitempty"`
+	Username        string     `json:"username,omitempty"`
+	Password        string     `json:"password,omitempty"`
+	Expiration      *time.Time `json:"expiration,omitempty"`
+}
+
+// expiresSoon returns true if the credentials need to be refreshed.
+func (c *Credentials) expiresSoon() bool {
+	return c.Expiration != nil && time.Now().Add(credentialRefreshMargin).After(*c.Expiration)
+}
+
+// CredentialHelper runs the configured credential helper for a backend,
+// caching its credentials until they are about to expire.
+type CredentialHelper struct {
+	command     []string
+	backendType BackendType
+
+	mu     sync.Mutex
+	cached *Credentials
+}
+
+// GetCredentialHelper returns the configured credential helper command, or "".
+func GetCredentialHelper() string {
+	if helper := os.Getenv("ARTIFACT_CREDENTIAL_HELPER"); helper != "" {
+		return helper
+	}
+
+	return viper.GetString("credentialHelper")
+}
+
+// NewCredentialHelper returns the credential helper for a backend type.
+// Without a configured helper command, credentials stored with 'artifact
+// login' are returned by it instead, and nil if there are none.
+func NewCredentialHelper(backendType BackendType) *CredentialHelper {
+	command := strings.Fields(GetCredentialHelper())
+	if len(command) > 0 {
+		return &CredentialHelper{command: command, backendType: backendType}
+	}
+
+	stored, err := storedCredentials(backendType)
+	if err != nil {
+		log.Warnf("%v\n", err)
+		return nil
+	}
+	if stored == nil {
+		return nil
+	}
+
+	return &CredentialHelper{backendType: backendType, cached: stored}
+}
+
+// Get returns the cached credentials, running the helper if there are
+// none yet or they are about to expire.
+func (h *CredentialHelper) Get(ctx context.Context) (*Credentials, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.cached != nil && !h.cached.expiresSoon() {
+		return h.cached, nil
+	}
+
+	// Stored credentials cannot be refreshed
+	if len(h.command) == 0 {
+		return nil, fmt.Errorf("stored credentials of the %s backend expired: run 'artifact login %s' again", h.backendType, h.backendType)
+	}
+
+	credentials, err := h.run(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	h.cached = credentials
+	return credentials, nil
+}
+
+func (h *CredentialHelper) run(ctx context.Context) (*Credentials, error) {
+	var stdout, stderr bytes.Buffer
+
+	args := append(h.command[1:], string(h.backendType))
+
+	// #nosec
+	cmd := exec.CommandContext(ctx, h.command[0], args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	log.Debug("Running credential helper...\n")
+	log.Debugf("* Command: %s\n", strings.Join(cmd.Args, " "))
+
+	err := cmd.Run()
+	if stderr.Len() > 0 {
+		log.Debugf("Credential helper output:\n%s\n", strings.TrimSpace(stderr.String()))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("credential helper '%s' failed: %v: %s", h.command[0], err, strings.TrimSpace(stderr.String()))
+	}
+
+	credentials := &Credentials{}
+	if err := json.Unmarshal(stdout.Bytes(), credentials); err != nil {
+		return nil, fmt.Errorf("credential helper '%s' returned invalid credentials: %v", h.command[0], err)
+	}
+
+	if credentials.Expiration != nil {
+		log.Debugf("* Expiration: %s\n", credentials.Expiration.Format(time.RFC3339))
+	}
+
+	return credentials, nil
+}
@@ -0,0 +1,101 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary doubles as a credential helper, counting its calls in
+// ARTIFACT_TEST_HELPER_CALLS and answering with credentials expiring after
+// ARTIFACT_TEST_HELPER_EXPIRES_IN.
+func TestMain(m *testing.M) {
+	if calls := os.Getenv("ARTIFACT_TEST_HELPER_CALLS"); calls != "" {
+		os.Exit(runTestHelper(calls))
+	}
+
+	os.Exit(m.Run())
+}
+
+func runTestHelper(calls string) int {
+	backendType := os.Args[len(os.Args)-1]
+	if backendType == "broken" {
+		fmt.Fprintln(os.Stderr, "vault is sealed")
+		return 1
+	}
+
+	f, err := os.OpenFile(calls, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return 2
+	}
+	_, _ = f.WriteString(".")
+	f.Close()
+
+	credentials := &Credentials{AccessKeyID: "key-" + backendType, Token: os.Args[1]}
+	if expiresIn, err := time.ParseDuration(os.Getenv("ARTIFACT_TEST_HELPER_EXPIRES_IN")); err == nil {
+		expiration := time.Now().Add(expiresIn)
+		credentials.Expiration = &expiration
+	}
+
+	_ = json.NewEncoder(os.Stdout).Encode(credentials)
+	return 0
+}
+
+func helperCalls(t *testing.T, calls string) int {
+	data, err := os.ReadFile(calls)
+	require.NoError(t, err)
+	return len(data)
+}
+
+func TestCredentialHelper(t *testing.T) {
+	ctx := context.Background()
+	calls := filepath.Join(t.TempDir(), "calls")
+	t.Setenv("ARTIFACT_TEST_HELPER_CALLS", calls)
+
+	t.Setenv("ARTIFACT_CREDENTIAL_HELPER", "")
+	assert.Nil(t, NewCredentialHelper(BackendTypeS3))
+
+	t.Setenv("ARTIFACT_CREDENTIAL_HELPER", os.Args[0]+" --role=ci")
+	helper := NewCredentialHelper(BackendTypeS3)
+	require.NotNil(t, helper)
+
+	credentials, err := helper.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "key-s3", credentials.AccessKeyID)
+	assert.Equal(t, "--role=ci", credentials.Token)
+	assert.Nil(t, credentials.Expiration)
+
+	// Credentials without an expiration are cached
+	_, err = helper.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, helperCalls(t, calls))
+
+	// Credentials about to expire are refreshed
+	t.Setenv("ARTIFACT_TEST_HELPER_EXPIRES_IN", "30s")
+	helper = NewCredentialHelper(BackendTypeHTTP)
+	credentials, err = helper.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "key-http", credentials.AccessKeyID)
+	assert.NotNil(t, credentials.Expiration)
+	_, err = helper.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 3, helperCalls(t, calls))
+
+	t.Setenv("ARTIFACT_TEST_HELPER_EXPIRES_IN", "1h")
+	helper = NewCredentialHelper(BackendTypeHTTP)
+	_, err = helper.Get(ctx)
+	require.NoError(t, err)
+	_, err = helper.Get(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 4, helperCalls(t, calls))
+
+	_, err = NewCredentialHelper("broken").Get(ctx)
+	assert.ErrorContains(t, err, "vault is sealed")
+}
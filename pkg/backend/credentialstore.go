@@ -0,0 +1,275 @@
+package backend
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	homedir "github.com/mitchellh/go-homedir"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"github.com/zalando/go-keyring"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Credentials stored with 'artifact login' are kept in a credential store:
+// the keychain of the OS, or a file encrypted with a passphrase, e.g. on
+// Linux machines without a secret service. The store is configured with
+// ARTIFACT_CREDENTIAL_STORE or the credentialStore config key, which login
+// sets, and is only read when it is configured.
+const (
+	CredentialStoreKeychain = "keychain"
+	CredentialStoreFile     = "file"
+)
+
+// credentialService is the service credentials are stored under in the keychain.
+const credentialService = "artifact"
+
+// CredentialStore keeps the credentials of backends, by backend type.
+type CredentialStore interface {
+	// Load returns the stored credentials, or nil if there are none.
+	Load(backendType BackendType) (*Credentials, error)
+	Save(backendType BackendType, credentials *Credentials) error
+	// Delete returns false if there were no stored credentials.
+	Delete(backendType BackendType) (bool, error)
+}
+
+// GetCredentialStore returns the configured credential store, or "".
+func GetCredentialStore() string {
+	if store := os.Getenv("ARTIFACT_CREDENTIAL_STORE"); store != "" {
+		return store
+	}
+
+	return viper.GetString("credentialStore")
+}
+
+// NewCredentialStore returns the credential store of the given kind.
+func NewCredentialStore(kind string) (CredentialStore, error) {
+	switch kind {
+	case CredentialStoreKeychain:
+		return &keychainStore{}, nil
+	case CredentialStoreFile:
+		path, err := credentialFilePath()
+		if err != nil {
+			return nil, err
+		}
+
+		return &fileStore{path: path, passphrase: os.Getenv("ARTIFACT_CREDENTIAL_PASSPHRASE")}, nil
+	default:
+		return nil, fmt.Errorf("invalid credential store '%s': use %s or %s", kind, CredentialStoreKeychain, CredentialStoreFile)
+	}
+}
+
+// storedCredentials returns the credentials stored for a backend type in
+// the configured store, or nil if there is none or it has none.
+func storedCredentials(backendType BackendType) (*Credentials, error) {
+	kind := GetCredentialStore()
+	if kind == "" {
+		return nil, nil
+	}
+
+	store, err := NewCredentialStore(kind)
+	if err != nil {
+		return nil, err
+	}
+
+	credentials, err := store.Load(backendType)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load stored credentials of the %s backend: %v", backendType, err)
+	}
+
+	if credentials != nil {
+		log.Debugf("Using credentials of the %s backend from the %s credential store\n", backendType, kind)
+	}
+
+	return credentials, nil
+}
+
+// keychainStore keeps credentials in the keychain of the OS: the macOS
+// Keychain, the Windows Credential Manager or the Secret Service on Linux.
+type keychainStore struct{}
+
+func (s *keychainStore) Load(backendType BackendType) (*Credentials, error) {
+	data, err := keyring.Get(credentialService, string(backendType))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	credentials := &Credentials{}
+	if err := json.Unmarshal([]byte(data), credentials); err != nil {
+		return nil, fmt.Errorf("invalid credentials in the keychain: %v", err)
+	}
+
+	return credentials, nil
+}
+
+func (s *keychainStore) Save(backendType BackendType, credentials *Credentials) error {
+	data, err := json.Marshal(credentials)
+	if err != nil {
+		return err
+	}
+
+	return keyring.Set(credentialService, string(backendType), string(data))
+}
+
+func (s *keychainStore) Delete(backendType BackendType) (bool, error) {
+	err := keyring.Delete(credentialService, string(backendType))
+	if errors.Is(err, keyring.ErrNotFound) {
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// fileStore keeps the credentials of all backends in a file encrypted
+// with AES-GCM, with a key derived from the passphrase with scrypt. The
+// file holds the salt, the nonce and the encrypted JSON, in that order.
+type fileStore struct {
+	path       string
+	passphrase string
+}
+
+const (
+	credentialFileSaltSize = 16
+	credentialFileKeySize  = 32
+)
+
+// credentialFilePath returns the configured credential file, or the default one.
+func credentialFilePath() (string, error) {
+	if path := os.Getenv("ARTIFACT_CREDENTIAL_FILE"); path != "" {
+		return path, nil
+	}
+	if path := viper.GetString("credentialFile"); path != "" {
+		return path, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".artifact-credentials"), nil
+}
+
+func (s *fileStore) Load(backendType BackendType) (*Credentials, error) {
+	all, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+
+	return all[backendType], nil
+}
+
+func (s *fileStore) Save(backendType BackendType, credentials *Credentials) error {
+	all, err := s.read()
+	if err != nil {
+		return err
+	}
+
+	all[backendType] = credentials
+	return s.write(all)
+}
+
+func (s *fileStore) Delete(backendType BackendType) (bool, error) {
+	all, err := s.read()
+	if err != nil {
+		return false, err
+	}
+
+	if _, ok := all[backendType]; !ok {
+		return false, nil
+	}
+
+	delete(all, backendType)
+	return true, s.write(all)
+}
+
+func (s *fileStore) read() (map[BackendType]*Credentials, error) {
+	all := map[BackendType]*Credentials{}
+
+	// #nosec
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return all, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < credentialFileSaltSize {
+		return nil, fmt.Errorf("credential file '%s' is corrupted", s.path)
+	}
+
+	aead, err := s.cipher(data[:credentialFileSaltSize])
+	if err != nil {
+		return nil, err
+	}
+
+	data = data[credentialFileSaltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, fmt.Errorf("credential file '%s' is corrupted", s.path)
+	}
+
+	plaintext, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt credential file '%s': wrong ARTIFACT_CREDENTIAL_PASSPHRASE?", s.path)
+	}
+
+	if err := json.Unmarshal(plaintext, &all); err != nil {
+		return nil, fmt.Errorf("credential file '%s' is corrupted: %v", s.path, err)
+	}
+
+	return all, nil
+}
+
+// write encrypts the credentials with a new salt and nonce every time.
+func (s *fileStore) write(all map[BackendType]*Credentials) error {
+	plaintext, err := json.Marshal(all)
+	if err != nil {
+		return err
+	}
+
+	salt := make([]byte, credentialFileSaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return err
+	}
+
+	aead, err := s.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	data := append(salt, aead.Seal(nonce, nonce, plaintext, nil)...)
+	return os.WriteFile(s.path, data, 0600)
+}
+
+func (s *fileStore) cipher(salt []byte) (cipher.AEAD, error) {
+	if s.passphrase == "" {
+		return nil, fmt.Errorf("ARTIFACT_CREDENTIAL_PASSPHRASE is not set: it encrypts the credential file '%s'", s.path)
+	}
+
+	key, err := scrypt.Key([]byte(s.passphrase), salt, 1<<15, 8, 1, credentialFileKeySize)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,122 @@
+package backend
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zalando/go-keyring"
+)
+
+func Test__FileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "credentials")
+	store := &fileStore{path: path, passphrase: "correct horse"}
+
+	credentials, err := store.Load(BackendTypeS3)
+	require.NoError(t, err)
+	assert.Nil(t, credentials)
+
+	require.NoError(t, store.Save(BackendTypeS3, &Credentials{AccessKeyID: "AKIA", SecretAccessKey: "secret"}))
+	require.NoError(t, store.Save(BackendTypeHTTP, &Credentials{Token: "token"}))
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "secret")
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+
+	credentials, err = store.Load(BackendTypeS3)
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{AccessKeyID: "AKIA", SecretAccessKey: "secret"}, credentials)
+
+	t.Run("wrong passphrase", func(t *testing.T) {
+		_, err := (&fileStore{path: path, passphrase: "wrong"}).Load(BackendTypeS3)
+		assert.ErrorContains(t, err, "failed to decrypt credential file")
+	})
+
+	t.Run("no passphrase", func(t *testing.T) {
+		_, err := (&fileStore{path: path}).Load(BackendTypeS3)
+		assert.ErrorContains(t, err, "ARTIFACT_CREDENTIAL_PASSPHRASE is not set")
+	})
+
+	t.Run("delete", func(t *testing.T) {
+		deleted, err := store.Delete(BackendTypeS3)
+		require.NoError(t, err)
+		assert.True(t, deleted)
+
+		deleted, err = store.Delete(BackendTypeS3)
+		require.NoError(t, err)
+		assert.False(t, deleted)
+
+		credentials, err := store.Load(BackendTypeHTTP)
+		require.NoError(t, err)
+		assert.Equal(t, "token", credentials.Token)
+	})
+}
+
+func Test__KeychainStore(t *testing.T) {
+	keyring.MockInit()
+	store := &keychainStore{}
+
+	credentials, err := store.Load(BackendTypeFTP)
+	require.NoError(t, err)
+	assert.Nil(t, credentials)
+
+	require.NoError(t, store.Save(BackendTypeFTP, &Credentials{Username: "ci", Password: "secret"}))
+
+	credentials, err = store.Load(BackendTypeFTP)
+	require.NoError(t, err)
+	assert.Equal(t, &Credentials{Username: "ci", Password: "secret"}, credentials)
+
+	deleted, err := store.Delete(BackendTypeFTP)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+
+	deleted, err = store.Delete(BackendTypeFTP)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
+func Test__NewCredentialHelper_StoredCredentials(t *testing.T) {
+	keyring.MockInit()
+	t.Setenv("ARTIFACT_CREDENTIAL_HELPER", "")
+
+	t.Run("store not configured", func(t *testing.T) {
+		t.Setenv("ARTIFACT_CREDENTIAL_STORE", "")
+		require.NoError(t, (&keychainStore{}).Save(BackendTypeHTTP, &Credentials{Token: "token"}))
+		assert.Nil(t, NewCredentialHelper(BackendTypeHTTP))
+	})
+
+	t.Run("no stored credentials", func(t *testing.T) {
+		t.Setenv("ARTIFACT_CREDENTIAL_STORE", CredentialStoreKeychain)
+		assert.Nil(t, NewCredentialHelper(BackendTypeS3))
+	})
+
+	t.Run("stored credentials", func(t *testing.T) {
+		t.Setenv("ARTIFACT_CREDENTIAL_STORE", CredentialStoreKeychain)
+
+		helper := NewCredentialHelper(BackendTypeHTTP)
+		require.NotNil(t, helper)
+
+		credentials, err := helper.Get(t.Context())
+		require.NoError(t, err)
+		assert.Equal(t, "token", credentials.Token)
+	})
+
+	t.Run("expired stored credentials", func(t *testing.T) {
+		t.Setenv("ARTIFACT_CREDENTIAL_STORE", CredentialStoreKeychain)
+		expiration := time.Now().Add(-time.Hour)
+		require.NoError(t, (&keychainStore{}).Save(BackendTypeS3, &Credentials{AccessKeyID: "ASIA", SecretAccessKey: "secret", Expiration: &expiration}))
+
+		helper := NewCredentialHelper(BackendTypeS3)
+		require.NotNil(t, helper)
+
+		_, err := helper.Get(t.Context())
+		assert.EqualError(t, err, "stored credentials of the s3 backend expired: run 'artifact login s3' again")
+	})
+}
@@ -0,0 +1,234 @@
+// Package execbackend implements the Backend interface by running an external
+// plugin executable for every operation, in the spirit of git and docker
+// credential helpers. It lets users add storage systems without forking the CLI.
+//
+// The plugin is selected with ARTIFACT_BACKEND=exec:/path/to/plugin and called
+// with the operation as its only argument: push, pull, yank or exists.
+// A JSON Request is written to its stdin, and it answers with a JSON Response
+// on stdout. Failures are reported with an error message and, for well-known
+// failures, an error code:
+//
+//	$ echo '{"version":1,"operation":"exists","remotePath":"artifacts/jobs/1/a.zip"}' | plugin exists
+//	{"exists":true}
+//
+//	$ echo '{"version":1,"operation":"pull","remotePath":"artifacts/jobs/1/b.zip","localPath":"/tmp/b.zip"}' | plugin pull
+//	{"error":"no such object","code":"not_found"}
+//
+// Anything the plugin writes to stderr is shown in verbose mode, and
+// included in the error if it exits with a non-zero status without a response.
+package execbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// ProtocolVersion is sent with every request, so plugins can detect
+// requests they do not understand.
+const ProtocolVersion = 1
+
+// Operations plugins are called with.
+const (
+	OperationPush   = "push"
+	OperationPull   = "pull"
+	OperationYank   = "yank"
+	OperationExists = "exists"
+)
+
+// Error codes plugins report well-known failures with.
+const (
+	CodeNotFound         = "not_found"
+	CodeAlreadyExists    = "already_exists"
+	CodePermissionDenied = "permission_denied"
+)
+
+func init() {
+	backend.RegisterExecBackend(func(path string) (backend.Backend, error) {
+		return New(path)
+	})
+}
+
+// Request is written to the plugin's stdin.
+type Request struct {
+	Version    int               `json:"version"`
+	Operation  string            `json:"operation"`
+	RemotePath string            `json:"remotePath"`
+	LocalPath  string            `json:"localPath,omitempty"` // absolute, for push and pull
+	Force      bool              `json:"force,omitempty"`     // overwrite existing files, for push and pull
+	Metadata   map[string]string `json:"metadata,omitempty"`  // user metadata, for push
+}
+
+// Response is read from the plugin's stdout.
+type Response struct {
+	Exists bool   `json:"exists,omitempty"` // for exists
+	Error  string `json:"error,omitempty"`
+	Code   string `json:"code,omitempty"` // one of the Code* constants, if applicable
+}
+
+// ExecBackend implements the Backend interface by running a plugin executable.
+type ExecBackend struct {
+	path string
+}
+
+// New creates a new ExecBackend for the plugin at path,
+// looking it up in PATH if it has no directory.
+func New(path string) (*ExecBackend, error) {
+	if path == "" {
+		return nil, fmt.Errorf("exec backend needs a plugin: set ARTIFACT_BACKEND=%s/path/to/plugin", backend.ExecBackendPrefix)
+	}
+
+	resolved, err := exec.LookPath(path)
+	if err != nil {
+		return nil, fmt.Errorf("backend plugin '%s' not found: %w", path, err)
+	}
+
+	log.Debug("ExecBackend: Plugin found\n")
+	log.Debugf("* Path: %s\n", resolved)
+
+	return &ExecBackend{path: resolved}, nil
+}
+
+// Push hands a local file or directory to the plugin. Plugins are expected
+// to report existing files with CodeAlreadyExists, unless forced.
+func (e *ExecBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("ExecBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.call(ctx, &Request{
+		Operation:  OperationPush,
+		RemotePath: remotePath,
+		LocalPath:  absPath,
+		Force:      opts.Force,
+		Metadata:   opts.Metadata,
+	})
+
+	return err
+}
+
+// Pull asks the plugin to download a file or directory to localPath.
+func (e *ExecBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("ExecBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.call(ctx, &Request{
+		Operation:  OperationPull,
+		RemotePath: remotePath,
+		LocalPath:  absPath,
+		Force:      opts.Force,
+	})
+
+	return err
+}
+
+// Yank asks the plugin to delete a file or directory.
+func (e *ExecBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("ExecBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	_, err := e.call(ctx, &Request{Operation: OperationYank, RemotePath: remotePath})
+	return err
+}
+
+// Exists asks the plugin whether a file exists.
+func (e *ExecBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("ExecBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	response, err := e.call(ctx, &Request{Operation: OperationExists, RemotePath: remotePath})
+	if err != nil {
+		return false, err
+	}
+
+	return response.Exists, nil
+}
+
+// Close releases resources. Plugins only run during calls, so this is a no-op.
+func (e *ExecBackend) Close() error {
+	return nil
+}
+
+// call runs the plugin for a single request and maps its response to backend errors.
+func (e *ExecBackend) call(ctx context.Context, req *Request) (*Response, error) {
+	req.Version = ProtocolVersion
+
+	input, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	var stdout, stderr bytes.Buffer
+
+	// #nosec
+	cmd := exec.CommandContext(ctx, e.path, req.Operation)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	runErr := cmd.Run()
+	if stderr.Len() > 0 {
+		log.Debugf("Plugin output:\n%s\n", strings.TrimSpace(stderr.String()))
+	}
+
+	response := &Response{}
+	if output := bytes.TrimSpace(stdout.Bytes()); len(output) > 0 {
+		if err := json.Unmarshal(output, response); err != nil {
+			return nil, fmt.Errorf("backend plugin '%s' returned an invalid %s response: %v", e.path, req.Operation, err)
+		}
+	}
+
+	if response.Error != "" {
+		return nil, responseError(req, response)
+	}
+
+	if runErr != nil {
+		return nil, fmt.Errorf("backend plugin '%s' failed to %s '%s': %v: %s", e.path, req.Operation, req.RemotePath, runErr, strings.TrimSpace(stderr.String()))
+	}
+
+	return response, nil
+}
+
+func responseError(req *Request, response *Response) error {
+	switch response.Code {
+	case CodeNotFound:
+		return &backend.ErrNotFound{Path: req.RemotePath}
+	case CodeAlreadyExists:
+		return &backend.ErrAlreadyExists{Path: req.RemotePath}
+	case CodePermissionDenied:
+		return &backend.ErrPermissionDenied{Operation: req.Operation, Path: req.RemotePath, Reason: response.Error}
+	default:
+		return fmt.Errorf("failed to %s '%s': %s", req.Operation, req.RemotePath, response.Error)
+	}
+}
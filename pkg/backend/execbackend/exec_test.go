@@ -0,0 +1,137 @@
+package execbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary doubles as a plugin storing files in ARTIFACT_EXEC_TEST_STORE.
+func TestMain(m *testing.M) {
+	if store := os.Getenv("ARTIFACT_EXEC_TEST_STORE"); store != "" && len(os.Args) == 2 {
+		os.Exit(runTestPlugin(store, os.Args[1]))
+	}
+
+	os.Exit(m.Run())
+}
+
+func runTestPlugin(store, operation string) int {
+	req := &Request{}
+	if err := json.NewDecoder(os.Stdin).Decode(req); err != nil || req.Operation != operation {
+		fmt.Fprintln(os.Stderr, "bad request")
+		return 2
+	}
+
+	respond := func(response *Response) int {
+		_ = json.NewEncoder(os.Stdout).Encode(response)
+		return 0
+	}
+
+	if req.RemotePath == "crash" {
+		fmt.Fprintln(os.Stderr, "storage exploded")
+		return 3
+	}
+
+	if strings.HasPrefix(req.RemotePath, "forbidden/") {
+		return respond(&Response{Error: "read-only path", Code: CodePermissionDenied})
+	}
+
+	stored := filepath.Join(store, req.RemotePath)
+	_, statErr := os.Stat(stored)
+
+	switch operation {
+	case OperationPush:
+		if statErr == nil && !req.Force {
+			return respond(&Response{Error: "exists", Code: CodeAlreadyExists})
+		}
+		data, err := os.ReadFile(req.LocalPath)
+		if err != nil {
+			return respond(&Response{Error: err.Error()})
+		}
+		_ = os.MkdirAll(filepath.Dir(stored), 0755)
+		_ = os.WriteFile(stored, data, 0644)
+	case OperationPull:
+		data, err := os.ReadFile(stored)
+		if err != nil {
+			return respond(&Response{Error: "no such object", Code: CodeNotFound})
+		}
+		_ = os.WriteFile(req.LocalPath, data, 0644)
+	case OperationYank:
+		_ = os.RemoveAll(stored)
+	case OperationExists:
+		return respond(&Response{Exists: statErr == nil})
+	}
+
+	return respond(&Response{})
+}
+
+func createTestExecBackend(t *testing.T) *ExecBackend {
+	t.Setenv("ARTIFACT_EXEC_TEST_STORE", t.TempDir())
+
+	execBackend, err := New(os.Args[0])
+	require.NoError(t, err)
+
+	return execBackend
+}
+
+func TestExecBackend_PushPullYank(t *testing.T) {
+	execBackend := createTestExecBackend(t)
+	ctx := context.Background()
+
+	localFile := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(localFile, []byte("hello"), 0644))
+
+	require.NoError(t, execBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+
+	exists, err := execBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	err = execBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+	assert.NoError(t, execBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{Force: true}))
+
+	pulled := filepath.Join(t.TempDir(), "pulled.txt")
+	require.NoError(t, execBackend.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, execBackend.Yank(ctx, "artifacts/jobs/1/a.txt"))
+	exists, err = execBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = execBackend.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestExecBackend_Errors(t *testing.T) {
+	execBackend := createTestExecBackend(t)
+	ctx := context.Background()
+
+	err := execBackend.Yank(ctx, "forbidden/a.txt")
+	var denied *backend.ErrPermissionDenied
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "read-only path", denied.Reason)
+
+	_, err = execBackend.Exists(ctx, "crash")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "storage exploded")
+
+	err = execBackend.Push(ctx, "a.txt", "artifacts/jobs/1/a.txt", backend.PushOptions{Lock: &backend.ObjectLock{LegalHold: true}})
+	assert.Equal(t, backend.ErrObjectLockNotSupported, err)
+
+	_, err = New(filepath.Join(t.TempDir(), "missing-plugin"))
+	assert.Error(t, err)
+}
@@ -10,8 +10,48 @@ import (
 //
 // For hub backend: requires SEMAPHORE_ARTIFACT_TOKEN and SEMAPHORE_ORGANIZATION_URL
 // For S3 backend: requires ARTIFACT_S3_BUCKET (and optional region, endpoint, etc.)
+// For HTTP backend: requires ARTIFACT_HTTP_URL (and optional auth header or token)
+// For WebHDFS backend: requires ARTIFACT_WEBHDFS_URL (and optional user or Kerberos settings)
+// For FTP backend: requires ARTIFACT_FTP_HOST (and optional credentials or TLS settings)
+// For rclone backend: requires ARTIFACT_RCLONE_REMOTE and an installed rclone binary
+// For IPFS backend: optional ARTIFACT_IPFS_URL (defaults to a local node) and manifest settings
+// For Artifactory backend: requires ARTIFACT_ARTIFACTORY_URL and ARTIFACT_ARTIFACTORY_REPOSITORY
+// For exec backend: requires ARTIFACT_BACKEND=exec:/path/to/plugin
+// For plugin backend: requires ARTIFACT_BACKEND=plugin:<name> (and optional ARTIFACT_PLUGIN_DIR)
+// For mirror backend: requires ARTIFACT_MIRROR_BACKENDS, e.g. hub,s3
+// For fallback backend: requires ARTIFACT_FALLBACK_BACKENDS, e.g. s3,hub
+//
+// If a cache is registered, it wraps the backend; see cachebackend.
+// In read-only mode, pushes and yanks are rejected; see IsReadOnly.
 func NewBackend() (Backend, error) {
-	backendType := GetBackendType()
+	b, err := NewBackendFor(GetBackendSetting())
+	if err != nil {
+		return nil, err
+	}
+
+	if wrapWithCache != nil {
+		wrapped, err := wrapWithCache(b)
+		if err != nil {
+			_ = b.Close()
+			return nil, err
+		}
+		b = wrapped
+	}
+
+	if IsReadOnly() {
+		b = NewReadOnly(b)
+	}
+
+	return b, nil
+}
+
+// NewBackendFor creates the backend for a setting such as "s3" or
+// "exec:/path/to/plugin". Backends wrapping others use it to create them.
+func NewBackendFor(setting string) (Backend, error) {
+	backendType, arg, ok := ParseBackendSetting(setting)
+	if !ok {
+		return nil, fmt.Errorf("unknown backend type: %s", setting)
+	}
 
 	switch backendType {
 	case BackendTypeHub:
@@ -26,6 +66,66 @@ func NewBackend() (Backend, error) {
 		}
 		return newS3Backend()
 
+	case BackendTypeHTTP:
+		if newHTTPBackend == nil {
+			return nil, fmt.Errorf("http backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/httpbackend is imported")
+		}
+		return newHTTPBackend()
+
+	case BackendTypeWebHDFS:
+		if newWebHDFSBackend == nil {
+			return nil, fmt.Errorf("webhdfs backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/webhdfsbackend is imported")
+		}
+		return newWebHDFSBackend()
+
+	case BackendTypeFTP:
+		if newFTPBackend == nil {
+			return nil, fmt.Errorf("ftp backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/ftpbackend is imported")
+		}
+		return newFTPBackend()
+
+	case BackendTypeRclone:
+		if newRcloneBackend == nil {
+			return nil, fmt.Errorf("rclone backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/rclonebackend is imported")
+		}
+		return newRcloneBackend()
+
+	case BackendTypeIPFS:
+		if newIPFSBackend == nil {
+			return nil, fmt.Errorf("ipfs backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/ipfsbackend is imported")
+		}
+		return newIPFSBackend()
+
+	case BackendTypeArtifactory:
+		if newArtifactoryBackend == nil {
+			return nil, fmt.Errorf("artifactory backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/artifactorybackend is imported")
+		}
+		return newArtifactoryBackend()
+
+	case BackendTypeExec:
+		if newExecBackend == nil {
+			return nil, fmt.Errorf("exec backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/execbackend is imported")
+		}
+		return newExecBackend(arg)
+
+	case BackendTypePlugin:
+		if newPluginBackend == nil {
+			return nil, fmt.Errorf("plugin backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/pluginbackend is imported")
+		}
+		return newPluginBackend(arg)
+
+	case BackendTypeMirror:
+		if newMirrorBackend == nil {
+			return nil, fmt.Errorf("mirror backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/mirrorbackend is imported")
+		}
+		return newMirrorBackend()
+
+	case BackendTypeFallback:
+		if newFallbackBackend == nil {
+			return nil, fmt.Errorf("fallback backend not registered - ensure github.com/semaphoreci/artifact/pkg/backend/mirrorbackend is imported")
+		}
+		return newFallbackBackend()
+
 	default:
 		return nil, fmt.Errorf("unknown backend type: %s", backendType)
 	}
@@ -34,6 +134,17 @@ func NewBackend() (Backend, error) {
 // These will be set by init() in the respective backend packages
 var newHubBackend func() (Backend, error)
 var newS3Backend func() (Backend, error)
+var newHTTPBackend func() (Backend, error)
+var newWebHDFSBackend func() (Backend, error)
+var newFTPBackend func() (Backend, error)
+var newRcloneBackend func() (Backend, error)
+var newIPFSBackend func() (Backend, error)
+var newArtifactoryBackend func() (Backend, error)
+var newExecBackend func(path string) (Backend, error)
+var newPluginBackend func(name string) (Backend, error)
+var newMirrorBackend func() (Backend, error)
+var newFallbackBackend func() (Backend, error)
+var wrapWithCache func(Backend) (Backend, error)
 
 // RegisterHubBackend registers the hub backend constructor.
 func RegisterHubBackend(fn func() (Backend, error)) {
@@ -44,3 +155,61 @@ func RegisterHubBackend(fn func() (Backend, error)) {
 func RegisterS3Backend(fn func() (Backend, error)) {
 	newS3Backend = fn
 }
+
+// RegisterHTTPBackend registers the HTTP backend constructor.
+func RegisterHTTPBackend(fn func() (Backend, error)) {
+	newHTTPBackend = fn
+}
+
+// RegisterWebHDFSBackend registers the WebHDFS backend constructor.
+func RegisterWebHDFSBackend(fn func() (Backend, error)) {
+	newWebHDFSBackend = fn
+}
+
+// RegisterFTPBackend registers the FTP backend constructor.
+func RegisterFTPBackend(fn func() (Backend, error)) {
+	newFTPBackend = fn
+}
+
+// RegisterRcloneBackend registers the rclone backend constructor.
+func RegisterRcloneBackend(fn func() (Backend, error)) {
+	newRcloneBackend = fn
+}
+
+// RegisterIPFSBackend registers the IPFS backend constructor.
+func RegisterIPFSBackend(fn func() (Backend, error)) {
+	newIPFSBackend = fn
+}
+
+// RegisterArtifactoryBackend registers the Artifactory backend constructor.
+func RegisterArtifactoryBackend(fn func() (Backend, error)) {
+	newArtifactoryBackend = fn
+}
+
+// RegisterExecBackend registers the exec plugin backend constructor,
+// which is passed the plugin executable.
+func RegisterExecBackend(fn func(path string) (Backend, error)) {
+	newExecBackend = fn
+}
+
+// RegisterPluginBackend registers the go-plugin backend constructor,
+// which is passed the plugin name.
+func RegisterPluginBackend(fn func(name string) (Backend, error)) {
+	newPluginBackend = fn
+}
+
+// RegisterMirrorBackend registers the mirror backend constructor.
+func RegisterMirrorBackend(fn func() (Backend, error)) {
+	newMirrorBackend = fn
+}
+
+// RegisterFallbackBackend registers the fallback backend constructor.
+func RegisterFallbackBackend(fn func() (Backend, error)) {
+	newFallbackBackend = fn
+}
+
+// RegisterCache registers a function wrapping backends created by NewBackend
+// with a local cache. It returns the backend unchanged if caching is disabled.
+func RegisterCache(fn func(Backend) (Backend, error)) {
+	wrapWithCache = fn
+}
@@ -0,0 +1,543 @@
+package ftpbackend
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterFTPBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// dialTimeout limits connecting to the server and every command sent to it.
+const dialTimeout = 30 * time.Second
+
+// conn is the subset of an FTP control connection used by the backend.
+type conn interface {
+	Stor(path string, r io.Reader) error
+	Retr(path string) (io.ReadCloser, error)
+	Delete(path string) error
+	MakeDir(path string) error
+	RemoveDir(path string) error
+	List(path string) ([]*ftp.Entry, error)
+	FileSize(path string) (int64, error)
+	Quit() error
+}
+
+// serverConn adapts ftp.ServerConn to conn.
+type serverConn struct {
+	*ftp.ServerConn
+}
+
+func (c serverConn) Retr(path string) (io.ReadCloser, error) {
+	return c.ServerConn.Retr(path)
+}
+
+// FTPBackend implements the Backend interface over a single FTP connection.
+// FTP runs one command at a time, so operations are serialized.
+type FTPBackend struct {
+	cfg *Config
+
+	mu   sync.Mutex
+	conn conn
+}
+
+// New creates a new FTPBackend instance from the environment/config file.
+func New() (*FTPBackend, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg)
+}
+
+// NewWithConfig connects and logs in to the server of a validated configuration.
+func NewWithConfig(cfg *Config) (*FTPBackend, error) {
+	options := []ftp.DialOption{ftp.DialWithTimeout(dialTimeout)}
+
+	tlsConfig := &tls.Config{
+		ServerName:         cfg.hostname(),
+		InsecureSkipVerify: cfg.TLSSkipVerify, // #nosec
+		MinVersion:         tls.VersionTLS12,
+	}
+
+	switch cfg.TLS {
+	case TLSExplicit:
+		options = append(options, ftp.DialWithExplicitTLS(tlsConfig))
+	case TLSImplicit:
+		options = append(options, ftp.DialWithTLS(tlsConfig))
+	}
+
+	user, password := cfg.User, cfg.Password
+	if cfg.CredentialHelper != nil {
+		credentials, err := cfg.CredentialHelper.Get(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		user, password = credentials.Username, credentials.Password
+	}
+	if user == "" {
+		user, password = "anonymous", "anonymous"
+	}
+
+	c, err := ftp.Dial(cfg.address(), options...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to FTP server '%s': %w", cfg.address(), err)
+	}
+
+	if err := c.Login(user, password); err != nil {
+		_ = c.Quit()
+		return nil, fmt.Errorf("failed to log in to FTP server '%s' as '%s': %w", cfg.address(), user, err)
+	}
+
+	log.Debug("FTPBackend: Client initialized\n")
+	log.Debugf("* Host: %s\n", cfg.address())
+	log.Debugf("* User: %s\n", user)
+	log.Debugf("* Prefix: %s\n", cfg.Prefix)
+	log.Debugf("* TLS: %s\n", cfg.TLS)
+
+	return newWithConn(cfg, serverConn{c}), nil
+}
+
+func newWithConn(cfg *Config, c conn) *FTPBackend {
+	return &FTPBackend{cfg: cfg, conn: c}
+}
+
+// Push uploads a local file or directory, one file at a time,
+// creating intermediate directories as needed.
+func (f *FTPBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("FTPBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		return f.pushFile(ctx, localPath, remotePath, opts)
+	}
+
+	return files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return f.pushFile(ctx, filePath, remotePath+"/"+filepath.ToSlash(relPath), opts)
+	})
+}
+
+func (f *FTPBackend) pushFile(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if !opts.Force {
+		exists, err := f.Exists(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &backend.ErrAlreadyExists{Path: remotePath}
+		}
+	}
+
+	checksum, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath) // #nosec
+	if err != nil {
+		return fmt.Errorf("failed to open local file '%s': %w", localPath, err)
+	}
+	defer file.Close()
+
+	if err := f.store(remotePath, file, "push"); err != nil {
+		return err
+	}
+
+	log.Debugf("Uploaded: %s -> %s\n", localPath, f.ftpPath(remotePath))
+
+	// Failing to store the checksum does not fail the push, as the file is already uploaded
+	sidecar := backend.ChecksumSidecarPath(remotePath)
+	if err := f.store(sidecar, bytes.NewReader([]byte(checksum)), "push"); err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+
+	return nil
+}
+
+// store uploads a file, creating its parent directories first.
+func (f *FTPBackend) store(remotePath string, r io.Reader, operation string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// Directories are created one level at a time, as FTP has no mkdir -p.
+	// Errors are ignored, since most of them are for existing directories;
+	// if a directory is really missing, the upload reports it.
+	dir := path.Dir(f.ftpPath(remotePath))
+	parts := strings.Split(strings.TrimPrefix(dir, "/"), "/")
+	for i := range parts {
+		if parts[i] == "" {
+			continue
+		}
+		_ = f.conn.MakeDir("/" + strings.Join(parts[:i+1], "/"))
+	}
+
+	return f.mapError(f.conn.Stor(f.ftpPath(remotePath), r), operation, remotePath)
+}
+
+// Pull downloads a file, or every file in a directory.
+func (f *FTPBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("FTPBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	exists, err := f.Exists(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if exists {
+		return f.pullFile(ctx, remotePath, localPath, opts)
+	}
+
+	found := false
+	dir := strings.TrimSuffix(remotePath, "/")
+	err = backend.Walk(ctx, f.readDir, dir, nil, func(info backend.ObjectInfo) error {
+		found = true
+		destPath := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(info.Path, dir+"/")))
+		return f.pullFile(ctx, info.Path, destPath, opts)
+	})
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) || (err == nil && !found) {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return err
+}
+
+func (f *FTPBackend) pullFile(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	body, err := f.Open(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	if err := files.WriteAtomically(localPath, body); err != nil {
+		return err
+	}
+
+	log.Debugf("Downloaded: %s -> %s\n", f.ftpPath(remotePath), localPath)
+	return nil
+}
+
+// Open streams the contents of a file. The connection is busy with the
+// transfer, so other operations wait until the returned reader is closed.
+func (f *FTPBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("FTPBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	f.mu.Lock()
+	body, err := f.conn.Retr(f.ftpPath(remotePath))
+	if err != nil {
+		f.mu.Unlock()
+		return nil, f.mapError(err, "pull", remotePath)
+	}
+
+	return &transfer{ReadCloser: body, unlock: f.mu.Unlock}, nil
+}
+
+// transfer releases the connection when the download is closed.
+type transfer struct {
+	io.ReadCloser
+	unlock func()
+	once   sync.Once
+}
+
+func (t *transfer) Close() error {
+	err := t.ReadCloser.Close()
+	t.once.Do(t.unlock)
+	return err
+}
+
+// Yank deletes a file or directory, along with its checksums, and removes
+// the directories left empty by it. Paths that do not exist are not an error.
+func (f *FTPBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("FTPBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	remotePath = strings.TrimSuffix(remotePath, "/")
+	if err := f.removeAll(ctx, remotePath); err != nil {
+		return err
+	}
+
+	for _, sidecar := range []string{backend.ChecksumSidecarPath(remotePath), backend.ChecksumSidecarPrefix(remotePath)} {
+		if err := f.removeAll(ctx, sidecar); err != nil {
+			log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+		}
+	}
+
+	f.removeEmptyParents(remotePath)
+	f.removeEmptyParents(backend.ChecksumSidecarPath(remotePath))
+	return nil
+}
+
+// removeAll deletes a file, or a directory and everything in it.
+func (f *FTPBackend) removeAll(ctx context.Context, remotePath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.removeAllLocked(ctx, remotePath)
+}
+
+func (f *FTPBackend) removeAllLocked(ctx context.Context, remotePath string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	err := f.conn.Delete(f.ftpPath(remotePath))
+	if err == nil {
+		return nil
+	}
+	if !isUnavailable(err) {
+		return f.mapError(err, "yank", remotePath)
+	}
+
+	// Not a file: either a directory, or nothing at all
+	entries, err := f.conn.List(f.ftpPath(remotePath))
+	if isUnavailable(err) {
+		return nil
+	}
+	if err != nil {
+		return f.mapError(err, "yank", remotePath)
+	}
+
+	for _, entry := range entries {
+		if entry.Name == "." || entry.Name == ".." {
+			continue
+		}
+		if err := f.removeAllLocked(ctx, remotePath+"/"+entry.Name); err != nil {
+			return err
+		}
+	}
+
+	err = f.conn.RemoveDir(f.ftpPath(remotePath))
+	if err != nil && !isUnavailable(err) {
+		return f.mapError(err, "yank", remotePath)
+	}
+
+	return nil
+}
+
+// removeEmptyParents removes the parent directories of remotePath, from the
+// deepest one up, until one is not empty. The prefix itself is kept.
+func (f *FTPBackend) removeEmptyParents(remotePath string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	for dir := path.Dir(remotePath); dir != "." && dir != "/"; dir = path.Dir(dir) {
+		if err := f.conn.RemoveDir(f.ftpPath(dir)); err != nil {
+			return
+		}
+
+		log.Debugf("Removed empty directory: %s\n", f.ftpPath(dir))
+	}
+}
+
+// Exists checks if a file exists.
+func (f *FTPBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("FTPBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	_, err := f.size(remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List lists the files under remotePrefix with one LIST command per
+// directory the walk reaches.
+func (f *FTPBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("FTPBackend: Listing...\n")
+	log.Debugf("* Prefix: %s\n", remotePrefix)
+
+	return backend.WalkPrefix(ctx, f.readDir, remotePrefix, fn)
+}
+
+// Checksum returns the checksum stored in the file's sidecar.
+func (f *FTPBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	exists, err := f.Exists(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sidecar, err := f.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// Close logs out and closes the connection.
+func (f *FTPBackend) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.conn.Quit()
+}
+
+// Helper functions
+
+// size returns the size of a file. Directories are reported as ErrNotFound.
+func (f *FTPBackend) size(remotePath string) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	size, err := f.conn.FileSize(f.ftpPath(remotePath))
+	if err != nil {
+		return 0, f.mapError(err, "pull", remotePath)
+	}
+
+	return size, nil
+}
+
+// readDir returns the entries of a directory, for backend.Walk.
+func (f *FTPBackend) readDir(ctx context.Context, dir string) ([]backend.DirEntry, error) {
+	f.mu.Lock()
+	entries, err := f.conn.List(f.ftpPath(dir))
+	f.mu.Unlock()
+	if err != nil {
+		return nil, f.mapError(err, "pull", dir)
+	}
+
+	result := []backend.DirEntry{}
+	for _, entry := range entries {
+		if entry.Name == "." || entry.Name == ".." {
+			continue
+		}
+
+		result = append(result, backend.DirEntry{
+			Name:  entry.Name,
+			IsDir: entry.Type == ftp.EntryTypeFolder,
+			Info: backend.ObjectInfo{
+				Size:    int64(entry.Size), // #nosec
+				ModTime: entry.Time,
+			},
+		})
+	}
+
+	return result, nil
+}
+
+// ftpPath returns the absolute server path of remotePath.
+func (f *FTPBackend) ftpPath(remotePath string) string {
+	return path.Join("/", f.cfg.Prefix, remotePath)
+}
+
+// mapError maps FTP replies to backend errors. Servers answer 550 for
+// missing files and for denied access alike, so 550 replies mentioning
+// permissions are reported as ErrPermissionDenied, and the rest as ErrNotFound.
+func (f *FTPBackend) mapError(err error, operation, remotePath string) error {
+	if err == nil {
+		return nil
+	}
+
+	var reply *textproto.Error
+	if !errors.As(err, &reply) {
+		return fmt.Errorf("failed to %s '%s': %w", operation, remotePath, err)
+	}
+
+	message := strings.ToLower(reply.Msg)
+	switch {
+	case reply.Code == ftp.StatusNotLoggedIn ||
+		(reply.Code == ftp.StatusFileUnavailable && (strings.Contains(message, "permission") || strings.Contains(message, "denied"))):
+		return &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: reply.Msg}
+	case reply.Code == ftp.StatusFileUnavailable:
+		return &backend.ErrNotFound{Path: remotePath}
+	default:
+		return fmt.Errorf("failed to %s '%s': FTP server replied %d %s", operation, remotePath, reply.Code, reply.Msg)
+	}
+}
+
+// isUnavailable returns true for 550 replies, which servers send for
+// missing paths, and for files that are not directories or vice versa.
+func isUnavailable(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code == ftp.StatusFileUnavailable
+}
@@ -0,0 +1,319 @@
+package ftpbackend
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/textproto"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConn keeps a file system in memory, and replies like an FTP server:
+// directories are created one level at a time, only empty directories can be
+// removed, and failures are 550 replies.
+type fakeConn struct {
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func newFakeConn() *fakeConn {
+	return &fakeConn{files: map[string][]byte{}, dirs: map[string]bool{"/": true}}
+}
+
+func unavailable(message string) error {
+	return &textproto.Error{Code: ftp.StatusFileUnavailable, Msg: message}
+}
+
+func (c *fakeConn) Stor(p string, r io.Reader) error {
+	if strings.HasPrefix(p, "/readonly/") {
+		return unavailable("Permission denied")
+	}
+	if !c.dirs[path.Dir(p)] {
+		return unavailable("No such file or directory")
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	c.files[p] = data
+	return nil
+}
+
+func (c *fakeConn) Retr(p string) (io.ReadCloser, error) {
+	data, ok := c.files[p]
+	if !ok {
+		return nil, unavailable("No such file or directory")
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (c *fakeConn) Delete(p string) error {
+	if _, ok := c.files[p]; !ok {
+		return unavailable("No such file or directory")
+	}
+
+	delete(c.files, p)
+	return nil
+}
+
+func (c *fakeConn) MakeDir(p string) error {
+	if c.dirs[p] || !c.dirs[path.Dir(p)] {
+		return unavailable("Cannot create directory")
+	}
+
+	c.dirs[p] = true
+	return nil
+}
+
+func (c *fakeConn) RemoveDir(p string) error {
+	if !c.dirs[p] || len(c.children(p)) > 0 {
+		return unavailable("Directory not empty")
+	}
+
+	delete(c.dirs, p)
+	return nil
+}
+
+func (c *fakeConn) List(p string) ([]*ftp.Entry, error) {
+	if !c.dirs[p] {
+		return nil, unavailable("No such file or directory")
+	}
+
+	entries := []*ftp.Entry{{Name: ".", Type: ftp.EntryTypeFolder}, {Name: "..", Type: ftp.EntryTypeFolder}}
+	for _, child := range c.children(p) {
+		entry := &ftp.Entry{Name: path.Base(child), Type: ftp.EntryTypeFolder, Time: time.Unix(1700000000, 0)}
+		if data, ok := c.files[child]; ok {
+			entry.Type = ftp.EntryTypeFile
+			entry.Size = uint64(len(data))
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (c *fakeConn) FileSize(p string) (int64, error) {
+	data, ok := c.files[p]
+	if !ok {
+		return 0, unavailable("Could not get file size")
+	}
+
+	return int64(len(data)), nil
+}
+
+func (c *fakeConn) Quit() error {
+	return nil
+}
+
+func (c *fakeConn) children(dir string) []string {
+	children := []string{}
+	for p := range c.files {
+		if path.Dir(p) == dir {
+			children = append(children, p)
+		}
+	}
+	for p := range c.dirs {
+		if p != "/" && path.Dir(p) == dir {
+			children = append(children, p)
+		}
+	}
+
+	sort.Strings(children)
+	return children
+}
+
+func (c *fakeConn) allDirs() []string {
+	dirs := []string{}
+	for dir := range c.dirs {
+		dirs = append(dirs, dir)
+	}
+
+	sort.Strings(dirs)
+	return dirs
+}
+
+func createTestBackend(t *testing.T, prefix string) (*FTPBackend, *fakeConn) {
+	c := newFakeConn()
+	if prefix != "" {
+		c.dirs["/"+prefix] = true
+	}
+
+	return newWithConn(&Config{Host: "ftp.example.com", Prefix: prefix}, c), c
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	return p
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("ARTIFACT_FTP_HOST", "ftp.example.com")
+	t.Setenv("ARTIFACT_FTP_TLS", "Explicit")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, TLSExplicit, cfg.TLS)
+	assert.Equal(t, "ftp.example.com:21", cfg.address())
+	assert.Equal(t, "ftp.example.com", cfg.hostname())
+
+	cfg.TLS = TLSImplicit
+	assert.Equal(t, "ftp.example.com:990", cfg.address())
+
+	cfg.Host = "ftp.example.com:2121"
+	assert.Equal(t, "ftp.example.com:2121", cfg.address())
+	assert.Equal(t, "ftp.example.com", cfg.hostname())
+
+	t.Setenv("ARTIFACT_FTP_TLS", "sometimes")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "invalid FTP TLS mode")
+
+	t.Setenv("ARTIFACT_FTP_HOST", "")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "ARTIFACT_FTP_HOST")
+}
+
+func TestFTPBackend_PushPull(t *testing.T) {
+	ftpBackend, c := createTestBackend(t, "drops")
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	// Intermediate directories are created
+	localFile := writeTestFile(t, dir, "a.txt", "hello")
+	require.NoError(t, ftpBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	assert.Equal(t, "hello", string(c.files["/drops/artifacts/jobs/1/a.txt"]))
+
+	exists, err := ftpBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = ftpBackend.Exists(ctx, "artifacts/jobs/1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = ftpBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+	assert.NoError(t, ftpBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{Force: true}))
+
+	checksum, err := ftpBackend.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", checksum)
+
+	pulled := filepath.Join(t.TempDir(), "pulled.txt")
+	require.NoError(t, ftpBackend.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	// Directories
+	localDir := filepath.Join(dir, "reports")
+	writeTestFile(t, localDir, "index.html", "<html>")
+	writeTestFile(t, localDir, "css/site.css", "body {}")
+	require.NoError(t, ftpBackend.Push(ctx, localDir, "artifacts/jobs/1/reports", backend.PushOptions{}))
+
+	pulledDir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, ftpBackend.Pull(ctx, "artifacts/jobs/1/reports", pulledDir, backend.PullOptions{}))
+	data, err = os.ReadFile(filepath.Join(pulledDir, "css", "site.css"))
+	require.NoError(t, err)
+	assert.Equal(t, "body {}", string(data))
+
+	err = ftpBackend.Pull(ctx, "artifacts/jobs/1/missing", pulled, backend.PullOptions{Force: true})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestFTPBackend_List(t *testing.T) {
+	ftpBackend, _ := createTestBackend(t, "")
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for _, name := range []string{"a/b.txt", "a-b.txt", "a/c/d.txt", "e.txt"} {
+		localFile := writeTestFile(t, dir, name, name)
+		require.NoError(t, ftpBackend.Push(ctx, localFile, "artifacts/jobs/1/"+name, backend.PushOptions{}))
+	}
+
+	paths := []string{}
+	err := ftpBackend.List(ctx, "artifacts/jobs/1/a", func(info backend.ObjectInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"artifacts/jobs/1/a-b.txt", "artifacts/jobs/1/a/b.txt", "artifacts/jobs/1/a/c/d.txt"}, paths)
+
+	paths = []string{}
+	err = ftpBackend.List(ctx, "artifacts/jobs/2/", func(info backend.ObjectInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Empty(t, paths)
+}
+
+func TestFTPBackend_Yank(t *testing.T) {
+	ftpBackend, c := createTestBackend(t, "drops")
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	for _, name := range []string{"a.txt", "reports/index.html", "reports/css/site.css"} {
+		localFile := writeTestFile(t, dir, name, name)
+		require.NoError(t, ftpBackend.Push(ctx, localFile, "artifacts/jobs/1/"+name, backend.PushOptions{}))
+	}
+
+	// Directories still holding files are kept
+	require.NoError(t, ftpBackend.Yank(ctx, "artifacts/jobs/1/reports"))
+	exists, err := ftpBackend.Exists(ctx, "artifacts/jobs/1/reports/index.html")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, []string{
+		"/", "/drops", "/drops/artifacts", "/drops/artifacts/jobs", "/drops/artifacts/jobs/1", "/drops/artifacts/jobs/1/.checksums",
+	}, c.allDirs())
+
+	// Empty directories are removed, up to the prefix
+	require.NoError(t, ftpBackend.Yank(ctx, "artifacts/jobs/1/a.txt"))
+	assert.Empty(t, c.files)
+	assert.Equal(t, []string{"/", "/drops"}, c.allDirs())
+
+	assert.NoError(t, ftpBackend.Yank(ctx, "artifacts/jobs/1/missing"))
+}
+
+func TestFTPBackend_Errors(t *testing.T) {
+	ftpBackend, _ := createTestBackend(t, "")
+	ctx := context.Background()
+	localFile := writeTestFile(t, t.TempDir(), "a.txt", "hello")
+
+	err := ftpBackend.Push(ctx, localFile, "readonly/a.txt", backend.PushOptions{})
+	var denied *backend.ErrPermissionDenied
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "Permission denied", denied.Reason)
+
+	err = ftpBackend.Push(ctx, localFile, "a.txt", backend.PushOptions{Lock: &backend.ObjectLock{LegalHold: true}})
+	assert.Equal(t, backend.ErrObjectLockNotSupported, err)
+
+	_, err = ftpBackend.Checksum(ctx, "missing.txt")
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	_, err = ftpBackend.Open(ctx, "missing.txt")
+	assert.ErrorAs(t, err, &notFound)
+
+	// A failed Open releases the connection
+	exists, err := ftpBackend.Exists(ctx, "missing.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
@@ -0,0 +1,135 @@
+// Package ftpbackend implements the Backend interface over FTP, optionally
+// secured with TLS (FTPS), for legacy artifact drops that partner systems
+// still consume via FTP.
+package ftpbackend
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/spf13/viper"
+)
+
+// TLS modes.
+const (
+	TLSNone     = ""         // plain FTP
+	TLSExplicit = "explicit" // AUTH TLS on the regular port
+	TLSImplicit = "implicit" // TLS from the start, usually on port 990
+)
+
+// Config holds FTP backend configuration.
+type Config struct {
+	// Host is the server address (required), with an optional port, e.g. ftp.example.com:21
+	Host string
+
+	// User and Password log in to the server. Without a user, anonymous is used.
+	User     string
+	Password string
+
+	// Prefix is the directory artifacts are stored under, / by default
+	Prefix string
+
+	// TLS is TLSNone, TLSExplicit or TLSImplicit
+	TLS string
+
+	// TLSSkipVerify accepts any server certificate, e.g. self-signed ones
+	TLSSkipVerify bool
+
+	// CredentialHelper provides the user and password instead of User
+	// and Password, if configured
+	CredentialHelper *backend.CredentialHelper
+}
+
+// LoadConfig loads FTP configuration from environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_FTP_HOST (required)
+//   - ARTIFACT_FTP_USER, ARTIFACT_FTP_PASSWORD (optional)
+//   - ARTIFACT_FTP_PREFIX (optional)
+//   - ARTIFACT_FTP_TLS (optional, explicit or implicit)
+//   - ARTIFACT_FTP_TLS_SKIP_VERIFY (optional, "true" to enable)
+//   - ARTIFACT_CREDENTIAL_HELPER (optional, see backend.CredentialHelper)
+//
+// Config file keys (under 'ftp' section):
+//   - host, user, password, prefix, tls, tlsSkipVerify
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// Load from environment variables first
+	cfg.Host = os.Getenv("ARTIFACT_FTP_HOST")
+	cfg.User = os.Getenv("ARTIFACT_FTP_USER")
+	cfg.Password = os.Getenv("ARTIFACT_FTP_PASSWORD")
+	cfg.Prefix = os.Getenv("ARTIFACT_FTP_PREFIX")
+	cfg.TLS = os.Getenv("ARTIFACT_FTP_TLS")
+	cfg.TLSSkipVerify = os.Getenv("ARTIFACT_FTP_TLS_SKIP_VERIFY") == "true"
+
+	// Fall back to config file for unset values
+	if cfg.Host == "" {
+		cfg.Host = viper.GetString("ftp.host")
+	}
+	if cfg.User == "" {
+		cfg.User = viper.GetString("ftp.user")
+	}
+	if cfg.Password == "" {
+		cfg.Password = viper.GetString("ftp.password")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = viper.GetString("ftp.prefix")
+	}
+	if cfg.TLS == "" {
+		cfg.TLS = viper.GetString("ftp.tls")
+	}
+	if !cfg.TLSSkipVerify {
+		cfg.TLSSkipVerify = viper.GetBool("ftp.tlsSkipVerify")
+	}
+
+	cfg.TLS = strings.ToLower(cfg.TLS)
+	cfg.CredentialHelper = backend.NewCredentialHelper(backend.BackendTypeFTP)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	if c.Host == "" {
+		return fmt.Errorf("FTP host is required: set ARTIFACT_FTP_HOST environment variable or ftp.host in config")
+	}
+
+	switch c.TLS {
+	case TLSNone, TLSExplicit, TLSImplicit:
+	default:
+		return fmt.Errorf("invalid FTP TLS mode '%s': use %s or %s", c.TLS, TLSExplicit, TLSImplicit)
+	}
+
+	return nil
+}
+
+// address returns Host with the default port of the TLS mode, if it has none.
+func (c *Config) address() string {
+	if _, _, err := net.SplitHostPort(c.Host); err == nil {
+		return c.Host
+	}
+
+	if c.TLS == TLSImplicit {
+		return net.JoinHostPort(c.Host, "990")
+	}
+
+	return net.JoinHostPort(c.Host, "21")
+}
+
+// hostname returns Host without its port, for verifying certificates.
+func (c *Config) hostname() string {
+	if host, _, err := net.SplitHostPort(c.Host); err == nil {
+		return host
+	}
+
+	return c.Host
+}
@@ -0,0 +1,72 @@
+package backend
+
+import (
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+)
+
+// Headers are the HTTP headers pushed files are served with, e.g. from
+// the bucket or with presigned URLs, so HTML reports and images render
+// in the browser.
+type Headers struct {
+	ContentType        string // Detected from each file when empty
+	CacheControl       string // e.g. "max-age=3600", empty for none
+	ContentDisposition string // e.g. "attachment", empty for none
+}
+
+// Set sets the headers that are not empty on header.
+func (h Headers) Set(header http.Header) {
+	for name, value := range map[string]string{
+		"Content-Type":        h.ContentType,
+		"Cache-Control":       h.CacheControl,
+		"Content-Disposition": h.ContentDisposition,
+	} {
+		if value != "" {
+			header.Set(name, value)
+		}
+	}
+}
+
+// ContentType returns the MIME type of the file name from its extension,
+// or failing that, from head, its first bytes. Only the first 512 bytes
+// of head are considered.
+func ContentType(name string, head []byte) string {
+	if contentType := mime.TypeByExtension(path.Ext(name)); contentType != "" {
+		return contentType
+	}
+
+	return http.DetectContentType(head)
+}
+
+// FileContentType returns the MIME type of the local file at localPath,
+// see ContentType.
+func FileContentType(localPath string) (string, error) {
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+
+	return ContentType(localPath, head[:n]), nil
+}
+
+// WithContentType returns the headers with the content type detected
+// from the local file at localPath, unless one is set.
+func (h Headers) WithContentType(localPath string) Headers {
+	if h.ContentType != "" {
+		return h
+	}
+
+	// Files that cannot be read fail their upload anyway
+	h.ContentType, _ = FileContentType(localPath)
+	return h
+}
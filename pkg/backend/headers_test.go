@@ -0,0 +1,38 @@
+package backend
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestContentType(t *testing.T) {
+	assert.Equal(t, "text/html; charset=utf-8", ContentType("report/index.html", nil))
+	assert.Equal(t, "image/png", ContentType("screenshot.png", nil))
+
+	// Files without a known extension are sniffed
+	assert.Equal(t, "image/png", ContentType("screenshot", []byte("\x89PNG\r\n\x1a\n")))
+	assert.Equal(t, "text/plain; charset=utf-8", ContentType("build", []byte("step 1 of 3\n")))
+}
+
+func TestHeadersWithContentType(t *testing.T) {
+	dir := t.TempDir()
+	page := filepath.Join(dir, "index")
+	require.NoError(t, os.WriteFile(page, []byte("<!DOCTYPE html><html></html>"), 0644))
+
+	assert.Equal(t, "text/html; charset=utf-8", Headers{}.WithContentType(page).ContentType)
+	assert.Equal(t, "text/plain", Headers{ContentType: "text/plain"}.WithContentType(page).ContentType)
+}
+
+func TestHeadersSet(t *testing.T) {
+	header := http.Header{}
+	Headers{ContentType: "text/html", CacheControl: "max-age=60"}.Set(header)
+
+	assert.Equal(t, "text/html", header.Get("Content-Type"))
+	assert.Equal(t, "max-age=60", header.Get("Cache-Control"))
+	assert.NotContains(t, header, "Content-Disposition")
+}
@@ -0,0 +1,542 @@
+package httpbackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterHTTPBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// HTTPBackend implements the Backend interface against a generic HTTP server.
+type HTTPBackend struct {
+	client *retryablehttp.Client
+	cfg    *Config
+}
+
+// New creates a new HTTPBackend instance from the environment/config file.
+func New() (*HTTPBackend, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg), nil
+}
+
+// NewWithConfig creates a new HTTPBackend instance for a validated configuration.
+func NewWithConfig(cfg *Config) *HTTPBackend {
+	client := retryablehttp.NewClient()
+	client.Logger = nil
+	retry.Load().Configure(client)
+
+	log.Debug("HTTPBackend: Client initialized\n")
+	log.Debugf("* URL: %s\n", cfg.URL)
+
+	return &HTTPBackend{client: client, cfg: cfg}
+}
+
+// Push uploads a local file or directory with one PUT request per file.
+func (h *HTTPBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("HTTPBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		return h.pushFile(ctx, localPath, remotePath, opts)
+	}
+
+	return files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return h.pushFile(ctx, filePath, remotePath+"/"+filepath.ToSlash(relPath), opts)
+	})
+}
+
+func (h *HTTPBackend) pushFile(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if err := h.checkNotExists(ctx, remotePath, opts); err != nil {
+		return err
+	}
+
+	checksum, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to open local file '%s': %w", localPath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", localPath, err)
+	}
+
+	// Empty files need a nil body, or they are sent with chunked encoding
+	var body interface{} = file
+	if info.Size() == 0 {
+		body = nil
+	}
+
+	req, err := h.newRequest(ctx, http.MethodPut, remotePath, body)
+	if err != nil {
+		return err
+	}
+
+	req.ContentLength = info.Size()
+	opts.Headers.WithContentType(localPath).Set(req.Header)
+
+	response, err := h.do(req, "push", remotePath)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	log.Debugf("Uploaded: %s -> %s\n", localPath, h.url(remotePath))
+	h.storeChecksum(ctx, remotePath, checksum)
+	return nil
+}
+
+// PushStream uploads a stream with a single PUT request. Streams of unknown
+// size are sent with chunked transfer encoding.
+func (h *HTTPBackend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	log.Debug("HTTPBackend: Pushing stream...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Size: %d\n", size)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	if err := h.checkNotExists(ctx, remotePath, opts); err != nil {
+		return err
+	}
+
+	hash := sha256.New()
+	body := io.TeeReader(r, hash)
+	if size == 0 {
+		body = nil
+	}
+
+	// A stream cannot be replayed, so it is sent without retries.
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, h.url(remotePath), body)
+	if err != nil {
+		return fmt.Errorf("failed to create new http request: %w", err)
+	}
+
+	req.ContentLength = size
+	opts.Headers.Set(req.Header)
+	if err := h.authorize(ctx, req.Header); err != nil {
+		return err
+	}
+
+	response, err := h.client.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to execute http request: %w", err)
+	}
+	if err := h.check(response, "push", remotePath); err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	h.storeChecksum(ctx, remotePath, hex.EncodeToString(hash.Sum(nil)))
+	return nil
+}
+
+// Pull downloads a file with a GET request. Plain HTTP servers offer
+// no listing, so directories cannot be pulled.
+func (h *HTTPBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("HTTPBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	body, err := h.Open(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	if err := files.WriteAtomically(localPath, body); err != nil {
+		return err
+	}
+
+	log.Debugf("Downloaded: %s -> %s\n", h.url(remotePath), localPath)
+	return nil
+}
+
+// Open streams the contents of a file with a GET request.
+func (h *HTTPBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("HTTPBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	req, err := h.newRequest(ctx, http.MethodGet, remotePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := h.do(req, "pull", remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// OpenRange streams a file from offset on with a Range request. Servers
+// that ignore the range send the whole file, and the first offset bytes
+// are skipped.
+func (h *HTTPBackend) OpenRange(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error) {
+	log.Debug("HTTPBackend: Opening range...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Offset: %d\n", offset)
+
+	if offset == 0 {
+		return h.Open(ctx, remotePath)
+	}
+
+	req, err := h.newRequest(ctx, http.MethodGet, remotePath, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+
+	response, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request to %s failed: %w", req.Method, req.URL, err)
+	}
+
+	// The offset is at or past the end of the file
+	if response.StatusCode == http.StatusRequestedRangeNotSatisfiable {
+		response.Body.Close()
+		return io.NopCloser(strings.NewReader("")), nil
+	}
+
+	if err := h.check(response, "pull", remotePath); err != nil {
+		return nil, err
+	}
+
+	if response.StatusCode != http.StatusPartialContent {
+		if err := backend.SkipBytes(response.Body, offset); err != nil {
+			response.Body.Close()
+			return nil, err
+		}
+	}
+
+	return response.Body, nil
+}
+
+// Yank deletes a file along with its checksum sidecar. Whether directories
+// can be deleted depends on the server, e.g. Artifactory deletes them recursively.
+func (h *HTTPBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("HTTPBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	if err := h.delete(ctx, remotePath); err != nil {
+		return err
+	}
+
+	if err := h.delete(ctx, backend.ChecksumSidecarPath(remotePath)); err != nil {
+		log.Warnf("Failed to remove checksum of '%s': %v\n", remotePath, err)
+	}
+
+	return nil
+}
+
+// delete sends a DELETE request. Files that do not exist are not an error.
+func (h *HTTPBackend) delete(ctx context.Context, remotePath string) error {
+	req, err := h.newRequest(ctx, http.MethodDelete, remotePath, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := h.do(req, "yank", remotePath)
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	return nil
+}
+
+// Exists checks if a file exists with a HEAD request.
+func (h *HTTPBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("HTTPBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	req, err := h.newRequest(ctx, http.MethodHead, remotePath, nil)
+	if err != nil {
+		return false, err
+	}
+
+	response, err := h.do(req, "exists", remotePath)
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	response.Body.Close()
+	return true, nil
+}
+
+// Stat describes a file with a HEAD request, from its Content-Length,
+// Last-Modified, ETag and Content-Type headers.
+func (h *HTTPBackend) Stat(ctx context.Context, remotePath string) (*backend.ObjectInfo, error) {
+	log.Debug("HTTPBackend: Stat...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	req, err := h.newRequest(ctx, http.MethodHead, remotePath, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := h.do(req, "pull", remotePath)
+	if err != nil {
+		return nil, err
+	}
+	response.Body.Close()
+
+	// Servers that do not send Last-Modified leave ModTime unset
+	modTime, _ := http.ParseTime(response.Header.Get("Last-Modified"))
+
+	return &backend.ObjectInfo{
+		Path:        remotePath,
+		Size:        response.ContentLength,
+		ModTime:     modTime,
+		ETag:        strings.Trim(response.Header.Get("ETag"), `"`),
+		ContentType: response.Header.Get("Content-Type"),
+	}, nil
+}
+
+// Checksum returns the checksum stored in the file's sidecar.
+func (h *HTTPBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	exists, err := h.Exists(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sidecar, err := h.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// Close releases resources. For HTTP backend, this is a no-op.
+func (h *HTTPBackend) Close() error {
+	return nil
+}
+
+// Helper functions
+
+// checkNotExists returns ErrAlreadyExists if remotePath exists, unless forced.
+func (h *HTTPBackend) checkNotExists(ctx context.Context, remotePath string, opts backend.PushOptions) error {
+	if opts.Force {
+		return nil
+	}
+
+	exists, err := h.Exists(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	}
+
+	return nil
+}
+
+// storeChecksum uploads the checksum sidecar of remotePath.
+// Failing to store it does not fail the push, as the file is already uploaded.
+func (h *HTTPBackend) storeChecksum(ctx context.Context, remotePath, checksum string) {
+	sidecar := backend.ChecksumSidecarPath(remotePath)
+
+	req, err := h.newRequest(ctx, http.MethodPut, sidecar, []byte(checksum))
+	if err == nil {
+		var response *http.Response
+		response, err = h.do(req, "push", sidecar)
+		if err == nil {
+			response.Body.Close()
+		}
+	}
+
+	if err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+}
+
+// url returns the URL of remotePath under the base URL.
+func (h *HTTPBackend) url(remotePath string) string {
+	segments := strings.Split(strings.Trim(remotePath, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	return strings.TrimSuffix(h.cfg.URL, "/") + "/" + strings.Join(segments, "/")
+}
+
+func (h *HTTPBackend) newRequest(ctx context.Context, method, remotePath string, body interface{}) (*retryablehttp.Request, error) {
+	req, err := retryablehttp.NewRequestWithContext(ctx, method, h.url(remotePath), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new http request: %w", err)
+	}
+
+	if err := h.authorize(ctx, req.Header); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// authorize adds the configured credentials to a request. A token from
+// the credential helper takes precedence over the configured one.
+func (h *HTTPBackend) authorize(ctx context.Context, header http.Header) error {
+	token := h.cfg.Token
+	if h.cfg.CredentialHelper != nil {
+		credentials, err := h.cfg.CredentialHelper.Get(ctx)
+		if err != nil {
+			return err
+		}
+		if credentials.Token != "" {
+			token = credentials.Token
+		}
+	}
+
+	if token != "" {
+		header.Set("Authorization", "Bearer "+token)
+	}
+
+	if name, value := h.cfg.authHeader(); name != "" {
+		header.Set(name, value)
+	}
+
+	return nil
+}
+
+// do executes a request, mapping error responses to backend errors.
+// The caller must close the body of the returned response.
+func (h *HTTPBackend) do(req *retryablehttp.Request, operation, remotePath string) (*http.Response, error) {
+	log.Debugf("%s '%s'...\n", req.Method, req.URL)
+
+	response, err := h.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request to %s failed: %w", req.Method, req.URL, err)
+	}
+
+	if err := h.check(response, operation, remotePath); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// check closes unsuccessful responses and maps them to backend errors.
+func (h *HTTPBackend) check(response *http.Response, operation, remotePath string) error {
+	log.Debugf("%s request got %d response.\n", response.Request.Method, response.StatusCode)
+	if common.IsStatusOK(response.StatusCode) {
+		return nil
+	}
+
+	// #nosec
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+
+	switch response.StatusCode {
+	case http.StatusNotFound:
+		return &backend.ErrNotFound{Path: remotePath}
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: response.Status}
+	default:
+		return fmt.Errorf("%s request to %s failed with %d status code: %s",
+			response.Request.Method, response.Request.URL, response.StatusCode, strings.TrimSpace(string(body)))
+	}
+}
@@ -0,0 +1,293 @@
+package httpbackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fileServer is a minimal blob store accepting PUT, GET, HEAD and DELETE.
+type fileServer struct {
+	mu      sync.Mutex
+	files   map[string][]byte
+	headers []http.Header
+}
+
+func (s *fileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.headers = append(s.headers, r.Header.Clone())
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	key := strings.TrimPrefix(r.URL.Path, "/repo/")
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		s.files[key] = data
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		data, ok := s.files[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write(data)
+	case http.MethodDelete:
+		if _, ok := s.files[key]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(s.files, key)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func createTestHTTPBackend(t *testing.T) (*HTTPBackend, *fileServer) {
+	files := &fileServer{files: map[string][]byte{}}
+	server := httptest.NewServer(files)
+	t.Cleanup(server.Close)
+
+	cfg := &Config{URL: server.URL + "/repo/", Token: "secret", AuthHeader: "X-Api-Key: key"}
+	require.NoError(t, cfg.Validate())
+
+	return NewWithConfig(cfg), files
+}
+
+func TestHTTPBackend_PushPullYank(t *testing.T) {
+	httpBackend, files := createTestHTTPBackend(t)
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "data", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data", "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data", "sub", "empty.txt"), []byte{}, 0644))
+
+	err := httpBackend.Push(ctx, filepath.Join(tmpDir, "data"), "artifacts/jobs/1/data", backend.PushOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(files.files["artifacts/jobs/1/data/a.txt"]))
+	assert.Contains(t, files.files, "artifacts/jobs/1/data/sub/empty.txt")
+	assert.Equal(t, "key", files.headers[0].Get("X-Api-Key"))
+
+	err = httpBackend.Push(ctx, filepath.Join(tmpDir, "data", "a.txt"), "artifacts/jobs/1/data/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	sum := sha256.Sum256([]byte("hello"))
+	checksum, err := httpBackend.Checksum(ctx, "artifacts/jobs/1/data/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, hex.EncodeToString(sum[:]), checksum)
+
+	localPath := filepath.Join(tmpDir, "pulled", "a.txt")
+	require.NoError(t, httpBackend.Pull(ctx, "artifacts/jobs/1/data/a.txt", localPath, backend.PullOptions{}))
+	data, err := os.ReadFile(localPath)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	err = httpBackend.Pull(ctx, "artifacts/jobs/1/missing.txt", filepath.Join(tmpDir, "missing.txt"), backend.PullOptions{})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	require.NoError(t, httpBackend.Yank(ctx, "artifacts/jobs/1/data/a.txt"))
+	exists, err := httpBackend.Exists(ctx, "artifacts/jobs/1/data/a.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+	assert.NotContains(t, files.files, backend.ChecksumSidecarPath("artifacts/jobs/1/data/a.txt"))
+}
+
+func TestHTTPBackend_PushStream(t *testing.T) {
+	httpBackend, files := createTestHTTPBackend(t)
+	ctx := context.Background()
+
+	err := httpBackend.PushStream(ctx, strings.NewReader("streamed"), -1, "artifacts/jobs/1/s.txt", backend.PushOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", string(files.files["artifacts/jobs/1/s.txt"]))
+
+	sum := sha256.Sum256([]byte("streamed"))
+	assert.Equal(t, hex.EncodeToString(sum[:]), string(files.files[backend.ChecksumSidecarPath("artifacts/jobs/1/s.txt")]))
+}
+
+func TestHTTPBackend_OpenRange(t *testing.T) {
+	httpBackend, files := createTestHTTPBackend(t)
+	ctx := context.Background()
+	files.files["artifacts/jobs/1/build.log"] = []byte("line 1\nline 2\n")
+
+	// The test server ignores ranges, so the skipped bytes are discarded
+	r, err := httpBackend.OpenRange(ctx, "artifacts/jobs/1/build.log", 7)
+	require.NoError(t, err)
+	data, err := io.ReadAll(r)
+	require.NoError(t, err)
+	r.Close()
+
+	assert.Equal(t, "line 2\n", string(data))
+	assert.Equal(t, "bytes=7-", files.headers[len(files.headers)-1].Get("Range"))
+
+	_, err = httpBackend.OpenRange(ctx, "artifacts/jobs/1/missing.log", 7)
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestHTTPBackend_PullTruncated(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "100")
+		_, _ = w.Write([]byte("trunc"))
+	}))
+	t.Cleanup(server.Close)
+
+	cfg := &Config{URL: server.URL + "/repo/"}
+	require.NoError(t, cfg.Validate())
+	httpBackend := NewWithConfig(cfg)
+
+	// Interrupted downloads leave neither a truncated file nor a partial one
+	localPath := filepath.Join(t.TempDir(), "a.txt")
+	err := httpBackend.Pull(context.Background(), "artifacts/jobs/1/a.txt", localPath, backend.PullOptions{})
+	assert.Error(t, err)
+	assert.NoFileExists(t, localPath)
+	assert.NoFileExists(t, localPath+files.PartialSuffix)
+}
+
+func TestHTTPBackend_PermissionDenied(t *testing.T) {
+	httpBackend, _ := createTestHTTPBackend(t)
+	httpBackend.cfg.Token = "wrong"
+
+	_, err := httpBackend.Exists(context.Background(), "artifacts/jobs/1/a.txt")
+	var denied *backend.ErrPermissionDenied
+	assert.ErrorAs(t, err, &denied)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{URL: "ftp://example.com"}).Validate())
+	assert.Error(t, (&Config{URL: "https://example.com", AuthHeader: "no-colon"}).Validate())
+	assert.NoError(t, (&Config{URL: "https://example.com", AuthHeader: "X-Api-Key: key"}).Validate())
+}
+
+func TestHTTPBackend_CredentialHelper(t *testing.T) {
+	helper := filepath.Join(t.TempDir(), "helper")
+	require.NoError(t, os.WriteFile(helper, []byte("#!/bin/sh\necho '{\"token\":\"secret\"}'\n"), 0755))
+	t.Setenv("ARTIFACT_CREDENTIAL_HELPER", helper)
+	t.Setenv("ARTIFACT_HTTP_TOKEN", "expired")
+
+	files := &fileServer{files: map[string][]byte{}}
+	server := httptest.NewServer(files)
+	t.Cleanup(server.Close)
+	t.Setenv("ARTIFACT_HTTP_URL", server.URL+"/repo/")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	require.NotNil(t, cfg.CredentialHelper)
+
+	exists, err := NewWithConfig(cfg).Exists(context.Background(), "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+// zeroServer is a blob store for large files of zeros: it only keeps the
+// size of files larger than a sidecar, and serves them as zeros.
+type zeroServer struct {
+	mu    sync.Mutex
+	files map[string][]byte
+	sizes map[string]int64
+}
+
+func (s *zeroServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	key := strings.TrimPrefix(r.URL.Path, "/repo/")
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(io.LimitReader(r.Body, 1024))
+		n, _ := io.Copy(io.Discard, r.Body)
+
+		s.mu.Lock()
+		if n == 0 {
+			s.files[key] = data
+		} else {
+			s.sizes[key] = int64(len(data)) + n
+		}
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		s.mu.Lock()
+		data, small := s.files[key]
+		size, large := s.sizes[key]
+		s.mu.Unlock()
+
+		switch {
+		case small:
+			_, _ = w.Write(data)
+		case large:
+			w.Header().Set("Content-Length", fmt.Sprint(size))
+			if r.Method == http.MethodGet {
+				_, _ = io.CopyN(w, zeroReader{}, size)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestHTTPBackend_LargeFileMemory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("pushes and pulls a 2 GB file")
+	}
+
+	server := httptest.NewServer(&zeroServer{files: map[string][]byte{}, sizes: map[string]int64{}})
+	t.Cleanup(server.Close)
+
+	cfg := &Config{URL: server.URL + "/repo/"}
+	require.NoError(t, cfg.Validate())
+	httpBackend := NewWithConfig(cfg)
+	ctx := context.Background()
+
+	// A sparse file takes no space on disk
+	const size = 2 << 30
+	localPath := filepath.Join(t.TempDir(), "large.bin")
+	f, err := os.Create(localPath)
+	require.NoError(t, err)
+	require.NoError(t, f.Truncate(size))
+	require.NoError(t, f.Close())
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	require.NoError(t, httpBackend.Push(ctx, localPath, "artifacts/jobs/1/large.bin", backend.PushOptions{}))
+
+	pulledPath := filepath.Join(t.TempDir(), "large.bin")
+	require.NoError(t, httpBackend.Pull(ctx, "artifacts/jobs/1/large.bin", pulledPath, backend.PullOptions{}))
+
+	runtime.ReadMemStats(&after)
+
+	info, err := os.Stat(pulledPath)
+	require.NoError(t, err)
+	assert.Equal(t, int64(size), info.Size())
+
+	// Both the client and the server stream the file: what is allocated
+	// over the whole transfer does not grow with its size
+	allocated := after.TotalAlloc - before.TotalAlloc
+	assert.Less(t, allocated, uint64(64<<20), "allocated %d bytes to transfer a %d bytes file", allocated, size)
+}
@@ -0,0 +1,102 @@
+// Package httpbackend implements the Backend interface with plain HTTP
+// requests against a base URL: files are uploaded with PUT, downloaded
+// with GET, checked with HEAD and removed with DELETE. This covers generic
+// Artifactory repositories, Nexus raw repositories and internal blob
+// services without a dedicated backend for each.
+package httpbackend
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/spf13/viper"
+)
+
+// Config holds HTTP backend configuration.
+type Config struct {
+	// URL is the base URL artifacts are stored under (required),
+	// e.g. https://artifactory.example.com/artifactory/generic-local
+	URL string
+
+	// AuthHeader is an extra header sent with every request, as "Name: value",
+	// e.g. "X-JFrog-Art-Api: <key>"
+	AuthHeader string
+
+	// Token is sent as a bearer token in the Authorization header
+	Token string
+
+	// CredentialHelper provides the token instead of Token, if configured
+	CredentialHelper *backend.CredentialHelper
+}
+
+// LoadConfig loads HTTP configuration from environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_HTTP_URL (required)
+//   - ARTIFACT_HTTP_AUTH_HEADER (optional)
+//   - ARTIFACT_HTTP_TOKEN (optional)
+//   - ARTIFACT_CREDENTIAL_HELPER (optional, see backend.CredentialHelper)
+//
+// Config file keys (under 'http' section):
+//   - url, authHeader, token
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// Load from environment variables first
+	cfg.URL = os.Getenv("ARTIFACT_HTTP_URL")
+	cfg.AuthHeader = os.Getenv("ARTIFACT_HTTP_AUTH_HEADER")
+	cfg.Token = os.Getenv("ARTIFACT_HTTP_TOKEN")
+
+	// Fall back to config file for unset values
+	if cfg.URL == "" {
+		cfg.URL = viper.GetString("http.url")
+	}
+	if cfg.AuthHeader == "" {
+		cfg.AuthHeader = viper.GetString("http.authHeader")
+	}
+	if cfg.Token == "" {
+		cfg.Token = viper.GetString("http.token")
+	}
+
+	cfg.CredentialHelper = backend.NewCredentialHelper(backend.BackendTypeHTTP)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("HTTP base URL is required: set ARTIFACT_HTTP_URL environment variable or http.url in config")
+	}
+
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid HTTP base URL '%s': use an http or https URL", c.URL)
+	}
+
+	if c.AuthHeader != "" {
+		if name, _ := c.authHeader(); name == "" {
+			return fmt.Errorf("invalid HTTP auth header: use 'Name: value'")
+		}
+	}
+
+	return nil
+}
+
+// authHeader splits AuthHeader into its name and value.
+func (c *Config) authHeader() (string, string) {
+	parts := strings.SplitN(c.AuthHeader, ":", 2)
+	if len(parts) != 2 {
+		return "", ""
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+}
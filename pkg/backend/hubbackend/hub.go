@@ -5,15 +5,28 @@ package hubbackend
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"hash"
+	"io"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/hashicorp/go-retryablehttp"
 	"github.com/semaphoreci/artifact/pkg/api"
 	"github.com/semaphoreci/artifact/pkg/backend"
 	"github.com/semaphoreci/artifact/pkg/files"
 	"github.com/semaphoreci/artifact/pkg/hub"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	"github.com/semaphoreci/artifact/pkg/resume"
 	"github.com/semaphoreci/artifact/pkg/storage"
 	log "github.com/sirupsen/logrus"
 )
@@ -46,10 +59,23 @@ func (h *HubBackend) Push(ctx context.Context, localPath, remotePath string, opt
 	log.Debugf("* Remote: %s\n", remotePath)
 	log.Debugf("* Force: %v\n", opts.Force)
 
-	// Locate all artifacts (handles both files and directories)
-	artifacts, err := locateArtifactsForPush(localPath, remotePath)
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	warnMetadataIgnored(opts)
+
+	isFile, err := files.IsFileSrc(localPath)
 	if err != nil {
-		return err
+		return fmt.Errorf("path '%s' does not exist locally", localPath)
 	}
 
 	// Determine request type based on force flag
@@ -58,25 +84,194 @@ func (h *HubBackend) Push(ctx context.Context, localPath, remotePath string, opt
 		requestType = hub.GenerateSignedURLsRequestPUSHFORCE
 	}
 
-	// Get signed URLs from hub
-	response, err := h.client.GenerateSignedURLs(api.RemotePaths(artifacts), requestType)
+	// Signed URLs are requested for the next batch while the directory is
+	// still walked, and with force, files uploaded, instead of walking and
+	// signing the whole tree upfront. The first failure of a stage stops
+	// them all.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	fail := func(err error) {
+		if err != nil {
+			once.Do(func() {
+				firstErr = err
+				cancel()
+			})
+		}
+	}
+
+	checksums := newChecksumSet()
+	located := make(chan *api.Artifact, signedURLBatchSize)
+	signed := make(chan *api.Artifact, signedURLBatchSize)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		defer close(located)
+		fail(walkArtifactsForPush(ctx, localPath, remotePath, isFile, opts.Resume, checksums, located))
+	}()
+	go func() {
+		defer wg.Done()
+		defer close(signed)
+		fail(h.signArtifactsForPush(ctx, located, requestType, opts, signed))
+	}()
+
+	_, err = executePush(ctx, signed, opts.Concurrency, opts.Resume, checksums)
+	fail(err)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+
+	// Files pushed by an interrupted run have no checksum stored yet either
+	h.storeChecksums(ctx, checksums.values, opts.Concurrency)
+	return nil
+}
+
+// Check requests a signed URL for remotePath, which fails if the artifact
+// token is not valid.
+func (h *HubBackend) Check(ctx context.Context, remotePath string) (time.Time, error) {
+	log.Debug("HubBackend: Checking...\n")
+	return h.client.Check(remotePath)
+}
+
+// warnMetadataIgnored warns that signed URL uploads cannot carry metadata.
+// The expiry recorded in the metadata is sent to Hub with the request instead.
+func warnMetadataIgnored(opts backend.PushOptions) {
+	ignored := len(opts.Metadata)
+	if _, ok := opts.Metadata[backend.ExpireAtMetadataKey]; ok {
+		ignored--
+	}
+
+	if ignored > 0 {
+		log.Warn("The Hub backend does not store metadata; it is not saved with the pushed files.\n")
+	}
+}
+
+// PushStream uploads a stream to remote storage via a Hub signed URL.
+// Signed URL uploads need the content length upfront, so streams
+// of unknown size are rejected with backend.ErrStreamingNotSupported.
+func (h *HubBackend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	log.Debug("HubBackend: Pushing stream...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Size: %d\n", size)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	if size < 0 {
+		return backend.ErrStreamingNotSupported
+	}
+
+	warnMetadataIgnored(opts)
+
+	requestType := hub.GenerateSignedURLsRequestPUSH
+	if opts.Force {
+		requestType = hub.GenerateSignedURLsRequestPUSHFORCE
+	}
+
+	response, err := h.client.GenerateExpiringSignedURLs([]string{remotePath}, requestType, opts.ExpireIn)
 	if err != nil {
 		return fmt.Errorf("failed to generate signed URLs: %w", err)
 	}
 
-	// Attach URLs to artifacts
-	if err := attachURLsToArtifacts(artifacts, response.Urls, opts.Force); err != nil {
+	artifact := &api.Artifact{RemotePath: remotePath}
+	if err := attachURLsToArtifacts([]*api.Artifact{artifact}, response.Urls, opts.Force); err != nil {
 		return err
 	}
 
-	// Execute the push operations
-	if _, err := executePush(artifacts); err != nil {
+	hash := sha256.New()
+	body, err := streamBody(r, hash)
+	if err != nil {
 		return err
 	}
 
+	client := storage.NewHTTPClient()
+	for _, signedURL := range artifact.URLs {
+		if signedURL.Method == "PUT" {
+			if err := signedURL.PutStream(ctx, client, body, size); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := signedURL.Follow(ctx, client, artifact); err != nil {
+			return err
+		}
+	}
+
+	h.storeChecksums(ctx, map[string]string{remotePath: hex.EncodeToString(hash.Sum(nil))}, 1)
 	return nil
 }
 
+// streamBody returns the body of the upload of r for PutStream, hashing
+// what is sent into digest. Seekable streams, e.g. staged files, are rewound
+// for every retry; others cannot be sent again once read from, so their
+// upload is only retried if it failed before reading anything.
+func streamBody(r io.Reader, digest hash.Hash) (retryablehttp.ReaderFunc, error) {
+	if seeker, ok := r.(io.Seeker); ok {
+		start, err := seeker.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, err
+		}
+
+		return func() (io.Reader, error) {
+			if _, err := seeker.Seek(start, io.SeekStart); err != nil {
+				return nil, err
+			}
+
+			digest.Reset()
+			return io.TeeReader(r, digest), nil
+		}, nil
+	}
+
+	counted := &countingReader{r: r}
+	return func() (io.Reader, error) {
+		if counted.n > 0 {
+			return nil, fmt.Errorf("the upload failed after %d bytes of the stream were sent, and a stream cannot be sent again", counted.n)
+		}
+
+		if counted.err != nil {
+			return nil, counted.err
+		}
+
+		return io.TeeReader(counted, digest), nil
+	}, nil
+}
+
+// countingReader counts the bytes read from r, and keeps the first error
+// other than io.EOF.
+type countingReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	if err != nil && err != io.EOF && c.err == nil {
+		c.err = err
+	}
+	return n, err
+}
+
 // Pull downloads a file or directory from remote storage via Hub signed URLs.
 func (h *HubBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
 	log.Debug("HubBackend: Pulling...\n")
@@ -100,14 +295,119 @@ func (h *HubBackend) Pull(ctx context.Context, remotePath, localPath string, opt
 		return err
 	}
 
+	// Pulled files are checked against the checksums they were pushed with
+	checksums := h.pulledChecksums(ctx, remotePath, artifacts, opts.Concurrency)
+
 	// Execute the pull operations
-	if _, err := executePull(artifacts); err != nil {
+	if _, err := executePull(ctx, artifacts, opts.Concurrency, checksums); err != nil {
 		return err
 	}
 
 	return nil
 }
 
+// Open streams the contents of a file from remote storage via a Hub signed URL.
+func (h *HubBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("HubBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	response, err := h.client.GenerateSignedURLs([]string{remotePath}, hub.GenerateSignedURLsRequestPULL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signed URLs: %w", err)
+	}
+
+	// A directory yields one URL per file, so look for the file itself.
+	for _, signedURL := range response.Urls {
+		obj, err := signedURL.GetObject()
+		if err != nil {
+			return nil, err
+		}
+
+		if obj == remotePath {
+			return signedURL.Open(ctx, storage.NewHTTPClient())
+		}
+	}
+
+	return nil, &backend.ErrNotFound{Path: remotePath}
+}
+
+// OpenTree streams every file under remotePath, or the file at remotePath,
+// via the Hub signed URLs generated for it, in path order. Checksum
+// sidecars are left out, like pulls do.
+func (h *HubBackend) OpenTree(ctx context.Context, remotePath string, fn func(backend.ObjectInfo, io.Reader) error) error {
+	log.Debug("HubBackend: Opening tree...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	response, err := h.client.GenerateSignedURLs([]string{remotePath}, hub.GenerateSignedURLsRequestPULL)
+	if err != nil {
+		return fmt.Errorf("failed to generate signed URLs: %w", err)
+	}
+
+	signed := map[string]*api.SignedURL{}
+	objects := []string{}
+	for _, signedURL := range response.Urls {
+		obj, err := signedURL.GetObject()
+		if err != nil {
+			return err
+		}
+
+		if backend.IsChecksumSidecar(obj) && !backend.IsChecksumSidecar(remotePath) {
+			continue
+		}
+
+		signed[obj] = signedURL
+		objects = append(objects, obj)
+	}
+
+	if len(objects) == 0 {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sort.Strings(objects)
+	client := storage.NewHTTPClient()
+	for _, obj := range objects {
+		r, size, err := signed[obj].OpenSized(ctx, client)
+		if err != nil {
+			return err
+		}
+
+		err = fn(backend.ObjectInfo{Path: obj, Size: size}, r)
+		_ = r.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Share returns the Hub signed URL to pull the file at remotePath. Hub
+// sets the lifetime of its URLs, so expiresIn is ignored; the expiry is
+// read from the URL itself.
+func (h *HubBackend) Share(ctx context.Context, remotePath string, expiresIn time.Duration) (string, time.Time, error) {
+	log.Debug("HubBackend: Sharing...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	response, err := h.client.GenerateSignedURLs([]string{remotePath}, hub.GenerateSignedURLsRequestPULL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to generate signed URLs: %w", err)
+	}
+
+	// A directory yields one URL per file, so look for the file itself.
+	for _, signedURL := range response.Urls {
+		obj, err := signedURL.GetObject()
+		if err != nil {
+			return "", time.Time{}, err
+		}
+
+		if obj == remotePath {
+			return signedURL.URL, signedURL.Expires(), nil
+		}
+	}
+
+	return "", time.Time{}, &backend.ErrNotFound{Path: remotePath}
+}
+
 // Yank deletes a file or directory from remote storage via Hub signed URLs.
 func (h *HubBackend) Yank(ctx context.Context, remotePath string) error {
 	log.Debug("HubBackend: Yanking...\n")
@@ -120,10 +420,11 @@ func (h *HubBackend) Yank(ctx context.Context, remotePath string) error {
 	}
 
 	// Execute the delete operations
-	if err := executeYank(response.Urls); err != nil {
+	if err := executeYank(ctx, response.Urls); err != nil {
 		return err
 	}
 
+	h.yankChecksums(ctx, remotePath)
 	return nil
 }
 
@@ -141,6 +442,79 @@ func (h *HubBackend) Exists(ctx context.Context, remotePath string) (bool, error
 	return len(response.Urls) > 0, nil
 }
 
+// listPageSize is how many artifacts List asks Hub for at once.
+const listPageSize = 1000
+
+// List calls fn for every artifact stored under remotePrefix, a page at a
+// time, hiding checksum sidecars. It returns ErrListingNotSupported if Hub
+// has no list API.
+func (h *HubBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("HubBackend: Listing...\n")
+	log.Debugf("* Remote: %s\n", remotePrefix)
+
+	pageToken := ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		response, err := h.client.ListArtifacts(remotePrefix, pageToken, listPageSize)
+		if errors.Is(err, hub.ErrListNotSupported) {
+			return backend.ErrListingNotSupported
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list '%s': %w", remotePrefix, err)
+		}
+
+		for _, artifact := range response.Artifacts {
+			if backend.IsChecksumSidecar(artifact.Path) && !backend.IsChecksumSidecar(remotePrefix) {
+				continue
+			}
+
+			err := fn(backend.ObjectInfo{Path: artifact.Path, Size: artifact.Size, ModTime: artifact.UpdatedAt, ETag: artifact.ETag})
+			if err == backend.StopListing {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		if response.NextPageToken == "" {
+			return nil
+		}
+		pageToken = response.NextPageToken
+	}
+}
+
+// Checksum returns the checksum stored in the file's sidecar.
+func (h *HubBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	exists, err := h.Exists(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sidecar, err := h.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
 // Close releases resources. For Hub backend, this is a no-op.
 func (h *HubBackend) Close() error {
 	return nil
@@ -148,22 +522,217 @@ func (h *HubBackend) Close() error {
 
 // Helper functions
 
-func locateArtifactsForPush(localPath, remotePath string) ([]*api.Artifact, error) {
-	isFile, err := files.IsFileSrc(localPath)
+// storeChecksums uploads a sidecar for every remote path in checksums,
+// concurrency at a time, asking the Hub for their signed URLs
+// signedURLBatchSize at a time. Failing to store them does not fail the
+// push: the files are already uploaded, and consumers treat missing
+// checksums as unknown.
+func (h *HubBackend) storeChecksums(ctx context.Context, checksums map[string]string, concurrency int) {
+	remotePaths := make([]string, 0, len(checksums))
+	for remotePath := range checksums {
+		remotePaths = append(remotePaths, remotePath)
+	}
+	sort.Strings(remotePaths)
+
+	client := newConcurrentHTTPClient(concurrency)
+	for start := 0; start < len(remotePaths); start += signedURLBatchSize {
+		batch := remotePaths[start:min(start+signedURLBatchSize, len(remotePaths))]
+
+		sidecars := make([]string, 0, len(batch))
+		for _, remotePath := range batch {
+			sidecars = append(sidecars, backend.ChecksumSidecarPath(remotePath))
+		}
+
+		response, err := h.client.GenerateSignedURLs(sidecars, hub.GenerateSignedURLsRequestPUSHFORCE)
+		if err != nil {
+			log.Warnf("Failed to store checksums: %v\n", err)
+			return
+		}
+
+		if len(response.Urls) != len(sidecars) {
+			log.Warnf("Failed to store checksums: got %d signed URLs, expected %d\n", len(response.Urls), len(sidecars))
+			return
+		}
+
+		_ = backend.Parallel(len(batch), concurrency, func(i int) error {
+			checksum := checksums[batch[i]]
+			body := func() (io.Reader, error) { return strings.NewReader(checksum), nil }
+			if err := response.Urls[i].PutStream(ctx, client, body, int64(len(checksum))); err != nil {
+				log.Warnf("Failed to store checksum of '%s': %v\n", batch[i], err)
+			}
+			return nil
+		})
+	}
+}
+
+// pulledChecksums returns the checksums stored for the artifacts of a
+// pull, by remote path, asking the Hub for the signed URLs of all their
+// sidecars at once. Files pushed before checksums were stored have none,
+// and failing to fetch them only leaves the files unverified.
+func (h *HubBackend) pulledChecksums(ctx context.Context, remotePath string, artifacts []*api.Artifact, concurrency int) map[string]string {
+	checksums := map[string]string{}
+
+	sidecars := map[string]string{}
+	for _, artifact := range artifacts {
+		sidecars[backend.ChecksumSidecarPath(artifact.RemotePath)] = artifact.RemotePath
+	}
+
+	// The sidecars of a directory are in a directory of their own
+	sidecarPath := backend.ChecksumSidecarPrefix(remotePath)
+	if len(artifacts) == 1 && artifacts[0].RemotePath == remotePath {
+		sidecarPath = backend.ChecksumSidecarPath(remotePath)
+	}
+
+	response, err := h.client.GenerateSignedURLs([]string{sidecarPath}, hub.GenerateSignedURLsRequestPULL)
+	if err != nil {
+		log.Debugf("Cannot get the checksums of '%s', not verifying them: %v\n", remotePath, err)
+		return checksums
+	}
+
+	client := newConcurrentHTTPClient(concurrency)
+	var mu sync.Mutex
+	_ = backend.Parallel(len(response.Urls), concurrency, func(i int) error {
+		obj, err := response.Urls[i].GetObject()
+		if err != nil {
+			return nil
+		}
+
+		file, ok := sidecars[obj]
+		if !ok {
+			return nil
+		}
+
+		checksum, err := readChecksum(ctx, client, response.Urls[i])
+		if err != nil {
+			log.Debugf("Cannot read the checksum of '%s', not verifying it: %v\n", file, err)
+			return nil
+		}
+
+		mu.Lock()
+		checksums[file] = checksum
+		mu.Unlock()
+		return nil
+	})
+
+	return checksums
+}
+
+// readChecksum downloads a checksum sidecar.
+func readChecksum(ctx context.Context, client *retryablehttp.Client, signedURL *api.SignedURL) (string, error) {
+	sidecar, err := signedURL.Open(ctx, client)
+	if err != nil {
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// yankChecksums removes the sidecars of a yanked file or directory.
+func (h *HubBackend) yankChecksums(ctx context.Context, remotePath string) {
+	sidecar := backend.ChecksumSidecarPath(remotePath)
+	sidecarDir := backend.ChecksumSidecarPrefix(remotePath) + "/"
+
+	toDelete := []*api.SignedURL{}
+	for _, p := range []string{sidecar, sidecarDir} {
+		response, err := h.client.GenerateSignedURLs([]string{p}, hub.GenerateSignedURLsRequestPULL)
+		if err != nil {
+			log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+			return
+		}
+
+		for _, signedURL := range response.Urls {
+			obj, err := signedURL.GetObject()
+			if err != nil || (obj != sidecar && !strings.HasPrefix(obj, sidecarDir)) {
+				continue
+			}
+			toDelete = append(toDelete, signedURL)
+		}
+	}
+
+	if err := executeYank(ctx, toDelete); err != nil {
+		log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+	}
+}
+
+// The Hub is asked to sign the URLs of up to signedURLBatchSize files at
+// once, as larger requests are rejected. Directory pushes wait at most
+// signedURLBatchDelay for a batch to fill up.
+const (
+	signedURLBatchSize  = 500
+	signedURLBatchDelay = 100 * time.Millisecond
+)
+
+// checksumSet collects the checksums of the files of a push, by remote
+// path, computed while they are uploaded.
+type checksumSet struct {
+	mu     sync.Mutex
+	values map[string]string
+}
+
+func newChecksumSet() *checksumSet {
+	return &checksumSet{values: map[string]string{}}
+}
+
+// set records the checksum of the bytes uploaded for remotePath.
+func (c *checksumSet) set(remotePath, checksum string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.values[remotePath] = checksum
+}
+
+// addLocal computes the checksum of the local file of an artifact an
+// interrupted run of the push uploaded, which is not uploaded again.
+// Files that cannot be read are left without a checksum.
+func (c *checksumSet) addLocal(artifact *api.Artifact) {
+	checksum, err := files.SHA256File(artifact.LocalPath)
 	if err != nil {
-		return nil, fmt.Errorf("path '%s' does not exist locally", localPath)
+		log.Warnf("Failed to compute checksum of '%s': %v\n", artifact.LocalPath, err)
+		return
+	}
+
+	c.set(artifact.RemotePath, checksum)
+}
+
+// walkArtifactsForPush sends the artifacts of the local file or directory
+// to located as they are found, skipping the ones an interrupted run of
+// the push already pushed, whose checksums are added to checksums.
+func walkArtifactsForPush(ctx context.Context, localPath, remotePath string, isFile bool, state *resume.State, checksums *checksumSet, located chan<- *api.Artifact) error {
+	skipped := 0
+	defer func() { backend.LogResumed(skipped) }()
+
+	send := func(artifact *api.Artifact, info os.FileInfo) error {
+		if state != nil && state.Pushed(artifact.LocalPath, info) {
+			progress.FromContext(ctx).Skip(info.Size())
+			backend.TransferStatsFromContext(ctx).Skipped(info.Size())
+			checksums.addLocal(artifact)
+			skipped++
+			return nil
+		}
+
+		select {
+		case located <- artifact:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
 	}
 
 	if isFile {
-		return []*api.Artifact{{
-			RemotePath: remotePath,
-			LocalPath:  localPath,
-		}}, nil
+		info, err := os.Stat(localPath)
+		if err != nil {
+			return err
+		}
+
+		return send(&api.Artifact{RemotePath: remotePath, LocalPath: localPath}, info)
 	}
 
-	// Walk directory
-	var artifacts []*api.Artifact
-	err = filepath.Walk(localPath, func(filename string, info os.FileInfo, err error) error {
+	return files.Walk(localPath, func(filename string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -172,18 +741,109 @@ func locateArtifactsForPush(localPath, remotePath string) ([]*api.Artifact, erro
 		}
 
 		name := filepath.ToSlash(filename)
-		artifacts = append(artifacts, &api.Artifact{
+		return send(&api.Artifact{
 			RemotePath: path.Join(remotePath, name[len(localPath):]),
 			LocalPath:  filename,
-		})
-		return nil
+		}, info)
 	})
+}
 
-	if err != nil {
-		return nil, err
+// signArtifactsForPush receives the located artifacts, asks the Hub for
+// their signed URLs in batches, and sends them to signed with their URLs
+// attached. A batch is signed once it has signedURLBatchSize artifacts,
+// or signedURLBatchDelay after its first one, so uploads start right away
+// even while a huge directory is still walked.
+//
+// Without force, the files of every batch that exist are looked up as it
+// is signed, but no batch is sent until all of them are checked, so a
+// push fails listing all the files that exist before uploading anything.
+func (h *HubBackend) signArtifactsForPush(ctx context.Context, located <-chan *api.Artifact, requestType hub.GenerateSignedURLsRequestType, opts backend.PushOptions, signed chan<- *api.Artifact) error {
+	batch := []*api.Artifact{}
+	held := []*api.Artifact{}
+	conflicts := []string{}
+
+	send := func(artifacts []*api.Artifact) error {
+		for _, artifact := range artifacts {
+			select {
+			case signed <- artifact:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+
+		return nil
 	}
 
-	return artifacts, nil
+	flush := func() error {
+		if len(batch) == 0 || ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		response, err := h.client.GenerateExpiringSignedURLs(api.RemotePaths(batch), requestType, opts.ExpireIn)
+		if err != nil {
+			return fmt.Errorf("failed to generate signed URLs: %w", err)
+		}
+
+		if err := attachURLsToArtifacts(batch, response.Urls, opts.Force); err != nil {
+			return err
+		}
+
+		if !opts.Force {
+			existing, err := existingArtifacts(ctx, batch, opts.Concurrency)
+			if err != nil {
+				return err
+			}
+
+			conflicts = append(conflicts, existing...)
+			if len(conflicts) == 0 {
+				held = append(held, batch...)
+			}
+
+			batch = []*api.Artifact{}
+			return nil
+		}
+
+		err = send(batch)
+		batch = []*api.Artifact{}
+		return err
+	}
+
+	timer := time.NewTimer(signedURLBatchDelay)
+	timer.Stop()
+	defer timer.Stop()
+
+	for {
+		select {
+		case artifact, ok := <-located:
+			if !ok {
+				if err := flush(); err != nil {
+					return err
+				}
+				if err := errAlreadyExist(conflicts); err != nil {
+					return err
+				}
+
+				return send(held)
+			}
+
+			if len(batch) == 0 {
+				timer.Reset(signedURLBatchDelay)
+			}
+
+			batch = append(batch, artifact)
+			if len(batch) < signedURLBatchSize {
+				continue
+			}
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		timer.Stop()
+		if err := flush(); err != nil {
+			return err
+		}
+	}
 }
 
 func attachURLsToArtifacts(artifacts []*api.Artifact, signedURLs []*api.SignedURL, force bool) error {
@@ -212,29 +872,106 @@ func attachURLsToArtifacts(artifacts []*api.Artifact, signedURLs []*api.SignedUR
 	return nil
 }
 
-func executePush(artifacts []*api.Artifact) (*storage.PushStats, error) {
-	client := storage.NewHTTPClient()
+// existingArtifacts follows the HEAD URLs of the artifacts of a push
+// without force, concurrency at a time, sharing one HTTP client, and
+// returns the remote paths of the ones that already exist.
+func existingArtifacts(ctx context.Context, artifacts []*api.Artifact, concurrency int) ([]string, error) {
+	client := newConcurrentHTTPClient(concurrency)
+
+	var mu sync.Mutex
+	conflicts := []string{}
+	err := backend.Parallel(len(artifacts), concurrency, func(i int) error {
+		for _, signedURL := range artifacts[i].URLs {
+			if signedURL.Method != "HEAD" {
+				continue
+			}
+
+			exists, err := signedURL.Exists(ctx, client)
+			if err != nil {
+				return err
+			}
+
+			if exists {
+				mu.Lock()
+				conflicts = append(conflicts, artifacts[i].RemotePath)
+				mu.Unlock()
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return conflicts, nil
+}
+
+// errAlreadyExist returns the error of a push without force listing every
+// file of it that exists, so all of them can be dealt with at once, or nil
+// if there is none.
+func errAlreadyExist(conflicts []string) error {
+	switch len(conflicts) {
+	case 0:
+		return nil
+	case 1:
+		return fmt.Errorf("'%s' already exists in the remote storage; delete it first, or use --force flag", conflicts[0])
+	}
+
+	sort.Strings(conflicts)
+	lines := []string{fmt.Sprintf("%d files already exist in the remote storage; delete them first, or use --force flag:", len(conflicts))}
+	for _, conflict := range conflicts {
+		lines = append(lines, fmt.Sprintf("* '%s'", conflict))
+	}
+
+	return errors.New(strings.Join(lines, "\n"))
+}
+
+// executePush uploads the signed artifacts as they are received,
+// concurrency at a time, sharing one HTTP client, and adds the checksums
+// of the bytes uploaded, hashed as they are sent, to checksums. Pushed artifacts are recorded in
+// state, if it is not nil. Their HEAD URLs are left to
+// existingArtifacts.
+func executePush(ctx context.Context, signed <-chan *api.Artifact, concurrency int, state *resume.State, checksums *checksumSet) (*storage.PushStats, error) {
+	client := newConcurrentHTTPClient(concurrency)
 	stats := &storage.PushStats{}
+	var mu sync.Mutex
 
-	for _, artifact := range artifacts {
+	err := backend.ParallelEach(signed, concurrency, func(artifact *api.Artifact) error {
 		fileInfo, err := os.Stat(artifact.LocalPath)
 		if err != nil {
-			return nil, fmt.Errorf("failed to stat '%s': %w", artifact.LocalPath, err)
+			return fmt.Errorf("failed to stat '%s': %w", artifact.LocalPath, err)
 		}
 
 		for _, signedURL := range artifact.URLs {
-			if err := signedURL.Follow(client, artifact); err != nil {
-				return nil, err
+			if signedURL.Method != "PUT" {
+				continue
 			}
+
+			if err := signedURL.Follow(ctx, client, artifact); err != nil {
+				return err
+			}
+
+			mu.Lock()
+			stats.FileCount++
+			stats.TotalSize += fileInfo.Size()
+			mu.Unlock()
 		}
 
-		for _, url := range artifact.URLs {
-			if url.Method == "PUT" {
-				stats.FileCount++
-				stats.TotalSize += fileInfo.Size()
-				break
+		if artifact.SHA256 != "" {
+			checksums.set(artifact.RemotePath, artifact.SHA256)
+		}
+
+		if state != nil {
+			if err := state.MarkPushed(artifact.LocalPath, fileInfo); err != nil {
+				log.Warnf("Failed to record the push of '%s': %v\n", artifact.LocalPath, err)
 			}
 		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return stats, nil
@@ -249,6 +986,10 @@ func buildArtifactsForPull(signedURLs []*api.SignedURL, remotePath, localPath st
 			return nil, err
 		}
 
+		if backend.IsChecksumSidecar(obj) && !backend.IsChecksumSidecar(remotePath) {
+			continue
+		}
+
 		destPath := path.Join(localPath, obj[len(remotePath):])
 
 		// Check if local file exists (unless force)
@@ -268,35 +1009,73 @@ func buildArtifactsForPull(signedURLs []*api.SignedURL, remotePath, localPath st
 	return artifacts, nil
 }
 
-func executePull(artifacts []*api.Artifact) (*storage.PullStats, error) {
-	client := storage.NewHTTPClient()
+// executePull downloads the artifacts, concurrency at a time, sharing one
+// HTTP client, and checks them against their checksums, by remote path.
+// Every artifact is tried, and the errors of all failed downloads are
+// returned; files that do not match their checksum are removed.
+func executePull(ctx context.Context, artifacts []*api.Artifact, concurrency int, checksums map[string]string) (*storage.PullStats, error) {
+	client := newConcurrentHTTPClient(concurrency)
 	stats := &storage.PullStats{}
+	var mu sync.Mutex
 
-	for _, artifact := range artifacts {
+	err := backend.ParallelAll(len(artifacts), concurrency, func(i int) error {
+		artifact := artifacts[i]
 		for _, signedURL := range artifact.URLs {
-			if err := signedURL.Follow(client, artifact); err != nil {
-				return nil, err
+			if err := signedURL.Follow(ctx, client, artifact); err != nil {
+				return err
 			}
 
 			if fileInfo, err := os.Stat(artifact.LocalPath); err == nil {
+				mu.Lock()
 				stats.FileCount++
 				stats.TotalSize += fileInfo.Size()
+				mu.Unlock()
 			}
 		}
+
+		if err := backend.VerifyFile(artifact.LocalPath, artifact.RemotePath, checksums[artifact.RemotePath]); err != nil {
+			_ = os.Remove(artifact.LocalPath)
+			return err
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	return stats, nil
 }
 
-func executeYank(signedURLs []*api.SignedURL) error {
+// newConcurrentHTTPClient returns the storage HTTP client, keeping a
+// connection per worker open between transfers.
+func newConcurrentHTTPClient(concurrency int) *retryablehttp.Client {
+	if concurrency < 1 {
+		concurrency = backend.DefaultConcurrency
+	}
+
 	client := storage.NewHTTPClient()
 
-	for _, u := range signedURLs {
-		u.Method = "DELETE"
-		if err := u.Follow(client, nil); err != nil {
-			return err
-		}
+	// The transport of the client carries the request timeout
+	transport, ok := client.HTTPClient.Transport.(*http.Transport)
+	if !ok {
+		transport = http.DefaultTransport.(*http.Transport)
 	}
+	transport = transport.Clone()
+	transport.MaxIdleConnsPerHost = concurrency
 
-	return nil
+	client.HTTPClient = &http.Client{Transport: transport}
+	return client
+}
+
+// executeYank deletes the files of the signed URLs, DefaultConcurrency at
+// a time, sharing one HTTP client.
+func executeYank(ctx context.Context, signedURLs []*api.SignedURL) error {
+	client := newConcurrentHTTPClient(backend.DefaultConcurrency)
+
+	return backend.Parallel(len(signedURLs), backend.DefaultConcurrency, func(i int) error {
+		u := signedURLs[i]
+		u.Method = "DELETE"
+		return u.Follow(ctx, client, nil)
+	})
 }
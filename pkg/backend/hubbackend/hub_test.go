@@ -0,0 +1,257 @@
+package hubbackend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/api"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/hub"
+	"github.com/semaphoreci/artifact/pkg/storage"
+	testsupport "github.com/semaphoreci/artifact/test/support"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestHubBackend returns a backend pushing to a mock of the Hub and
+// of its storage, and the number of paths of every request of signed URLs.
+func createTestHubBackend(t *testing.T) (*HubBackend, *testsupport.StorageMockServer, func() []int) {
+	storageServer, err := testsupport.NewStorageMockServer()
+	require.NoError(t, err)
+	require.NoError(t, storageServer.Init(nil))
+	t.Cleanup(storageServer.Close)
+
+	hubServer := testsupport.NewHubMockServer(storageServer)
+	hubServer.Init()
+	t.Cleanup(hubServer.Close)
+
+	target, err := url.Parse(hubServer.URL())
+	require.NoError(t, err)
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	var mu sync.Mutex
+	batches := []int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		request := hub.GenerateSignedURLsRequest{}
+		if json.Unmarshal(body, &request) == nil {
+			mu.Lock()
+			batches = append(batches, len(request.Paths))
+			mu.Unlock()
+		}
+
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	client := &hub.Client{URL: server.URL + "/api/v1/artifacts", Token: "token", HttpClient: http.DefaultClient}
+	return &HubBackend{client: client}, storageServer, func() []int {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]int{}, batches...)
+	}
+}
+
+func TestHubBackend_Push_Batches(t *testing.T) {
+	hubBackend, storageServer, batches := createTestHubBackend(t)
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	for i := 0; i < 1200; i++ {
+		name := filepath.Join(dir, fmt.Sprintf("%04d.xml", i))
+		if i%2 == 0 {
+			name = filepath.Join(dir, "sub", fmt.Sprintf("%04d.xml", i))
+		}
+		require.NoError(t, os.WriteFile(name, []byte(fmt.Sprintf("<report id=\"%d\"/>", i)), 0644))
+	}
+
+	err := hubBackend.Push(context.Background(), dir, "artifacts/jobs/1/reports", backend.PushOptions{Concurrency: 4})
+	require.NoError(t, err)
+
+	assert.True(t, storageServer.IsFile("artifacts/jobs/1/reports/0001.xml"))
+	assert.True(t, storageServer.IsFile("artifacts/jobs/1/reports/sub/1198.xml"))
+	assert.True(t, storageServer.IsFile(backend.ChecksumSidecarPath("artifacts/jobs/1/reports/sub/1198.xml")))
+
+	// The files and their checksum sidecars are never signed all at once
+	total := 0
+	for _, size := range batches() {
+		assert.LessOrEqual(t, size, signedURLBatchSize)
+		total += size
+	}
+	assert.Equal(t, 2*1200, total)
+	assert.GreaterOrEqual(t, len(batches()), 6)
+
+	// Pushing again lists every file that exists, and uploads nothing
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new.xml"), []byte("<report/>"), 0644))
+	err = hubBackend.Push(context.Background(), dir, "artifacts/jobs/1/reports", backend.PushOptions{})
+	assert.ErrorContains(t, err, "1200 files already exist in the remote storage")
+	assert.False(t, storageServer.IsFile("artifacts/jobs/1/reports/new.xml"))
+
+	// A file that exists in a later batch than new files stops them too
+	other := filepath.Join(t.TempDir(), "other")
+	require.NoError(t, os.MkdirAll(other, 0755))
+	for i := 0; i < 600; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(other, fmt.Sprintf("%04d.xml", i)), []byte("<report/>"), 0644))
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(other, "zzzz.xml"), []byte("<report/>"), 0644))
+	require.NoError(t, hubBackend.Push(context.Background(), filepath.Join(other, "zzzz.xml"), "artifacts/jobs/1/other/zzzz.xml", backend.PushOptions{}))
+
+	err = hubBackend.Push(context.Background(), other, "artifacts/jobs/1/other", backend.PushOptions{Concurrency: 4})
+	assert.EqualError(t, err, "'artifacts/jobs/1/other/zzzz.xml' already exists in the remote storage; delete it first, or use --force flag")
+	for i := 0; i < 600; i++ {
+		assert.False(t, storageServer.IsFile(fmt.Sprintf("artifacts/jobs/1/other/%04d.xml", i)))
+	}
+}
+
+func TestHubBackend_List(t *testing.T) {
+	hubBackend, _, _ := createTestHubBackend(t)
+
+	dir := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	for i := 0; i < 1200; i++ {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%04d.xml", i)), []byte("<report/>"), 0644))
+	}
+	require.NoError(t, hubBackend.Push(context.Background(), dir, "artifacts/jobs/1/reports", backend.PushOptions{Concurrency: 4}))
+
+	// Every page is listed, in order
+	listed := []backend.ObjectInfo{}
+	err := hubBackend.List(context.Background(), "artifacts/jobs/1/reports", func(obj backend.ObjectInfo) error {
+		listed = append(listed, obj)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, listed, 1200)
+	assert.Equal(t, "artifacts/jobs/1/reports/0000.xml", listed[0].Path)
+	assert.Equal(t, "artifacts/jobs/1/reports/1199.xml", listed[1199].Path)
+	assert.Equal(t, int64(len("<report/>")), listed[0].Size)
+	assert.False(t, listed[0].ModTime.IsZero())
+
+	// Checksum sidecars are hidden
+	count := 0
+	err = hubBackend.List(context.Background(), "artifacts/jobs/1", func(obj backend.ObjectInfo) error {
+		assert.False(t, backend.IsChecksumSidecar(obj.Path), obj.Path)
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1200, count)
+
+	// Listing stops early
+	count = 0
+	err = hubBackend.List(context.Background(), "artifacts/jobs/1/reports", func(obj backend.ObjectInfo) error {
+		count++
+		return backend.StopListing
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestHubBackend_List_NotSupported(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(server.Close)
+
+	client := &hub.Client{URL: server.URL + "/api/v1/artifacts", Token: "token", HttpClient: http.DefaultClient}
+	hubBackend := &HubBackend{client: client}
+
+	err := hubBackend.List(context.Background(), "artifacts/jobs/1", func(backend.ObjectInfo) error { return nil })
+	assert.ErrorIs(t, err, backend.ErrListingNotSupported)
+}
+
+func TestHubBackend_Push_MissingPath(t *testing.T) {
+	hubBackend, _, batches := createTestHubBackend(t)
+
+	err := hubBackend.Push(context.Background(), filepath.Join(t.TempDir(), "missing"), "artifacts/jobs/1/missing", backend.PushOptions{})
+	assert.ErrorContains(t, err, "does not exist locally")
+	assert.Empty(t, batches())
+}
+
+func TestExistingArtifacts(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/exists") {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	artifacts := func(names ...string) []*api.Artifact {
+		artifacts := []*api.Artifact{}
+		for _, name := range names {
+			artifacts = append(artifacts, &api.Artifact{
+				RemotePath: "artifacts/jobs/1/" + name,
+				URLs:       []*api.SignedURL{{URL: server.URL + "/" + name, Method: "HEAD"}, {URL: server.URL + "/" + name, Method: "PUT"}},
+			})
+		}
+		return artifacts
+	}
+
+	ctx := context.Background()
+	existing, err := existingArtifacts(ctx, artifacts("a.txt", "b.txt"), 2)
+	assert.NoError(t, err)
+	assert.Empty(t, existing)
+	assert.NoError(t, errAlreadyExist(existing))
+
+	existing, err = existingArtifacts(ctx, artifacts("a.txt", "exists-1.txt", "b.txt"), 2)
+	assert.NoError(t, err)
+	assert.EqualError(t, errAlreadyExist(existing), "'artifacts/jobs/1/exists-1.txt' already exists in the remote storage; delete it first, or use --force flag")
+
+	// Every conflict is listed at once
+	existing, err = existingArtifacts(ctx, artifacts("exists-3.txt", "a.txt", "exists-1.txt", "exists-2.txt"), 2)
+	assert.NoError(t, err)
+	assert.EqualError(t, errAlreadyExist(existing), "3 files already exist in the remote storage; delete them first, or use --force flag:\n"+
+		"* 'artifacts/jobs/1/exists-1.txt'\n"+
+		"* 'artifacts/jobs/1/exists-2.txt'\n"+
+		"* 'artifacts/jobs/1/exists-3.txt'")
+}
+
+func TestStreamBody(t *testing.T) {
+	t.Setenv("ARTIFACT_RETRY_BASE_DELAY", "1ms")
+
+	attempts := 0
+	received := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		attempts++
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received = string(body)
+	}))
+	defer server.Close()
+
+	signedURL := &api.SignedURL{URL: server.URL + "/a.txt", Method: "PUT"}
+	client := storage.NewHTTPClient()
+
+	// Seekable streams are sent again on retries, and hashed once
+	digest := sha256.New()
+	body, err := streamBody(strings.NewReader("hello"), digest)
+	require.NoError(t, err)
+	require.NoError(t, signedURL.PutStream(context.Background(), client, body, 5))
+	assert.Equal(t, 2, attempts)
+	assert.Equal(t, "hello", received)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", hex.EncodeToString(digest.Sum(nil)))
+
+	// Other streams cannot be sent again once read from
+	attempts = 0
+	body, err = streamBody(io.MultiReader(strings.NewReader("hello")), sha256.New())
+	require.NoError(t, err)
+	err = signedURL.PutStream(context.Background(), client, body, 5)
+	assert.ErrorContains(t, err, "a stream cannot be sent again")
+	assert.Equal(t, 1, attempts)
+}
@@ -0,0 +1,533 @@
+package ipfsbackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterIPFSBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// MFS entry types in listings.
+const (
+	entryTypeFile      = 0
+	entryTypeDirectory = 1
+)
+
+// IPFSBackend implements the Backend interface on the MFS of an IPFS node.
+type IPFSBackend struct {
+	cfg    *Config
+	client *http.Client
+
+	// manifestMu serializes appends to the manifest
+	manifestMu sync.Mutex
+}
+
+// ManifestEntry is a line of the manifest, recording the CID of a pushed file.
+type ManifestEntry struct {
+	Path   string    `json:"path"`
+	CID    string    `json:"cid"`
+	Size   int64     `json:"size"`
+	SHA256 string    `json:"sha256"`
+	Time   time.Time `json:"time"`
+}
+
+// stat is the response of files/stat.
+type stat struct {
+	Hash string `json:"Hash"`
+	Size int64  `json:"Size"`
+	Type string `json:"Type"` // file or directory
+}
+
+// lsEntry is an entry of the files/ls response.
+type lsEntry struct {
+	Name string `json:"Name"`
+	Type int    `json:"Type"`
+	Size int64  `json:"Size"`
+	Hash string `json:"Hash"`
+}
+
+// apiError is the error JSON object of the IPFS HTTP API.
+type apiError struct {
+	Message string `json:"Message"`
+}
+
+// New creates a new IPFSBackend instance from the environment/config file.
+func New() (*IPFSBackend, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg), nil
+}
+
+// NewWithConfig creates a new IPFSBackend instance for a validated configuration.
+func NewWithConfig(cfg *Config) *IPFSBackend {
+	log.Debug("IPFSBackend: Client initialized\n")
+	log.Debugf("* URL: %s\n", cfg.URL)
+	log.Debugf("* Prefix: %s\n", cfg.Prefix)
+	log.Debugf("* Manifest: %s\n", cfg.Manifest)
+
+	return &IPFSBackend{cfg: cfg, client: &http.Client{}}
+}
+
+// Push writes a local file or directory to MFS, one file at a time.
+func (i *IPFSBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("IPFSBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		return i.pushFile(ctx, localPath, remotePath, opts)
+	}
+
+	return files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return i.pushFile(ctx, filePath, remotePath+"/"+filepath.ToSlash(relPath), opts)
+	})
+}
+
+func (i *IPFSBackend) pushFile(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	file, err := os.Open(localPath) // #nosec
+	if err != nil {
+		return fmt.Errorf("failed to open local file '%s': %w", localPath, err)
+	}
+	defer file.Close()
+
+	if err := i.PushStream(ctx, file, -1, remotePath, opts); err != nil {
+		return err
+	}
+
+	log.Debugf("Uploaded: %s -> %s\n", localPath, i.cfg.mfsPath(remotePath))
+	return nil
+}
+
+// PushStream writes a stream to MFS, logs its CID and records it in the
+// manifest. The checksum is computed while uploading.
+func (i *IPFSBackend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	if !opts.Force {
+		exists, err := i.Exists(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &backend.ErrAlreadyExists{Path: remotePath}
+		}
+	}
+
+	hash := sha256.New()
+	if err := i.write(ctx, remotePath, io.TeeReader(r, hash)); err != nil {
+		return err
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	// Failing to store the checksum does not fail the push, as the file is already uploaded
+	if err := i.write(ctx, backend.ChecksumSidecarPath(remotePath), strings.NewReader(checksum)); err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+
+	s, err := i.stat(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Pushed '%s' as %s\n", remotePath, s.Hash)
+
+	return i.record(&ManifestEntry{Path: remotePath, CID: s.Hash, Size: s.Size, SHA256: checksum, Time: time.Now().UTC()})
+}
+
+// write creates or replaces an MFS file, creating its parent directories.
+// CIDv1 with raw leaves is used, so small files have the same CID as
+// when added with `ipfs add --cid-version=1`.
+func (i *IPFSBackend) write(ctx context.Context, remotePath string, r io.Reader) error {
+	body, writer := io.Pipe()
+	form := multipart.NewWriter(writer)
+
+	go func() {
+		part, err := form.CreateFormFile("file", path.Base(remotePath))
+		if err == nil {
+			_, err = files.Copy(part, r)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		writer.CloseWithError(err)
+	}()
+
+	params := url.Values{
+		"arg":         {i.cfg.mfsPath(remotePath)},
+		"create":      {"true"},
+		"parents":     {"true"},
+		"truncate":    {"true"},
+		"cid-version": {"1"},
+		"raw-leaves":  {"true"},
+	}
+
+	response, err := i.call(ctx, "files/write", params, body, form.FormDataContentType(), "push", remotePath)
+
+	// Unblocks the writer if the request failed before reading everything
+	body.Close()
+
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	return nil
+}
+
+// record appends an entry to the manifest, if one is configured.
+func (i *IPFSBackend) record(entry *ManifestEntry) error {
+	if i.cfg.Manifest == "" {
+		return nil
+	}
+
+	i.manifestMu.Lock()
+	defer i.manifestMu.Unlock()
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(i.cfg.Manifest, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644) // #nosec
+	if err != nil {
+		return fmt.Errorf("failed to open IPFS manifest '%s': %w", i.cfg.Manifest, err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write IPFS manifest '%s': %w", i.cfg.Manifest, err)
+	}
+
+	return nil
+}
+
+// Pull downloads a file, or every file in a directory.
+func (i *IPFSBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("IPFSBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	s, err := i.stat(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if s.Type != "directory" {
+		return i.pullFile(ctx, remotePath, localPath, opts)
+	}
+
+	dir := strings.TrimSuffix(remotePath, "/")
+	return backend.Walk(ctx, i.readDir, dir, nil, func(info backend.ObjectInfo) error {
+		destPath := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(info.Path, dir+"/")))
+		return i.pullFile(ctx, info.Path, destPath, opts)
+	})
+}
+
+func (i *IPFSBackend) pullFile(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	body, err := i.Open(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	if err := files.WriteAtomically(localPath, body); err != nil {
+		return err
+	}
+
+	log.Debugf("Downloaded: %s -> %s\n", i.cfg.mfsPath(remotePath), localPath)
+	return nil
+}
+
+// Open streams the contents of a file.
+func (i *IPFSBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("IPFSBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	params := url.Values{"arg": {i.cfg.mfsPath(remotePath)}}
+	response, err := i.call(ctx, "files/read", params, nil, "", "pull", remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// Yank removes a file or directory from MFS, along with its checksums.
+// The content stays on the node until it is garbage collected.
+// Paths that do not exist are not an error.
+func (i *IPFSBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("IPFSBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	if err := i.remove(ctx, remotePath); err != nil {
+		return err
+	}
+
+	for _, sidecar := range []string{backend.ChecksumSidecarPath(remotePath), backend.ChecksumSidecarPrefix(remotePath)} {
+		if err := i.remove(ctx, sidecar); err != nil {
+			log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+		}
+	}
+
+	return nil
+}
+
+func (i *IPFSBackend) remove(ctx context.Context, remotePath string) error {
+	params := url.Values{"arg": {i.cfg.mfsPath(remotePath)}, "recursive": {"true"}, "force": {"true"}}
+	response, err := i.call(ctx, "files/rm", params, nil, "", "yank", remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	return nil
+}
+
+// Exists checks if a file exists.
+func (i *IPFSBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("IPFSBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	s, err := i.stat(ctx, remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return s.Type == "file", nil
+}
+
+// CID returns the content identifier of a file or directory.
+func (i *IPFSBackend) CID(ctx context.Context, remotePath string) (string, error) {
+	s, err := i.stat(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+
+	return s.Hash, nil
+}
+
+// List lists the files under remotePrefix with one files/ls command per
+// directory the walk reaches. The ETag of a file is its CID. MFS keeps no
+// modification times.
+func (i *IPFSBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("IPFSBackend: Listing...\n")
+	log.Debugf("* Prefix: %s\n", remotePrefix)
+
+	return backend.WalkPrefix(ctx, i.readDir, remotePrefix, fn)
+}
+
+// Checksum returns the checksum stored in the file's sidecar.
+func (i *IPFSBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	exists, err := i.Exists(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sidecar, err := i.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// Close releases resources. For IPFS backend, this is a no-op.
+func (i *IPFSBackend) Close() error {
+	return nil
+}
+
+// Helper functions
+
+// stat describes a file or directory.
+func (i *IPFSBackend) stat(ctx context.Context, remotePath string) (*stat, error) {
+	params := url.Values{"arg": {i.cfg.mfsPath(remotePath)}}
+	response, err := i.call(ctx, "files/stat", params, nil, "", "pull", remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	s := &stat{}
+	if err := json.NewDecoder(response.Body).Decode(s); err != nil {
+		return nil, fmt.Errorf("failed to decode IPFS stat of '%s': %w", remotePath, err)
+	}
+
+	return s, nil
+}
+
+// readDir returns the entries of a directory, for backend.Walk.
+func (i *IPFSBackend) readDir(ctx context.Context, dir string) ([]backend.DirEntry, error) {
+	params := url.Values{"arg": {i.cfg.mfsPath(dir)}, "long": {"true"}, "U": {"true"}}
+	response, err := i.call(ctx, "files/ls", params, nil, "", "pull", dir)
+	if err != nil {
+		return nil, err
+	}
+
+	result := struct {
+		Entries []lsEntry `json:"Entries"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&result)
+	response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode IPFS listing of '%s': %w", dir, err)
+	}
+
+	entries := []backend.DirEntry{}
+	for _, entry := range result.Entries {
+		entries = append(entries, backend.DirEntry{
+			Name:  entry.Name,
+			IsDir: entry.Type == entryTypeDirectory,
+			Info:  backend.ObjectInfo{Size: entry.Size, ETag: entry.Hash},
+		})
+	}
+
+	return entries, nil
+}
+
+// call sends an IPFS HTTP API command, mapping error responses to backend
+// errors. The API only accepts POST requests. The caller must close the
+// body of the returned response.
+func (i *IPFSBackend) call(ctx context.Context, command string, params url.Values, body io.Reader, contentType, operation, remotePath string) (*http.Response, error) {
+	u := strings.TrimSuffix(i.cfg.URL, "/") + "/api/v0/" + command + "?" + params.Encode()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new http request: %w", err)
+	}
+
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if i.cfg.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+i.cfg.Token)
+	}
+
+	log.Debugf("POST '%s'...\n", u)
+	response, err := i.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("POST request to %s failed: %w", u, err)
+	}
+
+	log.Debugf("POST request got %d response.\n", response.StatusCode)
+	if common.IsStatusOK(response.StatusCode) {
+		return response, nil
+	}
+
+	// #nosec
+	defer response.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+
+	message := strings.TrimSpace(string(raw))
+	e := &apiError{}
+	if json.Unmarshal(raw, e) == nil && e.Message != "" {
+		message = e.Message
+	}
+
+	switch {
+	case strings.Contains(message, "does not exist") || strings.Contains(message, "not found"):
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	case response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		return nil, &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: message}
+	default:
+		return nil, fmt.Errorf("IPFS %s of '%s' failed with %d status code: %s", command, remotePath, response.StatusCode, message)
+	}
+}
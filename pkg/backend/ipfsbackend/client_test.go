@@ -0,0 +1,236 @@
+package ipfsbackend
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeMFS serves the MFS commands of the IPFS HTTP API used by the backend.
+// CIDs are derived from the contents.
+type fakeMFS struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func fakeCID(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "bafk" + hex.EncodeToString(sum[:8])
+}
+
+func (f *fakeMFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.Header.Get("Authorization") != "Bearer secret" {
+		w.WriteHeader(http.StatusForbidden)
+		return
+	}
+
+	p := r.URL.Query().Get("arg")
+	notExists := func() {
+		w.WriteHeader(http.StatusInternalServerError)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Message": "file does not exist", "Code": 0, "Type": "error"})
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, "/api/v0/") {
+	case "files/write":
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(file)
+		f.files[p] = data
+	case "files/read":
+		data, ok := f.files[p]
+		if !ok {
+			notExists()
+			return
+		}
+		_, _ = w.Write(data)
+	case "files/stat":
+		if data, ok := f.files[p]; ok {
+			_ = json.NewEncoder(w).Encode(stat{Hash: fakeCID(data), Size: int64(len(data)), Type: "file"})
+			return
+		}
+		if len(f.children(p)) == 0 {
+			notExists()
+			return
+		}
+		_ = json.NewEncoder(w).Encode(stat{Hash: "bafydir", Type: "directory"})
+	case "files/ls":
+		children := f.children(p)
+		if len(children) == 0 {
+			notExists()
+			return
+		}
+		entries := []lsEntry{}
+		for name, isDir := range children {
+			entry := lsEntry{Name: name, Type: entryTypeDirectory}
+			if !isDir {
+				data := f.files[path.Join(p, name)]
+				entry = lsEntry{Name: name, Type: entryTypeFile, Size: int64(len(data)), Hash: fakeCID(data)}
+			}
+			entries = append(entries, entry)
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"Entries": entries})
+	case "files/rm":
+		if _, ok := f.files[p]; !ok && len(f.children(p)) == 0 {
+			notExists()
+			return
+		}
+		for key := range f.files {
+			if key == p || strings.HasPrefix(key, p+"/") {
+				delete(f.files, key)
+			}
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// children returns the names of the entries in dir, and whether they are directories.
+func (f *fakeMFS) children(dir string) map[string]bool {
+	children := map[string]bool{}
+	for key := range f.files {
+		rel, ok := strings.CutPrefix(key, strings.TrimSuffix(dir, "/")+"/")
+		if !ok {
+			continue
+		}
+		name, _, isDir := strings.Cut(rel, "/")
+		children[name] = isDir
+	}
+
+	return children
+}
+
+func createTestIPFSBackend(t *testing.T, manifest string) (*IPFSBackend, *fakeMFS) {
+	mfs := &fakeMFS{files: map[string][]byte{}}
+	server := httptest.NewServer(mfs)
+	t.Cleanup(server.Close)
+
+	cfg := &Config{URL: server.URL, Prefix: "ci", Token: "secret", Manifest: manifest}
+	require.NoError(t, cfg.Validate())
+
+	return NewWithConfig(cfg), mfs
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	return p
+}
+
+func TestIPFSBackend_PushPullYank(t *testing.T) {
+	manifest := filepath.Join(t.TempDir(), "cids.jsonl")
+	ipfsBackend, mfs := createTestIPFSBackend(t, manifest)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	localFile := writeTestFile(t, dir, "a.txt", "hello")
+	require.NoError(t, ipfsBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	assert.Equal(t, "hello", string(mfs.files["/ci/artifacts/jobs/1/a.txt"]))
+
+	cid, err := ipfsBackend.CID(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, fakeCID([]byte("hello")), cid)
+
+	exists, err := ipfsBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	err = ipfsBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	checksum, err := ipfsBackend.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", checksum)
+
+	writeTestFile(t, dir, "reports/index.html", "<html>")
+	writeTestFile(t, dir, "reports/css/site.css", "body {}")
+	require.NoError(t, ipfsBackend.Push(ctx, filepath.Join(dir, "reports"), "artifacts/jobs/1/reports", backend.PushOptions{}))
+
+	pulled := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, ipfsBackend.Pull(ctx, "artifacts/jobs/1/reports", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(filepath.Join(pulled, "css", "site.css"))
+	require.NoError(t, err)
+	assert.Equal(t, "body {}", string(data))
+
+	objects := []backend.ObjectInfo{}
+	err = ipfsBackend.List(ctx, "artifacts/jobs/1/", func(info backend.ObjectInfo) error {
+		objects = append(objects, info)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, objects, 3)
+	assert.Equal(t, "artifacts/jobs/1/a.txt", objects[0].Path)
+	assert.Equal(t, cid, objects[0].ETag)
+	assert.Equal(t, "artifacts/jobs/1/reports/css/site.css", objects[1].Path)
+
+	// Every pushed file is recorded in the manifest
+	file, err := os.Open(manifest)
+	require.NoError(t, err)
+	defer file.Close()
+	entries := []ManifestEntry{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		entry := ManifestEntry{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	require.Len(t, entries, 3)
+	assert.Equal(t, "artifacts/jobs/1/a.txt", entries[0].Path)
+	assert.Equal(t, cid, entries[0].CID)
+	assert.Equal(t, checksum, entries[0].SHA256)
+	assert.Equal(t, int64(5), entries[0].Size)
+
+	require.NoError(t, ipfsBackend.Yank(ctx, "artifacts/jobs/1/reports"))
+	require.NoError(t, ipfsBackend.Yank(ctx, "artifacts/jobs/1/missing"))
+	assert.NotContains(t, mfs.files, "/ci/artifacts/jobs/1/reports/index.html")
+	assert.NotContains(t, mfs.files, "/ci/artifacts/jobs/1/.checksums/reports/index.html.sha256")
+	assert.Contains(t, mfs.files, "/ci/artifacts/jobs/1/a.txt")
+
+	err = ipfsBackend.Pull(ctx, "artifacts/jobs/1/reports", pulled, backend.PullOptions{Force: true})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestIPFSBackend_Errors(t *testing.T) {
+	ipfsBackend, _ := createTestIPFSBackend(t, "")
+	ctx := context.Background()
+
+	ipfsBackend.cfg.Token = "wrong"
+	_, err := ipfsBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	var denied *backend.ErrPermissionDenied
+	assert.ErrorAs(t, err, &denied)
+
+	err = ipfsBackend.PushStream(ctx, strings.NewReader("hello"), 5, "a.txt", backend.PushOptions{Lock: &backend.ObjectLock{LegalHold: true}})
+	assert.Equal(t, backend.ErrObjectLockNotSupported, err)
+}
+
+func TestConfig(t *testing.T) {
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, DefaultURL, cfg.URL)
+
+	assert.Error(t, (&Config{URL: "ipfs://node"}).Validate())
+	assert.Equal(t, "/artifacts/jobs/1/a.txt", cfg.mfsPath("artifacts/jobs/1/a.txt"))
+}
@@ -0,0 +1,95 @@
+// Package ipfsbackend implements an experimental content-addressed Backend
+// on the IPFS HTTP API of a Kubo node. Files are written to the node's
+// Mutable File System (MFS), which maps artifact paths to content
+// identifiers (CIDs) and keeps them from being garbage collected.
+// The CID of every pushed file is logged and can be recorded in a local
+// manifest, e.g. for reproducible-build attestations.
+package ipfsbackend
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultURL is the API address of a local Kubo node.
+const DefaultURL = "http://127.0.0.1:5001"
+
+// Config holds IPFS backend configuration.
+type Config struct {
+	// URL is the IPFS HTTP API address, DefaultURL if not set
+	URL string
+
+	// Prefix is the MFS directory artifacts are stored under, / by default
+	Prefix string
+
+	// Token is sent as a bearer token, for API endpoints behind a proxy
+	Token string
+
+	// Manifest is a local file the CIDs of pushed files are appended to,
+	// one JSON object per line. Disabled if not set.
+	Manifest string
+}
+
+// LoadConfig loads IPFS configuration from environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_IPFS_URL (optional, defaults to DefaultURL)
+//   - ARTIFACT_IPFS_PREFIX (optional)
+//   - ARTIFACT_IPFS_TOKEN (optional)
+//   - ARTIFACT_IPFS_MANIFEST (optional)
+//
+// Config file keys (under 'ipfs' section):
+//   - url, prefix, token, manifest
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// Load from environment variables first
+	cfg.URL = os.Getenv("ARTIFACT_IPFS_URL")
+	cfg.Prefix = os.Getenv("ARTIFACT_IPFS_PREFIX")
+	cfg.Token = os.Getenv("ARTIFACT_IPFS_TOKEN")
+	cfg.Manifest = os.Getenv("ARTIFACT_IPFS_MANIFEST")
+
+	// Fall back to config file for unset values
+	if cfg.URL == "" {
+		cfg.URL = viper.GetString("ipfs.url")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = viper.GetString("ipfs.prefix")
+	}
+	if cfg.Token == "" {
+		cfg.Token = viper.GetString("ipfs.token")
+	}
+	if cfg.Manifest == "" {
+		cfg.Manifest = viper.GetString("ipfs.manifest")
+	}
+
+	if cfg.URL == "" {
+		cfg.URL = DefaultURL
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	u, err := url.Parse(c.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid IPFS API URL '%s': use an http or https URL, e.g. %s", c.URL, DefaultURL)
+	}
+
+	return nil
+}
+
+// mfsPath returns the absolute MFS path of remotePath.
+func (c *Config) mfsPath(remotePath string) string {
+	return path.Join("/", c.Prefix, remotePath)
+}
@@ -0,0 +1,393 @@
+// Package memorybackend implements the Backend interface with an in-memory map.
+// It is meant for tests of tools embedding the artifact packages, which can
+// use it instead of a fake S3 server or network access:
+//
+//	b := memorybackend.New()
+//	b.Put("artifacts/jobs/1/app.zip", []byte("..."))
+//	err := b.Pull(ctx, "artifacts/jobs/1/app.zip", "app.zip", backend.PullOptions{})
+//
+// Besides Backend, it implements Lister, StreamPusher, Opener, RangeOpener,
+// Stater, Copier and ChecksumReader, and is safe for concurrent use.
+package memorybackend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+)
+
+type object struct {
+	data     []byte
+	modTime  time.Time
+	metadata map[string]string
+}
+
+// MemoryBackend stores files in memory, keyed by remote path.
+type MemoryBackend struct {
+	mu      sync.RWMutex
+	objects map[string]*object
+}
+
+// New creates an empty MemoryBackend.
+func New() *MemoryBackend {
+	return &MemoryBackend{objects: map[string]*object{}}
+}
+
+// Put stores data at remotePath, overwriting any existing file.
+func (m *MemoryBackend) Put(remotePath string, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.objects[remotePath] = &object{data: append([]byte{}, data...), modTime: time.Now()}
+}
+
+// Get returns the contents of the file at remotePath, if it exists.
+func (m *MemoryBackend) Get(remotePath string) ([]byte, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[remotePath]
+	if !ok {
+		return nil, false
+	}
+
+	return append([]byte{}, obj.data...), true
+}
+
+// Metadata returns the metadata the file at remotePath was pushed with.
+func (m *MemoryBackend) Metadata(remotePath string) map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	metadata := map[string]string{}
+	if obj, ok := m.objects[remotePath]; ok {
+		for key, value := range obj.metadata {
+			metadata[key] = value
+		}
+	}
+
+	return metadata
+}
+
+// Paths returns the remote paths of all stored files, sorted.
+func (m *MemoryBackend) Paths() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.pathsUnder("")
+}
+
+// Push stores a local file, or every file in a local directory.
+func (m *MemoryBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		data, err := os.ReadFile(localPath) // #nosec
+		if err != nil {
+			return err
+		}
+
+		return m.store(remotePath, data, opts)
+	}
+
+	return filepath.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		data, err := os.ReadFile(filePath) // #nosec
+		if err != nil {
+			return err
+		}
+
+		return m.store(path.Join(remotePath, filepath.ToSlash(relPath)), data, opts)
+	})
+}
+
+// PushStream stores everything read from r.
+func (m *MemoryBackend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	return m.store(remotePath, data, opts)
+}
+
+// Pull writes a file, or every file under a directory, to localPath.
+func (m *MemoryBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	files := map[string]string{}
+	if _, ok := m.objects[remotePath]; ok {
+		files[remotePath] = localPath
+	} else {
+		for _, p := range m.pathsUnder(remotePath) {
+			files[p] = filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(p, dirPrefix(remotePath))))
+		}
+	}
+
+	if len(files) == 0 {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	for p, destPath := range files {
+		if !opts.Force {
+			if _, err := os.Stat(destPath); err == nil {
+				return &backend.ErrAlreadyExists{Path: destPath}
+			}
+		}
+
+		if err := os.MkdirAll(filepath.Dir(destPath), 0755); err != nil {
+			return err
+		}
+
+		if err := os.WriteFile(destPath, m.objects[p].data, 0644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Open returns the contents of the file at remotePath.
+func (m *MemoryBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	data, ok := m.Get(remotePath)
+	if !ok {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+// OpenRange returns the contents of the file at remotePath from offset on.
+func (m *MemoryBackend) OpenRange(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error) {
+	data, ok := m.Get(remotePath)
+	if !ok {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+
+	return io.NopCloser(bytes.NewReader(data[offset:])), nil
+}
+
+// Copy copies a file, or every file under a directory, with its metadata.
+func (m *MemoryBackend) Copy(ctx context.Context, srcPath, dstPath string, opts backend.PushOptions) error {
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copies := map[string]*object{}
+	if obj, ok := m.objects[srcPath]; ok {
+		copies[dstPath] = obj
+	} else {
+		for _, p := range m.pathsUnder(srcPath) {
+			copies[path.Join(dstPath, strings.TrimPrefix(p, dirPrefix(srcPath)))] = m.objects[p]
+		}
+	}
+
+	if len(copies) == 0 {
+		return &backend.ErrNotFound{Path: srcPath}
+	}
+
+	if !opts.Force {
+		for p := range copies {
+			if _, ok := m.objects[p]; ok {
+				return &backend.ErrAlreadyExists{Path: p}
+			}
+		}
+	}
+
+	for p, obj := range copies {
+		metadata := map[string]string{}
+		for _, source := range []map[string]string{obj.metadata, opts.Metadata} {
+			for key, value := range source {
+				metadata[key] = value
+			}
+		}
+
+		m.objects[p] = &object{data: obj.data, modTime: time.Now(), metadata: metadata}
+	}
+
+	return nil
+}
+
+// Yank deletes a file, or every file under a directory.
+func (m *MemoryBackend) Yank(ctx context.Context, remotePath string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range m.pathsUnder(remotePath) {
+		delete(m.objects, p)
+	}
+	delete(m.objects, remotePath)
+
+	return nil
+}
+
+// Exists checks if a file exists at remotePath.
+func (m *MemoryBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	_, ok := m.Get(remotePath)
+	return ok, nil
+}
+
+// List calls fn for every file whose path starts with remotePrefix, in key order.
+func (m *MemoryBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	m.mu.RLock()
+	infos := []backend.ObjectInfo{}
+	for _, p := range m.pathsUnder("") {
+		if !strings.HasPrefix(p, remotePrefix) {
+			continue
+		}
+
+		obj := m.objects[p]
+		infos = append(infos, backend.ObjectInfo{Path: p, Size: int64(len(obj.data)), ModTime: obj.modTime, ETag: checksum(obj.data)})
+	}
+	m.mu.RUnlock()
+
+	// The lock is released, so fn may call back into the backend
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			if err == backend.StopListing {
+				return nil
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Stat describes the file at remotePath. Files have no content type.
+func (m *MemoryBackend) Stat(ctx context.Context, remotePath string) (*backend.ObjectInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	obj, ok := m.objects[remotePath]
+	if !ok {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return &backend.ObjectInfo{Path: remotePath, Size: int64(len(obj.data)), ModTime: obj.modTime, ETag: checksum(obj.data)}, nil
+}
+
+// Checksum returns the SHA256 checksum of the file at remotePath.
+func (m *MemoryBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	data, ok := m.Get(remotePath)
+	if !ok {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return checksum(data), nil
+}
+
+// Close is a no-op; stored files are kept.
+func (m *MemoryBackend) Close() error {
+	return nil
+}
+
+func (m *MemoryBackend) store(remotePath string, data []byte, opts backend.PushOptions) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.objects[remotePath]; ok && !opts.Force {
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	}
+
+	metadata := map[string]string{}
+	for key, value := range opts.Metadata {
+		metadata[key] = value
+	}
+
+	m.objects[remotePath] = &object{data: data, modTime: time.Now(), metadata: metadata}
+	return nil
+}
+
+// pathsUnder returns the sorted paths of the files in directory dir,
+// or of all files if dir is "". The caller must hold the lock.
+func (m *MemoryBackend) pathsUnder(dir string) []string {
+	prefix := dirPrefix(dir)
+
+	paths := []string{}
+	for p := range m.objects {
+		if strings.HasPrefix(p, prefix) {
+			paths = append(paths, p)
+		}
+	}
+
+	sort.Strings(paths)
+	return paths
+}
+
+func dirPrefix(dir string) string {
+	dir = strings.TrimSuffix(dir, "/")
+	if dir == "" {
+		return ""
+	}
+
+	return dir + "/"
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
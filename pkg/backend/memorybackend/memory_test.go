@@ -0,0 +1,101 @@
+package memorybackend
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryBackend_PushPullYank(t *testing.T) {
+	b := New()
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "data", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data", "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data", "sub", "b.txt"), []byte("b"), 0644))
+
+	opts := backend.PushOptions{Metadata: map[string]string{"owner": "ci"}}
+	require.NoError(t, b.Push(ctx, filepath.Join(tmpDir, "data"), "artifacts/jobs/1/data", opts))
+	assert.Equal(t, []string{"artifacts/jobs/1/data/a.txt", "artifacts/jobs/1/data/sub/b.txt"}, b.Paths())
+	assert.Equal(t, map[string]string{"owner": "ci"}, b.Metadata("artifacts/jobs/1/data/a.txt"))
+
+	err := b.Push(ctx, filepath.Join(tmpDir, "data", "a.txt"), "artifacts/jobs/1/data/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	pulled := filepath.Join(tmpDir, "pulled")
+	require.NoError(t, b.Pull(ctx, "artifacts/jobs/1/data", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(filepath.Join(pulled, "sub", "b.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "b", string(data))
+
+	err = b.Pull(ctx, "artifacts/jobs/1/data", pulled, backend.PullOptions{})
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	err = b.Pull(ctx, "artifacts/jobs/1/missing", pulled, backend.PullOptions{})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	// Yanking a directory does not touch siblings sharing its prefix
+	b.Put("artifacts/jobs/1/data-old.txt", []byte("old"))
+	require.NoError(t, b.Yank(ctx, "artifacts/jobs/1/data"))
+	assert.Equal(t, []string{"artifacts/jobs/1/data-old.txt"}, b.Paths())
+
+	exists, err := b.Exists(ctx, "artifacts/jobs/1/data-old.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestMemoryBackend_Capabilities(t *testing.T) {
+	b := New()
+	ctx := context.Background()
+
+	require.NoError(t, b.PushStream(ctx, strings.NewReader("hello"), -1, "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	b.Put("artifacts/jobs/1/b.txt", []byte("world"))
+	b.Put("artifacts/jobs/2/c.txt", []byte("!"))
+
+	r, err := b.Open(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(r)
+	assert.Equal(t, "hello", string(data))
+
+	checksum, err := b.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", checksum)
+
+	listed := []string{}
+	err = b.List(ctx, "artifacts/jobs/1/", func(obj backend.ObjectInfo) error {
+		listed = append(listed, obj.Path)
+		return backend.StopListing
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"artifacts/jobs/1/a.txt"}, listed)
+}
+
+func TestMemoryBackend_Concurrent(t *testing.T) {
+	b := New()
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "artifacts/jobs/1/" + string(rune('a'+i)) + ".txt"
+			assert.NoError(t, b.PushStream(ctx, strings.NewReader(name), -1, name, backend.PushOptions{}))
+			_, _ = b.Exists(ctx, name)
+		}(i)
+	}
+
+	wg.Wait()
+	assert.Len(t, b.Paths(), 20)
+}
@@ -0,0 +1,80 @@
+// Package mirrorbackend implements backends on top of two or more other
+// backends, its members. Reads are served by the first member that has the file.
+//
+// MirrorBackend pushes and yanks on every member, keeping artifacts replicated
+// while moving between stores, e.g. from Hub to an S3 bucket.
+//
+// FallbackBackend pushes and yanks on the last member only, the source of truth,
+// and reads from nearby copies first, e.g. a regional bucket in front of Hub.
+package mirrorbackend
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/spf13/viper"
+)
+
+// Config holds mirror or fallback backend configuration.
+type Config struct {
+	// Type is backend.BackendTypeMirror or backend.BackendTypeFallback
+	Type backend.BackendType
+
+	// Backends are the settings of the member backends, in read order,
+	// e.g. hub, s3 or exec:/path/to/plugin
+	Backends []string
+}
+
+// LoadConfig loads the configuration of a mirror or fallback backend from
+// environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_MIRROR_BACKENDS or ARTIFACT_FALLBACK_BACKENDS (required),
+//     comma-separated, e.g. hub,s3
+//
+// Config file keys (under 'mirror' or 'fallback' section):
+//   - backends, a list
+func LoadConfig(backendType backend.BackendType) (*Config, error) {
+	cfg := &Config{Type: backendType}
+
+	if backends := os.Getenv(cfg.envVar()); backends != "" {
+		for _, setting := range strings.Split(backends, ",") {
+			cfg.Backends = append(cfg.Backends, strings.TrimSpace(setting))
+		}
+	} else {
+		cfg.Backends = viper.GetStringSlice(string(backendType) + ".backends")
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	if len(c.Backends) < 2 {
+		return fmt.Errorf("%s backend needs at least two backends: set %s environment variable or %s.backends in config, e.g. hub,s3", c.Type, c.envVar(), c.Type)
+	}
+
+	for _, setting := range c.Backends {
+		backendType, _, ok := backend.ParseBackendSetting(setting)
+		if !ok {
+			return fmt.Errorf("invalid %s backend '%s'", c.Type, setting)
+		}
+
+		if backendType == backend.BackendTypeMirror || backendType == backend.BackendTypeFallback {
+			return fmt.Errorf("%s backends cannot contain %s backends", c.Type, backendType)
+		}
+	}
+
+	return nil
+}
+
+func (c *Config) envVar() string {
+	return "ARTIFACT_" + strings.ToUpper(string(c.Type)) + "_BACKENDS"
+}
@@ -0,0 +1,68 @@
+package mirrorbackend
+
+import (
+	"context"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterFallbackBackend(func() (backend.Backend, error) {
+		return NewFallback()
+	})
+}
+
+// FallbackBackend implements the Backend interface by reading from the first
+// member that has a file, and writing to the last member, the source of truth.
+type FallbackBackend struct {
+	members
+}
+
+// NewFallback creates a new FallbackBackend from configuration.
+func NewFallback() (*FallbackBackend, error) {
+	cfg, err := LoadConfig(backend.BackendTypeFallback)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFallbackWithConfig(cfg)
+}
+
+// NewFallbackWithConfig creates the member backends in cfg and reads from them in order.
+func NewFallbackWithConfig(cfg *Config) (*FallbackBackend, error) {
+	created, err := createMembers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FallbackBackend{members: created}, nil
+}
+
+// NewFallbackWithMembers reads from already created backends, in order.
+// The last one is the source of truth.
+func NewFallbackWithMembers(ms []Member) *FallbackBackend {
+	return &FallbackBackend{members: ms}
+}
+
+// Push uploads a local file or directory to the source of truth.
+// The other members are expected to be filled by their own replication.
+func (f *FallbackBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("FallbackBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	return f.source().Push(ctx, localPath, remotePath, opts)
+}
+
+// Yank deletes a file or directory from the source of truth.
+func (f *FallbackBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("FallbackBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	return f.source().Yank(ctx, remotePath)
+}
+
+func (f *FallbackBackend) source() backend.Backend {
+	return f.members[len(f.members)-1].Backend
+}
@@ -0,0 +1,50 @@
+package mirrorbackend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFallbackBackend(t *testing.T) {
+	regional, hub := newFakeBackend(), newFakeBackend()
+	fallback := NewFallbackWithMembers([]Member{{Name: "s3", Backend: regional}, {Name: "hub", Backend: hub}})
+	ctx := context.Background()
+	pulled := filepath.Join(t.TempDir(), "pulled.txt")
+
+	// Writes only go to the source of truth
+	require.NoError(t, fallback.Push(ctx, writeTestFile(t, "from hub"), "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	assert.Contains(t, hub.files, "artifacts/jobs/1/a.txt")
+	assert.Empty(t, regional.files)
+
+	require.NoError(t, fallback.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "from hub", string(data))
+
+	// Nearby copies are preferred
+	regional.files["artifacts/jobs/1/a.txt"] = []byte("from s3")
+	require.NoError(t, fallback.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{Force: true}))
+	data, err = os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "from s3", string(data))
+
+	regional.down = true
+	exists, err := fallback.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// A file missing from the source may be on an unavailable member
+	_, err = fallback.Exists(ctx, "artifacts/jobs/1/b.txt")
+	assert.ErrorIs(t, err, errDown)
+
+	regional.down = false
+	require.NoError(t, fallback.Yank(ctx, "artifacts/jobs/1/a.txt"))
+	assert.Empty(t, hub.files)
+	assert.Contains(t, regional.files, "artifacts/jobs/1/a.txt")
+}
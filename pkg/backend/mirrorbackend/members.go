@@ -0,0 +1,216 @@
+package mirrorbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// Member is a backend taking part in a mirror or fallback backend.
+type Member struct {
+	Name    string // the backend setting, used in messages
+	Backend backend.Backend
+}
+
+// members implements the operations shared by mirror and fallback backends.
+type members []Member
+
+// createMembers creates the member backends in cfg.
+func createMembers(cfg *Config) (members, error) {
+	created := members{}
+	for _, setting := range cfg.Backends {
+		b, err := backend.NewBackendFor(setting)
+		if err != nil {
+			_ = created.Close()
+			return nil, fmt.Errorf("failed to create %s backend '%s': %w", cfg.Type, setting, err)
+		}
+
+		created = append(created, Member{Name: setting, Backend: b})
+	}
+
+	log.Debugf("Created %s backend\n", cfg.Type)
+	log.Debugf("* Backends: %v\n", cfg.Backends)
+
+	return created, nil
+}
+
+// Pull downloads a file or directory from the first member that has it.
+func (ms members) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	return ms.firstAvailable(remotePath, func(b backend.Backend) error {
+		return b.Pull(ctx, remotePath, localPath, opts)
+	})
+}
+
+// Exists checks the members in order, until one of them has the file.
+func (ms members) Exists(ctx context.Context, remotePath string) (bool, error) {
+	var exists bool
+	err := ms.firstAvailable(remotePath, func(b backend.Backend) error {
+		var err error
+		exists, err = b.Exists(ctx, remotePath)
+		if err == nil && !exists {
+			return &backend.ErrNotFound{Path: remotePath}
+		}
+		return err
+	})
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+
+	return exists, err
+}
+
+// List lists the objects of the first member that supports listing.
+func (ms members) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	for _, member := range ms {
+		if lister, ok := member.Backend.(backend.Lister); ok {
+			return lister.List(ctx, remotePrefix, fn)
+		}
+	}
+
+	return backend.ErrListingNotSupported
+}
+
+// Open streams a file from the first member that has it and supports streaming.
+func (ms members) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	var r io.ReadCloser
+	err := ms.firstAvailable(remotePath, func(b backend.Backend) error {
+		opener, ok := b.(backend.Opener)
+		if !ok {
+			return backend.ErrOpenNotSupported
+		}
+
+		var err error
+		r, err = opener.Open(ctx, remotePath)
+		return err
+	})
+
+	return r, err
+}
+
+// Stat describes a file of the first member that has it.
+func (ms members) Stat(ctx context.Context, remotePath string) (*backend.ObjectInfo, error) {
+	var info *backend.ObjectInfo
+	err := ms.firstAvailable(remotePath, func(b backend.Backend) error {
+		var err error
+		info, err = backend.Stat(ctx, b, remotePath)
+		return err
+	})
+
+	return info, err
+}
+
+// Share creates a link to a file of the first member that has it.
+func (ms members) Share(ctx context.Context, remotePath string, expiresIn time.Duration) (string, time.Time, error) {
+	var link string
+	var expires time.Time
+	err := ms.firstAvailable(remotePath, func(b backend.Backend) error {
+		sharer, ok := b.(backend.Sharer)
+		if !ok {
+			return backend.ErrSharingNotSupported
+		}
+
+		var err error
+		link, expires, err = sharer.Share(ctx, remotePath, expiresIn)
+		return err
+	})
+
+	return link, expires, err
+}
+
+// Checksum returns the checksum stored by the first member that has the file.
+func (ms members) Checksum(ctx context.Context, remotePath string) (string, error) {
+	var checksum string
+	err := ms.firstAvailable(remotePath, func(b backend.Backend) error {
+		reader, ok := b.(backend.ChecksumReader)
+		if !ok {
+			return fmt.Errorf("checksums are not supported")
+		}
+
+		var err error
+		checksum, err = reader.Checksum(ctx, remotePath)
+		return err
+	})
+
+	return checksum, err
+}
+
+// Capabilities are the ones of any member, since reads go to the first
+// member that can serve them. Copies and streamed pushes are not fanned
+// out.
+func (ms members) Capabilities() backend.Capabilities {
+	capabilities := backend.Capabilities{}
+	for _, member := range ms {
+		c := backend.CapabilitiesOf(member.Backend)
+		capabilities.List = capabilities.List || c.List
+		capabilities.Share = capabilities.Share || c.Share
+		capabilities.Stat = capabilities.Stat || c.Stat
+		capabilities.Open = capabilities.Open || c.Open
+		capabilities.Checksum = capabilities.Checksum || c.Checksum
+	}
+
+	return capabilities
+}
+
+// Close closes every member.
+func (ms members) Close() error {
+	return ms.fanOut(func(b backend.Backend) error {
+		return b.Close()
+	})
+}
+
+// fanOut runs fn against every member and reports the members it failed on.
+// Errors keep their type, so callers can still check for ErrAlreadyExists.
+func (ms members) fanOut(fn func(backend.Backend) error) error {
+	errs := []error{}
+	for _, member := range ms {
+		if err := fn(member.Backend); err != nil {
+			errs = append(errs, fmt.Errorf("backend '%s': %w", member.Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// firstAvailable runs fn against the members in order until it succeeds.
+// Failed members are reported as warnings. If the file exists locally,
+// no other members are tried. If no member has the file, ErrNotFound is returned,
+// unless a member failed and might have it.
+func (ms members) firstAvailable(remotePath string, fn func(backend.Backend) error) error {
+	var lastErr error
+	failed := false
+
+	for _, member := range ms {
+		err := fn(member.Backend)
+		if err == nil {
+			return nil
+		}
+
+		var alreadyExists *backend.ErrAlreadyExists
+		if errors.As(err, &alreadyExists) {
+			return err
+		}
+
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			log.Debugf("'%s' not found in backend '%s'\n", remotePath, member.Name)
+			continue
+		}
+
+		log.Warnf("Backend '%s' is unavailable, trying the next one: %v\n", member.Name, err)
+		lastErr = fmt.Errorf("backend '%s': %w", member.Name, err)
+		failed = true
+	}
+
+	if failed {
+		return lastErr
+	}
+
+	return &backend.ErrNotFound{Path: remotePath}
+}
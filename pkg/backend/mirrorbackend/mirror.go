@@ -0,0 +1,76 @@
+package mirrorbackend
+
+import (
+	"context"
+	"errors"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterMirrorBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// MirrorBackend implements the Backend interface by fanning out writes
+// to all members and reading from the first member available.
+type MirrorBackend struct {
+	members
+}
+
+// New creates a new MirrorBackend from configuration.
+func New() (*MirrorBackend, error) {
+	cfg, err := LoadConfig(backend.BackendTypeMirror)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg)
+}
+
+// NewWithConfig creates the member backends in cfg and mirrors them.
+func NewWithConfig(cfg *Config) (*MirrorBackend, error) {
+	created, err := createMembers(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	return &MirrorBackend{members: created}, nil
+}
+
+// NewWithMembers mirrors already created backends, in read order.
+func NewWithMembers(ms []Member) *MirrorBackend {
+	return &MirrorBackend{members: ms}
+}
+
+// Push uploads a local file or directory to every member.
+// A failure on one member does not stop the others.
+func (m *MirrorBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("MirrorBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	return m.fanOut(func(b backend.Backend) error {
+		return b.Push(ctx, localPath, remotePath, opts)
+	})
+}
+
+// Yank deletes a file or directory from every member.
+// Members that do not have it are skipped.
+func (m *MirrorBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("MirrorBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	return m.fanOut(func(b backend.Backend) error {
+		err := b.Yank(ctx, remotePath)
+
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return nil
+		}
+
+		return err
+	})
+}
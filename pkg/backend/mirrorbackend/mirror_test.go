@@ -0,0 +1,204 @@
+package mirrorbackend
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeBackend keeps files in memory, and fails every operation when down.
+type fakeBackend struct {
+	files  map[string][]byte
+	down   bool
+	closed bool
+}
+
+var errDown = errors.New("connection refused")
+
+func newFakeBackend() *fakeBackend {
+	return &fakeBackend{files: map[string][]byte{}}
+}
+
+func (f *fakeBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if f.down {
+		return errDown
+	}
+	if _, ok := f.files[remotePath]; ok && !opts.Force {
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	}
+
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	f.files[remotePath] = data
+	return nil
+}
+
+func (f *fakeBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if f.down {
+		return errDown
+	}
+
+	data, ok := f.files[remotePath]
+	if !ok {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return os.WriteFile(localPath, data, 0644)
+}
+
+func (f *fakeBackend) Yank(ctx context.Context, remotePath string) error {
+	if f.down {
+		return errDown
+	}
+	if _, ok := f.files[remotePath]; !ok {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	delete(f.files, remotePath)
+	return nil
+}
+
+func (f *fakeBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	if f.down {
+		return false, errDown
+	}
+
+	_, ok := f.files[remotePath]
+	return ok, nil
+}
+
+func (f *fakeBackend) Close() error {
+	f.closed = true
+	return nil
+}
+
+func createTestMirror(t *testing.T) (*MirrorBackend, *fakeBackend, *fakeBackend) {
+	hub, s3 := newFakeBackend(), newFakeBackend()
+	return NewWithMembers([]Member{{Name: "hub", Backend: hub}, {Name: "s3", Backend: s3}}), hub, s3
+}
+
+func writeTestFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	return path
+}
+
+func TestMirrorBackend_Push(t *testing.T) {
+	mirror, hub, s3 := createTestMirror(t)
+	ctx := context.Background()
+	localFile := writeTestFile(t, "hello")
+
+	require.NoError(t, mirror.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	assert.Equal(t, []byte("hello"), hub.files["artifacts/jobs/1/a.txt"])
+	assert.Equal(t, []byte("hello"), s3.files["artifacts/jobs/1/a.txt"])
+
+	err := mirror.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	// A failing member does not stop the others, and is named in the error
+	hub.down = true
+	err = mirror.Push(ctx, localFile, "artifacts/jobs/1/b.txt", backend.PushOptions{})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backend 'hub': connection refused")
+	assert.NotContains(t, err.Error(), "'s3'")
+	assert.Contains(t, s3.files, "artifacts/jobs/1/b.txt")
+}
+
+func TestMirrorBackend_Pull(t *testing.T) {
+	mirror, hub, s3 := createTestMirror(t)
+	ctx := context.Background()
+	pulled := filepath.Join(t.TempDir(), "pulled.txt")
+
+	// Files missing from the first member are read from the next one
+	s3.files["artifacts/jobs/1/a.txt"] = []byte("from s3")
+	require.NoError(t, mirror.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "from s3", string(data))
+
+	// Unavailable members are skipped
+	hub.files["artifacts/jobs/1/b.txt"] = []byte("from hub")
+	s3.files["artifacts/jobs/1/b.txt"] = []byte("from s3")
+	hub.down = true
+	require.NoError(t, mirror.Pull(ctx, "artifacts/jobs/1/b.txt", pulled, backend.PullOptions{Force: true}))
+	data, err = os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "from s3", string(data))
+
+	hub.down = false
+	exists, err := mirror.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = mirror.Exists(ctx, "artifacts/jobs/1/missing.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = mirror.Pull(ctx, "artifacts/jobs/1/missing.txt", pulled, backend.PullOptions{Force: true})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	s3.down = true
+	err = mirror.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{Force: true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "backend 's3': connection refused")
+
+	err = mirror.List(ctx, "artifacts/", func(backend.ObjectInfo) error { return nil })
+	assert.Equal(t, backend.ErrListingNotSupported, err)
+}
+
+func TestMirrorBackend_YankAndClose(t *testing.T) {
+	mirror, hub, s3 := createTestMirror(t)
+	ctx := context.Background()
+
+	// Files only some members have are yanked where they exist
+	hub.files["artifacts/jobs/1/a.txt"] = []byte("hello")
+	require.NoError(t, mirror.Yank(ctx, "artifacts/jobs/1/a.txt"))
+	assert.Empty(t, hub.files)
+
+	s3.down = true
+	err := mirror.Yank(ctx, "artifacts/jobs/1/a.txt")
+	assert.ErrorIs(t, err, errDown)
+
+	require.NoError(t, mirror.Close())
+	assert.True(t, hub.closed)
+	assert.True(t, s3.closed)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	mirror := backend.BackendTypeMirror
+	assert.NoError(t, (&Config{Type: mirror, Backends: []string{"hub", "s3"}}).Validate())
+	assert.NoError(t, (&Config{Type: mirror, Backends: []string{"s3", "exec:/usr/local/bin/plugin", "plugin:ceph"}}).Validate())
+
+	err := (&Config{Type: mirror}).Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ARTIFACT_MIRROR_BACKENDS")
+
+	assert.Error(t, (&Config{Type: mirror, Backends: []string{"s3"}}).Validate())
+	assert.Error(t, (&Config{Type: mirror, Backends: []string{"s3", "gopher"}}).Validate())
+	assert.Error(t, (&Config{Type: mirror, Backends: []string{"s3", "mirror"}}).Validate())
+	assert.Error(t, (&Config{Type: mirror, Backends: []string{"s3", "fallback"}}).Validate())
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Setenv("ARTIFACT_MIRROR_BACKENDS", "hub, s3")
+	t.Setenv("ARTIFACT_FALLBACK_BACKENDS", "s3,hub")
+
+	cfg, err := LoadConfig(backend.BackendTypeMirror)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hub", "s3"}, cfg.Backends)
+
+	cfg, err = LoadConfig(backend.BackendTypeFallback)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"s3", "hub"}, cfg.Backends)
+}
@@ -0,0 +1,124 @@
+package backend
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"github.com/semaphoreci/artifact/pkg/retry"
+)
+
+// DefaultConcurrency is how many files a directory push or pull transfers
+// at once, unless its options say otherwise.
+const DefaultConcurrency = 8
+
+// Parallel calls fn for every index from 0 to n-1, with at most
+// concurrency calls running at once, or DefaultConcurrency if it is not
+// positive. While the storage throttles requests, fewer calls run at once
+// (see retry.Limiter). Once a call fails, no new calls are started, and
+// the first error is returned after the running ones finish.
+func Parallel(n, concurrency int, fn func(i int) error) error {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	limiter := retry.NewLimiter(concurrency)
+
+	indexes := make(chan int)
+	failed := make(chan struct{})
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for w := 0; w < min(concurrency, n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				limiter.Acquire()
+				err := fn(i)
+				limiter.Release()
+
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						close(failed)
+					})
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case indexes <- i:
+		case <-failed:
+			break feed
+		}
+	}
+
+	close(indexes)
+	wg.Wait()
+	return firstErr
+}
+
+// ParallelEach is like Parallel, but calls fn for every item received from
+// items until it is closed, so items can still be produced while the first
+// ones are processed. Once a call fails, the remaining items are received
+// without calling fn, so producers never block.
+func ParallelEach[T any](items <-chan T, concurrency int, fn func(T) error) error {
+	if concurrency < 1 {
+		concurrency = DefaultConcurrency
+	}
+
+	limiter := retry.NewLimiter(concurrency)
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		failed   atomic.Bool
+		firstErr error
+	)
+
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for item := range items {
+				if failed.Load() {
+					continue
+				}
+
+				limiter.Acquire()
+				err := fn(item)
+				limiter.Release()
+
+				if err != nil {
+					once.Do(func() {
+						firstErr = err
+						failed.Store(true)
+					})
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+// ParallelAll is like Parallel, but keeps calling fn after failures, and
+// returns the errors of every failed call joined.
+func ParallelAll(n, concurrency int, fn func(i int) error) error {
+	errs := make([]error, n)
+	_ = Parallel(n, concurrency, func(i int) error {
+		errs[i] = fn(i)
+		return nil
+	})
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,114 @@
+package backend_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallel(t *testing.T) {
+	var running, peak int32
+	var mu sync.Mutex
+	done := map[int]bool{}
+
+	err := backend.Parallel(20, 3, func(i int) error {
+		now := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+
+		for {
+			old := atomic.LoadInt32(&peak)
+			if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+				break
+			}
+		}
+
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		done[i] = true
+		mu.Unlock()
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, done, 20)
+	assert.LessOrEqual(t, peak, int32(3))
+}
+
+func TestParallel_StopsOnError(t *testing.T) {
+	var calls int32
+	err := backend.Parallel(100, 2, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 0 {
+			return errors.New("upload failed")
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	assert.EqualError(t, err, "upload failed")
+	assert.Less(t, atomic.LoadInt32(&calls), int32(100))
+
+	assert.NoError(t, backend.Parallel(0, 0, func(i int) error { return errors.New("not called") }))
+}
+
+func TestParallelEach(t *testing.T) {
+	items := make(chan int)
+	go func() {
+		defer close(items)
+		for i := 0; i < 20; i++ {
+			items <- i
+		}
+	}()
+
+	var sum int32
+	err := backend.ParallelEach(items, 3, func(i int) error {
+		atomic.AddInt32(&sum, int32(i))
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, int32(190), sum)
+
+	// Items are still received after a failure, without calling fn
+	items = make(chan int)
+	go func() {
+		defer close(items)
+		for i := 0; i < 100; i++ {
+			items <- i
+		}
+	}()
+
+	var calls int32
+	err = backend.ParallelEach(items, 2, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i == 0 {
+			return errors.New("upload failed")
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+
+	assert.EqualError(t, err, "upload failed")
+	assert.Less(t, atomic.LoadInt32(&calls), int32(100))
+}
+
+func TestParallelAll(t *testing.T) {
+	var calls int32
+	err := backend.ParallelAll(10, 3, func(i int) error {
+		atomic.AddInt32(&calls, 1)
+		if i%4 == 0 {
+			return fmt.Errorf("file %d failed", i)
+		}
+		return nil
+	})
+
+	assert.Equal(t, int32(10), calls)
+	assert.EqualError(t, err, "file 0 failed\nfile 4 failed\nfile 8 failed")
+	assert.NoError(t, backend.ParallelAll(3, 0, func(i int) error { return nil }))
+}
@@ -0,0 +1,162 @@
+// Package pluginbackend loads compiled backend plugins over hashicorp/go-plugin,
+// so third parties can ship backends for storage systems the CLI does not
+// support, e.g. Ceph RGW or proprietary object stores.
+//
+// Plugins are executables named artifact-backend-<name> in the plugin
+// directory, and are selected with ARTIFACT_BACKEND=plugin:<name>.
+// A plugin implements backend.Backend and serves it from main:
+//
+//	func main() {
+//		pluginbackend.Serve(&MyBackend{})
+//	}
+//
+// Unlike exec plugins, which are started for every operation, a go-plugin
+// backend runs for the lifetime of the command.
+package pluginbackend
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/hashicorp/go-plugin"
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// ExecutablePrefix precedes the backend name in plugin executables.
+const ExecutablePrefix = "artifact-backend-"
+
+func init() {
+	backend.RegisterPluginBackend(func(name string) (backend.Backend, error) {
+		return New(name)
+	})
+}
+
+// PluginBackend is a backend served by a plugin process.
+type PluginBackend struct {
+	backend.Backend
+	client *plugin.Client
+}
+
+// Dir returns the plugin directory: ARTIFACT_PLUGIN_DIR, the pluginDir
+// config key, or $HOME/.artifact/plugins.
+func Dir() (string, error) {
+	if dir := os.Getenv("ARTIFACT_PLUGIN_DIR"); dir != "" {
+		return dir, nil
+	}
+
+	if dir := viper.GetString("pluginDir"); dir != "" {
+		return dir, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".artifact", "plugins"), nil
+}
+
+// Discover returns the names of the backend plugins in dir, sorted.
+// A missing directory has no plugins.
+func Discover(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plugin directory '%s': %w", dir, err)
+	}
+
+	names := []string{}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), ExecutablePrefix) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+			continue
+		}
+
+		names = append(names, strings.TrimPrefix(entry.Name(), ExecutablePrefix))
+	}
+
+	sort.Strings(names)
+	return names, nil
+}
+
+// New starts the plugin with the given name from the plugin directory.
+func New(name string) (*PluginBackend, error) {
+	if name == "" {
+		return nil, fmt.Errorf("plugin backend needs a name: set ARTIFACT_BACKEND=%s<name>", backend.PluginBackendPrefix)
+	}
+
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := Discover(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debug("PluginBackend: Plugins discovered\n")
+	log.Debugf("* Directory: %s\n", dir)
+	log.Debugf("* Plugins: %s\n", strings.Join(names, ", "))
+
+	for _, found := range names {
+		if found == name {
+			return NewWithPath(filepath.Join(dir, ExecutablePrefix+name))
+		}
+	}
+
+	return nil, fmt.Errorf("backend plugin '%s' not found in '%s': install it as %s%s", name, dir, ExecutablePrefix, name)
+}
+
+// NewWithPath starts the plugin executable at path.
+func NewWithPath(path string) (*PluginBackend, error) {
+	level := hclog.Error
+	if log.IsLevelEnabled(log.DebugLevel) {
+		level = hclog.Debug
+	}
+
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          plugin.PluginSet{pluginName: &grpcPlugin{}},
+		Cmd:              exec.Command(path), // #nosec
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolGRPC},
+		Logger:           hclog.New(&hclog.LoggerOptions{Name: "plugin", Output: os.Stderr, Level: level}),
+	})
+
+	rpcClient, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to start backend plugin '%s': %w", path, err)
+	}
+
+	raw, err := rpcClient.Dispense(pluginName)
+	if err != nil {
+		client.Kill()
+		return nil, fmt.Errorf("failed to load backend plugin '%s': %w", path, err)
+	}
+
+	log.Debug("PluginBackend: Plugin started\n")
+	log.Debugf("* Path: %s\n", path)
+
+	return &PluginBackend{Backend: raw.(backend.Backend), client: client}, nil
+}
+
+// Close stops the plugin process.
+func (p *PluginBackend) Close() error {
+	p.client.Kill()
+	return nil
+}
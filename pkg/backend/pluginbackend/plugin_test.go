@@ -0,0 +1,146 @@
+package pluginbackend
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary doubles as a plugin serving testBackend.
+func TestMain(m *testing.M) {
+	if os.Getenv(Handshake.MagicCookieKey) == Handshake.MagicCookieValue {
+		Serve(&testBackend{files: map[string][]byte{}})
+		os.Exit(0)
+	}
+
+	os.Exit(m.Run())
+}
+
+// testBackend keeps files in memory, and only lives as long as the plugin process.
+type testBackend struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+func (b *testBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.files[remotePath]; ok && !opts.Force {
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	}
+
+	if strings.HasPrefix(remotePath, "readonly/") {
+		return &backend.ErrPermissionDenied{Operation: "push", Path: remotePath, Reason: "read-only"}
+	}
+
+	data, err := os.ReadFile(localPath)
+	if err != nil {
+		return err
+	}
+
+	b.files[remotePath] = append(data, []byte(opts.Metadata["suffix"])...)
+	return nil
+}
+
+func (b *testBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	data, ok := b.files[remotePath]
+	if !ok {
+		return &backend.ErrNotFound{Path: remotePath}
+	}
+
+	return os.WriteFile(localPath, data, 0644)
+}
+
+func (b *testBackend) Yank(ctx context.Context, remotePath string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.files, remotePath)
+	return nil
+}
+
+func (b *testBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	_, ok := b.files[remotePath]
+	return ok, nil
+}
+
+func (b *testBackend) Close() error {
+	return nil
+}
+
+func TestPluginBackend(t *testing.T) {
+	pluginBackend, err := NewWithPath(os.Args[0])
+	require.NoError(t, err)
+	defer pluginBackend.Close()
+
+	ctx := context.Background()
+	localFile := filepath.Join(t.TempDir(), "a.txt")
+	require.NoError(t, os.WriteFile(localFile, []byte("hello"), 0644))
+
+	opts := backend.PushOptions{Metadata: map[string]string{"suffix": "!"}}
+	require.NoError(t, pluginBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", opts))
+
+	exists, err := pluginBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	err = pluginBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	err = pluginBackend.Push(ctx, localFile, "readonly/a.txt", backend.PushOptions{})
+	var denied *backend.ErrPermissionDenied
+	require.ErrorAs(t, err, &denied)
+	assert.Equal(t, "read-only", denied.Reason)
+
+	pulled := filepath.Join(t.TempDir(), "pulled.txt")
+	require.NoError(t, pluginBackend.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, "hello!", string(data))
+
+	require.NoError(t, pluginBackend.Yank(ctx, "artifacts/jobs/1/a.txt"))
+	err = pluginBackend.Pull(ctx, "artifacts/jobs/1/a.txt", pulled, backend.PullOptions{})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "artifact-backend-ceph"), []byte{}, 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "artifact-backend-notes.txt"), []byte{}, 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other-tool"), []byte{}, 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "artifact-backend-dir"), 0755))
+
+	names, err := Discover(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ceph"}, names)
+
+	names, err = Discover(filepath.Join(dir, "missing"))
+	require.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func TestNew_PluginNotFound(t *testing.T) {
+	t.Setenv("ARTIFACT_BACKEND", "plugin:missing")
+	t.Setenv("ARTIFACT_PLUGIN_DIR", t.TempDir())
+	assert.Equal(t, backend.BackendTypePlugin, backend.GetBackendType())
+
+	_, err := New("missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "artifact-backend-missing")
+}
@@ -0,0 +1,268 @@
+package pluginbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// Handshake must match between the CLI and its plugins. ProtocolVersion
+// is increased on incompatible changes to the service below.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "ARTIFACT_BACKEND_PLUGIN",
+	MagicCookieValue: "c9f1a3d2-artifact-backend",
+}
+
+// pluginName is the name the backend is dispensed under.
+const pluginName = "backend"
+
+// The gRPC service has one method per Backend operation. Requests and
+// responses are google.protobuf.Struct messages, so plugins in any language
+// only need the well-known types instead of a generated client:
+//
+//	service Backend {
+//	  rpc Push(Struct) returns (Struct);   // remotePath, localPath, force, metadata
+//	  rpc Pull(Struct) returns (Struct);   // remotePath, localPath, force
+//	  rpc Yank(Struct) returns (Struct);   // remotePath
+//	  rpc Exists(Struct) returns (Struct); // remotePath -> exists
+//	}
+//
+// Errors are reported with the NotFound, AlreadyExists and PermissionDenied
+// status codes where they apply.
+const serviceName = "artifact.backend.v1.Backend"
+
+const (
+	methodPush   = "Push"
+	methodPull   = "Pull"
+	methodYank   = "Yank"
+	methodExists = "Exists"
+)
+
+// Serve runs impl as a backend plugin. Plugins call it from their main function.
+func Serve(impl backend.Backend) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         plugin.PluginSet{pluginName: &grpcPlugin{impl: impl}},
+		GRPCServer:      plugin.DefaultGRPCServer,
+	})
+}
+
+// grpcPlugin connects backends to go-plugin, on either side of the connection.
+type grpcPlugin struct {
+	plugin.NetRPCUnsupportedPlugin
+	impl backend.Backend
+}
+
+func (p *grpcPlugin) GRPCServer(broker *plugin.GRPCBroker, s *grpc.Server) error {
+	s.RegisterService(&serviceDesc, &server{impl: p.impl})
+	return nil
+}
+
+func (p *grpcPlugin) GRPCClient(ctx context.Context, broker *plugin.GRPCBroker, conn *grpc.ClientConn) (interface{}, error) {
+	return &client{conn: conn}, nil
+}
+
+// handler is implemented by server, and only exists to type-check registrations.
+type handler interface {
+	handle(ctx context.Context, method string, req *structpb.Struct) (*structpb.Struct, error)
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: serviceName,
+	HandlerType: (*handler)(nil),
+	Methods: []grpc.MethodDesc{
+		methodDesc(methodPush),
+		methodDesc(methodPull),
+		methodDesc(methodYank),
+		methodDesc(methodExists),
+	},
+}
+
+func methodDesc(method string) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: method,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			req := &structpb.Struct{}
+			if err := dec(req); err != nil {
+				return nil, err
+			}
+
+			h := srv.(handler)
+			if interceptor == nil {
+				return h.handle(ctx, method, req)
+			}
+
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + serviceName + "/" + method}
+			return interceptor(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return h.handle(ctx, method, req.(*structpb.Struct))
+			})
+		},
+	}
+}
+
+// server runs in the plugin process and calls its backend implementation.
+type server struct {
+	impl backend.Backend
+}
+
+func (s *server) handle(ctx context.Context, method string, req *structpb.Struct) (*structpb.Struct, error) {
+	fields := req.GetFields()
+	remotePath := fields["remotePath"].GetStringValue()
+	localPath := fields["localPath"].GetStringValue()
+	force := fields["force"].GetBoolValue()
+
+	var err error
+	response := map[string]interface{}{}
+
+	switch method {
+	case methodPush:
+		metadata := map[string]string{}
+		for key, value := range fields["metadata"].GetStructValue().GetFields() {
+			metadata[key] = value.GetStringValue()
+		}
+		err = s.impl.Push(ctx, localPath, remotePath, backend.PushOptions{Force: force, Metadata: metadata})
+	case methodPull:
+		err = s.impl.Pull(ctx, remotePath, localPath, backend.PullOptions{Force: force})
+	case methodYank:
+		err = s.impl.Yank(ctx, remotePath)
+	case methodExists:
+		var exists bool
+		exists, err = s.impl.Exists(ctx, remotePath)
+		response["exists"] = exists
+	}
+
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return structpb.NewStruct(response)
+}
+
+// client runs in the CLI and implements Backend by calling the plugin.
+// Local paths are sent as absolute paths.
+type client struct {
+	conn *grpc.ClientConn
+}
+
+func (c *client) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return err
+	}
+
+	metadata := map[string]interface{}{}
+	for key, value := range opts.Metadata {
+		metadata[key] = value
+	}
+
+	_, err = c.call(ctx, methodPush, remotePath, map[string]interface{}{
+		"remotePath": remotePath,
+		"localPath":  absPath,
+		"force":      opts.Force,
+		"metadata":   metadata,
+	})
+	return err
+}
+
+func (c *client) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	absPath, err := filepath.Abs(localPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = c.call(ctx, methodPull, remotePath, map[string]interface{}{
+		"remotePath": remotePath,
+		"localPath":  absPath,
+		"force":      opts.Force,
+	})
+	return err
+}
+
+func (c *client) Yank(ctx context.Context, remotePath string) error {
+	_, err := c.call(ctx, methodYank, remotePath, map[string]interface{}{"remotePath": remotePath})
+	return err
+}
+
+func (c *client) Exists(ctx context.Context, remotePath string) (bool, error) {
+	response, err := c.call(ctx, methodExists, remotePath, map[string]interface{}{"remotePath": remotePath})
+	if err != nil {
+		return false, err
+	}
+
+	return response.GetFields()["exists"].GetBoolValue(), nil
+}
+
+func (c *client) Close() error {
+	return nil
+}
+
+func (c *client) call(ctx context.Context, method, remotePath string, fields map[string]interface{}) (*structpb.Struct, error) {
+	req, err := structpb.NewStruct(fields)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &structpb.Struct{}
+	if err := c.conn.Invoke(ctx, "/"+serviceName+"/"+method, req, response); err != nil {
+		return nil, fromStatus(err, method, remotePath)
+	}
+
+	return response, nil
+}
+
+// toStatus maps backend errors to gRPC status codes.
+func toStatus(err error) error {
+	var notFound *backend.ErrNotFound
+	var alreadyExists *backend.ErrAlreadyExists
+	var denied *backend.ErrPermissionDenied
+
+	switch {
+	case errors.As(err, &notFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &alreadyExists):
+		return status.Error(codes.AlreadyExists, err.Error())
+	case errors.As(err, &denied):
+		return status.Error(codes.PermissionDenied, denied.Reason)
+	default:
+		return status.Error(codes.Unknown, err.Error())
+	}
+}
+
+// fromStatus maps gRPC status codes back to backend errors.
+func fromStatus(err error, method, remotePath string) error {
+	s, _ := status.FromError(err)
+	operation := strings.ToLower(method)
+
+	switch s.Code() {
+	case codes.NotFound:
+		return &backend.ErrNotFound{Path: remotePath}
+	case codes.AlreadyExists:
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	case codes.PermissionDenied:
+		return &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: s.Message()}
+	default:
+		return fmt.Errorf("backend plugin failed to %s '%s': %s", operation, remotePath, s.Message())
+	}
+}
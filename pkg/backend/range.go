@@ -0,0 +1,46 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// OpenRange reads the file at remotePath from offset on with the backend's
+// RangeOpener. Backends that can only open whole files stream it from the
+// start and skip the first offset bytes. It returns ErrOpenNotSupported if
+// the backend can do neither.
+func OpenRange(ctx context.Context, b Backend, remotePath string, offset int64) (io.ReadCloser, error) {
+	if opener, ok := b.(RangeOpener); ok {
+		return opener.OpenRange(ctx, remotePath, offset)
+	}
+
+	opener, ok := b.(Opener)
+	if !ok {
+		return nil, ErrOpenNotSupported
+	}
+
+	r, err := opener.Open(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := SkipBytes(r, offset); err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// SkipBytes discards the first n bytes of r, for servers that answer a
+// range request with the whole file. Files shorter than n are read to
+// their end, which leaves nothing to read after the offset.
+func SkipBytes(r io.Reader, n int64) error {
+	_, err := io.CopyN(io.Discard, r, n)
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+
+	return err
+}
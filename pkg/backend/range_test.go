@@ -0,0 +1,54 @@
+package backend_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openOnly hides every optional capability of a backend but opening files.
+type openOnly struct {
+	backend.Backend
+	backend.Opener
+}
+
+func TestOpenRange(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/build.log", []byte("line 1\nline 2\n"))
+
+	for name, b := range map[string]backend.Backend{
+		"range opener": memory,
+		"opener":       openOnly{Backend: memory, Opener: memory},
+		"read-only":    backend.NewReadOnly(openOnly{Backend: memory, Opener: memory}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			read := func(offset int64) string {
+				r, err := backend.OpenRange(ctx, b, "artifacts/jobs/1/build.log", offset)
+				require.NoError(t, err)
+				defer r.Close()
+
+				data, err := io.ReadAll(r)
+				require.NoError(t, err)
+				return string(data)
+			}
+
+			assert.Equal(t, "line 1\nline 2\n", read(0))
+			assert.Equal(t, "line 2\n", read(7))
+			assert.Equal(t, "", read(14))
+			assert.Equal(t, "", read(100))
+
+			_, err := backend.OpenRange(ctx, b, "artifacts/jobs/1/missing.log", 0)
+			var notFound *backend.ErrNotFound
+			assert.ErrorAs(t, err, &notFound)
+		})
+	}
+
+	_, err := backend.OpenRange(ctx, plain{Backend: memory}, "artifacts/jobs/1/build.log", 0)
+	assert.Equal(t, backend.ErrOpenNotSupported, err)
+}
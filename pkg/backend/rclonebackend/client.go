@@ -0,0 +1,505 @@
+package rclonebackend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterRcloneBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// rclone exit codes for missing paths.
+const (
+	exitDirectoryNotFound = 3
+	exitFileNotFound      = 4
+)
+
+// RcloneBackend implements the Backend interface by running rclone commands.
+type RcloneBackend struct {
+	cfg    *Config
+	binary string
+}
+
+// entry is an item of `rclone lsjson` output.
+type entry struct {
+	Name    string    `json:"Name"`
+	Size    int64     `json:"Size"`
+	ModTime time.Time `json:"ModTime"`
+	IsDir   bool      `json:"IsDir"`
+}
+
+// New creates a new RcloneBackend instance from the environment/config file.
+func New() (*RcloneBackend, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg)
+}
+
+// NewWithConfig creates a new RcloneBackend instance for a validated configuration.
+func NewWithConfig(cfg *Config) (*RcloneBackend, error) {
+	binary, err := exec.LookPath(cfg.Binary)
+	if err != nil {
+		return nil, fmt.Errorf("rclone binary '%s' not found: install rclone or set ARTIFACT_RCLONE_BINARY: %w", cfg.Binary, err)
+	}
+
+	log.Debug("RcloneBackend: Client initialized\n")
+	log.Debugf("* Binary: %s\n", binary)
+	log.Debugf("* Remote: %s\n", cfg.Remote)
+
+	return &RcloneBackend{cfg: cfg, binary: binary}, nil
+}
+
+// Push uploads a local file or directory, one file at a time.
+func (r *RcloneBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("RcloneBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		return r.pushFile(ctx, localPath, remotePath, opts)
+	}
+
+	return files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return r.pushFile(ctx, filePath, remotePath+"/"+filepath.ToSlash(relPath), opts)
+	})
+}
+
+func (r *RcloneBackend) pushFile(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	if !opts.Force {
+		exists, err := r.Exists(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &backend.ErrAlreadyExists{Path: remotePath}
+		}
+	}
+
+	checksum, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
+	if err := r.run(ctx, nil, nil, "push", remotePath, "copyto", localPath, r.cfg.path(remotePath)); err != nil {
+		return err
+	}
+
+	log.Debugf("Uploaded: %s -> %s\n", localPath, r.cfg.path(remotePath))
+
+	// Failing to store the checksum does not fail the push, as the file is already uploaded
+	if err := r.rcat(ctx, bytes.NewReader([]byte(checksum)), backend.ChecksumSidecarPath(remotePath)); err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+
+	return nil
+}
+
+// PushStream uploads a stream with `rclone rcat`, which accepts streams
+// of unknown size. The checksum is computed while uploading.
+func (r *RcloneBackend) PushStream(ctx context.Context, reader io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	log.Debug("RcloneBackend: Pushing stream...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Size: %d\n", size)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	if !opts.Force {
+		exists, err := r.Exists(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &backend.ErrAlreadyExists{Path: remotePath}
+		}
+	}
+
+	hash := sha256.New()
+	if err := r.rcat(ctx, io.TeeReader(reader, hash), remotePath); err != nil {
+		return err
+	}
+
+	checksum := hex.EncodeToString(hash.Sum(nil))
+	if err := r.rcat(ctx, strings.NewReader(checksum), backend.ChecksumSidecarPath(remotePath)); err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+
+	return nil
+}
+
+func (r *RcloneBackend) rcat(ctx context.Context, reader io.Reader, remotePath string) error {
+	return r.run(ctx, reader, nil, "push", remotePath, "rcat", r.cfg.path(remotePath))
+}
+
+// Pull downloads a file, or every file in a directory.
+func (r *RcloneBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("RcloneBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	stat, err := r.stat(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if !stat.IsDir {
+		return r.pullFile(ctx, remotePath, localPath, opts)
+	}
+
+	dir := strings.TrimSuffix(remotePath, "/")
+	return backend.Walk(ctx, r.readDir, dir, nil, func(info backend.ObjectInfo) error {
+		destPath := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(info.Path, dir+"/")))
+		return r.pullFile(ctx, info.Path, destPath, opts)
+	})
+}
+
+func (r *RcloneBackend) pullFile(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	if err := r.run(ctx, nil, nil, "pull", remotePath, "copyto", r.cfg.path(remotePath), localPath); err != nil {
+		return err
+	}
+
+	log.Debugf("Downloaded: %s -> %s\n", r.cfg.path(remotePath), localPath)
+	return nil
+}
+
+// Open streams the contents of a file with `rclone cat`.
+func (r *RcloneBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("RcloneBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	// rclone reports missing files only after starting to stream, so they are checked first
+	stat, err := r.stat(ctx, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	if stat.IsDir {
+		return nil, &backend.ErrNotFound{Path: remotePath}
+	}
+
+	var stderr bytes.Buffer
+	cmd := r.command(ctx, "cat", r.cfg.path(remotePath))
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start rclone: %w", err)
+	}
+
+	return &catReader{ReadCloser: stdout, cmd: cmd, wait: func() error {
+		return r.commandError(cmd.Wait(), &stderr, "pull", remotePath)
+	}}, nil
+}
+
+// catReader waits for `rclone cat` when the stream ends, reporting failures
+// that happened while streaming. Closing it early stops rclone.
+type catReader struct {
+	io.ReadCloser
+	cmd  *exec.Cmd
+	wait func() error
+
+	once sync.Once
+	eof  bool
+	err  error
+}
+
+func (c *catReader) Read(p []byte) (int, error) {
+	n, err := c.ReadCloser.Read(p)
+	if err == io.EOF {
+		c.eof = true
+		if waitErr := c.finish(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+
+	return n, err
+}
+
+func (c *catReader) Close() error {
+	if !c.eof {
+		_ = c.cmd.Process.Kill()
+		_ = c.ReadCloser.Close()
+		_ = c.finish()
+		return nil
+	}
+
+	return c.finish()
+}
+
+func (c *catReader) finish() error {
+	c.once.Do(func() { c.err = c.wait() })
+	return c.err
+}
+
+// Yank deletes a file or directory, along with its checksums.
+// Paths that do not exist are not an error.
+func (r *RcloneBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("RcloneBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	if err := r.delete(ctx, remotePath); err != nil {
+		return err
+	}
+
+	for _, sidecar := range []string{backend.ChecksumSidecarPath(remotePath), backend.ChecksumSidecarPrefix(remotePath)} {
+		if err := r.delete(ctx, sidecar); err != nil {
+			log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+		}
+	}
+
+	return nil
+}
+
+// delete removes a file with `rclone deletefile`, or a directory with `rclone purge`.
+func (r *RcloneBackend) delete(ctx context.Context, remotePath string) error {
+	stat, err := r.stat(ctx, remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	command := "deletefile"
+	if stat.IsDir {
+		command = "purge"
+	}
+
+	err = r.run(ctx, nil, nil, "yank", remotePath, command, r.cfg.path(remotePath))
+	if errors.As(err, &notFound) {
+		return nil
+	}
+
+	return err
+}
+
+// Exists checks if a file exists.
+func (r *RcloneBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("RcloneBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	stat, err := r.stat(ctx, remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return !stat.IsDir, nil
+}
+
+// List lists the files under remotePrefix with one `rclone lsjson` per
+// directory the walk reaches.
+func (r *RcloneBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("RcloneBackend: Listing...\n")
+	log.Debugf("* Prefix: %s\n", remotePrefix)
+
+	return backend.WalkPrefix(ctx, r.readDir, remotePrefix, fn)
+}
+
+// Checksum returns the checksum stored in the file's sidecar.
+func (r *RcloneBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	exists, err := r.Exists(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sidecar, err := r.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// Close releases resources. rclone only runs during calls, so this is a no-op.
+func (r *RcloneBackend) Close() error {
+	return nil
+}
+
+// Helper functions
+
+// stat describes a file or directory with `rclone lsjson --stat`.
+func (r *RcloneBackend) stat(ctx context.Context, remotePath string) (*entry, error) {
+	var stdout bytes.Buffer
+	if err := r.run(ctx, nil, &stdout, "pull", remotePath, "lsjson", "--stat", r.cfg.path(remotePath)); err != nil {
+		return nil, err
+	}
+
+	stat := &entry{}
+	if err := json.Unmarshal(stdout.Bytes(), stat); err != nil {
+		return nil, fmt.Errorf("failed to decode rclone listing of '%s': %w", remotePath, err)
+	}
+
+	return stat, nil
+}
+
+// readDir returns the entries of a directory, for backend.Walk.
+func (r *RcloneBackend) readDir(ctx context.Context, dir string) ([]backend.DirEntry, error) {
+	var stdout bytes.Buffer
+	if err := r.run(ctx, nil, &stdout, "pull", dir, "lsjson", r.cfg.path(dir)); err != nil {
+		return nil, err
+	}
+
+	listing := []entry{}
+	if err := json.Unmarshal(stdout.Bytes(), &listing); err != nil {
+		return nil, fmt.Errorf("failed to decode rclone listing of '%s': %w", dir, err)
+	}
+
+	entries := []backend.DirEntry{}
+	for _, e := range listing {
+		entries = append(entries, backend.DirEntry{
+			Name:  e.Name,
+			IsDir: e.IsDir,
+			Info:  backend.ObjectInfo{Size: e.Size, ModTime: e.ModTime},
+		})
+	}
+
+	return entries, nil
+}
+
+// command prepares an rclone command with the configured flags.
+func (r *RcloneBackend) command(ctx context.Context, args ...string) *exec.Cmd {
+	fullArgs := []string{}
+	if r.cfg.ConfigFile != "" {
+		fullArgs = append(fullArgs, "--config", r.cfg.ConfigFile)
+	}
+	fullArgs = append(fullArgs, r.cfg.Flags...)
+	fullArgs = append(fullArgs, args...)
+
+	log.Debugf("rclone %s...\n", strings.Join(fullArgs, " "))
+
+	// #nosec
+	return exec.CommandContext(ctx, r.binary, fullArgs...)
+}
+
+// run runs an rclone command to completion, mapping failures to backend errors.
+func (r *RcloneBackend) run(ctx context.Context, stdin io.Reader, stdout io.Writer, operation, remotePath string, args ...string) error {
+	var stderr bytes.Buffer
+
+	cmd := r.command(ctx, args...)
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+
+	return r.commandError(cmd.Run(), &stderr, operation, remotePath)
+}
+
+// commandError maps a failed rclone command to backend errors, using
+// rclone's exit codes for missing paths.
+func (r *RcloneBackend) commandError(err error, stderr *bytes.Buffer, operation, remotePath string) error {
+	if stderr.Len() > 0 {
+		log.Debugf("rclone output:\n%s\n", strings.TrimSpace(stderr.String()))
+	}
+	if err == nil {
+		return nil
+	}
+
+	output := strings.TrimSpace(stderr.String())
+	lower := strings.ToLower(output)
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		switch {
+		case exitErr.ExitCode() == exitDirectoryNotFound || exitErr.ExitCode() == exitFileNotFound:
+			return &backend.ErrNotFound{Path: remotePath}
+		case strings.Contains(lower, "permission denied") || strings.Contains(lower, "accessdenied") || strings.Contains(lower, "403"):
+			return &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: output}
+		}
+	}
+
+	return fmt.Errorf("rclone failed to %s '%s': %v: %s", operation, remotePath, err, output)
+}
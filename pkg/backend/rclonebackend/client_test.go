@@ -0,0 +1,229 @@
+package rclonebackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The test binary doubles as an rclone binary, serving the test: remote
+// from ARTIFACT_RCLONE_TEST_ROOT with the commands the backend uses.
+func TestMain(m *testing.M) {
+	if root := os.Getenv("ARTIFACT_RCLONE_TEST_ROOT"); root != "" {
+		os.Exit(runFakeRclone(root, os.Args[1:]))
+	}
+
+	os.Exit(m.Run())
+}
+
+func runFakeRclone(root string, args []string) int {
+	positional := []string{}
+	stat := false
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--config":
+			i++
+		case args[i] == "--stat":
+			stat = true
+		case !strings.HasPrefix(args[i], "--"):
+			positional = append(positional, args[i])
+		}
+	}
+
+	local := func(p string) string {
+		if rel, ok := strings.CutPrefix(p, "test:"); ok {
+			return filepath.Join(root, filepath.FromSlash(rel))
+		}
+		return p
+	}
+
+	fail := func(code int, format string, a ...interface{}) int {
+		fmt.Fprintf(os.Stderr, format+"\n", a...)
+		return code
+	}
+
+	command, paths := positional[0], positional[1:]
+	if strings.Contains(paths[len(paths)-1], "readonly/") {
+		return fail(1, "AccessDenied: Access Denied")
+	}
+
+	switch command {
+	case "copyto", "cat", "rcat":
+		var src io.Reader = os.Stdin
+		if command != "rcat" {
+			file, err := os.Open(local(paths[0]))
+			if err != nil {
+				return fail(4, "object not found")
+			}
+			defer file.Close()
+			src = file
+		}
+
+		var dst io.Writer = os.Stdout
+		if command != "cat" {
+			target := local(paths[len(paths)-1])
+			_ = os.MkdirAll(filepath.Dir(target), 0755)
+			file, err := os.Create(target)
+			if err != nil {
+				return fail(1, "%v", err)
+			}
+			defer file.Close()
+			dst = file
+		}
+
+		_, _ = io.Copy(dst, src)
+	case "lsjson":
+		info, err := os.Stat(local(paths[0]))
+		if err != nil {
+			return fail(3, "directory not found")
+		}
+		if stat {
+			_ = json.NewEncoder(os.Stdout).Encode(entry{Name: info.Name(), Size: info.Size(), IsDir: info.IsDir()})
+			return 0
+		}
+		entries, _ := os.ReadDir(local(paths[0]))
+		result := []entry{}
+		for _, e := range entries {
+			info, _ := e.Info()
+			result = append(result, entry{Name: e.Name(), Size: info.Size(), ModTime: info.ModTime(), IsDir: e.IsDir()})
+		}
+		_ = json.NewEncoder(os.Stdout).Encode(result)
+	case "deletefile":
+		if err := os.Remove(local(paths[0])); err != nil {
+			return fail(4, "object not found")
+		}
+	case "purge":
+		if err := os.RemoveAll(local(paths[0])); err != nil {
+			return fail(3, "directory not found")
+		}
+	default:
+		return fail(1, "unknown command %s", command)
+	}
+
+	return 0
+}
+
+func createTestRcloneBackend(t *testing.T) (*RcloneBackend, string) {
+	root := t.TempDir()
+	t.Setenv("ARTIFACT_RCLONE_TEST_ROOT", root)
+
+	cfg := &Config{Remote: "test:", Binary: os.Args[0], ConfigFile: "rclone.conf", Flags: []string{"--fast-list"}}
+	require.NoError(t, cfg.Validate())
+
+	rcloneBackend, err := NewWithConfig(cfg)
+	require.NoError(t, err)
+
+	return rcloneBackend, root
+}
+
+func writeTestFile(t *testing.T, dir, name, content string) string {
+	p := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+	require.NoError(t, os.WriteFile(p, []byte(content), 0644))
+	return p
+}
+
+func TestRcloneBackend_PushPullYank(t *testing.T) {
+	rcloneBackend, root := createTestRcloneBackend(t)
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	localFile := writeTestFile(t, dir, "a.txt", "hello")
+	require.NoError(t, rcloneBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	assert.FileExists(t, filepath.Join(root, "artifacts", "jobs", "1", "a.txt"))
+
+	exists, err := rcloneBackend.Exists(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = rcloneBackend.Exists(ctx, "artifacts/jobs/1")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	err = rcloneBackend.Push(ctx, localFile, "artifacts/jobs/1/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+
+	checksum, err := rcloneBackend.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", checksum)
+
+	require.NoError(t, rcloneBackend.PushStream(ctx, strings.NewReader("streamed"), -1, "artifacts/jobs/1/reports/s.txt", backend.PushOptions{}))
+	writeTestFile(t, dir, "reports/index.html", "<html>")
+	require.NoError(t, rcloneBackend.Push(ctx, filepath.Join(dir, "reports"), "artifacts/jobs/1/reports", backend.PushOptions{}))
+
+	pulled := filepath.Join(t.TempDir(), "reports")
+	require.NoError(t, rcloneBackend.Pull(ctx, "artifacts/jobs/1/reports", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(filepath.Join(pulled, "s.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", string(data))
+	assert.FileExists(t, filepath.Join(pulled, "index.html"))
+
+	body, err := rcloneBackend.Open(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	data, err = io.ReadAll(body)
+	require.NoError(t, err)
+	assert.NoError(t, body.Close())
+	assert.Equal(t, "hello", string(data))
+
+	paths := []string{}
+	err = rcloneBackend.List(ctx, "artifacts/jobs/1/", func(info backend.ObjectInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"artifacts/jobs/1/a.txt", "artifacts/jobs/1/reports/index.html", "artifacts/jobs/1/reports/s.txt"}, paths)
+
+	require.NoError(t, rcloneBackend.Yank(ctx, "artifacts/jobs/1/reports"))
+	require.NoError(t, rcloneBackend.Yank(ctx, "artifacts/jobs/1/a.txt"))
+	require.NoError(t, rcloneBackend.Yank(ctx, "artifacts/jobs/1/missing"))
+	assert.NoDirExists(t, filepath.Join(root, "artifacts", "jobs", "1", "reports"))
+	assert.NoDirExists(t, filepath.Join(root, "artifacts", "jobs", "1", ".checksums", "reports"))
+
+	err = rcloneBackend.Pull(ctx, "artifacts/jobs/1/a.txt", filepath.Join(t.TempDir(), "a.txt"), backend.PullOptions{})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestRcloneBackend_Errors(t *testing.T) {
+	rcloneBackend, _ := createTestRcloneBackend(t)
+	ctx := context.Background()
+	localFile := writeTestFile(t, t.TempDir(), "a.txt", "hello")
+
+	err := rcloneBackend.Push(ctx, localFile, "readonly/a.txt", backend.PushOptions{Force: true})
+	var denied *backend.ErrPermissionDenied
+	require.ErrorAs(t, err, &denied)
+	assert.Contains(t, denied.Reason, "AccessDenied")
+
+	err = rcloneBackend.Push(ctx, localFile, "a.txt", backend.PushOptions{Lock: &backend.ObjectLock{LegalHold: true}})
+	assert.Equal(t, backend.ErrObjectLockNotSupported, err)
+
+	_, err = NewWithConfig(&Config{Remote: "test:", Binary: filepath.Join(t.TempDir(), "rclone")})
+	assert.ErrorContains(t, err, "not found")
+}
+
+func TestConfig(t *testing.T) {
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{Remote: "gdrive"}).Validate())
+	assert.NoError(t, (&Config{Remote: "gdrive:"}).Validate())
+
+	assert.Equal(t, "gdrive:artifacts/jobs/1/a.txt", (&Config{Remote: "gdrive:"}).path("artifacts/jobs/1/a.txt"))
+	assert.Equal(t, "b2:bucket/ci/artifacts/jobs/1/a.txt", (&Config{Remote: "b2:bucket/ci"}).path("artifacts/jobs/1/a.txt"))
+	assert.Equal(t, "b2:bucket/ci", (&Config{Remote: "b2:bucket/ci"}).path(""))
+
+	t.Setenv("ARTIFACT_RCLONE_REMOTE", "b2:bucket")
+	t.Setenv("ARTIFACT_RCLONE_FLAGS", "--fast-list --transfers 8")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "rclone", cfg.Binary)
+	assert.Equal(t, []string{"--fast-list", "--transfers", "8"}, cfg.Flags)
+}
@@ -0,0 +1,102 @@
+// Package rclonebackend implements the Backend interface by running an
+// installed rclone binary, so the storage providers rclone supports can be
+// used without a native backend for each. Artifacts are stored under an
+// rclone remote, configured with `rclone config` as usual.
+package rclonebackend
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Config holds rclone backend configuration.
+type Config struct {
+	// Remote is the rclone remote artifacts are stored under (required),
+	// with an optional path, e.g. gdrive: or b2:bucket/ci
+	Remote string
+
+	// Binary is the rclone executable, looked up in PATH by default
+	Binary string
+
+	// ConfigFile is the rclone config file, rclone's default if not set
+	ConfigFile string
+
+	// Flags are extra flags passed to every rclone command, e.g. --fast-list
+	Flags []string
+}
+
+// LoadConfig loads rclone configuration from environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_RCLONE_REMOTE (required)
+//   - ARTIFACT_RCLONE_BINARY (optional, defaults to rclone)
+//   - ARTIFACT_RCLONE_CONFIG (optional)
+//   - ARTIFACT_RCLONE_FLAGS (optional, separated by spaces)
+//
+// Config file keys (under 'rclone' section):
+//   - remote, binary, config, flags
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// Load from environment variables first
+	cfg.Remote = os.Getenv("ARTIFACT_RCLONE_REMOTE")
+	cfg.Binary = os.Getenv("ARTIFACT_RCLONE_BINARY")
+	cfg.ConfigFile = os.Getenv("ARTIFACT_RCLONE_CONFIG")
+	flags := os.Getenv("ARTIFACT_RCLONE_FLAGS")
+
+	// Fall back to config file for unset values
+	if cfg.Remote == "" {
+		cfg.Remote = viper.GetString("rclone.remote")
+	}
+	if cfg.Binary == "" {
+		cfg.Binary = viper.GetString("rclone.binary")
+	}
+	if cfg.ConfigFile == "" {
+		cfg.ConfigFile = viper.GetString("rclone.config")
+	}
+	if flags == "" {
+		flags = viper.GetString("rclone.flags")
+	}
+
+	if cfg.Binary == "" {
+		cfg.Binary = "rclone"
+	}
+	cfg.Flags = strings.Fields(flags)
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	if c.Remote == "" {
+		return fmt.Errorf("rclone remote is required: set ARTIFACT_RCLONE_REMOTE environment variable or rclone.remote in config")
+	}
+
+	if !strings.Contains(c.Remote, ":") {
+		return fmt.Errorf("invalid rclone remote '%s': use <name>: or <name>:<path>", c.Remote)
+	}
+
+	return nil
+}
+
+// path returns the rclone path of remotePath, e.g. b2:bucket/ci/artifacts/jobs/1/a.zip
+func (c *Config) path(remotePath string) string {
+	remotePath = strings.Trim(remotePath, "/")
+	if remotePath == "" {
+		return c.Remote
+	}
+
+	if strings.HasSuffix(c.Remote, ":") || strings.HasSuffix(c.Remote, "/") {
+		return c.Remote + remotePath
+	}
+
+	return c.Remote + "/" + remotePath
+}
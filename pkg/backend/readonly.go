@@ -0,0 +1,110 @@
+package backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// IsReadOnly returns true if backends are read-only: ARTIFACT_READONLY=true
+// or the readonly config key. Either one is enough, so a setting on shared
+// runners cannot be turned off by the other.
+func IsReadOnly() bool {
+	return os.Getenv("ARTIFACT_READONLY") == "true" || viper.GetBool("readonly")
+}
+
+// ReadOnlyBackend wraps a backend, rejecting pushes and yanks with ErrReadOnly.
+// Reads, including the optional read interfaces, go to the wrapped backend.
+type ReadOnlyBackend struct {
+	Backend
+}
+
+// NewReadOnly wraps b in a ReadOnlyBackend.
+func NewReadOnly(b Backend) *ReadOnlyBackend {
+	return &ReadOnlyBackend{Backend: b}
+}
+
+// Capabilities are the ones of the wrapped backend. Writes are rejected
+// with ErrReadOnly rather than reported as unsupported.
+func (r *ReadOnlyBackend) Capabilities() Capabilities {
+	return CapabilitiesOf(r.Backend)
+}
+
+// Push rejects the push with ErrReadOnly.
+func (r *ReadOnlyBackend) Push(ctx context.Context, localPath, remotePath string, opts PushOptions) error {
+	return &ErrReadOnly{Operation: "push", Path: remotePath}
+}
+
+// PushStream rejects the push with ErrReadOnly.
+func (r *ReadOnlyBackend) PushStream(ctx context.Context, reader io.Reader, size int64, remotePath string, opts PushOptions) error {
+	return &ErrReadOnly{Operation: "push", Path: remotePath}
+}
+
+// Yank rejects the yank with ErrReadOnly.
+func (r *ReadOnlyBackend) Yank(ctx context.Context, remotePath string) error {
+	return &ErrReadOnly{Operation: "yank", Path: remotePath}
+}
+
+// Copy rejects the copy with ErrReadOnly.
+func (r *ReadOnlyBackend) Copy(ctx context.Context, srcPath, dstPath string, opts PushOptions) error {
+	return &ErrReadOnly{Operation: "copy", Path: dstPath}
+}
+
+// Open streams a file from the wrapped backend, if it can.
+func (r *ReadOnlyBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	opener, ok := r.Backend.(Opener)
+	if !ok {
+		return nil, ErrOpenNotSupported
+	}
+
+	return opener.Open(ctx, remotePath)
+}
+
+// OpenRange reads a file of the wrapped backend from offset on.
+func (r *ReadOnlyBackend) OpenRange(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error) {
+	return OpenRange(ctx, r.Backend, remotePath, offset)
+}
+
+// Check checks the wrapped backend.
+func (r *ReadOnlyBackend) Check(ctx context.Context, remotePath string) (time.Time, error) {
+	return Check(ctx, r.Backend, remotePath)
+}
+
+// List lists the wrapped backend, if it supports listing.
+func (r *ReadOnlyBackend) List(ctx context.Context, remotePrefix string, fn func(ObjectInfo) error) error {
+	lister, ok := r.Backend.(Lister)
+	if !ok {
+		return ErrListingNotSupported
+	}
+
+	return lister.List(ctx, remotePrefix, fn)
+}
+
+// Stat describes a file of the wrapped backend.
+func (r *ReadOnlyBackend) Stat(ctx context.Context, remotePath string) (*ObjectInfo, error) {
+	return Stat(ctx, r.Backend, remotePath)
+}
+
+// Share creates a link to a file of the wrapped backend, if it can.
+func (r *ReadOnlyBackend) Share(ctx context.Context, remotePath string, expiresIn time.Duration) (string, time.Time, error) {
+	sharer, ok := r.Backend.(Sharer)
+	if !ok {
+		return "", time.Time{}, ErrSharingNotSupported
+	}
+
+	return sharer.Share(ctx, remotePath, expiresIn)
+}
+
+// Checksum returns the checksum stored by the wrapped backend, if it stores checksums.
+func (r *ReadOnlyBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	reader, ok := r.Backend.(ChecksumReader)
+	if !ok {
+		return "", fmt.Errorf("the configured backend does not store checksums")
+	}
+
+	return reader.Checksum(ctx, remotePath)
+}
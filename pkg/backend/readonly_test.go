@@ -0,0 +1,60 @@
+package backend_test
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadOnlyBackend(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/a.txt", []byte("hello"))
+	readOnly := backend.NewReadOnly(memory)
+
+	var errReadOnly *backend.ErrReadOnly
+	err := readOnly.Push(ctx, "a.txt", "artifacts/jobs/1/b.txt", backend.PushOptions{})
+	require.ErrorAs(t, err, &errReadOnly)
+	assert.Equal(t, "push", errReadOnly.Operation)
+
+	err = readOnly.PushStream(ctx, strings.NewReader("hello"), 5, "artifacts/jobs/1/b.txt", backend.PushOptions{})
+	assert.ErrorAs(t, err, &errReadOnly)
+
+	err = backend.Copy(ctx, readOnly, "artifacts/jobs/1/a.txt", "artifacts/jobs/1/b.txt", backend.PushOptions{})
+	require.ErrorAs(t, err, &errReadOnly)
+	assert.Equal(t, "copy", errReadOnly.Operation)
+
+	err = readOnly.Yank(ctx, "artifacts/jobs/1/a.txt")
+	require.ErrorAs(t, err, &errReadOnly)
+	assert.Equal(t, "yank", errReadOnly.Operation)
+	assert.Equal(t, []string{"artifacts/jobs/1/a.txt"}, memory.Paths())
+
+	body, err := readOnly.Open(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(body)
+	require.NoError(t, err)
+	body.Close()
+	assert.Equal(t, "hello", string(data))
+
+	paths := []string{}
+	err = readOnly.List(ctx, "artifacts/jobs/1/", func(info backend.ObjectInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"artifacts/jobs/1/a.txt"}, paths)
+}
+
+func TestIsReadOnly(t *testing.T) {
+	t.Setenv("ARTIFACT_READONLY", "")
+	assert.False(t, backend.IsReadOnly())
+
+	t.Setenv("ARTIFACT_READONLY", "true")
+	assert.True(t, backend.IsReadOnly())
+}
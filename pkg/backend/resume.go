@@ -0,0 +1,14 @@
+package backend
+
+import log "github.com/sirupsen/logrus"
+
+// LogResumed reports the files a resumed push skips because an earlier,
+// interrupted run of it already pushed them.
+func LogResumed(skipped int) {
+	switch {
+	case skipped == 1:
+		log.Info("Resuming push: 1 file was already pushed.\n")
+	case skipped > 1:
+		log.Infof("Resuming push: %d files were already pushed.\n", skipped)
+	}
+}
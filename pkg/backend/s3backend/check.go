@@ -0,0 +1,59 @@
+package s3backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+// Check sends HeadBucket to the bucket, and to the read replica if one is
+// configured, which fails if the bucket does not exist or the credentials
+// cannot access it. remotePath is not needed. The time of the server is
+// taken from the Date header of the response, even a failed one.
+func (s *S3Backend) Check(ctx context.Context, remotePath string) (time.Time, error) {
+	log.Debug("S3Backend: Checking...\n")
+
+	serverTime, err := checkBucket(ctx, s.primary())
+	if err != nil || s.readClient == nil {
+		return serverTime, err
+	}
+
+	if _, err := checkBucket(ctx, s.reader()); err != nil {
+		return serverTime, fmt.Errorf("read replica: %w", err)
+	}
+
+	return serverTime, nil
+}
+
+func checkBucket(ctx context.Context, t target) (time.Time, error) {
+	result, err := t.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(t.bucket)})
+	if err == nil {
+		serverTime, _ := awsmiddleware.GetServerTime(result.ResultMetadata)
+		return serverTime, nil
+	}
+
+	var responseErr *awshttp.ResponseError
+	if !errors.As(err, &responseErr) {
+		return time.Time{}, fmt.Errorf("failed to reach bucket '%s': %w", t.bucket, err)
+	}
+
+	serverTime, _ := http.ParseTime(responseErr.Response.Header.Get("Date"))
+	switch responseErr.HTTPStatusCode() {
+	case http.StatusNotFound:
+		return serverTime, fmt.Errorf("bucket '%s' does not exist", t.bucket)
+	case http.StatusForbidden:
+		return serverTime, fmt.Errorf("access to bucket '%s' denied: check the credentials and their permissions", t.bucket)
+	case http.StatusMovedPermanently:
+		return serverTime, fmt.Errorf("bucket '%s' is in another region: check the configured region", t.bucket)
+	default:
+		return serverTime, fmt.Errorf("failed to check bucket '%s': %w", t.bucket, err)
+	}
+}
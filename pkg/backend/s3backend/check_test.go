@@ -0,0 +1,24 @@
+package s3backend
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_Check(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	serverTime, err := s3Backend.Check(ctx, "artifacts/jobs/1/.check")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), serverTime, time.Minute)
+
+	s3Backend.cfg.Bucket = "missing-bucket"
+	_, err = s3Backend.Check(ctx, "artifacts/jobs/1/.check")
+	assert.EqualError(t, err, "bucket 'missing-bucket' does not exist")
+}
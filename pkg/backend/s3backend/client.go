@@ -2,17 +2,26 @@ package s3backend
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	"github.com/semaphoreci/artifact/pkg/retry"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -26,6 +35,9 @@ func init() {
 type S3Backend struct {
 	client *s3.Client
 	cfg    *Config
+
+	// readClient serves pulls from the read replica, if one is configured.
+	readClient *s3.Client
 }
 
 // New creates a new S3Backend instance.
@@ -38,13 +50,26 @@ func New() (*S3Backend, error) {
 	}
 
 	// Build AWS config with automatic credential chain
-	awsCfgOpts := []func(*config.LoadOptions) error{}
+	policy := retry.Load()
+	awsCfgOpts := []func(*config.LoadOptions) error{
+		config.WithRetryer(retryer(policy)),
+		config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(func(t *http.Transport) {
+			t.ResponseHeaderTimeout = policy.RequestTimeout
+		})),
+	}
 
 	// Set region if specified
 	if cfg.Region != "" {
 		awsCfgOpts = append(awsCfgOpts, config.WithRegion(cfg.Region))
 	}
 
+	// Credential helpers replace the default chain
+	if cfg.CredentialHelper != nil {
+		awsCfgOpts = append(awsCfgOpts, config.WithCredentialsProvider(
+			aws.NewCredentialsCache(helperCredentials(cfg.CredentialHelper)),
+		))
+	}
+
 	awsCfg, err := config.LoadDefaultConfig(context.Background(), awsCfgOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS config: %w", err)
@@ -65,17 +90,72 @@ func New() (*S3Backend, error) {
 		})
 	}
 
+	if cfg.ChecksumsWhenRequired {
+		s3Opts = append(s3Opts, func(o *s3.Options) {
+			o.RequestChecksumCalculation = aws.RequestChecksumCalculationWhenRequired
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		})
+	}
+
 	client := s3.NewFromConfig(awsCfg, s3Opts...)
 
 	log.Debug("S3Backend: Client initialized\n")
 	log.Debugf("* Bucket: %s\n", cfg.Bucket)
 	log.Debugf("* Region: %s\n", cfg.Region)
 	log.Debugf("* Endpoint: %s\n", cfg.Endpoint)
+	log.Debugf("* Provider: %s\n", cfg.Provider)
 
-	return &S3Backend{
+	s3Backend := &S3Backend{
 		client: client,
 		cfg:    cfg,
-	}, nil
+	}
+
+	if cfg.HasReadReplica() {
+		s3Backend.readClient = s3.NewFromConfig(awsCfg, append(s3Opts, func(o *s3.Options) {
+			if cfg.ReadRegion != "" {
+				o.Region = cfg.ReadRegion
+			}
+			if cfg.ReadEndpoint != "" {
+				o.BaseEndpoint = aws.String(cfg.ReadEndpoint)
+			}
+		})...)
+
+		log.Debug("S3Backend: Read replica initialized\n")
+		log.Debugf("* Bucket: %s\n", cfg.readBucket())
+		log.Debugf("* Region: %s\n", cfg.ReadRegion)
+		log.Debugf("* Endpoint: %s\n", cfg.ReadEndpoint)
+	}
+
+	return s3Backend, nil
+}
+
+// helperCredentials adapts a credential helper to the AWS SDK, which
+// asks it again when the credentials expire.
+func helperCredentials(helper *backend.CredentialHelper) aws.CredentialsProvider {
+	return aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+		credentials, err := helper.Get(ctx)
+		if err != nil {
+			return aws.Credentials{}, err
+		}
+
+		if credentials.AccessKeyID == "" || credentials.SecretAccessKey == "" {
+			return aws.Credentials{}, fmt.Errorf("credential helper returned no accessKeyId and secretAccessKey")
+		}
+
+		awsCredentials := aws.Credentials{
+			AccessKeyID:     credentials.AccessKeyID,
+			SecretAccessKey: credentials.SecretAccessKey,
+			SessionToken:    credentials.SessionToken,
+			Source:          "ArtifactCredentialHelper",
+		}
+
+		if credentials.Expiration != nil {
+			awsCredentials.CanExpire = true
+			awsCredentials.Expires = *credentials.Expiration
+		}
+
+		return awsCredentials, nil
+	})
 }
 
 // Push uploads a local file or directory to S3.
@@ -91,6 +171,8 @@ func (s *S3Backend) Push(ctx context.Context, localPath, remotePath string, opts
 		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
 	}
 
+	s.ensureExpireRule(ctx, opts)
+
 	if info.IsDir() {
 		return s.pushDirectory(ctx, localPath, remotePath, opts)
 	}
@@ -112,6 +194,11 @@ func (s *S3Backend) pushFile(ctx context.Context, localPath, remotePath string,
 		}
 	}
 
+	checksum, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
 	// Open local file
 	file, err := os.Open(localPath)
 	if err != nil {
@@ -119,11 +206,57 @@ func (s *S3Backend) pushFile(ctx context.Context, localPath, remotePath string,
 	}
 	defer file.Close()
 
+	// Large files are uploaded in parts, so a slow part does not time out the whole file
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat local file '%s': %w", localPath, err)
+	}
+
+	opts.Metadata = backend.WithFileAttributes(opts.Metadata, info)
+
+	// Compressed and encrypted files are streamed, encoded as they are read
+	if opts.Compress != "" || opts.Encrypt != nil {
+		tracked := progress.FromContext(ctx).File(localPath, info.Size())
+		defer tracked.Done()
+
+		if err := s.uploadStream(ctx, tracked.Reader(file), info.Size(), remotePath, opts, checksum); err != nil {
+			return err
+		}
+
+		log.Debugf("Uploaded encoded: %s -> s3://%s/%s\n", localPath, s.cfg.Bucket, key)
+		return nil
+	}
+
+	opts.Headers = opts.Headers.WithContentType(localPath)
+
+	if info.Size() >= s.cfg.multipartThreshold() {
+		if err := s.pushFileMultipart(ctx, file, info, key, opts, checksum); err != nil {
+			return err
+		}
+
+		log.Debugf("Uploaded in parts: %s -> s3://%s/%s\n", localPath, s.cfg.Bucket, key)
+		return nil
+	}
+
+	tracked := progress.FromContext(ctx).File(localPath, info.Size())
+	defer tracked.Done()
+
 	// Upload to S3
+	lockMode, retainUntil, legalHold := lockFields(s.objectLock(opts))
+	contentType, cacheControl, contentDisposition := headerFields(opts.Headers)
 	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket: aws.String(s.cfg.Bucket),
-		Key:    aws.String(key),
-		Body:   file,
+		Bucket:                    aws.String(s.cfg.Bucket),
+		Key:                       aws.String(key),
+		Body:                      tracked.Reader(file),
+		ChecksumSHA256:            s.uploadChecksum(checksum),
+		Metadata:                  objectMetadata(opts, checksum),
+		ContentType:               contentType,
+		CacheControl:              cacheControl,
+		ContentDisposition:        contentDisposition,
+		ObjectLockMode:            lockMode,
+		ObjectLockRetainUntilDate: retainUntil,
+		ObjectLockLegalHoldStatus: legalHold,
+		Tagging:                   expireTagging(opts),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to upload to S3: %w", err)
@@ -133,17 +266,75 @@ func (s *S3Backend) pushFile(ctx context.Context, localPath, remotePath string,
 	return nil
 }
 
+// uploadChecksum returns the checksum of a file in the form S3 checks
+// uploads against, so a file corrupted on its way is rejected, unless the
+// provider rejects checksum headers.
+func (s *S3Backend) uploadChecksum(checksum string) *string {
+	raw, err := hex.DecodeString(checksum)
+	if err != nil || len(raw) == 0 || s.cfg.ChecksumsWhenRequired {
+		return nil
+	}
+
+	return aws.String(base64.StdEncoding.EncodeToString(raw))
+}
+
+// objectMetadata is the user metadata of opts along with the checksum, if known.
+func objectMetadata(opts backend.PushOptions, checksum string) map[string]string {
+	metadata := map[string]string{}
+	for key, value := range opts.Metadata {
+		metadata[key] = value
+	}
+
+	if checksum != "" {
+		metadata[backend.ChecksumMetadataKey] = checksum
+	}
+
+	return metadata
+}
+
+// headerFields returns the headers of pushed files as S3 input fields.
+func headerFields(h backend.Headers) (contentType, cacheControl, contentDisposition *string) {
+	optional := func(value string) *string {
+		if value == "" {
+			return nil
+		}
+		return aws.String(value)
+	}
+
+	return optional(h.ContentType), optional(h.CacheControl), optional(h.ContentDisposition)
+}
+
+// pushDirectory uploads the files of a directory, opts.Concurrency at a time.
+// Files an interrupted run already pushed are skipped.
 func (s *S3Backend) pushDirectory(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
-	return filepath.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+	filePaths, infos := []string{}, []os.FileInfo{}
+	skipped := 0
+	err := files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 		if info.IsDir() {
 			return nil
 		}
+		if opts.Resume != nil && opts.Resume.Pushed(filePath, info) {
+			progress.FromContext(ctx).Skip(info.Size())
+			backend.TransferStatsFromContext(ctx).Skipped(info.Size())
+			skipped++
+			return nil
+		}
 
+		filePaths, infos = append(filePaths, filePath), append(infos, info)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	backend.LogResumed(skipped)
+
+	return backend.Parallel(len(filePaths), opts.Concurrency, func(i int) error {
 		// Calculate relative path
-		relPath, err := filepath.Rel(localPath, filePath)
+		relPath, err := filepath.Rel(localPath, filePaths[i])
 		if err != nil {
 			return err
 		}
@@ -151,25 +342,50 @@ func (s *S3Backend) pushDirectory(ctx context.Context, localPath, remotePath str
 		// Build remote path
 		destPath := path.Join(remotePath, filepath.ToSlash(relPath))
 
-		return s.pushFile(ctx, filePath, destPath, opts)
+		if err := s.pushFile(ctx, filePaths[i], destPath, opts); err != nil {
+			return err
+		}
+
+		if opts.Resume != nil {
+			if err := opts.Resume.MarkPushed(filePaths[i], infos[i]); err != nil {
+				log.Warnf("Failed to record the push of '%s': %v\n", filePaths[i], err)
+			}
+		}
+		return nil
 	})
 }
 
-// Pull downloads a file or directory from S3.
+// Pull downloads a file or directory from S3, using the read replica if one
+// is configured. Files not replicated yet are pulled from the primary bucket.
 func (s *S3Backend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
 	log.Debug("S3Backend: Pulling...\n")
 	log.Debugf("* Remote: %s\n", remotePath)
 	log.Debugf("* Local: %s\n", localPath)
 
+	err := s.pullFrom(ctx, s.reader(), remotePath, localPath, opts)
+
+	var notFound *backend.ErrNotFound
+	if s.readClient != nil && errors.As(err, &notFound) {
+		log.Debugf("'%s' not found in the read replica, pulling from the primary bucket...\n", remotePath)
+		return s.pullFrom(ctx, s.primary(), remotePath, localPath, opts)
+	}
+
+	return err
+}
+
+func (s *S3Backend) pullFrom(ctx context.Context, t target, remotePath, localPath string, opts backend.PullOptions) error {
 	key := s.prefixedKey(remotePath)
 
 	// List objects with this prefix to handle both files and directories
-	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
-		Bucket: aws.String(s.cfg.Bucket),
+	paginator := s3.NewListObjectsV2Paginator(t.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(t.bucket),
 		Prefix: aws.String(key),
 	})
 
-	foundAny := false
+	// Every file is checked before any is downloaded, so a pull
+	// that would overwrite local files leaves them untouched
+	keys, etags, destPaths := []string{}, []string{}, []string{}
+	size := int64(0)
 	for paginator.HasMorePages() {
 		page, err := paginator.NextPage(ctx)
 		if err != nil {
@@ -177,8 +393,10 @@ func (s *S3Backend) Pull(ctx context.Context, remotePath, localPath string, opts
 		}
 
 		for _, obj := range page.Contents {
-			foundAny = true
 			objKey := aws.ToString(obj.Key)
+			if backend.IsChecksumSidecar(s.unprefixedKey(objKey)) {
+				continue
+			}
 
 			// Calculate local destination
 			relPath := strings.TrimPrefix(objKey, key)
@@ -191,63 +409,254 @@ func (s *S3Backend) Pull(ctx context.Context, remotePath, localPath string, opts
 				}
 			}
 
-			if err := s.pullFile(ctx, objKey, destPath); err != nil {
-				return err
-			}
+			keys = append(keys, objKey)
+			etags = append(etags, aws.ToString(obj.ETag))
+			destPaths = append(destPaths, destPath)
+			size += aws.ToInt64(obj.Size)
 		}
 	}
 
-	if !foundAny {
+	if len(keys) == 0 {
 		return &backend.ErrNotFound{Path: remotePath}
 	}
 
-	return nil
+	progress.FromContext(ctx).SetTotal(len(keys), size)
+
+	return backend.ParallelAll(len(keys), opts.Concurrency, func(i int) error {
+		return s.pullFile(ctx, t, keys[i], etags[i], destPaths[i])
+	})
 }
 
-func (s *S3Backend) pullFile(ctx context.Context, key, localPath string) error {
-	// Ensure directory exists
-	dir := filepath.Dir(localPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+// pullFile downloads an object through a partial file, which is moved
+// into place once complete. A partial file left by an interrupted pull of
+// the same version of the object is resumed with a Range request.
+func (s *S3Backend) pullFile(ctx context.Context, t target, key, etag, localPath string) error {
+	partial, err := files.OpenPartial(localPath)
+	if err != nil {
+		return err
 	}
 
-	// Download from S3
-	result, err := s.client.GetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(s.cfg.Bucket),
-		Key:    aws.String(key),
-	})
+	if partial.ETag != etag {
+		if err := partial.Restart(etag); err != nil {
+			_ = partial.Close()
+			return err
+		}
+	}
+
+	result, err := s.getObject(ctx, t, key, partial)
 	if err != nil {
-		return fmt.Errorf("failed to download from S3: %w", err)
+		_ = partial.Close()
+		return err
+	}
+
+	// Compressed and encrypted objects are always downloaded whole
+	if encoded(result.Metadata) {
+		resumed := partial.Offset > 0
+		if err := partial.Restart(""); err != nil {
+			_ = result.Body.Close()
+			_ = partial.Close()
+			return err
+		}
+
+		if resumed {
+			_ = result.Body.Close()
+			if result, err = s.getObject(ctx, t, key, partial); err != nil {
+				_ = partial.Close()
+				return err
+			}
+		}
 	}
 	defer result.Body.Close()
 
-	// Create local file
-	file, err := os.Create(localPath)
+	resumed := partial.Offset > 0
+	if resumed {
+		log.Infof("Resuming download of '%s' from byte %d.\n", localPath, partial.Offset)
+	}
+
+	tracked := progress.FromContext(ctx).File(localPath, partial.Offset+aws.ToInt64(result.ContentLength))
+	defer tracked.Done()
+	tracked.Skip(partial.Offset)
+
+	body, err := decode(ctx, s.unprefixedKey(key), result.Metadata, io.NopCloser(tracked.Reader(result.Body)))
 	if err != nil {
-		return fmt.Errorf("failed to create local file '%s': %w", localPath, err)
+		_ = partial.Close()
+		return fmt.Errorf("failed to download '%s': %w", localPath, err)
 	}
-	defer file.Close()
 
-	// Copy content
-	if _, err := io.Copy(file, result.Body); err != nil {
-		return fmt.Errorf("failed to write to local file: %w", err)
+	hash := sha256.New()
+	if _, err := files.Copy(io.MultiWriter(partial, hash), body); err != nil {
+		_ = partial.Close()
+		return fmt.Errorf("failed to write to local file '%s', pull again to resume: %w", localPath, err)
 	}
 
-	log.Debugf("Downloaded: s3://%s/%s -> %s\n", s.cfg.Bucket, key, localPath)
+	// The download is checked against the checksum the object was pushed
+	// with, if any. A resumed one is pieced together from two responses,
+	// so the whole file is hashed again.
+	remotePath := s.unprefixedKey(key)
+	expected, ok := result.Metadata[backend.ChecksumMetadataKey]
+	if !ok {
+		expected = s.sidecarChecksum(ctx, remotePath)
+	}
+
+	actual := hex.EncodeToString(hash.Sum(nil))
+	if resumed && expected != "" {
+		actual, err = files.SHA256File(partial.Name())
+		if err != nil {
+			_ = partial.Close()
+			return err
+		}
+	}
+
+	if err := backend.VerifyChecksum(remotePath, expected, actual); err != nil {
+		_ = partial.Discard()
+		return err
+	}
+
+	if err := partial.Complete(); err != nil {
+		return err
+	}
+
+	if err := backend.RestoreFileAttributes(localPath, result.Metadata); err != nil {
+		log.Warnf("%v\n", err)
+	}
+
+	log.Debugf("Downloaded: s3://%s/%s -> %s\n", t.bucket, key, localPath)
 	return nil
 }
 
+// getObject starts the download of an object from the end of the partial
+// file on. If the object changed since, or the partial file is already as
+// large as it, the partial file is restarted and the whole object downloaded.
+func (s *S3Backend) getObject(ctx context.Context, t target, key string, partial *files.Partial) (*s3.GetObjectOutput, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(key),
+	}
+
+	if partial.Offset > 0 {
+		rangeInput := *input
+		rangeInput.Range = aws.String(fmt.Sprintf("bytes=%d-", partial.Offset))
+		rangeInput.IfMatch = aws.String(partial.ETag)
+
+		// The checksum of the whole object can never match the part read
+		result, err := t.client.GetObject(ctx, &rangeInput, func(o *s3.Options) {
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		})
+		if err == nil {
+			return result, nil
+		}
+
+		if !strings.Contains(err.Error(), "InvalidRange") && !strings.Contains(err.Error(), "PreconditionFailed") {
+			return nil, fmt.Errorf("failed to download from S3: %w", err)
+		}
+
+		log.Debugf("Cannot resume the download of '%s', starting over: %v\n", key, err)
+		if err := partial.Restart(partial.ETag); err != nil {
+			return nil, err
+		}
+	}
+
+	result, err := t.client.GetObject(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+
+	return result, nil
+}
+
+// Open streams the contents of a file stored in S3, using the read replica
+// if one is configured and falling back to the primary bucket.
+func (s *S3Backend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("S3Backend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	r, err := s.openFrom(ctx, s.reader(), remotePath, 0)
+
+	var notFound *backend.ErrNotFound
+	if s.readClient != nil && errors.As(err, &notFound) {
+		log.Debugf("'%s' not found in the read replica, opening it from the primary bucket...\n", remotePath)
+		return s.openFrom(ctx, s.primary(), remotePath, 0)
+	}
+
+	return r, err
+}
+
+// OpenRange streams a file stored in S3 from offset on with a Range request.
+// Ranges are read from the primary bucket, since they follow files that are
+// still growing and a read replica may lag behind.
+func (s *S3Backend) OpenRange(ctx context.Context, remotePath string, offset int64) (io.ReadCloser, error) {
+	log.Debug("S3Backend: Opening range...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Offset: %d\n", offset)
+
+	return s.openFrom(ctx, s.primary(), remotePath, offset)
+}
+
+func (s *S3Backend) openFrom(ctx context.Context, t target, remotePath string, offset int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(s.prefixedKey(remotePath)),
+	}
+	var optFns []func(*s3.Options)
+	if offset > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=%d-", offset))
+		// Some S3-compatible stores return the checksum of the whole object
+		// with a range, which the part read can never match
+		optFns = append(optFns, func(o *s3.Options) {
+			o.ResponseChecksumValidation = aws.ResponseChecksumValidationWhenRequired
+		})
+	}
+
+	result, err := t.client.GetObject(ctx, input, optFns...)
+	if err != nil {
+		if strings.Contains(err.Error(), "NoSuchKey") || strings.Contains(err.Error(), "404") {
+			return nil, &backend.ErrNotFound{Path: remotePath}
+		}
+		// The offset is at or past the end of the file
+		if offset > 0 && strings.Contains(err.Error(), "InvalidRange") {
+			return io.NopCloser(strings.NewReader("")), nil
+		}
+		return nil, fmt.Errorf("failed to download from S3: %w", err)
+	}
+
+	if !encoded(result.Metadata) {
+		return result.Body, nil
+	}
+
+	if offset > 0 {
+		_ = result.Body.Close()
+		return nil, fmt.Errorf("'%s' is stored compressed or encrypted, and cannot be read from an offset", remotePath)
+	}
+
+	return decode(ctx, remotePath, result.Metadata, result.Body)
+}
+
 // Yank deletes a file or directory from S3.
 func (s *S3Backend) Yank(ctx context.Context, remotePath string) error {
 	log.Debug("S3Backend: Yanking...\n")
 	log.Debugf("* Remote: %s\n", remotePath)
 
-	key := s.prefixedKey(remotePath)
+	if err := s.checkNotLocked(ctx, remotePath); err != nil {
+		return err
+	}
+
+	if err := s.deletePrefix(ctx, s.prefixedKey(remotePath)); err != nil {
+		return err
+	}
+
+	// Remove the checksums stored for the yanked files
+	if err := s.deletePrefix(ctx, s.prefixedKey(backend.ChecksumSidecarPrefix(remotePath))); err != nil {
+		log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+	}
 
-	// List all objects with this prefix
+	return nil
+}
+
+// deletePrefix deletes every object whose key starts with prefix.
+func (s *S3Backend) deletePrefix(ctx context.Context, prefix string) error {
 	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(s.cfg.Bucket),
-		Prefix: aws.String(key),
+		Prefix: aws.String(prefix),
 	})
 
 	for paginator.HasMorePages() {
@@ -261,6 +670,13 @@ func (s *S3Backend) Yank(ctx context.Context, remotePath string) error {
 				Bucket: aws.String(s.cfg.Bucket),
 				Key:    obj.Key,
 			})
+			if err != nil && strings.Contains(err.Error(), "AccessDenied") {
+				return &backend.ErrPermissionDenied{
+					Operation: "yank",
+					Path:      s.unprefixedKey(aws.ToString(obj.Key)),
+					Reason:    "access denied; the object may be protected by S3 Object Lock",
+				}
+			}
 			if err != nil {
 				return fmt.Errorf("failed to delete S3 object '%s': %w", aws.ToString(obj.Key), err)
 			}
@@ -273,22 +689,176 @@ func (s *S3Backend) Yank(ctx context.Context, remotePath string) error {
 
 // Exists checks if a file exists in S3.
 func (s *S3Backend) Exists(ctx context.Context, remotePath string) (bool, error) {
-	key := s.prefixedKey(remotePath)
+	_, err := s.headObject(ctx, remotePath)
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to check S3 object existence: %w", err)
+	}
 
-	_, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
-		Bucket: aws.String(s.cfg.Bucket),
-		Key:    aws.String(key),
+	return true, nil
+}
+
+// headObject sends a HEAD request for remotePath to the read replica if one
+// is configured, falling back to the primary bucket for files not
+// replicated yet, and returns an ErrNotFound if neither has it.
+func (s *S3Backend) headObject(ctx context.Context, remotePath string) (*s3.HeadObjectOutput, error) {
+	head, err := s.headObjectFrom(ctx, s.reader(), remotePath)
+
+	var notFound *backend.ErrNotFound
+	if s.readClient != nil && errors.As(err, &notFound) {
+		log.Debugf("'%s' not found in the read replica, checking the primary bucket...\n", remotePath)
+		return s.headObjectFrom(ctx, s.primary(), remotePath)
+	}
+
+	return head, err
+}
+
+func (s *S3Backend) headObjectFrom(ctx context.Context, t target, remotePath string) (*s3.HeadObjectOutput, error) {
+	head, err := t.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(t.bucket),
+		Key:    aws.String(s.prefixedKey(remotePath)),
 	})
 	if err != nil {
-		// Check if it's a "not found" error
 		// AWS SDK v2 doesn't have a typed error, so we check the string
 		if strings.Contains(err.Error(), "NotFound") || strings.Contains(err.Error(), "404") {
-			return false, nil
+			return nil, &backend.ErrNotFound{Path: remotePath}
 		}
-		return false, fmt.Errorf("failed to check S3 object existence: %w", err)
+		return nil, err
 	}
 
-	return true, nil
+	return head, nil
+}
+
+// List calls fn for every object stored under remotePrefix, one listing page at a time.
+// Listings are served by the read replica if one is configured.
+func (s *S3Backend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("S3Backend: Listing...\n")
+	log.Debugf("* Remote: %s\n", remotePrefix)
+
+	key := s.prefixedKey(remotePrefix)
+	t := s.reader()
+
+	paginator := s3.NewListObjectsV2Paginator(t.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(t.bucket),
+		Prefix: aws.String(key),
+	})
+
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			objPath := s.unprefixedKey(aws.ToString(obj.Key))
+			if backend.IsChecksumSidecar(objPath) {
+				continue
+			}
+
+			err := fn(backend.ObjectInfo{
+				Path:    objPath,
+				Size:    aws.ToInt64(obj.Size),
+				ModTime: aws.ToTime(obj.LastModified),
+				ETag:    strings.Trim(aws.ToString(obj.ETag), `"`),
+			})
+			if err == backend.StopListing {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Stat describes an object with a HEAD request, sent to the read replica
+// if one is configured.
+func (s *S3Backend) Stat(ctx context.Context, remotePath string) (*backend.ObjectInfo, error) {
+	log.Debug("S3Backend: Stat...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	head, err := s.headObject(ctx, remotePath)
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to check S3 object '%s': %w", remotePath, err)
+	}
+
+	// Encoded files are described by their size before encoding, the
+	// number of bytes they are pulled as
+	size := aws.ToInt64(head.ContentLength)
+	if encoded(head.Metadata) {
+		if original := originalSize(head.Metadata); original >= 0 {
+			size = original
+		}
+	}
+
+	return &backend.ObjectInfo{
+		Path:        remotePath,
+		Size:        size,
+		ModTime:     aws.ToTime(head.LastModified),
+		ETag:        strings.Trim(aws.ToString(head.ETag), `"`),
+		ContentType: aws.ToString(head.ContentType),
+	}, nil
+}
+
+// Checksum returns the checksum stored in the object's metadata,
+// falling back to its sidecar for objects uploaded in multiple parts.
+func (s *S3Backend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	head, err := s.headObject(ctx, remotePath)
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", err
+		}
+		return "", fmt.Errorf("failed to check S3 object '%s': %w", remotePath, err)
+	}
+
+	if checksum, ok := head.Metadata[backend.ChecksumMetadataKey]; ok {
+		return checksum, nil
+	}
+
+	return s.readSidecar(ctx, remotePath)
+}
+
+// readSidecar returns the checksum stored in the sidecar of remotePath,
+// or nothing if it has none.
+func (s *S3Backend) readSidecar(ctx context.Context, remotePath string) (string, error) {
+	sidecar, err := s.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// sidecarChecksum returns the checksum of a pulled object stored without
+// one in its metadata, e.g. a streamed one. Failing to read it only
+// leaves the object unverified.
+func (s *S3Backend) sidecarChecksum(ctx context.Context, remotePath string) string {
+	checksum, err := s.readSidecar(ctx, remotePath)
+	if err != nil {
+		log.Debugf("Cannot read the checksum of '%s', not verifying it: %v\n", remotePath, err)
+	}
+
+	return checksum
 }
 
 // Close releases any resources. For S3 backend, this is a no-op.
@@ -303,3 +873,11 @@ func (s *S3Backend) prefixedKey(remotePath string) string {
 	}
 	return remotePath
 }
+
+// unprefixedKey strips the configured prefix from an S3 key.
+func (s *S3Backend) unprefixedKey(key string) string {
+	if s.cfg.Prefix != "" {
+		return strings.TrimPrefix(strings.TrimPrefix(key, path.Clean(s.cfg.Prefix)), "/")
+	}
+	return key
+}
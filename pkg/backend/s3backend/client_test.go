@@ -1,12 +1,21 @@
 package s3backend
 
 import (
+	"bytes"
 	"context"
+	"fmt"
+	"io"
+	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"filippo.io/age"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
@@ -14,6 +23,9 @@ import (
 	"github.com/johannesboyne/gofakes3"
 	"github.com/johannesboyne/gofakes3/backend/s3mem"
 	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/compress"
+	"github.com/semaphoreci/artifact/pkg/encrypt"
+	"github.com/semaphoreci/artifact/pkg/files"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -111,6 +123,33 @@ func TestS3Backend_Push_Directory(t *testing.T) {
 	assert.True(t, exists)
 }
 
+func TestS3Backend_Push_DirectoryConcurrently(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	for i := 0; i < 50; i++ {
+		err := os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("file%02d.txt", i)), []byte("content"), 0644)
+		require.NoError(t, err)
+	}
+
+	ctx := context.Background()
+	err := s3Backend.Push(ctx, tmpDir, "artifacts/jobs/456/data", backend.PushOptions{Concurrency: 4})
+	require.NoError(t, err)
+
+	count := 0
+	err = s3Backend.List(ctx, "artifacts/jobs/456/data/", func(obj backend.ObjectInfo) error {
+		count++
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 50, count)
+
+	// A file that already exists fails the push
+	err = s3Backend.Push(ctx, tmpDir, "artifacts/jobs/456/data", backend.PushOptions{Concurrency: 4})
+	assert.IsType(t, &backend.ErrAlreadyExists{}, err)
+}
+
 func TestS3Backend_Push_AlreadyExists(t *testing.T) {
 	s3Backend, _, cleanup := createTestS3Backend(t)
 	defer cleanup()
@@ -160,6 +199,378 @@ func TestS3Backend_Pull_SingleFile(t *testing.T) {
 	assert.Equal(t, "test content", string(content))
 }
 
+func TestS3Backend_Pull_DirectoryConcurrently(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	for i := 0; i < 50; i++ {
+		err := os.WriteFile(filepath.Join(tmpDir, fmt.Sprintf("file%02d.txt", i)), []byte(fmt.Sprintf("content %d", i)), 0644)
+		require.NoError(t, err)
+	}
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, tmpDir, "artifacts/jobs/456/data", backend.PushOptions{}))
+
+	pullDir := filepath.Join(t.TempDir(), "data")
+	err := s3Backend.Pull(ctx, "artifacts/jobs/456/data", pullDir, backend.PullOptions{Concurrency: 4})
+	require.NoError(t, err)
+
+	pulled, err := os.ReadDir(pullDir)
+	require.NoError(t, err)
+	assert.Len(t, pulled, 50)
+
+	content, err := os.ReadFile(filepath.Join(pullDir, "file42.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "content 42", string(content))
+
+	// Existing files fail the pull before anything is downloaded
+	require.NoError(t, os.Remove(filepath.Join(pullDir, "file00.txt")))
+	err = s3Backend.Pull(ctx, "artifacts/jobs/456/data", pullDir, backend.PullOptions{Concurrency: 4})
+	assert.ErrorContains(t, err, "already exists locally")
+	assert.NoFileExists(t, filepath.Join(pullDir, "file00.txt"))
+}
+
+func TestS3Backend_Pull_ResumesPartialDownload(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	ranges := []string{}
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet && r.URL.Query().Get("list-type") == "" {
+			ranges = append(ranges, r.Header.Get("Range"))
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "source.bin")
+	require.NoError(t, os.WriteFile(srcFile, []byte("0123456789"), 0644))
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/source.bin", backend.PushOptions{}))
+
+	head, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("artifacts/jobs/1/source.bin"),
+	})
+	require.NoError(t, err)
+	etag := aws.ToString(head.ETag)
+
+	writePartial := func(localPath, etag, contents string) {
+		partial, err := files.OpenPartial(localPath)
+		require.NoError(t, err)
+		require.NoError(t, partial.Restart(etag))
+		partial.Write([]byte(contents))
+		require.NoError(t, partial.Close())
+	}
+
+	t.Run("download is resumed", func(t *testing.T) {
+		ranges = []string{}
+		dstFile := filepath.Join(t.TempDir(), "resumed.bin")
+		writePartial(dstFile, etag, "0123")
+
+		require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/source.bin", dstFile, backend.PullOptions{}))
+
+		content, err := os.ReadFile(dstFile)
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", string(content))
+		assert.Equal(t, []string{"bytes=4-"}, ranges)
+		assert.NoFileExists(t, dstFile+files.PartialSuffix)
+	})
+
+	t.Run("changed object is downloaded again", func(t *testing.T) {
+		ranges = []string{}
+		dstFile := filepath.Join(t.TempDir(), "changed.bin")
+		writePartial(dstFile, `"stale"`, "abcd")
+
+		require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/source.bin", dstFile, backend.PullOptions{}))
+
+		content, err := os.ReadFile(dstFile)
+		require.NoError(t, err)
+		assert.Equal(t, "0123456789", string(content))
+		assert.Equal(t, []string{""}, ranges)
+	})
+
+	t.Run("corrupt partial file fails the checksum", func(t *testing.T) {
+		dstFile := filepath.Join(t.TempDir(), "corrupt.bin")
+		writePartial(dstFile, etag, "abcd")
+
+		err := s3Backend.Pull(ctx, "artifacts/jobs/1/source.bin", dstFile, backend.PullOptions{})
+		assert.ErrorContains(t, err, "does not match its checksum")
+		var mismatch *backend.ErrChecksumMismatch
+		assert.ErrorAs(t, err, &mismatch)
+		assert.NoFileExists(t, dstFile)
+		assert.NoFileExists(t, dstFile+files.PartialSuffix)
+	})
+}
+
+func TestS3Backend_Pull_VerifiesChecksum(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	checksums := []string{}
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			checksums = append(checksums, r.Header.Get("X-Amz-Checksum-Sha256"))
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	srcFile := filepath.Join(t.TempDir(), "source.txt")
+	require.NoError(t, os.WriteFile(srcFile, []byte("hello"), 0644))
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/source.txt", backend.PushOptions{}))
+
+	// The upload carries its SHA256, for S3 to reject it if it arrives corrupted
+	assert.Equal(t, []string{"LPJNul+wow4m6DsqxbninhsWHlwfp0JecwQzYpOLmCQ="}, checksums)
+
+	t.Run("matching download is kept", func(t *testing.T) {
+		dstFile := filepath.Join(t.TempDir(), "source.txt")
+		require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/source.txt", dstFile, backend.PullOptions{}))
+		assert.FileExists(t, dstFile)
+	})
+
+	t.Run("corrupt download is removed", func(t *testing.T) {
+		_, err := s3Backend.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:   aws.String("test-bucket"),
+			Key:      aws.String("artifacts/jobs/1/corrupt.txt"),
+			Body:     strings.NewReader("corrupted"),
+			Metadata: map[string]string{backend.ChecksumMetadataKey: strings.Repeat("0", 64)},
+		})
+		require.NoError(t, err)
+
+		dstFile := filepath.Join(t.TempDir(), "corrupt.txt")
+		err = s3Backend.Pull(ctx, "artifacts/jobs/1/corrupt.txt", dstFile, backend.PullOptions{})
+
+		var mismatch *backend.ErrChecksumMismatch
+		require.ErrorAs(t, err, &mismatch)
+		assert.Equal(t, "artifacts/jobs/1/corrupt.txt", mismatch.Path)
+		assert.NoFileExists(t, dstFile)
+		assert.NoFileExists(t, dstFile+files.PartialSuffix)
+	})
+}
+
+func TestS3Backend_Push_Compressed(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	contents := strings.Repeat("2024-01-01T00:00:00Z INFO build step finished\n", 1000)
+	srcFile := filepath.Join(t.TempDir(), "build.log")
+	require.NoError(t, os.WriteFile(srcFile, []byte(contents), 0644))
+	checksum, err := files.SHA256File(srcFile)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, encoding := range compress.Encodings {
+		t.Run(encoding, func(t *testing.T) {
+			remotePath := "artifacts/jobs/1/" + encoding + "/build.log"
+			require.NoError(t, s3Backend.Push(ctx, srcFile, remotePath, backend.PushOptions{Compress: encoding}))
+
+			head, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String("test-bucket"),
+				Key:    aws.String(remotePath),
+			})
+			require.NoError(t, err)
+			assert.Less(t, aws.ToInt64(head.ContentLength)*10, int64(len(contents)))
+			assert.Equal(t, encoding, head.Metadata[backend.EncodingMetadataKey])
+			assert.Equal(t, strconv.Itoa(len(contents)), head.Metadata[backend.SizeMetadataKey])
+
+			// The size and checksum are the ones of the uncompressed file
+			info, err := s3Backend.Stat(ctx, remotePath)
+			require.NoError(t, err)
+			assert.Equal(t, int64(len(contents)), info.Size)
+
+			stored, err := s3Backend.Checksum(ctx, remotePath)
+			require.NoError(t, err)
+			assert.Equal(t, checksum, stored)
+
+			dstFile := filepath.Join(t.TempDir(), "build.log")
+			require.NoError(t, s3Backend.Pull(ctx, remotePath, dstFile, backend.PullOptions{}))
+			pulled, err := os.ReadFile(dstFile)
+			require.NoError(t, err)
+			assert.Equal(t, contents, string(pulled))
+
+			r, err := s3Backend.Open(ctx, remotePath)
+			require.NoError(t, err)
+			require.Implements(t, (*backend.SizedReader)(nil), r)
+			assert.Equal(t, int64(len(contents)), r.(backend.SizedReader).Size())
+			opened, err := io.ReadAll(r)
+			require.NoError(t, err)
+			require.NoError(t, r.Close())
+			assert.Equal(t, contents, string(opened))
+		})
+	}
+
+	t.Run("partial download is restarted", func(t *testing.T) {
+		head, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String("artifacts/jobs/1/gzip/build.log"),
+		})
+		require.NoError(t, err)
+
+		dstFile := filepath.Join(t.TempDir(), "build.log")
+		partial, err := files.OpenPartial(dstFile)
+		require.NoError(t, err)
+		require.NoError(t, partial.Restart(aws.ToString(head.ETag)))
+		_, _ = partial.Write([]byte(contents[:100]))
+		require.NoError(t, partial.Close())
+
+		require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/gzip/build.log", dstFile, backend.PullOptions{}))
+		pulled, err := os.ReadFile(dstFile)
+		require.NoError(t, err)
+		assert.Equal(t, contents, string(pulled))
+	})
+}
+
+func TestS3Backend_Push_Encrypted(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	contents := strings.Repeat("customer,email\n42,someone@example.com\n", 100)
+	srcFile := filepath.Join(t.TempDir(), "export.csv")
+	require.NoError(t, os.WriteFile(srcFile, []byte(contents), 0644))
+
+	ctx := context.Background()
+	remotePath := "artifacts/jobs/1/export.csv"
+	opts := backend.PushOptions{Compress: compress.Zstd, Encrypt: []age.Recipient{identity.Recipient()}}
+	require.NoError(t, s3Backend.Push(ctx, srcFile, remotePath, opts))
+
+	// The storage only sees ciphertext
+	result, err := s3Backend.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String(remotePath),
+	})
+	require.NoError(t, err)
+	stored, err := io.ReadAll(result.Body)
+	require.NoError(t, err)
+	result.Body.Close()
+	assert.NotContains(t, string(stored), "someone@example.com")
+
+	head, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String(remotePath),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, encrypt.Age, head.Metadata[backend.EncryptionMetadataKey])
+	assert.Equal(t, compress.Zstd, head.Metadata[backend.EncodingMetadataKey])
+
+	dstFile := filepath.Join(t.TempDir(), "export.csv")
+	err = s3Backend.Pull(ctx, remotePath, dstFile, backend.PullOptions{})
+	assert.ErrorIs(t, err, encrypt.ErrNoKey)
+	assert.NoFileExists(t, dstFile)
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	err = s3Backend.Pull(encrypt.NewContext(ctx, []age.Identity{other}), remotePath, dstFile, backend.PullOptions{})
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	ctx = encrypt.NewContext(ctx, []age.Identity{identity})
+	require.NoError(t, s3Backend.Pull(ctx, remotePath, dstFile, backend.PullOptions{}))
+	pulled, err := os.ReadFile(dstFile)
+	require.NoError(t, err)
+	assert.Equal(t, contents, string(pulled))
+
+	r, err := s3Backend.Open(ctx, remotePath)
+	require.NoError(t, err)
+	opened, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.NoError(t, r.Close())
+	assert.Equal(t, contents, string(opened))
+}
+
+func TestS3Backend_Push_Headers(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	// The fake S3 server does not store Cache-Control
+	var mu sync.Mutex
+	cacheControl := map[string]string{}
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			mu.Lock()
+			cacheControl[strings.TrimPrefix(r.URL.Path, "/test-bucket/")] = r.Header.Get("Cache-Control")
+			mu.Unlock()
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "index.html"), []byte("<html></html>"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "logo"), []byte("\x89PNG\r\n\x1a\n"), 0644))
+
+	ctx := context.Background()
+	head := func(remotePath string) *s3.HeadObjectOutput {
+		output, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String("test-bucket"),
+			Key:    aws.String(remotePath),
+		})
+		require.NoError(t, err)
+		return output
+	}
+
+	t.Run("detects the content type of each file", func(t *testing.T) {
+		opts := backend.PushOptions{Headers: backend.Headers{CacheControl: "max-age=3600"}}
+		require.NoError(t, s3Backend.Push(ctx, dir, "artifacts/jobs/1/report", opts))
+
+		page := head("artifacts/jobs/1/report/index.html")
+		assert.Equal(t, "text/html; charset=utf-8", aws.ToString(page.ContentType))
+		assert.Equal(t, "max-age=3600", cacheControl["artifacts/jobs/1/report/index.html"])
+		assert.Equal(t, "image/png", aws.ToString(head("artifacts/jobs/1/report/logo").ContentType))
+	})
+
+	t.Run("sets the given headers", func(t *testing.T) {
+		opts := backend.PushOptions{Headers: backend.Headers{ContentType: "text/plain", ContentDisposition: "attachment"}}
+		require.NoError(t, s3Backend.Push(ctx, filepath.Join(dir, "index.html"), "artifacts/jobs/1/source.html", opts))
+
+		page := head("artifacts/jobs/1/source.html")
+		assert.Equal(t, "text/plain", aws.ToString(page.ContentType))
+		assert.Equal(t, "attachment", aws.ToString(page.ContentDisposition))
+	})
+
+	t.Run("detects the content type of streams", func(t *testing.T) {
+		require.NoError(t, s3Backend.PushStream(ctx, strings.NewReader("<!DOCTYPE html>"), -1, "artifacts/jobs/1/page", backend.PushOptions{}))
+		assert.Equal(t, "text/html; charset=utf-8", aws.ToString(head("artifacts/jobs/1/page").ContentType))
+	})
+}
+
+func TestS3Backend_Push_FileAttributes(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	srcFile := filepath.Join(t.TempDir(), "tool")
+	require.NoError(t, os.WriteFile(srcFile, []byte("#!/bin/sh\necho ok\n"), 0755))
+	require.NoError(t, os.Chmod(srcFile, 0755))
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	require.NoError(t, os.Chtimes(srcFile, mtime, mtime))
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/bin/tool", backend.PushOptions{}))
+
+	head, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String("test-bucket"),
+		Key:    aws.String("artifacts/jobs/1/bin/tool"),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "0755", head.Metadata[backend.ModeMetadataKey])
+	assert.Equal(t, "2024-01-02T03:04:05.000000006Z", head.Metadata[backend.MTimeMetadataKey])
+
+	dstDir := t.TempDir()
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/bin", dstDir, backend.PullOptions{}))
+
+	info, err := os.Stat(filepath.Join(dstDir, "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	assert.True(t, mtime.Equal(info.ModTime()), "modification time %s", info.ModTime())
+}
+
 func TestS3Backend_Pull_NotFound(t *testing.T) {
 	s3Backend, _, cleanup := createTestS3Backend(t)
 	defer cleanup()
@@ -227,3 +638,139 @@ func TestS3Backend_Exists(t *testing.T) {
 	assert.NoError(t, err)
 	assert.True(t, exists)
 }
+
+func TestS3Backend_List(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	testFile := filepath.Join(tmpDir, "test.txt")
+	err := os.WriteFile(testFile, []byte("listed"), 0644)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		err = s3Backend.Push(ctx, testFile, "artifacts/jobs/1/"+name, backend.PushOptions{})
+		require.NoError(t, err)
+	}
+
+	listed := []backend.ObjectInfo{}
+	err = s3Backend.List(ctx, "artifacts/jobs/1/", func(obj backend.ObjectInfo) error {
+		listed = append(listed, obj)
+		return nil
+	})
+	assert.NoError(t, err)
+	require.Len(t, listed, 3)
+	assert.Equal(t, "artifacts/jobs/1/a.txt", listed[0].Path)
+	assert.Equal(t, int64(6), listed[0].Size)
+	assert.False(t, listed[0].ModTime.IsZero())
+
+	// Stopping early is not an error
+	count := 0
+	err = s3Backend.List(ctx, "artifacts/jobs/1/", func(obj backend.ObjectInfo) error {
+		count++
+		return backend.StopListing
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+}
+
+func TestS3Backend_PushStream(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+
+	// Small streams are uploaded with a single request
+	err := s3Backend.PushStream(ctx, strings.NewReader("streamed"), -1, "artifacts/jobs/1/small.txt", backend.PushOptions{})
+	require.NoError(t, err)
+
+	err = s3Backend.PushStream(ctx, strings.NewReader("streamed"), -1, "artifacts/jobs/1/small.txt", backend.PushOptions{})
+	var existsErr *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &existsErr)
+
+	dstFile := filepath.Join(tmpDir, "small.txt")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/small.txt", dstFile, backend.PullOptions{}))
+	content, err := os.ReadFile(dstFile)
+	require.NoError(t, err)
+	assert.Equal(t, "streamed", string(content))
+
+	// Larger streams use a multipart upload
+	large := bytes.Repeat([]byte("0123456789"), streamPartSize/10+1024)
+	err = s3Backend.PushStream(ctx, bytes.NewReader(large), int64(len(large)), "artifacts/jobs/1/large.bin", backend.PushOptions{})
+	require.NoError(t, err)
+
+	dstFile = filepath.Join(tmpDir, "large.bin")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/large.bin", dstFile, backend.PullOptions{}))
+	content, err = os.ReadFile(dstFile)
+	require.NoError(t, err)
+	assert.Equal(t, large, content)
+}
+
+func TestS3Backend_Checksum(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "source.txt")
+	require.NoError(t, os.WriteFile(srcFile, []byte("toolchain"), 0644))
+
+	// Checksums of pushed files are stored in the object metadata
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/source.txt", backend.PushOptions{}))
+	checksum, err := s3Backend.Checksum(ctx, "artifacts/jobs/1/source.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "0db3de82a739e43a2b560d166d037c3c0061601bb194866eb79b2c87045d00f2", checksum)
+
+	// Multipart uploads store it in a sidecar, which is hidden from listings
+	large := bytes.Repeat([]byte("x"), streamPartSize+1)
+	require.NoError(t, s3Backend.PushStream(ctx, bytes.NewReader(large), -1, "artifacts/jobs/1/large.bin", backend.PushOptions{}))
+	checksum, err = s3Backend.Checksum(ctx, "artifacts/jobs/1/large.bin")
+	require.NoError(t, err)
+	assert.Len(t, checksum, 64)
+
+	listed := []string{}
+	require.NoError(t, s3Backend.List(ctx, "artifacts/jobs/1/", func(obj backend.ObjectInfo) error {
+		listed = append(listed, obj.Path)
+		return nil
+	}))
+	assert.Equal(t, []string{"artifacts/jobs/1/large.bin", "artifacts/jobs/1/source.txt"}, listed)
+
+	// Yanking removes the sidecar too
+	require.NoError(t, s3Backend.Yank(ctx, "artifacts/jobs/1/large.bin"))
+	exists, err := s3Backend.Exists(ctx, backend.ChecksumSidecarPath("artifacts/jobs/1/large.bin"))
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = s3Backend.Checksum(ctx, "artifacts/jobs/1/missing.txt")
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestS3Backend_OpenRange(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	err := s3Backend.PushStream(ctx, strings.NewReader("line 1\nline 2\n"), -1, "artifacts/jobs/1/build.log", backend.PushOptions{})
+	require.NoError(t, err)
+
+	read := func(offset int64) string {
+		r, err := s3Backend.OpenRange(ctx, "artifacts/jobs/1/build.log", offset)
+		require.NoError(t, err)
+		defer r.Close()
+
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	assert.Equal(t, "line 1\nline 2\n", read(0))
+	assert.Equal(t, "line 2\n", read(7))
+	assert.Equal(t, "", read(14))
+
+	_, err = s3Backend.OpenRange(ctx, "artifacts/jobs/1/missing.log", 7)
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
@@ -6,7 +6,12 @@ package s3backend
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
 	"github.com/spf13/viper"
 )
 
@@ -27,6 +32,49 @@ type Config struct {
 
 	// Prefix is an optional path prefix for all artifacts
 	Prefix string
+
+	// ReadBucket, ReadRegion and ReadEndpoint configure a read replica, e.g.
+	// a cross-region replica or a CDN-backed endpoint. Pulls and listings
+	// are served from it, while pushes and yanks go to the primary bucket.
+	// Unset fields default to the primary's values.
+	ReadBucket   string
+	ReadRegion   string
+	ReadEndpoint string
+
+	// ObjectLockMode is the default Object Lock retention mode for pushed
+	// objects, GOVERNANCE or COMPLIANCE. The bucket must have Object Lock enabled.
+	ObjectLockMode string
+
+	// ObjectLockRetainFor is how long pushed objects are retained with ObjectLockMode
+	ObjectLockRetainFor time.Duration
+
+	// ObjectLockLegalHold puts a legal hold on pushed objects by default
+	ObjectLockLegalHold bool
+
+	// ExpireLifecycleRules adds a bucket lifecycle rule for every retention
+	// files are pushed with, so the files tagged with it expire
+	ExpireLifecycleRules bool
+
+	// MultipartThreshold is the size from which files are uploaded in parts,
+	// PartSize the size of the parts and PartConcurrency how many parts of
+	// a file are uploaded at once. Zero values use the defaults.
+	MultipartThreshold int64
+	PartSize           int64
+	PartConcurrency    int
+
+	// Provider is a preset for an S3-compatible service, e.g. r2 or minio,
+	// setting the endpoint, region and quirks not configured explicitly
+	Provider string
+
+	// AccountID is the account the endpoint of some providers includes, e.g. R2
+	AccountID string
+
+	// ChecksumsWhenRequired is set by providers rejecting default checksum headers
+	ChecksumsWhenRequired bool
+
+	// CredentialHelper provides the access keys instead of the default
+	// credential chain, if configured
+	CredentialHelper *backend.CredentialHelper
 }
 
 // LoadConfig loads S3 configuration from environment variables and config file.
@@ -38,9 +86,27 @@ type Config struct {
 //   - ARTIFACT_S3_ENDPOINT (optional)
 //   - ARTIFACT_S3_FORCE_PATH_STYLE (optional, "true" to enable)
 //   - ARTIFACT_S3_PREFIX (optional)
+//   - ARTIFACT_S3_READ_BUCKET (optional)
+//   - ARTIFACT_S3_READ_REGION (optional)
+//   - ARTIFACT_S3_READ_ENDPOINT (optional)
+//   - ARTIFACT_S3_OBJECT_LOCK_MODE (optional, GOVERNANCE or COMPLIANCE)
+//   - ARTIFACT_S3_OBJECT_LOCK_RETAIN_FOR (required with a mode, e.g. "365d")
+//   - ARTIFACT_S3_OBJECT_LOCK_LEGAL_HOLD (optional, "true" to enable)
+//   - ARTIFACT_S3_EXPIRE_LIFECYCLE_RULES (optional, "true" to enable)
+//   - ARTIFACT_S3_MULTIPART_THRESHOLD (optional, e.g. "64MB")
+//   - ARTIFACT_S3_PART_SIZE (optional, e.g. "16MB")
+//   - ARTIFACT_S3_PART_CONCURRENCY (optional, e.g. "4")
+//   - ARTIFACT_S3_PROVIDER (optional, one of Providers)
+//   - ARTIFACT_S3_ACCOUNT_ID (optional, required by the r2 provider)
+//   - ARTIFACT_CREDENTIAL_HELPER (optional, see backend.CredentialHelper)
 //
 // Config file keys (under 's3' section):
 //   - bucket, region, endpoint, forcePathStyle, prefix
+//   - readBucket, readRegion, readEndpoint
+//   - objectLockMode, objectLockRetainFor, objectLockLegalHold
+//   - expireLifecycleRules
+//   - multipartThreshold, partSize, partConcurrency
+//   - provider, accountId
 func LoadConfig() (*Config, error) {
 	cfg := &Config{}
 
@@ -50,6 +116,18 @@ func LoadConfig() (*Config, error) {
 	cfg.Endpoint = os.Getenv("ARTIFACT_S3_ENDPOINT")
 	cfg.ForcePathStyle = os.Getenv("ARTIFACT_S3_FORCE_PATH_STYLE") == "true"
 	cfg.Prefix = os.Getenv("ARTIFACT_S3_PREFIX")
+	cfg.ReadBucket = os.Getenv("ARTIFACT_S3_READ_BUCKET")
+	cfg.ReadRegion = os.Getenv("ARTIFACT_S3_READ_REGION")
+	cfg.ReadEndpoint = os.Getenv("ARTIFACT_S3_READ_ENDPOINT")
+	cfg.ObjectLockMode = os.Getenv("ARTIFACT_S3_OBJECT_LOCK_MODE")
+	cfg.ObjectLockLegalHold = os.Getenv("ARTIFACT_S3_OBJECT_LOCK_LEGAL_HOLD") == "true"
+	cfg.ExpireLifecycleRules = os.Getenv("ARTIFACT_S3_EXPIRE_LIFECYCLE_RULES") == "true"
+	cfg.Provider = os.Getenv("ARTIFACT_S3_PROVIDER")
+	cfg.AccountID = os.Getenv("ARTIFACT_S3_ACCOUNT_ID")
+	retainFor := os.Getenv("ARTIFACT_S3_OBJECT_LOCK_RETAIN_FOR")
+	multipartThreshold := os.Getenv("ARTIFACT_S3_MULTIPART_THRESHOLD")
+	partSize := os.Getenv("ARTIFACT_S3_PART_SIZE")
+	partConcurrency := os.Getenv("ARTIFACT_S3_PART_CONCURRENCY")
 
 	// Fall back to config file for unset values
 	if cfg.Bucket == "" {
@@ -67,6 +145,67 @@ func LoadConfig() (*Config, error) {
 	if cfg.Prefix == "" {
 		cfg.Prefix = viper.GetString("s3.prefix")
 	}
+	if cfg.ReadBucket == "" {
+		cfg.ReadBucket = viper.GetString("s3.readBucket")
+	}
+	if cfg.ReadRegion == "" {
+		cfg.ReadRegion = viper.GetString("s3.readRegion")
+	}
+	if cfg.ReadEndpoint == "" {
+		cfg.ReadEndpoint = viper.GetString("s3.readEndpoint")
+	}
+	if cfg.ObjectLockMode == "" {
+		cfg.ObjectLockMode = viper.GetString("s3.objectLockMode")
+	}
+	if retainFor == "" {
+		retainFor = viper.GetString("s3.objectLockRetainFor")
+	}
+	if !cfg.ObjectLockLegalHold {
+		cfg.ObjectLockLegalHold = viper.GetBool("s3.objectLockLegalHold")
+	}
+	if !cfg.ExpireLifecycleRules {
+		cfg.ExpireLifecycleRules = viper.GetBool("s3.expireLifecycleRules")
+	}
+	if multipartThreshold == "" {
+		multipartThreshold = viper.GetString("s3.multipartThreshold")
+	}
+	if partSize == "" {
+		partSize = viper.GetString("s3.partSize")
+	}
+	if partConcurrency == "" {
+		partConcurrency = viper.GetString("s3.partConcurrency")
+	}
+	if cfg.Provider == "" {
+		cfg.Provider = viper.GetString("s3.provider")
+	}
+	if cfg.AccountID == "" {
+		cfg.AccountID = viper.GetString("s3.accountId")
+	}
+
+	cfg.CredentialHelper = backend.NewCredentialHelper(backend.BackendTypeS3)
+
+	if err := cfg.applyProvider(); err != nil {
+		return nil, err
+	}
+
+	cfg.ObjectLockMode = strings.ToUpper(cfg.ObjectLockMode)
+	if retainFor != "" {
+		age, err := common.ParseAge(retainFor)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3 object lock retention: %w", err)
+		}
+		cfg.ObjectLockRetainFor = age
+	}
+
+	if err := cfg.parseMultipart(multipartThreshold, partSize, partConcurrency); err != nil {
+		return nil, err
+	}
+
+	if lock := cfg.defaultObjectLock(); lock != nil {
+		if err := lock.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid S3 object lock config: %w", err)
+		}
+	}
 
 	// Validate required fields
 	if cfg.Bucket == "" {
@@ -75,3 +214,59 @@ func LoadConfig() (*Config, error) {
 
 	return cfg, nil
 }
+
+// parseMultipart sets the multipart upload settings from their configured
+// values, leaving unset ones at zero for the defaults.
+func (c *Config) parseMultipart(threshold, partSize, partConcurrency string) error {
+	var err error
+	if threshold != "" {
+		if c.MultipartThreshold, err = common.ParseSize(threshold); err != nil {
+			return fmt.Errorf("invalid S3 multipart threshold: %w", err)
+		}
+	}
+
+	if partSize != "" {
+		if c.PartSize, err = common.ParseSize(partSize); err != nil {
+			return fmt.Errorf("invalid S3 part size: %w", err)
+		}
+		if c.PartSize < minPartSize {
+			return fmt.Errorf("invalid S3 part size: '%s' is below the minimum of 5MB", partSize)
+		}
+	}
+
+	if partConcurrency != "" {
+		if c.PartConcurrency, err = strconv.Atoi(partConcurrency); err != nil || c.PartConcurrency < 1 {
+			return fmt.Errorf("invalid S3 part concurrency '%s': use a positive number", partConcurrency)
+		}
+	}
+
+	return nil
+}
+
+// defaultObjectLock returns the configured lock for pushed objects, or nil if none is.
+func (c *Config) defaultObjectLock() *backend.ObjectLock {
+	if c.ObjectLockMode == "" && c.ObjectLockRetainFor == 0 && !c.ObjectLockLegalHold {
+		return nil
+	}
+
+	lock := &backend.ObjectLock{Mode: c.ObjectLockMode, LegalHold: c.ObjectLockLegalHold}
+	if c.ObjectLockRetainFor > 0 {
+		lock.RetainUntil = time.Now().Add(c.ObjectLockRetainFor)
+	}
+
+	return lock
+}
+
+// HasReadReplica returns true if reads are served from a separate bucket or endpoint.
+func (c *Config) HasReadReplica() bool {
+	return c.ReadBucket != "" || c.ReadRegion != "" || c.ReadEndpoint != ""
+}
+
+// readBucket returns the bucket reads are served from.
+func (c *Config) readBucket() string {
+	if c.ReadBucket != "" {
+		return c.ReadBucket
+	}
+
+	return c.Bucket
+}
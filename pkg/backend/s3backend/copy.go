@@ -0,0 +1,138 @@
+package s3backend
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// Copy copies a file, or every file under a directory, within the bucket
+// with CopyObject, so the data never leaves S3. Objects keep their metadata,
+// including the checksum, and get opts.Metadata added; the checksum sidecars of multipart uploads are
+// copied after the objects.
+func (s *S3Backend) Copy(ctx context.Context, srcPath, dstPath string, opts backend.PushOptions) error {
+	log.Debug("S3Backend: Copying...\n")
+	log.Debugf("* Source: %s\n", srcPath)
+	log.Debugf("* Destination: %s\n", dstPath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	copied, err := s.copyPrefix(ctx, srcPath, dstPath, false, opts)
+	if err != nil {
+		return err
+	}
+	if copied == 0 {
+		return &backend.ErrNotFound{Path: srcPath}
+	}
+
+	// Sidecars go along with the files that were just copied
+	sidecarSrc, sidecarDst := backend.ChecksumSidecarPrefix(srcPath), backend.ChecksumSidecarPrefix(dstPath)
+	if _, err := s.copyPrefix(ctx, sidecarSrc, sidecarDst, true, backend.PushOptions{Force: true}); err != nil {
+		log.Warnf("Failed to copy checksums of '%s': %v\n", srcPath, err)
+	}
+
+	return nil
+}
+
+// copyPrefix copies the object at srcPath and the objects below it to
+// dstPath, returning how many were copied. Checksum sidecars are copied
+// instead of the files when sidecars is set.
+func (s *S3Backend) copyPrefix(ctx context.Context, srcPath, dstPath string, sidecars bool, opts backend.PushOptions) (int, error) {
+	srcKey := s.prefixedKey(srcPath)
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.cfg.Bucket),
+		Prefix: aws.String(srcKey),
+	})
+
+	copied := 0
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return copied, fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			key := aws.ToString(obj.Key)
+			if backend.IsChecksumSidecar(s.unprefixedKey(key)) != sidecars {
+				continue
+			}
+
+			// Skip siblings sharing the prefix, e.g. app.zip.bak when copying app.zip
+			rest := strings.TrimPrefix(key, srcKey)
+			if rest != "" && !strings.HasPrefix(rest, "/") && !(sidecars && rest == ".sha256") {
+				continue
+			}
+
+			if err := s.copyObject(ctx, key, dstPath+rest, opts); err != nil {
+				return copied, err
+			}
+			copied++
+		}
+	}
+
+	return copied, nil
+}
+
+func (s *S3Backend) copyObject(ctx context.Context, srcKey, dstPath string, opts backend.PushOptions) error {
+	if !opts.Force {
+		exists, err := s.Exists(ctx, dstPath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &backend.ErrAlreadyExists{Path: dstPath}
+		}
+	}
+
+	dstKey := s.prefixedKey(dstPath)
+	source := (&url.URL{Path: s.cfg.Bucket + "/" + srcKey}).EscapedPath()
+
+	lockMode, retainUntil, legalHold := lockFields(s.objectLock(opts))
+	input := &s3.CopyObjectInput{
+		Bucket:                    aws.String(s.cfg.Bucket),
+		Key:                       aws.String(dstKey),
+		CopySource:                aws.String(source),
+		ObjectLockMode:            lockMode,
+		ObjectLockRetainUntilDate: retainUntil,
+		ObjectLockLegalHoldStatus: legalHold,
+	}
+
+	// Metadata can only be replaced as a whole, so the source's is merged in
+	if len(opts.Metadata) > 0 {
+		head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(s.cfg.Bucket),
+			Key:    aws.String(srcKey),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read metadata of S3 object '%s': %w", srcKey, err)
+		}
+
+		metadata := head.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		for key, value := range opts.Metadata {
+			metadata[key] = value
+		}
+
+		input.Metadata = metadata
+		input.MetadataDirective = types.MetadataDirectiveReplace
+		input.ContentType = head.ContentType
+		input.CacheControl = head.CacheControl
+		input.ContentDisposition = head.ContentDisposition
+	}
+
+	_, err := s.client.CopyObject(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to copy S3 object '%s': %w", srcKey, err)
+	}
+
+	log.Debugf("Copied: s3://%s/%s -> s3://%s/%s\n", s.cfg.Bucket, srcKey, s.cfg.Bucket, dstKey)
+	return nil
+}
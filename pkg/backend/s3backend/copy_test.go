@@ -0,0 +1,76 @@
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_Copy(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "dist", "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "dist", "app.zip"), []byte("toolchain"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "dist", "bin", "app"), []byte("binary"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "dist.txt"), []byte("sibling"), 0644))
+
+	require.NoError(t, s3Backend.Push(ctx, filepath.Join(tmpDir, "dist"), "artifacts/jobs/1/dist", backend.PushOptions{}))
+	require.NoError(t, s3Backend.Push(ctx, filepath.Join(tmpDir, "dist.txt"), "artifacts/jobs/1/dist.txt", backend.PushOptions{}))
+
+	large := bytes.Repeat([]byte("x"), streamPartSize+1)
+	require.NoError(t, s3Backend.PushStream(ctx, bytes.NewReader(large), -1, "artifacts/jobs/1/dist/large.bin", backend.PushOptions{}))
+
+	// Copying a directory copies the files below it, but not its siblings
+	require.NoError(t, s3Backend.Copy(ctx, "artifacts/jobs/1/dist", "artifacts/projects/2/release", backend.PushOptions{}))
+
+	listed := []string{}
+	require.NoError(t, s3Backend.List(ctx, "artifacts/projects/2/", func(obj backend.ObjectInfo) error {
+		listed = append(listed, obj.Path)
+		return nil
+	}))
+	assert.Equal(t, []string{
+		"artifacts/projects/2/release/app.zip",
+		"artifacts/projects/2/release/bin/app",
+		"artifacts/projects/2/release/large.bin",
+	}, listed)
+
+	// Checksums are copied from the metadata and from sidecars
+	checksum, err := s3Backend.Checksum(ctx, "artifacts/projects/2/release/app.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "0db3de82a739e43a2b560d166d037c3c0061601bb194866eb79b2c87045d00f2", checksum)
+
+	checksum, err = s3Backend.Checksum(ctx, "artifacts/projects/2/release/large.bin")
+	require.NoError(t, err)
+	assert.Len(t, checksum, 64)
+
+	// Single files, existing destinations and missing sources
+	require.NoError(t, s3Backend.Copy(ctx, "artifacts/jobs/1/dist/app.zip", "artifacts/workflows/3/app.zip", backend.PushOptions{}))
+	exists, err := s3Backend.Exists(ctx, "artifacts/workflows/3/app.zip")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	err = s3Backend.Copy(ctx, "artifacts/jobs/1/dist/app.zip", "artifacts/workflows/3/app.zip", backend.PushOptions{})
+	assert.IsType(t, &backend.ErrAlreadyExists{}, err)
+	assert.NoError(t, s3Backend.Copy(ctx, "artifacts/jobs/1/dist/app.zip", "artifacts/workflows/3/app.zip", backend.PushOptions{Force: true}))
+
+	err = s3Backend.Copy(ctx, "artifacts/jobs/1/missing", "artifacts/workflows/3/missing", backend.PushOptions{})
+	assert.IsType(t, &backend.ErrNotFound{}, err)
+
+	// Metadata is added to the metadata of the source, checksum included
+	require.NoError(t, s3Backend.Copy(ctx, "artifacts/jobs/1/dist/app.zip", "artifacts/projects/2/app.zip", backend.PushOptions{Metadata: map[string]string{"git-sha": "abc"}}))
+	head, err := s3Backend.client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String("test-bucket"), Key: aws.String("artifacts/projects/2/app.zip")})
+	require.NoError(t, err)
+	assert.Equal(t, "abc", head.Metadata["git-sha"])
+	assert.Equal(t, "0db3de82a739e43a2b560d166d037c3c0061601bb194866eb79b2c87045d00f2", head.Metadata[backend.ChecksumMetadataKey])
+}
@@ -0,0 +1,110 @@
+package s3backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/compress"
+	"github.com/semaphoreci/artifact/pkg/encrypt"
+)
+
+// encoded reports whether an object is stored compressed or encrypted.
+// Offsets in such objects are no offsets in the files pushed, so they
+// are always read whole.
+func encoded(metadata map[string]string) bool {
+	return metadata[backend.EncodingMetadataKey] != "" || metadata[backend.EncryptionMetadataKey] != ""
+}
+
+// encode returns r compressed with opts.Compress, and then encrypted to
+// opts.Encrypt, as they are set. Closing it stops reading r.
+func encode(r io.Reader, opts backend.PushOptions) (io.ReadCloser, error) {
+	encoded := io.NopCloser(r)
+
+	if opts.Compress != "" {
+		compressed, err := compress.Compress(opts.Compress, encoded)
+		if err != nil {
+			return nil, err
+		}
+		encoded = compressed
+	}
+
+	if opts.Encrypt != nil {
+		encrypted, err := encrypt.Encrypt(opts.Encrypt, encoded)
+		if err != nil {
+			_ = encoded.Close()
+			return nil, err
+		}
+		encoded = &chainCloser{ReadCloser: encrypted, next: encoded}
+	}
+
+	return encoded, nil
+}
+
+// decode returns the body of the object at remotePath decrypted with the
+// identities of ctx, and then decompressed, as its metadata says.
+// Closing it closes body.
+func decode(ctx context.Context, remotePath string, metadata map[string]string, body io.ReadCloser) (io.ReadCloser, error) {
+	if encryption := metadata[backend.EncryptionMetadataKey]; encryption != "" {
+		if encryption != encrypt.Age {
+			_ = body.Close()
+			return nil, fmt.Errorf("'%s' is encrypted with unsupported %s", remotePath, encryption)
+		}
+
+		decrypted, err := encrypt.Decrypt(encrypt.IdentitiesFromContext(ctx), body)
+		if err != nil {
+			_ = body.Close()
+			return nil, fmt.Errorf("'%s' is encrypted: %w", remotePath, err)
+		}
+		body = decrypted
+	}
+
+	if encoding := metadata[backend.EncodingMetadataKey]; encoding != "" {
+		decompressed, err := compress.Decompress(encoding, body)
+		if err != nil {
+			_ = body.Close()
+			return nil, err
+		}
+		body = decompressed
+	}
+
+	return &sizedReadCloser{ReadCloser: body, size: originalSize(metadata)}, nil
+}
+
+// originalSize returns the size an encoded file had before it was encoded,
+// or -1 if it was not recorded, e.g. for a stream of unknown size.
+func originalSize(metadata map[string]string) int64 {
+	size, err := strconv.ParseInt(metadata[backend.SizeMetadataKey], 10, 64)
+	if err != nil || size < 0 {
+		return -1
+	}
+
+	return size
+}
+
+// sizedReadCloser is a decoded file, implementing backend.SizedReader.
+type sizedReadCloser struct {
+	io.ReadCloser
+	size int64
+}
+
+func (r *sizedReadCloser) Size() int64 {
+	return r.size
+}
+
+// chainCloser closes the reader it reads from, and then the next one.
+type chainCloser struct {
+	io.ReadCloser
+	next io.Closer
+}
+
+func (c *chainCloser) Close() error {
+	err := c.ReadCloser.Close()
+	if closeErr := c.next.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
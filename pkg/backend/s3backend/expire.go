@@ -0,0 +1,104 @@
+package s3backend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// ExpireTagKey is the object tag holding the number of days after which an
+// object pushed with --expire-in expires. Bucket lifecycle rules filter on
+// it, since they expire objects by age rather than by date.
+const ExpireTagKey = "artifact-expire-days"
+
+// expireDays rounds a retention up to the whole days lifecycle rules use.
+func expireDays(expireIn time.Duration) int {
+	day := 24 * time.Hour
+	return int((expireIn + day - 1) / day)
+}
+
+// expireTagging returns the Tagging field of uploads expiring with opts,
+// or nil for uploads kept forever.
+func expireTagging(opts backend.PushOptions) *string {
+	if opts.ExpireIn <= 0 {
+		return nil
+	}
+
+	tags := url.Values{ExpireTagKey: {strconv.Itoa(expireDays(opts.ExpireIn))}}
+	return aws.String(tags.Encode())
+}
+
+// expireRuleID names the lifecycle rule expiring objects tagged with days.
+func expireRuleID(days int) string {
+	return fmt.Sprintf("artifact-expire-%dd", days)
+}
+
+// ensureExpireRule adds a bucket lifecycle rule expiring the objects pushed
+// with opts, if configured with ExpireLifecycleRules and none exists yet.
+// Failing to add it only warns: the objects are tagged, so a rule added
+// later by an administrator still expires them.
+func (s *S3Backend) ensureExpireRule(ctx context.Context, opts backend.PushOptions) {
+	if opts.ExpireIn <= 0 || !s.cfg.ExpireLifecycleRules {
+		return
+	}
+
+	days := expireDays(opts.ExpireIn)
+	if err := s.addExpireRule(ctx, days); err != nil {
+		log.Warnf("Failed to add a lifecycle rule expiring files after %d days to bucket '%s': %v\n", days, s.cfg.Bucket, err)
+	}
+}
+
+func (s *S3Backend) addExpireRule(ctx context.Context, days int) error {
+	rules := []types.LifecycleRule{}
+	out, err := s.client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(s.cfg.Bucket),
+	})
+
+	var apiErr smithy.APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.ErrorCode() == "NoSuchLifecycleConfiguration":
+	case err != nil:
+		return err
+	default:
+		rules = out.Rules
+	}
+
+	id := expireRuleID(days)
+	for _, rule := range rules {
+		if aws.ToString(rule.ID) == id {
+			return nil
+		}
+	}
+
+	// Lifecycle configurations are replaced as a whole, so the existing
+	// rules are written back along with the new one
+	rules = append(rules, types.LifecycleRule{
+		ID:     aws.String(id),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilter{
+			Tag: &types.Tag{Key: aws.String(ExpireTagKey), Value: aws.String(strconv.Itoa(days))},
+		},
+		Expiration: &types.LifecycleExpiration{Days: aws.Int32(int32(days))},
+	})
+
+	_, err = s.client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(s.cfg.Bucket),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: rules},
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Added lifecycle rule '%s' to bucket '%s'.\n", id, s.cfg.Bucket)
+	return nil
+}
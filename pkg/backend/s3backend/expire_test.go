@@ -0,0 +1,68 @@
+package s3backend
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_Push_ExpireIn(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	// The fake server ignores tags and lifecycle rules, so check the requests instead
+	var mu sync.Mutex
+	tags := map[string]string{}
+	lifecycles := []string{}
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if _, ok := r.URL.Query()["lifecycle"]; ok {
+			if r.Method == http.MethodGet {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = io.WriteString(w, `<Error><Code>NoSuchLifecycleConfiguration</Code><Message>none</Message></Error>`)
+				return
+			}
+
+			body, _ := io.ReadAll(r.Body)
+			lifecycles = append(lifecycles, string(body))
+			return
+		}
+
+		if r.Method == http.MethodPut {
+			tags[r.URL.Path] = r.Header.Get("X-Amz-Tagging")
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	srcFile := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, os.WriteFile(srcFile, []byte("report"), 0644))
+
+	ctx := context.Background()
+	opts := backend.PushOptions{ExpireIn: 36 * time.Hour}
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/report.html", opts))
+	assert.Equal(t, "artifact-expire-days=2", tags["/test-bucket/artifacts/jobs/1/report.html"])
+	assert.Empty(t, lifecycles)
+
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/kept.html", backend.PushOptions{}))
+	assert.Equal(t, "", tags["/test-bucket/artifacts/jobs/1/kept.html"])
+
+	// With lifecycle rules enabled, a rule expiring the tagged objects is added
+	s3Backend.cfg.ExpireLifecycleRules = true
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/report.html", backend.PushOptions{Force: true, ExpireIn: 14 * 24 * time.Hour}))
+	require.Len(t, lifecycles, 1)
+	assert.Contains(t, lifecycles[0], "<ID>artifact-expire-14d</ID>")
+	assert.Contains(t, lifecycles[0], "<Key>artifact-expire-days</Key><Value>14</Value>")
+	assert.Contains(t, lifecycles[0], "<Days>14</Days>")
+}
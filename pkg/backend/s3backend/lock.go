@@ -0,0 +1,117 @@
+package s3backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	log "github.com/sirupsen/logrus"
+)
+
+// objectLock returns the lock to apply to pushed objects:
+// the one requested for the push, or the configured default.
+func (s *S3Backend) objectLock(opts backend.PushOptions) *backend.ObjectLock {
+	if opts.Lock != nil {
+		return opts.Lock
+	}
+
+	return s.cfg.defaultObjectLock()
+}
+
+// lockFields converts a lock into the Object Lock fields of PutObject and
+// CreateMultipartUpload. A nil lock yields empty fields.
+func lockFields(lock *backend.ObjectLock) (types.ObjectLockMode, *time.Time, types.ObjectLockLegalHoldStatus) {
+	if lock == nil {
+		return "", nil, ""
+	}
+
+	var mode types.ObjectLockMode
+	var retainUntil *time.Time
+	if lock.Mode != "" {
+		mode = types.ObjectLockMode(lock.Mode)
+		retainUntil = aws.Time(lock.RetainUntil)
+	}
+
+	var legalHold types.ObjectLockLegalHoldStatus
+	if lock.LegalHold {
+		legalHold = types.ObjectLockLegalHoldStatusOn
+	}
+
+	return mode, retainUntil, legalHold
+}
+
+// checkNotLocked returns backend.ErrPermissionDenied if any object under
+// remotePath is protected by Object Lock. Deleting an object without a
+// version ID succeeds even when it is locked, leaving the object hidden
+// behind a delete marker, so yank checks first to fail loudly instead.
+func (s *S3Backend) checkNotLocked(ctx context.Context, remotePath string) error {
+	if !s.objectLockEnabled(ctx) {
+		return nil
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.cfg.Bucket),
+		Prefix: aws.String(s.prefixedKey(remotePath)),
+	})
+
+	now := time.Now()
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list S3 objects: %w", err)
+		}
+
+		for _, obj := range page.Contents {
+			head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+				Bucket: aws.String(s.cfg.Bucket),
+				Key:    obj.Key,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to check S3 object '%s': %w", aws.ToString(obj.Key), err)
+			}
+
+			if reason := lockedReason(head, now); reason != "" {
+				return &backend.ErrPermissionDenied{
+					Operation: "yank",
+					Path:      s.unprefixedKey(aws.ToString(obj.Key)),
+					Reason:    reason,
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// objectLockEnabled reports whether the bucket has Object Lock enabled.
+// Buckets without it, and services that do not support it, report false.
+func (s *S3Backend) objectLockEnabled(ctx context.Context) bool {
+	out, err := s.client.GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(s.cfg.Bucket),
+	})
+	if err != nil {
+		log.Debugf("Could not get object lock configuration of '%s': %v\n", s.cfg.Bucket, err)
+		return false
+	}
+
+	return out.ObjectLockConfiguration != nil &&
+		out.ObjectLockConfiguration.ObjectLockEnabled == types.ObjectLockEnabledEnabled
+}
+
+// lockedReason returns why an object cannot be deleted, or "" if it can.
+func lockedReason(head *s3.HeadObjectOutput, now time.Time) string {
+	if head.ObjectLockLegalHoldStatus == types.ObjectLockLegalHoldStatusOn {
+		return "it is under an S3 Object Lock legal hold"
+	}
+
+	if head.ObjectLockMode != "" && head.ObjectLockRetainUntilDate != nil && now.Before(*head.ObjectLockRetainUntilDate) {
+		return fmt.Sprintf("it is protected by S3 Object Lock in %s mode until %s",
+			head.ObjectLockMode, head.ObjectLockRetainUntilDate.Format(time.RFC3339))
+	}
+
+	return ""
+}
@@ -0,0 +1,81 @@
+package s3backend
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_Push_ObjectLock(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	// The fake server ignores Object Lock, so check the request headers instead
+	var mu sync.Mutex
+	headers := map[string]http.Header{}
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			mu.Lock()
+			headers[r.URL.Path] = r.Header.Clone()
+			mu.Unlock()
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "release.zip")
+	require.NoError(t, os.WriteFile(srcFile, []byte("release"), 0644))
+
+	retainUntil := time.Date(2040, 1, 1, 0, 0, 0, 0, time.UTC)
+	lock := &backend.ObjectLock{Mode: backend.ObjectLockCompliance, RetainUntil: retainUntil, LegalHold: true}
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/projects/1/release.zip", backend.PushOptions{Lock: lock}))
+
+	sent := headers["/test-bucket/artifacts/projects/1/release.zip"]
+	require.NotNil(t, sent)
+	assert.Equal(t, "COMPLIANCE", sent.Get("X-Amz-Object-Lock-Mode"))
+	assert.Equal(t, "2040-01-01T00:00:00Z", sent.Get("X-Amz-Object-Lock-Retain-Until-Date"))
+	assert.Equal(t, "ON", sent.Get("X-Amz-Object-Lock-Legal-Hold"))
+
+	// Without a lock, the configured default applies
+	s3Backend.cfg.ObjectLockLegalHold = true
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/projects/1/default.zip", backend.PushOptions{}))
+
+	sent = headers["/test-bucket/artifacts/projects/1/default.zip"]
+	require.NotNil(t, sent)
+	assert.Equal(t, "", sent.Get("X-Amz-Object-Lock-Mode"))
+	assert.Equal(t, "ON", sent.Get("X-Amz-Object-Lock-Legal-Hold"))
+}
+
+func TestLockedReason(t *testing.T) {
+	now := time.Now()
+
+	assert.Equal(t, "", lockedReason(&s3.HeadObjectOutput{}, now))
+
+	assert.Contains(t, lockedReason(&s3.HeadObjectOutput{
+		ObjectLockLegalHoldStatus: types.ObjectLockLegalHoldStatusOn,
+	}, now), "legal hold")
+
+	assert.Contains(t, lockedReason(&s3.HeadObjectOutput{
+		ObjectLockMode:            types.ObjectLockModeGovernance,
+		ObjectLockRetainUntilDate: aws.Time(now.Add(time.Hour)),
+	}, now), "GOVERNANCE mode until")
+
+	assert.Equal(t, "", lockedReason(&s3.HeadObjectOutput{
+		ObjectLockMode:            types.ObjectLockModeCompliance,
+		ObjectLockRetainUntilDate: aws.Time(now.Add(-time.Hour)),
+	}, now))
+}
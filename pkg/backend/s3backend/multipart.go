@@ -0,0 +1,224 @@
+package s3backend
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/progress"
+	"github.com/semaphoreci/artifact/pkg/resume"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultMultipartThreshold is the size from which files are uploaded
+	// in parts rather than with a single PutObject.
+	DefaultMultipartThreshold = 64 * 1024 * 1024
+
+	// DefaultPartSize is the size of the parts of multipart file uploads.
+	DefaultPartSize = 16 * 1024 * 1024
+
+	// DefaultPartConcurrency is how many parts of a file are uploaded at once.
+	DefaultPartConcurrency = 4
+
+	// minPartSize and maxParts are S3's limits for multipart uploads.
+	minPartSize = 5 * 1024 * 1024
+	maxParts    = 10000
+)
+
+// multipartThreshold returns the configured threshold, or the default.
+func (c *Config) multipartThreshold() int64 {
+	if c.MultipartThreshold > 0 {
+		return c.MultipartThreshold
+	}
+
+	return DefaultMultipartThreshold
+}
+
+// partSize returns the part size for a file of size bytes: the configured
+// one, raised to S3's minimum and to what fits in S3's maximum of parts.
+func (c *Config) partSize(size int64) int64 {
+	partSize := c.PartSize
+	if partSize <= 0 {
+		partSize = DefaultPartSize
+	}
+
+	return max(partSize, minPartSize, (size+maxParts-1)/maxParts)
+}
+
+// partConcurrency returns the configured part concurrency, or the default.
+func (c *Config) partConcurrency() int {
+	if c.PartConcurrency > 0 {
+		return c.PartConcurrency
+	}
+
+	return DefaultPartConcurrency
+}
+
+// pushFileMultipart uploads a large file in parts, several at once, each
+// read from its own section of the file. Failed parts are retried by the
+// S3 client; if one still fails, the upload is aborted, unless the push is
+// resumable: then the uploaded parts are kept, and the next run of the
+// push only uploads the missing ones. Cancelled uploads are always aborted.
+func (s *S3Backend) pushFileMultipart(ctx context.Context, file *os.File, info os.FileInfo, key string, opts backend.PushOptions, checksum string) error {
+	size := info.Size()
+	state := opts.Resume
+
+	upload := s.resumableUpload(ctx, key, info, state)
+	if upload == nil {
+		lockMode, retainUntil, legalHold := lockFields(s.objectLock(opts))
+		contentType, cacheControl, contentDisposition := headerFields(opts.Headers)
+		created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+			Bucket:                    aws.String(s.cfg.Bucket),
+			Key:                       aws.String(key),
+			Metadata:                  objectMetadata(opts, checksum),
+			ContentType:               contentType,
+			CacheControl:              cacheControl,
+			ContentDisposition:        contentDisposition,
+			ObjectLockMode:            lockMode,
+			ObjectLockRetainUntilDate: retainUntil,
+			ObjectLockLegalHoldStatus: legalHold,
+			Tagging:                   expireTagging(opts),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to start multipart upload: %w", err)
+		}
+
+		upload = &resume.Upload{ID: aws.ToString(created.UploadId), PartSize: s.cfg.partSize(size), Parts: map[int32]string{}}
+		if state != nil {
+			if err := state.StartUpload(key, upload.ID, info, upload.PartSize); err != nil {
+				log.Warnf("Failed to record the upload of '%s': %v\n", key, err)
+			}
+		}
+	}
+
+	uploadID := aws.String(upload.ID)
+	partSize := upload.PartSize
+	parts := int((size + partSize - 1) / partSize)
+	completed := make([]types.CompletedPart, parts)
+
+	// Parts uploaded by an interrupted run are kept
+	missing := []int{}
+	uploaded := int64(0)
+	for i := range completed {
+		partNumber := int32(i + 1)
+		if etag, ok := upload.Parts[partNumber]; ok {
+			completed[i] = types.CompletedPart{ETag: aws.String(etag), PartNumber: aws.Int32(partNumber)}
+			uploaded += min(partSize, size-int64(i)*partSize)
+			continue
+		}
+		missing = append(missing, i)
+	}
+
+	if len(missing) < parts {
+		log.Infof("Resuming upload of '%s': %d of %d parts were already uploaded.\n", key, parts-len(missing), parts)
+	}
+
+	tracked := progress.FromContext(ctx).File(file.Name(), size)
+	defer tracked.Done()
+	tracked.Skip(uploaded)
+
+	var mu sync.Mutex
+	err := backend.Parallel(len(missing), s.cfg.partConcurrency(), func(j int) error {
+		i := missing[j]
+		offset := int64(i) * partSize
+		length := min(partSize, size-offset)
+		partNumber := aws.Int32(int32(i + 1))
+
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:        aws.String(s.cfg.Bucket),
+			Key:           aws.String(key),
+			UploadId:      uploadID,
+			PartNumber:    partNumber,
+			Body:          tracked.Reader(io.NewSectionReader(file, offset, length)),
+			ContentLength: aws.Int64(length),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload part %d: %w", i+1, err)
+		}
+
+		completed[i] = types.CompletedPart{ETag: out.ETag, PartNumber: partNumber}
+		if state != nil {
+			if err := state.MarkPart(key, *partNumber, aws.ToString(out.ETag)); err != nil {
+				log.Warnf("Failed to record part %d of '%s': %v\n", i+1, key, err)
+			}
+		}
+
+		mu.Lock()
+		uploaded += length
+		log.Debugf("Uploaded part %d of %d of s3://%s/%s (%d%%)\n", i+1, parts, s.cfg.Bucket, key, uploaded*100/size)
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		// A cancelled push, e.g. on Ctrl-C or --timeout, leaves no parts behind
+		if state != nil && ctx.Err() == nil {
+			log.Warnf("The upload of '%s' was interrupted; run the push again to resume it.\n", key)
+			return err
+		}
+
+		s.abortMultipart(key, uploadID)
+		if state != nil {
+			if err := state.DropUpload(key); err != nil {
+				log.Warnf("Failed to record the upload of '%s': %v\n", key, err)
+			}
+		}
+		return err
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.cfg.Bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+
+	if state != nil {
+		if err := state.DropUpload(key); err != nil {
+			log.Warnf("Failed to record the upload of '%s': %v\n", key, err)
+		}
+	}
+
+	if err != nil {
+		s.abortMultipart(key, uploadID)
+		return fmt.Errorf("failed to complete multipart upload: %w", err)
+	}
+
+	return nil
+}
+
+// resumableUpload returns the multipart upload an interrupted run of the
+// push started for key, if the local file did not change since and S3
+// still has the upload, e.g. it was not aborted by a lifecycle rule.
+func (s *S3Backend) resumableUpload(ctx context.Context, key string, info os.FileInfo, state *resume.State) *resume.Upload {
+	if state == nil {
+		return nil
+	}
+
+	upload := state.Upload(key, info)
+	if upload == nil {
+		return nil
+	}
+
+	_, err := s.client.ListParts(ctx, &s3.ListPartsInput{
+		Bucket:   aws.String(s.cfg.Bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(upload.ID),
+		MaxParts: aws.Int32(1),
+	})
+	if err != nil {
+		log.Debugf("Cannot resume the upload of '%s', starting over: %v\n", key, err)
+		if err := state.DropUpload(key); err != nil {
+			log.Warnf("Failed to record the upload of '%s': %v\n", key, err)
+		}
+		return nil
+	}
+
+	return upload
+}
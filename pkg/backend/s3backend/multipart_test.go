@@ -0,0 +1,215 @@
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/resume"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_Push_Multipart(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	s3Backend.cfg.MultipartThreshold = 8 * 1024 * 1024
+	s3Backend.cfg.PartSize = minPartSize
+	s3Backend.cfg.PartConcurrency = 2
+
+	var mu sync.Mutex
+	parts := 0
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") != "" {
+			mu.Lock()
+			parts++
+			mu.Unlock()
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	// 12MB in parts of 5MB
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12*1024*1024/16)
+	srcFile := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(srcFile, data, 0644))
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/video.mp4", backend.PushOptions{}))
+	assert.Equal(t, 3, parts)
+
+	pulled := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/video.mp4", pulled, backend.PullOptions{}))
+	content, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+
+	// The checksum is known upfront, so it is stored in the metadata
+	checksum, err := s3Backend.Checksum(ctx, "artifacts/jobs/1/video.mp4")
+	require.NoError(t, err)
+	assert.Len(t, checksum, 64)
+
+	// Small files are uploaded at once
+	smallFile := filepath.Join(t.TempDir(), "small.txt")
+	require.NoError(t, os.WriteFile(smallFile, []byte("small"), 0644))
+	require.NoError(t, s3Backend.Push(ctx, smallFile, "artifacts/jobs/1/small.txt", backend.PushOptions{}))
+	assert.Equal(t, 3, parts)
+}
+
+func TestConfig_PartSize(t *testing.T) {
+	cfg := &Config{}
+	assert.Equal(t, int64(DefaultPartSize), cfg.partSize(100))
+	assert.Equal(t, int64(DefaultMultipartThreshold), cfg.multipartThreshold())
+	assert.Equal(t, DefaultPartConcurrency, cfg.partConcurrency())
+
+	// Files too large for 10,000 parts get larger ones
+	assert.Equal(t, int64(21474837), cfg.partSize(200*1024*1024*1024))
+}
+
+func TestLoadConfig_Multipart(t *testing.T) {
+	t.Setenv("ARTIFACT_S3_BUCKET", "artifacts")
+	t.Setenv("ARTIFACT_S3_MULTIPART_THRESHOLD", "1GB")
+	t.Setenv("ARTIFACT_S3_PART_SIZE", "64MB")
+	t.Setenv("ARTIFACT_S3_PART_CONCURRENCY", "16")
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, int64(1<<30), cfg.MultipartThreshold)
+	assert.Equal(t, int64(64<<20), cfg.PartSize)
+	assert.Equal(t, 16, cfg.PartConcurrency)
+
+	t.Setenv("ARTIFACT_S3_PART_SIZE", "1MB")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "below the minimum of 5MB")
+
+	t.Setenv("ARTIFACT_S3_PART_SIZE", "")
+	t.Setenv("ARTIFACT_S3_PART_CONCURRENCY", "0")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "invalid S3 part concurrency")
+}
+
+func TestS3Backend_Push_ResumeMultipart(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	s3Backend.cfg.MultipartThreshold = 8 * 1024 * 1024
+	s3Backend.cfg.PartSize = minPartSize
+	s3Backend.cfg.PartConcurrency = 1
+
+	// The third part fails once, as if the connection dropped
+	var mu sync.Mutex
+	uploadedParts := []string{}
+	failPart := "3"
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		part := r.URL.Query().Get("partNumber")
+		if r.Method == http.MethodPut && part != "" {
+			mu.Lock()
+			defer mu.Unlock()
+			if part == failPart {
+				failPart = ""
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			uploadedParts = append(uploadedParts, part)
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12*1024*1024/16)
+	srcFile := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(srcFile, data, 0644))
+
+	state, err := resume.Open(filepath.Join(t.TempDir(), "push.jsonl"))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	opts := backend.PushOptions{Resume: state}
+	require.Error(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/video.mp4", opts))
+	assert.Equal(t, []string{"1", "2"}, uploadedParts)
+
+	// Only the missing part is uploaded again
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/video.mp4", opts))
+	assert.Equal(t, []string{"1", "2", "3"}, uploadedParts)
+
+	pulled := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/video.mp4", pulled, backend.PullOptions{}))
+	content, err := os.ReadFile(pulled)
+	require.NoError(t, err)
+	assert.Equal(t, data, content)
+}
+
+func TestS3Backend_Push_ResumeDirectory(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	for _, name := range []string{"a.txt", "b.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte(name), 0644))
+	}
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, filepath.Join(tmpDir, "a.txt"), "artifacts/jobs/1/data/a.txt", backend.PushOptions{}))
+
+	// a.txt was pushed by an interrupted run, so it is not pushed again,
+	// which would fail as it exists
+	state, err := resume.Open(filepath.Join(t.TempDir(), "push.jsonl"))
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(tmpDir, "a.txt"))
+	require.NoError(t, err)
+	require.NoError(t, state.MarkPushed(filepath.Join(tmpDir, "a.txt"), info))
+
+	require.NoError(t, s3Backend.Push(ctx, tmpDir, "artifacts/jobs/1/data", backend.PushOptions{Resume: state}))
+	assert.True(t, state.Pushed(filepath.Join(tmpDir, "a.txt"), info))
+
+	exists, err := s3Backend.Exists(ctx, "artifacts/jobs/1/data/b.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestS3Backend_Push_CancelledMultipartIsAborted(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	s3Backend.cfg.MultipartThreshold = 8 * 1024 * 1024
+	s3Backend.cfg.PartSize = minPartSize
+	s3Backend.cfg.PartConcurrency = 1
+
+	// The push is cancelled, e.g. with Ctrl-C, while the second part is uploaded
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	aborted := false
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut && r.URL.Query().Get("partNumber") == "2" {
+			cancel()
+		}
+		if r.Method == http.MethodDelete && r.URL.Query().Get("uploadId") != "" {
+			aborted = true
+		}
+		faker.ServeHTTP(w, r)
+	})
+
+	data := bytes.Repeat([]byte("0123456789abcdef"), 12*1024*1024/16)
+	srcFile := filepath.Join(t.TempDir(), "video.mp4")
+	require.NoError(t, os.WriteFile(srcFile, data, 0644))
+	info, err := os.Stat(srcFile)
+	require.NoError(t, err)
+
+	state, err := resume.Open(filepath.Join(t.TempDir(), "push.jsonl"))
+	require.NoError(t, err)
+
+	err = s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/video.mp4", backend.PushOptions{Resume: state})
+	require.ErrorIs(t, err, context.Canceled)
+
+	// No parts are left behind, even though the push is resumable
+	assert.True(t, aborted)
+	assert.Nil(t, state.Upload("artifacts/jobs/1/video.mp4", info))
+}
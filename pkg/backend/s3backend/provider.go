@@ -0,0 +1,93 @@
+package s3backend
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Provider describes an S3-compatible storage service, so it can be selected
+// by name instead of configuring its endpoint and quirks by hand.
+type Provider struct {
+	// Endpoint is the endpoint URL, with {region} and {accountId} placeholders.
+	// Providers without one are self-hosted and need an explicit endpoint.
+	Endpoint string
+
+	// Region is the region used unless one is configured
+	Region string
+
+	// ForcePathStyle is needed by services without virtual-hosted-style URLs
+	ForcePathStyle bool
+
+	// ChecksumsWhenRequired only sends and validates checksums where S3 requires them,
+	// for services rejecting the SDK's default checksum headers
+	ChecksumsWhenRequired bool
+}
+
+// Providers are the presets available with ARTIFACT_S3_PROVIDER.
+var Providers = map[string]Provider{
+	"r2": {
+		Endpoint:              "https://{accountId}.r2.cloudflarestorage.com",
+		Region:                "auto",
+		ChecksumsWhenRequired: true,
+	},
+	"spaces": {
+		Endpoint:              "https://{region}.digitaloceanspaces.com",
+		Region:                "nyc3",
+		ChecksumsWhenRequired: true,
+	},
+	"wasabi": {
+		Endpoint:              "https://s3.{region}.wasabisys.com",
+		Region:                "us-east-1",
+		ChecksumsWhenRequired: true,
+	},
+	"minio": {
+		Region:         "us-east-1",
+		ForcePathStyle: true,
+	},
+}
+
+// applyProvider fills in the settings of the configured provider.
+// Explicitly configured settings take precedence.
+func (c *Config) applyProvider() error {
+	c.Provider = strings.ToLower(c.Provider)
+	if c.Provider == "" || c.Provider == "aws" {
+		return nil
+	}
+
+	provider, ok := Providers[c.Provider]
+	if !ok {
+		return fmt.Errorf("unknown S3 provider '%s': use one of %s", c.Provider, strings.Join(providerNames(), ", "))
+	}
+
+	if c.Region == "" {
+		c.Region = provider.Region
+	}
+
+	if c.Endpoint == "" {
+		if provider.Endpoint == "" {
+			return fmt.Errorf("S3 provider '%s' needs an endpoint: set ARTIFACT_S3_ENDPOINT or s3.endpoint in config", c.Provider)
+		}
+
+		if strings.Contains(provider.Endpoint, "{accountId}") && c.AccountID == "" {
+			return fmt.Errorf("S3 provider '%s' needs an account ID: set ARTIFACT_S3_ACCOUNT_ID or s3.accountId in config", c.Provider)
+		}
+
+		c.Endpoint = strings.NewReplacer("{region}", c.Region, "{accountId}", c.AccountID).Replace(provider.Endpoint)
+	}
+
+	c.ForcePathStyle = c.ForcePathStyle || provider.ForcePathStyle
+	c.ChecksumsWhenRequired = provider.ChecksumsWhenRequired
+
+	return nil
+}
+
+func providerNames() []string {
+	names := []string{"aws"}
+	for name := range Providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
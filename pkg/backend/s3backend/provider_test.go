@@ -0,0 +1,51 @@
+package s3backend
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadConfig_Provider(t *testing.T) {
+	t.Setenv("ARTIFACT_S3_BUCKET", "artifacts")
+
+	t.Setenv("ARTIFACT_S3_PROVIDER", "r2")
+	_, err := LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ARTIFACT_S3_ACCOUNT_ID")
+
+	t.Setenv("ARTIFACT_S3_ACCOUNT_ID", "abc123")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://abc123.r2.cloudflarestorage.com", cfg.Endpoint)
+	assert.Equal(t, "auto", cfg.Region)
+	assert.True(t, cfg.ChecksumsWhenRequired)
+
+	t.Setenv("ARTIFACT_S3_PROVIDER", "Spaces")
+	t.Setenv("ARTIFACT_S3_REGION", "fra1")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://fra1.digitaloceanspaces.com", cfg.Endpoint)
+	assert.False(t, cfg.ForcePathStyle)
+
+	// Self-hosted providers have no default endpoint
+	t.Setenv("ARTIFACT_S3_PROVIDER", "minio")
+	t.Setenv("ARTIFACT_S3_REGION", "")
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "ARTIFACT_S3_ENDPOINT")
+
+	t.Setenv("ARTIFACT_S3_ENDPOINT", "http://minio:9000")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "http://minio:9000", cfg.Endpoint)
+	assert.Equal(t, "us-east-1", cfg.Region)
+	assert.True(t, cfg.ForcePathStyle)
+	assert.False(t, cfg.ChecksumsWhenRequired)
+
+	t.Setenv("ARTIFACT_S3_PROVIDER", "backblaze")
+	_, err = LoadConfig()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "aws, minio, r2, spaces, wasabi")
+}
@@ -0,0 +1,26 @@
+package s3backend
+
+import (
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// target is a bucket along with the client used to reach it.
+type target struct {
+	client *s3.Client
+	bucket string
+}
+
+// primary returns the bucket all writes go to.
+func (s *S3Backend) primary() target {
+	return target{client: s.client, bucket: s.cfg.Bucket}
+}
+
+// reader returns the bucket reads are served from: the read replica
+// if one is configured, the primary bucket otherwise.
+func (s *S3Backend) reader() target {
+	if s.readClient == nil {
+		return s.primary()
+	}
+
+	return target{client: s.readClient, bucket: s.cfg.readBucket()}
+}
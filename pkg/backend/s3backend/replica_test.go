@@ -0,0 +1,78 @@
+package s3backend
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_ReadReplica(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	replica, _, cleanupReplica := createTestS3Backend(t)
+	defer cleanupReplica()
+
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	write := func(name, contents string) string {
+		p := filepath.Join(tmpDir, name)
+		require.NoError(t, os.WriteFile(p, []byte(contents), 0644))
+		return p
+	}
+
+	// Both buckets have the file, with different contents to tell them apart
+	require.NoError(t, s3Backend.Push(ctx, write("primary.txt", "primary"), "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+	require.NoError(t, replica.Push(ctx, write("replica.txt", "replica"), "artifacts/jobs/1/a.txt", backend.PushOptions{}))
+
+	s3Backend.readClient = replica.client
+
+	// Pushes go to the primary bucket only
+	require.NoError(t, s3Backend.Push(ctx, write("new.txt", "new"), "artifacts/jobs/1/new.txt", backend.PushOptions{}))
+	exists, err := replica.Exists(ctx, "artifacts/jobs/1/new.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Pulls are served by the replica
+	dst := filepath.Join(tmpDir, "pulled-a.txt")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/a.txt", dst, backend.PullOptions{}))
+	content, _ := os.ReadFile(dst)
+	assert.Equal(t, "replica", string(content))
+
+	// Files not replicated yet come from the primary
+	dst = filepath.Join(tmpDir, "pulled-new.txt")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/new.txt", dst, backend.PullOptions{}))
+	content, _ = os.ReadFile(dst)
+	assert.Equal(t, "new", string(content))
+
+	// So are HEAD requests, falling back to the primary bucket the same way
+	checksum, err := s3Backend.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	replicaChecksum, err := replica.Checksum(ctx, "artifacts/jobs/1/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, replicaChecksum, checksum)
+
+	exists, err = s3Backend.Exists(ctx, "artifacts/jobs/1/new.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	info, err := s3Backend.Stat(ctx, "artifacts/jobs/1/new.txt")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), info.Size)
+
+	_, err = s3Backend.Stat(ctx, "artifacts/jobs/1/missing.txt")
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+
+	r, err := s3Backend.Open(ctx, "artifacts/jobs/1/new.txt")
+	require.NoError(t, err)
+	content, _ = io.ReadAll(r)
+	r.Close()
+	assert.Equal(t, "new", string(content))
+}
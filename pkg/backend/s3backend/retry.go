@@ -0,0 +1,70 @@
+package s3backend
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/ratelimit"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/semaphoreci/artifact/pkg/retry"
+)
+
+// throttleCodes are the error codes S3 and S3-compatible stores ask to
+// slow down with, e.g. SlowDown.
+var throttleCodes = awsretry.ThrottleErrorCode{Codes: awsretry.DefaultThrottleErrorCodes}
+
+// retryer returns the retryer of the S3 clients: the SDK's standard one,
+// which retries server errors, throttling and network errors, with the
+// attempts and delays of the retry policy. Throttling with a plain 429
+// status is retried too, for S3-compatible stores, and the SDK's client-side
+// retry quota is disabled, so concurrent transfers failing together are all
+// retried.
+func retryer(p retry.Policy) func() aws.Retryer {
+	return func() aws.Retryer {
+		return awsretry.NewStandard(func(o *awsretry.StandardOptions) {
+			o.MaxAttempts = p.Attempts
+			o.MaxBackoff = p.MaxDelay
+			o.Backoff = backoff(p)
+			o.RateLimiter = ratelimit.None
+			o.Retryables = append(o.Retryables, awsretry.RetryableHTTPStatusCode{
+				Codes: map[int]struct{}{http.StatusTooManyRequests: {}},
+			})
+		})
+	}
+}
+
+// backoff waits between the attempts of S3 requests as the policy does,
+// counting them as retries.
+// Throttling, e.g. SlowDown errors, is recorded for the limiters of
+// running transfers, and the wait it asks for with a Retry-After header
+// is honored, up to the maximum delay.
+type backoff retry.Policy
+
+// BackoffDelay implements the SDK's BackoffDelayer; attempts count from one.
+func (b backoff) BackoffDelay(attempt int, err error) (time.Duration, error) {
+	p := retry.Policy(b)
+	retry.Retried()
+
+	var responseErr *smithyhttp.ResponseError
+	hasResponse := errors.As(err, &responseErr) && responseErr.Response != nil
+
+	throttled := throttleCodes.IsErrorThrottle(err) == aws.TrueTernary
+	if hasResponse && retry.IsThrottle(responseErr.Response.StatusCode) {
+		throttled = true
+	}
+
+	if throttled {
+		retry.Throttled()
+	}
+
+	if hasResponse {
+		if delay, ok := retry.RetryAfter(responseErr.Response.Response); ok {
+			return min(delay, p.MaxDelay), nil
+		}
+	}
+
+	return p.Delay(attempt - 1), nil
+}
@@ -0,0 +1,115 @@
+package s3backend
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_RetriesTransientErrors(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	p := retry.Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+	s3Backend.client = s3.New(s3Backend.client.Options(), func(o *s3.Options) {
+		o.Retryer = retryer(p)()
+	})
+
+	var mu sync.Mutex
+	failures := map[string]int{}
+	faker := server.Config.Handler
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		// Every request fails twice, with throttling and then a server error
+		key := r.Method + " " + r.URL.String()
+		switch failures[key] {
+		case 0:
+			failures[key]++
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 1:
+			failures[key]++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			faker.ServeHTTP(w, r)
+		}
+	})
+
+	tmpDir := t.TempDir()
+	srcFile := filepath.Join(tmpDir, "source.txt")
+	require.NoError(t, os.WriteFile(srcFile, []byte("test content"), 0644))
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, srcFile, "artifacts/jobs/1/source.txt", backend.PushOptions{Force: true}))
+
+	dstFile := filepath.Join(tmpDir, "destination.txt")
+	require.NoError(t, s3Backend.Pull(ctx, "artifacts/jobs/1/source.txt", dstFile, backend.PullOptions{}))
+
+	content, err := os.ReadFile(dstFile)
+	require.NoError(t, err)
+	assert.Equal(t, "test content", string(content))
+}
+
+func TestS3Backend_RetriesGiveUp(t *testing.T) {
+	s3Backend, server, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	p := retry.Policy{Attempts: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+	s3Backend.client = s3.New(s3Backend.client.Options(), func(o *s3.Options) {
+		o.Retryer = retryer(p)()
+	})
+
+	requests := 0
+	server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	_, err := s3Backend.Exists(context.Background(), "artifacts/jobs/1/source.txt")
+	assert.Error(t, err)
+	assert.Equal(t, 2, requests)
+}
+
+func TestBackoff_Throttling(t *testing.T) {
+	p := retry.Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute}
+
+	slowDown := func(header http.Header) error {
+		return &smithyhttp.ResponseError{
+			Response: &smithyhttp.Response{Response: &http.Response{StatusCode: http.StatusServiceUnavailable, Header: header}},
+			Err:      &smithy.GenericAPIError{Code: "SlowDown", Message: "Please reduce your request rate."},
+		}
+	}
+
+	// SlowDown reduces the concurrency of running transfers
+	limiter := retry.NewLimiter(8)
+	delay, err := backoff(p).BackoffDelay(1, slowDown(http.Header{}))
+	require.NoError(t, err)
+	assert.Less(t, delay, time.Millisecond+1)
+	limiter.Acquire()
+	assert.Equal(t, 4, limiter.Limit())
+	limiter.Release()
+
+	// Retry-After is honored
+	delay, err = backoff(p).BackoffDelay(1, slowDown(http.Header{"Retry-After": []string{"5"}}))
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, delay)
+
+	// Other errors back off as the policy does
+	delay, err = backoff(p).BackoffDelay(2, errors.New("connection reset by peer"))
+	require.NoError(t, err)
+	assert.Less(t, delay, 2*time.Millisecond+1)
+}
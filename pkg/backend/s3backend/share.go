@@ -0,0 +1,43 @@
+package s3backend
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	log "github.com/sirupsen/logrus"
+)
+
+// maxPresignExpiry is the longest validity of a SigV4 presigned URL.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// Share returns a presigned GET URL for the file at remotePath in the
+// primary bucket, valid for expiresIn. Links signed with temporary
+// credentials stop working when the credentials expire, whichever is first.
+func (s *S3Backend) Share(ctx context.Context, remotePath string, expiresIn time.Duration) (string, time.Time, error) {
+	log.Debug("S3Backend: Sharing...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Expires in: %s\n", expiresIn)
+
+	if expiresIn <= 0 || expiresIn > maxPresignExpiry {
+		return "", time.Time{}, fmt.Errorf("presigned S3 links expire after 1 second to 7 days, not %s", expiresIn)
+	}
+
+	// Presigning does not check the object, so links to missing files are refused here
+	if _, err := s.Stat(ctx, remotePath); err != nil {
+		return "", time.Time{}, err
+	}
+
+	expires := time.Now().Add(expiresIn)
+	request, err := s3.NewPresignClient(s.client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.cfg.Bucket),
+		Key:    aws.String(s.prefixedKey(remotePath)),
+	}, s3.WithPresignExpires(expiresIn))
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("failed to presign S3 object '%s': %w", remotePath, err)
+	}
+
+	return request.URL, expires, nil
+}
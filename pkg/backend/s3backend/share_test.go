@@ -0,0 +1,51 @@
+package s3backend
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestS3Backend_Share(t *testing.T) {
+	s3Backend, _, cleanup := createTestS3Backend(t)
+	defer cleanup()
+
+	testFile := filepath.Join(t.TempDir(), "report.html")
+	require.NoError(t, os.WriteFile(testFile, []byte("<html>"), 0644))
+
+	ctx := context.Background()
+	require.NoError(t, s3Backend.Push(ctx, testFile, "artifacts/jobs/1/report.html", backend.PushOptions{}))
+
+	link, expires, err := s3Backend.Share(ctx, "artifacts/jobs/1/report.html", time.Hour)
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), expires, time.Minute)
+
+	u, err := url.Parse(link)
+	require.NoError(t, err)
+	assert.Equal(t, "3600", u.Query().Get("X-Amz-Expires"))
+
+	// The link works without credentials
+	resp, err := http.Get(link)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "<html>", string(body))
+
+	_, _, err = s3Backend.Share(ctx, "artifacts/jobs/1/missing.html", time.Hour)
+	var notFound *backend.ErrNotFound
+	assert.True(t, errors.As(err, &notFound))
+
+	_, _, err = s3Backend.Share(ctx, "artifacts/jobs/1/report.html", 8*24*time.Hour)
+	assert.ErrorContains(t, err, "7 days")
+}
@@ -0,0 +1,223 @@
+package s3backend
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/encrypt"
+	log "github.com/sirupsen/logrus"
+)
+
+// streamPartSize is the size of the parts used for streaming uploads.
+// Only one part is held in memory at a time. With S3's limit of 10,000
+// parts per upload, streams of up to ~78 GB can be uploaded.
+const streamPartSize = 8 * 1024 * 1024
+
+// PushStream uploads a stream to S3. Streams that fit in a single part are
+// uploaded with one PutObject call; larger ones use a multipart upload.
+// The checksum is only known once the whole stream was read, so for
+// multipart uploads it is stored in a sidecar instead of the metadata.
+func (s *S3Backend) PushStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions) error {
+	log.Debug("S3Backend: Pushing stream...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Size: %d\n", size)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if !opts.Force {
+		exists, err := s.Exists(ctx, remotePath)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return &backend.ErrAlreadyExists{Path: remotePath}
+		}
+	}
+
+	s.ensureExpireRule(ctx, opts)
+
+	return s.uploadStream(ctx, r, size, remotePath, opts, "")
+}
+
+// uploadStream uploads a stream, compressed with opts.Compress and
+// encrypted to opts.Encrypt, if set. The checksum of the stream as read
+// is computed as it is read, unless it is given.
+func (s *S3Backend) uploadStream(ctx context.Context, r io.Reader, size int64, remotePath string, opts backend.PushOptions, checksum string) error {
+	key := s.prefixedKey(remotePath)
+	part := make([]byte, streamPartSize)
+
+	hash := sha256.New()
+	r = io.TeeReader(r, hash)
+
+	if opts.Compress != "" || opts.Encrypt != nil {
+		encoded, err := encode(r, opts)
+		if err != nil {
+			return err
+		}
+		defer encoded.Close()
+		r = encoded
+	}
+
+	n, err := io.ReadFull(r, part)
+	lockMode, retainUntil, legalHold := lockFields(s.objectLock(opts))
+
+	// Encoded streams are no longer of the type of what was read
+	if opts.Headers.ContentType == "" && opts.Compress == "" && opts.Encrypt == nil {
+		opts.Headers.ContentType = backend.ContentType(remotePath, part[:n])
+	}
+	contentType, cacheControl, contentDisposition := headerFields(opts.Headers)
+
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		if checksum == "" {
+			checksum = hex.EncodeToString(hash.Sum(nil))
+		}
+
+		_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+			Bucket:                    aws.String(s.cfg.Bucket),
+			Key:                       aws.String(key),
+			Body:                      bytes.NewReader(part[:n]),
+			Metadata:                  streamMetadata(opts, checksum, size),
+			ContentType:               contentType,
+			CacheControl:              cacheControl,
+			ContentDisposition:        contentDisposition,
+			ObjectLockMode:            lockMode,
+			ObjectLockRetainUntilDate: retainUntil,
+			ObjectLockLegalHoldStatus: legalHold,
+			Tagging:                   expireTagging(opts),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload to S3: %w", err)
+		}
+
+		log.Debugf("Uploaded stream -> s3://%s/%s\n", s.cfg.Bucket, key)
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:                    aws.String(s.cfg.Bucket),
+		Key:                       aws.String(key),
+		Metadata:                  streamMetadata(opts, checksum, size),
+		ContentType:               contentType,
+		CacheControl:              cacheControl,
+		ContentDisposition:        contentDisposition,
+		ObjectLockMode:            lockMode,
+		ObjectLockRetainUntilDate: retainUntil,
+		ObjectLockLegalHoldStatus: legalHold,
+		Tagging:                   expireTagging(opts),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %w", err)
+	}
+
+	if err := s.uploadMultipart(ctx, key, created.UploadId, part, r); err != nil {
+		return err
+	}
+
+	if checksum != "" {
+		return nil
+	}
+
+	sidecarKey := s.prefixedKey(backend.ChecksumSidecarPath(remotePath))
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.cfg.Bucket),
+		Key:    aws.String(sidecarKey),
+		Body:   strings.NewReader(hex.EncodeToString(hash.Sum(nil))),
+	})
+	if err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+
+	return nil
+}
+
+// streamMetadata is the metadata of a stream of size bytes, or of unknown
+// size if negative, recording how it is compressed and encrypted, if it is.
+func streamMetadata(opts backend.PushOptions, checksum string, size int64) map[string]string {
+	metadata := objectMetadata(opts, checksum)
+	if opts.Compress != "" {
+		metadata[backend.EncodingMetadataKey] = opts.Compress
+	}
+	if opts.Encrypt != nil {
+		metadata[backend.EncryptionMetadataKey] = encrypt.Age
+	}
+	if encoded(metadata) && size >= 0 {
+		metadata[backend.SizeMetadataKey] = strconv.FormatInt(size, 10)
+	}
+
+	return metadata
+}
+
+// uploadMultipart uploads first and then the rest of r as parts of a started
+// multipart upload, reusing the first buffer for every part. The upload is
+// aborted on failure so no orphaned parts are left behind.
+func (s *S3Backend) uploadMultipart(ctx context.Context, key string, uploadID *string, first []byte, r io.Reader) error {
+	abort := func(cause error) error {
+		s.abortMultipart(key, uploadID)
+		return cause
+	}
+
+	completed := []types.CompletedPart{}
+	part := first
+
+	for partNumber := int32(1); len(part) > 0; partNumber++ {
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.cfg.Bucket),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int32(partNumber),
+			Body:       bytes.NewReader(part),
+		})
+		if err != nil {
+			return abort(fmt.Errorf("failed to upload part %d: %w", partNumber, err))
+		}
+
+		completed = append(completed, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(partNumber)})
+		log.Debugf("Uploaded part %d of s3://%s/%s\n", partNumber, s.cfg.Bucket, key)
+
+		n, err := io.ReadFull(r, first)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return abort(fmt.Errorf("failed to read stream: %w", err))
+		}
+
+		part = first[:n]
+	}
+
+	_, err := s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.cfg.Bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completed},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete multipart upload: %w", err))
+	}
+
+	log.Debugf("Uploaded stream in %d parts -> s3://%s/%s\n", len(completed), s.cfg.Bucket, key)
+	return nil
+}
+
+// abortMultipart aborts a multipart upload, so no orphaned parts are left
+// behind. It runs even if the context of the upload was canceled.
+func (s *S3Backend) abortMultipart(key string, uploadID *string) {
+	_, err := s.client.AbortMultipartUpload(context.Background(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.cfg.Bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+	if err != nil {
+		log.Warnf("Failed to abort multipart upload for '%s': %v\n", key, err)
+	}
+}
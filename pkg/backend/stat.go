@@ -0,0 +1,39 @@
+package backend
+
+import (
+	"context"
+)
+
+// Stat describes the file at remotePath with the backend's Stater, or by
+// listing it if the backend only implements Lister. It returns ErrNotFound
+// if the file does not exist, and ErrListingNotSupported if the backend
+// can do neither.
+func Stat(ctx context.Context, b Backend, remotePath string) (*ObjectInfo, error) {
+	if stater, ok := b.(Stater); ok {
+		return stater.Stat(ctx, remotePath)
+	}
+
+	lister, ok := b.(Lister)
+	if !ok {
+		return nil, ErrListingNotSupported
+	}
+
+	var found *ObjectInfo
+	err := lister.List(ctx, remotePath, func(info ObjectInfo) error {
+		// Listings are in key order, so the file itself comes first
+		if info.Path == remotePath {
+			found = &info
+		}
+
+		return StopListing
+	})
+
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, &ErrNotFound{Path: remotePath}
+	}
+
+	return found, nil
+}
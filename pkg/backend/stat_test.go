@@ -0,0 +1,49 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// plain hides every optional capability of a backend.
+type plain struct {
+	backend.Backend
+}
+
+// listOnly hides every optional capability of a backend but listing.
+type listOnly struct {
+	backend.Backend
+	backend.Lister
+}
+
+func TestStat(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+	memory.Put("artifacts/jobs/1/a.txt", []byte("hello"))
+	memory.Put("artifacts/jobs/1/a.txt.bak", []byte("hello again"))
+
+	for name, b := range map[string]backend.Backend{
+		"stater":    memory,
+		"lister":    listOnly{Backend: memory, Lister: memory},
+		"read-only": backend.NewReadOnly(listOnly{Backend: memory, Lister: memory}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			info, err := backend.Stat(ctx, b, "artifacts/jobs/1/a.txt")
+			require.NoError(t, err)
+			assert.Equal(t, "artifacts/jobs/1/a.txt", info.Path)
+			assert.Equal(t, int64(5), info.Size)
+
+			_, err = backend.Stat(ctx, b, "artifacts/jobs/1/a")
+			var notFound *backend.ErrNotFound
+			assert.ErrorAs(t, err, &notFound)
+		})
+	}
+
+	_, err := backend.Stat(ctx, plain{Backend: memory}, "artifacts/jobs/1/a.txt")
+	assert.Equal(t, backend.ErrListingNotSupported, err)
+}
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/retry"
+)
+
+type transferStatsKey struct{}
+
+// WithTransferStats returns a copy of ctx carrying the stats.
+func WithTransferStats(ctx context.Context, s *TransferStats) context.Context {
+	return context.WithValue(ctx, transferStatsKey{}, s)
+}
+
+// TransferStatsFromContext returns the stats of ctx, or nil if it carries
+// none.
+func TransferStatsFromContext(ctx context.Context) *TransferStats {
+	s, _ := ctx.Value(transferStatsKey{}).(*TransferStats)
+	return s
+}
+
+// TransferStats sums up the files of a push or pull that were transferred,
+// skipped and failed, and the requests retried since it started. It
+// travels with the context of the transfer, so backends record the files
+// they skip, e.g. the ones an interrupted push already uploaded. It is
+// safe for concurrent use, and its methods do nothing on a nil
+// TransferStats.
+type TransferStats struct {
+	mu           sync.Mutex
+	start        time.Time
+	retries      int64 // retry.Retries() at the start
+	files        int
+	bytes        int64
+	skippedFiles int
+	skippedBytes int64
+	failedFiles  int
+}
+
+// NewTransferStats returns stats starting now.
+func NewTransferStats() *TransferStats {
+	return &TransferStats{start: time.Now(), retries: retry.Retries()}
+}
+
+// Transferred records a file of size bytes that was transferred.
+func (s *TransferStats) Transferred(size int64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.files++
+	s.bytes += size
+}
+
+// Skipped records a file of size bytes that needed no transfer.
+func (s *TransferStats) Skipped(size int64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.skippedFiles++
+	s.skippedBytes += size
+}
+
+// Failed records a file that failed to transfer.
+func (s *TransferStats) Failed() {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failedFiles++
+}
+
+// Complete records the totals of a transfer that succeeded without its
+// files being recorded one by one, e.g. a directory pushed at once: the
+// files and bytes not skipped count as transferred. It does nothing if
+// some files were recorded as transferred or failed.
+func (s *TransferStats) Complete(files int, size int64) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.files > 0 || s.failedFiles > 0 {
+		return
+	}
+
+	s.files = max(files-s.skippedFiles, 0)
+	s.bytes = max(size-s.skippedBytes, 0)
+}
+
+// TransferSummary is a snapshot of TransferStats, with the time the
+// transfer took so far and its average throughput.
+type TransferSummary struct {
+	Files          int     `json:"files"`
+	Bytes          int64   `json:"bytes"`
+	SkippedFiles   int     `json:"skippedFiles"`
+	SkippedBytes   int64   `json:"skippedBytes"`
+	FailedFiles    int     `json:"failedFiles"`
+	Retries        int64   `json:"retries"`
+	ElapsedSeconds float64 `json:"elapsedSeconds"`
+	BytesPerSecond float64 `json:"bytesPerSecond"`
+}
+
+// Summary returns the stats so far. Skipped bytes do not count towards
+// the throughput.
+func (s *TransferStats) Summary() TransferSummary {
+	if s == nil {
+		return TransferSummary{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elapsed := time.Since(s.start).Seconds()
+	summary := TransferSummary{
+		Files:          s.files,
+		Bytes:          s.bytes,
+		SkippedFiles:   s.skippedFiles,
+		SkippedBytes:   s.skippedBytes,
+		FailedFiles:    s.failedFiles,
+		Retries:        retry.Retries() - s.retries,
+		ElapsedSeconds: elapsed,
+	}
+
+	if elapsed > 0 {
+		summary.BytesPerSecond = float64(s.bytes) / elapsed
+	}
+
+	return summary
+}
@@ -0,0 +1,59 @@
+package backend_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferStats(t *testing.T) {
+	t.Run("files are recorded one by one", func(t *testing.T) {
+		s := backend.NewTransferStats()
+		s.Transferred(100)
+		s.Transferred(50)
+		s.Skipped(30)
+		s.Failed()
+		retry.Retried()
+
+		// Totals of the whole transfer are ignored once files were recorded
+		s.Complete(10, 1000)
+
+		summary := s.Summary()
+		assert.Equal(t, 2, summary.Files)
+		assert.Equal(t, int64(150), summary.Bytes)
+		assert.Equal(t, 1, summary.SkippedFiles)
+		assert.Equal(t, int64(30), summary.SkippedBytes)
+		assert.Equal(t, 1, summary.FailedFiles)
+		assert.Equal(t, int64(1), summary.Retries)
+		assert.Greater(t, summary.ElapsedSeconds, 0.0)
+		assert.Greater(t, summary.BytesPerSecond, 0.0)
+	})
+
+	t.Run("complete leaves out skipped files", func(t *testing.T) {
+		s := backend.NewTransferStats()
+		s.Skipped(300)
+		s.Complete(4, 1000)
+
+		summary := s.Summary()
+		assert.Equal(t, 3, summary.Files)
+		assert.Equal(t, int64(700), summary.Bytes)
+		assert.Equal(t, 1, summary.SkippedFiles)
+	})
+
+	t.Run("travels with the context", func(t *testing.T) {
+		s := backend.NewTransferStats()
+		ctx := backend.WithTransferStats(context.Background(), s)
+		assert.Same(t, s, backend.TransferStatsFromContext(ctx))
+
+		stats := backend.TransferStatsFromContext(context.Background())
+		assert.Nil(t, stats)
+
+		// Stats are optional
+		stats.Transferred(10)
+		stats.Complete(1, 10)
+		assert.Equal(t, backend.TransferSummary{}, stats.Summary())
+	})
+}
@@ -0,0 +1,112 @@
+package backend
+
+import (
+	"context"
+	"errors"
+	"path"
+	"sort"
+	"strings"
+)
+
+// DirEntry is a file or directory read by a ReadDirFunc. Info describes
+// files; its Path is set by the walk.
+type DirEntry struct {
+	Name  string
+	IsDir bool
+	Info  ObjectInfo
+}
+
+// ReadDirFunc reads the entries of the directory dir, "" for the root,
+// in any order. It returns ErrNotFound if there is no such directory.
+// Backends storing files in a directory tree, e.g. FTP servers, implement
+// one to walk and list it with Walk and WalkPrefix.
+type ReadDirFunc func(ctx context.Context, dir string) ([]DirEntry, error)
+
+// Walk calls fn for every file under dir, in key order, skipping checksum
+// sidecars. Directories are read one at a time as the walk reaches them.
+// If descend is set, only the directories it accepts are walked.
+func Walk(ctx context.Context, readDir ReadDirFunc, dir string, descend func(string) bool, fn func(ObjectInfo) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	entries, err := readDir(ctx, dir)
+	if err != nil {
+		return err
+	}
+
+	// Sorting directories as if they had a trailing slash keeps the walk in
+	// key order, e.g. "a-b" comes before the files in "a/"
+	sortKey := func(entry DirEntry) string {
+		if entry.IsDir {
+			return entry.Name + "/"
+		}
+		return entry.Name
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return sortKey(entries[i]) < sortKey(entries[j])
+	})
+
+	for _, entry := range entries {
+		entryPath := entry.Name
+		if dir != "" {
+			entryPath = dir + "/" + entry.Name
+		}
+
+		if IsChecksumSidecar(entryPath) {
+			continue
+		}
+
+		if entry.IsDir {
+			if descend != nil && !descend(entryPath) {
+				continue
+			}
+			if err := Walk(ctx, readDir, entryPath, descend, fn); err != nil {
+				return err
+			}
+			continue
+		}
+
+		info := entry.Info
+		info.Path = entryPath
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WalkPrefix calls fn for every file whose path starts with remotePrefix,
+// in key order, walking only the directories that can hold one. Listing a
+// missing directory finds nothing, and fn returning StopListing ends the
+// walk without an error, as Lister.List expects.
+func WalkPrefix(ctx context.Context, readDir ReadDirFunc, remotePrefix string, fn func(ObjectInfo) error) error {
+	// Start from the deepest directory containing every match
+	dir := strings.TrimSuffix(remotePrefix, "/")
+	if !strings.HasSuffix(remotePrefix, "/") {
+		dir = path.Dir(remotePrefix)
+		if dir == "." {
+			dir = ""
+		}
+	}
+
+	descend := func(dirPath string) bool {
+		return strings.HasPrefix(dirPath+"/", remotePrefix) || strings.HasPrefix(remotePrefix, dirPath+"/")
+	}
+
+	err := Walk(ctx, readDir, dir, descend, func(info ObjectInfo) error {
+		if !strings.HasPrefix(info.Path, remotePrefix) {
+			return nil
+		}
+
+		return fn(info)
+	})
+
+	var notFound *ErrNotFound
+	if errors.Is(err, StopListing) || errors.As(err, &notFound) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,97 @@
+package backend_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// tree returns a ReadDirFunc over the files at paths, recording the
+// directories read.
+func tree(paths []string, read *[]string) backend.ReadDirFunc {
+	return func(ctx context.Context, dir string) ([]backend.DirEntry, error) {
+		*read = append(*read, dir)
+
+		prefix := ""
+		if dir != "" {
+			prefix = dir + "/"
+		}
+
+		seen := map[string]bool{}
+		entries := []backend.DirEntry{}
+		for _, p := range paths {
+			if !strings.HasPrefix(p, prefix) {
+				continue
+			}
+
+			name, rest, isDir := strings.Cut(strings.TrimPrefix(p, prefix), "/")
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+			entries = append(entries, backend.DirEntry{Name: name, IsDir: isDir, Info: backend.ObjectInfo{Size: int64(len(rest))}})
+		}
+
+		if len(entries) == 0 {
+			return nil, &backend.ErrNotFound{Path: dir}
+		}
+
+		return entries, nil
+	}
+}
+
+func TestWalk(t *testing.T) {
+	read := []string{}
+	readDir := tree([]string{"a/x.txt", "a-b.txt", backend.ChecksumSidecarPath("a/x.txt"), "a/b/y.txt", "c.txt"}, &read)
+
+	paths := []string{}
+	err := backend.Walk(context.Background(), readDir, "", nil, func(info backend.ObjectInfo) error {
+		paths = append(paths, info.Path)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a-b.txt", "a/b/y.txt", "a/x.txt", "c.txt"}, paths)
+}
+
+func TestWalkPrefix(t *testing.T) {
+	files := []string{"a/x.txt", "a/xy.txt", "a/b/y.txt", "a-b.txt", "c/z.txt"}
+
+	list := func(prefix string, stopAfter int) ([]string, []string) {
+		read := []string{}
+		paths := []string{}
+		err := backend.WalkPrefix(context.Background(), tree(files, &read), prefix, func(info backend.ObjectInfo) error {
+			paths = append(paths, info.Path)
+			if len(paths) == stopAfter {
+				return backend.StopListing
+			}
+			return nil
+		})
+
+		require.NoError(t, err)
+		return paths, read
+	}
+
+	// Only the directories that can hold a match are read
+	paths, read := list("a/x", 0)
+	assert.Equal(t, []string{"a/x.txt", "a/xy.txt"}, paths)
+	assert.Equal(t, []string{"a"}, read)
+
+	paths, _ = list("a/", 0)
+	assert.Equal(t, []string{"a/b/y.txt", "a/x.txt", "a/xy.txt"}, paths)
+
+	paths, _ = list("a", 0)
+	assert.Equal(t, []string{"a-b.txt", "a/b/y.txt", "a/x.txt", "a/xy.txt"}, paths)
+
+	// StopListing ends the walk early
+	paths, _ = list("", 2)
+	assert.Equal(t, []string{"a-b.txt", "a/b/y.txt"}, paths)
+
+	// Missing directories list nothing
+	paths, _ = list("missing/", 0)
+	assert.Empty(t, paths)
+}
@@ -0,0 +1,502 @@
+package webhdfsbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	log "github.com/sirupsen/logrus"
+)
+
+func init() {
+	backend.RegisterWebHDFSBackend(func() (backend.Backend, error) {
+		return New()
+	})
+}
+
+// doer sends HTTP requests, with or without SPNEGO authentication.
+type doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// WebHDFSBackend implements the Backend interface with the WebHDFS REST API.
+type WebHDFSBackend struct {
+	cfg *Config
+
+	// client sends requests to the NameNode, authenticated with SPNEGO if configured
+	client doer
+
+	// data sends file contents to the DataNodes, which are authorized by
+	// the delegation token in the NameNode's redirect
+	data *http.Client
+}
+
+// fileStatus is the WebHDFS FileStatus JSON object.
+type fileStatus struct {
+	PathSuffix       string `json:"pathSuffix"`
+	Type             string `json:"type"` // FILE or DIRECTORY
+	Length           int64  `json:"length"`
+	ModificationTime int64  `json:"modificationTime"` // milliseconds since the epoch
+}
+
+// remoteException is the WebHDFS error JSON object.
+type remoteException struct {
+	RemoteException struct {
+		Exception string `json:"exception"`
+		Message   string `json:"message"`
+	} `json:"RemoteException"`
+}
+
+// New creates a new WebHDFSBackend instance from the environment/config file.
+func New() (*WebHDFSBackend, error) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithConfig(cfg)
+}
+
+// NewWithConfig creates a new WebHDFSBackend instance for a validated configuration.
+func NewWithConfig(cfg *Config) (*WebHDFSBackend, error) {
+	// Uploads are redirected to a DataNode, and the file is sent there
+	// by pushFile, so redirects are only followed for downloads.
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if req.Method == http.MethodPut {
+				return http.ErrUseLastResponse
+			}
+			if len(via) >= 10 {
+				return errors.New("stopped after 10 redirects")
+			}
+			return nil
+		},
+	}
+
+	w := &WebHDFSBackend{cfg: cfg, client: httpClient, data: &http.Client{}}
+	if cfg.Kerberos {
+		spnegoClient, err := newSPNEGOClient(cfg, httpClient)
+		if err != nil {
+			return nil, err
+		}
+		w.client = spnegoClient
+	}
+
+	log.Debug("WebHDFSBackend: Client initialized\n")
+	log.Debugf("* URL: %s\n", cfg.URL)
+	log.Debugf("* Prefix: %s\n", cfg.Prefix)
+	log.Debugf("* Kerberos: %v\n", cfg.Kerberos)
+
+	return w, nil
+}
+
+// Push uploads a local file or directory, one file at a time.
+func (w *WebHDFSBackend) Push(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	log.Debug("WebHDFSBackend: Pushing...\n")
+	log.Debugf("* Local: %s\n", localPath)
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Force: %v\n", opts.Force)
+
+	if opts.Lock != nil {
+		return backend.ErrObjectLockNotSupported
+	}
+
+	if opts.Compress != "" {
+		return backend.ErrCompressionNotSupported
+	}
+
+	if opts.Encrypt != nil {
+		return backend.ErrEncryptionNotSupported
+	}
+
+	info, err := os.Stat(localPath)
+	if err != nil {
+		return fmt.Errorf("failed to stat local path '%s': %w", localPath, err)
+	}
+
+	if !info.IsDir() {
+		return w.pushFile(ctx, localPath, remotePath, opts)
+	}
+
+	return files.Walk(localPath, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(localPath, filePath)
+		if err != nil {
+			return err
+		}
+
+		return w.pushFile(ctx, filePath, remotePath+"/"+filepath.ToSlash(relPath), opts)
+	})
+}
+
+func (w *WebHDFSBackend) pushFile(ctx context.Context, localPath, remotePath string, opts backend.PushOptions) error {
+	checksum, err := files.SHA256File(localPath)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(localPath) // #nosec
+	if err != nil {
+		return fmt.Errorf("failed to open local file '%s': %w", localPath, err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat '%s': %w", localPath, err)
+	}
+
+	if err := w.create(ctx, remotePath, file, info.Size(), opts.Force); err != nil {
+		return err
+	}
+
+	log.Debugf("Uploaded: %s -> %s\n", localPath, w.hdfsPath(remotePath))
+
+	// Failing to store the checksum does not fail the push, as the file is already uploaded
+	sidecar := backend.ChecksumSidecarPath(remotePath)
+	if err := w.create(ctx, sidecar, bytes.NewReader([]byte(checksum)), int64(len(checksum)), true); err != nil {
+		log.Warnf("Failed to store checksum of '%s': %v\n", remotePath, err)
+	}
+
+	return nil
+}
+
+// create uploads a file in two steps: the NameNode creates it and redirects
+// to a DataNode, which receives the contents. Parent directories are created
+// as needed. Without overwrite, existing files are reported as ErrAlreadyExists.
+func (w *WebHDFSBackend) create(ctx context.Context, remotePath string, r io.Reader, size int64, overwrite bool) error {
+	params := url.Values{"overwrite": {strconv.FormatBool(overwrite)}}
+	response, err := w.call(ctx, http.MethodPut, "CREATE", remotePath, params, "push")
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+
+	location := response.Header.Get("Location")
+	if response.StatusCode != http.StatusTemporaryRedirect || location == "" {
+		return fmt.Errorf("WebHDFS did not redirect the upload of '%s' to a DataNode: got %d status code", remotePath, response.StatusCode)
+	}
+
+	// Empty files need a nil body, or they are sent with chunked encoding
+	if size == 0 {
+		r = nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, location, r)
+	if err != nil {
+		return fmt.Errorf("failed to create new http request: %w", err)
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+
+	log.Debugf("PUT '%s'...\n", location)
+	response, err = w.data.Do(req)
+	if err != nil {
+		return fmt.Errorf("PUT request to %s failed: %w", location, err)
+	}
+	if err := check(response, "push", remotePath); err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	return nil
+}
+
+// Pull downloads a file, or every file in a directory.
+func (w *WebHDFSBackend) Pull(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	log.Debug("WebHDFSBackend: Pulling...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+	log.Debugf("* Local: %s\n", localPath)
+
+	status, err := w.status(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+
+	if status.Type != "DIRECTORY" {
+		return w.pullFile(ctx, remotePath, localPath, opts)
+	}
+
+	dir := strings.TrimSuffix(remotePath, "/")
+	return backend.Walk(ctx, w.readDir, dir, nil, func(info backend.ObjectInfo) error {
+		destPath := filepath.Join(localPath, filepath.FromSlash(strings.TrimPrefix(info.Path, dir+"/")))
+		return w.pullFile(ctx, info.Path, destPath, opts)
+	})
+}
+
+func (w *WebHDFSBackend) pullFile(ctx context.Context, remotePath, localPath string, opts backend.PullOptions) error {
+	if !opts.Force {
+		if _, err := os.Stat(localPath); err == nil {
+			return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+		}
+	}
+
+	body, err := w.Open(ctx, remotePath)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	dir := filepath.Dir(localPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory '%s': %w", dir, err)
+	}
+
+	if err := files.WriteAtomically(localPath, body); err != nil {
+		return err
+	}
+
+	log.Debugf("Downloaded: %s -> %s\n", w.hdfsPath(remotePath), localPath)
+	return nil
+}
+
+// Open streams the contents of a file, following the redirect to a DataNode.
+func (w *WebHDFSBackend) Open(ctx context.Context, remotePath string) (io.ReadCloser, error) {
+	log.Debug("WebHDFSBackend: Opening...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	response, err := w.call(ctx, http.MethodGet, "OPEN", remotePath, nil, "pull")
+	if err != nil {
+		return nil, err
+	}
+
+	return response.Body, nil
+}
+
+// Yank deletes a file or directory recursively, along with its checksums.
+// Paths that do not exist are not an error.
+func (w *WebHDFSBackend) Yank(ctx context.Context, remotePath string) error {
+	log.Debug("WebHDFSBackend: Yanking...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	if err := w.delete(ctx, remotePath); err != nil {
+		return err
+	}
+
+	for _, sidecar := range []string{backend.ChecksumSidecarPath(remotePath), backend.ChecksumSidecarPrefix(remotePath)} {
+		if err := w.delete(ctx, sidecar); err != nil {
+			log.Warnf("Failed to remove checksums of '%s': %v\n", remotePath, err)
+		}
+	}
+
+	return nil
+}
+
+func (w *WebHDFSBackend) delete(ctx context.Context, remotePath string) error {
+	response, err := w.call(ctx, http.MethodDelete, "DELETE", remotePath, url.Values{"recursive": {"true"}}, "yank")
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+	return nil
+}
+
+// Exists checks if a file exists.
+func (w *WebHDFSBackend) Exists(ctx context.Context, remotePath string) (bool, error) {
+	log.Debug("WebHDFSBackend: Checking existence...\n")
+	log.Debugf("* Remote: %s\n", remotePath)
+
+	status, err := w.status(ctx, remotePath)
+
+	var notFound *backend.ErrNotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return status.Type == "FILE", nil
+}
+
+// List lists the files under remotePrefix with one LISTSTATUS request
+// per directory the walk reaches.
+func (w *WebHDFSBackend) List(ctx context.Context, remotePrefix string, fn func(backend.ObjectInfo) error) error {
+	log.Debug("WebHDFSBackend: Listing...\n")
+	log.Debugf("* Prefix: %s\n", remotePrefix)
+
+	return backend.WalkPrefix(ctx, w.readDir, remotePrefix, fn)
+}
+
+// Checksum returns the checksum stored in the file's sidecar.
+func (w *WebHDFSBackend) Checksum(ctx context.Context, remotePath string) (string, error) {
+	exists, err := w.Exists(ctx, remotePath)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", &backend.ErrNotFound{Path: remotePath}
+	}
+
+	sidecar, err := w.Open(ctx, backend.ChecksumSidecarPath(remotePath))
+	if err != nil {
+		var notFound *backend.ErrNotFound
+		if errors.As(err, &notFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer sidecar.Close()
+
+	checksum, err := io.ReadAll(io.LimitReader(sidecar, 128))
+	if err != nil {
+		return "", fmt.Errorf("failed to read checksum of '%s': %w", remotePath, err)
+	}
+
+	return strings.TrimSpace(string(checksum)), nil
+}
+
+// Close releases resources. For WebHDFS backend, this is a no-op.
+func (w *WebHDFSBackend) Close() error {
+	return nil
+}
+
+// Helper functions
+
+// status returns the FileStatus of remotePath.
+func (w *WebHDFSBackend) status(ctx context.Context, remotePath string) (*fileStatus, error) {
+	response, err := w.call(ctx, http.MethodGet, "GETFILESTATUS", remotePath, nil, "pull")
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	result := struct {
+		FileStatus fileStatus `json:"FileStatus"`
+	}{}
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to decode WebHDFS file status of '%s': %w", remotePath, err)
+	}
+
+	return &result.FileStatus, nil
+}
+
+// readDir returns the entries of a directory, for backend.Walk.
+func (w *WebHDFSBackend) readDir(ctx context.Context, dir string) ([]backend.DirEntry, error) {
+	response, err := w.call(ctx, http.MethodGet, "LISTSTATUS", dir, nil, "pull")
+	if err != nil {
+		return nil, err
+	}
+
+	result := struct {
+		FileStatuses struct {
+			FileStatus []fileStatus `json:"FileStatus"`
+		} `json:"FileStatuses"`
+	}{}
+	err = json.NewDecoder(response.Body).Decode(&result)
+	response.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode WebHDFS listing of '%s': %w", dir, err)
+	}
+
+	entries := []backend.DirEntry{}
+	for _, status := range result.FileStatuses.FileStatus {
+		entries = append(entries, backend.DirEntry{
+			Name:  status.PathSuffix,
+			IsDir: status.Type == "DIRECTORY",
+			Info: backend.ObjectInfo{
+				Size:    status.Length,
+				ModTime: time.UnixMilli(status.ModificationTime),
+			},
+		})
+	}
+
+	return entries, nil
+}
+
+// hdfsPath returns the absolute HDFS path of remotePath.
+func (w *WebHDFSBackend) hdfsPath(remotePath string) string {
+	return path.Join("/", w.cfg.Prefix, remotePath)
+}
+
+// call sends a WebHDFS operation to the NameNode, mapping error responses to
+// backend errors. The caller must close the body of the returned response.
+func (w *WebHDFSBackend) call(ctx context.Context, method, op, remotePath string, params url.Values, operation string) (*http.Response, error) {
+	query := url.Values{"op": {op}}
+	for key, values := range params {
+		query[key] = values
+	}
+	if !w.cfg.Kerberos && w.cfg.User != "" {
+		query.Set("user.name", w.cfg.User)
+	}
+
+	segments := strings.Split(w.hdfsPath(remotePath), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+
+	u := strings.TrimSuffix(w.cfg.URL, "/") + "/webhdfs/v1" + strings.Join(segments, "/") + "?" + query.Encode()
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create new http request: %w", err)
+	}
+
+	log.Debugf("%s '%s'...\n", method, u)
+	response, err := w.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request to %s failed: %w", method, u, err)
+	}
+
+	if response.StatusCode == http.StatusTemporaryRedirect {
+		return response, nil
+	}
+
+	if err := check(response, operation, remotePath); err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// check closes unsuccessful responses and maps them to backend errors,
+// using the Java exception WebHDFS reports where possible.
+func check(response *http.Response, operation, remotePath string) error {
+	log.Debugf("%s request got %d response.\n", response.Request.Method, response.StatusCode)
+	if common.IsStatusOK(response.StatusCode) {
+		return nil
+	}
+
+	// #nosec
+	defer response.Body.Close()
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+
+	exception := &remoteException{}
+	message := strings.TrimSpace(string(body))
+	if json.Unmarshal(body, exception) == nil && exception.RemoteException.Exception != "" {
+		message = exception.RemoteException.Exception + ": " + exception.RemoteException.Message
+	}
+
+	switch {
+	case exception.RemoteException.Exception == "FileNotFoundException" || response.StatusCode == http.StatusNotFound:
+		return &backend.ErrNotFound{Path: remotePath}
+	case exception.RemoteException.Exception == "FileAlreadyExistsException":
+		return &backend.ErrAlreadyExists{Path: remotePath}
+	case exception.RemoteException.Exception == "AccessControlException" ||
+		response.StatusCode == http.StatusUnauthorized || response.StatusCode == http.StatusForbidden:
+		return &backend.ErrPermissionDenied{Operation: operation, Path: remotePath, Reason: message}
+	default:
+		return fmt.Errorf("%s request to %s failed with %d status code: %s",
+			response.Request.Method, response.Request.URL, response.StatusCode, message)
+	}
+}
@@ -0,0 +1,239 @@
+package webhdfsbackend
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeHDFS serves the WebHDFS operations used by the backend. Uploads and
+// downloads are redirected to /datanode, like a real NameNode does.
+type fakeHDFS struct {
+	mu    sync.Mutex
+	files map[string][]byte // by absolute HDFS path
+	users []string
+}
+
+func (f *fakeHDFS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if strings.HasPrefix(r.URL.Path, "/datanode/") {
+		p := strings.TrimPrefix(r.URL.Path, "/datanode")
+		if r.Method == http.MethodPut {
+			data, _ := io.ReadAll(r.Body)
+			f.files[p] = data
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		_, _ = w.Write(f.files[p])
+		return
+	}
+
+	p := strings.TrimPrefix(r.URL.Path, "/webhdfs/v1")
+	query := r.URL.Query()
+	f.users = append(f.users, query.Get("user.name"))
+
+	switch query.Get("op") {
+	case "CREATE":
+		if _, ok := f.files[p]; ok && query.Get("overwrite") != "true" {
+			remoteError(w, http.StatusForbidden, "FileAlreadyExistsException", p+" already exists")
+			return
+		}
+		w.Header().Set("Location", "http://"+r.Host+"/datanode"+p)
+		w.WriteHeader(http.StatusTemporaryRedirect)
+	case "OPEN":
+		if _, ok := f.files[p]; !ok {
+			remoteError(w, http.StatusNotFound, "FileNotFoundException", "File does not exist: "+p)
+			return
+		}
+		http.Redirect(w, r, "/datanode"+p, http.StatusTemporaryRedirect)
+	case "GETFILESTATUS":
+		statuses := f.list(p)
+		if len(statuses) == 0 {
+			remoteError(w, http.StatusNotFound, "FileNotFoundException", "File does not exist: "+p)
+			return
+		}
+		status := map[string]interface{}{"type": "DIRECTORY"}
+		if data, ok := f.files[p]; ok {
+			status = map[string]interface{}{"type": "FILE", "length": len(data)}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"FileStatus": status})
+	case "LISTSTATUS":
+		statuses := f.list(p)
+		if len(statuses) == 0 {
+			remoteError(w, http.StatusNotFound, "FileNotFoundException", "File does not exist: "+p)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"FileStatuses": map[string]interface{}{"FileStatus": statuses}})
+	case "DELETE":
+		for name := range f.files {
+			if name == p || strings.HasPrefix(name, p+"/") {
+				delete(f.files, name)
+			}
+		}
+		_, _ = w.Write([]byte(`{"boolean": true}`))
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+// list returns the entries of directory p, or p itself if it is a file.
+func (f *fakeHDFS) list(p string) []map[string]interface{} {
+	if data, ok := f.files[p]; ok {
+		return []map[string]interface{}{{"pathSuffix": "", "type": "FILE", "length": len(data)}}
+	}
+
+	entries := map[string]map[string]interface{}{}
+	for name, data := range f.files {
+		rel := strings.TrimPrefix(name, strings.TrimSuffix(p, "/")+"/")
+		if rel == name {
+			continue
+		}
+		if child, _, isDir := strings.Cut(rel, "/"); isDir {
+			entries[child] = map[string]interface{}{"pathSuffix": child, "type": "DIRECTORY"}
+		} else {
+			entries[child] = map[string]interface{}{"pathSuffix": child, "type": "FILE", "length": len(data), "modificationTime": 1700000000000}
+		}
+	}
+
+	// Real NameNodes return entries sorted by name, without the trailing slash
+	names := []string{}
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	statuses := []map[string]interface{}{}
+	for _, name := range names {
+		statuses = append(statuses, entries[name])
+	}
+	return statuses
+}
+
+func remoteError(w http.ResponseWriter, status int, exception, message string) {
+	w.WriteHeader(status)
+	fmt.Fprintf(w, `{"RemoteException":{"exception":%q,"javaClassName":"java.io.%s","message":%q}}`, exception, exception, message)
+}
+
+func createTestWebHDFSBackend(t *testing.T) (*WebHDFSBackend, *fakeHDFS) {
+	hdfs := &fakeHDFS{files: map[string][]byte{}}
+	server := httptest.NewServer(hdfs)
+	t.Cleanup(server.Close)
+
+	cfg := &Config{URL: server.URL, Prefix: "/ci", User: "builder"}
+	require.NoError(t, cfg.Validate())
+
+	w, err := NewWithConfig(cfg)
+	require.NoError(t, err)
+	return w, hdfs
+}
+
+func TestWebHDFSBackend_PushPullYank(t *testing.T) {
+	w, hdfs := createTestWebHDFSBackend(t)
+	ctx := context.Background()
+
+	tmpDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(tmpDir, "data", "sub"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data", "a.txt"), []byte("hello"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "data", "sub", "empty.txt"), []byte{}, 0644))
+
+	require.NoError(t, w.Push(ctx, filepath.Join(tmpDir, "data"), "artifacts/jobs/1/data", backend.PushOptions{}))
+	assert.Equal(t, []byte("hello"), hdfs.files["/ci/artifacts/jobs/1/data/a.txt"])
+	assert.Contains(t, hdfs.files, "/ci/artifacts/jobs/1/.checksums/data/a.txt.sha256")
+	assert.Contains(t, hdfs.users, "builder")
+
+	err := w.Push(ctx, filepath.Join(tmpDir, "data", "a.txt"), "artifacts/jobs/1/data/a.txt", backend.PushOptions{})
+	var alreadyExists *backend.ErrAlreadyExists
+	assert.ErrorAs(t, err, &alreadyExists)
+	require.NoError(t, w.Push(ctx, filepath.Join(tmpDir, "data", "a.txt"), "artifacts/jobs/1/data/a.txt", backend.PushOptions{Force: true}))
+
+	exists, err := w.Exists(ctx, "artifacts/jobs/1/data/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	checksum, err := w.Checksum(ctx, "artifacts/jobs/1/data/a.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824", checksum)
+
+	// Directories are pulled file by file, without checksum sidecars
+	pulled := filepath.Join(tmpDir, "pulled")
+	require.NoError(t, w.Pull(ctx, "artifacts/jobs/1", pulled, backend.PullOptions{}))
+	data, err := os.ReadFile(filepath.Join(pulled, "data", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+	assert.FileExists(t, filepath.Join(pulled, "data", "sub", "empty.txt"))
+	assert.NoDirExists(t, filepath.Join(pulled, ".checksums"))
+
+	require.NoError(t, w.Yank(ctx, "artifacts/jobs/1/data"))
+	assert.Empty(t, hdfs.files)
+
+	err = w.Pull(ctx, "artifacts/jobs/1/data/a.txt", filepath.Join(tmpDir, "missing.txt"), backend.PullOptions{})
+	var notFound *backend.ErrNotFound
+	assert.ErrorAs(t, err, &notFound)
+}
+
+func TestWebHDFSBackend_List(t *testing.T) {
+	w, hdfs := createTestWebHDFSBackend(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"a/x.txt", "a-b.txt", "ab/y.txt", "b.txt", ".checksums/b.txt.sha256"} {
+		hdfs.files["/ci/artifacts/jobs/1/"+name] = []byte(name)
+	}
+
+	list := func(prefix string) []string {
+		paths := []string{}
+		require.NoError(t, w.List(ctx, prefix, func(obj backend.ObjectInfo) error {
+			paths = append(paths, obj.Path)
+			return nil
+		}))
+		return paths
+	}
+
+	assert.Equal(t, []string{
+		"artifacts/jobs/1/a-b.txt",
+		"artifacts/jobs/1/a/x.txt",
+		"artifacts/jobs/1/ab/y.txt",
+		"artifacts/jobs/1/b.txt",
+	}, list("artifacts/jobs/1/"))
+	assert.Equal(t, []string{"artifacts/jobs/1/a-b.txt", "artifacts/jobs/1/a/x.txt", "artifacts/jobs/1/ab/y.txt"}, list("artifacts/jobs/1/a"))
+	assert.Equal(t, []string{"artifacts/jobs/1/a/x.txt"}, list("artifacts/jobs/1/a/"))
+	assert.Empty(t, list("artifacts/jobs/2/"))
+}
+
+func TestWebHDFSBackend_Errors(t *testing.T) {
+	w, _ := createTestWebHDFSBackend(t)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		remoteError(rw, http.StatusForbidden, "AccessControlException", "Permission denied: user=builder, access=WRITE")
+	}))
+	defer server.Close()
+	w.cfg.URL = server.URL
+
+	err := w.Yank(context.Background(), "artifacts/jobs/1/a.txt")
+	var denied *backend.ErrPermissionDenied
+	require.ErrorAs(t, err, &denied)
+	assert.Contains(t, denied.Reason, "Permission denied: user=builder")
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, (&Config{URL: "http://namenode:9870"}).Validate())
+	assert.NoError(t, (&Config{URL: "https://namenode:9871", Kerberos: true, Keytab: "ci.keytab", Principal: "ci@EXAMPLE.COM"}).Validate())
+
+	assert.Error(t, (&Config{}).Validate())
+	assert.Error(t, (&Config{URL: "namenode:9870"}).Validate())
+	assert.Error(t, (&Config{URL: "http://namenode:9870", Keytab: "ci.keytab"}).Validate())
+	assert.Error(t, (&Config{URL: "http://namenode:9870", Keytab: "ci.keytab", Principal: "ci"}).Validate())
+}
@@ -0,0 +1,129 @@
+// Package webhdfsbackend implements the Backend interface with the WebHDFS
+// REST API, storing artifacts straight in a Hadoop cluster. It authenticates
+// with simple authentication (user.name) or, on secured clusters, Kerberos
+// over SPNEGO.
+package webhdfsbackend
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// Config holds WebHDFS backend configuration.
+type Config struct {
+	// URL is the NameNode's HTTP address (required), e.g. http://namenode:9870
+	URL string
+
+	// Prefix is the HDFS directory artifacts are stored under, / by default
+	Prefix string
+
+	// User is sent as user.name with simple authentication
+	User string
+
+	// Kerberos enables SPNEGO authentication instead of simple authentication
+	Kerberos bool
+
+	// Keytab and Principal (user@REALM) log in to Kerberos. Without them,
+	// the credential cache of kinit is used.
+	Keytab    string
+	Principal string
+}
+
+// LoadConfig loads WebHDFS configuration from environment variables and config file.
+// Environment variables take precedence over config file values.
+//
+// Environment variables:
+//   - ARTIFACT_WEBHDFS_URL (required)
+//   - ARTIFACT_WEBHDFS_PREFIX (optional)
+//   - ARTIFACT_WEBHDFS_USER (optional, defaults to HADOOP_USER_NAME or the current user)
+//   - ARTIFACT_WEBHDFS_KERBEROS (optional, "true" to enable)
+//   - ARTIFACT_WEBHDFS_KEYTAB, ARTIFACT_WEBHDFS_PRINCIPAL (optional)
+//
+// Config file keys (under 'webhdfs' section):
+//   - url, prefix, user, kerberos, keytab, principal
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+
+	// Load from environment variables first
+	cfg.URL = os.Getenv("ARTIFACT_WEBHDFS_URL")
+	cfg.Prefix = os.Getenv("ARTIFACT_WEBHDFS_PREFIX")
+	cfg.User = os.Getenv("ARTIFACT_WEBHDFS_USER")
+	cfg.Kerberos = os.Getenv("ARTIFACT_WEBHDFS_KERBEROS") == "true"
+	cfg.Keytab = os.Getenv("ARTIFACT_WEBHDFS_KEYTAB")
+	cfg.Principal = os.Getenv("ARTIFACT_WEBHDFS_PRINCIPAL")
+
+	// Fall back to config file for unset values
+	if cfg.URL == "" {
+		cfg.URL = viper.GetString("webhdfs.url")
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = viper.GetString("webhdfs.prefix")
+	}
+	if cfg.User == "" {
+		cfg.User = viper.GetString("webhdfs.user")
+	}
+	if !cfg.Kerberos {
+		cfg.Kerberos = viper.GetBool("webhdfs.kerberos")
+	}
+	if cfg.Keytab == "" {
+		cfg.Keytab = viper.GetString("webhdfs.keytab")
+	}
+	if cfg.Principal == "" {
+		cfg.Principal = viper.GetString("webhdfs.principal")
+	}
+
+	// Hadoop clients default to the same user
+	if cfg.User == "" {
+		cfg.User = os.Getenv("HADOOP_USER_NAME")
+	}
+	if cfg.User == "" {
+		if current, err := user.Current(); err == nil {
+			cfg.User = current.Username
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate checks that the configuration is valid.
+func (c *Config) Validate() error {
+	if c.URL == "" {
+		return fmt.Errorf("WebHDFS URL is required: set ARTIFACT_WEBHDFS_URL environment variable or webhdfs.url in config")
+	}
+
+	if !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("WebHDFS URL '%s' must start with http:// or https://", c.URL)
+	}
+
+	if (c.Keytab == "") != (c.Principal == "") {
+		return fmt.Errorf("WebHDFS keytab and principal must be set together")
+	}
+
+	if _, _, err := c.splitPrincipal(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// splitPrincipal splits Principal into its user and realm.
+func (c *Config) splitPrincipal() (string, string, error) {
+	if c.Principal == "" {
+		return "", "", nil
+	}
+
+	name, realm, ok := strings.Cut(c.Principal, "@")
+	if !ok || name == "" || realm == "" {
+		return "", "", fmt.Errorf("invalid Kerberos principal '%s': use user@REALM", c.Principal)
+	}
+
+	return name, realm, nil
+}
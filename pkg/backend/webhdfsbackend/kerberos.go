@@ -0,0 +1,62 @@
+package webhdfsbackend
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	krb5client "github.com/jcmturner/gokrb5/v8/client"
+	krb5config "github.com/jcmturner/gokrb5/v8/config"
+	"github.com/jcmturner/gokrb5/v8/credentials"
+	"github.com/jcmturner/gokrb5/v8/keytab"
+	"github.com/jcmturner/gokrb5/v8/spnego"
+)
+
+// newSPNEGOClient wraps httpClient with SPNEGO authentication, logging in with
+// the configured keytab or the credential cache. Kerberos is configured in
+// KRB5_CONFIG or /etc/krb5.conf, and the cache is found in KRB5CCNAME or
+// /tmp/krb5cc_<uid>, like kinit does.
+func newSPNEGOClient(cfg *Config, httpClient *http.Client) (*spnego.Client, error) {
+	krb5ConfigPath := os.Getenv("KRB5_CONFIG")
+	if krb5ConfigPath == "" {
+		krb5ConfigPath = "/etc/krb5.conf"
+	}
+
+	krb5Config, err := krb5config.Load(krb5ConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Kerberos config '%s': %w", krb5ConfigPath, err)
+	}
+
+	var client *krb5client.Client
+	if cfg.Keytab != "" {
+		kt, err := keytab.Load(cfg.Keytab)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keytab '%s': %w", cfg.Keytab, err)
+		}
+
+		name, realm, _ := cfg.splitPrincipal()
+		client = krb5client.NewWithKeytab(name, realm, kt, krb5Config, krb5client.DisablePAFXFAST(true))
+	} else {
+		cachePath := strings.TrimPrefix(os.Getenv("KRB5CCNAME"), "FILE:")
+		if cachePath == "" {
+			cachePath = fmt.Sprintf("/tmp/krb5cc_%d", os.Getuid())
+		}
+
+		cache, err := credentials.LoadCCache(cachePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kerberos credential cache '%s', run kinit or set a keytab: %w", cachePath, err)
+		}
+
+		client, err = krb5client.NewFromCCache(cache, krb5Config, krb5client.DisablePAFXFAST(true))
+		if err != nil {
+			return nil, fmt.Errorf("failed to use Kerberos credential cache '%s': %w", cachePath, err)
+		}
+	}
+
+	if err := client.Login(); err != nil {
+		return nil, fmt.Errorf("failed to log in to Kerberos: %w", err)
+	}
+
+	return spnego.NewClient(client, httpClient, ""), nil
+}
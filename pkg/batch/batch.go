@@ -0,0 +1,138 @@
+// Package batch reads manifests describing several pushes, pulls and
+// yanks, so they can run with one backend connection and one summary
+// instead of one artifact invocation each:
+//
+//	category: workflow
+//	operations:
+//	  - push: build/app.tar.gz
+//	    destination: releases/app.tar.gz
+//	    force: true
+//	  - pull: reports/
+//	    category: job
+//	    id: 4b7a...
+//	  - yank: tmp/
+package batch
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+
+	"github.com/semaphoreci/artifact/pkg/files"
+	"gopkg.in/yaml.v3"
+)
+
+// Operation is a push, pull or yank of one path, with its options.
+type Operation struct {
+	Push        string            `yaml:"push"`        // local file or directory to push
+	Pull        string            `yaml:"pull"`        // stored file or directory to pull
+	Yank        string            `yaml:"yank"`        // stored file or directory to yank
+	Category    string            `yaml:"category"`    // job, workflow or project; the manifest's by default
+	ID          string            `yaml:"id"`          // ID of the job, workflow or project; the environment's by default
+	Destination string            `yaml:"destination"` // where pushes and pulls are stored
+	Force       bool              `yaml:"force"`       // overwrite existing files
+	IfChanged   bool              `yaml:"ifChanged"`   // only push files that differ from the stored ones
+	Metadata    map[string]string `yaml:"metadata"`    // metadata set on pushed files
+
+	// Kind is push, pull or yank, and Path the path it is given.
+	Kind string `yaml:"-"`
+	Path string `yaml:"-"`
+}
+
+// Manifest is an ordered list of operations.
+type Manifest struct {
+	Category   string       `yaml:"category"` // default category of the operations, job if not set
+	Operations []*Operation `yaml:"operations"`
+}
+
+// LoadFile reads a manifest from a YAML file. Unknown keys are rejected,
+// since a misspelled option would otherwise be silently ignored.
+func LoadFile(filePath string) (*Manifest, error) {
+	// #nosec
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest '%s': %v", filePath, err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	m := &Manifest{}
+	if err := decoder.Decode(m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest '%s': %v", filePath, err)
+	}
+
+	if err := m.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest '%s': %v", filePath, err)
+	}
+
+	return m, nil
+}
+
+// Validate checks every operation and fills in its kind, path and category.
+func (m *Manifest) Validate() error {
+	if m.Category == "" {
+		m.Category = files.ResourceTypeJob
+	}
+
+	if err := validateCategory(m.Category); err != nil {
+		return err
+	}
+
+	if len(m.Operations) == 0 {
+		return fmt.Errorf("no operations")
+	}
+
+	for i, op := range m.Operations {
+		if op == nil {
+			return fmt.Errorf("operation %d is empty", i+1)
+		}
+
+		if op.Category == "" {
+			op.Category = m.Category
+		}
+
+		if err := op.validate(); err != nil {
+			return fmt.Errorf("operation %d: %v", i+1, err)
+		}
+	}
+
+	return nil
+}
+
+func (op *Operation) validate() error {
+	kinds := 0
+	for kind, path := range map[string]string{files.OperationPush: op.Push, files.OperationPull: op.Pull, files.OperationYank: op.Yank} {
+		if path != "" {
+			op.Kind, op.Path = kind, path
+			kinds++
+		}
+	}
+
+	if kinds != 1 {
+		return fmt.Errorf("use exactly one of push, pull or yank")
+	}
+
+	if err := validateCategory(op.Category); err != nil {
+		return err
+	}
+
+	if op.Kind == files.OperationYank && (op.Destination != "" || op.Force) {
+		return fmt.Errorf("yanks take no destination or force")
+	}
+
+	if op.Kind != files.OperationPush && (op.IfChanged || len(op.Metadata) > 0) {
+		return fmt.Errorf("ifChanged and metadata only apply to pushes")
+	}
+
+	return nil
+}
+
+func validateCategory(category string) error {
+	switch category {
+	case files.ResourceTypeJob, files.ResourceTypeWorkflow, files.ResourceTypeProject:
+		return nil
+	default:
+		return fmt.Errorf("unknown category '%s': use job, workflow or project", category)
+	}
+}
@@ -0,0 +1,74 @@
+package batch
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testManifest = `category: workflow
+operations:
+  - push: build/app.tar.gz
+    destination: releases/app.tar.gz
+    force: true
+    metadata:
+      version: "1.2"
+  - pull: reports/
+    category: job
+    id: "42"
+  - yank: tmp/
+`
+
+func Test__LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ops.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(testManifest), 0600))
+
+	m, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, m.Operations, 3)
+
+	assert.Equal(t, "push", m.Operations[0].Kind)
+	assert.Equal(t, "build/app.tar.gz", m.Operations[0].Path)
+	assert.Equal(t, "workflow", m.Operations[0].Category)
+	assert.Equal(t, map[string]string{"version": "1.2"}, m.Operations[0].Metadata)
+
+	assert.Equal(t, "pull", m.Operations[1].Kind)
+	assert.Equal(t, "job", m.Operations[1].Category)
+	assert.Equal(t, "42", m.Operations[1].ID)
+
+	assert.Equal(t, "yank", m.Operations[2].Kind)
+	assert.Equal(t, "workflow", m.Operations[2].Category)
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.yaml"))
+	assert.ErrorContains(t, err, "failed to read manifest")
+
+	require.NoError(t, os.WriteFile(path, []byte("operations:\n  - push: a\n    forse: true\n"), 0600))
+	_, err = LoadFile(path)
+	assert.ErrorContains(t, err, "field forse not found")
+}
+
+func Test__Validate(t *testing.T) {
+	testCases := []struct {
+		manifest Manifest
+		err      string
+	}{
+		{Manifest{}, "no operations"},
+		{Manifest{Category: "team", Operations: []*Operation{{Push: "a"}}}, "unknown category 'team': use job, workflow or project"},
+		{Manifest{Operations: []*Operation{{}}}, "operation 1: use exactly one of push, pull or yank"},
+		{Manifest{Operations: []*Operation{{Push: "a", Pull: "b"}}}, "operation 1: use exactly one of push, pull or yank"},
+		{Manifest{Operations: []*Operation{{Push: "a"}, {Yank: "b", Force: true}}}, "operation 2: yanks take no destination or force"},
+		{Manifest{Operations: []*Operation{{Pull: "a", IfChanged: true}}}, "operation 1: ifChanged and metadata only apply to pushes"},
+		{Manifest{Operations: []*Operation{{Pull: "a", Category: "team"}}}, "operation 1: unknown category 'team': use job, workflow or project"},
+	}
+
+	for _, tc := range testCases {
+		assert.EqualError(t, tc.manifest.Validate(), tc.err)
+	}
+
+	m := Manifest{Operations: []*Operation{{Pull: "a"}}}
+	require.NoError(t, m.Validate())
+	assert.Equal(t, "job", m.Operations[0].Category)
+}
@@ -0,0 +1,489 @@
+// Package browse implements the terminal UI of 'artifact browse', which
+// navigates the artifact stores of a job, workflow and project, previews
+// small text files and pulls or yanks the selected files.
+package browse
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/common"
+)
+
+// PreviewLimit is the size up to which text files are previewed.
+const PreviewLimit = 64 * 1024
+
+// Store is the artifact store of a job, workflow or project.
+type Store struct {
+	Category string // job, workflow or project
+	ID       string
+}
+
+func (s Store) String() string {
+	return fmt.Sprintf("%s %s", s.Category, s.ID)
+}
+
+// Actions are the backend operations the browser triggers. Names are
+// relative to the store.
+type Actions interface {
+	// List returns every file of the store, with paths relative to it.
+	List(ctx context.Context, store Store) ([]backend.ObjectInfo, error)
+
+	// Read returns up to limit bytes of a file.
+	Read(ctx context.Context, store Store, name string, limit int64) ([]byte, error)
+
+	// Pull downloads a file or directory, and returns where to.
+	Pull(ctx context.Context, store Store, name string) (string, error)
+
+	// Yank deletes a file or directory.
+	Yank(ctx context.Context, store Store, name string) error
+}
+
+type mode int
+
+const (
+	modeStores mode = iota
+	modeList
+	modePreview
+	modeConfirm
+)
+
+type listedMsg struct {
+	store   Store
+	objects []backend.ObjectInfo
+	err     error
+}
+
+type previewMsg struct {
+	name string
+	data []byte
+	err  error
+}
+
+type doneMsg struct {
+	status string
+	yanked bool
+}
+
+// Model is the bubbletea model of the browser.
+type Model struct {
+	ctx     context.Context
+	actions Actions
+	stores  []Store
+
+	mode    mode
+	store   Store
+	root    *node
+	dir     *node
+	cursor  int
+	marked  map[string]bool
+	preview []string
+	offset  int
+	status  string
+	busy    bool
+	height  int
+}
+
+// New returns a browser of stores. With a single store, it is opened
+// right away.
+func New(ctx context.Context, actions Actions, stores []Store) *Model {
+	return &Model{ctx: ctx, actions: actions, stores: stores, marked: map[string]bool{}, height: 24}
+}
+
+func (m *Model) Init() tea.Cmd {
+	if len(m.stores) == 1 {
+		return m.open(m.stores[0])
+	}
+
+	return nil
+}
+
+func (m *Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.height = msg.Height
+		return m, nil
+
+	case listedMsg:
+		m.busy = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error listing %s: %v", msg.store, msg.err)
+			return m, nil
+		}
+
+		// Stay in the same directory when refreshing
+		current := ""
+		if m.dir != nil {
+			current = m.dir.Name
+		} else {
+			m.cursor = 0
+		}
+
+		m.mode, m.store = modeList, msg.store
+		m.root = buildTree(msg.objects)
+		m.dir = m.root.find(current)
+		m.marked = map[string]bool{}
+		m.moveCursor(0, len(m.dir.children))
+		return m, nil
+
+	case previewMsg:
+		m.busy = false
+		if msg.err != nil {
+			m.status = fmt.Sprintf("Error reading '%s': %v", msg.name, msg.err)
+			return m, nil
+		}
+
+		m.mode, m.offset = modePreview, 0
+		m.preview = previewLines(msg.data)
+		return m, nil
+
+	case doneMsg:
+		m.busy = false
+		m.marked = map[string]bool{}
+		if !msg.yanked {
+			m.status = msg.status
+			return m, nil
+		}
+
+		// Yanked files are gone from the listing once it is refreshed
+		m.status = msg.status
+		return m, m.open(m.store)
+
+	case tea.KeyMsg:
+		return m.handleKey(msg.String())
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleKey(key string) (tea.Model, tea.Cmd) {
+	if key == "ctrl+c" || (key == "q" && m.mode != modeConfirm) {
+		return m, tea.Quit
+	}
+
+	// Keys are ignored while an operation runs, so it cannot be triggered twice
+	if m.busy {
+		return m, nil
+	}
+
+	switch m.mode {
+	case modeStores:
+		return m.handleStoresKey(key)
+	case modePreview:
+		return m.handlePreviewKey(key)
+	case modeConfirm:
+		return m.handleConfirmKey(key)
+	default:
+		return m.handleListKey(key)
+	}
+}
+
+func (m *Model) handleStoresKey(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		m.moveCursor(-1, len(m.stores))
+	case "down", "j":
+		m.moveCursor(1, len(m.stores))
+	case "enter", "right", "l":
+		if len(m.stores) > 0 {
+			return m, m.open(m.stores[m.cursor])
+		}
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleListKey(key string) (tea.Model, tea.Cmd) {
+	entries := m.dir.children
+
+	switch key {
+	case "up", "k":
+		m.moveCursor(-1, len(entries))
+	case "down", "j":
+		m.moveCursor(1, len(entries))
+	case "enter", "right", "l":
+		if len(entries) == 0 {
+			return m, nil
+		}
+
+		entry := entries[m.cursor]
+		if entry.Dir {
+			m.dir, m.cursor = entry, 0
+			return m, nil
+		}
+
+		if entry.Size > PreviewLimit {
+			m.status = fmt.Sprintf("'%s' is too large to preview (%s); pull it instead.", entry.Name, common.FormatSize(entry.Size))
+			return m, nil
+		}
+
+		return m, m.run(func() tea.Msg {
+			data, err := m.actions.Read(m.ctx, m.store, entry.Name, PreviewLimit)
+			return previewMsg{name: entry.Name, data: data, err: err}
+		})
+	case "left", "h", "backspace", "esc":
+		m.leaveDir()
+	case " ":
+		if len(entries) > 0 {
+			name := entries[m.cursor].Name
+			m.marked[name] = !m.marked[name]
+			if !m.marked[name] {
+				delete(m.marked, name)
+			}
+			m.moveCursor(1, len(entries))
+		}
+	case "p":
+		return m, m.pull(m.selection())
+	case "d":
+		if len(m.selection()) > 0 {
+			m.mode = modeConfirm
+		}
+	case "r":
+		return m, m.open(m.store)
+	}
+
+	return m, nil
+}
+
+func (m *Model) handlePreviewKey(key string) (tea.Model, tea.Cmd) {
+	switch key {
+	case "up", "k":
+		if m.offset > 0 {
+			m.offset--
+		}
+	case "down", "j":
+		if m.offset < len(m.preview)-1 {
+			m.offset++
+		}
+	case "left", "h", "backspace", "esc", "enter":
+		m.mode = modeList
+	}
+
+	return m, nil
+}
+
+func (m *Model) handleConfirmKey(key string) (tea.Model, tea.Cmd) {
+	m.mode = modeList
+	if key != "y" {
+		m.status = "Nothing was yanked."
+		return m, nil
+	}
+
+	return m, m.yank(m.selection())
+}
+
+// leaveDir goes to the parent directory, or back to the list of stores
+// from the root of one.
+func (m *Model) leaveDir() {
+	if m.dir.parent != nil {
+		left := m.dir
+		m.dir = m.dir.parent
+		m.cursor = 0
+		for i, child := range m.dir.children {
+			if child == left {
+				m.cursor = i
+			}
+		}
+		return
+	}
+
+	if len(m.stores) > 1 {
+		m.mode, m.dir, m.cursor = modeStores, nil, 0
+	}
+}
+
+// selection returns the names of the marked entries, or else of the one
+// under the cursor.
+func (m *Model) selection() []string {
+	names := []string{}
+	for name := range m.marked {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 && len(m.dir.children) > 0 {
+		names = append(names, m.dir.children[m.cursor].Name)
+	}
+
+	return names
+}
+
+func (m *Model) open(store Store) tea.Cmd {
+	return m.run(func() tea.Msg {
+		objects, err := m.actions.List(m.ctx, store)
+		return listedMsg{store: store, objects: objects, err: err}
+	})
+}
+
+func (m *Model) pull(names []string) tea.Cmd {
+	if len(names) == 0 {
+		return nil
+	}
+
+	m.status = fmt.Sprintf("Pulling %d %s...", len(names), items(len(names)))
+	return m.run(func() tea.Msg {
+		destination := ""
+		for _, name := range names {
+			var err error
+			if destination, err = m.actions.Pull(m.ctx, m.store, name); err != nil {
+				return doneMsg{status: fmt.Sprintf("Error pulling '%s': %v", name, err)}
+			}
+		}
+
+		if len(names) == 1 {
+			return doneMsg{status: fmt.Sprintf("Pulled '%s' to '%s'.", names[0], destination)}
+		}
+		return doneMsg{status: fmt.Sprintf("Pulled %d items.", len(names))}
+	})
+}
+
+func (m *Model) yank(names []string) tea.Cmd {
+	m.status = fmt.Sprintf("Yanking %d %s...", len(names), items(len(names)))
+	return m.run(func() tea.Msg {
+		for i, name := range names {
+			if err := m.actions.Yank(m.ctx, m.store, name); err != nil {
+				return doneMsg{status: fmt.Sprintf("Error yanking '%s': %v", name, err), yanked: i > 0}
+			}
+		}
+
+		return doneMsg{status: fmt.Sprintf("Yanked %d %s.", len(names), items(len(names))), yanked: true}
+	})
+}
+
+func (m *Model) run(cmd func() tea.Msg) tea.Cmd {
+	m.busy = true
+	return cmd
+}
+
+// moveCursor moves the cursor by delta, within count entries.
+func (m *Model) moveCursor(delta, count int) {
+	m.cursor += delta
+	if m.cursor >= count {
+		m.cursor = count - 1
+	}
+	if m.cursor < 0 {
+		m.cursor = 0
+	}
+}
+
+func (m *Model) View() string {
+	b := &strings.Builder{}
+
+	switch m.mode {
+	case modeStores:
+		b.WriteString("Artifact stores\n\n")
+		for i, store := range m.stores {
+			fmt.Fprintf(b, "%s %s\n", cursor(i == m.cursor), store)
+		}
+		m.writeFooter(b, "↑/↓ move · enter open · q quit")
+
+	case modePreview:
+		fmt.Fprintf(b, "%s: %s\n\n", m.store, m.dir.children[m.cursor].Name)
+		for _, line := range window(m.preview, m.offset, m.rows()) {
+			b.WriteString(line + "\n")
+		}
+		m.writeFooter(b, "↑/↓ scroll · ← back · q quit")
+
+	default:
+		fmt.Fprintf(b, "%s: /%s\n\n", m.store, m.dir.Name)
+		if len(m.dir.children) == 0 {
+			b.WriteString("  (empty)\n")
+		}
+
+		first := 0
+		if m.cursor >= m.rows() {
+			first = m.cursor - m.rows() + 1
+		}
+
+		for i, entry := range window(m.dir.children, first, m.rows()) {
+			i += first
+			name := entry.baseName()
+			if entry.Dir {
+				name += "/"
+			}
+
+			mark := " "
+			if m.marked[entry.Name] {
+				mark = "*"
+			}
+
+			modTime := ""
+			if !entry.ModTime.IsZero() {
+				modTime = entry.ModTime.Local().Format("2006-01-02 15:04")
+			}
+
+			fmt.Fprintf(b, "%s%s %-40s %10s  %s\n", cursor(i == m.cursor), mark, name, common.FormatSize(entry.Size), modTime)
+		}
+
+		if m.mode == modeConfirm {
+			names := m.selection()
+			m.writeFooter(b, fmt.Sprintf("Yank %d %s? [y/N]", len(names), items(len(names))))
+		} else {
+			m.writeFooter(b, "↑/↓ move · enter open · ← back · space mark · p pull · d yank · r refresh · q quit")
+		}
+	}
+
+	return b.String()
+}
+
+func (m *Model) writeFooter(b *strings.Builder, help string) {
+	b.WriteString("\n")
+	if m.status != "" {
+		b.WriteString(m.status + "\n")
+	}
+	b.WriteString(help + "\n")
+}
+
+// rows returns how many entries fit between the header and the footer.
+func (m *Model) rows() int {
+	if rows := m.height - 6; rows > 0 {
+		return rows
+	}
+
+	return 1
+}
+
+// previewLines splits a text file into lines; binary files are not shown.
+func previewLines(data []byte) []string {
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return []string{"(binary file, not previewed)"}
+	}
+
+	text := strings.ReplaceAll(string(data), "\t", "    ")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+func window[T any](values []T, first, count int) []T {
+	if first > len(values) {
+		first = len(values)
+	}
+
+	last := first + count
+	if last > len(values) {
+		last = len(values)
+	}
+
+	return values[first:last]
+}
+
+func cursor(selected bool) string {
+	if selected {
+		return ">"
+	}
+
+	return " "
+}
+
+func items(count int) string {
+	if count == 1 {
+		return "item"
+	}
+
+	return "items"
+}
@@ -0,0 +1,181 @@
+package browse
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeActions struct {
+	files  map[string]string
+	pulled []string
+	yanked []string
+}
+
+func (f *fakeActions) List(ctx context.Context, store Store) ([]backend.ObjectInfo, error) {
+	objects := []backend.ObjectInfo{}
+	for name, contents := range f.files {
+		objects = append(objects, backend.ObjectInfo{Path: name, Size: int64(len(contents))})
+	}
+	return objects, nil
+}
+
+func (f *fakeActions) Read(ctx context.Context, store Store, name string, limit int64) ([]byte, error) {
+	return []byte(f.files[name]), nil
+}
+
+func (f *fakeActions) Pull(ctx context.Context, store Store, name string) (string, error) {
+	f.pulled = append(f.pulled, name)
+	return name, nil
+}
+
+func (f *fakeActions) Yank(ctx context.Context, store Store, name string) error {
+	if name == "locked.txt" {
+		return errors.New("denied")
+	}
+
+	f.yanked = append(f.yanked, name)
+	for file := range f.files {
+		if file == name || strings.HasPrefix(file, name+"/") {
+			delete(f.files, file)
+		}
+	}
+	return nil
+}
+
+// run feeds msg to the model, and the messages of the commands it
+// returns, until there are none left.
+func run(m *Model, msg tea.Msg) {
+	for msg != nil {
+		_, cmd := m.Update(msg)
+		if cmd == nil {
+			return
+		}
+		msg = cmd()
+	}
+}
+
+func press(m *Model, keys ...string) {
+	for _, key := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+		switch key {
+		case "enter":
+			msg = tea.KeyMsg{Type: tea.KeyEnter}
+		case "left":
+			msg = tea.KeyMsg{Type: tea.KeyLeft}
+		case " ":
+			msg = tea.KeyMsg{Type: tea.KeySpace}
+		}
+		run(m, msg)
+	}
+}
+
+func newTestModel(actions *fakeActions, stores ...Store) *Model {
+	m := New(context.Background(), actions, stores)
+	if cmd := m.Init(); cmd != nil {
+		run(m, cmd())
+	}
+	return m
+}
+
+func Test__Browse_Navigate(t *testing.T) {
+	actions := &fakeActions{files: map[string]string{
+		"app.zip":          "zip",
+		"logs/app.log":     "started\nlistening\n",
+		"logs/binary.dump": "\x00\x01",
+	}}
+	m := newTestModel(actions, Store{Category: "job", ID: "1"})
+
+	// Directories come first
+	view := m.View()
+	assert.Contains(t, view, "job 1: /")
+	assert.Less(t, strings.Index(view, "logs/"), strings.Index(view, "app.zip"))
+
+	press(m, "enter")
+	assert.Contains(t, m.View(), "job 1: /logs")
+
+	press(m, "enter")
+	assert.Contains(t, m.View(), "job 1: logs/app.log")
+	assert.Contains(t, m.View(), "listening")
+
+	press(m, "left", "j", "enter")
+	assert.Contains(t, m.View(), "(binary file, not previewed)")
+
+	press(m, "left", "left")
+	assert.Contains(t, m.View(), "job 1: /\n")
+}
+
+func Test__Browse_Stores(t *testing.T) {
+	actions := &fakeActions{files: map[string]string{"a.txt": "a"}}
+	m := newTestModel(actions, Store{Category: "job", ID: "1"}, Store{Category: "project", ID: "p"})
+
+	assert.Contains(t, m.View(), "> job 1")
+	press(m, "j", "enter")
+	assert.Contains(t, m.View(), "project p: /")
+
+	press(m, "left")
+	assert.Contains(t, m.View(), "Artifact stores")
+}
+
+func Test__Browse_PullAndYank(t *testing.T) {
+	actions := &fakeActions{files: map[string]string{
+		"a.txt":      "a",
+		"b.txt":      "b",
+		"locked.txt": "l",
+		"logs/x.log": "x",
+	}}
+	m := newTestModel(actions, Store{Category: "job", ID: "1"})
+
+	// Without marks, the entry under the cursor is pulled
+	press(m, "p")
+	assert.Equal(t, []string{"logs"}, actions.pulled)
+	assert.Contains(t, m.View(), "Pulled 'logs' to 'logs'.")
+
+	press(m, "j", " ", " ", "p")
+	assert.Equal(t, []string{"logs", "a.txt", "b.txt"}, actions.pulled)
+
+	// Marks are cleared once used; yanking asks for confirmation
+	press(m, "k", "k", " ", " ", "d")
+	assert.Contains(t, m.View(), "Yank 2 items? [y/N]")
+	press(m, "n")
+	assert.Empty(t, actions.yanked)
+	assert.Contains(t, m.View(), "Nothing was yanked.")
+
+	press(m, "d", "y")
+	assert.Equal(t, []string{"a.txt", "b.txt"}, actions.yanked)
+	assert.Contains(t, m.View(), "Yanked 2 items.")
+	assert.NotContains(t, m.View(), "a.txt")
+
+	// Failures are shown, and the file is kept
+	press(m, "d", "y")
+	assert.Contains(t, m.View(), "Error yanking 'locked.txt': denied")
+	require.Contains(t, m.View(), "locked.txt")
+}
+
+func Test__BuildTree(t *testing.T) {
+	root := buildTree([]backend.ObjectInfo{
+		{Path: "a/b/c.txt", Size: 1},
+		{Path: "a/d.txt", Size: 2},
+		{Path: "e.txt", Size: 4},
+	})
+
+	require.Len(t, root.children, 2)
+	assert.Equal(t, int64(7), root.Size)
+
+	a := root.children[0]
+	assert.True(t, a.Dir)
+	assert.Equal(t, "a", a.Name)
+	assert.Equal(t, int64(3), a.Size)
+	assert.Equal(t, "a/b", a.children[0].Name)
+	assert.Equal(t, "d.txt", a.children[1].baseName())
+
+	assert.Equal(t, a.children[0], root.find("a/b"))
+	assert.Equal(t, a, root.find("a/gone"))
+	assert.Equal(t, root, root.find(""))
+}
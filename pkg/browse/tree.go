@@ -0,0 +1,94 @@
+package browse
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+)
+
+// node is a file or directory of a store. Directories only exist as
+// prefixes of the stored files, so their size and modification time are
+// those of the files they contain.
+type node struct {
+	Name     string // path relative to the store, e.g. logs/app.log
+	Dir      bool
+	Size     int64
+	ModTime  time.Time
+	parent   *node
+	children []*node
+}
+
+// baseName returns the last segment of the node's name.
+func (n *node) baseName() string {
+	return n.Name[strings.LastIndex(n.Name, "/")+1:]
+}
+
+// buildTree arranges the files of a store, named relative to it, into
+// directories sorted with subdirectories first.
+func buildTree(objects []backend.ObjectInfo) *node {
+	root := &node{Dir: true}
+	dirs := map[string]*node{"": root}
+
+	for _, obj := range objects {
+		parent := root
+		segments := strings.Split(obj.Path, "/")
+		for i := range segments[:len(segments)-1] {
+			name := strings.Join(segments[:i+1], "/")
+			dir, ok := dirs[name]
+			if !ok {
+				dir = &node{Name: name, Dir: true, parent: parent}
+				parent.children = append(parent.children, dir)
+				dirs[name] = dir
+			}
+			parent = dir
+		}
+
+		parent.children = append(parent.children, &node{
+			Name:    obj.Path,
+			Size:    obj.Size,
+			ModTime: obj.ModTime,
+			parent:  parent,
+		})
+
+		for dir := parent; dir != nil; dir = dir.parent {
+			dir.Size += obj.Size
+			if obj.ModTime.After(dir.ModTime) {
+				dir.ModTime = obj.ModTime
+			}
+		}
+	}
+
+	for _, dir := range dirs {
+		sort.Slice(dir.children, func(i, j int) bool {
+			a, b := dir.children[i], dir.children[j]
+			if a.Dir != b.Dir {
+				return a.Dir
+			}
+			return a.Name < b.Name
+		})
+	}
+
+	return root
+}
+
+// find returns the directory named name, or its closest existing parent,
+// e.g. after the directory was yanked.
+func (n *node) find(name string) *node {
+	for _, child := range n.children {
+		if !child.Dir {
+			continue
+		}
+
+		if child.Name == name {
+			return child
+		}
+
+		if strings.HasPrefix(name, child.Name+"/") {
+			return child.find(name)
+		}
+	}
+
+	return n
+}
@@ -0,0 +1,158 @@
+// Package cache names and finds the entries stored by 'artifact cache'.
+//
+// An entry is a tar.gz archive of a local file or directory, stored in the
+// project store at .cache/<key>/<escaped local path>.tar.gz, so the path it
+// is restored to is known without reading the archive.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/files"
+)
+
+// Dir is the directory of the project store holding cache entries.
+const Dir = ".cache"
+
+// Entry is a cache entry found in the project store.
+type Entry struct {
+	Key       string    // Key the entry was stored with
+	LocalPath string    // Slash-separated local path the entry was stored from
+	Path      string    // Path of the archive, relative to the project store
+	ModTime   time.Time // When the entry was stored
+}
+
+// keyFuncs are the functions available in key templates.
+var keyFuncs = template.FuncMap{
+	"checksum": checksum,
+	"env":      os.Getenv,
+	"os":       func() string { return runtime.GOOS },
+	"arch":     func() string { return runtime.GOARCH },
+}
+
+// RenderKey expands a key template, e.g. 'gems-{{ checksum "Gemfile.lock" }}',
+// and normalizes the result: slashes and whitespace become dashes, so
+// branch names can be used in keys.
+func RenderKey(text string) (string, error) {
+	t, err := template.New("key").Funcs(keyFuncs).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid cache key '%s': %v", text, err)
+	}
+
+	var b strings.Builder
+	if err := t.Execute(&b, nil); err != nil {
+		return "", fmt.Errorf("invalid cache key '%s': %v", text, err)
+	}
+
+	key := strings.Join(strings.Fields(strings.ReplaceAll(b.String(), "/", "-")), "-")
+	if key == "" || key == "." || key == ".." {
+		return "", fmt.Errorf("cache key '%s' is empty", text)
+	}
+
+	return key, nil
+}
+
+// RenderKeys expands a comma-separated list of key templates, in order.
+func RenderKeys(list string) ([]string, error) {
+	keys := []string{}
+	for _, text := range strings.Split(list, ",") {
+		key, err := RenderKey(text)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, nil
+}
+
+// ObjectName returns the path, relative to the project store, of the
+// entry storing localPath under key.
+func ObjectName(key, localPath string) string {
+	name := url.PathEscape(filepath.ToSlash(filepath.Clean(localPath)))
+	return path.Join(Dir, key, name+"."+files.ArchiveTarGz)
+}
+
+// ParseObjectName returns the entry stored at name, a path relative to the
+// project store, or false if it is not a cache entry.
+func ParseObjectName(name string) (*Entry, bool) {
+	rest := strings.TrimPrefix(name, Dir+"/")
+	key, escaped, ok := strings.Cut(rest, "/")
+	if rest == name || !ok || key == "" || strings.Contains(escaped, "/") {
+		return nil, false
+	}
+
+	escaped, ok = strings.CutSuffix(escaped, "."+files.ArchiveTarGz)
+	if !ok {
+		return nil, false
+	}
+
+	localPath, err := url.PathUnescape(escaped)
+	if err != nil || localPath == "" {
+		return nil, false
+	}
+
+	return &Entry{Key: key, LocalPath: localPath, Path: name}, true
+}
+
+// Match returns the entry to restore for key: the one stored with exactly
+// that key, or else the most recently stored one whose key starts with it.
+func Match(entries []Entry, key string) (*Entry, bool) {
+	candidates := []Entry{}
+	for _, e := range entries {
+		if e.Key == key {
+			return &e, true
+		}
+
+		if strings.HasPrefix(e.Key, key) {
+			candidates = append(candidates, e)
+		}
+	}
+
+	if len(candidates) == 0 {
+		return nil, false
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].ModTime.After(candidates[j].ModTime)
+	})
+
+	return &candidates[0], true
+}
+
+// checksum returns the SHA256 checksum of a file, or of the checksums of
+// several files, e.g. every lockfile of a monorepo.
+func checksum(filenames ...string) (string, error) {
+	if len(filenames) == 0 {
+		return "", fmt.Errorf("checksum needs at least one file")
+	}
+
+	sums := make([]string, 0, len(filenames))
+	for _, filename := range filenames {
+		sum, err := files.SHA256File(filename)
+		if err != nil {
+			return "", err
+		}
+
+		sums = append(sums, sum)
+	}
+
+	if len(sums) == 1 {
+		return sums[0], nil
+	}
+
+	h := sha256.Sum256([]byte(strings.Join(sums, "\n")))
+	return hex.EncodeToString(h[:]), nil
+}
@@ -0,0 +1,76 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__RenderKey(t *testing.T) {
+	dir := t.TempDir()
+	lockfile := filepath.Join(dir, "Gemfile.lock")
+	require.NoError(t, os.WriteFile(lockfile, []byte("rake"), 0600))
+	t.Setenv("SEMAPHORE_GIT_BRANCH", "feature/x y")
+
+	key, err := RenderKey(`gems-{{ checksum "` + lockfile + `" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "gems-a1b74cc9c5d0ff8cd313f0ecfc76b54e2dfa670e2fd20eda46ee998f0ab68712", key)
+
+	key, err = RenderKey(`gems-{{ env "SEMAPHORE_GIT_BRANCH" }}`)
+	require.NoError(t, err)
+	assert.Equal(t, "gems-feature-x-y", key)
+
+	keys, err := RenderKeys(`a-{{ checksum "` + lockfile + `" "` + lockfile + `" }},a-`)
+	require.NoError(t, err)
+	assert.Len(t, keys, 2)
+	assert.NotEqual(t, "a-a1b74cc9c5d0ff8cd313f0ecfc76b54e2dfa670e2fd20eda46ee998f0ab68712", keys[0])
+	assert.Equal(t, "a-", keys[1])
+
+	_, err = RenderKey(`gems-{{ checksum "missing.lock" }}`)
+	assert.ErrorContains(t, err, "missing.lock")
+
+	_, err = RenderKey(" ")
+	assert.ErrorContains(t, err, "is empty")
+
+	_, err = RenderKey("{{ nope }}")
+	assert.ErrorContains(t, err, "invalid cache key")
+}
+
+func Test__ObjectName(t *testing.T) {
+	name := ObjectName("gems-1", "vendor/bundle/")
+	assert.Equal(t, ".cache/gems-1/vendor%2Fbundle.tar.gz", name)
+
+	entry, ok := ParseObjectName(name)
+	require.True(t, ok)
+	assert.Equal(t, "gems-1", entry.Key)
+	assert.Equal(t, "vendor/bundle", entry.LocalPath)
+
+	for _, name := range []string{"app.zip", ".cache/gems-1", ".cache/gems-1/a/b.tar.gz", ".cache/gems-1/b.zip"} {
+		_, ok := ParseObjectName(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func Test__Match(t *testing.T) {
+	now := time.Now()
+	entries := []Entry{
+		{Key: "gems-abc", ModTime: now.Add(-time.Hour)},
+		{Key: "gems-def", ModTime: now},
+		{Key: "gems", ModTime: now.Add(-2 * time.Hour)},
+	}
+
+	entry, ok := Match(entries, "gems")
+	require.True(t, ok)
+	assert.Equal(t, "gems", entry.Key)
+
+	entry, ok = Match(entries, "gems-")
+	require.True(t, ok)
+	assert.Equal(t, "gems-def", entry.Key)
+
+	_, ok = Match(entries, "node")
+	assert.False(t, ok)
+}
@@ -0,0 +1,260 @@
+// Package client is the Go API of artifact, for programs that push and
+// pull artifacts without running the CLI. It is a supported library API:
+// its exported names only change in a backwards compatible way.
+//
+//	c, err := client.New(client.WithStore(client.StoreWorkflow, ""))
+//	if err != nil {
+//		return err
+//	}
+//	defer c.Close()
+//
+//	err = c.Push(ctx, "dist/app.zip", "releases/app.zip", client.Force())
+//
+// Paths in stores are relative to the store, like in the CLI. The backend
+// is configured with the same environment variables as the CLI, e.g.
+// ARTIFACT_BACKEND, or given with WithBackend.
+//
+// Directories pushed by the CLI with --transactional, --cas, --pack or
+// --delta are stored in a layout only the CLI reads; pull them with it.
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+
+	// The backends New can create
+	_ "github.com/semaphoreci/artifact/pkg/backend/artifactorybackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/cachebackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/execbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/ftpbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/httpbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/hubbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/ipfsbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/mirrorbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/pluginbackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/rclonebackend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/s3backend"
+	_ "github.com/semaphoreci/artifact/pkg/backend/webhdfsbackend"
+)
+
+// The stores a Client reads and writes.
+const (
+	StoreJob      = files.ResourceTypeJob
+	StoreWorkflow = files.ResourceTypeWorkflow
+	StoreProject  = files.ResourceTypeProject
+)
+
+// Object describes a stored file. Its Path is relative to the store.
+type Object = backend.ObjectInfo
+
+// Errors returned by a Client, to be checked with errors.As.
+type (
+	// ErrNotFound is returned when a file does not exist in the store.
+	ErrNotFound = backend.ErrNotFound
+
+	// ErrAlreadyExists is returned when a push or pull would overwrite a
+	// file without Force.
+	ErrAlreadyExists = backend.ErrAlreadyExists
+
+	// ErrReadOnly is returned when writing to a read-only backend.
+	ErrReadOnly = backend.ErrReadOnly
+)
+
+// ErrListingNotSupported is returned by List, and by Stat on some backends,
+// when the backend cannot list stored files.
+var ErrListingNotSupported = backend.ErrListingNotSupported
+
+// StopListing is returned by a List callback to end the listing early.
+var StopListing = backend.StopListing
+
+// Client pushes, pulls, yanks and lists the files of a store.
+// It is safe for concurrent use.
+type Client struct {
+	backend      backend.Backend
+	closeBackend bool
+	store        *files.PathResolver
+	concurrency  int
+}
+
+type options struct {
+	backend     backend.Backend
+	storeType   string
+	storeID     string
+	concurrency int
+}
+
+// Option configures a Client.
+type Option func(*options)
+
+// WithBackend makes the Client use b instead of the configured backend.
+// Close does not close it.
+func WithBackend(b backend.Backend) Option {
+	return func(o *options) { o.backend = b }
+}
+
+// WithStore sets the store the Client uses: StoreJob, the default,
+// StoreWorkflow or StoreProject, and its ID, "" for the one in
+// SEMAPHORE_JOB_ID, SEMAPHORE_WORKFLOW_ID or SEMAPHORE_PROJECT_ID.
+func WithStore(storeType, id string) Option {
+	return func(o *options) {
+		o.storeType = storeType
+		o.storeID = id
+	}
+}
+
+// WithConcurrency sets how many files of a directory are transferred at
+// once, backend.DefaultConcurrency by default.
+func WithConcurrency(n int) Option {
+	return func(o *options) { o.concurrency = n }
+}
+
+// New creates a Client for the store and backend set by opts.
+func New(opts ...Option) (*Client, error) {
+	o := &options{storeType: StoreJob}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.concurrency < 0 {
+		return nil, fmt.Errorf("invalid concurrency %d", o.concurrency)
+	}
+
+	store, err := files.NewPathResolver(o.storeType, o.storeID)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Client{backend: o.backend, store: store, concurrency: o.concurrency}
+	if c.backend == nil {
+		if c.backend, err = backend.NewBackend(); err != nil {
+			return nil, err
+		}
+		c.closeBackend = true
+	}
+
+	return c, nil
+}
+
+// Close releases the backend the Client created.
+func (c *Client) Close() error {
+	if !c.closeBackend {
+		return nil
+	}
+
+	return c.backend.Close()
+}
+
+// CallOption configures a single Push or Pull.
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	force    bool
+	metadata map[string]string
+	expireIn time.Duration
+}
+
+// Force overwrites the files a Push or Pull would otherwise fail on with
+// ErrAlreadyExists.
+func Force() CallOption {
+	return func(o *callOptions) { o.force = true }
+}
+
+// WithMetadata stores metadata with every pushed file. Pulls ignore it.
+func WithMetadata(metadata map[string]string) CallOption {
+	return func(o *callOptions) { o.metadata = metadata }
+}
+
+// ExpireIn deletes the pushed files after d, on backends that can expire
+// files. Pulls ignore it.
+func ExpireIn(d time.Duration) CallOption {
+	return func(o *callOptions) { o.expireIn = d }
+}
+
+func newCallOptions(opts []CallOption) *callOptions {
+	o := &callOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// Push uploads the local file or directory at localPath to remotePath in
+// the store, "" for the base name of localPath.
+func (c *Client) Push(ctx context.Context, localPath, remotePath string, opts ...CallOption) error {
+	o := newCallOptions(opts)
+	paths := c.store.Push(localPath, remotePath)
+
+	return c.backend.Push(ctx, paths.Source, paths.Destination, backend.PushOptions{
+		Force:       o.force,
+		Metadata:    o.metadata,
+		ExpireIn:    o.expireIn,
+		Concurrency: c.concurrency,
+	})
+}
+
+// Pull downloads the file or directory at remotePath in the store to
+// localPath, "" for the base name of remotePath.
+func (c *Client) Pull(ctx context.Context, remotePath, localPath string, opts ...CallOption) error {
+	o := newCallOptions(opts)
+	paths := c.store.Pull(remotePath, localPath)
+
+	return c.backend.Pull(ctx, paths.Source, paths.Destination, backend.PullOptions{
+		Force:       o.force,
+		Concurrency: c.concurrency,
+	})
+}
+
+// Yank deletes the file or directory at remotePath in the store.
+func (c *Client) Yank(ctx context.Context, remotePath string) error {
+	return c.backend.Yank(ctx, c.store.PrefixedPath(files.ToRelative(remotePath)))
+}
+
+// List calls fn for every file under remotePath in the store, "" for the
+// whole store, in path order. It returns ErrListingNotSupported if the
+// backend cannot list files, and stops early without an error if fn
+// returns StopListing.
+func (c *Client) List(ctx context.Context, remotePath string, fn func(Object) error) error {
+	lister, ok := c.backend.(backend.Lister)
+	if !ok || !backend.CapabilitiesOf(c.backend).List {
+		return ErrListingNotSupported
+	}
+
+	root := c.store.PrefixedPath("")
+	prefix := strings.TrimSuffix(c.store.PrefixedPath(files.ToRelative(remotePath)), "/")
+
+	err := lister.List(ctx, prefix, func(obj backend.ObjectInfo) error {
+		if obj.Path != prefix && !strings.HasPrefix(obj.Path, prefix+"/") || backend.IsChecksumSidecar(obj.Path) {
+			return nil
+		}
+
+		obj.Path = strings.TrimPrefix(strings.TrimPrefix(obj.Path, root), "/")
+		return fn(obj)
+	})
+
+	if errors.Is(err, backend.StopListing) {
+		return nil
+	}
+
+	return err
+}
+
+// Stat describes the file at remotePath in the store. It returns
+// ErrNotFound if there is no such file.
+func (c *Client) Stat(ctx context.Context, remotePath string) (*Object, error) {
+	remotePath = files.ToRelative(remotePath)
+	info, err := backend.Stat(ctx, c.backend, c.store.PrefixedPath(remotePath))
+	if err != nil {
+		return nil, err
+	}
+
+	obj := *info
+	obj.Path = remotePath
+	return &obj, nil
+}
@@ -0,0 +1,77 @@
+package client
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend/memorybackend"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Client(t *testing.T) {
+	ctx := context.Background()
+	memory := memorybackend.New()
+
+	c, err := New(WithBackend(memory), WithStore(StoreWorkflow, "w1"), WithConcurrency(2))
+	require.NoError(t, err)
+	defer c.Close()
+
+	source := filepath.Join(t.TempDir(), "dist")
+	require.NoError(t, os.MkdirAll(filepath.Join(source, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "app.zip"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "bin", "tool"), []byte("tool"), 0755))
+
+	require.NoError(t, c.Push(ctx, source, "releases/v1", WithMetadata(map[string]string{"version": "v1"})))
+	assert.Equal(t, "v1", memory.Metadata("artifacts/workflows/w1/releases/v1/app.zip")["version"])
+
+	var exists *ErrAlreadyExists
+	assert.ErrorAs(t, c.Push(ctx, source, "releases/v1"), &exists)
+	require.NoError(t, c.Push(ctx, source, "releases/v1", Force()))
+
+	listed := []string{}
+	require.NoError(t, c.List(ctx, "releases", func(obj Object) error {
+		listed = append(listed, obj.Path)
+		return nil
+	}))
+	assert.Equal(t, []string{"releases/v1/app.zip", "releases/v1/bin/tool"}, listed)
+
+	listed = []string{}
+	require.NoError(t, c.List(ctx, "", func(obj Object) error {
+		listed = append(listed, obj.Path)
+		return StopListing
+	}))
+	assert.Equal(t, []string{"releases/v1/app.zip"}, listed)
+
+	info, err := c.Stat(ctx, "/releases/v1/app.zip")
+	require.NoError(t, err)
+	assert.Equal(t, "releases/v1/app.zip", info.Path)
+	assert.Equal(t, int64(3), info.Size)
+
+	dest := filepath.Join(t.TempDir(), "v1")
+	require.NoError(t, c.Pull(ctx, "releases/v1", dest))
+	data, err := os.ReadFile(filepath.Join(dest, "bin", "tool"))
+	require.NoError(t, err)
+	assert.Equal(t, "tool", string(data))
+
+	require.NoError(t, c.Yank(ctx, "releases/v1"))
+	var notFound *ErrNotFound
+	_, err = c.Stat(ctx, "releases/v1/app.zip")
+	assert.ErrorAs(t, err, &notFound)
+	assert.ErrorAs(t, c.Pull(ctx, "releases/v1", dest, Force()), &notFound)
+}
+
+func Test__New(t *testing.T) {
+	t.Setenv("SEMAPHORE_PROJECT_ID", "")
+
+	_, err := New(WithBackend(memorybackend.New()), WithStore(StoreProject, ""))
+	assert.ErrorContains(t, err, "project ID is not set")
+
+	_, err = New(WithBackend(memorybackend.New()), WithStore("team", "1"))
+	assert.EqualError(t, err, "unrecognized resource type 'team'")
+
+	_, err = New(WithBackend(memorybackend.New()), WithStore(StoreJob, "1"), WithConcurrency(-1))
+	assert.EqualError(t, err, "invalid concurrency -1")
+}
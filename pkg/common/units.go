@@ -0,0 +1,97 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var sizeUnits = map[string]int64{
+	"":    1,
+	"B":   1,
+	"K":   1 << 10,
+	"KB":  1 << 10,
+	"KIB": 1 << 10,
+	"M":   1 << 20,
+	"MB":  1 << 20,
+	"MIB": 1 << 20,
+	"G":   1 << 30,
+	"GB":  1 << 30,
+	"GIB": 1 << 30,
+	"T":   1 << 40,
+	"TB":  1 << 40,
+	"TIB": 1 << 40,
+}
+
+// ParseSize parses human readable sizes such as "512", "10KB" or "5G".
+// Units are binary, so 1KB is 1024 bytes.
+func ParseSize(value string) (int64, error) {
+	trimmed := strings.ToUpper(strings.TrimSpace(value))
+	i := strings.IndexFunc(trimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	number, unit := trimmed, ""
+	if i >= 0 {
+		number, unit = trimmed[:i], strings.TrimSpace(trimmed[i:])
+	}
+
+	multiplier, ok := sizeUnits[unit]
+	if !ok || number == "" {
+		return 0, fmt.Errorf("invalid size '%s'", value)
+	}
+
+	n, err := strconv.ParseFloat(number, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size '%s'", value)
+	}
+
+	return int64(n * float64(multiplier)), nil
+}
+
+// FormatSize converts a size in bytes to a human readable format, such
+// as "1.5 MB", with the binary units ParseSize accepts.
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
+
+// ParseAge parses durations in the same format used by --expire-in:
+// Nh for N hours, Nd for N days, Nw for N weeks, Nm for N months
+// and Ny for N years. Months are 30 days and years are 365 days.
+func ParseAge(value string) (time.Duration, error) {
+	trimmed := strings.TrimSpace(value)
+	if len(trimmed) < 2 {
+		return 0, fmt.Errorf("invalid duration '%s'", value)
+	}
+
+	n, err := strconv.Atoi(trimmed[:len(trimmed)-1])
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid duration '%s'", value)
+	}
+
+	day := 24 * time.Hour
+	switch trimmed[len(trimmed)-1] {
+	case 'h':
+		return time.Duration(n) * time.Hour, nil
+	case 'd':
+		return time.Duration(n) * day, nil
+	case 'w':
+		return time.Duration(n) * 7 * day, nil
+	case 'm':
+		return time.Duration(n) * 30 * day, nil
+	case 'y':
+		return time.Duration(n) * 365 * day, nil
+	default:
+		return 0, fmt.Errorf("invalid duration '%s'", value)
+	}
+}
@@ -0,0 +1,57 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__ParseSize(t *testing.T) {
+	check := func(value string, expected int64) {
+		size, err := ParseSize(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	check("0", 0)
+	check("512", 512)
+	check("512B", 512)
+	check("10K", 10*1024)
+	check("10kb", 10*1024)
+	check("1.5MB", 1536*1024)
+	check("5G", 5*1024*1024*1024)
+	check("2 GiB", 2*1024*1024*1024)
+
+	for _, invalid := range []string{"", "MB", "10XB", "-1", "1.2.3K"} {
+		_, err := ParseSize(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
+
+func Test__FormatSize(t *testing.T) {
+	assert.Equal(t, "512 B", FormatSize(512))
+	assert.Equal(t, "1.5 KB", FormatSize(1536))
+	assert.Equal(t, "2.0 GB", FormatSize(2*1024*1024*1024))
+}
+
+func Test__ParseAge(t *testing.T) {
+	day := 24 * time.Hour
+
+	check := func(value string, expected time.Duration) {
+		age, err := ParseAge(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, age, value)
+	}
+
+	check("12h", 12*time.Hour)
+	check("30d", 30*day)
+	check("2w", 14*day)
+	check("1m", 30*day)
+	check("1y", 365*day)
+
+	for _, invalid := range []string{"", "d", "10", "10x", "-1d"} {
+		_, err := ParseAge(invalid)
+		assert.NotNil(t, err, invalid)
+	}
+}
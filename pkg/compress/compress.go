@@ -0,0 +1,105 @@
+// Package compress holds the compressions files can be pushed with, see
+// push --compress: gzip, which any tool can read, and zstd, which is
+// faster at a similar ratio. Text-heavy artifacts, like logs, JUnit
+// reports and coverage, shrink several times with either.
+package compress
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/semaphoreci/artifact/pkg/files"
+)
+
+// Compressions, as recorded in the metadata of compressed files.
+const (
+	Gzip = "gzip"
+	Zstd = "zstd"
+)
+
+// Encodings are the supported compressions.
+var Encodings = []string{Gzip, Zstd}
+
+// Validate returns an error unless encoding is a supported compression.
+func Validate(encoding string) error {
+	for _, supported := range Encodings {
+		if encoding == supported {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("unsupported compression '%s': use %s", encoding, strings.Join(Encodings, " or "))
+}
+
+// Compress returns the contents of r compressed with encoding, compressing
+// them as they are read. Closing it stops reading r.
+func Compress(encoding string, r io.Reader) (io.ReadCloser, error) {
+	if err := Validate(encoding); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := newWriter(encoding, pw)
+		if err == nil {
+			_, err = files.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// Decompress returns the contents of r decompressed with encoding.
+// Closing it closes r.
+func Decompress(encoding string, r io.ReadCloser) (io.ReadCloser, error) {
+	switch encoding {
+	case Gzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+
+		return &decompressor{Reader: gz, close: gz.Close, r: r}, nil
+
+	case Zstd:
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress: %w", err)
+		}
+
+		return &decompressor{Reader: zr, close: func() error { zr.Close(); return nil }, r: r}, nil
+	}
+
+	return nil, Validate(encoding)
+}
+
+func newWriter(encoding string, w io.Writer) (io.WriteCloser, error) {
+	if encoding == Zstd {
+		return zstd.NewWriter(w)
+	}
+
+	return gzip.NewWriter(w), nil
+}
+
+type decompressor struct {
+	io.Reader
+	close func() error
+	r     io.ReadCloser
+}
+
+func (d *decompressor) Close() error {
+	err := d.close()
+	if closeErr := d.r.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
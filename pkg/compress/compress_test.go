@@ -0,0 +1,54 @@
+package compress
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Validate(t *testing.T) {
+	assert.NoError(t, Validate(Gzip))
+	assert.NoError(t, Validate(Zstd))
+	assert.EqualError(t, Validate("brotli"), "unsupported compression 'brotli': use gzip or zstd")
+}
+
+func Test__CompressDecompress(t *testing.T) {
+	contents := strings.Repeat("<testcase name=\"a\" time=\"0.01\"/>\n", 1000)
+
+	for _, encoding := range Encodings {
+		t.Run(encoding, func(t *testing.T) {
+			compressed, err := Compress(encoding, strings.NewReader(contents))
+			require.NoError(t, err)
+
+			data, err := io.ReadAll(compressed)
+			require.NoError(t, err)
+			require.NoError(t, compressed.Close())
+			assert.Less(t, len(data)*10, len(contents))
+
+			decompressed, err := Decompress(encoding, io.NopCloser(bytes.NewReader(data)))
+			require.NoError(t, err)
+
+			result, err := io.ReadAll(decompressed)
+			require.NoError(t, err)
+			require.NoError(t, decompressed.Close())
+			assert.Equal(t, contents, string(result))
+		})
+	}
+}
+
+func Test__CompressUnsupported(t *testing.T) {
+	_, err := Compress("brotli", strings.NewReader(""))
+	assert.Error(t, err)
+
+	_, err = Decompress("brotli", io.NopCloser(strings.NewReader("")))
+	assert.Error(t, err)
+}
+
+func Test__DecompressCorrupt(t *testing.T) {
+	_, err := Decompress(Gzip, io.NopCloser(strings.NewReader("not gzip")))
+	assert.ErrorContains(t, err, "failed to decompress")
+}
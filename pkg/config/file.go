@@ -0,0 +1,202 @@
+// Package config reads and writes the artifact CLI config file.
+// Viper is used to read configuration at runtime, but it cannot write back
+// a file without also persisting defaults and environment values, so commands
+// that change the config file go through this package instead.
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	homedir "github.com/mitchellh/go-homedir"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// File is a config file loaded for editing.
+// Keys are dot-separated paths into nested sections, e.g. "s3.bucket".
+type File struct {
+	Path   string
+	values map[string]interface{}
+}
+
+// Path returns the config file in use: the one given with --config or found
+// by viper, or $HOME/.artifact.yaml if there is none yet.
+func Path() (string, error) {
+	if used := viper.ConfigFileUsed(); used != "" {
+		return used, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".artifact.yaml"), nil
+}
+
+// Load reads the config file at path. A missing file results in an empty config.
+func Load(path string) (*File, error) {
+	f := &File{Path: path, values: map[string]interface{}{}}
+
+	// #nosec
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return f, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file '%s': %v", path, err)
+	}
+
+	if err := yaml.Unmarshal(data, &f.values); err != nil {
+		return nil, fmt.Errorf("failed to parse config file '%s': %v", path, err)
+	}
+
+	if f.values == nil {
+		f.values = map[string]interface{}{}
+	}
+
+	return f, nil
+}
+
+// LoadDefault loads the config file in use, as returned by Path.
+func LoadDefault() (*File, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+
+	return Load(path)
+}
+
+// Get returns the value stored under key.
+func (f *File) Get(key string) (interface{}, bool) {
+	var current interface{} = f.values
+
+	for _, part := range strings.Split(key, ".") {
+		section, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+
+		current, ok = section[part]
+		if !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+// Set stores value under key, creating intermediate sections as needed.
+func (f *File) Set(key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	section := f.values
+
+	for _, part := range parts[:len(parts)-1] {
+		next, ok := section[part].(map[string]interface{})
+		if !ok {
+			next = map[string]interface{}{}
+			section[part] = next
+		}
+
+		section = next
+	}
+
+	section[parts[len(parts)-1]] = value
+}
+
+// Unset removes key, returning false if it was not set.
+// Sections left empty by the removal are removed as well.
+func (f *File) Unset(key string) bool {
+	return unset(f.values, strings.Split(key, "."))
+}
+
+func unset(section map[string]interface{}, parts []string) bool {
+	if len(parts) == 1 {
+		if _, ok := section[parts[0]]; !ok {
+			return false
+		}
+
+		delete(section, parts[0])
+		return true
+	}
+
+	next, ok := section[parts[0]].(map[string]interface{})
+	if !ok || !unset(next, parts[1:]) {
+		return false
+	}
+
+	if len(next) == 0 {
+		delete(section, parts[0])
+	}
+
+	return true
+}
+
+// Keys returns all leaf keys in the config, sorted.
+func (f *File) Keys() []string {
+	keys := []string{}
+	collectKeys("", f.values, &keys)
+	sort.Strings(keys)
+	return keys
+}
+
+func collectKeys(prefix string, section map[string]interface{}, keys *[]string) {
+	for name, value := range section {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			collectKeys(key, nested, keys)
+			continue
+		}
+
+		*keys = append(*keys, key)
+	}
+}
+
+// Save writes the config back to its file.
+func (f *File) Save() error {
+	data, err := yaml.Marshal(f.values)
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %v", err)
+	}
+
+	if err := os.WriteFile(f.Path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write config file '%s': %v", f.Path, err)
+	}
+
+	return nil
+}
+
+// Template returns the contents of a new config file for the backend:
+// the backend setting, followed by the settings of the backend commented out.
+func Template(backendSetting string) []byte {
+	lines := []string{
+		"# Config file of the artifact CLI. Change it with 'artifact config set KEY VALUE';",
+		"# 'artifact config list --all' describes every setting. Environment variables,",
+		"# e.g. ARTIFACT_BACKEND, take precedence over the values in this file.",
+		fmt.Sprintf("backend: %s", backendSetting),
+	}
+
+	section := strings.ToLower(backendSetting) + "."
+	for _, setting := range Settings {
+		if !strings.HasPrefix(setting.Key, section) {
+			continue
+		}
+
+		if len(lines) == 4 {
+			lines = append(lines, fmt.Sprintf("# %s:", strings.TrimSuffix(section, ".")))
+		}
+		lines = append(lines, fmt.Sprintf("#   %s:  # %s", strings.TrimPrefix(setting.Key, section), setting.Description))
+	}
+
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
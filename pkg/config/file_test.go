@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".artifact.yaml")
+
+	f, err := Load(path)
+	require.NoError(t, err)
+	assert.Empty(t, f.Keys())
+
+	f.Set("backend", "s3")
+	f.Set("s3.bucket", "my-bucket")
+	f.Set("aliases.coverage", "workflow:reports/lcov.info")
+	require.NoError(t, f.Save())
+
+	f, err = Load(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"aliases.coverage", "backend", "s3.bucket"}, f.Keys())
+
+	value, ok := f.Get("s3.bucket")
+	assert.True(t, ok)
+	assert.Equal(t, "my-bucket", value)
+
+	_, ok = f.Get("s3.region")
+	assert.False(t, ok)
+
+	assert.True(t, f.Unset("aliases.coverage"))
+	assert.False(t, f.Unset("aliases.coverage"))
+	assert.False(t, f.Unset("missing.key"))
+	assert.Equal(t, []string{"backend", "s3.bucket"}, f.Keys())
+}
+
+func Test__Load_InvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".artifact.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("backend: [s3"), 0600))
+
+	_, err := Load(path)
+	assert.Error(t, err)
+}
+
+func Test__Template(t *testing.T) {
+	template := string(Template("http"))
+	assert.Contains(t, template, "backend: http\n# http:\n#   url:  # base URL artifacts are stored under\n")
+	assert.NotContains(t, template, "s3")
+
+	f, err := Load(filepath.Join(t.TempDir(), ".artifact.yaml"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(f.Path, Template("s3"), 0600))
+
+	f, err = Load(f.Path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"backend"}, f.Keys())
+	assert.Empty(t, f.Validate())
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// ProfilesKey is the config file section holding named profiles, e.g.
+// profiles.prod.s3.bucket.
+const ProfilesKey = "profiles"
+
+// ActiveProfile returns the name of the profile to use: the one given with
+// --profile, or ARTIFACT_PROFILE. It is empty if no profile is selected.
+func ActiveProfile(flag string) string {
+	if flag != "" {
+		return flag
+	}
+
+	return os.Getenv("ARTIFACT_PROFILE")
+}
+
+// Profiles returns the names of the profiles in the config file, sorted.
+func Profiles() []string {
+	names := []string{}
+	for name := range viper.GetStringMap(ProfilesKey) {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// ApplyProfile overlays the settings of a profile on the config file, so
+// profiles.prod.s3.bucket is read as s3.bucket. Settings the profile does
+// not have keep their values, and environment variables still take
+// precedence over both.
+func ApplyProfile(name string) error {
+	// Viper lowercases config keys
+	settings, ok := viper.GetStringMap(ProfilesKey)[strings.ToLower(name)].(map[string]interface{})
+	if !ok {
+		profiles := Profiles()
+		if len(profiles) == 0 {
+			return fmt.Errorf("profile '%s' not found: the config file has no profiles", name)
+		}
+
+		return fmt.Errorf("profile '%s' not found: available profiles are %s", name, strings.Join(profiles, ", "))
+	}
+
+	return viper.MergeConfigMap(settings)
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__ApplyProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".artifact.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+backend: s3
+s3:
+  bucket: shared
+  region: eu-west-1
+profiles:
+  prod:
+    s3:
+      bucket: prod-artifacts
+  Dev:
+    backend: http
+    http:
+      url: https://dev.example.com/artifacts
+`), 0600))
+
+	viper.SetConfigFile(path)
+	defer viper.Reset()
+	require.NoError(t, viper.ReadInConfig())
+
+	assert.Equal(t, []string{"dev", "prod"}, Profiles())
+
+	require.NoError(t, ApplyProfile("prod"))
+	assert.Equal(t, "s3", viper.GetString("backend"))
+	assert.Equal(t, "prod-artifacts", viper.GetString("s3.bucket"))
+	assert.Equal(t, "eu-west-1", viper.GetString("s3.region"))
+
+	require.NoError(t, ApplyProfile("Dev"))
+	assert.Equal(t, "http", viper.GetString("backend"))
+	assert.Equal(t, "https://dev.example.com/artifacts", viper.GetString("http.url"))
+
+	err := ApplyProfile("staging")
+	assert.EqualError(t, err, "profile 'staging' not found: available profiles are dev, prod")
+}
+
+func Test__ActiveProfile(t *testing.T) {
+	t.Setenv("ARTIFACT_PROFILE", "dev")
+	assert.Equal(t, "prod", ActiveProfile("prod"))
+	assert.Equal(t, "dev", ActiveProfile(""))
+
+	t.Setenv("ARTIFACT_PROFILE", "")
+	assert.Equal(t, "", ActiveProfile(""))
+}
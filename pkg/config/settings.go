@@ -0,0 +1,234 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Kinds of settings, which decide how values given on the command line are stored.
+const (
+	KindString  = "string"
+	KindBool    = "bool"
+	KindList    = "list"    // comma-separated on the command line
+	KindMap     = "map"     // free-form entries below the key, e.g. aliases.coverage
+	KindSection = "section" // structured values that are edited in the file, e.g. policy
+)
+
+// Setting is a key the artifact CLI reads from the config file.
+type Setting struct {
+	Key         string
+	Kind        string
+	Description string
+}
+
+// Settings lists every key read from the config file, besides profiles,
+// which hold the same keys under profiles.NAME.
+var Settings = []Setting{
+	{Key: "backend", Kind: KindString, Description: "storage backend: hub, s3, http, webhdfs, ftp, rclone, ipfs, artifactory, mirror, fallback, exec:PATH or plugin:NAME"},
+	{Key: "readonly", Kind: KindBool, Description: "reject pushes and yanks"},
+	{Key: "cacheDir", Kind: KindString, Description: "directory pulled files are cached in"},
+	{Key: "credentialHelper", Kind: KindString, Description: "command printing backend credentials"},
+	{Key: "credentialStore", Kind: KindString, Description: "where 'artifact login' stores credentials: keychain or file"},
+	{Key: "credentialFile", Kind: KindString, Description: "encrypted file of the file credential store"},
+	{Key: "pluginDir", Kind: KindString, Description: "directory plugin backends are loaded from"},
+	{Key: "aliases", Kind: KindMap, Description: "named artifact paths, e.g. aliases.coverage: workflow:reports/lcov.info"},
+	{Key: "pullMappings", Kind: KindMap, Description: "local destinations of pulled paths"},
+	{Key: "policy", Kind: KindSection, Description: "rules for pushes and yanks"},
+	{Key: "signing.key", Kind: KindString, Description: "private key 'artifact sign' signs files with"},
+	{Key: "signing.publicKey", Kind: KindString, Description: "public key signatures are checked with"},
+	{Key: "retry.attempts", Kind: KindString, Description: "requests in total before a failing backend request gives up"},
+	{Key: "retry.baseDelay", Kind: KindString, Description: "delay before the first retry, doubled for each further one, e.g. 500ms"},
+	{Key: "retry.maxDelay", Kind: KindString, Description: "upper bound of the delay between retries, e.g. 20s"},
+	{Key: "retry.requestTimeout", Kind: KindString, Description: "how long a request waits for the response to start, e.g. 1m"},
+	{Key: "push.maxFileSize", Kind: KindString, Description: "largest file a push may upload, e.g. 500MB"},
+	{Key: "push.maxTotalSize", Kind: KindString, Description: "largest total size of the files of a push, e.g. 10GB"},
+	{Key: "push.maxFiles", Kind: KindString, Description: "most files a push may upload"},
+
+	{Key: "s3.bucket", Kind: KindString, Description: "bucket artifacts are stored in"},
+	{Key: "s3.region", Kind: KindString, Description: "region of the bucket"},
+	{Key: "s3.endpoint", Kind: KindString, Description: "endpoint of S3-compatible storage"},
+	{Key: "s3.forcePathStyle", Kind: KindBool, Description: "address the bucket in the path instead of the host name"},
+	{Key: "s3.prefix", Kind: KindString, Description: "key prefix of all artifacts"},
+	{Key: "s3.provider", Kind: KindString, Description: "S3-compatible provider preset, e.g. r2"},
+	{Key: "s3.accountId", Kind: KindString, Description: "account ID used by provider presets"},
+	{Key: "s3.readBucket", Kind: KindString, Description: "read replica pulls are served from"},
+	{Key: "s3.readRegion", Kind: KindString, Description: "region of the read replica"},
+	{Key: "s3.readEndpoint", Kind: KindString, Description: "endpoint of the read replica"},
+	{Key: "s3.objectLockMode", Kind: KindString, Description: "object lock retention mode of pushed files: GOVERNANCE or COMPLIANCE"},
+	{Key: "s3.objectLockRetainFor", Kind: KindString, Description: "object lock retention period, e.g. 30d"},
+	{Key: "s3.objectLockLegalHold", Kind: KindBool, Description: "put pushed files under legal hold"},
+	{Key: "s3.expireLifecycleRules", Kind: KindBool, Description: "add bucket lifecycle rules expiring files pushed with --expire-in"},
+	{Key: "s3.multipartThreshold", Kind: KindString, Description: "size from which files are uploaded in parts, e.g. 64MB"},
+	{Key: "s3.partSize", Kind: KindString, Description: "size of the parts of multipart uploads, at least 5MB"},
+	{Key: "s3.partConcurrency", Kind: KindString, Description: "number of parts of a file uploaded at once"},
+
+	{Key: "http.url", Kind: KindString, Description: "base URL artifacts are stored under"},
+	{Key: "http.token", Kind: KindString, Description: "bearer token sent with every request"},
+	{Key: "http.authHeader", Kind: KindString, Description: "extra header sent with every request, e.g. 'X-Api-Key: KEY'"},
+
+	{Key: "webhdfs.url", Kind: KindString, Description: "WebHDFS endpoint"},
+	{Key: "webhdfs.user", Kind: KindString, Description: "HDFS user"},
+	{Key: "webhdfs.prefix", Kind: KindString, Description: "directory of all artifacts"},
+	{Key: "webhdfs.kerberos", Kind: KindBool, Description: "authenticate with Kerberos"},
+	{Key: "webhdfs.principal", Kind: KindString, Description: "Kerberos principal"},
+	{Key: "webhdfs.keytab", Kind: KindString, Description: "Kerberos keytab file"},
+
+	{Key: "ftp.host", Kind: KindString, Description: "FTP server, as HOST:PORT"},
+	{Key: "ftp.user", Kind: KindString, Description: "FTP user"},
+	{Key: "ftp.password", Kind: KindString, Description: "FTP password"},
+	{Key: "ftp.prefix", Kind: KindString, Description: "directory of all artifacts"},
+	{Key: "ftp.tls", Kind: KindString, Description: "TLS mode: explicit or implicit"},
+	{Key: "ftp.tlsSkipVerify", Kind: KindBool, Description: "skip verification of the server certificate"},
+
+	{Key: "rclone.remote", Kind: KindString, Description: "rclone remote, e.g. gdrive:artifacts"},
+	{Key: "rclone.binary", Kind: KindString, Description: "path of the rclone binary"},
+	{Key: "rclone.config", Kind: KindString, Description: "rclone config file"},
+	{Key: "rclone.flags", Kind: KindString, Description: "extra flags passed to rclone"},
+
+	{Key: "ipfs.url", Kind: KindString, Description: "IPFS node API URL"},
+	{Key: "ipfs.token", Kind: KindString, Description: "token sent to the IPFS node"},
+	{Key: "ipfs.prefix", Kind: KindString, Description: "MFS directory of all artifacts"},
+	{Key: "ipfs.manifest", Kind: KindString, Description: "file the content identifiers of pushed files are recorded in"},
+
+	{Key: "artifactory.url", Kind: KindString, Description: "Artifactory URL"},
+	{Key: "artifactory.repository", Kind: KindString, Description: "repository artifacts are deployed to"},
+	{Key: "artifactory.prefix", Kind: KindString, Description: "path of all artifacts in the repository"},
+	{Key: "artifactory.user", Kind: KindString, Description: "Artifactory user"},
+	{Key: "artifactory.password", Kind: KindString, Description: "Artifactory password"},
+	{Key: "artifactory.token", Kind: KindString, Description: "Artifactory access token"},
+	{Key: "artifactory.properties", Kind: KindMap, Description: "properties set on deployed files"},
+
+	{Key: "mirror.backends", Kind: KindList, Description: "backends the mirror backend writes to"},
+	{Key: "fallback.backends", Kind: KindList, Description: "backends the fallback backend reads from"},
+
+	// Kept so config files written for older versions stay valid
+	{Key: "ProjectArtifactsExpire", Kind: KindString, Description: "unused"},
+	{Key: "WorkflowArtifactsExpire", Kind: KindString, Description: "unused"},
+	{Key: "JobArtifactsExpire", Kind: KindString, Description: "unused"},
+}
+
+// LookupSetting returns the setting key refers to. Keys are matched without
+// regard to case, like viper does, and may be entries of a map setting, e.g.
+// aliases.coverage, or point into a profile, e.g. profiles.prod.s3.bucket.
+// Unknown keys are reported with the closest known key, to catch typos.
+func LookupSetting(key string) (*Setting, error) {
+	parts := strings.Split(key, ".")
+	if strings.EqualFold(parts[0], ProfilesKey) {
+		switch {
+		case len(parts) == 1:
+			return &Setting{Key: ProfilesKey, Kind: KindSection, Description: "named sets of settings"}, nil
+		case len(parts) == 2:
+			return &Setting{Key: ProfilesKey + "." + parts[1], Kind: KindSection, Description: "settings of a profile"}, nil
+		case strings.EqualFold(parts[2], ProfilesKey):
+			return nil, fmt.Errorf("unknown setting '%s': profiles cannot be nested", key)
+		}
+
+		setting, err := LookupSetting(strings.Join(parts[2:], "."))
+		if err != nil {
+			return nil, fmt.Errorf("profile '%s': %v", parts[1], err)
+		}
+
+		profiled := *setting
+		profiled.Key = strings.Join(parts[:2], ".") + "." + setting.Key
+		return &profiled, nil
+	}
+
+	for i := range Settings {
+		setting := Settings[i]
+		if strings.EqualFold(key, setting.Key) {
+			return &setting, nil
+		}
+
+		// Entries of maps and everything inside sections
+		prefix := setting.Key + "."
+		if (setting.Kind == KindMap || setting.Kind == KindSection) && len(key) > len(prefix) && strings.EqualFold(key[:len(prefix)], prefix) {
+			entry := Setting{Key: setting.Key + key[len(setting.Key):], Kind: KindString, Description: setting.Description}
+			if setting.Kind == KindSection {
+				entry.Kind = KindSection
+			}
+
+			return &entry, nil
+		}
+	}
+
+	if suggestion := closestSetting(key); suggestion != "" {
+		return nil, fmt.Errorf("unknown setting '%s', did you mean '%s'?", key, suggestion)
+	}
+
+	return nil, fmt.Errorf("unknown setting '%s'", key)
+}
+
+// ParseValue converts a value given on the command line for the setting
+// into the type stored in the config file.
+func (s *Setting) ParseValue(value string) (interface{}, error) {
+	switch s.Kind {
+	case KindBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value '%s' for '%s': use true or false", value, s.Key)
+		}
+		return b, nil
+	case KindList:
+		values := []string{}
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values, nil
+	case KindMap, KindSection:
+		return nil, fmt.Errorf("'%s' cannot be set from the command line: edit the config file instead", s.Key)
+	default:
+		return value, nil
+	}
+}
+
+// Validate returns an error for every key in the config file that the
+// artifact CLI does not read, which viper would silently ignore.
+func (f *File) Validate() []error {
+	errs := []error{}
+	for _, key := range f.Keys() {
+		if _, err := LookupSetting(key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errs
+}
+
+// closestSetting returns the known key most similar to key,
+// or "" if none is close enough to be a likely typo.
+func closestSetting(key string) string {
+	best, bestDistance := "", 4
+	for _, setting := range Settings {
+		if d := editDistance(strings.ToLower(key), strings.ToLower(setting.Key)); d < bestDistance {
+			best, bestDistance = setting.Key, d
+		}
+	}
+
+	return best
+}
+
+// editDistance returns the Levenshtein distance between a and b.
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+
+	return previous[len(b)]
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__LookupSetting(t *testing.T) {
+	for key, expected := range map[string]string{
+		"s3.bucket":                       "s3.bucket",
+		"S3.Bucket":                       "s3.bucket",
+		"aliases.coverage":                "aliases.coverage",
+		"artifactory.properties.team":     "artifactory.properties.team",
+		"policy.rules":                    "policy.rules",
+		"profiles.prod":                   "profiles.prod",
+		"profiles.prod.s3.forcepathstyle": "profiles.prod.s3.forcePathStyle",
+	} {
+		setting, err := LookupSetting(key)
+		if assert.NoError(t, err, key) {
+			assert.Equal(t, expected, setting.Key, key)
+		}
+	}
+
+	_, err := LookupSetting("s3.bukcet")
+	assert.EqualError(t, err, "unknown setting 's3.bukcet', did you mean 's3.bucket'?")
+
+	_, err = LookupSetting("profiles.prod.s3.bukcet")
+	assert.EqualError(t, err, "profile 'prod': unknown setting 's3.bukcet', did you mean 's3.bucket'?")
+
+	_, err = LookupSetting("compression")
+	assert.EqualError(t, err, "unknown setting 'compression'")
+
+	_, err = LookupSetting("profiles.prod.profiles.dev")
+	assert.Error(t, err)
+}
+
+func Test__Setting_ParseValue(t *testing.T) {
+	setting, err := LookupSetting("s3.forcePathStyle")
+	require.NoError(t, err)
+
+	value, err := setting.ParseValue("true")
+	require.NoError(t, err)
+	assert.Equal(t, true, value)
+
+	_, err = setting.ParseValue("yes please")
+	assert.Error(t, err)
+
+	setting, err = LookupSetting("mirror.backends")
+	require.NoError(t, err)
+
+	value, err = setting.ParseValue("hub, s3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"hub", "s3"}, value)
+
+	setting, err = LookupSetting("policy")
+	require.NoError(t, err)
+
+	_, err = setting.ParseValue("deny")
+	assert.Error(t, err)
+}
+
+func Test__File_Validate(t *testing.T) {
+	f, err := Load(filepath.Join(t.TempDir(), ".artifact.yaml"))
+	require.NoError(t, err)
+
+	f.Set("backend", "s3")
+	f.Set("s3.bucket", "my-bucket")
+	f.Set("s3.regoin", "eu-west-1")
+	f.Set("profiles.prod.s3.bucket", "prod-bucket")
+	f.Set("policy.rules", []interface{}{map[string]interface{}{"deny": true}})
+
+	errs := f.Validate()
+	require.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "unknown setting 's3.regoin', did you mean 's3.region'?")
+}
@@ -0,0 +1,228 @@
+// Package delta splits large files into content-defined blocks, so a new
+// version of a file can be pushed by uploading only the blocks that
+// changed, see push --delta. Block boundaries are found with a rolling
+// hash of the contents rather than at fixed offsets, so inserting or
+// removing bytes only changes the blocks around the edit.
+//
+// The blocks of a file are stored by their SHA256 checksum under its
+// hidden sidecar prefix, and the file itself is replaced by an index
+// listing its blocks in order, which pulls reassemble it from.
+package delta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+)
+
+// Magic is the first line of an index, telling it apart from other files.
+const Magic = "artifact-delta/1\n"
+
+// Bounds of the size of the blocks: a boundary is found every AvgBlockSize
+// bytes on average, but never closer than MinBlockSize to the previous one,
+// nor farther than MaxBlockSize.
+const (
+	MinBlockSize = 256 * 1024
+	AvgBlockSize = 1024 * 1024
+	MaxBlockSize = 4 * 1024 * 1024
+)
+
+// blockMask selects the top bits of the rolling hash, which depend on the
+// last 64 bytes read: a boundary is found when all of them are zero, one
+// time in AvgBlockSize.
+const blockMask = uint64(AvgBlockSize-1) << (64 - 20)
+
+// gear holds a random value per byte for the rolling hash. It is generated
+// from a fixed seed, so files are split at the same offsets by every
+// version of artifact.
+var gear = func() [256]uint64 {
+	var table [256]uint64
+	seed := uint64(0x61727469666163) // "artifac"
+	for i := range table {
+		// splitmix64
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		table[i] = z ^ (z >> 31)
+	}
+
+	return table
+}()
+
+// Block is a range of a file, with the checksum it is stored by.
+type Block struct {
+	SHA256 string `json:"sha256"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+}
+
+// Index lists the blocks of a file in order, with the size and checksum of
+// the whole file to verify it once reassembled.
+//
+// Retired lists the blocks of the previous version of the file that this
+// one no longer uses. They are kept until the next push, so the previous
+// version can still be reassembled by pulls that started before this one.
+type Index struct {
+	Size    int64    `json:"size"`
+	SHA256  string   `json:"sha256"`
+	Blocks  []Block  `json:"blocks"`
+	Retired []string `json:"retired,omitempty"`
+}
+
+// Uses reports whether one of the blocks of the index has checksum sum.
+func (i *Index) Uses(sum string) bool {
+	for _, block := range i.Blocks {
+		if block.SHA256 == sum {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Split reads r to its end and returns the index of its blocks.
+func Split(r io.Reader) (*Index, error) {
+	index := &Index{Blocks: []Block{}}
+	whole, block := sha256.New(), sha256.New()
+
+	var offset, size int64
+	var hash uint64
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := r.Read(buf)
+		data := buf[:n]
+		whole.Write(data)
+
+		start := 0
+		for i, b := range data {
+			size++
+			hash = hash<<1 + gear[b]
+			if size < MinBlockSize || (size < MaxBlockSize && hash&blockMask != 0) {
+				continue
+			}
+
+			block.Write(data[start : i+1])
+			start = i + 1
+			index.Blocks = append(index.Blocks, Block{SHA256: hex.EncodeToString(block.Sum(nil)), Offset: offset, Size: size})
+			offset, size, hash = offset+size, 0, 0
+			block.Reset()
+		}
+		block.Write(data[start:])
+
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if size > 0 {
+		index.Blocks = append(index.Blocks, Block{SHA256: hex.EncodeToString(block.Sum(nil)), Offset: offset, Size: size})
+	}
+
+	index.Size = offset + size
+	index.SHA256 = hex.EncodeToString(whole.Sum(nil))
+	return index, nil
+}
+
+// Marshal returns the index as stored in place of the file: Magic followed
+// by its JSON encoding.
+func (i *Index) Marshal() ([]byte, error) {
+	data, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(Magic), data...), nil
+}
+
+// IsIndex reports whether data, or the start of it, is an index.
+func IsIndex(head []byte) bool {
+	return bytes.HasPrefix(head, []byte(Magic))
+}
+
+// Parse parses an index stored by Marshal.
+func Parse(data []byte) (*Index, error) {
+	if !IsIndex(data) {
+		return nil, fmt.Errorf("not a delta index")
+	}
+
+	index := &Index{}
+	if err := json.Unmarshal(data[len(Magic):], index); err != nil {
+		return nil, fmt.Errorf("invalid delta index: %v", err)
+	}
+
+	var offset int64
+	for _, block := range index.Blocks {
+		if block.Offset != offset || block.Size <= 0 || len(block.SHA256) != sha256.Size*2 {
+			return nil, fmt.Errorf("invalid delta index: bad block at offset %d", block.Offset)
+		}
+		offset += block.Size
+	}
+
+	if offset != index.Size {
+		return nil, fmt.Errorf("invalid delta index: blocks add up to %d bytes, not %d", offset, index.Size)
+	}
+
+	return index, nil
+}
+
+// BlockDir returns the hidden directory the blocks of the file at
+// remotePath are stored in. It is below the sidecar prefix of the file,
+// so the blocks are left out of listings and pulls, and yanked with it.
+func BlockDir(remotePath string) string {
+	return path.Join(backend.ChecksumSidecarPrefix(remotePath), "blocks")
+}
+
+// BlockPath returns the path the block with checksum sum of the file at
+// remotePath is stored at.
+func BlockPath(remotePath, sum string) string {
+	return path.Join(BlockDir(remotePath), sum)
+}
+
+// Assemble writes the blocks of the index to w in order, reading each one
+// with open, and checks them, and the whole file, against their checksums.
+func (i *Index) Assemble(w io.Writer, open func(Block) (io.ReadCloser, error)) error {
+	whole := sha256.New()
+	for _, block := range i.Blocks {
+		if err := copyBlock(io.MultiWriter(w, whole), block, open); err != nil {
+			return err
+		}
+	}
+
+	if actual := hex.EncodeToString(whole.Sum(nil)); actual != i.SHA256 {
+		return fmt.Errorf("reassembled file has checksum %s, expected %s", actual, i.SHA256)
+	}
+
+	return nil
+}
+
+// copyBlock copies the block read with open to w, checking its checksum.
+func copyBlock(w io.Writer, block Block, open func(Block) (io.ReadCloser, error)) error {
+	r, err := open(block)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	sum := sha256.New()
+	n, err := files.Copy(io.MultiWriter(w, sum), io.LimitReader(r, block.Size))
+	if err != nil {
+		return fmt.Errorf("failed to read block %s: %v", block.SHA256, err)
+	}
+
+	if n != block.Size || hex.EncodeToString(sum.Sum(nil)) != block.SHA256 {
+		return fmt.Errorf("block %s is corrupted", block.SHA256)
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package delta
+
+import (
+	"bytes"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func randomBytes(seed int64, n int) []byte {
+	data := make([]byte, n)
+	rand.New(rand.NewSource(seed)).Read(data)
+	return data
+}
+
+func Test__Split(t *testing.T) {
+	data := randomBytes(1, 12*1024*1024)
+	index, err := Split(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(data)), index.Size)
+	assert.Greater(t, len(index.Blocks), 2)
+
+	var offset int64
+	for _, block := range index.Blocks[:len(index.Blocks)-1] {
+		assert.Equal(t, offset, block.Offset)
+		assert.GreaterOrEqual(t, block.Size, int64(MinBlockSize))
+		assert.LessOrEqual(t, block.Size, int64(MaxBlockSize))
+		offset += block.Size
+	}
+
+	// Inserting bytes only changes the blocks around them
+	edited := append(append(append([]byte{}, data[:5*1024*1024]...), []byte("inserted")...), data[5*1024*1024:]...)
+	editedIndex, err := Split(bytes.NewReader(edited))
+	require.NoError(t, err)
+
+	changed := 0
+	for _, block := range editedIndex.Blocks {
+		if !index.Uses(block.SHA256) {
+			changed++
+		}
+	}
+	assert.LessOrEqual(t, changed, 2)
+
+	// Empty files have no blocks
+	empty, err := Split(bytes.NewReader(nil))
+	require.NoError(t, err)
+	assert.Empty(t, empty.Blocks)
+	assert.Equal(t, int64(0), empty.Size)
+}
+
+func Test__MarshalParseAssemble(t *testing.T) {
+	data := randomBytes(2, 3*1024*1024)
+	index, err := Split(bytes.NewReader(data))
+	require.NoError(t, err)
+
+	stored, err := index.Marshal()
+	require.NoError(t, err)
+	assert.True(t, IsIndex(stored))
+	assert.False(t, IsIndex(data))
+
+	parsed, err := Parse(stored)
+	require.NoError(t, err)
+	assert.Equal(t, index, parsed)
+
+	open := func(block Block) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(data[block.Offset : block.Offset+block.Size])), nil
+	}
+
+	out := &bytes.Buffer{}
+	require.NoError(t, parsed.Assemble(out, open))
+	assert.Equal(t, data, out.Bytes())
+
+	// Corrupted blocks are detected
+	corrupted := func(block Block) (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(make([]byte, block.Size))), nil
+	}
+	err = parsed.Assemble(io.Discard, corrupted)
+	assert.ErrorContains(t, err, "is corrupted")
+
+	_, err = Parse([]byte(Magic + `{"size":10,"sha256":"","blocks":[]}`))
+	assert.ErrorContains(t, err, "blocks add up to 0 bytes, not 10")
+
+	_, err = Parse([]byte(`{"size":0}`))
+	assert.EqualError(t, err, "not a delta index")
+}
+
+func Test__BlockPath(t *testing.T) {
+	assert.Equal(t, "artifacts/jobs/1/.checksums/db.sqlite/blocks/abc", BlockPath("artifacts/jobs/1/db.sqlite", "abc"))
+}
@@ -0,0 +1,215 @@
+// Package encrypt encrypts pushed files on the runner with age, see
+// push --encrypt, so the storage, and whoever else can read it, only ever
+// sees ciphertext. Files are encrypted to age recipients, whose private
+// keys decrypt them, or to a passphrase.
+//
+// Keys are read from the environment, where CI secrets usually live:
+// recipients and identities as age key lines, e.g. "age1..." and
+// "AGE-SECRET-KEY-1...", either in the variable itself or in a file it
+// names.
+package encrypt
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"filippo.io/age"
+	"github.com/semaphoreci/artifact/pkg/files"
+)
+
+// Age is the encryption recorded in the metadata of encrypted files.
+const Age = "age"
+
+// Environment variables holding the keys.
+const (
+	PassphraseEnv     = "ARTIFACT_ENCRYPTION_PASSPHRASE"
+	PassphraseFileEnv = "ARTIFACT_ENCRYPTION_PASSPHRASE_FILE"
+	RecipientsEnv     = "ARTIFACT_ENCRYPTION_RECIPIENTS"
+	RecipientsFileEnv = "ARTIFACT_ENCRYPTION_RECIPIENTS_FILE"
+	IdentityEnv       = "ARTIFACT_ENCRYPTION_IDENTITY"
+	IdentityFileEnv   = "ARTIFACT_ENCRYPTION_IDENTITY_FILE"
+)
+
+// ErrNoKey is returned when decrypting without any identity to do it with.
+var ErrNoKey = fmt.Errorf("no key to decrypt with: set $%s, $%s or $%s", IdentityEnv, IdentityFileEnv, PassphraseEnv)
+
+// ParseRecipients parses age public keys, one per line, or separated by
+// commas or spaces. Lines starting with # are ignored.
+func ParseRecipients(keys string) ([]age.Recipient, error) {
+	recipients, err := age.ParseRecipients(strings.NewReader(keyLines(keys)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// PassphraseRecipient returns the recipient encrypting to passphrase. It
+// is the only recipient of the files it encrypts.
+func PassphraseRecipient(passphrase string) (age.Recipient, error) {
+	return age.NewScryptRecipient(passphrase)
+}
+
+// RecipientsFromEnv returns the recipients of $ARTIFACT_ENCRYPTION_RECIPIENTS
+// or the file $ARTIFACT_ENCRYPTION_RECIPIENTS_FILE names, or failing both,
+// the passphrase of $ARTIFACT_ENCRYPTION_PASSPHRASE or of the file
+// $ARTIFACT_ENCRYPTION_PASSPHRASE_FILE names. It returns none if all are unset.
+func RecipientsFromEnv() ([]age.Recipient, error) {
+	keys, err := fromEnv(RecipientsEnv, RecipientsFileEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if keys != "" {
+		return ParseRecipients(keys)
+	}
+
+	passphrase, err := fromEnv(PassphraseEnv, PassphraseFileEnv)
+	if err != nil || passphrase == "" {
+		return nil, err
+	}
+
+	recipient, err := PassphraseRecipient(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return []age.Recipient{recipient}, nil
+}
+
+// IdentitiesFromEnv returns the identities of $ARTIFACT_ENCRYPTION_IDENTITY
+// and of the file $ARTIFACT_ENCRYPTION_IDENTITY_FILE names, and the
+// passphrase of $ARTIFACT_ENCRYPTION_PASSPHRASE or of the file
+// $ARTIFACT_ENCRYPTION_PASSPHRASE_FILE names, if set.
+func IdentitiesFromEnv() ([]age.Identity, error) {
+	identities := []age.Identity{}
+
+	for _, env := range [][2]string{{IdentityEnv, ""}, {"", IdentityFileEnv}} {
+		keys, err := fromEnv(env[0], env[1])
+		if err != nil {
+			return nil, err
+		}
+		if keys == "" {
+			continue
+		}
+
+		parsed, err := age.ParseIdentities(strings.NewReader(keyLines(keys)))
+		if err != nil {
+			return nil, fmt.Errorf("invalid encryption identity in $%s: %w", env[0]+env[1], err)
+		}
+		identities = append(identities, parsed...)
+	}
+
+	passphrase, err := fromEnv(PassphraseEnv, PassphraseFileEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	if passphrase != "" {
+		identity, err := age.NewScryptIdentity(passphrase)
+		if err != nil {
+			return nil, err
+		}
+		identities = append(identities, identity)
+	}
+
+	return identities, nil
+}
+
+// Encrypt returns the contents of r encrypted to recipients, encrypting
+// them as they are read. Closing it stops reading r.
+func Encrypt(recipients []age.Recipient, r io.Reader) (io.ReadCloser, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients to encrypt to")
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		w, err := age.Encrypt(pw, recipients...)
+		if err == nil {
+			_, err = files.Copy(w, r)
+			if closeErr := w.Close(); err == nil {
+				err = closeErr
+			}
+		}
+
+		_ = pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+// Decrypt returns the contents of r decrypted with one of identities.
+// Closing it closes r.
+func Decrypt(identities []age.Identity, r io.ReadCloser) (io.ReadCloser, error) {
+	if len(identities) == 0 {
+		return nil, ErrNoKey
+	}
+
+	decrypted, err := age.Decrypt(r, identities...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt: %w", err)
+	}
+
+	return struct {
+		io.Reader
+		io.Closer
+	}{decrypted, r}, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the identities files pulled
+// with it are decrypted with.
+func NewContext(ctx context.Context, identities []age.Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, identities)
+}
+
+// IdentitiesFromContext returns the identities of ctx, or none if it
+// carries none.
+func IdentitiesFromContext(ctx context.Context) []age.Identity {
+	identities, _ := ctx.Value(contextKey{}).([]age.Identity)
+	return identities
+}
+
+// fromEnv returns the value of the env var, or if unset, the contents of
+// the file the fileEnv var names, trimmed. Either name may be empty.
+func fromEnv(env, fileEnv string) (string, error) {
+	if value := os.Getenv(env); value != "" {
+		return strings.TrimSpace(value), nil
+	}
+
+	path := os.Getenv(fileEnv)
+	if path == "" {
+		return "", nil
+	}
+
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read $%s: %w", fileEnv, err)
+	}
+
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// keyLines puts keys separated by commas or spaces on lines of their own.
+func keyLines(keys string) string {
+	lines := []string{}
+	for _, line := range strings.Split(keys, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+			continue
+		}
+
+		lines = append(lines, strings.FieldsFunc(line, func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t'
+		})...)
+	}
+
+	return strings.Join(lines, "\n")
+}
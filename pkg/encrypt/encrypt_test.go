@@ -0,0 +1,117 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"filippo.io/age"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__EncryptDecrypt(t *testing.T) {
+	identity, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+
+	encrypted, err := Encrypt([]age.Recipient{identity.Recipient()}, strings.NewReader("secret"))
+	require.NoError(t, err)
+	data, err := io.ReadAll(encrypted)
+	require.NoError(t, err)
+	require.NoError(t, encrypted.Close())
+	assert.NotContains(t, string(data), "secret")
+
+	_, err = Decrypt(nil, io.NopCloser(bytes.NewReader(data)))
+	assert.ErrorIs(t, err, ErrNoKey)
+
+	other, err := age.GenerateX25519Identity()
+	require.NoError(t, err)
+	_, err = Decrypt([]age.Identity{other}, io.NopCloser(bytes.NewReader(data)))
+	assert.ErrorContains(t, err, "failed to decrypt")
+
+	decrypted, err := Decrypt([]age.Identity{other, identity}, io.NopCloser(bytes.NewReader(data)))
+	require.NoError(t, err)
+	result, err := io.ReadAll(decrypted)
+	require.NoError(t, err)
+	require.NoError(t, decrypted.Close())
+	assert.Equal(t, "secret", string(result))
+
+	_, err = Encrypt(nil, strings.NewReader("secret"))
+	assert.Error(t, err)
+}
+
+func Test__ParseRecipients(t *testing.T) {
+	a, _ := age.GenerateX25519Identity()
+	b, _ := age.GenerateX25519Identity()
+
+	recipients, err := ParseRecipients("# CI\n" + a.Recipient().String() + ", " + b.Recipient().String())
+	require.NoError(t, err)
+	assert.Len(t, recipients, 2)
+
+	_, err = ParseRecipients("not-a-key")
+	assert.ErrorContains(t, err, "invalid encryption recipients")
+}
+
+func Test__RecipientsFromEnv(t *testing.T) {
+	identity, _ := age.GenerateX25519Identity()
+	keysFile := filepath.Join(t.TempDir(), "recipients.txt")
+	require.NoError(t, os.WriteFile(keysFile, []byte(identity.Recipient().String()+"\n"), 0600))
+
+	t.Setenv(RecipientsEnv, "")
+	t.Setenv(RecipientsFileEnv, "")
+	t.Setenv(PassphraseEnv, "")
+	t.Setenv(PassphraseFileEnv, "")
+
+	recipients, err := RecipientsFromEnv()
+	require.NoError(t, err)
+	assert.Empty(t, recipients)
+
+	t.Setenv(PassphraseEnv, "correct horse battery staple")
+	recipients, err = RecipientsFromEnv()
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+	assert.IsType(t, &age.ScryptRecipient{}, recipients[0])
+
+	// Recipients win over the passphrase
+	t.Setenv(RecipientsFileEnv, keysFile)
+	recipients, err = RecipientsFromEnv()
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+	assert.Equal(t, identity.Recipient().String(), recipients[0].(*age.X25519Recipient).String())
+
+	t.Setenv(RecipientsFileEnv, filepath.Join(t.TempDir(), "missing.txt"))
+	_, err = RecipientsFromEnv()
+	assert.ErrorContains(t, err, "failed to read $"+RecipientsFileEnv)
+}
+
+func Test__IdentitiesFromEnv(t *testing.T) {
+	a, _ := age.GenerateX25519Identity()
+	b, _ := age.GenerateX25519Identity()
+	keyFile := filepath.Join(t.TempDir(), "key.txt")
+	require.NoError(t, os.WriteFile(keyFile, []byte("# created: today\n"+b.String()+"\n"), 0600))
+
+	t.Setenv(IdentityEnv, a.String())
+	t.Setenv(IdentityFileEnv, keyFile)
+	t.Setenv(PassphraseEnv, "correct horse battery staple")
+	t.Setenv(PassphraseFileEnv, "")
+
+	identities, err := IdentitiesFromEnv()
+	require.NoError(t, err)
+	assert.Len(t, identities, 3)
+
+	t.Setenv(IdentityEnv, "not-a-key")
+	_, err = IdentitiesFromEnv()
+	assert.ErrorContains(t, err, "invalid encryption identity in $"+IdentityEnv)
+}
+
+func Test__Context(t *testing.T) {
+	assert.Empty(t, IdentitiesFromContext(context.Background()))
+
+	identity, _ := age.GenerateX25519Identity()
+	ctx := NewContext(context.Background(), []age.Identity{identity})
+	assert.Equal(t, []age.Identity{identity}, IdentitiesFromContext(ctx))
+}
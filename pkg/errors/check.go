@@ -20,7 +20,7 @@ func Check(err error) {
 // Exit quits the application with a given value.
 func Exit(code int) {
 	if flag.Lookup("test.v") == nil {
-		os.Exit(1)
+		os.Exit(code)
 	} else {
 		fmt.Printf("Exit %d\n", code)
 	}
@@ -0,0 +1,429 @@
+package files
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats a directory can be bundled into.
+const (
+	ArchiveTarGz = "tar.gz"
+	ArchiveZip   = "zip"
+)
+
+// archiveExtensions maps the extensions of archive names to their formats.
+var archiveExtensions = []struct{ ext, format string }{
+	{".tar.gz", ArchiveTarGz},
+	{".tgz", ArchiveTarGz},
+	{".zip", ArchiveZip},
+}
+
+// ValidateArchiveFormat returns an error for unknown archive formats.
+func ValidateArchiveFormat(format string) error {
+	if format != ArchiveTarGz && format != ArchiveZip {
+		return fmt.Errorf("unknown archive format '%s': use %s or %s", format, ArchiveTarGz, ArchiveZip)
+	}
+
+	return nil
+}
+
+// ArchiveFormatOf returns the format of an archive from its name, and
+// the name without the extension.
+func ArchiveFormatOf(name string) (format, trimmed string, ok bool) {
+	for _, e := range archiveExtensions {
+		if strings.HasSuffix(strings.ToLower(name), e.ext) && len(name) > len(e.ext) {
+			return e.format, name[:len(name)-len(e.ext)], true
+		}
+	}
+
+	return "", "", false
+}
+
+// WriteArchive bundles the file or directory at source into w. Entries
+// are named relative to source, or after the file itself, and keep the
+// mode of the files and symlinks. It returns the number and total size
+// of the archived files.
+func WriteArchive(w io.Writer, format, source string) (int, int64, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	base := source
+	if !info.IsDir() {
+		base = filepath.Dir(source)
+	}
+
+	return WriteArchiveRelative(w, format, source, base)
+}
+
+// WriteArchiveRelative is like WriteArchive, but names the entries
+// relative to base, e.g. the parent of source to keep its name in them.
+func WriteArchiveRelative(w io.Writer, format, source, base string) (int, int64, error) {
+	var aw archiveWriter
+	switch format {
+	case ArchiveTarGz:
+		gz := gzip.NewWriter(w)
+		aw = &tarArchiveWriter{gz: gz, tw: tar.NewWriter(gz)}
+	case ArchiveZip:
+		aw = &zipArchiveWriter{zw: zip.NewWriter(w)}
+	default:
+		return 0, 0, ValidateArchiveFormat(format)
+	}
+
+	count, size := 0, int64(0)
+	err := Walk(source, func(filename string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(base, filename)
+		if err != nil || rel == "." {
+			return err
+		}
+
+		name := filepath.ToSlash(rel)
+		switch {
+		case info.IsDir():
+			return aw.dir(name+"/", info)
+		case info.Mode()&os.ModeSymlink != 0:
+			target, err := os.Readlink(filename)
+			if err != nil {
+				return err
+			}
+			return aw.symlink(name, target, info)
+		case info.Mode().IsRegular():
+			f, err := os.Open(filename)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+
+			if err := aw.file(name, info, f); err != nil {
+				return fmt.Errorf("failed to archive '%s': %v", filename, err)
+			}
+
+			count++
+			size += info.Size()
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, size, aw.close()
+}
+
+// ExtractArchive unpacks the archive in f into the directory destination.
+// Entries escaping destination are rejected, and symlinks are created
+// after every file, so no file is written through one. Existing files are
+// only replaced if overwrite is set. It returns the number and total size
+// of the extracted files.
+func ExtractArchive(f *os.File, format, destination string, overwrite bool) (int, int64, error) {
+	x := &archiveExtractor{destination: destination, overwrite: overwrite}
+	if err := os.MkdirAll(destination, 0755); err != nil {
+		return 0, 0, err
+	}
+
+	var err error
+	switch format {
+	case ArchiveTarGz:
+		err = x.extractTar(f)
+	case ArchiveZip:
+		err = x.extractZip(f)
+	default:
+		err = ValidateArchiveFormat(format)
+	}
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for _, link := range x.symlinks {
+		if err := x.createSymlink(link.name, link.target); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	return x.count, x.size, nil
+}
+
+type archiveWriter interface {
+	dir(name string, info os.FileInfo) error
+	symlink(name, target string, info os.FileInfo) error
+	file(name string, info os.FileInfo, r io.Reader) error
+	close() error
+}
+
+type tarArchiveWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func (a *tarArchiveWriter) dir(name string, info os.FileInfo) error {
+	return a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: name, Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()})
+}
+
+func (a *tarArchiveWriter) symlink(name, target string, info os.FileInfo) error {
+	return a.tw.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: name, Linkname: target, Mode: 0777, ModTime: info.ModTime()})
+}
+
+func (a *tarArchiveWriter) file(name string, info os.FileInfo, r io.Reader) error {
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: name, Size: info.Size(), Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()}
+	if err := a.tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err := Copy(a.tw, r)
+	return err
+}
+
+func (a *tarArchiveWriter) close() error {
+	if err := a.tw.Close(); err != nil {
+		return err
+	}
+
+	return a.gz.Close()
+}
+
+type zipArchiveWriter struct {
+	zw *zip.Writer
+}
+
+func (a *zipArchiveWriter) dir(name string, info os.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = name
+	_, err = a.zw.CreateHeader(header)
+	return err
+}
+
+func (a *zipArchiveWriter) symlink(name, target string, info os.FileInfo) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	// Zip stores the target of a symlink as its contents
+	header.Name = name
+	w, err := a.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(w, target)
+	return err
+}
+
+func (a *zipArchiveWriter) file(name string, info os.FileInfo, r io.Reader) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+
+	header.Name = name
+	header.Method = zip.Deflate
+	w, err := a.zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = Copy(w, r)
+	return err
+}
+
+func (a *zipArchiveWriter) close() error {
+	return a.zw.Close()
+}
+
+type archiveExtractor struct {
+	destination string
+	overwrite   bool
+	symlinks    []struct{ name, target string }
+	count       int
+	size        int64
+}
+
+func (x *archiveExtractor) extractTar(f *os.File) error {
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return fmt.Errorf("not a tar.gz archive: %v", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read tar.gz archive: %v", err)
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = x.createDir(header.Name)
+		case tar.TypeSymlink:
+			err = x.addSymlink(header.Name, header.Linkname)
+		case tar.TypeReg:
+			err = x.createFile(header.Name, os.FileMode(header.Mode).Perm(), tr)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (x *archiveExtractor) extractZip(f *os.File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	zr, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return fmt.Errorf("not a zip archive: %v", err)
+	}
+
+	for _, entry := range zr.File {
+		if err := x.extractZipEntry(entry); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (x *archiveExtractor) extractZipEntry(entry *zip.File) error {
+	mode := entry.Mode()
+	if mode.IsDir() {
+		return x.createDir(entry.Name)
+	}
+
+	r, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("failed to read '%s' from zip archive: %v", entry.Name, err)
+	}
+	defer r.Close()
+
+	if mode&os.ModeSymlink != 0 {
+		target, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return x.addSymlink(entry.Name, string(target))
+	}
+
+	return x.createFile(entry.Name, mode.Perm(), r)
+}
+
+// localPath returns where an entry is extracted, rejecting names that
+// would escape the destination.
+func (x *archiveExtractor) localPath(name string) (string, error) {
+	slashed := filepath.ToSlash(name)
+	for _, part := range strings.Split(slashed, "/") {
+		if part == ".." {
+			return "", fmt.Errorf("archive entry '%s' is outside of the destination", name)
+		}
+	}
+
+	if strings.HasPrefix(slashed, "/") || filepath.IsAbs(name) {
+		return "", fmt.Errorf("archive entry '%s' is outside of the destination", name)
+	}
+
+	return filepath.Join(x.destination, filepath.FromSlash(path.Clean(slashed))), nil
+}
+
+func (x *archiveExtractor) createDir(name string) error {
+	localPath, err := x.localPath(name)
+	if err != nil {
+		return err
+	}
+
+	return os.MkdirAll(localPath, 0755)
+}
+
+func (x *archiveExtractor) createFile(name string, mode os.FileMode, r io.Reader) error {
+	localPath, err := x.localPath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := x.checkOverwrite(localPath); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	if mode == 0 {
+		mode = 0644
+	}
+
+	// #nosec
+	f, err := os.OpenFile(localPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, mode)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := Copy(f, r)
+	if err != nil {
+		return fmt.Errorf("failed to extract '%s': %v", name, err)
+	}
+
+	x.count++
+	x.size += n
+	return f.Close()
+}
+
+func (x *archiveExtractor) addSymlink(name, target string) error {
+	if _, err := x.localPath(name); err != nil {
+		return err
+	}
+
+	x.symlinks = append(x.symlinks, struct{ name, target string }{name, target})
+	return nil
+}
+
+func (x *archiveExtractor) createSymlink(name, target string) error {
+	localPath, err := x.localPath(name)
+	if err != nil {
+		return err
+	}
+
+	if err := x.checkOverwrite(localPath); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	_ = os.Remove(localPath)
+	return os.Symlink(target, localPath)
+}
+
+func (x *archiveExtractor) checkOverwrite(localPath string) error {
+	if _, err := os.Lstat(localPath); err == nil && !x.overwrite {
+		return fmt.Errorf("'%s' already exists locally; delete it first, or use --force flag", localPath)
+	}
+
+	return nil
+}
@@ -0,0 +1,121 @@
+package files
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__ArchiveRoundTrip(t *testing.T) {
+	source := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(source, "nested", "empty"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "a.txt"), []byte("aa"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "nested", "run.sh"), []byte("#!/bin/sh"), 0755))
+	require.NoError(t, os.Symlink("a.txt", filepath.Join(source, "link")))
+
+	for _, format := range []string{ArchiveTarGz, ArchiveZip} {
+		t.Run(format, func(t *testing.T) {
+			archive := writeArchiveFile(t, format, source)
+			count, size, err := WriteArchive(&bytes.Buffer{}, format, source)
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+			assert.Equal(t, int64(11), size)
+
+			destination := filepath.Join(t.TempDir(), "out")
+			count, size, err = ExtractArchive(archive, format, destination, false)
+			require.NoError(t, err)
+			assert.Equal(t, 2, count)
+			assert.Equal(t, int64(11), size)
+
+			data, err := os.ReadFile(filepath.Join(destination, "link"))
+			require.NoError(t, err)
+			assert.Equal(t, "aa", string(data))
+
+			info, err := os.Stat(filepath.Join(destination, "nested", "run.sh"))
+			require.NoError(t, err)
+			assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+			assert.DirExists(t, filepath.Join(destination, "nested", "empty"))
+
+			// Existing files are only replaced with overwrite
+			_, err = archive.Seek(0, 0)
+			require.NoError(t, err)
+			_, _, err = ExtractArchive(archive, format, destination, false)
+			assert.ErrorContains(t, err, "already exists locally")
+
+			_, err = archive.Seek(0, 0)
+			require.NoError(t, err)
+			_, _, err = ExtractArchive(archive, format, destination, true)
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("single file", func(t *testing.T) {
+		archive := writeArchiveFile(t, ArchiveZip, filepath.Join(source, "a.txt"))
+
+		destination := t.TempDir()
+		count, _, err := ExtractArchive(archive, ArchiveZip, destination, false)
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.FileExists(t, filepath.Join(destination, "a.txt"))
+	})
+}
+
+func Test__ExtractArchiveRejectsEscapingEntries(t *testing.T) {
+	for _, name := range []string{"../evil.txt", "nested/../../evil.txt", "/evil.txt"} {
+		buf := &bytes.Buffer{}
+		gz := gzip.NewWriter(buf)
+		tw := tar.NewWriter(gz)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Size: 4, Mode: 0644}))
+		_, err := tw.Write([]byte("evil"))
+		require.NoError(t, err)
+		require.NoError(t, tw.Close())
+		require.NoError(t, gz.Close())
+
+		archivePath := filepath.Join(t.TempDir(), "evil.tar.gz")
+		require.NoError(t, os.WriteFile(archivePath, buf.Bytes(), 0600))
+		f, err := os.Open(archivePath)
+		require.NoError(t, err)
+		defer f.Close()
+
+		parent := t.TempDir()
+		_, _, err = ExtractArchive(f, ArchiveTarGz, filepath.Join(parent, "out"), false)
+		assert.ErrorContains(t, err, "outside of the destination", name)
+		assert.NoFileExists(t, filepath.Join(parent, "evil.txt"))
+	}
+}
+
+func Test__ArchiveFormatOf(t *testing.T) {
+	check := func(name, format, trimmed string, ok bool) {
+		f, n, o := ArchiveFormatOf(name)
+		assert.Equal(t, format, f, name)
+		assert.Equal(t, trimmed, n, name)
+		assert.Equal(t, ok, o, name)
+	}
+
+	check("build.tar.gz", ArchiveTarGz, "build", true)
+	check("dir/build.TGZ", ArchiveTarGz, "dir/build", true)
+	check("build.zip", ArchiveZip, "build", true)
+	check("build.tar", "", "", false)
+	check(".zip", "", "", false)
+
+	assert.NoError(t, ValidateArchiveFormat(ArchiveZip))
+	assert.Error(t, ValidateArchiveFormat("rar"))
+}
+
+func writeArchiveFile(t *testing.T, format, source string) *os.File {
+	f, err := os.Create(filepath.Join(t.TempDir(), "archive."+format))
+	require.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	_, _, err = WriteArchive(f, format, source)
+	require.NoError(t, err)
+	_, err = f.Seek(0, 0)
+	require.NoError(t, err)
+	return f
+}
@@ -0,0 +1,48 @@
+package files
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// SHA256File returns the hex-encoded SHA256 checksum of a local file.
+func SHA256File(localPath string) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to open '%s': %v", localPath, err)
+	}
+
+	// #nosec
+	defer f.Close()
+
+	hash := sha256.New()
+	if _, err := Copy(hash, f); err != nil {
+		return "", fmt.Errorf("failed to read '%s': %v", localPath, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// FileDigests returns the hex-encoded MD5 and SHA256 checksums of a local
+// file, reading it once. The MD5 is only meant for comparing a file with
+// the ETag of an S3 object uploaded in a single part.
+func FileDigests(localPath string) (string, string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to open '%s': %v", localPath, err)
+	}
+
+	// #nosec
+	defer f.Close()
+
+	md5Hash, sha256Hash := md5.New(), sha256.New() // #nosec
+	if _, err := Copy(io.MultiWriter(md5Hash, sha256Hash), f); err != nil {
+		return "", "", fmt.Errorf("failed to read '%s': %v", localPath, err)
+	}
+
+	return hex.EncodeToString(md5Hash.Sum(nil)), hex.EncodeToString(sha256Hash.Sum(nil)), nil
+}
@@ -0,0 +1,29 @@
+package files
+
+import (
+	"io"
+	"sync"
+)
+
+// copyBufferSize is the size of the buffers of Copy: larger than the
+// 32 KB of io.Copy, so large files are transferred with fewer system calls.
+const copyBufferSize = 256 * 1024
+
+var copyBuffers = sync.Pool{
+	New: func() any {
+		buf := make([]byte, copyBufferSize)
+		return &buf
+	},
+}
+
+// Copy is io.Copy with a pooled buffer, so transfers reuse a few buffers
+// instead of allocating one per file, and use constant memory however
+// large the file. Like io.Copy, it uses no buffer at all if src is an
+// io.WriterTo or dst an io.ReaderFrom, e.g. to let the kernel copy
+// between files.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	buf := copyBuffers.Get().(*[]byte)
+	defer copyBuffers.Put(buf)
+
+	return io.CopyBuffer(dst, src, *buf)
+}
@@ -0,0 +1,168 @@
+package files
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile is the file of a pushed directory listing the files and
+// directories under it that are not pushed, e.g. caches and temp files.
+const IgnoreFile = ".artifactignore"
+
+// Filter selects the files of a pushed directory by their slash-separated
+// path relative to it. A nil Filter keeps every file.
+type Filter struct {
+	include []string
+	exclude []string
+	ignore  []ignoreRule
+}
+
+// ignoreRule is a line of an IgnoreFile.
+type ignoreRule struct {
+	pattern string
+	negate  bool // the line starts with "!": matching files are kept
+	dirOnly bool // the line ends with "/": only directories match
+}
+
+// NewFilter returns the filter of the directory dir: files must match one
+// of the include globs, if any, and neither match an exclude glob, nor be
+// in a directory that does, nor be ignored by the IgnoreFile of dir. It
+// returns nil if nothing is filtered out.
+func NewFilter(dir string, include, exclude []string) (*Filter, error) {
+	for _, patterns := range [][]string{include, exclude} {
+		for _, pattern := range patterns {
+			if err := ValidateGlob(pattern); err != nil {
+				return nil, fmt.Errorf("invalid glob '%s': %v", pattern, err)
+			}
+		}
+	}
+
+	ignore, err := readIgnoreFile(filepath.Join(dir, IgnoreFile))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(include) == 0 && len(exclude) == 0 && len(ignore) == 0 {
+		return nil, nil
+	}
+
+	return &Filter{include: include, exclude: exclude, ignore: ignore}, nil
+}
+
+// Keep reports whether the file at the slash-separated path rel, relative
+// to the filtered directory, is kept.
+func (f *Filter) Keep(rel string) bool {
+	if f == nil {
+		return true
+	}
+
+	if len(f.include) > 0 && !matchAny(f.include, rel) {
+		return false
+	}
+
+	if matchAny(f.exclude, withParents(rel)...) {
+		return false
+	}
+
+	// The last line matching the file, or a directory it is in, decides
+	ignored := false
+	for _, rule := range f.ignore {
+		if rule.matches(rel) {
+			ignored = !rule.negate
+		}
+	}
+
+	return !ignored
+}
+
+// readIgnoreFile parses the IgnoreFile at filename, if there is one. Its
+// lines are globs, see MatchGlob, with the syntax of .gitignore files:
+// blank lines and lines starting with "#" are skipped, "!" keeps files
+// an earlier line ignored, and a trailing "/" only matches directories.
+// Globs without any other "/" match at any depth, the others are relative
+// to the directory.
+func readIgnoreFile(filename string) ([]ignoreRule, error) {
+	f, err := os.Open(filename)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %v", filename, err)
+	}
+	defer f.Close()
+
+	rules := []ignoreRule{}
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		rule := ignoreRule{}
+		if strings.HasPrefix(text, "!") {
+			rule.negate, text = true, text[1:]
+		}
+		if strings.HasSuffix(text, "/") {
+			rule.dirOnly, text = true, strings.TrimSuffix(text, "/")
+		}
+		if strings.Contains(text, "/") {
+			text = strings.TrimPrefix(text, "/")
+		} else {
+			text = "**/" + text
+		}
+
+		if err := ValidateGlob(text); err != nil {
+			return nil, fmt.Errorf("invalid glob on line %d of '%s': %v", line, filename, err)
+		}
+
+		rule.pattern = text
+		rules = append(rules, rule)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read '%s': %v", filename, err)
+	}
+
+	return rules, nil
+}
+
+// matches reports whether the rule matches the file at rel, or one of the
+// directories it is in.
+func (r ignoreRule) matches(rel string) bool {
+	names := withParents(rel)
+	if r.dirOnly {
+		names = names[:len(names)-1]
+	}
+
+	return matchAny([]string{r.pattern}, names...)
+}
+
+// withParents returns the directories rel is in, from the outermost, and
+// then rel itself, e.g. "a", "a/b" and "a/b/c.txt" for "a/b/c.txt".
+func withParents(rel string) []string {
+	segments := strings.Split(rel, "/")
+	names := make([]string, len(segments))
+	for i := range segments {
+		names[i] = strings.Join(segments[:i+1], "/")
+	}
+
+	return names
+}
+
+// matchAny reports whether one of the names matches one of the patterns.
+// The patterns are validated upfront, so matching cannot fail.
+func matchAny(patterns []string, names ...string) bool {
+	for _, pattern := range patterns {
+		for _, name := range names {
+			if matched, _ := MatchGlob(pattern, name); matched {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,69 @@
+package files
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__NewFilter(t *testing.T) {
+	dir := t.TempDir()
+
+	filter, err := NewFilter(dir, nil, nil)
+	require.NoError(t, err)
+	assert.Nil(t, filter)
+	assert.True(t, filter.Keep("anything"))
+
+	_, err = NewFilter(dir, []string{"[a"}, nil)
+	assert.ErrorContains(t, err, "invalid glob '[a'")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, IgnoreFile), []byte("ok\n[b\n"), 0644))
+	_, err = NewFilter(dir, nil, nil)
+	assert.ErrorContains(t, err, "invalid glob on line 2")
+}
+
+func Test__FilterIncludeExclude(t *testing.T) {
+	filter, err := NewFilter(t.TempDir(), []string{"**/*.xml", "coverage/**"}, []string{".git", "**/tmp"})
+	require.NoError(t, err)
+
+	assert.True(t, filter.Keep("junit.xml"))
+	assert.True(t, filter.Keep("reports/unit/junit.xml"))
+	assert.True(t, filter.Keep("coverage/index.html"))
+	assert.False(t, filter.Keep("build.log"))
+
+	// Excluded directories drop everything under them
+	assert.False(t, filter.Keep(".git/config.xml"))
+	assert.False(t, filter.Keep("reports/tmp/junit.xml"))
+}
+
+func Test__FilterIgnoreFile(t *testing.T) {
+	dir := t.TempDir()
+	ignore := `# Build caches
+node_modules/
+*.tmp
+/out.log
+logs/*.log
+!logs/keep.log
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, IgnoreFile), []byte(ignore), 0644))
+
+	filter, err := NewFilter(dir, nil, nil)
+	require.NoError(t, err)
+
+	assert.False(t, filter.Keep("node_modules/a/index.js"))
+	assert.False(t, filter.Keep("web/node_modules/index.js"))
+	assert.True(t, filter.Keep("node_modules"), "only directories match a trailing slash")
+
+	assert.False(t, filter.Keep("a.tmp"))
+	assert.False(t, filter.Keep("deep/down/b.tmp"))
+
+	assert.False(t, filter.Keep("out.log"))
+	assert.True(t, filter.Keep("nested/out.log"))
+
+	assert.False(t, filter.Keep("logs/build.log"))
+	assert.True(t, filter.Keep("logs/keep.log"))
+	assert.True(t, filter.Keep("report.html"))
+}
@@ -0,0 +1,78 @@
+package files
+
+import (
+	"path"
+	"strings"
+)
+
+// MatchGlob reports whether the slash-separated name matches pattern.
+// Besides the path.Match syntax, a "**" segment matches zero or more
+// directories, so "reports/**/*.xml" matches both "reports/junit.xml"
+// and "reports/unit/a/junit.xml".
+func MatchGlob(pattern, name string) (bool, error) {
+	return matchSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+// ValidateGlob returns path.ErrBadPattern if pattern is malformed.
+func ValidateGlob(pattern string) error {
+	for _, segment := range strings.Split(pattern, "/") {
+		if _, err := path.Match(segment, ""); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IsGlob reports whether name contains glob metacharacters, so it should be
+// expanded rather than used as a path.
+func IsGlob(name string) bool {
+	return strings.ContainsAny(name, "*?[")
+}
+
+// GlobBase returns the leading segments of pattern without metacharacters,
+// under which every name it matches is found, e.g. "reports" for
+// "reports/**/*.xml", or "" if the first segment is a glob.
+func GlobBase(pattern string) string {
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if IsGlob(segment) {
+			return strings.Join(segments[:i], "/")
+		}
+	}
+
+	return pattern
+}
+
+func matchSegments(pattern, name []string) (bool, error) {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			rest := pattern[1:]
+			if len(rest) == 0 {
+				return true, nil
+			}
+
+			for i := 0; i <= len(name); i++ {
+				matched, err := matchSegments(rest, name[i:])
+				if err != nil || matched {
+					return matched, err
+				}
+			}
+
+			return false, nil
+		}
+
+		if len(name) == 0 {
+			return false, nil
+		}
+
+		matched, err := path.Match(pattern[0], name[0])
+		if err != nil || !matched {
+			return false, err
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0, nil
+}
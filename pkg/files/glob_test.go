@@ -0,0 +1,48 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__MatchGlob(t *testing.T) {
+	check := func(pattern, name string, expected bool) {
+		matched, err := MatchGlob(pattern, name)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, matched, pattern, name)
+	}
+
+	check("*.txt", "a.txt", true)
+	check("*.txt", "dir/a.txt", false)
+	check("dir/*.txt", "dir/a.txt", true)
+	check("**/*.txt", "a.txt", true)
+	check("**/*.txt", "a/b/c.txt", true)
+	check("**/*.txt", "a/b/c.log", false)
+	check("reports/**/junit*.xml", "reports/junit-1.xml", true)
+	check("reports/**/junit*.xml", "reports/unit/a/junit-2.xml", true)
+	check("reports/**/junit*.xml", "other/junit-1.xml", false)
+	check("releases/**", "releases/v1/app.tar", true)
+	check("releases/**", "other/app.tar", false)
+	check("file?.log", "file1.log", true)
+	check("file[0-9].log", "filea.log", false)
+}
+
+func Test__ValidateGlob(t *testing.T) {
+	assert.Nil(t, ValidateGlob("**/*.txt"))
+	assert.NotNil(t, ValidateGlob("reports/[a-"))
+}
+
+func Test__IsGlob(t *testing.T) {
+	assert.True(t, IsGlob("**/*.png"))
+	assert.True(t, IsGlob("file?.log"))
+	assert.True(t, IsGlob("file[0-9].log"))
+	assert.False(t, IsGlob("screenshots/a.png"))
+}
+
+func Test__GlobBase(t *testing.T) {
+	assert.Equal(t, "screenshots", GlobBase("screenshots/**/*.png"))
+	assert.Equal(t, "a/b", GlobBase("a/b/*.txt"))
+	assert.Equal(t, "", GlobBase("*.txt"))
+	assert.Equal(t, "", GlobBase("**/*.txt"))
+}
@@ -0,0 +1,125 @@
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const lockPollInterval = 100 * time.Millisecond
+
+// DestinationLock is an advisory lock held while writing into a local destination,
+// so concurrent artifact processes on the same machine don't interleave writes.
+// The operating system releases it if the process dies.
+type DestinationLock struct {
+	file *os.File
+	path string
+}
+
+// LockDestination takes an exclusive lock on the local path, waiting up to
+// timeout for other artifact processes holding it. Lock files live in a
+// temporary directory of the user, keyed by the absolute destination path,
+// so nothing is left behind in the destination itself, and are removed on
+// Unlock.
+func LockDestination(destination string, timeout time.Duration) (*DestinationLock, error) {
+	lockPath, err := lockFilePath(destination)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(timeout)
+	waiting := false
+
+	for {
+		locked, err := tryLockPath(lockPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to lock '%s': %v", destination, err)
+		}
+
+		if locked != nil {
+			return &DestinationLock{file: locked, path: lockPath}, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %v waiting for another artifact process writing to '%s'", timeout, destination)
+		}
+
+		if !waiting {
+			log.Infof("Waiting for another artifact process writing to '%s'...\n", destination)
+			waiting = true
+		}
+
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// tryLockPath opens the lock file at lockPath and locks it, returning nil
+// if another process holds it. A lock taken on a file its holder removed
+// on Unlock meanwhile is given up, and nil returned to try again.
+func tryLockPath(lockPath string) (*os.File, error) {
+	// #nosec
+	file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open lock file '%s': %v", lockPath, err)
+	}
+
+	locked, err := tryLockFile(file)
+	if err != nil || !locked {
+		file.Close()
+		return nil, err
+	}
+
+	opened, err := file.Stat()
+	if err != nil {
+		_ = unlockFile(file)
+		file.Close()
+		return nil, err
+	}
+
+	current, err := os.Stat(lockPath)
+	if err != nil || !os.SameFile(opened, current) {
+		_ = unlockFile(file)
+		file.Close()
+		return nil, nil
+	}
+
+	return file, nil
+}
+
+// Unlock removes the lock file and releases the lock.
+func (l *DestinationLock) Unlock() error {
+	// Removed while still locked, so whoever locks it next either holds
+	// the only lock file or notices it was removed, see tryLockPath.
+	// Windows cannot remove open files, so it is left there.
+	_ = os.Remove(l.path)
+
+	if err := unlockFile(l.file); err != nil {
+		l.file.Close()
+		return err
+	}
+
+	return l.file.Close()
+}
+
+// lockFilePath returns the lock file of destination, in a directory only
+// the current user can write to, so users of a shared machine do not
+// create lock files the others cannot open.
+func lockFilePath(destination string) (string, error) {
+	absolute, err := filepath.Abs(destination)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve '%s': %v", destination, err)
+	}
+
+	dir := filepath.Join(os.TempDir(), fmt.Sprintf("artifact-locks-%d", os.Getuid()))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create lock directory '%s': %v", dir, err)
+	}
+
+	sum := sha256.Sum256([]byte(filepath.Clean(absolute)))
+	return filepath.Join(dir, hex.EncodeToString(sum[:16])+".lock"), nil
+}
@@ -0,0 +1,44 @@
+package files
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__LockDestination(t *testing.T) {
+	destination := filepath.Join(t.TempDir(), "reports")
+
+	lock, err := LockDestination(destination, time.Second)
+	require.NoError(t, err)
+
+	// A second lock on the same destination times out while the first is held
+	_, err = LockDestination(destination, 200*time.Millisecond)
+	assert.ErrorContains(t, err, "timed out")
+
+	// Other destinations are not affected
+	other, err := LockDestination(destination+"-other", time.Second)
+	require.NoError(t, err)
+	require.NoError(t, other.Unlock())
+
+	// Once released, the destination can be locked again
+	released := make(chan struct{})
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		assert.NoError(t, lock.Unlock())
+		close(released)
+	}()
+
+	again, err := LockDestination(destination, 5*time.Second)
+	require.NoError(t, err)
+	<-released
+
+	// Lock files are removed on unlock
+	lockPath, err := lockFilePath(destination)
+	require.NoError(t, err)
+	require.NoError(t, again.Unlock())
+	assert.NoFileExists(t, lockPath)
+}
@@ -0,0 +1,56 @@
+package files
+
+import (
+	"path"
+	"strings"
+)
+
+// MappedDestination returns the local destination for pulling source from
+// a resourceType store, according to mappings from remote prefixes to local
+// directories, e.g. {"reports/": "./artifacts/reports"}.
+//
+// Prefixes may be qualified with a resource type ("workflow:reports/") to only
+// apply to that store; qualified prefixes win over unqualified ones, and longer
+// prefixes win over shorter ones. Returns "" if no mapping applies.
+//
+// Prefixes are matched case-insensitively, since viper lowercases the keys
+// of the pullMappings setting; the pulled path keeps its own case.
+func MappedDestination(mappings map[string]string, resourceType, source string) string {
+	relative := ToRelative(source)
+	bestPrefix, bestDirectory, bestScoped := "", "", false
+	found := false
+
+	for key, directory := range mappings {
+		prefix, scoped := key, false
+		if i := strings.Index(key, ":"); i >= 0 {
+			if !strings.EqualFold(key[:i], resourceType) {
+				continue
+			}
+			prefix, scoped = key[i+1:], true
+		}
+
+		prefix = strings.TrimSuffix(ToRelative(prefix), "/")
+		if prefix != "" && !hasPrefixFold(relative+"/", prefix+"/") {
+			continue
+		}
+
+		better := !found ||
+			(scoped && !bestScoped) ||
+			(scoped == bestScoped && len(prefix) > len(bestPrefix))
+
+		if better {
+			bestPrefix, bestDirectory, bestScoped = prefix, directory, scoped
+			found = true
+		}
+	}
+
+	if !found {
+		return ""
+	}
+
+	return path.Join(bestDirectory, relative[len(bestPrefix):])
+}
+
+func hasPrefixFold(s, prefix string) bool {
+	return len(s) >= len(prefix) && strings.EqualFold(s[:len(prefix)], prefix)
+}
@@ -0,0 +1,40 @@
+package files
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__MappedDestination(t *testing.T) {
+	mappings := map[string]string{
+		"reports/":          "./artifacts/reports",
+		"reports/coverage":  "coverage",
+		"workflow:reports/": "wf-reports",
+		"job:":              "job-files",
+	}
+
+	check := func(resourceType, source, expected string) {
+		assert.Equal(t, expected, MappedDestination(mappings, resourceType, source), resourceType, source)
+	}
+
+	check(ResourceTypeProject, "reports", "artifacts/reports")
+	check(ResourceTypeProject, "reports/junit.xml", "artifacts/reports/junit.xml")
+	check(ResourceTypeProject, "./reports/a/b.xml", "artifacts/reports/a/b.xml")
+	check(ResourceTypeProject, "reports/coverage/lcov.info", "coverage/lcov.info")
+	check(ResourceTypeProject, "reports-old/junit.xml", "")
+	check(ResourceTypeProject, "other.txt", "")
+	check(ResourceTypeWorkflow, "reports/coverage/lcov.info", "wf-reports/coverage/lcov.info")
+	check(ResourceTypeJob, "other.txt", "job-files/other.txt")
+	check(ResourceTypeJob, "reports/junit.xml", "job-files/reports/junit.xml")
+}
+
+func Test__MappedDestination_MixedCase(t *testing.T) {
+	// viper lowercases the keys of the setting, e.g. of "Reports/"
+	mappings := map[string]string{"reports/": "out", "Workflow:Build/": "Build"}
+
+	assert.Equal(t, "out/Junit.xml", MappedDestination(mappings, ResourceTypeJob, "Reports/Junit.xml"))
+	assert.Equal(t, "out", MappedDestination(mappings, ResourceTypeJob, "REPORTS"))
+	assert.Equal(t, "Build/App.zip", MappedDestination(mappings, ResourceTypeWorkflow, "Build/App.zip"))
+	assert.Equal(t, "", MappedDestination(mappings, ResourceTypeJob, "Reports-old/Junit.xml"))
+}
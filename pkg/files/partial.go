@@ -0,0 +1,173 @@
+package files
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// PartialSuffix is appended to the name of a file while it is downloaded.
+const PartialSuffix = ".partial"
+
+// etagSuffix is appended to the name of the partial file to record the
+// version of the remote file being downloaded.
+const etagSuffix = ".etag"
+
+// Partial is a file being downloaded. Its contents are written next to it,
+// to a file ending in PartialSuffix, which is only moved into place once
+// the download completes. The ETag of the remote file is recorded along
+// with it, so an interrupted download is resumed, instead of started over,
+// if the remote file did not change since.
+type Partial struct {
+	path    string
+	f       *os.File
+	ETag    string // ETag of the remote file being downloaded, if known
+	Offset  int64  // Bytes downloaded by an interrupted run
+	written int64
+}
+
+// OpenPartial opens the partial download of the local file path, keeping
+// what an interrupted download left, if its ETag was recorded.
+func OpenPartial(path string) (*Partial, error) {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory '%s': %v", dir, err)
+	}
+
+	p := &Partial{path: path}
+
+	// #nosec
+	if etag, err := os.ReadFile(p.partialPath() + etagSuffix); err == nil {
+		p.ETag = strings.TrimSpace(string(etag))
+	}
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	if p.ETag == "" {
+		flags |= os.O_TRUNC
+	}
+
+	// #nosec
+	f, err := os.OpenFile(p.partialPath(), flags, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create local file '%s': %v", p.partialPath(), err)
+	}
+	p.f = f
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	p.Offset = info.Size()
+
+	return p, nil
+}
+
+// WriteAtomically writes the contents of r to the local file path through
+// a Partial, so the file only appears once it is complete: readers never
+// see it truncated, and a failed write leaves the previous file, if any,
+// in place. What an interrupted download of path left is discarded.
+func WriteAtomically(path string, r io.Reader) error {
+	partial, err := OpenPartial(path)
+	if err != nil {
+		return err
+	}
+
+	if partial.ETag != "" || partial.Offset > 0 {
+		if err := partial.Restart(""); err != nil {
+			_ = partial.Discard()
+			return err
+		}
+	}
+
+	if _, err := Copy(partial, r); err != nil {
+		_ = partial.Discard()
+		return fmt.Errorf("failed to write to local file: %w", err)
+	}
+
+	return partial.Complete()
+}
+
+// Restart discards what was downloaded, and records the ETag of the remote
+// file downloaded from now on, e.g. when the remote file changed since the
+// interrupted download.
+func (p *Partial) Restart(etag string) error {
+	if err := p.f.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate '%s': %v", p.partialPath(), err)
+	}
+
+	p.ETag, p.Offset, p.written = etag, 0, 0
+	if etag == "" {
+		return removeIfExists(p.partialPath() + etagSuffix)
+	}
+
+	return os.WriteFile(p.partialPath()+etagSuffix, []byte(etag), 0644)
+}
+
+// Write appends to the partial file.
+func (p *Partial) Write(b []byte) (int, error) {
+	n, err := p.f.Write(b)
+	p.written += int64(n)
+	return n, err
+}
+
+// Name returns the path of the partial file.
+func (p *Partial) Name() string {
+	return p.partialPath()
+}
+
+// Complete moves the downloaded file into place.
+func (p *Partial) Complete() error {
+	if err := p.f.Close(); err != nil {
+		return fmt.Errorf("failed to write '%s': %v", p.partialPath(), err)
+	}
+
+	if err := os.Rename(p.partialPath(), p.path); err != nil {
+		return fmt.Errorf("failed to move '%s' into place: %v", p.partialPath(), err)
+	}
+
+	return removeIfExists(p.partialPath() + etagSuffix)
+}
+
+// Close closes the partial file, keeping what was downloaded to resume it
+// later. Partial files with nothing to resume are removed.
+func (p *Partial) Close() error {
+	if err := p.f.Close(); err != nil {
+		return err
+	}
+
+	if p.ETag == "" || p.Offset+p.written == 0 {
+		return p.remove()
+	}
+
+	return nil
+}
+
+// Discard closes and removes the partial file, e.g. when the downloaded
+// contents turned out to be corrupt.
+func (p *Partial) Discard() error {
+	_ = p.f.Close()
+	return p.remove()
+}
+
+func (p *Partial) remove() error {
+	if err := removeIfExists(p.partialPath()); err != nil {
+		return err
+	}
+
+	return removeIfExists(p.partialPath() + etagSuffix)
+}
+
+func (p *Partial) partialPath() string {
+	return p.path + PartialSuffix
+}
+
+func removeIfExists(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
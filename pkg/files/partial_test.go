@@ -0,0 +1,124 @@
+package files
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__Partial(t *testing.T) {
+	t.Run("complete moves the file into place", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dir", "file.bin")
+
+		partial, err := OpenPartial(path)
+		assert.Nil(t, err)
+		assert.Equal(t, int64(0), partial.Offset)
+		assert.Nil(t, partial.Restart(`"v1"`))
+
+		partial.Write([]byte("hello"))
+		assert.Nil(t, partial.Complete())
+
+		assertFileContents(t, path, "hello")
+		assert.NoFileExists(t, path+PartialSuffix)
+		assert.NoFileExists(t, path+PartialSuffix+etagSuffix)
+	})
+
+	t.Run("interrupted download is resumed", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.bin")
+
+		partial, _ := OpenPartial(path)
+		partial.Restart(`"v1"`)
+		partial.Write([]byte("hel"))
+		assert.Nil(t, partial.Close())
+		assert.NoFileExists(t, path)
+
+		partial, err := OpenPartial(path)
+		assert.Nil(t, err)
+		assert.Equal(t, `"v1"`, partial.ETag)
+		assert.Equal(t, int64(3), partial.Offset)
+
+		partial.Write([]byte("lo"))
+		assert.Nil(t, partial.Complete())
+		assertFileContents(t, path, "hello")
+	})
+
+	t.Run("restart discards what was downloaded", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.bin")
+
+		partial, _ := OpenPartial(path)
+		partial.Restart(`"v1"`)
+		partial.Write([]byte("old"))
+		partial.Close()
+
+		partial, _ = OpenPartial(path)
+		assert.Nil(t, partial.Restart(`"v2"`))
+		assert.Equal(t, int64(0), partial.Offset)
+		partial.Write([]byte("new"))
+		assert.Nil(t, partial.Complete())
+		assertFileContents(t, path, "new")
+	})
+
+	t.Run("nothing is kept without an etag", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.bin")
+
+		partial, _ := OpenPartial(path)
+		partial.Restart("")
+		partial.Write([]byte("hel"))
+		assert.Nil(t, partial.Close())
+		assert.NoFileExists(t, path+PartialSuffix)
+
+		partial, _ = OpenPartial(path)
+		assert.Equal(t, int64(0), partial.Offset)
+		assert.Nil(t, partial.Close())
+		assert.NoFileExists(t, path+PartialSuffix)
+	})
+
+	t.Run("discard removes the partial file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.bin")
+
+		partial, _ := OpenPartial(path)
+		partial.Restart(`"v1"`)
+		partial.Write([]byte("hel"))
+		assert.Nil(t, partial.Discard())
+		assert.NoFileExists(t, path+PartialSuffix)
+		assert.NoFileExists(t, path+PartialSuffix+etagSuffix)
+	})
+
+	t.Run("atomic write replaces the file once complete", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "dir", "file.bin")
+		assert.Nil(t, WriteAtomically(path, strings.NewReader("hello")))
+		assertFileContents(t, path, "hello")
+
+		// What an interrupted resumable download left is not appended to
+		partial, _ := OpenPartial(path)
+		partial.Restart(`"v1"`)
+		partial.Write([]byte("stale"))
+		partial.Close()
+
+		assert.Nil(t, WriteAtomically(path, strings.NewReader("world")))
+		assertFileContents(t, path, "world")
+		assert.NoFileExists(t, path+PartialSuffix+etagSuffix)
+	})
+
+	t.Run("failed atomic write keeps the previous file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file.bin")
+		os.WriteFile(path, []byte("previous"), 0644)
+
+		r := io.MultiReader(strings.NewReader("trunc"), iotest.ErrReader(errors.New("connection reset")))
+		assert.ErrorContains(t, WriteAtomically(path, r), "connection reset")
+		assertFileContents(t, path, "previous")
+		assert.NoFileExists(t, path+PartialSuffix)
+	})
+}
+
+func assertFileContents(t *testing.T, path, expected string) {
+	contents, err := os.ReadFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, string(contents))
+}
@@ -0,0 +1,55 @@
+package files
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/common"
+)
+
+// ErrNotEnoughSpace is returned by CheckFreeSpace when the filesystem of
+// a destination has less free space than needed.
+type ErrNotEnoughSpace struct {
+	Path   string
+	Needed int64
+	Free   int64
+}
+
+func (e *ErrNotEnoughSpace) Error() string {
+	return fmt.Sprintf("not enough free space for '%s': %s needed, but only %s free", e.Path, common.FormatSize(e.Needed), common.FormatSize(e.Free))
+}
+
+// CheckFreeSpace returns an *ErrNotEnoughSpace if the filesystem the local
+// path destination is, or would be created, on has less than needed bytes
+// free for unprivileged users.
+func CheckFreeSpace(destination string, needed int64) error {
+	dir, err := filepath.Abs(destination)
+	if err != nil {
+		return err
+	}
+
+	// Find the closest directory that exists
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			break
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	free, err := freeSpace(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get the free space of '%s': %v", dir, err)
+	}
+
+	if free < needed {
+		return &ErrNotEnoughSpace{Path: destination, Needed: needed, Free: free}
+	}
+
+	return nil
+}
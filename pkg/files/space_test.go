@@ -0,0 +1,23 @@
+package files
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__CheckFreeSpace(t *testing.T) {
+	// Destinations that do not exist yet are checked on their closest parent
+	destination := filepath.Join(t.TempDir(), "not", "created")
+	assert.NoError(t, CheckFreeSpace(destination, 1))
+
+	err := CheckFreeSpace(destination, 1<<62)
+	var notEnough *ErrNotEnoughSpace
+	if assert.True(t, errors.As(err, &notEnough)) {
+		assert.Equal(t, destination, notEnough.Path)
+		assert.Less(t, notEnough.Free, notEnough.Needed)
+		assert.Contains(t, err.Error(), "not enough free space for '"+destination+"': 4.0 EB needed")
+	}
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package files
+
+import "syscall"
+
+func freeSpace(dir string) (int64, error) {
+	stat := syscall.Statfs_t{}
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+
+	// #nosec
+	return int64(stat.Bavail) * int64(stat.Bsize), nil
+}
@@ -0,0 +1,20 @@
+//go:build windows
+
+package files
+
+import "golang.org/x/sys/windows"
+
+func freeSpace(dir string) (int64, error) {
+	name, err := windows.UTF16PtrFromString(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(name, &free, nil, nil); err != nil {
+		return 0, err
+	}
+
+	// #nosec
+	return int64(free), nil
+}
@@ -0,0 +1,156 @@
+package files
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// WalkConcurrency is the number of directories Walk reads, and of files it
+// stats, at once.
+var WalkConcurrency = 16
+
+// walkStatBatch is the number of files of a directory stat'ed by one worker.
+const walkStatBatch = 256
+
+// Walk walks the file tree rooted at root like filepath.Walk does, calling
+// fn for every file and directory in lexical order, with the same handling
+// of errors and filepath.SkipDir. Unlike filepath.Walk, it reads
+// directories and stats their files WalkConcurrency at a time, ahead of
+// fn, so walking huge trees is not bound by the latency of each call.
+func Walk(root string, fn filepath.WalkFunc) error {
+	w := &walker{sem: make(chan struct{}, max(WalkConcurrency, 1)), stop: make(chan struct{})}
+	defer close(w.stop)
+
+	info, err := os.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = w.walk(root, info, w.read(root, info), fn)
+	}
+
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+
+	return err
+}
+
+// walker reads the directories of a Walk in the background.
+type walker struct {
+	sem  chan struct{}
+	stop chan struct{}
+}
+
+// walkedDir is a directory read by a walker. Its entries, sorted by name,
+// and err are set once done is closed.
+type walkedDir struct {
+	entries []walkedEntry
+	err     error
+	done    chan struct{}
+}
+
+type walkedEntry struct {
+	name string
+	info fs.FileInfo
+	err  error
+	dir  *walkedDir
+}
+
+// read starts reading the directory path, and the directories under it,
+// in the background. It returns nil if info is not a directory.
+func (w *walker) read(path string, info fs.FileInfo) *walkedDir {
+	if !info.IsDir() {
+		return nil
+	}
+
+	d := &walkedDir{done: make(chan struct{})}
+	go func() {
+		defer close(d.done)
+
+		if !w.acquire() {
+			return
+		}
+		entries, err := os.ReadDir(path)
+		<-w.sem
+
+		d.entries, d.err = make([]walkedEntry, len(entries)), err
+		w.stat(entries, d.entries)
+
+		for i := range d.entries {
+			e := &d.entries[i]
+			if e.err == nil && e.info != nil {
+				e.dir = w.read(filepath.Join(path, e.name), e.info)
+			}
+		}
+	}()
+
+	return d
+}
+
+// stat sets the names and infos of entries into walked, walkStatBatch
+// entries at a time.
+func (w *walker) stat(entries []fs.DirEntry, walked []walkedEntry) {
+	var wg sync.WaitGroup
+	for start := 0; start < len(entries); start += walkStatBatch {
+		end := min(start+walkStatBatch, len(entries))
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if !w.acquire() {
+				return
+			}
+			defer func() { <-w.sem }()
+
+			for i := start; i < end; i++ {
+				info, err := entries[i].Info()
+				walked[i] = walkedEntry{name: entries[i].Name(), info: info, err: err}
+			}
+		}()
+	}
+
+	wg.Wait()
+}
+
+// acquire waits for a free worker, and returns false if the walk is over.
+func (w *walker) acquire() bool {
+	select {
+	case w.sem <- struct{}{}:
+		return true
+	case <-w.stop:
+		return false
+	}
+}
+
+// walk calls fn for path and everything under it in lexical order, like
+// the walk function of filepath.Walk.
+func (w *walker) walk(path string, info fs.FileInfo, d *walkedDir, fn filepath.WalkFunc) error {
+	if d == nil {
+		return fn(path, info, nil)
+	}
+
+	<-d.done
+	err := fn(path, info, d.err)
+	if d.err != nil || err != nil {
+		return err
+	}
+
+	for _, e := range d.entries {
+		filename := filepath.Join(path, e.name)
+		if e.err != nil {
+			if err := fn(filename, nil, e.err); err != nil && err != filepath.SkipDir {
+				return err
+			}
+			continue
+		}
+
+		err := w.walk(filename, e.info, e.dir, fn)
+		if err != nil && (!e.info.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package files
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Walk(t *testing.T) {
+	root := t.TempDir()
+	for i := 0; i < 20; i++ {
+		dir := filepath.Join(root, fmt.Sprintf("dir-%d", i), "nested")
+		require.NoError(t, os.MkdirAll(dir, 0755))
+		for j := 0; j < 30; j++ {
+			require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("file-%d.txt", j)), []byte("x"), 0644))
+		}
+	}
+	require.NoError(t, os.WriteFile(filepath.Join(root, "top.txt"), []byte("top"), 0644))
+
+	walk := func(walk func(string, filepath.WalkFunc) error, fn filepath.WalkFunc) ([]string, error) {
+		walked := []string{}
+		err := walk(root, func(filename string, info os.FileInfo, err error) error {
+			if err == nil {
+				walked = append(walked, fmt.Sprintf("%s %d %v", filename, info.Size(), info.IsDir()))
+			}
+			return fn(filename, info, err)
+		})
+		return walked, err
+	}
+
+	t.Run("same files and order as filepath.Walk", func(t *testing.T) {
+		visit := func(string, os.FileInfo, error) error { return nil }
+		expected, err := walk(filepath.Walk, visit)
+		require.NoError(t, err)
+
+		walked, err := walk(Walk, visit)
+		require.NoError(t, err)
+		assert.Equal(t, expected, walked)
+	})
+
+	t.Run("skipping directories", func(t *testing.T) {
+		skip := func(filename string, info os.FileInfo, err error) error {
+			if info.IsDir() && filepath.Base(filename) == "nested" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		expected, err := walk(filepath.Walk, skip)
+		require.NoError(t, err)
+
+		walked, err := walk(Walk, skip)
+		require.NoError(t, err)
+		assert.Equal(t, expected, walked)
+	})
+
+	t.Run("errors stop the walk", func(t *testing.T) {
+		failed := errors.New("failed")
+		walked, err := walk(Walk, func(filename string, info os.FileInfo, err error) error {
+			if filepath.Base(filename) == "file-3.txt" {
+				return failed
+			}
+			return nil
+		})
+		assert.Equal(t, failed, err)
+		assert.Contains(t, walked[len(walked)-1], filepath.Join("dir-0", "nested", "file-3.txt"))
+	})
+
+	t.Run("missing roots are reported", func(t *testing.T) {
+		err := Walk(filepath.Join(root, "missing"), func(filename string, info os.FileInfo, err error) error {
+			return err
+		})
+		assert.True(t, os.IsNotExist(err))
+	})
+}
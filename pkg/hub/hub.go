@@ -3,6 +3,7 @@ package hub
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -12,6 +13,7 @@ import (
 	retryablehttp "github.com/hashicorp/go-retryablehttp"
 	api "github.com/semaphoreci/artifact/pkg/api"
 	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/retry"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -31,8 +33,9 @@ const (
 )
 
 type GenerateSignedURLsRequest struct {
-	Paths []string                      `json:"paths,omitempty"`
-	Type  GenerateSignedURLsRequestType `json:"type,omitempty"`
+	Paths    []string                      `json:"paths,omitempty"`
+	Type     GenerateSignedURLsRequestType `json:"type,omitempty"`
+	ExpireIn int64                         `json:"expire_in,omitempty"` // seconds until pushed artifacts expire
 }
 
 type GenerateSignedURLsResponse struct {
@@ -40,6 +43,34 @@ type GenerateSignedURLsResponse struct {
 	Error string           `json:"error,omitempty"`
 }
 
+// ListArtifactsRequest asks for a page of the artifacts stored under Path.
+// PageToken is the NextPageToken of the previous page, empty for the first.
+type ListArtifactsRequest struct {
+	Path      string `json:"path"`
+	PageToken string `json:"page_token,omitempty"`
+	PageSize  int    `json:"page_size,omitempty"`
+}
+
+// ListedArtifact is a stored artifact of a ListArtifactsResponse.
+type ListedArtifact struct {
+	Path      string    `json:"path"`
+	Size      int64     `json:"size"`
+	UpdatedAt time.Time `json:"updated_at"`
+	ETag      string    `json:"etag,omitempty"`
+}
+
+// ListArtifactsResponse is a page of artifacts, in path order. The last
+// page has no NextPageToken.
+type ListArtifactsResponse struct {
+	Artifacts     []ListedArtifact `json:"artifacts"`
+	NextPageToken string           `json:"next_page_token,omitempty"`
+	Error         string           `json:"error,omitempty"`
+}
+
+// ErrListNotSupported is returned by ListArtifacts by Hubs without the
+// list API.
+var ErrListNotSupported = errors.New("hub does not support listing artifacts")
+
 func NewClient() (*Client, error) {
 	token := os.Getenv("SEMAPHORE_ARTIFACT_TOKEN")
 	if token == "" {
@@ -69,9 +100,16 @@ func NewClient() (*Client, error) {
 }
 
 func (c *Client) GenerateSignedURLs(remotePaths []string, requestType GenerateSignedURLsRequestType) (*GenerateSignedURLsResponse, error) {
+	return c.GenerateExpiringSignedURLs(remotePaths, requestType, 0)
+}
+
+// GenerateExpiringSignedURLs is like GenerateSignedURLs, but asks Hub to
+// remove the pushed artifacts after expireIn, if it is set.
+func (c *Client) GenerateExpiringSignedURLs(remotePaths []string, requestType GenerateSignedURLsRequestType, expireIn time.Duration) (*GenerateSignedURLsResponse, error) {
 	reqBody := GenerateSignedURLsRequest{
-		Paths: remotePaths,
-		Type:  requestType,
+		Paths:    remotePaths,
+		Type:     requestType,
+		ExpireIn: int64(expireIn / time.Second),
 	}
 
 	log.Debug("Sending request to generate signed URLs...\n")
@@ -85,14 +123,7 @@ func (c *Client) GenerateSignedURLs(remotePaths []string, requestType GenerateSi
 		return nil, err
 	}
 
-	retryClient := retryablehttp.NewClient()
-
-	// 4 retries means 5 requests in total
-	retryClient.RetryMax = 4
-	retryClient.RetryWaitMax = 1 * time.Second
-	retryClient.Logger = &leveledLogger{}
-
-	httpResp, err := retryClient.Do(req)
+	httpResp, err := newRetryClient().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request did not return a non-5xx response: %v", err)
 	}
@@ -106,6 +137,79 @@ func (c *Client) GenerateSignedURLs(remotePaths []string, requestType GenerateSi
 	return &response, nil
 }
 
+// Check requests a signed URL to pull remotePath, which fails if the token
+// is not valid, and returns the time of the server from its response.
+func (c *Client) Check(remotePath string) (time.Time, error) {
+	req, err := createRequest("POST", c.URL, c.Token, GenerateSignedURLsRequest{
+		Paths: []string{remotePath},
+		Type:  GenerateSignedURLsRequestPULL,
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	httpResp, err := newRetryClient().Do(req)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("request did not return a non-5xx response: %v", err)
+	}
+
+	serverTime, _ := http.ParseTime(httpResp.Header.Get("Date"))
+	if httpResp.StatusCode == http.StatusUnauthorized || httpResp.StatusCode == http.StatusForbidden {
+		httpResp.Body.Close()
+		return serverTime, fmt.Errorf("hub rejected the artifact token with %d status code", httpResp.StatusCode)
+	}
+
+	var response GenerateSignedURLsResponse
+	return serverTime, decodeResponse(httpResp, &response)
+}
+
+// ListArtifacts returns the page of the artifacts stored under remotePath
+// that pageToken starts, of up to pageSize artifacts.
+func (c *Client) ListArtifacts(remotePath, pageToken string, pageSize int) (*ListArtifactsResponse, error) {
+	req, err := createRequest("POST", c.URL+"/list", c.Token, ListArtifactsRequest{
+		Path:      remotePath,
+		PageToken: pageToken,
+		PageSize:  pageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	httpResp, err := newRetryClient().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request did not return a non-5xx response: %v", err)
+	}
+
+	// #nosec
+	defer httpResp.Body.Close()
+
+	if httpResp.StatusCode == http.StatusNotFound || httpResp.StatusCode == http.StatusNotImplemented {
+		return nil, ErrListNotSupported
+	}
+
+	if !common.IsStatusOK(httpResp.StatusCode) {
+		return nil, fmt.Errorf("failed to list artifacts - hub returned %d status code", httpResp.StatusCode)
+	}
+
+	var response ListArtifactsResponse
+	if err := json.NewDecoder(httpResp.Body).Decode(&response); err != nil {
+		return nil, fmt.Errorf("failed to decode list http response: %v", err)
+	}
+
+	if len(response.Error) > 0 {
+		return nil, fmt.Errorf("list response returned errors: %s", response.Error)
+	}
+
+	return &response, nil
+}
+
+func newRetryClient() *retryablehttp.Client {
+	retryClient := retryablehttp.NewClient()
+	retryClient.Logger = &leveledLogger{}
+	retry.Load().Configure(retryClient)
+	return retryClient
+}
+
 func createRequest(method, url, token string, reqBody interface{}) (*retryablehttp.Request, error) {
 	var serializedRequestRata bytes.Buffer
 	if err := json.NewEncoder(&serializedRequestRata).Encode(reqBody); err != nil {
@@ -1,10 +1,12 @@
 package hub
 
 import (
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -110,6 +112,48 @@ func Test__GenerateSignedURL(t *testing.T) {
 	})
 }
 
+func Test__GenerateExpiringSignedURLs(t *testing.T) {
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		w.Write([]byte(`{"urls": []}`))
+	}))
+	defer server.Close()
+
+	client := Client{URL: server.URL, Token: "token", HttpClient: &http.Client{}}
+	_, err := client.GenerateExpiringSignedURLs([]string{"artifacts/jobs/1/x.zip"}, GenerateSignedURLsRequestPUSH, 2*time.Hour)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"paths": ["artifacts/jobs/1/x.zip"], "expire_in": 7200}`, string(body))
+
+	_, err = client.GenerateSignedURLs([]string{"artifacts/jobs/1/x.zip"}, GenerateSignedURLsRequestPUSH)
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"paths": ["artifacts/jobs/1/x.zip"]}`, string(body))
+}
+
+func Test__Check(t *testing.T) {
+	t.Run("valid token", func(t *testing.T) {
+		noOfCalls := 0
+		mockArtifactHubServer := generateMockServer(&noOfCalls, 200, []byte(`{"urls": []}`))
+		defer mockArtifactHubServer.Close()
+
+		client := Client{URL: mockArtifactHubServer.URL, Token: "token", HttpClient: &http.Client{}}
+		serverTime, err := client.Check("artifacts/jobs/1/.check")
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), serverTime, time.Minute)
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		noOfCalls := 0
+		mockArtifactHubServer := generateMockServer(&noOfCalls, 401, []byte("{}"))
+		defer mockArtifactHubServer.Close()
+
+		client := Client{URL: mockArtifactHubServer.URL, Token: "token", HttpClient: &http.Client{}}
+		_, err := client.Check("artifacts/jobs/1/.check")
+		assert.EqualError(t, err, "hub rejected the artifact token with 401 status code")
+		assert.Equal(t, 1, noOfCalls)
+	})
+}
+
 func generateSignedURLsHelper(url string) (*GenerateSignedURLsResponse, error) {
 	client := Client{
 		URL:        url,
@@ -0,0 +1,243 @@
+// Package manifest describes the files of a pushed directory: their paths,
+// sizes and SHA256 checksums. The manifest is stored with the directory, so
+// a pull can be verified, and compared with local files, without listing
+// the backend.
+package manifest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+)
+
+// Name is the name of the manifest of a directory.
+const Name = ".manifest.json"
+
+// Version is the version of the manifest format.
+const Version = 1
+
+// Outcomes of checking a local file against the manifest.
+const (
+	StatusOK       = "ok"
+	StatusMismatch = "mismatch" // the local file differs from the listed one
+	StatusMissing  = "missing"  // the file is listed, but not found locally
+	StatusExtra    = "extra"    // the file exists locally, but is not listed
+)
+
+// Entry is a file of the manifest, with its path relative to the directory.
+type Entry struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest lists the files of a directory when it was pushed, with the
+// metadata they were pushed with.
+//
+// The manifest of a directory pushed with --transactional also commits it:
+// its files are stored under TransactionDir, and Transaction names the
+// one it commits. Previous is the transaction it replaced, whose files are
+// kept until the next push, so pulls that read the previous manifest can
+// finish.
+//
+// The files of a directory pushed with --cas are stored as blobs instead,
+// see BlobPath, which Blobs is set for.
+type Manifest struct {
+	Version     int               `json:"version"`
+	Created     time.Time         `json:"created"`
+	Metadata    map[string]string `json:"metadata,omitempty"`
+	Files       []Entry           `json:"files"`
+	Transaction string            `json:"transaction,omitempty"`
+	Previous    string            `json:"previous,omitempty"`
+	Blobs       bool              `json:"blobs,omitempty"`
+}
+
+// Result is the outcome of checking one file against the manifest.
+type Result struct {
+	Path   string
+	Status string
+}
+
+// Path returns where the manifest of the remote directory remoteDir is
+// stored. It lives with the checksum sidecars of the directory, so it is
+// hidden from listings and pulls, and yanked with the directory.
+func Path(remoteDir string) string {
+	return path.Join(backend.ChecksumSidecarPrefix(remoteDir), Name)
+}
+
+// TransactionDir returns where the files of the transaction id of the
+// remote directory remoteDir are stored, hidden like the manifest.
+func TransactionDir(remoteDir, id string) string {
+	return path.Join(backend.ChecksumSidecarPrefix(remoteDir), "transactions", id)
+}
+
+// BlobDir is where blobs are stored, outside of any job, workflow or
+// project store, so identical files are stored once for all of them.
+const BlobDir = "artifacts/blobs"
+
+// BlobPath returns where the blob of the file with the SHA256 checksum sum
+// is stored.
+func BlobPath(sum string) string {
+	return path.Join(BlobDir, sum)
+}
+
+// FilePath returns where the file of the entry of the manifest of the
+// remote directory remoteDir committed is stored: its blob, or its path
+// in the transaction.
+func (m *Manifest) FilePath(remoteDir string, entry Entry) string {
+	if m.Blobs {
+		return BlobPath(entry.SHA256)
+	}
+
+	return path.Join(TransactionDir(remoteDir, m.Transaction), entry.Path)
+}
+
+// TransactionID returns the id of a transaction pushing the files of the
+// manifest: a checksum of their paths, sizes and checksums, so pushing the
+// same files again is the same transaction.
+func (m *Manifest) TransactionID() string {
+	h := sha256.New()
+	for _, entry := range m.Files {
+		fmt.Fprintf(h, "%s\x00%d\x00%s\n", entry.Path, entry.Size, entry.SHA256)
+	}
+
+	return hex.EncodeToString(h.Sum(nil))[:32]
+}
+
+// Generate returns the manifest of the local directory dir.
+func Generate(dir string, metadata map[string]string) (*Manifest, error) {
+	m := &Manifest{Version: Version, Created: time.Now().UTC(), Metadata: metadata, Files: []Entry{}}
+
+	err := files.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+
+		checksum, err := files.SHA256File(filename)
+		if err != nil {
+			return err
+		}
+
+		m.Files = append(m.Files, Entry{Path: filepath.ToSlash(rel), Size: info.Size(), SHA256: checksum})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(m.Files, func(i, j int) bool {
+		return m.Files[i].Path < m.Files[j].Path
+	})
+
+	return m, nil
+}
+
+// Parse reads a manifest stored at Path.
+func Parse(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+
+	if m.Version != Version {
+		return nil, fmt.Errorf("unsupported manifest version %d", m.Version)
+	}
+
+	return m, nil
+}
+
+// Marshal returns the manifest in the format stored at Path.
+func (m *Manifest) Marshal() ([]byte, error) {
+	return json.MarshalIndent(m, "", "  ")
+}
+
+// TotalSize returns the size of the listed files.
+func (m *Manifest) TotalSize() int64 {
+	var total int64
+	for _, entry := range m.Files {
+		total += entry.Size
+	}
+
+	return total
+}
+
+// Verify checks the local directory dir against the manifest, and returns
+// the outcome for every listed file and every local file not listed.
+func (m *Manifest) Verify(dir string) ([]Result, error) {
+	results := []Result{}
+	listed := map[string]bool{}
+
+	for _, entry := range m.Files {
+		listed[entry.Path] = true
+		status, err := verifyEntry(filepath.Join(dir, filepath.FromSlash(entry.Path)), entry)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, Result{Path: entry.Path, Status: status})
+	}
+
+	err := filepath.Walk(dir, func(filename string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, filename)
+		if err != nil {
+			return err
+		}
+
+		if !listed[filepath.ToSlash(rel)] {
+			results = append(results, Result{Path: filepath.ToSlash(rel), Status: StatusExtra})
+		}
+		return nil
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Path < results[j].Path
+	})
+
+	return results, nil
+}
+
+func verifyEntry(filename string, entry Entry) (string, error) {
+	info, err := os.Stat(filename)
+	if os.IsNotExist(err) {
+		return StatusMissing, nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size() != entry.Size {
+		return StatusMismatch, nil
+	}
+
+	checksum, err := files.SHA256File(filename)
+	if err != nil {
+		return "", err
+	}
+
+	if checksum != entry.SHA256 {
+		return StatusMismatch, nil
+	}
+
+	return StatusOK, nil
+}
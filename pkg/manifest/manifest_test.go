@@ -0,0 +1,86 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__GenerateAndVerify(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "bin"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "app.zip"), []byte("app"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "app"), []byte("binary"), 0644))
+
+	m, err := Generate(dir, map[string]string{"commit": "abc"})
+	require.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Path: "app.zip", Size: 3, SHA256: "a172cedcae47474b615c54d510a5d84a8dea3032e958587430b413538be3f333"},
+		{Path: "bin/app", Size: 6, SHA256: "9a3a45d01531a20e89ac6ae10b0b0beb0492acd7216a368aa062d1a5fecaf9cd"},
+	}, m.Files)
+	assert.Equal(t, int64(9), m.TotalSize())
+
+	// The manifest survives being stored
+	data, err := m.Marshal()
+	require.NoError(t, err)
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, m.Files, parsed.Files)
+	assert.Equal(t, "abc", parsed.Metadata["commit"])
+
+	results, err := parsed.Verify(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{{Path: "app.zip", Status: StatusOK}, {Path: "bin/app", Status: StatusOK}}, results)
+
+	// Same size, different contents
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bin", "app"), []byte("BINARY"), 0644))
+	require.NoError(t, os.Remove(filepath.Join(dir, "app.zip")))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "extra.sh"), []byte("extra"), 0644))
+
+	results, err = parsed.Verify(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []Result{
+		{Path: "app.zip", Status: StatusMissing},
+		{Path: "bin/app", Status: StatusMismatch},
+		{Path: "extra.sh", Status: StatusExtra},
+	}, results)
+}
+
+func Test__Parse(t *testing.T) {
+	_, err := Parse([]byte("not json"))
+	assert.Error(t, err)
+
+	_, err = Parse([]byte(`{"version": 2, "files": []}`))
+	assert.ErrorContains(t, err, "unsupported manifest version 2")
+}
+
+func Test__Path(t *testing.T) {
+	assert.Equal(t, "artifacts/jobs/1/.checksums/build/.manifest.json", Path("artifacts/jobs/1/build"))
+	assert.Equal(t, "artifacts/jobs/1/.checksums/build/.manifest.json", Path("artifacts/jobs/1/build/"))
+}
+
+func Test__Transaction(t *testing.T) {
+	assert.Equal(t, "artifacts/jobs/1/.checksums/build/transactions/abc", TransactionDir("artifacts/jobs/1/build/", "abc"))
+
+	m := &Manifest{Files: []Entry{{Path: "a.txt", Size: 1, SHA256: "aa"}}}
+	id := m.TransactionID()
+	assert.Len(t, id, 32)
+	assert.Equal(t, id, (&Manifest{Files: []Entry{{Path: "a.txt", Size: 1, SHA256: "aa"}}}).TransactionID())
+
+	// Any change to the files is another transaction
+	assert.NotEqual(t, id, (&Manifest{Files: []Entry{{Path: "b.txt", Size: 1, SHA256: "aa"}}}).TransactionID())
+	assert.NotEqual(t, id, (&Manifest{Files: []Entry{{Path: "a.txt", Size: 1, SHA256: "ab"}}}).TransactionID())
+}
+
+func Test__FilePath(t *testing.T) {
+	entry := Entry{Path: "logs/a.txt", Size: 1, SHA256: "aa"}
+
+	m := &Manifest{Transaction: "abc"}
+	assert.Equal(t, "artifacts/jobs/1/.checksums/build/transactions/abc/logs/a.txt", m.FilePath("artifacts/jobs/1/build", entry))
+
+	m.Blobs = true
+	assert.Equal(t, "artifacts/blobs/aa", m.FilePath("artifacts/jobs/1/build", entry))
+}
@@ -0,0 +1,279 @@
+// Package pack packs many small files of a directory into larger tar
+// archives, so pushing them takes one upload per pack instead of one per
+// file, see push --pack.
+//
+// The packs of a directory are stored by their SHA256 checksum under its
+// hidden sidecar prefix, with an index listing the files of each pack,
+// which pulls read to unpack them.
+package pack
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+)
+
+// Version is the version of the index format.
+const Version = 1
+
+// Defaults of push --pack: files smaller than DefaultThreshold are packed,
+// into packs of up to DefaultSize.
+const (
+	DefaultThreshold = 64 * 1024
+	DefaultSize      = 16 * 1024 * 1024
+)
+
+// Pack is a tar archive of files, named by its checksum.
+type Pack struct {
+	Name  string           `json:"name"`
+	Size  int64            `json:"size"`
+	Files []manifest.Entry `json:"files"`
+}
+
+// Index lists the packs of a directory.
+type Index struct {
+	Version int    `json:"version"`
+	Packs   []Pack `json:"packs"`
+}
+
+// IndexPath returns where the index of the packs of the remote directory
+// remoteDir is stored. Like its manifest, it is hidden from listings and
+// pulls, and yanked with the directory.
+func IndexPath(remoteDir string) string {
+	return path.Join(backend.ChecksumSidecarPrefix(remoteDir), "packs", "index.json")
+}
+
+// Path returns where the pack name of the remote directory remoteDir is
+// stored.
+func Path(remoteDir, name string) string {
+	return path.Join(backend.ChecksumSidecarPrefix(remoteDir), "packs", name+".tar")
+}
+
+// Plan groups the files in packs of up to size bytes, in order. A file
+// larger than size gets a pack of its own.
+func Plan(entries []manifest.Entry, size int64) [][]manifest.Entry {
+	groups := [][]manifest.Entry{}
+	var group []manifest.Entry
+	var total int64
+
+	for _, entry := range entries {
+		if len(group) > 0 && total+entry.Size > size {
+			groups = append(groups, group)
+			group, total = nil, 0
+		}
+
+		group = append(group, entry)
+		total += entry.Size
+	}
+
+	if len(group) > 0 {
+		groups = append(groups, group)
+	}
+
+	return groups
+}
+
+// Write writes the pack of the files of the local directory dir to w, and
+// returns the entries with their checksums set.
+func Write(w io.Writer, dir string, entries []manifest.Entry) ([]manifest.Entry, error) {
+	tw := tar.NewWriter(w)
+	packed := make([]manifest.Entry, len(entries))
+
+	for i, entry := range entries {
+		checksum, err := writeFile(tw, filepath.Join(dir, filepath.FromSlash(entry.Path)), entry)
+		if err != nil {
+			return nil, err
+		}
+
+		packed[i] = manifest.Entry{Path: entry.Path, Size: entry.Size, SHA256: checksum}
+	}
+
+	return packed, tw.Close()
+}
+
+func writeFile(tw *tar.Writer, localPath string, entry manifest.Entry) (string, error) {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+
+	// #nosec
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	if info.Size() != entry.Size {
+		return "", fmt.Errorf("'%s' changed while it was packed", localPath)
+	}
+
+	header := &tar.Header{Typeflag: tar.TypeReg, Name: entry.Path, Size: info.Size(), Mode: int64(info.Mode().Perm()), ModTime: info.ModTime()}
+	if err := tw.WriteHeader(header); err != nil {
+		return "", err
+	}
+
+	hash := sha256.New()
+	if _, err := files.Copy(io.MultiWriter(tw, hash), f); err != nil {
+		return "", fmt.Errorf("failed to read '%s': %v", localPath, err)
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Extract unpacks the files of the pack p read from r into the local
+// directory destination, checking them against their checksums. Only the
+// files keep reports are written, or all of them if keep is nil. It
+// returns the number and total size of the files written.
+func Extract(r io.Reader, destination string, p Pack, keep func(manifest.Entry) bool) (int, int64, error) {
+	count, size := 0, int64(0)
+	written := ""
+	err := Read(r, p, func(entry manifest.Entry, mode os.FileMode, r io.Reader) error {
+		written = ""
+		if keep != nil && !keep(entry) {
+			return nil
+		}
+
+		written = filepath.Join(destination, filepath.FromSlash(entry.Path))
+		if err := extractFile(r, written, mode); err != nil {
+			return err
+		}
+
+		count++
+		size += entry.Size
+		return nil
+	})
+
+	// A file that does not match its checksum is the last one written
+	var mismatch *backend.ErrChecksumMismatch
+	if errors.As(err, &mismatch) && written != "" {
+		_ = os.Remove(written)
+	}
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return count, size, nil
+}
+
+// Read calls fn for every file of the pack p read from r, with its mode
+// and contents, in the order they are packed. fn may leave the contents
+// unread. Every file is checked against its checksum after fn returns, and
+// Read fails with an ErrChecksumMismatch if it differs.
+func Read(r io.Reader, p Pack, fn func(entry manifest.Entry, mode os.FileMode, r io.Reader) error) error {
+	listed := map[string]manifest.Entry{}
+	for _, entry := range p.Files {
+		listed[entry.Path] = entry
+	}
+
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("invalid pack %s: %v", p.Name, err)
+		}
+
+		entry, ok := listed[header.Name]
+		if !ok || header.Typeflag != tar.TypeReg || !filepath.IsLocal(filepath.FromSlash(entry.Path)) {
+			return fmt.Errorf("invalid pack %s: unexpected entry '%s'", p.Name, header.Name)
+		}
+
+		delete(listed, header.Name)
+
+		hash := sha256.New()
+		contents := io.TeeReader(tr, hash)
+		if err := fn(entry, os.FileMode(header.Mode).Perm(), contents); err != nil {
+			return err
+		}
+		if _, err := io.Copy(io.Discard, contents); err != nil {
+			return fmt.Errorf("invalid pack %s: %v", p.Name, err)
+		}
+
+		if checksum := hex.EncodeToString(hash.Sum(nil)); checksum != entry.SHA256 {
+			return &backend.ErrChecksumMismatch{Path: entry.Path, Expected: entry.SHA256, Actual: checksum}
+		}
+	}
+
+	for name := range listed {
+		return fmt.Errorf("invalid pack %s: '%s' is missing", p.Name, name)
+	}
+
+	return nil
+}
+
+func extractFile(r io.Reader, localPath string, mode os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(localPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode|0200)
+	if err != nil {
+		return err
+	}
+
+	_, err = files.Copy(f, r)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// Find returns the pack holding the file at the path relative to the
+// packed directory, and its entry.
+func (i *Index) Find(relativePath string) (Pack, manifest.Entry, bool) {
+	for _, p := range i.Packs {
+		for _, entry := range p.Files {
+			if entry.Path == relativePath {
+				return p, entry, true
+			}
+		}
+	}
+
+	return Pack{}, manifest.Entry{}, false
+}
+
+// Marshal returns the index as stored.
+func (i *Index) Marshal() ([]byte, error) {
+	return json.MarshalIndent(i, "", "  ")
+}
+
+// Parse parses an index stored by Marshal.
+func Parse(data []byte) (*Index, error) {
+	index := &Index{}
+	if err := json.Unmarshal(data, index); err != nil {
+		return nil, fmt.Errorf("invalid pack index: %v", err)
+	}
+
+	if index.Version != Version {
+		return nil, fmt.Errorf("unsupported pack index version %d", index.Version)
+	}
+
+	return index, nil
+}
+
+// Has reports whether the index has a pack named name.
+func (i *Index) Has(name string) bool {
+	for _, p := range i.Packs {
+		if p.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,141 @@
+package pack
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/semaphoreci/artifact/pkg/backend"
+	"github.com/semaphoreci/artifact/pkg/manifest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Plan(t *testing.T) {
+	entries := []manifest.Entry{{Path: "a", Size: 40}, {Path: "b", Size: 40}, {Path: "c", Size: 30}, {Path: "d", Size: 150}, {Path: "e", Size: 1}}
+
+	groups := Plan(entries, 100)
+	assert.Equal(t, [][]manifest.Entry{
+		{{Path: "a", Size: 40}, {Path: "b", Size: 40}},
+		{{Path: "c", Size: 30}},
+		{{Path: "d", Size: 150}},
+		{{Path: "e", Size: 1}},
+	}, groups)
+	assert.Empty(t, Plan(nil, 100))
+}
+
+func Test__WriteExtract(t *testing.T) {
+	source := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(source, "logs"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "a.txt"), []byte("a"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "logs", "b.log"), []byte("bb"), 0644))
+
+	buf := &bytes.Buffer{}
+	packed, err := Write(buf, source, []manifest.Entry{{Path: "a.txt", Size: 1}, {Path: "logs/b.log", Size: 2}})
+	require.NoError(t, err)
+	require.Len(t, packed, 2)
+	assert.Equal(t, "ca978112ca1bbdcafac231b39a23dc4da786eff8147c4e72b9807785afee48bb", packed[0].SHA256)
+
+	p := Pack{Name: "test", Size: int64(buf.Len()), Files: packed}
+
+	t.Run("every file", func(t *testing.T) {
+		dest := t.TempDir()
+		count, size, err := Extract(bytes.NewReader(buf.Bytes()), dest, p, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+		assert.Equal(t, int64(3), size)
+
+		data, err := os.ReadFile(filepath.Join(dest, "logs", "b.log"))
+		require.NoError(t, err)
+		assert.Equal(t, "bb", string(data))
+	})
+
+	t.Run("only the files kept", func(t *testing.T) {
+		dest := t.TempDir()
+		count, _, err := Extract(bytes.NewReader(buf.Bytes()), dest, p, func(e manifest.Entry) bool { return e.Path == "a.txt" })
+		require.NoError(t, err)
+		assert.Equal(t, 1, count)
+		assert.NoFileExists(t, filepath.Join(dest, "logs", "b.log"))
+	})
+
+	t.Run("checksum mismatch", func(t *testing.T) {
+		corrupted := p
+		corrupted.Files = []manifest.Entry{packed[0], {Path: "logs/b.log", Size: 2, SHA256: packed[0].SHA256}}
+
+		dest := t.TempDir()
+		_, _, err := Extract(bytes.NewReader(buf.Bytes()), dest, corrupted, nil)
+		var mismatch *backend.ErrChecksumMismatch
+		assert.ErrorAs(t, err, &mismatch)
+		assert.NoFileExists(t, filepath.Join(dest, "logs", "b.log"))
+	})
+
+	t.Run("entries missing from the index", func(t *testing.T) {
+		evil := &bytes.Buffer{}
+		tw := tar.NewWriter(evil)
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: "../escape", Size: 1, Mode: 0644}))
+		_, _ = tw.Write([]byte("x"))
+		require.NoError(t, tw.Close())
+
+		_, _, err := Extract(evil, t.TempDir(), p, nil)
+		assert.ErrorContains(t, err, "unexpected entry '../escape'")
+	})
+}
+
+func Test__Read(t *testing.T) {
+	source := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(source, "a.txt"), []byte("a"), 0600))
+	require.NoError(t, os.WriteFile(filepath.Join(source, "b.txt"), []byte("bb"), 0644))
+
+	buf := &bytes.Buffer{}
+	packed, err := Write(buf, source, []manifest.Entry{{Path: "a.txt", Size: 1}, {Path: "b.txt", Size: 2}})
+	require.NoError(t, err)
+	p := Pack{Name: "test", Size: int64(buf.Len()), Files: packed}
+
+	// Files left unread are still checked
+	read := map[string]string{}
+	err = Read(bytes.NewReader(buf.Bytes()), p, func(entry manifest.Entry, mode os.FileMode, r io.Reader) error {
+		if entry.Path == "b.txt" {
+			data, _ := io.ReadAll(r)
+			read[entry.Path] = string(data)
+			assert.Equal(t, os.FileMode(0644), mode)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"b.txt": "bb"}, read)
+
+	corrupted := p
+	corrupted.Files = []manifest.Entry{{Path: "a.txt", Size: 1, SHA256: packed[1].SHA256}, packed[1]}
+	err = Read(bytes.NewReader(buf.Bytes()), corrupted, func(manifest.Entry, os.FileMode, io.Reader) error { return nil })
+	var mismatch *backend.ErrChecksumMismatch
+	require.ErrorAs(t, err, &mismatch)
+	assert.Equal(t, "a.txt", mismatch.Path)
+
+	index := &Index{Packs: []Pack{p}}
+	found, entry, ok := index.Find("b.txt")
+	assert.True(t, ok)
+	assert.Equal(t, "test", found.Name)
+	assert.Equal(t, int64(2), entry.Size)
+	_, _, ok = index.Find("c.txt")
+	assert.False(t, ok)
+}
+
+func Test__Index(t *testing.T) {
+	index := &Index{Version: Version, Packs: []Pack{{Name: "abc", Size: 10, Files: []manifest.Entry{{Path: "a.txt", Size: 1, SHA256: "00"}}}}}
+	data, err := index.Marshal()
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, index, parsed)
+	assert.True(t, parsed.Has("abc"))
+	assert.False(t, parsed.Has("def"))
+
+	_, err = Parse([]byte(`{"version": 2}`))
+	assert.EqualError(t, err, "unsupported pack index version 2")
+
+	assert.Equal(t, "artifacts/jobs/1/.checksums/out/packs/index.json", IndexPath("artifacts/jobs/1/out"))
+}
@@ -0,0 +1,316 @@
+// Package policy evaluates guardrails that platform teams configure
+// for pushes and yanks, e.g. size limits or protected paths.
+// Rules are read from the "policy" section of the config file,
+// or from a separate policy file with the same layout:
+//
+//	rules:
+//	  - name: protect-releases
+//	    operations: [yank]
+//	    match: "releases/**"
+//	    deny: true
+//	    message: releases are kept forever
+package policy
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// Operations rules can apply to.
+const (
+	OperationPush = "push"
+	OperationYank = "yank"
+)
+
+// Rule restricts the operations it applies to. A rule applies to every
+// operation, category and path unless it is narrowed down, and must
+// deny, limit the size or require metadata.
+type Rule struct {
+	Name            string   `yaml:"name" mapstructure:"name"`
+	Operations      []string `yaml:"operations" mapstructure:"operations"`           // push, yank
+	Categories      []string `yaml:"categories" mapstructure:"categories"`           // job, workflow, project
+	Match           string   `yaml:"match" mapstructure:"match"`                     // glob relative to the category, e.g. releases/**
+	Deny            bool     `yaml:"deny" mapstructure:"deny"`                       // reject the operation outright
+	MaxSize         string   `yaml:"maxSize" mapstructure:"maxSize"`                 // largest total push size, e.g. 5GB
+	RequireMetadata []string `yaml:"requireMetadata" mapstructure:"requireMetadata"` // metadata keys pushes must set
+	Message         string   `yaml:"message" mapstructure:"message"`                 // shown to users on violations
+
+	maxSize int64
+}
+
+// Policy is an ordered list of rules. Every rule is evaluated,
+// so a violation message lists all rules an operation breaks.
+type Policy struct {
+	Rules []*Rule `yaml:"rules" mapstructure:"rules"`
+}
+
+// Request describes an operation to evaluate.
+type Request struct {
+	Operation string
+	Category  string
+	Path      string            // relative to the category, empty for all of it
+	Dir       bool              // whether Path may contain other files
+	Size      int64             // total size of pushed files, -1 if unknown
+	Metadata  map[string]string // metadata set on pushed files
+}
+
+// Violation is a broken rule.
+type Violation struct {
+	Rule   string
+	Reason string
+}
+
+// ViolationError is returned for operations a policy does not allow.
+type ViolationError struct {
+	Operation  string
+	Path       string
+	Violations []Violation
+}
+
+func (e *ViolationError) Error() string {
+	target := e.Path
+	if target == "" {
+		target = "."
+	}
+
+	if len(e.Violations) == 1 {
+		v := e.Violations[0]
+		return fmt.Sprintf("%s of '%s' denied by policy rule '%s': %s", e.Operation, target, v.Rule, v.Reason)
+	}
+
+	lines := []string{fmt.Sprintf("%s of '%s' denied by policy:", e.Operation, target)}
+	for _, v := range e.Violations {
+		lines = append(lines, fmt.Sprintf("  - rule '%s': %s", v.Rule, v.Reason))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Load reads the policy from the config file. A config without
+// a policy section results in an empty policy, which allows everything.
+func Load() (*Policy, error) {
+	p := &Policy{}
+	if err := viper.UnmarshalKey("policy", p); err != nil {
+		return nil, fmt.Errorf("failed to read policy from config: %v", err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// LoadFile reads the policy from a YAML file.
+func LoadFile(filePath string) (*Policy, error) {
+	// #nosec
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read policy file '%s': %v", filePath, err)
+	}
+
+	p := &Policy{}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse policy file '%s': %v", filePath, err)
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid policy file '%s': %v", filePath, err)
+	}
+
+	return p, nil
+}
+
+// Validate checks every rule and names unnamed ones after their position.
+func (p *Policy) Validate() error {
+	for i, rule := range p.Rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("rule %d", i+1)
+		}
+
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("invalid policy rule '%s': %v", rule.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (r *Rule) validate() error {
+	for _, op := range r.Operations {
+		if op != OperationPush && op != OperationYank {
+			return fmt.Errorf("unknown operation '%s': use %s or %s", op, OperationPush, OperationYank)
+		}
+	}
+
+	for _, category := range r.Categories {
+		switch category {
+		case files.ResourceTypeJob, files.ResourceTypeWorkflow, files.ResourceTypeProject:
+		default:
+			return fmt.Errorf("unknown category '%s': use job, workflow or project", category)
+		}
+	}
+
+	if err := files.ValidateGlob(r.Match); err != nil {
+		return fmt.Errorf("invalid match '%s': %v", r.Match, err)
+	}
+
+	if r.MaxSize != "" {
+		size, err := common.ParseSize(r.MaxSize)
+		if err != nil {
+			return err
+		}
+		r.maxSize = size
+	}
+
+	if !r.Deny && r.MaxSize == "" && len(r.RequireMetadata) == 0 {
+		return fmt.Errorf("a rule needs deny, maxSize or requireMetadata")
+	}
+
+	return nil
+}
+
+// MaxSize returns the smallest size limit of the rules that apply to req,
+// or 0 if there is none. It is used to enforce limits on pushes whose size
+// is only known once they are done, e.g. streamed downloads.
+func (p *Policy) MaxSize(req Request) int64 {
+	var limit int64
+	for _, rule := range p.Rules {
+		if rule.maxSize > 0 && rule.appliesTo(req) && (limit == 0 || rule.maxSize < limit) {
+			limit = rule.maxSize
+		}
+	}
+
+	return limit
+}
+
+// Evaluate returns a *ViolationError if req breaks any rule.
+func (p *Policy) Evaluate(req Request) error {
+	violations := []Violation{}
+
+	for _, rule := range p.Rules {
+		if !rule.appliesTo(req) {
+			continue
+		}
+
+		for _, reason := range rule.check(req) {
+			violations = append(violations, Violation{Rule: rule.Name, Reason: reason})
+		}
+	}
+
+	if len(violations) == 0 {
+		return nil
+	}
+
+	return &ViolationError{Operation: req.Operation, Path: req.Path, Violations: violations}
+}
+
+func (r *Rule) appliesTo(req Request) bool {
+	if len(r.Operations) > 0 && !contains(r.Operations, req.Operation) {
+		return false
+	}
+
+	if len(r.Categories) > 0 && !contains(r.Categories, req.Category) {
+		return false
+	}
+
+	if r.Match == "" {
+		return true
+	}
+
+	if matched, _ := files.MatchGlob(r.Match, req.Path); matched {
+		return true
+	}
+
+	// Directories are covered by rules for any file they may contain,
+	// so yanking "." cannot get around a rule protecting "releases/**".
+	return req.Dir && mayContainMatch(r.Match, req.Path)
+}
+
+func (r *Rule) check(req Request) []string {
+	reasons := []string{}
+
+	if r.Deny {
+		reasons = append(reasons, r.explain(fmt.Sprintf("paths matching '%s' are protected", r.matchOrAll())))
+	}
+
+	if r.maxSize > 0 && req.Operation == OperationPush && req.Size > r.maxSize {
+		reasons = append(reasons, r.explain(fmt.Sprintf("%d bytes exceed the limit of %s", req.Size, r.MaxSize)))
+	}
+
+	if req.Operation == OperationPush {
+		missing := []string{}
+		for _, key := range r.RequireMetadata {
+			if _, ok := req.Metadata[key]; !ok {
+				missing = append(missing, key)
+			}
+		}
+
+		if len(missing) > 0 {
+			sort.Strings(missing)
+			reasons = append(reasons, r.explain(fmt.Sprintf("missing required metadata: %s; set it with --metadata KEY=VALUE", strings.Join(missing, ", "))))
+		}
+	}
+
+	return reasons
+}
+
+// explain prefixes a reason with the rule's message, if it has one.
+func (r *Rule) explain(reason string) string {
+	if r.Message == "" {
+		return reason
+	}
+
+	return fmt.Sprintf("%s (%s)", r.Message, reason)
+}
+
+func (r *Rule) matchOrAll() string {
+	if r.Match == "" {
+		return "**"
+	}
+
+	return r.Match
+}
+
+// mayContainMatch reports whether files under dir could match pattern.
+func mayContainMatch(pattern, dir string) bool {
+	dir = strings.Trim(dir, "/")
+	if dir == "" || dir == "." {
+		return true
+	}
+
+	patternSegments := strings.Split(pattern, "/")
+	for i, segment := range strings.Split(dir, "/") {
+		if i >= len(patternSegments) {
+			return false
+		}
+
+		if patternSegments[i] == "**" {
+			return true
+		}
+
+		if matched, _ := path.Match(patternSegments[i], segment); !matched {
+			return false
+		}
+	}
+
+	return len(patternSegments) > len(strings.Split(dir, "/"))
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
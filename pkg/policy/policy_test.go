@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPolicy = `rules:
+  - name: no-huge-pushes
+    operations: [push]
+    maxSize: 5GB
+  - name: protect-releases
+    operations: [yank]
+    match: "releases/**"
+    deny: true
+    message: releases are kept forever
+  - operations: [push]
+    categories: [project]
+    requireMetadata: [owner, ticket]
+`
+
+func Test__LoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy), 0600))
+
+	p, err := LoadFile(path)
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 3)
+	assert.Equal(t, "rule 3", p.Rules[2].Name)
+	assert.Equal(t, int64(5<<30), p.Rules[0].maxSize)
+
+	_, err = LoadFile(filepath.Join(t.TempDir(), "missing.yml"))
+	assert.Error(t, err)
+}
+
+func Test__Load(t *testing.T) {
+	defer viper.Reset()
+
+	p, err := Load()
+	require.NoError(t, err)
+	assert.Empty(t, p.Rules)
+
+	viper.Set("policy", map[string]interface{}{
+		"rules": []interface{}{
+			map[string]interface{}{"name": "limit", "maxsize": "1MB"},
+		},
+	})
+
+	p, err = Load()
+	require.NoError(t, err)
+	require.Len(t, p.Rules, 1)
+	assert.Equal(t, int64(1<<20), p.MaxSize(Request{Operation: OperationPush, Category: "job"}))
+}
+
+func Test__Validate(t *testing.T) {
+	invalid := map[string]*Rule{
+		"unknown operation": {Operations: []string{"pull"}, Deny: true},
+		"unknown category":  {Categories: []string{"org"}, Deny: true},
+		"invalid match":     {Match: "[", Deny: true},
+		"invalid size":      {MaxSize: "5 parsecs"},
+		"no effect":         {Name: "noop"},
+	}
+
+	for name, rule := range invalid {
+		p := &Policy{Rules: []*Rule{rule}}
+		assert.Error(t, p.Validate(), name)
+	}
+}
+
+func Test__Evaluate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yml")
+	require.NoError(t, os.WriteFile(path, []byte(testPolicy), 0600))
+
+	p, err := LoadFile(path)
+	require.NoError(t, err)
+
+	t.Run("allowed", func(t *testing.T) {
+		assert.NoError(t, p.Evaluate(Request{Operation: OperationPush, Category: "job", Path: "a.txt", Size: 1 << 30}))
+		assert.NoError(t, p.Evaluate(Request{Operation: OperationYank, Category: "project", Path: "builds/a.zip"}))
+		assert.NoError(t, p.Evaluate(Request{Operation: OperationYank, Category: "project", Path: "builds", Dir: true}))
+		assert.NoError(t, p.Evaluate(Request{
+			Operation: OperationPush,
+			Category:  "project",
+			Path:      "releases/v1.zip",
+			Size:      -1,
+			Metadata:  map[string]string{"owner": "platform", "ticket": "OPS-1"},
+		}))
+	})
+
+	t.Run("too large", func(t *testing.T) {
+		err := p.Evaluate(Request{Operation: OperationPush, Category: "job", Path: "big.iso", Size: 6 << 30})
+		assert.EqualError(t, err, "push of 'big.iso' denied by policy rule 'no-huge-pushes': 6442450944 bytes exceed the limit of 5GB")
+	})
+
+	t.Run("protected path", func(t *testing.T) {
+		err := p.Evaluate(Request{Operation: OperationYank, Category: "project", Path: "releases/v1.zip"})
+		assert.EqualError(t, err, "yank of 'releases/v1.zip' denied by policy rule 'protect-releases': releases are kept forever (paths matching 'releases/**' are protected)")
+	})
+
+	t.Run("protected path in yanked directory", func(t *testing.T) {
+		assert.Error(t, p.Evaluate(Request{Operation: OperationYank, Category: "project", Path: "", Dir: true}))
+		assert.Error(t, p.Evaluate(Request{Operation: OperationYank, Category: "project", Path: "releases", Dir: true}))
+	})
+
+	t.Run("several violations", func(t *testing.T) {
+		err := p.Evaluate(Request{Operation: OperationPush, Category: "project", Path: "big.iso", Size: 6 << 30, Metadata: map[string]string{"owner": "platform"}})
+
+		violation, ok := err.(*ViolationError)
+		require.True(t, ok)
+		assert.Equal(t, []Violation{
+			{Rule: "no-huge-pushes", Reason: "6442450944 bytes exceed the limit of 5GB"},
+			{Rule: "rule 3", Reason: "missing required metadata: ticket; set it with --metadata KEY=VALUE"},
+		}, violation.Violations)
+		assert.Contains(t, err.Error(), "push of 'big.iso' denied by policy:\n  - rule 'no-huge-pushes'")
+	})
+}
+
+func Test__MayContainMatch(t *testing.T) {
+	assert.True(t, mayContainMatch("releases/**", ""))
+	assert.True(t, mayContainMatch("releases/**", "releases"))
+	assert.True(t, mayContainMatch("*/sub/*.txt", "two-levels"))
+	assert.True(t, mayContainMatch("**/secret", "a/b/c"))
+	assert.False(t, mayContainMatch("releases/**", "builds"))
+	assert.False(t, mayContainMatch("releases/*.zip", "releases/v1/nested"))
+	assert.False(t, mayContainMatch("a/b", "a/b"))
+}
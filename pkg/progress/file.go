@@ -0,0 +1,118 @@
+package progress
+
+import "io"
+
+// File is a file being transferred, shown with a bar of its own.
+type File struct {
+	t    *Tracker
+	name string
+	size int64 // negative if unknown
+	done int64
+}
+
+// File starts tracking the transfer of a file of size bytes, or of
+// unknown size if it is negative. Done must be called once it ends.
+func (t *Tracker) File(name string, size int64) *File {
+	if t == nil {
+		return nil
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	f := &File{t: t, name: name, size: size}
+	t.active = append(t.active, f)
+	return f
+}
+
+// Skip counts n bytes of the file that need no transfer as done, e.g.
+// the start of a download an interrupted pull already wrote.
+func (f *File) Skip(n int64) {
+	if f == nil {
+		return
+	}
+
+	f.t.mu.Lock()
+	defer f.t.mu.Unlock()
+
+	f.done += n
+	f.t.done += n
+}
+
+// Done ends the transfer of the file, which counts as done if all its
+// bytes were.
+func (f *File) Done() {
+	if f == nil {
+		return
+	}
+
+	f.t.mu.Lock()
+	defer f.t.mu.Unlock()
+
+	for i, active := range f.t.active {
+		if active == f {
+			f.t.active = append(f.t.active[:i], f.t.active[i+1:]...)
+			if f.size < 0 || f.done >= f.size {
+				f.t.doneFiles++
+			}
+			return
+		}
+	}
+}
+
+// Reader returns a reader counting the bytes of the file read from r. If
+// r is an io.Seeker, so is the reader, and bytes read again after seeking
+// back, e.g. when a request is retried, are only counted once.
+func (f *File) Reader(r io.Reader) io.Reader {
+	if f == nil {
+		return r
+	}
+
+	cr := &reader{r: r, f: f}
+	if seeker, ok := r.(io.Seeker); ok {
+		return &readSeeker{reader: cr, seeker: seeker}
+	}
+
+	return cr
+}
+
+func (f *File) add(n int64) {
+	f.t.mu.Lock()
+	defer f.t.mu.Unlock()
+
+	f.done += n
+	f.t.done += n
+	f.t.transferred += n
+}
+
+type reader struct {
+	r    io.Reader
+	f    *File
+	pos  int64
+	seen int64 // the furthest position read up to
+}
+
+func (r *reader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.pos += int64(n)
+	if r.pos > r.seen {
+		r.f.add(r.pos - r.seen)
+		r.seen = r.pos
+	}
+
+	return n, err
+}
+
+type readSeeker struct {
+	*reader
+	seeker io.Seeker
+}
+
+func (r *readSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.seeker.Seek(offset, whence)
+	if err == nil {
+		r.pos = pos
+	}
+
+	return pos, err
+}
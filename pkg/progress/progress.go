@@ -0,0 +1,354 @@
+// Package progress reports the progress of transfers. On a terminal, it
+// draws a bar for each file being transferred and one for the whole
+// transfer, with its speed and the time left, redrawn a few times a
+// second. Elsewhere, e.g. in CI logs, it logs a line every so often.
+//
+// A Tracker travels with the context of the transfer, so backends report
+// what they transfer without knowing how it is shown. Its methods do
+// nothing on a nil Tracker, so transfers without one are not reported.
+package progress
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/common"
+	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+const (
+	// RedrawInterval is how often the bars are redrawn on a terminal.
+	RedrawInterval = 200 * time.Millisecond
+
+	// LogInterval is how often the progress is logged elsewhere. Transfers
+	// shorter than that log nothing.
+	LogInterval = 10 * time.Second
+
+	// speedWindow is how far back the speed is measured.
+	speedWindow = 10 * time.Second
+
+	// maxFileBars is how many files being transferred get a bar of their
+	// own; the others are summed up in a line.
+	maxFileBars = 5
+
+	barWidth = 20
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the tracker.
+func NewContext(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tracker of ctx, or nil if it carries none.
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(contextKey{}).(*Tracker)
+	return t
+}
+
+// Tracker sums up the bytes and files of a transfer, and reports them
+// until it is stopped. It is safe for concurrent use.
+type Tracker struct {
+	verb  string // e.g. "Pushed", starting the logged lines
+	out   io.Writer
+	tty   bool
+	width int
+
+	mu          sync.Mutex
+	files       int   // files in total, zero if unknown
+	size        int64 // bytes in total, zero if unknown
+	doneFiles   int
+	done        int64 // bytes done, skipped ones included
+	transferred int64 // bytes actually transferred, for the speed
+	active      []*File
+	samples     []sample
+	speed       float64 // bytes per second
+	drawn       int     // lines drawn on the terminal
+
+	logOut  io.Writer // the log output while bars are drawn
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+type sample struct {
+	at          time.Time
+	transferred int64
+}
+
+// New returns a tracker reporting on out: with bars if out is a
+// terminal, and with log lines starting with verb otherwise.
+func New(verb string, out io.Writer) *Tracker {
+	t := &Tracker{verb: verb, out: out, width: 80}
+
+	if f, ok := out.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		t.tty = true
+		if width, _, err := term.GetSize(int(f.Fd())); err == nil && width > 0 {
+			t.width = width
+		}
+	}
+
+	return t
+}
+
+// SetTotal sets how many files, and bytes, the transfer has in total, so
+// the time left can be estimated.
+func (t *Tracker) SetTotal(files int, size int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.files, t.size = files, size
+}
+
+// Skip counts a file of size bytes that needs no transfer as done, e.g.
+// one an interrupted push already uploaded.
+func (t *Tracker) Skip(size int64) {
+	if t == nil {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.doneFiles++
+	t.done += size
+}
+
+// Start reports the progress until Stop is called. While bars are drawn,
+// log lines are printed above them.
+func (t *Tracker) Start() {
+	if t == nil {
+		return
+	}
+
+	interval := LogInterval
+	if t.tty {
+		interval = RedrawInterval
+		t.logOut = log.StandardLogger().Out
+		log.SetOutput(logWriter{t})
+	}
+
+	t.samples = []sample{{at: time.Now()}}
+	t.stop, t.stopped = make(chan struct{}), make(chan struct{})
+
+	go func() {
+		defer close(t.stopped)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-t.stop:
+				return
+			case now := <-ticker.C:
+				t.report(now)
+			}
+		}
+	}()
+}
+
+// Stop ends the report, erasing the bars.
+func (t *Tracker) Stop() {
+	if t == nil || t.stop == nil {
+		return
+	}
+
+	close(t.stop)
+	<-t.stopped
+	t.stop = nil
+
+	if t.tty {
+		t.mu.Lock()
+		t.erase()
+		t.mu.Unlock()
+		log.SetOutput(t.logOut)
+	}
+}
+
+// report measures the speed at now, and draws the bars, or logs a line.
+func (t *Tracker) report(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.measure(now)
+	if t.tty {
+		t.redraw()
+		return
+	}
+
+	log.Info(t.summary())
+}
+
+// measure updates the speed with the bytes transferred until now, over
+// the last speedWindow. The caller holds the lock.
+func (t *Tracker) measure(now time.Time) {
+	t.samples = append(t.samples, sample{at: now, transferred: t.transferred})
+	for len(t.samples) > 2 && now.Sub(t.samples[0].at) > speedWindow {
+		t.samples = t.samples[1:]
+	}
+
+	first := t.samples[0]
+	if elapsed := now.Sub(first.at).Seconds(); elapsed > 0 {
+		t.speed = float64(t.transferred-first.transferred) / elapsed
+	}
+}
+
+// summary returns the line logged on the progress, e.g.
+// "Pushed 1.2 GB of 2.3 GB (52%), 3 of 10 files, at 14.2 MB/s, 1m20s left.".
+// The caller holds the lock.
+func (t *Tracker) summary() string {
+	if t.size <= 0 {
+		return fmt.Sprintf("%s %s, %d %s, at %s.\n", t.verb, common.FormatSize(t.done), t.doneFiles, plural(t.doneFiles), t.formatSpeed())
+	}
+
+	line := fmt.Sprintf("%s %s of %s (%d%%), %d of %d %s, at %s", t.verb, common.FormatSize(t.done), common.FormatSize(t.size),
+		percent(t.done, t.size), t.doneFiles, t.files, plural(t.files), t.formatSpeed())
+	if left, ok := t.left(); ok {
+		line += fmt.Sprintf(", %s left", left)
+	}
+
+	return line + ".\n"
+}
+
+// left returns the time left at the current speed. The caller holds the lock.
+func (t *Tracker) left() (time.Duration, bool) {
+	if t.size <= 0 || t.speed <= 0 {
+		return 0, false
+	}
+
+	remaining := max(t.size-t.done, 0)
+	return (time.Duration(float64(remaining)/t.speed) * time.Second).Round(time.Second), true
+}
+
+func (t *Tracker) formatSpeed() string {
+	return common.FormatSize(int64(t.speed)) + "/s"
+}
+
+// lines returns the bars: one for each file being transferred, up to
+// maxFileBars, and one for the whole transfer. The caller holds the lock.
+func (t *Tracker) lines() []string {
+	lines := []string{}
+
+	// Names take what the bar and the figures leave of the line
+	nameWidth := max(t.width-barWidth-35, 10)
+	for i, f := range t.active {
+		if i == maxFileBars {
+			more := len(t.active) - maxFileBars
+			lines = append(lines, fmt.Sprintf("  ... and %d more %s", more, plural(more)))
+			break
+		}
+
+		sizes := common.FormatSize(f.done)
+		if f.size > 0 {
+			sizes += " / " + common.FormatSize(f.size)
+		}
+		lines = append(lines, fmt.Sprintf("  %-*s %s %3d%%  %s", nameWidth, shorten(f.name, nameWidth), bar(f.done, f.size), percent(f.done, f.size), sizes))
+	}
+
+	total := ""
+	if t.size > 0 {
+		total = fmt.Sprintf("%s %3d%%  %s / %s  %d/%d %s  %s", bar(t.done, t.size), percent(t.done, t.size),
+			common.FormatSize(t.done), common.FormatSize(t.size), t.doneFiles, t.files, plural(t.files), t.formatSpeed())
+		if left, ok := t.left(); ok {
+			total += fmt.Sprintf("  ETA %s", left)
+		}
+	} else {
+		total = fmt.Sprintf("%s  %d %s  %s", common.FormatSize(t.done), t.doneFiles, plural(t.doneFiles), t.formatSpeed())
+	}
+
+	return append(lines, total)
+}
+
+// redraw replaces the bars drawn last. The caller holds the lock.
+func (t *Tracker) redraw() {
+	t.erase()
+
+	lines := t.lines()
+	for _, line := range lines {
+		// A wrapped line would throw off the erasing of the next redraw
+		if len(line) >= t.width {
+			line = line[:t.width-1]
+		}
+		fmt.Fprintln(t.out, line)
+	}
+
+	t.drawn = len(lines)
+}
+
+// erase moves the cursor up to the bars drawn last, and clears them. The
+// caller holds the lock.
+func (t *Tracker) erase() {
+	if t.drawn > 0 {
+		fmt.Fprintf(t.out, "\x1b[%dA\x1b[J", t.drawn)
+		t.drawn = 0
+	}
+}
+
+// logWriter prints log lines above the bars.
+type logWriter struct {
+	t *Tracker
+}
+
+func (w logWriter) Write(p []byte) (int, error) {
+	w.t.mu.Lock()
+	defer w.t.mu.Unlock()
+
+	drawn := w.t.drawn > 0
+	w.t.erase()
+	n, err := w.t.out.Write(p)
+	if drawn {
+		w.t.redraw()
+	}
+
+	return n, err
+}
+
+func bar(done, size int64) string {
+	filled := 0
+	if size > 0 {
+		filled = int(min(done, size) * barWidth / size)
+	}
+
+	if filled == barWidth {
+		return "[" + strings.Repeat("=", barWidth) + "]"
+	}
+
+	return "[" + strings.Repeat("=", filled) + ">" + strings.Repeat(" ", barWidth-filled-1) + "]"
+}
+
+func percent(done, size int64) int {
+	if size <= 0 {
+		return 0
+	}
+
+	return int(min(done, size) * 100 / size)
+}
+
+// shorten keeps the end of names longer than width, which tells files
+// apart better than their start.
+func shorten(name string, width int) string {
+	if len(name) <= width {
+		return name
+	}
+
+	return "..." + name[len(name)-width+3:]
+}
+
+func plural(count int) string {
+	if count == 1 {
+		return "file"
+	}
+
+	return "files"
+}
@@ -0,0 +1,160 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Context(t *testing.T) {
+	assert.Nil(t, FromContext(context.Background()))
+
+	tracker := New("Pushed", io.Discard)
+	assert.Same(t, tracker, FromContext(NewContext(context.Background(), tracker)))
+}
+
+func Test__NilTracker(t *testing.T) {
+	var tracker *Tracker
+	tracker.Start()
+	tracker.SetTotal(1, 10)
+	tracker.Skip(10)
+
+	f := tracker.File("a.txt", 10)
+	assert.Nil(t, f)
+	f.Skip(5)
+	f.Done()
+
+	r := strings.NewReader("hello")
+	assert.Same(t, r, f.Reader(r))
+	tracker.Stop()
+}
+
+func Test__FileReader(t *testing.T) {
+	t.Run("counts the bytes read", func(t *testing.T) {
+		tracker := New("Pulled", io.Discard)
+		f := tracker.File("a.txt", -1)
+
+		_, err := io.Copy(io.Discard, f.Reader(io.NopCloser(strings.NewReader("hello"))))
+		require.NoError(t, err)
+		assert.Equal(t, int64(5), tracker.done)
+		assert.Equal(t, int64(5), tracker.transferred)
+	})
+
+	t.Run("counts bytes read again after seeking back once", func(t *testing.T) {
+		tracker := New("Pushed", io.Discard)
+		f := tracker.File("a.txt", 5)
+
+		r := f.Reader(strings.NewReader("hello"))
+		seeker, ok := r.(io.ReadSeeker)
+		require.True(t, ok)
+
+		_, err := io.Copy(io.Discard, seeker)
+		require.NoError(t, err)
+		_, err = seeker.Seek(0, io.SeekStart)
+		require.NoError(t, err)
+		_, err = io.Copy(io.Discard, seeker)
+		require.NoError(t, err)
+
+		assert.Equal(t, int64(5), tracker.transferred)
+	})
+}
+
+func Test__FileDone(t *testing.T) {
+	tracker := New("Pushed", io.Discard)
+
+	complete := tracker.File("a.txt", 5)
+	complete.Skip(2)
+	_, _ = io.Copy(io.Discard, complete.Reader(strings.NewReader("abc")))
+	failed := tracker.File("b.txt", 5)
+	assert.Len(t, tracker.active, 2)
+
+	complete.Done()
+	failed.Done()
+	assert.Empty(t, tracker.active)
+	assert.Equal(t, 1, tracker.doneFiles)
+	assert.Equal(t, int64(5), tracker.done)
+	assert.Equal(t, int64(3), tracker.transferred)
+}
+
+func Test__Summary(t *testing.T) {
+	tracker := New("Pushed", io.Discard)
+	tracker.SetTotal(3, 4096)
+	tracker.Skip(1024)
+
+	start := time.Now()
+	tracker.samples = []sample{{at: start}}
+
+	f := tracker.File("a.txt", 3072)
+	_, _ = io.Copy(io.Discard, f.Reader(bytes.NewReader(make([]byte, 1024))))
+	tracker.measure(start.Add(time.Second))
+
+	assert.Equal(t, "Pushed 2.0 KB of 4.0 KB (50%), 1 of 3 files, at 1.0 KB/s, 2s left.\n", tracker.summary())
+
+	unknown := New("Pulled", io.Discard)
+	unknown.Skip(100)
+	assert.Equal(t, "Pulled 100 B, 1 file, at 0 B/s.\n", unknown.summary())
+}
+
+func Test__Bars(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracker := &Tracker{verb: "Pushed", out: out, tty: true, width: 80}
+	tracker.SetTotal(2, 200)
+
+	f := tracker.File("build/app.zip", 100)
+	_, _ = io.Copy(io.Discard, f.Reader(bytes.NewReader(make([]byte, 50))))
+
+	tracker.redraw()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "build/app.zip")
+	assert.Contains(t, lines[0], "[==========>         ]  50%  50 B / 100 B")
+	assert.Contains(t, lines[1], "[=====>              ]  25%  50 B / 200 B  0/2 files")
+
+	// Redrawing erases the bars first
+	out.Reset()
+	tracker.redraw()
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[2A\x1b[J"))
+
+	// Logs are printed above the bars
+	out.Reset()
+	_, err := logWriter{tracker}.Write([]byte("a log line\n"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out.String(), "\x1b[2A\x1b[Ja log line\n"))
+	assert.Equal(t, 2, tracker.drawn)
+}
+
+func Test__BarsOfManyFiles(t *testing.T) {
+	tracker := &Tracker{out: io.Discard, tty: true, width: 80}
+	for i := 0; i < maxFileBars+3; i++ {
+		tracker.File("file", 10)
+	}
+
+	lines := tracker.lines()
+	assert.Len(t, lines, maxFileBars+2)
+	assert.Equal(t, "  ... and 3 more files", lines[maxFileBars])
+}
+
+func Test__StartStop(t *testing.T) {
+	out := &bytes.Buffer{}
+	tracker := &Tracker{verb: "Pushed", out: out, tty: true, width: 80}
+
+	logOut := log.StandardLogger().Out
+	tracker.Start()
+	assert.IsType(t, logWriter{}, log.StandardLogger().Out)
+
+	tracker.Stop()
+	tracker.Stop()
+	assert.Equal(t, logOut, log.StandardLogger().Out)
+}
+
+func Test__Shorten(t *testing.T) {
+	assert.Equal(t, "short.txt", shorten("short.txt", 10))
+	assert.Equal(t, "...ong.txt", shorten("a/very/long.txt", 10))
+}
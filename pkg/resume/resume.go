@@ -0,0 +1,226 @@
+// Package resume records the progress of a push in a local state file, so
+// a push that was interrupted can skip the files, and the parts of
+// multipart uploads, that were already uploaded when it is run again.
+//
+// The state file is a log of JSON lines appended as the push progresses,
+// so recording one more file costs a single small write:
+//
+//	{"file":"build/app.zip","size":1024,"modTime":"2024-01-01T00:00:00Z"}
+//	{"upload":"artifacts/jobs/1/video.mp4","uploadId":"abc","size":12884901888,"modTime":"...","partSize":16777216}
+//	{"upload":"artifacts/jobs/1/video.mp4","part":1,"etag":"\"d41d8c\""}
+package resume
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Upload is a multipart upload that was started, with the parts that
+// were uploaded so far, by part number.
+type Upload struct {
+	ID       string
+	Size     int64
+	ModTime  time.Time
+	PartSize int64
+	Parts    map[int32]string // ETags of the uploaded parts
+}
+
+// record is a line of the state file.
+type record struct {
+	File     string    `json:"file,omitempty"`
+	Upload   string    `json:"upload,omitempty"`
+	UploadID string    `json:"uploadId,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	ModTime  time.Time `json:"modTime,omitempty"`
+	PartSize int64     `json:"partSize,omitempty"`
+	Part     int32     `json:"part,omitempty"`
+	ETag     string    `json:"etag,omitempty"`
+	Drop     bool      `json:"drop,omitempty"`
+}
+
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// State is the progress of a push. It is safe for concurrent use.
+type State struct {
+	path    string
+	mu      sync.Mutex
+	f       *os.File
+	files   map[string]fileState
+	uploads map[string]*Upload
+}
+
+// Open loads the state file at path, or starts an empty state if there is
+// none. Nothing is written until progress is recorded.
+func Open(path string) (*State, error) {
+	s := &State{path: path, files: map[string]fileState{}, uploads: map[string]*Upload{}}
+
+	// #nosec
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		r := record{}
+
+		// A push killed while writing leaves a partial last line
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+
+		s.apply(r)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read upload state '%s': %v", path, err)
+	}
+
+	return s, nil
+}
+
+// Empty returns true if no progress was recorded.
+func (s *State) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.files) == 0 && len(s.uploads) == 0
+}
+
+// Pushed returns true if the local file was pushed, and has not changed since.
+func (s *State) Pushed(localPath string, info os.FileInfo) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	file, ok := s.files[localPath]
+	return ok && file.size == info.Size() && file.modTime.Equal(info.ModTime())
+}
+
+// MarkPushed records that the local file was pushed.
+func (s *State) MarkPushed(localPath string, info os.FileInfo) error {
+	return s.append(record{File: localPath, Size: info.Size(), ModTime: info.ModTime()})
+}
+
+// Upload returns the multipart upload started for key from the local file,
+// or nil if there is none or the file changed since.
+func (s *State) Upload(key string, info os.FileInfo) *Upload {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.uploads[key]
+	if !ok || upload.Size != info.Size() || !upload.ModTime.Equal(info.ModTime()) {
+		return nil
+	}
+
+	parts := map[int32]string{}
+	for number, etag := range upload.Parts {
+		parts[number] = etag
+	}
+
+	return &Upload{ID: upload.ID, Size: upload.Size, ModTime: upload.ModTime, PartSize: upload.PartSize, Parts: parts}
+}
+
+// StartUpload records a multipart upload of the local file to key.
+func (s *State) StartUpload(key, uploadID string, info os.FileInfo, partSize int64) error {
+	return s.append(record{Upload: key, UploadID: uploadID, Size: info.Size(), ModTime: info.ModTime(), PartSize: partSize})
+}
+
+// MarkPart records an uploaded part of the multipart upload to key.
+func (s *State) MarkPart(key string, part int32, etag string) error {
+	return s.append(record{Upload: key, Part: part, ETag: etag})
+}
+
+// DropUpload forgets the multipart upload to key, e.g. once it was
+// completed or found to be gone.
+func (s *State) DropUpload(key string) error {
+	return s.append(record{Upload: key, Drop: true})
+}
+
+// Close closes the state file, keeping it for the next run.
+func (s *State) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		return nil
+	}
+
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
+
+// Remove deletes the state file, once the push is complete.
+func (s *State) Remove() error {
+	if err := s.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Remove(s.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+func (s *State) append(r record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.f == nil {
+		if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+			return err
+		}
+
+		f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return err
+		}
+		s.f = f
+	}
+
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to record upload progress: %v", err)
+	}
+
+	s.apply(r)
+	return nil
+}
+
+// apply updates the state with a record. The caller holds the lock, or
+// has the only reference to the state.
+func (s *State) apply(r record) {
+	switch {
+	case r.File != "":
+		s.files[r.File] = fileState{size: r.Size, modTime: r.ModTime}
+
+	case r.Upload != "" && r.Drop:
+		delete(s.uploads, r.Upload)
+
+	case r.Upload != "" && r.UploadID != "":
+		s.uploads[r.Upload] = &Upload{ID: r.UploadID, Size: r.Size, ModTime: r.ModTime, PartSize: r.PartSize, Parts: map[int32]string{}}
+
+	case r.Upload != "" && r.Part > 0:
+		if upload, ok := s.uploads[r.Upload]; ok {
+			upload.Parts[r.Part] = r.ETag
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package resume
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__State(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "state", "push.jsonl")
+	file := filepath.Join(dir, "app.zip")
+	require.NoError(t, os.WriteFile(file, []byte("app"), 0644))
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+
+	state, err := Open(path)
+	require.NoError(t, err)
+	assert.True(t, state.Empty())
+	assert.NoFileExists(t, path)
+
+	require.NoError(t, state.MarkPushed(file, info))
+	require.NoError(t, state.StartUpload("video.mp4", "upload-1", info, 5))
+	require.NoError(t, state.MarkPart("video.mp4", 1, `"etag-1"`))
+	require.NoError(t, state.StartUpload("gone.mp4", "upload-2", info, 5))
+	require.NoError(t, state.DropUpload("gone.mp4"))
+	require.NoError(t, state.Close())
+
+	// A push killed while writing leaves a partial line
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	require.NoError(t, err)
+	_, err = f.WriteString(`{"file":"trunc`)
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	state, err = Open(path)
+	require.NoError(t, err)
+	assert.False(t, state.Empty())
+	assert.True(t, state.Pushed(file, info))
+	assert.Nil(t, state.Upload("gone.mp4", info))
+
+	upload := state.Upload("video.mp4", info)
+	require.NotNil(t, upload)
+	assert.Equal(t, "upload-1", upload.ID)
+	assert.Equal(t, int64(5), upload.PartSize)
+	assert.Equal(t, map[int32]string{1: `"etag-1"`}, upload.Parts)
+
+	// Changed files are pushed again
+	later := info.ModTime().Add(time.Second)
+	require.NoError(t, os.Chtimes(file, later, later))
+	changed, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.False(t, state.Pushed(file, changed))
+	assert.Nil(t, state.Upload("video.mp4", changed))
+
+	require.NoError(t, state.Remove())
+	assert.NoFileExists(t, path)
+	require.NoError(t, state.Remove())
+}
@@ -0,0 +1,155 @@
+// Package retention decides when stored files expire. Rules are kept in
+// the backend itself, so every machine running prune or gc applies the
+// same ones:
+//
+//	rules:
+//	  - category: job
+//	    match: "**/*.log"
+//	    expireIn: 7d
+package retention
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/files"
+	"gopkg.in/yaml.v3"
+)
+
+// Path is where the retention policy is stored in the backend, outside of
+// every artifact store.
+const Path = "artifacts/.retention.yaml"
+
+// Rule expires the files of a category matching a glob once they are
+// older than ExpireIn.
+type Rule struct {
+	Category string `yaml:"category"` // job, workflow or project
+	Match    string `yaml:"match"`    // glob relative to the category, e.g. **/*.log
+	ExpireIn string `yaml:"expireIn"` // age after which files expire, e.g. 7d
+
+	expireIn time.Duration
+}
+
+// Policy is the list of retention rules.
+type Policy struct {
+	Rules []*Rule `yaml:"rules"`
+}
+
+// Parse reads a policy stored at Path.
+func Parse(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := yaml.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("failed to parse retention policy: %v", err)
+	}
+
+	for _, rule := range p.Rules {
+		if err := rule.validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// Marshal returns the policy in the format stored at Path.
+func (p *Policy) Marshal() ([]byte, error) {
+	return yaml.Marshal(p)
+}
+
+// Set adds a rule, or replaces the rule with the same category and match.
+func (p *Policy) Set(category, match, expireIn string) error {
+	rule := &Rule{Category: category, Match: match, ExpireIn: expireIn}
+	if err := rule.validate(); err != nil {
+		return err
+	}
+
+	for i, r := range p.Rules {
+		if r.Category == rule.Category && r.Match == rule.Match {
+			p.Rules[i] = rule
+			return nil
+		}
+	}
+
+	p.Rules = append(p.Rules, rule)
+	return nil
+}
+
+// Unset removes the rule with the given category and match, and returns
+// false if there is none.
+func (p *Policy) Unset(category, match string) bool {
+	for i, r := range p.Rules {
+		if r.Category == category && r.Match == normalizeMatch(match) {
+			p.Rules = append(p.Rules[:i], p.Rules[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExpiresIn returns how long files of category at name, relative to the
+// category, are kept. If several rules match, the longest retention wins,
+// so a broad rule never shortens the retention of files a narrower one
+// keeps. It returns false if no rule matches.
+func (p *Policy) ExpiresIn(category, name string) (time.Duration, bool) {
+	var longest time.Duration
+	found := false
+
+	for _, rule := range p.Rules {
+		if rule.Category != category {
+			continue
+		}
+
+		if matched, _ := files.MatchGlob(rule.Match, name); !matched {
+			continue
+		}
+
+		if !found || rule.expireIn > longest {
+			longest, found = rule.expireIn, true
+		}
+	}
+
+	return longest, found
+}
+
+// Expired returns true if a file of category at name, last modified at
+// modTime, has outlived the rules matching it.
+func (p *Policy) Expired(category, name string, modTime, now time.Time) bool {
+	expireIn, ok := p.ExpiresIn(category, name)
+	return ok && now.Sub(modTime) >= expireIn
+}
+
+func (r *Rule) validate() error {
+	switch r.Category {
+	case files.ResourceTypeJob, files.ResourceTypeWorkflow, files.ResourceTypeProject:
+	default:
+		return fmt.Errorf("invalid retention category '%s': use job, workflow or project", r.Category)
+	}
+
+	r.Match = normalizeMatch(r.Match)
+	if err := files.ValidateGlob(r.Match); err != nil {
+		return fmt.Errorf("invalid retention match '%s': %v", r.Match, err)
+	}
+
+	expireIn, err := common.ParseAge(r.ExpireIn)
+	if err != nil {
+		return fmt.Errorf("invalid retention for '%s': %v", r.Match, err)
+	}
+
+	if expireIn <= 0 {
+		return fmt.Errorf("invalid retention for '%s': it must be longer than 0", r.Match)
+	}
+
+	r.expireIn = expireIn
+	return nil
+}
+
+// normalizeMatch makes rules without a glob apply to every file.
+func normalizeMatch(match string) string {
+	if match == "" {
+		return "**"
+	}
+
+	return match
+}
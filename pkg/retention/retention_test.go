@@ -0,0 +1,54 @@
+package retention
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__Policy(t *testing.T) {
+	p := &Policy{}
+	require.NoError(t, p.Set("job", "**/*.log", "7d"))
+	require.NoError(t, p.Set("job", "", "30d"))
+	require.NoError(t, p.Set("workflow", "reports/**", "2w"))
+
+	// Setting a rule again replaces it
+	require.NoError(t, p.Set("job", "**/*.log", "1d"))
+	assert.Len(t, p.Rules, 3)
+
+	expireIn, ok := p.ExpiresIn("job", "logs/build.log")
+	require.True(t, ok)
+	assert.Equal(t, 30*24*time.Hour, expireIn)
+
+	_, ok = p.ExpiresIn("workflow", "app.zip")
+	assert.False(t, ok)
+
+	now := time.Now()
+	assert.True(t, p.Expired("workflow", "reports/junit.xml", now.Add(-15*24*time.Hour), now))
+	assert.False(t, p.Expired("workflow", "reports/junit.xml", now.Add(-13*24*time.Hour), now))
+	assert.False(t, p.Expired("project", "app.zip", now.Add(-1000*24*time.Hour), now))
+
+	data, err := p.Marshal()
+	require.NoError(t, err)
+
+	parsed, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, p.Rules, parsed.Rules)
+
+	assert.True(t, parsed.Unset("job", ""))
+	assert.False(t, parsed.Unset("job", ""))
+	assert.Len(t, parsed.Rules, 2)
+}
+
+func Test__PolicyValidation(t *testing.T) {
+	p := &Policy{}
+	assert.ErrorContains(t, p.Set("build", "", "7d"), "invalid retention category 'build'")
+	assert.ErrorContains(t, p.Set("job", "[", "7d"), "invalid retention match")
+	assert.ErrorContains(t, p.Set("job", "", "soon"), "invalid duration 'soon'")
+	assert.ErrorContains(t, p.Set("job", "", "0d"), "must be longer than 0")
+
+	_, err := Parse([]byte("rules:\n  - category: job\n    expireIn: 1x\n"))
+	assert.ErrorContains(t, err, "invalid duration '1x'")
+}
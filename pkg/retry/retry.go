@@ -0,0 +1,204 @@
+// Package retry holds the policy transient failures of backend requests are
+// retried with: server errors (5xx), throttling (429) and network errors
+// such as reset connections. It is shared by the HTTP clients of all
+// backends and the S3 SDK, so every backend retries the same way.
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Defaults of the policy.
+const (
+	DefaultAttempts  = 5
+	DefaultBaseDelay = 500 * time.Millisecond
+	DefaultMaxDelay  = 20 * time.Second
+
+	// DefaultRequestTimeout is how long a request waits for the response
+	// to start. It does not bound the transfer of the body, so large files
+	// are never cut off.
+	DefaultRequestTimeout = time.Minute
+)
+
+// retries counts the requests retried by any client, for the summary of
+// transfers.
+var retries atomic.Int64
+
+// Retried records that a failed request is tried again.
+func Retried() {
+	retries.Add(1)
+}
+
+// Retries returns how many requests were retried so far.
+func Retries() int64 {
+	return retries.Load()
+}
+
+// Policy is how often, and how long apart, a failed request is tried again.
+type Policy struct {
+	Attempts  int           // requests in total, the first one included
+	BaseDelay time.Duration // delay before the first retry, doubled for each further one
+	MaxDelay  time.Duration // upper bound of the delay
+
+	// RequestTimeout is how long a request waits for the response to
+	// start before it fails, and is retried; zero waits forever.
+	RequestTimeout time.Duration
+}
+
+// Default returns the default policy.
+func Default() Policy {
+	return Policy{Attempts: DefaultAttempts, BaseDelay: DefaultBaseDelay, MaxDelay: DefaultMaxDelay, RequestTimeout: DefaultRequestTimeout}
+}
+
+// Load returns the policy configured with the ARTIFACT_RETRY_ATTEMPTS,
+// ARTIFACT_RETRY_BASE_DELAY, ARTIFACT_RETRY_MAX_DELAY and
+// ARTIFACT_REQUEST_TIMEOUT env vars, or the retry.attempts, retry.baseDelay,
+// retry.maxDelay and retry.requestTimeout config keys, e.g. "10", "1s",
+// "1m" and "2m". Invalid values are ignored with a warning.
+func Load() Policy {
+	p := Default()
+
+	if value := setting("ARTIFACT_RETRY_ATTEMPTS", "retry.attempts"); value != "" {
+		attempts, err := strconv.Atoi(value)
+		if err != nil || attempts < 1 {
+			log.Warnf("Ignoring invalid retry attempts '%s': use a positive number\n", value)
+		} else {
+			p.Attempts = attempts
+		}
+	}
+
+	if value := setting("ARTIFACT_RETRY_BASE_DELAY", "retry.baseDelay"); value != "" {
+		p.BaseDelay = parseDelay(value, p.BaseDelay)
+	}
+
+	if value := setting("ARTIFACT_RETRY_MAX_DELAY", "retry.maxDelay"); value != "" {
+		p.MaxDelay = parseDelay(value, p.MaxDelay)
+	}
+
+	if value := setting("ARTIFACT_REQUEST_TIMEOUT", "retry.requestTimeout"); value != "" {
+		p.RequestTimeout = parseDelay(value, p.RequestTimeout)
+	}
+
+	if p.MaxDelay < p.BaseDelay {
+		p.MaxDelay = p.BaseDelay
+	}
+
+	return p
+}
+
+func setting(env, key string) string {
+	if value := os.Getenv(env); value != "" {
+		return value
+	}
+
+	return viper.GetString(key)
+}
+
+func parseDelay(value string, fallback time.Duration) time.Duration {
+	delay, err := time.ParseDuration(value)
+	if err != nil || delay < 0 {
+		log.Warnf("Ignoring invalid duration '%s': use a duration like 500ms or 2s\n", value)
+		return fallback
+	}
+
+	return delay
+}
+
+// Delay returns how long to wait before retry number attempt, counting
+// from zero: the base delay doubled for every earlier retry, capped at the
+// maximum, of which a random half is waited, so clients failing together
+// do not retry together.
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.BaseDelay
+	for i := 0; i < attempt && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	delay = min(delay, p.MaxDelay)
+
+	if delay < 2 {
+		return delay
+	}
+
+	// #nosec
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)))
+}
+
+// Configure makes the client retry with the policy, and time out requests
+// after its request timeout. Throttled responses
+// are recorded for the limiters of running transfers, and the wait they
+// ask for with a Retry-After header is honored, up to the maximum delay.
+func (p Policy) Configure(client *retryablehttp.Client) {
+	p.configureTimeout(client)
+	client.RetryMax = p.Attempts - 1
+	client.RetryWaitMin = p.BaseDelay
+	client.RetryWaitMax = p.MaxDelay
+	client.CheckRetry = func(ctx context.Context, resp *http.Response, err error) (bool, error) {
+		if resp != nil && IsThrottle(resp.StatusCode) {
+			Throttled()
+		}
+
+		return retryablehttp.DefaultRetryPolicy(ctx, resp, err)
+	}
+	client.Backoff = func(_, _ time.Duration, attempt int, resp *http.Response) time.Duration {
+		if delay, ok := RetryAfter(resp); ok {
+			return min(delay, p.MaxDelay)
+		}
+
+		return p.Delay(attempt)
+	}
+	client.RequestLogHook = func(_ retryablehttp.Logger, req *http.Request, attempt int) {
+		if attempt > 0 {
+			Retried()
+
+			// Signed URLs carry their credentials in the query
+			u := *req.URL
+			u.RawQuery = ""
+			log.Warnf("Retrying %s %s (attempt %d of %d)...\n", req.Method, u.Redacted(), attempt+1, p.Attempts)
+		}
+	}
+}
+
+// configureTimeout makes the client's requests wait for the response to
+// start for at most the request timeout. The transport is cloned, so
+// shared ones, like http.DefaultTransport, are left untouched.
+func (p Policy) configureTimeout(client *retryablehttp.Client) {
+	if p.RequestTimeout <= 0 {
+		return
+	}
+
+	httpClient := http.Client{}
+	if client.HTTPClient != nil {
+		httpClient = *client.HTTPClient
+	}
+
+	httpClient.Transport = p.Transport(httpClient.Transport)
+	client.HTTPClient = &httpClient
+}
+
+// Transport returns a clone of transport, or of http.DefaultTransport if
+// it is nil, whose requests time out after the request timeout.
+// Transports of other types are returned as they are.
+func (p Policy) Transport(transport http.RoundTripper) http.RoundTripper {
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	t, ok := transport.(*http.Transport)
+	if !ok {
+		return transport
+	}
+
+	t = t.Clone()
+	t.ResponseHeaderTimeout = p.RequestTimeout
+	return t
+}
@@ -0,0 +1,174 @@
+package retry
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/go-retryablehttp"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__Load(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		assert.Equal(t, Default(), Load())
+	})
+
+	t.Run("env vars", func(t *testing.T) {
+		t.Setenv("ARTIFACT_RETRY_ATTEMPTS", "10")
+		t.Setenv("ARTIFACT_RETRY_BASE_DELAY", "1s")
+		t.Setenv("ARTIFACT_RETRY_MAX_DELAY", "1m")
+		t.Setenv("ARTIFACT_REQUEST_TIMEOUT", "2m")
+
+		assert.Equal(t, Policy{Attempts: 10, BaseDelay: time.Second, MaxDelay: time.Minute, RequestTimeout: 2 * time.Minute}, Load())
+	})
+
+	t.Run("config file", func(t *testing.T) {
+		viper.Set("retry.attempts", "3")
+		defer viper.Set("retry.attempts", nil)
+
+		assert.Equal(t, 3, Load().Attempts)
+	})
+
+	t.Run("invalid values are ignored", func(t *testing.T) {
+		t.Setenv("ARTIFACT_RETRY_ATTEMPTS", "0")
+		t.Setenv("ARTIFACT_RETRY_BASE_DELAY", "soon")
+
+		assert.Equal(t, Default(), Load())
+	})
+
+	t.Run("max delay is at least the base delay", func(t *testing.T) {
+		t.Setenv("ARTIFACT_RETRY_BASE_DELAY", "30s")
+
+		assert.Equal(t, 30*time.Second, Load().MaxDelay)
+	})
+}
+
+func Test__Delay(t *testing.T) {
+	p := Policy{Attempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		delay := p.Delay(attempt)
+		assert.GreaterOrEqual(t, delay, expected/2)
+		assert.Less(t, delay, expected)
+	}
+
+	// Doubling stops at the maximum instead of overflowing
+	assert.GreaterOrEqual(t, p.Delay(1000), 5*time.Second)
+}
+
+func Test__Configure(t *testing.T) {
+	p := Policy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			switch requests {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusOK)
+			}
+		}))
+		defer server.Close()
+
+		client := retryablehttp.NewClient()
+		client.Logger = nil
+		p.Configure(client)
+
+		before := Retries()
+		response, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, 3, requests)
+		assert.Equal(t, before+2, Retries())
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+
+		client := retryablehttp.NewClient()
+		client.Logger = nil
+		p.Configure(client)
+
+		response, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusForbidden, response.StatusCode)
+		assert.Equal(t, 1, requests)
+	})
+
+	t.Run("retries stop after the attempts", func(t *testing.T) {
+		requests := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		client := retryablehttp.NewClient()
+		client.Logger = nil
+		p.Configure(client)
+
+		_, err := client.Get(server.URL)
+		assert.Error(t, err)
+		assert.Equal(t, 3, requests)
+	})
+
+	t.Run("throttling is recorded", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTooManyRequests)
+		}))
+		defer server.Close()
+
+		client := retryablehttp.NewClient()
+		client.Logger = nil
+		p.Configure(client)
+
+		before := throttles.Load()
+		_, _ = client.Get(server.URL)
+		assert.Equal(t, before+3, throttles.Load())
+	})
+
+	t.Run("requests waiting for the response time out", func(t *testing.T) {
+		var requests atomic.Int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if requests.Add(1) == 1 {
+				time.Sleep(200 * time.Millisecond)
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer server.Close()
+
+		p := p
+		p.RequestTimeout = 50 * time.Millisecond
+
+		client := retryablehttp.NewClient()
+		client.Logger = nil
+		p.Configure(client)
+
+		response, err := client.Get(server.URL)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusOK, response.StatusCode)
+		assert.Equal(t, int32(2), requests.Load())
+		assert.Zero(t, http.DefaultTransport.(*http.Transport).ResponseHeaderTimeout)
+	})
+
+	t.Run("Retry-After is waited for up to the max delay", func(t *testing.T) {
+		client := retryablehttp.NewClient()
+		p.Configure(client)
+
+		response := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": []string{"120"}}}
+		assert.Equal(t, p.MaxDelay, client.Backoff(0, 0, 0, response))
+	})
+}
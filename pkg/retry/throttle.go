@@ -0,0 +1,125 @@
+package retry
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// throttles counts the throttled responses seen by any client, e.g. 429s
+// and S3 SlowDown errors. Limiters compare it with the count they last saw.
+var throttles atomic.Int64
+
+// Throttled records that the storage asked to slow down, so the limiters
+// of running transfers reduce their concurrency.
+func Throttled() {
+	throttles.Add(1)
+}
+
+// IsThrottle reports whether the status code of a response asks to slow
+// down: 429, or 503, which S3 answers with SlowDown.
+func IsThrottle(statusCode int) bool {
+	return statusCode == http.StatusTooManyRequests || statusCode == http.StatusServiceUnavailable
+}
+
+// RetryAfter returns how long a throttled response asks to wait with its
+// Retry-After header, given in seconds or as an HTTP date.
+func RetryAfter(resp *http.Response) (time.Duration, bool) {
+	if resp == nil || !IsThrottle(resp.StatusCode) {
+		return 0, false
+	}
+
+	value := resp.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		return max(time.Until(date), 0), true
+	}
+
+	return 0, false
+}
+
+// Limiter bounds how many transfers run at once, adapting to throttling:
+// the limit is halved whenever the storage throttled requests since it
+// was last checked, and raised by one again after as many transfers as
+// the limit succeeded without throttling, up to the maximum.
+type Limiter struct {
+	mu        sync.Mutex
+	cond      *sync.Cond
+	max       int
+	limit     int
+	active    int
+	succeeded int
+	seen      int64
+}
+
+// NewLimiter returns a limiter allowing up to concurrency transfers at once.
+func NewLimiter(concurrency int) *Limiter {
+	l := &Limiter{max: concurrency, limit: concurrency, seen: throttles.Load()}
+	l.cond = sync.NewCond(&l.mu)
+	return l
+}
+
+// Acquire waits until another transfer may start.
+func (l *Limiter) Acquire() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.adapt()
+	for l.active >= l.limit {
+		l.cond.Wait()
+		l.adapt()
+	}
+
+	l.active++
+}
+
+// Release ends a transfer started with Acquire.
+func (l *Limiter) Release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.active--
+	if !l.adapt() && l.limit < l.max {
+		l.succeeded++
+		if l.succeeded >= l.limit {
+			l.limit++
+			l.succeeded = 0
+			log.Debugf("No more throttling, raising concurrency to %d.\n", l.limit)
+		}
+	}
+
+	l.cond.Broadcast()
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+// adapt halves the limit if there was throttling since the last check,
+// and reports whether there was. The caller holds the lock.
+func (l *Limiter) adapt() bool {
+	seen := throttles.Load()
+	if seen == l.seen {
+		return false
+	}
+
+	l.seen = seen
+	l.succeeded = 0
+	if l.limit > 1 {
+		l.limit = max(l.limit/2, 1)
+		log.Warnf("The storage is throttling requests, reducing concurrency to %d.\n", l.limit)
+	}
+
+	return true
+}
@@ -0,0 +1,105 @@
+package retry
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test__RetryAfter(t *testing.T) {
+	response := func(status int, retryAfter string) *http.Response {
+		return &http.Response{StatusCode: status, Header: http.Header{"Retry-After": []string{retryAfter}}}
+	}
+
+	delay, ok := RetryAfter(response(http.StatusTooManyRequests, "3"))
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, delay)
+
+	delay, ok = RetryAfter(response(http.StatusServiceUnavailable, time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)))
+	assert.True(t, ok)
+	assert.InDelta(t, time.Minute, delay, float64(2*time.Second))
+
+	delay, ok = RetryAfter(response(http.StatusServiceUnavailable, time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat)))
+	assert.True(t, ok)
+	assert.Zero(t, delay)
+
+	_, ok = RetryAfter(response(http.StatusInternalServerError, "3"))
+	assert.False(t, ok)
+
+	_, ok = RetryAfter(response(http.StatusTooManyRequests, "later"))
+	assert.False(t, ok)
+
+	_, ok = RetryAfter(nil)
+	assert.False(t, ok)
+}
+
+func Test__Limiter(t *testing.T) {
+	l := NewLimiter(8)
+	assert.Equal(t, 8, l.Limit())
+
+	// Throttling halves the limit once per check
+	Throttled()
+	Throttled()
+	l.Acquire()
+	assert.Equal(t, 4, l.Limit())
+	l.Release()
+	assert.Equal(t, 4, l.Limit())
+
+	Throttled()
+	l.Acquire()
+	l.Release()
+	assert.Equal(t, 2, l.Limit())
+
+	// Transfers succeeding without throttling raise it again
+	for i := 0; i < 2; i++ {
+		l.Acquire()
+		l.Release()
+	}
+	assert.Equal(t, 3, l.Limit())
+
+	for i := 0; i < 100; i++ {
+		l.Acquire()
+		l.Release()
+	}
+	assert.Equal(t, 8, l.Limit())
+
+	// It never drops below one
+	for i := 0; i < 5; i++ {
+		Throttled()
+		l.Acquire()
+		assert.GreaterOrEqual(t, l.Limit(), 1)
+		l.Release()
+	}
+	Throttled()
+	l.Acquire()
+	assert.Equal(t, 1, l.Limit())
+	l.Release()
+}
+
+func Test__LimiterBlocksAboveTheLimit(t *testing.T) {
+	l := NewLimiter(2)
+	l.Acquire()
+	l.Acquire()
+
+	Throttled()
+	l.Release()
+
+	// One transfer is running, and the limit is now one
+	acquired := make(chan struct{})
+	go func() {
+		l.Acquire()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("acquired above the limit")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Equal(t, 1, l.Limit())
+
+	l.Release()
+	<-acquired
+}
@@ -0,0 +1,316 @@
+// Package selfupdate finds the latest release of the CLI on GitHub and
+// replaces the running binary with it, after checking the release's
+// checksums and, given a public key, their signature.
+package selfupdate
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/semaphoreci/artifact/pkg/signing"
+)
+
+const (
+	ChannelStable = "stable" // the latest release
+	ChannelEdge   = "edge"   // the latest release or pre-release
+
+	// DefaultURL is the GitHub API endpoint of the repository's releases.
+	DefaultURL = "https://api.github.com/repos/semaphoreci/artifact"
+
+	// ChecksumsName is the asset listing the SHA256 checksums of the archives.
+	ChecksumsName = "artifact_checksums.txt"
+)
+
+// Release is a GitHub release.
+type Release struct {
+	Tag        string  `json:"tag_name"`
+	Prerelease bool    `json:"prerelease"`
+	Assets     []Asset `json:"assets"`
+}
+
+// Asset is a file attached to a release.
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the release's version, without the leading "v".
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.Tag, "v")
+}
+
+// Asset returns the asset named name.
+func (r *Release) Asset(name string) (*Asset, error) {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i], nil
+		}
+	}
+
+	return nil, fmt.Errorf("release %s has no asset '%s'", r.Tag, name)
+}
+
+// Client fetches releases from the GitHub API.
+type Client struct {
+	URL        string // API endpoint of the repository, e.g. DefaultURL
+	Token      string // optional GitHub token, to raise rate limits
+	HttpClient *http.Client
+}
+
+// Latest returns the latest release of a channel.
+func (c *Client) Latest(ctx context.Context, channel string) (*Release, error) {
+	switch channel {
+	case ChannelStable:
+		release := &Release{}
+		if err := c.getJSON(ctx, c.URL+"/releases/latest", release); err != nil {
+			return nil, err
+		}
+		return release, nil
+
+	case ChannelEdge:
+		// Releases are listed newest first, including pre-releases
+		releases := []*Release{}
+		if err := c.getJSON(ctx, c.URL+"/releases?per_page=1", &releases); err != nil {
+			return nil, err
+		}
+		if len(releases) == 0 {
+			return nil, fmt.Errorf("no releases found")
+		}
+		return releases[0], nil
+
+	default:
+		return nil, fmt.Errorf("unknown channel '%s': use %s or %s", channel, ChannelStable, ChannelEdge)
+	}
+}
+
+// Download returns the contents of an asset.
+func (c *Client) Download(ctx context.Context, asset *Asset) ([]byte, error) {
+	resp, err := c.get(ctx, asset.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download '%s': %v", asset.Name, err)
+	}
+
+	return data, nil
+}
+
+func (c *Client) getJSON(ctx context.Context, url string, v interface{}) error {
+	resp, err := c.get(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("failed to parse response of %s: %v", url, err)
+	}
+
+	return nil
+}
+
+func (c *Client) get(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.Token != "" && strings.HasPrefix(url, c.URL) {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HttpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("request to %s failed with status %d", url, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// ArchiveName returns the name of the release archive for a platform, as
+// named by the release configuration, e.g. artifact_Linux_x86_64.tar.gz.
+func ArchiveName(goos, goarch, goarm string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	case "arm":
+		arch = "armv" + goarm
+	}
+
+	return fmt.Sprintf("artifact_%s_%s.tar.gz", strings.ToUpper(goos[:1])+goos[1:], arch)
+}
+
+// VerifyChecksum checks data against its checksum in checksums, in the
+// format of sha256sum.
+func VerifyChecksum(checksums []byte, name string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || fields[1] != name {
+			continue
+		}
+
+		sum := sha256.Sum256(data)
+		if hex.EncodeToString(sum[:]) != strings.ToLower(fields[0]) {
+			return fmt.Errorf("checksum of '%s' does not match", name)
+		}
+		return nil
+	}
+
+	return fmt.Errorf("no checksum for '%s'", name)
+}
+
+// VerifyChecksums checks the signature of the checksums file, made with
+// 'cosign sign-blob', so every archive it lists is trusted.
+func VerifyChecksums(key crypto.PublicKey, checksums, signature []byte) error {
+	if err := signing.Verify(key, bytes.NewReader(checksums), signature); err != nil {
+		return fmt.Errorf("signature of %s: %v", ChecksumsName, err)
+	}
+
+	return nil
+}
+
+// ExtractBinary returns the file named name from a tar.gz archive.
+func ExtractBinary(archive io.Reader, name string) ([]byte, error) {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("archive has no '%s'", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// Replace replaces the binary at exe with data. The new binary is written
+// next to it and renamed over it, so exe is never left half written. The
+// old binary is moved aside first, as running binaries cannot be replaced
+// on Windows.
+func Replace(exe string, data []byte) error {
+	info, err := os.Stat(exe)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(exe), ".artifact-update-*")
+	if err != nil {
+		return fmt.Errorf("failed to write next to '%s': %v", exe, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), info.Mode().Perm()|0111); err != nil {
+		return err
+	}
+
+	old := exe + ".old"
+	_ = os.Remove(old)
+	if err := os.Rename(exe, old); err != nil {
+		return fmt.Errorf("failed to replace '%s': %v", exe, err)
+	}
+
+	if err := os.Rename(tmp.Name(), exe); err != nil {
+		_ = os.Rename(old, exe)
+		return fmt.Errorf("failed to replace '%s': %v", exe, err)
+	}
+
+	// Windows keeps the old binary open while it runs; it is removed by the next update
+	_ = os.Remove(old)
+	return nil
+}
+
+// Newer returns true if version a is newer than b. Versions are compared
+// as major.minor.patch, and a pre-release, e.g. 1.2.0-rc.1, is older than
+// its release. Development builds, with no version, are older than any.
+func Newer(a, b string) bool {
+	ap, apre, aok := parseVersion(a)
+	bp, bpre, bok := parseVersion(b)
+	if !aok || !bok {
+		return aok && !bok
+	}
+
+	for i := range ap {
+		if ap[i] != bp[i] {
+			return ap[i] > bp[i]
+		}
+	}
+
+	// Pre-release identifiers are compared as strings, which orders rc.1 < rc.2
+	switch {
+	case apre == bpre:
+		return false
+	case apre == "":
+		return true
+	case bpre == "":
+		return false
+	default:
+		return apre > bpre
+	}
+}
+
+func parseVersion(version string) ([3]int, string, bool) {
+	parts := [3]int{}
+	version = strings.TrimPrefix(version, "v")
+
+	core, pre, _ := strings.Cut(version, "-")
+	fields := strings.Split(core, ".")
+	if len(fields) != 3 {
+		return parts, "", false
+	}
+
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil {
+			return parts, "", false
+		}
+		parts[i] = n
+	}
+
+	return parts, pre, true
+}
@@ -0,0 +1,83 @@
+package selfupdate
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__ArchiveName(t *testing.T) {
+	assert.Equal(t, "artifact_Linux_x86_64.tar.gz", ArchiveName("linux", "amd64", ""))
+	assert.Equal(t, "artifact_Darwin_arm64.tar.gz", ArchiveName("darwin", "arm64", ""))
+	assert.Equal(t, "artifact_Windows_i386.tar.gz", ArchiveName("windows", "386", ""))
+	assert.Equal(t, "artifact_Linux_armv7.tar.gz", ArchiveName("linux", "arm", "7"))
+}
+
+func Test__VerifyChecksum(t *testing.T) {
+	sum := sha256.Sum256([]byte("binary"))
+	checksums := []byte(hex.EncodeToString(sum[:]) + "  artifact_Linux_x86_64.tar.gz\nabc  artifact_Darwin_arm64.tar.gz\n")
+
+	assert.NoError(t, VerifyChecksum(checksums, "artifact_Linux_x86_64.tar.gz", []byte("binary")))
+	assert.ErrorContains(t, VerifyChecksum(checksums, "artifact_Linux_x86_64.tar.gz", []byte("tampered")), "does not match")
+	assert.ErrorContains(t, VerifyChecksum(checksums, "artifact_Linux_arm64.tar.gz", []byte("binary")), "no checksum")
+}
+
+func Test__Newer(t *testing.T) {
+	assert.True(t, Newer("1.2.0", "v1.1.9"))
+	assert.True(t, Newer("v1.10.0", "1.9.0"))
+	assert.True(t, Newer("1.2.0", "1.2.0-rc.1"))
+	assert.True(t, Newer("1.2.0-rc.2", "1.2.0-rc.1"))
+	assert.True(t, Newer("0.1.0", "dev"))
+	assert.False(t, Newer("1.2.0", "1.2.0"))
+	assert.False(t, Newer("1.2.0-rc.1", "1.2.0"))
+	assert.False(t, Newer("1.1.0", "1.2.0"))
+	assert.False(t, Newer("dev", "1.2.0"))
+}
+
+func Test__ExtractBinary(t *testing.T) {
+	archive := &bytes.Buffer{}
+	gz := gzip.NewWriter(archive)
+	tw := tar.NewWriter(gz)
+	for name, contents := range map[string]string{"README.md": "docs", "artifact": "binary"} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(contents)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	binary, err := ExtractBinary(bytes.NewReader(archive.Bytes()), "artifact")
+	require.NoError(t, err)
+	assert.Equal(t, "binary", string(binary))
+
+	_, err = ExtractBinary(bytes.NewReader(archive.Bytes()), "artifact.exe")
+	assert.ErrorContains(t, err, "archive has no 'artifact.exe'")
+}
+
+func Test__Replace(t *testing.T) {
+	exe := filepath.Join(t.TempDir(), "artifact")
+	require.NoError(t, os.WriteFile(exe, []byte("old"), 0700))
+
+	require.NoError(t, Replace(exe, []byte("new")))
+
+	contents, err := os.ReadFile(exe)
+	require.NoError(t, err)
+	assert.Equal(t, "new", string(contents))
+
+	info, err := os.Stat(exe)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0711), info.Mode().Perm())
+
+	// Nothing is left next to the binary
+	entries, err := os.ReadDir(filepath.Dir(exe))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
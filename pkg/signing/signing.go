@@ -0,0 +1,188 @@
+// Package signing creates and checks detached signatures of artifacts.
+//
+// Signatures follow the format of 'cosign sign-blob --key': the base64
+// encoded signature of the SHA256 digest of the file, made with an ECDSA or
+// RSA key. They are stored next to the signed file, with a ".sig" extension,
+// so they can also be checked with 'cosign verify-blob --key cosign.pub'.
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// SignatureExt is appended to the path of a file to get its signature's.
+const SignatureExt = ".sig"
+
+// ErrInvalidSignature is returned when a signature does not match the file.
+var ErrInvalidSignature = errors.New("signature does not match the file")
+
+// SignaturePath returns the path of the signature of remotePath.
+func SignaturePath(remotePath string) string {
+	return remotePath + SignatureExt
+}
+
+// IsSignature returns true for paths of signatures.
+func IsSignature(remotePath string) bool {
+	return strings.HasSuffix(remotePath, SignatureExt)
+}
+
+// PrivateKeyPath returns the configured signing key: ARTIFACT_SIGNING_KEY
+// or the signing.key config key.
+func PrivateKeyPath() string {
+	if path := os.Getenv("ARTIFACT_SIGNING_KEY"); path != "" {
+		return path
+	}
+
+	return viper.GetString("signing.key")
+}
+
+// PublicKeyPath returns the configured verification key:
+// ARTIFACT_SIGNING_PUBLIC_KEY or the signing.publicKey config key.
+func PublicKeyPath() string {
+	if path := os.Getenv("ARTIFACT_SIGNING_PUBLIC_KEY"); path != "" {
+		return path
+	}
+
+	return viper.GetString("signing.publicKey")
+}
+
+// LoadPrivateKey reads an unencrypted PEM encoded ECDSA or RSA private key.
+func LoadPrivateKey(filename string) (crypto.Signer, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var key interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		if strings.Contains(block.Type, "ENCRYPTED") {
+			return nil, fmt.Errorf("'%s' is encrypted: use an unencrypted PKCS#8 key, e.g. from 'openssl genpkey -algorithm EC -pkeyopt ec_paramgen_curve:P-256'", filename)
+		}
+		return nil, fmt.Errorf("'%s' is a %s, not a private key", filename, block.Type)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse private key '%s': %v", filename, err)
+	}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		return k, nil
+	case *rsa.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("'%s' is not an ECDSA or RSA key", filename)
+	}
+}
+
+// LoadPublicKey reads a PEM encoded ECDSA or RSA public key, e.g. cosign.pub.
+func LoadPublicKey(filename string) (crypto.PublicKey, error) {
+	block, err := readPEM(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.Type != "PUBLIC KEY" {
+		return nil, fmt.Errorf("'%s' is a %s, not a public key", filename, block.Type)
+	}
+
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key '%s': %v", filename, err)
+	}
+
+	switch key.(type) {
+	case *ecdsa.PublicKey, *rsa.PublicKey:
+		return key, nil
+	default:
+		return nil, fmt.Errorf("'%s' is not an ECDSA or RSA key", filename)
+	}
+}
+
+// Sign returns the base64 encoded signature of the contents of r.
+func Sign(signer crypto.Signer, r io.Reader) ([]byte, error) {
+	digest, err := digestOf(r)
+	if err != nil {
+		return nil, err
+	}
+
+	signature, err := signer.Sign(rand.Reader, digest, crypto.SHA256)
+	if err != nil {
+		return nil, err
+	}
+
+	return []byte(base64.StdEncoding.EncodeToString(signature)), nil
+}
+
+// Verify checks the base64 encoded signature of the contents of r, and
+// returns ErrInvalidSignature if it does not match.
+func Verify(key crypto.PublicKey, r io.Reader, signature []byte) error {
+	decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(signature)))
+	if err != nil {
+		return fmt.Errorf("%w: it is not base64 encoded", ErrInvalidSignature)
+	}
+
+	digest, err := digestOf(r)
+	if err != nil {
+		return err
+	}
+
+	valid := false
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(k, digest, decoded)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, decoded) == nil
+	}
+
+	if !valid {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func digestOf(r io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+func readPEM(filename string) (*pem.Block, error) {
+	// #nosec
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("'%s' is not PEM encoded", filename)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,94 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test__SignAndVerify(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	for name, key := range map[string]crypto.Signer{"ecdsa": ecKey, "rsa": rsaKey} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			privatePath, publicPath := writeTestKeys(t, dir, key)
+
+			signer, err := LoadPrivateKey(privatePath)
+			require.NoError(t, err)
+			publicKey, err := LoadPublicKey(publicPath)
+			require.NoError(t, err)
+
+			signature, err := Sign(signer, strings.NewReader("app binary"))
+			require.NoError(t, err)
+
+			assert.NoError(t, Verify(publicKey, strings.NewReader("app binary"), signature))
+			assert.NoError(t, Verify(publicKey, strings.NewReader("app binary"), append(signature, '\n')))
+			assert.ErrorIs(t, Verify(publicKey, strings.NewReader("tampered"), signature), ErrInvalidSignature)
+		})
+	}
+
+	t.Run("rejects signatures of other keys", func(t *testing.T) {
+		otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+
+		signature, err := Sign(otherKey, strings.NewReader("app binary"))
+		require.NoError(t, err)
+		assert.ErrorIs(t, Verify(ecKey.Public(), strings.NewReader("app binary"), signature), ErrInvalidSignature)
+	})
+}
+
+func Test__LoadKeys(t *testing.T) {
+	dir := t.TempDir()
+
+	encrypted := filepath.Join(dir, "cosign.key")
+	require.NoError(t, os.WriteFile(encrypted, pem.EncodeToMemory(&pem.Block{Type: "ENCRYPTED SIGSTORE PRIVATE KEY", Bytes: []byte("x")}), 0600))
+	_, err := LoadPrivateKey(encrypted)
+	assert.ErrorContains(t, err, "is encrypted")
+
+	notPEM := filepath.Join(dir, "key.txt")
+	require.NoError(t, os.WriteFile(notPEM, []byte("key"), 0600))
+	_, err = LoadPublicKey(notPEM)
+	assert.ErrorContains(t, err, "not PEM encoded")
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	privatePath, _ := writeTestKeys(t, dir, key)
+	_, err = LoadPublicKey(privatePath)
+	assert.ErrorContains(t, err, "not a public key")
+}
+
+func Test__SignaturePath(t *testing.T) {
+	assert.Equal(t, "artifacts/projects/1/bin/app.sig", SignaturePath("artifacts/projects/1/bin/app"))
+	assert.True(t, IsSignature("artifacts/projects/1/bin/app.sig"))
+	assert.False(t, IsSignature("artifacts/projects/1/bin/app"))
+}
+
+// writeTestKeys writes key as PEM encoded PKCS#8 and PKIX files to dir,
+// and returns their paths.
+func writeTestKeys(t *testing.T, dir string, key crypto.Signer) (string, string) {
+	privateDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	publicDER, err := x509.MarshalPKIXPublicKey(key.Public())
+	require.NoError(t, err)
+
+	privatePath := filepath.Join(dir, "signing.key")
+	publicPath := filepath.Join(dir, "signing.pub")
+	require.NoError(t, os.WriteFile(privatePath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privateDER}), 0600))
+	require.NoError(t, os.WriteFile(publicPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER}), 0600))
+	return privatePath, publicPath
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -22,7 +23,7 @@ type PullStats struct {
 	TotalSize int64
 }
 
-func Pull(hubClient *hub.Client, resolver *files.PathResolver, options PullOptions) (*files.ResolvedPath, *PullStats, error) {
+func Pull(ctx context.Context, hubClient *hub.Client, resolver *files.PathResolver, options PullOptions) (*files.ResolvedPath, *PullStats, error) {
 	paths, err := resolver.Resolve(files.OperationPull, options.SourcePath, options.DestinationOverride)
 	if err != nil {
 		return nil, nil, err
@@ -43,7 +44,7 @@ func Pull(hubClient *hub.Client, resolver *files.PathResolver, options PullOptio
 		return nil, nil, err
 	}
 
-	stats, err := doPull(artifacts)
+	stats, err := doPull(ctx, artifacts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -78,13 +79,13 @@ func buildArtifacts(signedURLs []*api.SignedURL, paths *files.ResolvedPath, forc
 	return artifacts, nil
 }
 
-func doPull(artifacts []*api.Artifact) (*PullStats, error) {
+func doPull(ctx context.Context, artifacts []*api.Artifact) (*PullStats, error) {
 	client := NewHTTPClient()
 	stats := &PullStats{}
 
 	for _, artifact := range artifacts {
 		for _, signedURL := range artifact.URLs {
-			if err := signedURL.Follow(client, artifact); err != nil {
+			if err := signedURL.Follow(ctx, client, artifact); err != nil {
 				return nil, err
 			}
 
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path"
@@ -31,7 +32,7 @@ func (o *PushOptions) RequestType() hub.GenerateSignedURLsRequestType {
 	return hub.GenerateSignedURLsRequestPUSH
 }
 
-func Push(hubClient *hub.Client, resolver *files.PathResolver, options PushOptions) (*files.ResolvedPath, *PushStats, error) {
+func Push(ctx context.Context, hubClient *hub.Client, resolver *files.PathResolver, options PushOptions) (*files.ResolvedPath, *PushStats, error) {
 	paths, err := resolver.Resolve(files.OperationPush, options.SourcePath, options.DestinationOverride)
 	if err != nil {
 		return nil, nil, err
@@ -57,7 +58,7 @@ func Push(hubClient *hub.Client, resolver *files.PathResolver, options PushOptio
 		return nil, nil, err
 	}
 
-	stats, err := doPush(artifacts)
+	stats, err := doPush(ctx, artifacts)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -77,7 +78,7 @@ func LocateArtifacts(paths *files.ResolvedPath) ([]*api.Artifact, error) {
 	}
 
 	items := []*api.Artifact{}
-	err = filepath.Walk(paths.Source, func(filename string, info os.FileInfo, err error) error {
+	err = files.Walk(paths.Source, func(filename string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
@@ -136,7 +137,7 @@ func attachURLs(items []*api.Artifact, signedURLs []*api.SignedURL, force bool)
 	return nil
 }
 
-func doPush(artifacts []*api.Artifact) (*PushStats, error) {
+func doPush(ctx context.Context, artifacts []*api.Artifact) (*PushStats, error) {
 	client := NewHTTPClient()
 	stats := &PushStats{}
 
@@ -147,7 +148,7 @@ func doPush(artifacts []*api.Artifact) (*PushStats, error) {
 		}
 
 		for _, signedURL := range artifact.URLs {
-			if err := signedURL.Follow(client, artifact); err != nil {
+			if err := signedURL.Follow(ctx, client, artifact); err != nil {
 				return nil, err
 			}
 		}
@@ -1,24 +1,23 @@
 package storage
 
 import (
-	"io/ioutil"
+	"io"
 	"net/http"
-	"time"
 
 	"github.com/hashicorp/go-retryablehttp"
 	"github.com/semaphoreci/artifact/pkg/common"
+	"github.com/semaphoreci/artifact/pkg/retry"
 	log "github.com/sirupsen/logrus"
 )
 
-// NewHTTPClient creates a new retryable HTTP client for storage operations.
+// maxLoggedBodySize is how much of the body of a failed request is logged.
+const maxLoggedBodySize = 4096
+
+// NewHTTPClient creates a new HTTP client for storage operations, retrying
+// with the configured retry policy.
 func NewHTTPClient() *retryablehttp.Client {
-	return &retryablehttp.Client{
-		HTTPClient:   http.DefaultClient,
-		RetryWaitMin: 500 * time.Millisecond,
-		RetryWaitMax: time.Second,
-		RetryMax:     4,
-		CheckRetry:   retryablehttp.DefaultRetryPolicy,
-		Backoff:      retryablehttp.DefaultBackoff,
+	client := &retryablehttp.Client{
+		HTTPClient: http.DefaultClient,
 		ResponseLogHook: func(l retryablehttp.Logger, r *http.Response) {
 			if common.IsStatusOK(r.StatusCode) {
 				return
@@ -29,7 +28,10 @@ func NewHTTPClient() *retryablehttp.Client {
 				return
 			}
 
-			body, err := ioutil.ReadAll(r.Body)
+			// Only the start of the body is logged: error responses of
+			// storage services are short, but a misbehaving proxy may
+			// answer with a whole file.
+			body, err := io.ReadAll(io.LimitReader(r.Body, maxLoggedBodySize))
 			if err != nil {
 				log.Errorf(
 					"%s request to %s failed with %d status code\n",
@@ -37,6 +39,7 @@ func NewHTTPClient() *retryablehttp.Client {
 					r.Request.URL,
 					r.StatusCode,
 				)
+				return
 			}
 
 			log.Errorf(
@@ -48,4 +51,7 @@ func NewHTTPClient() *retryablehttp.Client {
 			)
 		},
 	}
+
+	retry.Load().Configure(client)
+	return client
 }
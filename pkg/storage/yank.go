@@ -1,19 +1,20 @@
 package storage
 
 import (
+	"context"
 	api "github.com/semaphoreci/artifact/pkg/api"
 	hub "github.com/semaphoreci/artifact/pkg/hub"
 	log "github.com/sirupsen/logrus"
 )
 
 // Deletes a file or directory from the remote storage
-func Yank(hubClient *hub.Client, name string) error {
+func Yank(ctx context.Context, hubClient *hub.Client, name string) error {
 	response, err := hubClient.GenerateSignedURLs([]string{name}, hub.GenerateSignedURLsRequestYANK)
 	if err != nil {
 		return err
 	}
 
-	err = doYank(response.Urls)
+	err = doYank(ctx, response.Urls)
 	if err != nil {
 		log.Errorf("Error deleting artifact. Make sure the artifact you are trying to yank exists: %v\n", err)
 		return err
@@ -22,13 +23,13 @@ func Yank(hubClient *hub.Client, name string) error {
 	return nil
 }
 
-func doYank(URLs []*api.SignedURL) error {
+func doYank(ctx context.Context, URLs []*api.SignedURL) error {
 	client := NewHTTPClient()
 
 	for _, u := range URLs {
 		// The hub is not returning the method for yank operations, so we fill it here
 		u.Method = "DELETE"
-		if err := u.Follow(client, nil); err != nil {
+		if err := u.Follow(ctx, client, nil); err != nil {
 			return err
 		}
 	}
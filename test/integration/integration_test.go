@@ -1,6 +1,8 @@
 package integration_test
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -103,6 +105,19 @@ func Test__Pull(t *testing.T) {
 		os.RemoveAll("one-level")
 	})
 
+	t.Run("pulling file that does not match its checksum fails", func(t *testing.T) {
+		sum := sha256.Sum256([]byte("original"))
+		assert.Nil(t, os.WriteFile(filepath.Join(storage.StorageDirectory, "artifacts/jobs/1/corrupt.txt"), []byte("corrupted"), 0600))
+		assert.Nil(t, os.MkdirAll(filepath.Join(storage.StorageDirectory, "artifacts/jobs/1/.checksums"), 0755))
+		assert.Nil(t, os.WriteFile(filepath.Join(storage.StorageDirectory, "artifacts/jobs/1/.checksums/corrupt.txt.sha256"), []byte(hex.EncodeToString(sum[:])), 0600))
+
+		output, err := executeCommand("pull", rootFolder, []string{"corrupt.txt"})
+		assert.NotNil(t, err)
+		assert.Contains(t, output, "Error pulling artifact")
+		assert.Contains(t, output, "artifacts/jobs/1/corrupt.txt does not match its checksum")
+		assert.NoFileExists(t, "corrupt.txt")
+	})
+
 	hub.Close()
 	storage.Close()
 }
@@ -228,7 +243,9 @@ func Test__Push(t *testing.T) {
 		output, err := executeCommand("push", rootFolder, []string{tmpDir, "-d", "one-level"})
 		assert.NotNil(t, err)
 		assert.Contains(t, output, "Error pushing artifact")
-		assert.Contains(t, output, "'artifacts/jobs/1/one-level/file1.txt' already exists in the remote storage; delete it first, or use --force flag")
+		assert.Contains(t, output, "2 files already exist in the remote storage; delete them first, or use --force flag:")
+		assert.Contains(t, output, "* 'artifacts/jobs/1/one-level/file1.txt'")
+		assert.Contains(t, output, "* 'artifacts/jobs/1/one-level/file2.txt'")
 		os.RemoveAll(tmpDir)
 	})
 
@@ -279,7 +296,7 @@ func Test__Push(t *testing.T) {
 
 		output, err := executeTempScript(tmpScript)
 		assert.Nil(t, err)
-		assert.Contains(t, output, "Detected stdin, saving it to a temporary file...")
+		assert.Contains(t, output, "Detected stdin, streaming it to the storage...")
 		assert.Contains(t, output, "Successfully pushed artifact for current job")
 
 		output, err = executeCommand("pull", rootFolder, []string{"from-dash.txt"})
@@ -306,7 +323,7 @@ func Test__Push(t *testing.T) {
 
 		output, err := executeTempScript(tmpScript)
 		assert.Nil(t, err)
-		assert.Contains(t, output, "Detected stdin, saving it to a temporary file...")
+		assert.Contains(t, output, "Detected stdin, streaming it to the storage...")
 		assert.Contains(t, output, "Successfully pushed artifact for current job")
 
 		output, err = executeCommand("pull", rootFolder, []string{"from-dev-stdin.txt"})
@@ -336,7 +353,7 @@ func Test__Push(t *testing.T) {
 
 		output, err := executeTempScript(tmpScript)
 		assert.Nil(t, err)
-		assert.NotContains(t, output, "Detected stdin, saving it to a temporary file...")
+		assert.NotContains(t, output, "Detected stdin, streaming it to the storage...")
 		assert.Contains(t, output, "Successfully pushed artifact for current job")
 
 		output, err = executeCommand("pull", rootFolder, []string{"not-from-pipe.txt"})
@@ -364,7 +381,7 @@ func Test__Push(t *testing.T) {
 
 		output, err := executeTempScript(tmpScript)
 		assert.Nil(t, err)
-		assert.Contains(t, output, "Detected stdin, saving it to a temporary file...")
+		assert.Contains(t, output, "Detected stdin, streaming it to the storage...")
 		assert.Contains(t, output, "Successfully pushed artifact for current job")
 
 		// Pull uploaded artifact
@@ -441,7 +458,8 @@ func Test__PushOn5xxEventuallySucceeds(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Contains(t, output, "temporarily unavailable")
 	assert.Contains(t, output, "Successfully pushed artifact for current job")
-	assert.Equal(t, 3, storage.RequestCount)
+	// 2 failed + 1 successful upload, plus the checksum sidecar
+	assert.Equal(t, 4, storage.RequestCount)
 
 	os.Remove(tmpFile.Name())
 	hub.Close()
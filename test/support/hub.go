@@ -6,6 +6,9 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/semaphoreci/artifact/pkg/api"
@@ -16,6 +19,7 @@ type HubMockServer struct {
 	Server        *httptest.Server
 	Handler       http.Handler
 	StorageServer *StorageMockServer
+	NoList        bool // answer list requests with 404, like Hubs without the list API
 }
 
 func NewHubMockServer(storageServer *StorageMockServer) *HubMockServer {
@@ -26,6 +30,8 @@ func (m *HubMockServer) Init() {
 	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if strings.HasSuffix(r.URL.Path, "/api/v1/artifacts") {
 			m.handleRequest(w, r)
+		} else if strings.HasSuffix(r.URL.Path, "/api/v1/artifacts/list") && !m.NoList {
+			m.handleListRequest(w, r)
 		} else {
 			w.WriteHeader(404)
 		}
@@ -75,6 +81,60 @@ func (m *HubMockServer) handleRequest(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(data)
 }
 
+// handleListRequest answers with a page of the files under the requested
+// path; page tokens are the index of the first file of the page.
+func (m *HubMockServer) handleListRequest(w http.ResponseWriter, r *http.Request) {
+	request := hub.ListArtifactsRequest{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		fmt.Printf("[HUB MOCK] Error unmarshaling list request: %v\n", err)
+		w.WriteHeader(500)
+		return
+	}
+
+	fmt.Printf("[HUB MOCK] Received list request: %v\n", request)
+
+	names := []string{}
+	if m.StorageServer.IsFile(request.Path) {
+		names = append(names, request.Path)
+	} else if m.StorageServer.IsDir(request.Path) {
+		found, err := m.StorageServer.findFilesInDir(request.Path)
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		names = found
+	}
+	sort.Strings(names)
+
+	start, _ := strconv.Atoi(request.PageToken)
+	end := len(names)
+	if request.PageSize > 0 && start+request.PageSize < end {
+		end = start + request.PageSize
+	}
+
+	response := &hub.ListArtifactsResponse{Artifacts: []hub.ListedArtifact{}}
+	for _, name := range names[min(start, len(names)):end] {
+		info, err := os.Stat(m.StorageServer.filePath(name))
+		if err != nil {
+			w.WriteHeader(500)
+			return
+		}
+		response.Artifacts = append(response.Artifacts, hub.ListedArtifact{Path: name, Size: info.Size(), UpdatedAt: info.ModTime()})
+	}
+
+	if end < len(names) {
+		response.NextPageToken = strconv.Itoa(end)
+	}
+
+	data, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(500)
+		return
+	}
+
+	_, _ = w.Write(data)
+}
+
 func (m *HubMockServer) generateUrls(request hub.GenerateSignedURLsRequest) ([]*api.SignedURL, error) {
 	switch request.Type {
 	case hub.GenerateSignedURLsRequestPUSH:
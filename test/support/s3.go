@@ -0,0 +1,88 @@
+package testsupport
+
+import (
+	"bytes"
+	"context"
+	"net/http/httptest"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/johannesboyne/gofakes3"
+	"github.com/johannesboyne/gofakes3/backend/s3mem"
+)
+
+const S3MockBucket = "test-bucket"
+
+// S3MockServer is an in-memory S3 server for exercising the S3 backend
+// through the CLI commands.
+type S3MockServer struct {
+	Server *httptest.Server
+	Client *s3.Client
+}
+
+func NewS3MockServer() (*S3MockServer, error) {
+	faker := gofakes3.New(s3mem.New())
+	server := httptest.NewServer(faker.Server())
+
+	awsCfg, err := config.LoadDefaultConfig(context.Background(),
+		config.WithRegion("us-east-1"),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider("test", "test", "")),
+	)
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		o.BaseEndpoint = aws.String(server.URL)
+		o.UsePathStyle = true
+	})
+
+	_, err = client.CreateBucket(context.Background(), &s3.CreateBucketInput{
+		Bucket: aws.String(S3MockBucket),
+	})
+	if err != nil {
+		server.Close()
+		return nil, err
+	}
+
+	return &S3MockServer{Server: server, Client: client}, nil
+}
+
+// UseAsBackend points the artifact CLI at this server through environment variables.
+func (m *S3MockServer) UseAsBackend() {
+	os.Setenv("ARTIFACT_BACKEND", "s3")
+	os.Setenv("ARTIFACT_S3_BUCKET", S3MockBucket)
+	os.Setenv("ARTIFACT_S3_REGION", "us-east-1")
+	os.Setenv("ARTIFACT_S3_ENDPOINT", m.Server.URL)
+	os.Setenv("ARTIFACT_S3_FORCE_PATH_STYLE", "true")
+	os.Setenv("AWS_ACCESS_KEY_ID", "test")
+	os.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+}
+
+func (m *S3MockServer) PutFiles(files []FileMock) error {
+	for _, file := range files {
+		_, err := m.Client.PutObject(context.Background(), &s3.PutObjectInput{
+			Bucket: aws.String(S3MockBucket),
+			Key:    aws.String(file.Name),
+			Body:   bytes.NewReader([]byte(file.Contents)),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *S3MockServer) Close() {
+	os.Unsetenv("ARTIFACT_BACKEND")
+	os.Unsetenv("ARTIFACT_S3_BUCKET")
+	os.Unsetenv("ARTIFACT_S3_REGION")
+	os.Unsetenv("ARTIFACT_S3_ENDPOINT")
+	os.Unsetenv("ARTIFACT_S3_FORCE_PATH_STYLE")
+	m.Server.Close()
+}
@@ -201,7 +201,8 @@ func (m *StorageMockServer) PullURLs(paths []string) ([]*api.SignedURL, error) {
 		return signedURLs, nil
 	}
 
-	return nil, fmt.Errorf("%s does not exist", path)
+	// Like the hub, return no URLs for paths that do not exist.
+	return []*api.SignedURL{}, nil
 }
 
 func (m *StorageMockServer) YankURLs(paths []string) ([]*api.SignedURL, error) {
@@ -289,19 +290,14 @@ func (m *StorageMockServer) addFile(fileName string, reader io.ReadCloser) error
 		return err
 	}
 
-	// #nosec
-	newFile, err := os.Create(filePath)
+	// Like real storage, keep nothing of an upload cut short
+	data, err := io.ReadAll(reader)
 	if err != nil {
 		return err
 	}
 
-	defer newFile.Close()
-
-	if _, err := io.Copy(newFile, reader); err != nil {
-		return err
-	}
-
-	return nil
+	// #nosec
+	return os.WriteFile(filePath, data, 0600)
 }
 
 func (m *StorageMockServer) removeFile(fileName string) error {